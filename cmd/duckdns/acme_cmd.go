@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+const (
+	// defaultACMEPropagationTimeout は、present で TXT レコードが DuckDNS のネームサーバーに反映されるまで待つ時間の既定値です
+	defaultACMEPropagationTimeout = 2 * time.Minute
+
+	// acmePollInterval は、TXT レコードが反映されたか問い合わせる間隔です
+	acmePollInterval = 5 * time.Second
+
+	// acmeTimeout は、DuckDNS に TXT レコードを書き込むときのタイムアウトです
+	acmeTimeout = time.Minute
+)
+
+// txtResolver は、TXT レコードを問い合わせる Resolver です（*net.Resolver が満たすます）。
+type txtResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// acmeHook は、acme present / cleanup が書き込む DuckDNS と、反映を確かめる DNS の問い合わせ先です。
+// テストでは、テスト用のサーバーと Resolver に差し替えるますね。
+type acmeHook struct {
+	// newClient は、設定から DuckDNS のクライアントをつくるます
+	newClient func(cfg *config.Config) *duckdns.Client
+
+	// resolver は、TXT レコードの反映を確かめる Resolver を返すます
+	resolver func(ctx context.Context) txtResolver
+}
+
+// defaultACMEHook は、本物の DuckDNS と duckdns.org のネームサーバーを使う acmeHook です。
+var defaultACMEHook = acmeHook{
+	newClient: newDuckDNSClient,
+	resolver:  duckDNSNameservers,
+}
+
+// runACMECommand は、"duckdns acme" を実行するます。
+// ACME の DNS-01 チャレンジの TXT レコードを、present で書き込んで cleanup で消去するますね。
+func runACMECommand(args []string) int {
+	if len(args) == 0 {
+		printACMEUsage()
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "present":
+		return defaultACMEHook.run("present", args[1:])
+	case "cleanup":
+		return defaultACMEHook.run("cleanup", args[1:])
+	case "-h", "-help", "--help", "help":
+		printACMEUsage()
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "不明な acme サブコマンドです: %s\n\n", args[0])
+		printACMEUsage()
+		return exitUsage
+	}
+}
+
+// printACMEUsage は、acme コマンドのヘルプを表示するます。
+func printACMEUsage() {
+	fmt.Fprintf(os.Stderr, `使い方:
+  %[1]s acme present [オプション] [<FQDN> <値>]
+  %[1]s acme cleanup [オプション] [<FQDN> <値>]
+
+ACME (Let's Encrypt など) の DNS-01 チャレンジの TXT レコードを DuckDNS に書き込み (present)、消去 (cleanup) します。
+*.home.duckdns.org のようなワイルドカードの証明書も取得できます。
+
+サブコマンド:
+  present   TXT レコードを書き込み、DuckDNS のネームサーバーに反映されるまで待ちます
+  cleanup   TXT レコードを消去します
+
+FQDN と値を省略した場合は、certbot の環境変数 CERTBOT_DOMAIN と CERTBOT_VALIDATION を使います。
+
+例:
+  # certbot の manual プラグインのフック
+  certbot certonly --manual --preferred-challenges dns \
+    --manual-auth-hook "%[1]s acme present" \
+    --manual-cleanup-hook "%[1]s acme cleanup" \
+    -d "*.home.duckdns.org"
+
+  # lego の exec プロバイダー
+  EXEC_PATH=/usr/local/bin/duckdns-acme lego --dns exec -d "*.home.duckdns.org" run
+  (duckdns-acme は「exec duckdns acme "$@"」を実行するシェルスクリプト)
+
+トークンは設定ファイル (-config) のドメインのトークン、-token、または環境変数 DUCKDNS_TOKEN を使います。
+DuckDNS の TXT レコードは1つしかないため、home.duckdns.org と *.home.duckdns.org を1つの証明書で
+同時に取得すると、先に書き込んだ値が上書きされて検証に失敗します。*.home.duckdns.org だけで取得してください。
+`, os.Args[0])
+}
+
+// run は、acme present / cleanup を実行するます。
+func (h acmeHook) run(action string, args []string) int {
+	fs := flag.NewFlagSet("acme "+action, flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (duckdns のドメインとトークンを使う)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagToken, "token", "", "DuckDNS API トークン (duckdns.token を上書き)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	timeout := fs.Duration("propagation-timeout", defaultACMEPropagationTimeout, "present で TXT レコードが反映されるまで待つ最大の時間 (0 の場合は待たない)")
+	fs.Usage = printACMEUsage
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	domain, value, err := acmeChallenge(action, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	// フックの出力は certbot や lego のログに混ざるので、警告以上だけを出すます
+	if err := initLogger(config.LogConfig{Level: firstNonEmpty(flagLogLevel, "warn")}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	defer closeLogFile()
+
+	cfg, err := readConfiguration()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfig
+	}
+	token := acmeToken(cfg, domain)
+	if token == "" {
+		fmt.Fprintf(os.Stderr, "%s のトークンが設定されていないます (設定ファイル、-token、または環境変数 DUCKDNS_TOKEN で指定してください)\n", domain)
+		return exitConfig
+	}
+
+	client := h.newClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), acmeTimeout)
+	defer cancel()
+	if action == "cleanup" {
+		_, err = client.ClearTXT(ctx, []string{domain}, token)
+	} else {
+		_, err = client.SetTXT(ctx, []string{domain}, token, value)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s の TXT レコードを書き込めなかったます: %v\n", domain, err)
+		if errors.Is(err, duckdns.ErrRejected) {
+			return exitRejected
+		}
+		return exitNetwork
+	}
+
+	if action == "present" && *timeout > 0 {
+		if err := waitForTXT(h.resolver, domain+".duckdns.org", value, *timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitNetwork
+		}
+	}
+	return exitOK
+}
+
+// acmeChallenge は、引数か certbot の環境変数から、TXT レコードを書き込むドメインと値を決めるます。
+// lego の "_acme-challenge.home.duckdns.org." のような FQDN も、DuckDNS のドメイン名 ("home") にするますね。
+// cleanup では値を使わないので、省略できるます。
+func acmeChallenge(action string, args []string) (string, string, error) {
+	fqdn, value := os.Getenv("CERTBOT_DOMAIN"), os.Getenv("CERTBOT_VALIDATION")
+	switch len(args) {
+	case 0:
+		if fqdn == "" || (action == "present" && value == "") {
+			return "", "", errors.New("FQDN と値を指定してください (certbot のフックの場合は CERTBOT_DOMAIN と CERTBOT_VALIDATION)")
+		}
+	case 1, 2:
+		fqdn, value = args[0], ""
+		if len(args) == 2 {
+			value = args[1]
+		}
+	default:
+		return "", "", fmt.Errorf("引数が多すぎます: %v", args)
+	}
+	if action == "present" && value == "" {
+		return "", "", errors.New("TXT レコードの値を指定してください")
+	}
+
+	domain, err := duckdns.NormalizeDomain(fqdn)
+	if err != nil {
+		return "", "", err
+	}
+	return domain, value, nil
+}
+
+// acmeToken は、ドメインのトークンを返すます。
+// 設定ファイルにあるドメインはそのトークンを、ないドメインは duckdns.token (-token、DUCKDNS_TOKEN) を使うますね。
+func acmeToken(cfg *config.Config, domain string) string {
+	for _, target := range cfg.Targets() {
+		if target.Provider == nil && target.Domain == domain && target.Token != "" {
+			return target.Token
+		}
+	}
+	return cfg.DuckDNS.Token
+}
+
+// waitForTXT は、DuckDNS のネームサーバーに問い合わせて、TXT レコードが value になるまで待つます。
+// ACME のサーバーが古い値を見て検証に失敗しないように、反映を確かめてからフックを終えるますね。
+// newResolver は、問い合わせに使う Resolver を返すます（ふだんは duckDNSNameservers ですね）。
+func waitForTXT(newResolver func(ctx context.Context) txtResolver, fqdn, value string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resolver := newResolver(ctx)
+	for {
+		txts, err := resolver.LookupTXT(ctx, fqdn)
+		if err == nil && slices.Contains(txts, value) {
+			return nil
+		}
+		slog.Debug("TXT レコードの反映を待つます", "fqdn", fqdn, "txt", txts, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s の TXT レコードが %s 以内に反映されなかったます", fqdn, timeout)
+		case <-time.After(acmePollInterval):
+		}
+	}
+}
+
+// duckDNSNameservers は、duckdns.org のネームサーバーに直接問い合わせる Resolver を返すます。
+// キャッシュを持つリゾルバーでは反映が遅れて見えるからですね。ネームサーバーが分からない場合はシステムのリゾルバーを使うます。
+func duckDNSNameservers(ctx context.Context) txtResolver {
+	nss, err := net.DefaultResolver.LookupNS(ctx, "duckdns.org")
+	if err != nil {
+		return net.DefaultResolver
+	}
+	var addrs []string
+	for _, ns := range nss {
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, ns.Host)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip.IP.String(), "53"))
+		}
+	}
+	if len(addrs) == 0 {
+		return net.DefaultResolver
+	}
+	resolver, err := httpclient.NewResolver(addrs)
+	if err != nil {
+		return net.DefaultResolver
+	}
+	return resolver
+}
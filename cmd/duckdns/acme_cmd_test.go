@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/pkg/duckdnstest"
+)
+
+// TestACMEChallenge は、引数と certbot の環境変数から、TXT レコードのドメインと値を決めることをテストするます。
+func TestACMEChallenge(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		args       []string
+		domain     string
+		validation string
+		wantDomain string
+		wantValue  string
+		wantErr    bool
+	}{
+		{name: "引数の FQDN と値", action: "present", args: []string{"home.duckdns.org", "abc"}, wantDomain: "home", wantValue: "abc"},
+		{name: "lego の FQDN", action: "present", args: []string{"_acme-challenge.home.duckdns.org.", "abc"}, wantDomain: "home", wantValue: "abc"},
+		{name: "サブドメインだけ", action: "present", args: []string{"home", "abc"}, wantDomain: "home", wantValue: "abc"},
+		{name: "certbot の環境変数", action: "present", domain: "home.duckdns.org", validation: "xyz", wantDomain: "home", wantValue: "xyz"},
+		{name: "引数が環境変数より優先", action: "present", args: []string{"nas.duckdns.org", "abc"}, domain: "home.duckdns.org", validation: "xyz", wantDomain: "nas", wantValue: "abc"},
+		{name: "cleanup は値を省略できる", action: "cleanup", args: []string{"_acme-challenge.home.duckdns.org."}, wantDomain: "home"},
+		{name: "cleanup の環境変数", action: "cleanup", domain: "home.duckdns.org", wantDomain: "home"},
+		{name: "present で値がない", action: "present", args: []string{"home.duckdns.org"}, wantErr: true},
+		{name: "present で環境変数の値がない", action: "present", domain: "home.duckdns.org", wantErr: true},
+		{name: "引数も環境変数もない", action: "cleanup", wantErr: true},
+		{name: "引数が多すぎる", action: "present", args: []string{"home", "abc", "extra"}, wantErr: true},
+		{name: "DuckDNS 以外のドメイン", action: "present", args: []string{"_acme-challenge.example.com.", "abc"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CERTBOT_DOMAIN", tt.domain)
+			t.Setenv("CERTBOT_VALIDATION", tt.validation)
+
+			domain, value, err := acmeChallenge(tt.action, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("エラーの有無が一致しないます。期待: %v, 実際: %v", tt.wantErr, err)
+			}
+			if domain != tt.wantDomain || value != tt.wantValue {
+				t.Errorf("acmeChallenge() = %q, %q, %q, %q であるべきです", domain, value, tt.wantDomain, tt.wantValue)
+			}
+		})
+	}
+}
+
+// TestACMEToken は、ドメインごとのトークンを duckdns.token より優先することをテストするます。
+func TestACMEToken(t *testing.T) {
+	cfg := &config.Config{DuckDNS: config.DuckDNSConfig{
+		Token: "global-token",
+		Domains: []config.DomainConfig{
+			{Name: "home", Token: "home-token"},
+			{Name: "nas"},
+		},
+	}}
+
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "home", want: "home-token"},
+		{domain: "nas", want: "global-token"},
+		{domain: "other", want: "global-token"},
+	}
+	for _, tt := range tests {
+		if got := acmeToken(cfg, tt.domain); got != tt.want {
+			t.Errorf("acmeToken(%q) = %q, %q であるべきです", tt.domain, got, tt.want)
+		}
+	}
+}
+
+// serverTXT は、テスト用のサーバーに書き込まれた TXT レコードを返す txtResolver です。
+type serverTXT struct {
+	srv     *duckdnstest.Server
+	lookups []string
+}
+
+// LookupTXT は、サーバーのドメインの TXT レコードを返すます。
+func (r *serverTXT) LookupTXT(_ context.Context, name string) ([]string, error) {
+	r.lookups = append(r.lookups, name)
+	if txt := r.srv.Record(name).TXT; txt != "" {
+		return []string{txt}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// TestACMEHook は、present と cleanup でテスト用のサーバーの TXT レコードを書き込み・消去することをテストするます。
+func TestACMEHook(t *testing.T) {
+	t.Setenv("DUCKDNS_TOKEN", "")
+	t.Setenv("DUCKDNS_PROFILE", "")
+	t.Setenv("CERTBOT_DOMAIN", "")
+	t.Setenv("CERTBOT_VALIDATION", "")
+
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+	srv.AddDomain("home-token", "home")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `duckdns:
+  token: "global-token"
+  domains:
+    - name: "home"
+      token: "home-token"
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("設定ファイルの作成に失敗: %v", err)
+	}
+
+	resolver := &serverTXT{srv: srv}
+	hook := acmeHook{
+		newClient: func(*config.Config) *duckdns.Client {
+			return srv.Client(duckdns.WithRetry(duckdns.RetryConfig{}))
+		},
+		resolver: func(context.Context) txtResolver { return resolver },
+	}
+
+	if code := hook.run("present", []string{"-config", path, "_acme-challenge.home.duckdns.org.", "challenge"}); code != exitOK {
+		t.Fatalf("present の終了コード = %d, exitOK であるべきです", code)
+	}
+	if got := srv.Record("home").TXT; got != "challenge" {
+		t.Errorf("present で TXT レコードを書き込むべきです: %q", got)
+	}
+	requests := srv.Requests()
+	if len(requests) != 1 || requests[0].Token != "home-token" || !requests[0].HasTXT {
+		t.Errorf("ドメインのトークンで TXT レコードを書き込むべきです: %+v", requests)
+	}
+	if len(resolver.lookups) == 0 || resolver.lookups[0] != "home.duckdns.org" {
+		t.Errorf("書き込んだあとで TXT レコードの反映を確かめるべきです: %v", resolver.lookups)
+	}
+
+	if code := hook.run("cleanup", []string{"-config", path, "_acme-challenge.home.duckdns.org.", "challenge"}); code != exitOK {
+		t.Fatalf("cleanup の終了コード = %d, exitOK であるべきです", code)
+	}
+	if got := srv.Record("home").TXT; got != "" {
+		t.Errorf("cleanup で TXT レコードを消去するべきです: %q", got)
+	}
+
+	// 登録していないドメインは、duckdns.token で書き込もうとして拒否される
+	if code := hook.run("present", []string{"-config", path, "other.duckdns.org", "challenge"}); code != exitRejected {
+		t.Errorf("拒否された場合の終了コード = %d, exitRejected であるべきです", code)
+	}
+}
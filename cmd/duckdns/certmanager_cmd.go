@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/certmanager"
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/kube"
+)
+
+// defaultSolverListen は、cert-manager の webhook ソルバーが待ち受けるアドレスの既定値です。
+// API サーバーから Service を通して接続されるので、すべてのアドレスで待ち受けるます。
+const defaultSolverListen = ":8443"
+
+// certReloadInterval は、サーバー証明書のファイルが変わったか確かめる間隔です。
+const certReloadInterval = time.Minute
+
+// runCertManagerCommand は、"duckdns cert-manager" を実行するます。
+// cert-manager の DNS-01 の webhook ソルバーとして待ち受けて、チャレンジの TXT レコードを DuckDNS に書き込むますね。
+func runCertManagerCommand(args []string) int {
+	fs := flag.NewFlagSet("cert-manager", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (duckdns のドメインとトークンを使う)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagToken, "token", "", "DuckDNS API トークン (Issuer に tokenSecretRef がない場合に使う)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	fs.StringVar(&flagLogFormat, "log-format", "", "ログ形式 (log.format を上書き)")
+	listen := fs.String("listen", defaultSolverListen, "待ち受けるアドレス")
+	groupName := fs.String("group-name", firstNonEmpty(os.Getenv("GROUP_NAME"), certmanager.DefaultGroupName), "Issuer の webhook.groupName に指定する API グループ (環境変数: GROUP_NAME)")
+	certFile := fs.String("tls-cert-file", "", "サーバー証明書のファイル (必須)")
+	keyFile := fs.String("tls-key-file", "", "サーバー証明書の秘密鍵のファイル (必須)")
+	clientCAFile := fs.String("client-ca-file", "", "API サーバーのクライアント証明書を検証する CA 証明書のファイル (省略した場合は kube-system の extension-apiserver-authentication から読み込む)")
+	apiServer := fs.String("api-server", "", "API サーバーの URL (例: http://127.0.0.1:8001。省略した場合は Pod のサービスアカウントを使う)")
+	tokenFile := fs.String("token-file", "", "API サーバーの Bearer トークンのファイル (-api-server を指定した場合)")
+	caFile := fs.String("ca-file", "", "API サーバーの証明書を検証する CA 証明書のファイル (-api-server を指定した場合)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s cert-manager [オプション]\n\n"+
+			"cert-manager の DNS-01 の webhook ソルバーとして待ち受け、チャレンジの TXT レコードを DuckDNS に書き込みます。\n"+
+			"API サーバーの APIService (%s/%s) から HTTPS で接続されるので、サーバー証明書が必要です。\n"+
+			"トークンは Issuer の config.tokenSecretRef の Secret、設定ファイルのドメインのトークン、-token の順に使います。\n\nオプション:\n",
+			os.Args[0], certmanager.DefaultGroupName, certmanager.Version)
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *certFile == "" || *keyFile == "" {
+		fmt.Fprintln(os.Stderr, "-tls-cert-file と -tls-key-file を指定してください")
+		return exitUsage
+	}
+
+	kubeCfg := kube.Config{Host: *apiServer, TokenFile: *tokenFile, CAFile: *caFile}
+	if *apiServer == "" {
+		var err error
+		if kubeCfg, err = kube.InCluster(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+	kubeClient, err := kube.NewClient(kubeCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if err := initLogger(config.LogConfig{Level: flagLogLevel, Format: flagLogFormat}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	defer closeLogFile()
+
+	// トークンは Issuer の Secret から読み込めるので、設定ファイルのドメインとトークンは省略できるます
+	cfg, err := readConfiguration()
+	if err != nil {
+		slog.Error("設定の読み込みに失敗したます",
+			"error", err,
+			"config_path", displayConfigPath(configPath),
+		)
+		return exitConfig
+	}
+	if err := initLogger(cfg.Log, logLocation(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	tokens := make(map[string]string)
+	for _, target := range cfg.Targets() {
+		if target.Provider == nil && target.Token != "" {
+			tokens[target.Domain] = target.Token
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	certs := &certReloader{certFile: *certFile, keyFile: *keyFile}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		slog.Error("サーバー証明書を読み込めないます", "error", err)
+		return exitConfig
+	}
+	clientCAs, allowedNames, err := loadClientCA(ctx, kubeClient, *clientCAFile)
+	if err != nil {
+		slog.Error("API サーバーのクライアント証明書の CA を読み込めないます", "error", err)
+		return exitConfig
+	}
+
+	solver := certmanager.NewSolver(newDuckDNSClient(cfg), kubeClient, tokens, cfg.DuckDNS.Token)
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           certmanager.RequireClientCert(certmanager.NewHandler(*groupName, solver), allowedNames),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+			ClientCAs:      clientCAs,
+			// kubelet のヘルスチェック (/healthz) はクライアント証明書を送らないので、証明書は任意にするます
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		},
+	}
+	// 停止するときは、処理中のリクエスト (DuckDNS への書き込み) が終わるのを待つます
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, stop := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer stop()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("cert-manager の webhook ソルバーとして待ち受けるます",
+		"version", version,
+		"listen", *listen,
+		"group_name", *groupName,
+		"solver_name", certmanager.SolverName,
+	)
+	if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("待ち受けられなかったます", "listen", *listen, "error", err)
+		return exitFailure
+	}
+	<-stopped
+	slog.Info("cert-manager の webhook ソルバーを終了するます")
+	return exitOK
+}
+
+// loadClientCA は、API サーバーのクライアント証明書を検証する CA と、許可する証明書の名前を読み込むます。
+// file を省略した場合は、API サーバーが公開している extension-apiserver-authentication から読み込むますね。
+func loadClientCA(ctx context.Context, client *kube.Client, file string) (*x509.CertPool, []string, error) {
+	var pem []byte
+	var allowedNames []string
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		pem = data
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, operatorAPITimeout)
+		defer cancel()
+		const namespace, name = "kube-system", "extension-apiserver-authentication"
+		data, err := client.ConfigMapValue(ctx, namespace, name, "requestheader-client-ca-file")
+		if err != nil {
+			return nil, nil, err
+		}
+		pem = []byte(data)
+		// 許可する名前がない (空の配列) 場合は、CA で検証できた証明書をすべて許可するます
+		if names, err := client.ConfigMapValue(ctx, namespace, name, "requestheader-allowed-names"); err == nil {
+			if err := json.Unmarshal([]byte(names), &allowedNames); err != nil {
+				return nil, nil, fmt.Errorf("requestheader-allowed-names の形式が不正です: %w", err)
+			}
+		}
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("CA 証明書の形式が不正です")
+	}
+	return pool, allowedNames, nil
+}
+
+// certReloader は、サーバー証明書のファイルが変わったら読み込み直すます。
+// cert-manager が証明書を更新すると Secret のファイルが置き換わるので、再起動しなくても新しい証明書を使えるますね。
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// GetCertificate は、tls.Config.GetCertificate として、今のサーバー証明書を返すます。
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert != nil && time.Since(c.checkedAt) < certReloadInterval {
+		return c.cert, nil
+	}
+	c.checkedAt = time.Now()
+
+	info, err := os.Stat(c.certFile)
+	if err != nil {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil && info.ModTime().Equal(c.modTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert != nil {
+			slog.Warn("新しいサーバー証明書を読み込めないので、今の証明書を使い続けるます", "error", err)
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil {
+		slog.Info("サーバー証明書を読み込み直したます", "cert_file", c.certFile)
+	}
+	c.cert, c.modTime = &cert, info.ModTime()
+	return c.cert, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/provider"
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// clearTimeout は、DuckDNS のレコードを消去するときのタイムアウトです。
+const clearTimeout = time.Minute
+
+// clearResultJSON は、clear の JSON 出力の1ドメイン分の結果です。
+type clearResultJSON struct {
+	Domain  string `json:"domain"`
+	Cleared bool   `json:"cleared"`
+	Error   string `json:"error,omitempty"`
+}
+
+// clearOutput は、clear の JSON 出力です。
+type clearOutput struct {
+	OK      bool              `json:"ok"`
+	Results []clearResultJSON `json:"results"`
+}
+
+// runClearCommand は、"duckdns clear" を実行するます。
+// -domain のドメインのレコード (A と AAAA) を DuckDNS の clear で消去して、結果を表示して状態ファイルに記録するますね。
+// ホストを廃止するときや、わざとサービスを止めるときに使うます。
+func runClearCommand(args []string) int {
+	fs := flag.NewFlagSet("clear", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (duckdns のドメインとトークンを使う)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagToken, "token", "", "DuckDNS API トークン (duckdns.token を上書き)")
+	fs.StringVar(&stateFile, "state-file", "", "状態ファイルのパス (環境変数: DUCKDNS_STATE_FILE)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	domainList := fs.String("domain", "", "レコードを消去するドメイン (カンマ区切りで複数指定可)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s clear -domain <ドメイン>[,<ドメイン>...] [オプション]\n\n"+
+			"DuckDNS のドメインのレコード (A と AAAA) を消去します。\n"+
+			"ホストを廃止するときや、わざとサービスを止めるときに使います。結果は状態ファイルの履歴にも記録します。\n"+
+			"トークンは設定ファイル (-config) のドメインのトークン、-token、または環境変数 DUCKDNS_TOKEN を使います。\n"+
+			"常駐している run は次のチェックでまた登録するので、先に停止してください。\n\nオプション:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "引数が多すぎます: %v\n", fs.Args())
+		return exitUsage
+	}
+	if *domainList == "" {
+		fmt.Fprintln(os.Stderr, "レコードを消去するドメインを -domain で指定してください")
+		return exitUsage
+	}
+
+	if err := initLogger(config.LogConfig{Level: firstNonEmpty(flagLogLevel, "warn")}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	defer closeLogFile()
+
+	var domains []string
+	for _, d := range strings.Split(*domainList, ",") {
+		domain, err := duckdns.NormalizeDomain(strings.TrimSpace(d))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+		domains = append(domains, domain)
+	}
+	cfg, err := readConfiguration()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfig
+	}
+
+	// 同じトークンのドメインは1回のリクエストにまとめるます
+	var tokens []string
+	byToken := make(map[string][]string)
+	for _, domain := range domains {
+		token := acmeToken(cfg, domain)
+		if token == "" {
+			fmt.Fprintf(os.Stderr, "%s のトークンが設定されていないます (設定ファイル、-token、または環境変数 DUCKDNS_TOKEN で指定してください)\n", domain)
+			return exitConfig
+		}
+		if _, ok := byToken[token]; !ok {
+			tokens = append(tokens, token)
+		}
+		byToken[token] = append(byToken[token], domain)
+	}
+
+	store, err := newStateStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "状態の保存先を準備できないます: %v\n", err)
+		return exitConfig
+	}
+	client := newDuckDNSClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), clearTimeout)
+	defer cancel()
+
+	output := clearOutput{OK: true}
+	var results []scheduler.Result
+	for _, token := range tokens {
+		var p provider.Clearer = provider.NewDuckDNS(client, token)
+		err := p.Clear(ctx, byToken[token])
+		for _, domain := range byToken[token] {
+			output.Results = append(output.Results, clearResultJSON{Domain: domain, Cleared: err == nil, Error: errorString(err)})
+			results = append(results, scheduler.Result{Domain: domain, Provider: p.Name(), Err: err})
+			if recErr := store.RecordClear(domain, err); recErr != nil {
+				slog.Warn("状態ファイルへの記録に失敗したます", "path", store.Path(), "error", recErr)
+			}
+			if err != nil {
+				output.OK = false
+			}
+		}
+	}
+	// 終了コードは update と同じく、ドメインごとの結果から決めるます
+	code := exitCodeForResults(results)
+	if jsonOutput() {
+		if writeCode := writeJSONOutput(output); writeCode != exitOK {
+			return writeCode
+		}
+		return code
+	}
+
+	for _, r := range output.Results {
+		if r.Cleared {
+			fmt.Printf("✓ %s.duckdns.org のレコードを消去したます\n", r.Domain)
+		} else {
+			fmt.Printf("✗ %s.duckdns.org のレコードを消去できなかったます: %s\n", r.Domain, r.Error)
+		}
+	}
+	return code
+}
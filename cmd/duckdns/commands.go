@@ -0,0 +1,522 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/selfupdate"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// command は、サブコマンドの定義です。
+type command struct {
+	// name は、コマンド名です
+	name string
+
+	// summary は、ヘルプに表示する1行の説明です
+	summary string
+
+	// run は、コマンドを実行して終了コードを返す関数です
+	run func(args []string) int
+}
+
+// commands は、サブコマンドの一覧です（ヘルプにはこの順で表示するます）。
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "常駐してIPアドレスを定期的にチェックし、DuckDNS を更新 (デフォルト)", runRunCommand},
+		{"update", "IPアドレスのチェックと更新を1回だけ実行して終了", runUpdateCommand},
+		{"clear", "ドメインのレコードを DuckDNS から消去 (ホストの廃止やサービスの停止に)", runClearCommand},
+		{"validate", "設定ファイルを検証 (config validate と同じ)", runConfigValidate},
+		{"doctor", "設定・IP取得ソース・名前解決・トークン・時刻のずれを診断", runDoctorCommand},
+		{"ip", "グローバルIPアドレスを取得して表示 (DuckDNS は更新しない)", runIPCommand},
+		{"test-sources", "すべてのIP取得ソースに問い合わせて、応答と一致を確認", runTestSourcesCommand},
+		{"status", "ドメインごとの最新の更新状況を表示", runStatusCommand},
+		{"history", "更新履歴を表示", runHistoryCommand},
+		{"version", "バージョン情報を表示", runVersionCommand},
+		{"self-update", "GitHub のリリースから新しいバージョンをダウンロードして置き換え", runSelfUpdateCommand},
+		{"config", "設定ファイルの作成 (init) と検証 (validate)", runConfigCommand},
+		{"install", "systemd のサービスや launchd のエージェントとしてインストールして起動 (-systemd, -launchd)", runInstallCommand},
+		{"uninstall", "systemd のサービスや launchd のエージェントを停止してアンインストール (-systemd, -launchd)", runUninstallCommand},
+		{"operator", "Kubernetes の DuckDNSRecord を監視して、オブジェクトごとにドメインを更新", runOperatorCommand},
+		{"external-dns", "Kubernetes の external-dns の webhook プロバイダーとして DuckDNS のレコードを管理", runExternalDNSCommand},
+		{"cert-manager", "cert-manager の DNS-01 の webhook ソルバーとして、チャレンジの TXT レコードを DuckDNS に書き込み", runCertManagerCommand},
+		{"docker", "Docker のコンテナのラベルを監視してドメインを更新", runDockerCommand},
+		{"acme", "ACME の DNS-01 チャレンジの TXT レコードを書き込み・消去 (certbot・lego のフック)", runACMECommand},
+	}
+}
+
+// runCLI は、コマンドライン引数からサブコマンドを選んで実行し、終了コードを返すます。
+// コマンドを省略した場合 (引数なし、または "-" で始まる場合) は、
+// 従来どおりのフラグで run として動くますね。
+// グローバルオプションの --output は、コマンドの前後どちらにも書けるます。
+func runCLI(args []string) int {
+	// --output はすべてのコマンドで共通なので、先に取り出しておくます
+	args, format, err := extractOutputFlag(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+	outputFormat = format
+
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelpArg(args[0])) {
+		return runLegacyCommand(args)
+	}
+
+	name := args[0]
+	if isHelpArg(name) {
+		printUsage()
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "不明なコマンドです: %s\n\n", name)
+	printUsage()
+	return exitUsage
+}
+
+// isHelpArg は、ヘルプを表示する引数かどうかを返すます。
+func isHelpArg(arg string) bool {
+	switch arg {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+// parseFlags は、フラグを解析するます。
+// -h の場合は終了コード 0、解析エラーの場合は 2 と false を返すますね。
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// runRunCommand は、"duckdns run" を実行するます。
+func runRunCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	registerRunFlags(fs)
+	fs.BoolVar(&detach, "detach", false, "バックグラウンドに移り、PID ファイルを書いて、ログを log.file に書く")
+	fs.Usage = func() {
+		printRunUsage("run", "常駐してIPアドレスを定期的にチェックし、DuckDNS を更新します。\n"+
+			"  -detach           バックグラウンドに移って常駐します (サービスマネージャーのない環境向け)\n"+
+			"                    ログは log.file (-log-file) に書き、PID は -pid-file (デフォルト: 状態ファイルと\n"+
+			"                    同じディレクトリの duckdns.pid) に書きます")
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	// -detach の場合は、バックグラウンドの子プロセスを起動して終了するます (子プロセスはそのまま常駐するますね)
+	if detach && os.Getenv(detachedEnv) == "" {
+		return runDetached()
+	}
+	return runDaemon()
+}
+
+// runUpdateCommand は、"duckdns update" を実行するます。
+// 1回だけチェックと更新をして、結果を終了コードで返すますね。
+func runUpdateCommand(args []string) int {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	registerRunFlags(fs)
+	fs.BoolVar(&exitIfUnchanged, "exit-unchanged", false, "どのドメインもIPアドレスが前回から変わっていない場合は終了コード 7 で終了する")
+	fs.Usage = func() {
+		printRunUsage("update", "IPアドレスのチェックと更新を1回だけ実行して終了します。\n"+
+			"cron や systemd タイマーから起動する場合に使用します (update.interval は省略できます)。\n"+
+			"すべてのドメインの更新に成功した場合は終了コード 0、失敗した場合は種類ごとの終了コードで終了します。\n"+
+			"  -exit-unchanged    どのドメインもIPアドレスが前回から変わっていない場合は終了コード 7 で終了")
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	runOnce = true
+	return runDaemon()
+}
+
+// runLegacyCommand は、コマンドを省略した従来の呼び出し方を実行するます。
+// run のフラグに加えて、-once (update と同じ) と -version が使えるます。
+func runLegacyCommand(args []string) int {
+	fs := flag.NewFlagSet("duckdns", flag.ContinueOnError)
+	registerRunFlags(fs)
+	showVersion := fs.Bool("version", false, "バージョン情報を表示")
+	fs.BoolVar(&runOnce, "once", false, "IPアドレスのチェックと更新を1回だけ実行して終了 (update と同じ)")
+	fs.Usage = printUsage
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *showVersion {
+		printVersion()
+		return exitOK
+	}
+	return runDaemon()
+}
+
+// runVersionCommand は、"duckdns version" を実行するます。
+func runVersionCommand(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s version\n\nバージョン情報を表示します。\n", os.Args[0])
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	printVersion()
+	return exitOK
+}
+
+// runStatusCommand は、"duckdns status" を実行するます。
+// 状態ファイルから、ドメインごとの最新の更新状況を表示するますね。
+func runStatusCommand(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (state.backend を読み込む)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.StringVar(&stateFile, "state-file", "", "状態ファイルのパス (環境変数: DUCKDNS_STATE_FILE)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s status [オプション]\n\nドメインごとの最新の更新状況を表示します。\n\nオプション:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	store, err := openStatusStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "状態の保存先を準備できないます: %v\n", err)
+		return exitConfig
+	}
+	st, err := store.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "状態ファイルを読み込めないます: %v\n", err)
+		return exitFailure
+	}
+
+	if jsonOutput() {
+		return writeJSONOutput(struct {
+			StateFile string                `json:"state_file"`
+			Domains   []*state.DomainStatus `json:"domains"`
+			Sources   []*state.SourceStatus `json:"sources,omitempty"`
+			Release   *state.ReleaseStatus  `json:"release,omitempty"`
+		}{store.Path(), st.SortedDomains(), st.SortedSources(), st.Release})
+	}
+
+	fmt.Printf("状態ファイル: %s\n", store.Path())
+	if len(st.Domains) == 0 {
+		fmt.Println("まだ記録がないます (run または update を実行すると記録されるます)")
+		printReleaseStatus(st.Release)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tIP\tPENDING\tLAST UPDATE\tLAST IP CHANGE\tLAST SUCCESS\tLAST CHECK\tFAILURES\tLAST ERROR")
+	for _, d := range st.SortedDomains() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			d.Domain,
+			firstNonEmpty(d.IP, "-"),
+			firstNonEmpty(d.PendingIP, "-"),
+			formatTime(d.LastUpdate),
+			formatTime(d.LastIPChange),
+			formatTime(d.LastSuccess),
+			formatTime(d.LastCheck),
+			d.ConsecutiveFailures,
+			firstNonEmpty(singleLine(d.LastError), "-"),
+		)
+	}
+	w.Flush()
+
+	printSourceStatus(st.SortedSources())
+	printReleaseStatus(st.Release)
+	return exitOK
+}
+
+// printSourceStatus は、続けて失敗している IP取得ソースと、しばらく使わないようにしているソースを表示するます。
+// 失敗しているソースがなければ、何も表示しないますね。
+func printSourceStatus(sources []*state.SourceStatus) {
+	if len(sources) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("失敗している IP取得ソース:")
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tFAILURES\tBLACKLISTED UNTIL\tLAST ERROR")
+	for _, source := range sources {
+		until := "-"
+		if source.Blacklisted(now) {
+			until = formatTime(source.BlacklistedUntil)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n",
+			source.URL,
+			source.ConsecutiveFailures,
+			until,
+			firstNonEmpty(singleLine(source.LastError), "-"),
+		)
+	}
+	w.Flush()
+}
+
+// printReleaseStatus は、release_check で確認した新しいバージョンの有無を表示するます。
+// 確認していなければ、何も表示しないますね。
+func printReleaseStatus(release *state.ReleaseStatus) {
+	if release == nil {
+		return
+	}
+
+	fmt.Println()
+	switch {
+	case release.UpdateAvailable:
+		fmt.Printf("新しいバージョンがあるます: %s (今のバージョン: %s、duckdns self-update で更新できるます)\n", release.Latest, release.Current)
+		if release.URL != "" {
+			fmt.Printf("  %s\n", release.URL)
+		}
+	case release.Latest != "" && !selfupdate.IsRelease(release.Current):
+		fmt.Printf("開発版のビルドです: %s (最新のリリース: %s)\n", release.Current, release.Latest)
+	case release.Latest != "":
+		fmt.Printf("最新のバージョンです: %s (最新のリリース: %s)\n", release.Current, release.Latest)
+	}
+	fmt.Printf("バージョンの確認: %s", formatTime(release.CheckedAt))
+	if release.LastError != "" {
+		fmt.Printf(" (失敗: %s)", singleLine(release.LastError))
+	}
+	fmt.Println()
+}
+
+// runHistoryCommand は、"duckdns history" を実行するます。
+// 状態ファイルから、更新と失敗の履歴を古い順に表示するますね。
+func runHistoryCommand(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (state.backend を読み込む)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.StringVar(&stateFile, "state-file", "", "状態ファイルのパス (環境変数: DUCKDNS_STATE_FILE)")
+	limit := fs.Int("n", 20, "表示する件数 (0 の場合はすべて)")
+	domain := fs.String("domain", "", "指定したドメインの履歴だけを表示")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s history [オプション]\n\n更新と失敗の履歴を表示します (変更がなかったチェックは記録されません)。\n\nオプション:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	store, err := openStatusStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "状態の保存先を準備できないます: %v\n", err)
+		return exitConfig
+	}
+	st, err := store.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "状態ファイルを読み込めないます: %v\n", err)
+		return exitFailure
+	}
+
+	events := []state.Event{}
+	for _, e := range st.History {
+		if *domain == "" || e.Domain == *domain {
+			events = append(events, e)
+		}
+	}
+	if *limit > 0 && len(events) > *limit {
+		events = events[len(events)-*limit:]
+	}
+
+	if jsonOutput() {
+		return writeJSONOutput(struct {
+			StateFile string        `json:"state_file"`
+			Events    []state.Event `json:"events"`
+		}{store.Path(), events})
+	}
+
+	if len(events) == 0 {
+		fmt.Println("履歴がないます")
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tDOMAIN\tRESULT\tIP\tERROR")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			formatTime(e.Time),
+			e.Domain,
+			e.Result,
+			firstNonEmpty(e.IP, "-"),
+			firstNonEmpty(singleLine(e.Error), "-"),
+		)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// formatTime は、時刻をローカル時刻で表示用に整形するます。ゼロ値は "-" にするますね。
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// singleLine は、複数行のエラーメッセージを表に収まるように1行にまとめるます。
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// printUsage は、ヘルプメッセージを表示します
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `DuckDNS 自動更新プログラム
+
+使い方:
+  %s <コマンド> [オプション]
+
+コマンド:
+`, os.Args[0])
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, `
+グローバルオプション:
+  --output <text|json>
+                    結果の出力形式 (update, status, history, doctor, ip, test-sources)
+                    json の場合は、スクリプトや監視から読める JSON を標準出力に書きます
+
+終了コード:
+  0  成功
+  1  そのほかの失敗 (中断など)
+  2  コマンドやオプションの誤り
+  3  設定の読み込みや検証の失敗
+  4  IPアドレスの取得や DuckDNS への接続の失敗
+  5  DuckDNS が更新を拒否 (KO。ドメイン名やトークンの誤り)
+  6  複数ドメインのうち一部だけが失敗
+  7  IPアドレスが前回から変わっていない (update -exit-unchanged のときだけ)
+
+各コマンドのオプションは "%s <コマンド> -h" で表示します。
+コマンドを省略した場合は run として動作します (従来の -once と -version も使えます)。
+
+環境変数:
+  DUCKDNS_DOMAIN    DuckDNS ドメイン名 (必須)
+  DUCKDNS_TOKEN     DuckDNS API トークン (必須)
+  DUCKDNS_INTERVAL  更新チェック間隔 (例: 5m, 1h) デフォルト: 5m
+  DUCKDNS_IP_SOURCES
+                    IP取得ソースのURL (カンマ区切り)
+  DUCKDNS_LOG_LEVEL ログレベル (debug, info, warn, error)
+  DUCKDNS_LOG_FORMAT
+                    ログ形式 (text, json)
+  DUCKDNS_LOG_FILE  ログを追記するファイル
+  DUCKDNS_PROFILE   適用するプロファイル名
+  DUCKDNS_STATE_FILE
+                    更新状況と履歴を保存する状態ファイル
+
+例:
+  # 設定ファイルを使用して起動
+  %s run -config /etc/duckdns/config.yaml
+
+  # 環境変数を使用して起動
+  export DUCKDNS_DOMAIN="your-domain"
+  export DUCKDNS_TOKEN="your-token"
+  %s run
+
+  # 1回だけ更新して終了 (cron 向け)
+  %s update -config /etc/duckdns/config.yaml
+
+  # 問題の切り分け (サポート依頼に結果を添付してください)
+  %s doctor -config /etc/duckdns/config.yaml
+
+  # グローバルIPアドレスだけを表示 (スクリプト向け)
+  %s ip
+  %s ip -6 -json
+
+  # 更新状況と履歴を確認
+  %s status
+  %s history -n 50
+
+  # 結果を JSON で出力 (監視やスクリプト向け)
+  %s update --output json
+
+  # systemd のサービスとしてインストールして起動 (macOS では install -launchd)
+  sudo %s install -systemd -config /etc/duckdns/config.yaml
+
+  # GitHub のリリースから新しいバージョンに更新
+  sudo %s self-update
+
+詳細:
+  https://github.com/horitaku/duckdns
+
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+}
+
+// printRunUsage は、run と update のヘルプメッセージを表示するます。
+func printRunUsage(name, description string) {
+	fmt.Fprintf(os.Stderr, `使い方:
+  %s %s [オプション]
+
+%s
+
+オプション:
+  -config <path>    設定ファイルのパスを指定 (YAML形式)
+                    指定しない場合は次の順に設定ファイルを探索します:
+                      $XDG_CONFIG_HOME/duckdns/config.yaml (~/.config/duckdns/config.yaml)
+                      /etc/duckdns/config.yaml
+                      ./config.yaml
+                    見つからない場合は環境変数のみから設定を読み込みます
+                    http://, https://, s3://bucket/key の URL も指定できます
+                    consul://host:8500/key, etcd://host:2379/key を指定すると
+                    キーの変更を監視して自動で再読み込みします
+
+  -config-sha256 <hex>
+                    リモート設定の SHA-256 チェックサムを検証します
+  -config-pubkey <base64>
+                    リモート設定の Ed25519 署名 (<URL>.sig) を検証します
+  -config-cache-dir <dir>
+                    リモート設定のキャッシュ先 (デフォルト: ~/.cache/duckdns)
+                    取得に失敗した場合は前回のキャッシュで起動します
+
+  -profile <name>   設定ファイルの profiles セクションから適用するプロファイルを指定
+                    指定しない場合は環境変数 DUCKDNS_PROFILE を使用します
+
+  -allow-unknown-keys
+                    設定ファイルの未知のキーを無視します
+                    デフォルトでは "intervall:" のようなタイプミスをエラーにします
+
+  -domain <name>    DuckDNS ドメイン名 (duckdns.domain を上書き)
+  -token <token>    DuckDNS API トークン (duckdns.token を上書き)
+                    ps などで見える可能性があるため、常駐時は環境変数を推奨します
+  -interval <dur>   更新チェック間隔 (update.interval を上書き, 例: 5m, 1h)
+  -ip-sources <urls>
+                    IP取得ソースのURL、カンマ区切り (ip_sources を上書き)
+  -ipv6             IPv6 アドレス (AAAA) も更新する (update.ipv6 を有効にする)
+  -log-level <lvl>  ログレベル: debug, info, warn, error (log.level を上書き)
+  -log-format <fmt> ログ形式: text, json (log.format を上書き)
+  -log-file <path>  ログを追記するファイル (log.file を上書き)
+
+  -check-credentials
+                    起動時にトークンとドメインを1回だけ確認し、拒否されたら終了コード 5 で終了します
+                    登録済みのIPアドレスをそのまま送るため、DNS レコードは変わりません (run のみ)
+
+  -state-file <path>
+                    更新状況と履歴を保存する状態ファイル (status, history で表示)
+                    デフォルト: root の場合は /var/lib/duckdns/state.json、
+                    それ以外は ~/.local/state/duckdns/state.json
+  -pid-file <path>  常駐するときに PID を書き込むファイル (run のみ)
+                    PID ファイルのプロセスが動いている場合は、二重に起動せずに終了します
+
+  設定値の優先度: フラグ > 環境変数 > 設定ファイル > 既定値
+  run の実行中に SIGHUP を送ると設定を再読み込みします (検証に失敗した場合は今の設定を継続)
+
+`, os.Args[0], name, description)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// runConfigCommand は、"duckdns config <サブコマンド>" を実行して終了コードを返すます。
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		printConfigUsage()
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "init":
+		return runConfigInit(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "-h", "-help", "--help", "help":
+		printConfigUsage()
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "不明な config サブコマンドです: %s\n\n", args[0])
+		printConfigUsage()
+		return exitUsage
+	}
+}
+
+// printConfigUsage は、config コマンドのヘルプを表示するます。
+func printConfigUsage() {
+	fmt.Fprintf(os.Stderr, `使い方:
+  %s config <サブコマンド> [オプション]
+
+サブコマンド:
+  init      コメント付きの雛形設定ファイルを作成します
+  validate  設定ファイルを検証し、問題を "ファイル:行:列: キー: 内容" 形式で表示します
+            問題がある場合は終了コード 1 で終了します
+`, os.Args[0])
+}
+
+// runConfigInit は、雛形の設定ファイルを書き出すます。
+// フラグで指定されなかった値は、端末から実行されている場合だけ対話的に聞くますね。
+func runConfigInit(args []string) int {
+	fs := flag.NewFlagSet("config init", flag.ContinueOnError)
+	output := fs.String("o", defaultInitPath(), "書き込み先のパス")
+	domain := fs.String("domain", "", "DuckDNS ドメイン名")
+	token := fs.String("token", "", "DuckDNS API トークン")
+	interval := fs.Duration("interval", config.DefaultInterval, "更新チェック間隔")
+	ipSources := fs.String("ip-sources", "", "IP取得ソースのURL (カンマ区切り)")
+	logLevel := fs.String("log-level", "info", "ログレベル (debug, info, warn, error)")
+	logFormat := fs.String("log-format", "text", "ログ形式 (text, json)")
+	force := fs.Bool("force", false, "既存のファイルを上書きする")
+	nonInteractive := fs.Bool("non-interactive", false, "対話的な入力を行わない")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	// 対話的に入力してもらう前に、上書きにならないか確認しておくます
+	if _, err := os.Stat(*output); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "設定ファイルが既に存在します (上書きするには -force を指定してください): %s\n", *output)
+		return exitFailure
+	}
+
+	opts := config.StarterOptions{
+		Domain:    *domain,
+		Token:     *token,
+		Interval:  *interval,
+		IPSources: splitList(*ipSources),
+		LogLevel:  *logLevel,
+		LogFormat: *logFormat,
+	}
+
+	// 端末から実行されていて、足りない値がある場合は聞くます
+	if !*nonInteractive && isTerminal(os.Stdin) {
+		if err := promptStarterOptions(os.Stdin, os.Stderr, &opts); err != nil {
+			fmt.Fprintf(os.Stderr, "入力の読み込みに失敗したます: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	data, err := config.RenderStarter(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+
+	if err := config.WriteStarter(*output, data, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+
+	fmt.Printf("設定ファイルを作成したます: %s (パーミッション 0600)\n", *output)
+	if opts.Domain == "" || opts.Token == "" {
+		fmt.Println("domain と token はプレースホルダーのままなので、編集してから起動してください")
+	}
+	return exitOK
+}
+
+// runConfigValidate は、設定ファイルを検証して、見つかった問題を位置付きで表示するます。
+// CI で使えるように、問題があれば終了コード 1 を返すますね。
+func runConfigValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	allowUnknown := fs.Bool("allow-unknown-keys", false, "未知のキーをエラーにしない")
+	profileName := fs.String("profile", "", "適用して検証するプロファイル名")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s config validate [オプション] [設定ファイルのパス...]\n\nオプション:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	// パスが指定されない場合は、起動時と同じ標準パスから探すます
+	paths := fs.Args()
+	if len(paths) == 0 {
+		path, ok := config.FindConfigFile(config.DefaultSearchPaths())
+		if !ok {
+			fmt.Fprintln(os.Stderr, "検証する設定ファイルが見つからないます")
+			return exitUsage
+		}
+		paths = []string{path}
+	}
+
+	opts := config.LoadOptions{AllowUnknownKeys: *allowUnknown, Profile: *profileName}
+	failed := false
+	for _, path := range paths {
+		problems := config.ValidateFile(path, opts)
+		if len(problems) == 0 {
+			fmt.Printf("%s: OK\n", path)
+			continue
+		}
+		failed = true
+		for _, problem := range problems {
+			fmt.Println(problem.String())
+		}
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// promptStarterOptions は、未指定のドメイン名・トークン・更新間隔を端末から聞くます。
+// 空のまま Enter を押した場合は、表示された既定値を使うますね。
+func promptStarterOptions(in io.Reader, out io.Writer, opts *config.StarterOptions) error {
+	reader := bufio.NewReader(in)
+
+	ask := func(label, current string) (string, error) {
+		if current != "" {
+			fmt.Fprintf(out, "%s [%s]: ", label, current)
+		} else {
+			fmt.Fprintf(out, "%s: ", label)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if answer := strings.TrimSpace(line); answer != "" {
+			return answer, nil
+		}
+		return current, nil
+	}
+
+	var err error
+	if opts.Domain == "" {
+		if opts.Domain, err = ask("DuckDNS ドメイン名 (.duckdns.org は不要)", ""); err != nil {
+			return err
+		}
+	}
+	if opts.Token == "" {
+		if opts.Token, err = ask("DuckDNS API トークン", ""); err != nil {
+			return err
+		}
+	}
+
+	answer, err := ask("更新チェック間隔", opts.Interval.String())
+	if err != nil {
+		return err
+	}
+	interval, err := time.ParseDuration(answer)
+	if err != nil {
+		return fmt.Errorf("更新間隔の形式が正しくありません (%s): %w", answer, err)
+	}
+	opts.Interval = interval
+
+	return nil
+}
+
+// defaultInitPath は、config init の既定の書き込み先を返すます。
+// root で実行した場合は /etc/duckdns/config.yaml、それ以外はユーザーの設定ディレクトリを使うますね。
+func defaultInitPath() string {
+	if os.Geteuid() == 0 {
+		return filepath.Join("/etc", "duckdns", "config.yaml")
+	}
+	if paths := config.DefaultSearchPaths(); len(paths) > 0 && strings.HasSuffix(paths[0], filepath.Join("duckdns", "config.yaml")) && !strings.HasPrefix(paths[0], "/etc") {
+		return paths[0]
+	}
+	return "config.yaml"
+}
+
+// isTerminal は、ファイルが端末（キャラクターデバイス）かどうかを返すます。
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// splitList は、カンマ区切りの文字列を空要素なしのリストに分割するます。
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
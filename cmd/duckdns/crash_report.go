@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/crashreport"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// crashReportDir は、クラッシュレポートを書き出すディレクトリを決めるます。
+// crash_report.dir を省略したら、状態ファイルと同じディレクトリの crash に書き出すますね。
+func crashReportDir(cfg config.CrashReportConfig) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	return filepath.Join(filepath.Dir(resolveStatePath()), "crash")
+}
+
+// newPanicHandler は、パニックから回復したときにクラッシュレポートを書き出す PanicHandler をつくるます。
+// スタックトレースといっしょに、ドメインごとの最新の状況と秘密の値を伏せた設定も書き出すので、
+// まれにしか起きないパニックでも、あとから原因を調べられるますね。
+func newPanicHandler(cfg *config.Config, store *state.Store) scheduler.PanicHandler {
+	dir := crashReportDir(cfg.CrashReport)
+	return func(r scheduler.PanicReport) {
+		report := crashreport.Report{
+			Time:    r.Time,
+			Version: version,
+			Where:   r.Where,
+			Panic:   r.Value,
+			Domains: r.Domains,
+			Stack:   r.Stack,
+		}
+		if st, err := store.Load(); err == nil {
+			report.Status = st.SortedDomains()
+		}
+		if redacted, err := cfg.Redacted(); err == nil {
+			report.Config = redacted
+		}
+
+		path, err := crashreport.Write(dir, cfg.CrashReport.MaxReports, report)
+		if err != nil {
+			slog.Error("クラッシュレポートを書けなかったます", "dir", dir, "error", err)
+			return
+		}
+		slog.Warn("クラッシュレポートを書いたます", "path", path, "where", r.Where)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/doctor"
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// credentialCheckTimeout は、起動時の認証情報の確認1件あたりのタイムアウトです。
+const credentialCheckTimeout = 15 * time.Second
+
+// checkCredentials は、起動時に1回だけ DuckDNS のトークンとドメインを確認するます (-check-credentials)。
+// 登録済みのIPアドレスをそのまま送るので、DNS レコードは変わらないますね。
+// 拒否されたドメインがあれば、毎回のチェックで失敗し続けないようにすぐ終了するためのエラーを返すます。
+// 登録済みのIPアドレスが分からない場合や、DuckDNS に接続できない場合は、警告だけして起動を続けるます。
+func checkCredentials(ctx context.Context, cfg *config.Config, client *duckdns.Client) error {
+	var rejected []string
+	for _, target := range cfg.Targets() {
+		// DuckDNS 以外のプロバイダーのドメインは対象外ですね
+		if target.Provider != nil {
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, credentialCheckTimeout)
+		result := doctor.CheckToken(checkCtx, client, net.DefaultResolver, target.Domain, target.Token)
+		cancel()
+
+		switch result.Status {
+		case doctor.StatusPass:
+			slog.Info("トークンとドメインを確認したます",
+				"domain", target.Domain,
+				"detail", result.Detail,
+			)
+		case doctor.StatusFail:
+			slog.Error("DuckDNS がトークンかドメインを拒否したます",
+				"domain", target.Domain,
+				"detail", result.Detail,
+			)
+			rejected = append(rejected, target.Domain)
+		default:
+			slog.Warn("トークンとドメインを確認できなかったので、そのまま起動するます",
+				"domain", target.Domain,
+				"detail", result.Detail,
+			)
+		}
+	}
+
+	if len(rejected) > 0 {
+		return fmt.Errorf("%w: トークンまたはドメイン名が正しくありません (%s)", duckdns.ErrRejected, strings.Join(rejected, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// detachedEnv は、-detach でバックグラウンドに移った子プロセスであることを表す環境変数です。
+const detachedEnv = "DUCKDNS_DETACHED"
+
+// runDetached は、この OS では -detach に対応していないことを伝えるます。
+// Windows ではサービスやタスクスケジューラーから起動してくださいね。
+func runDetached() int {
+	fmt.Fprintln(os.Stderr, "-detach はこの OS では使えないます。サービスやタスクスケジューラーから起動してください")
+	return exitUsage
+}
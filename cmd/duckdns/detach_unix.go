@@ -0,0 +1,67 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/horitaku/duckdns/internal/logger"
+	"github.com/horitaku/duckdns/internal/pidfile"
+)
+
+// detachedEnv は、-detach でバックグラウンドに移った子プロセスであることを表す環境変数です。
+const detachedEnv = "DUCKDNS_DETACHED"
+
+// runDetached は、同じ引数で自分自身を新しいセッションの子プロセスとして起動して、すぐに終了するます (run -detach)。
+// 端末から切り離すので、ログは log.file に、PID は PID ファイルに書くますね。
+// 設定の誤りはバックグラウンドに移る前に端末に出すように、先に設定を読み込んで検証するます。
+func runDetached() int {
+	cfg, err := loadConfiguration()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "設定の読み込みに失敗したます: %v\n", err)
+		return exitConfig
+	}
+	if cfg.Log.File == "" {
+		fmt.Fprintln(os.Stderr, "-detach でバックグラウンドに移るときは、ログの出力先を log.file (-log-file, 環境変数 DUCKDNS_LOG_FILE) で指定してください")
+		return exitUsage
+	}
+	pidPath := resolvePIDFile()
+	if pid, ok := pidfile.Running(pidPath); ok {
+		fmt.Fprintf(os.Stderr, "既に起動しています (PID: %d, PID ファイル: %s)\n", pid, pidPath)
+		return exitFailure
+	}
+
+	// 子プロセスの標準出力と標準エラー出力もログファイルに向けて、panic のメッセージも残るようにするます
+	out, err := logger.OpenFile(cfg.Log.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	defer out.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "実行ファイルのパスが分からないます: %v\n", err)
+		return exitFailure
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), detachedEnv+"=1")
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// 新しいセッションにして、端末を閉じても SIGHUP で止まらないようにするます
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "バックグラウンドで起動できなかったます: %v\n", err)
+		return exitFailure
+	}
+
+	fmt.Printf("バックグラウンドで起動したます (PID: %d)\n", cmd.Process.Pid)
+	fmt.Printf("  ログ: %s\n", cfg.Log.File)
+	fmt.Printf("  PID ファイル: %s\n", pidPath)
+	fmt.Printf("  停止するには kill $(cat %s) を実行してくださいね\n", pidPath)
+	_ = cmd.Process.Release()
+	return exitOK
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/summary"
+)
+
+// digestTimeout は、1日ごとのまとめを webhook に知らせるときのタイムアウトです。
+const digestTimeout = 10 * time.Second
+
+// digestPayload は、1日ごとのまとめを webhook に POST する JSON です。
+type digestPayload struct {
+	Event          string            `json:"event"`
+	Message        string            `json:"message"`
+	PeriodStart    time.Time         `json:"period_start"`
+	PeriodEnd      time.Time         `json:"period_end"`
+	UptimeSeconds  int64             `json:"uptime_seconds"`
+	Checks         int               `json:"checks"`
+	Successes      int               `json:"successes"`
+	Failures       int               `json:"failures"`
+	IPChanges      int               `json:"ip_changes"`
+	IPs            map[string]string `json:"ips"`
+	SourceFailures map[string]int    `json:"source_failures"`
+	Version        string            `json:"version"`
+	Time           time.Time         `json:"time"`
+}
+
+// runDigest は、ctx がキャンセルされるまで、1日ごとのまとめを update.digest.webhook に知らせるます。
+// at を指定したら毎日その時刻に、省略したら起動してから24時間ごとに知らせるますね。
+func runDigest(ctx context.Context, cfg *config.Config, collector *summary.Collector) {
+	report := func(s summary.Snapshot) { notifyDigest(ctx, cfg, s) }
+	hour, minute, ok := cfg.Update.Digest.TimeOfDay()
+	if !ok {
+		collector.Run(ctx, 24*time.Hour, report)
+		return
+	}
+	loc, err := cfg.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	collector.RunDaily(ctx, hour, minute, loc, report)
+}
+
+// notifyDigest は、1日ごとのまとめを webhook に POST して、結果をログに出すます。
+func notifyDigest(ctx context.Context, cfg *config.Config, s summary.Snapshot) {
+	payload := digestPayload{
+		Event:          "digest",
+		Message:        "DuckDNS daily digest",
+		PeriodStart:    s.Start,
+		PeriodEnd:      s.End,
+		UptimeSeconds:  int64(s.Uptime / time.Second),
+		Checks:         s.Checks,
+		Successes:      s.Successes,
+		Failures:       s.Failures,
+		IPChanges:      s.IPChanges,
+		IPs:            s.IPs,
+		SourceFailures: s.SourceFailures,
+		Version:        version,
+		Time:           time.Now(),
+	}
+
+	webhook := cfg.Update.Digest.Webhook
+	ctx, cancel := context.WithTimeout(ctx, digestTimeout)
+	defer cancel()
+	if err := postWebhook(ctx, cfg, webhook, payload); err != nil {
+		slog.Error("1日ごとのまとめを webhook に知らせられなかったます",
+			"webhook", config.RedactURL(webhook),
+			"error", err,
+		)
+		return
+	}
+	slog.Info("1日ごとのまとめを webhook に知らせたます",
+		"webhook", config.RedactURL(webhook),
+		"checks", s.Checks,
+		"failures", s.Failures,
+		"ip_changes", s.IPChanges,
+	)
+}
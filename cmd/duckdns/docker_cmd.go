@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/docker"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// runDockerCommand は、"duckdns docker" を実行するます。
+// ローカルの Docker デーモンを監視して、ラベル duckdns.domain が付いたコンテナのドメインを更新するますね。
+// 設定ファイルは、ドメイン以外の共通の設定 (トークンや更新間隔、IP取得ソースなど) に使うます。
+func runDockerCommand(args []string) int {
+	fs := flag.NewFlagSet("docker", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "共通の設定ファイルのパスまたはURL (ドメインの設定は使わない)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagToken, "token", "", "DuckDNS API トークン (ラベル duckdns.token がないコンテナで使う。duckdns.token を上書き)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	fs.StringVar(&flagLogFormat, "log-format", "", "ログ形式 (log.format を上書き)")
+	fs.StringVar(&stateFile, "state-file", "", "更新状況と履歴を保存する状態ファイル (環境変数: DUCKDNS_STATE_FILE)")
+	host := fs.String("host", "", "Docker デーモンのアドレス (環境変数: DOCKER_HOST。省略時は "+docker.DefaultHost+")")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s docker [オプション]\n\n"+
+			"ローカルの Docker デーモンを監視して、ラベル %s が付いた実行中のコンテナのドメインを更新します。\n"+
+			"コンテナが起動・停止するたびに、更新するドメインを入れ替えます。\n"+
+			"ラベル:\n"+
+			"  %-18s 更新するドメイン名 (カンマ区切りで複数)\n"+
+			"  %-18s DuckDNS のトークン (省略時は設定ファイルの duckdns.token)\n"+
+			"  %-18s 更新チェックの間隔 (例: 5m)\n"+
+			"  %-18s IPv6アドレスも更新するかどうか (true または false)\n"+
+			"設定ファイルのドメインとプロバイダーは使わず、トークンや更新間隔などの共通の設定だけを使います。\n\nオプション:\n",
+			os.Args[0], docker.LabelDomain,
+			docker.LabelDomain, docker.LabelToken, docker.LabelInterval, docker.LabelIPv6)
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	dockerHost := firstNonEmpty(*host, os.Getenv("DOCKER_HOST"), docker.DefaultHost)
+	dockerClient, err := docker.NewClient(dockerHost)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if err := initLogger(config.LogConfig{Level: flagLogLevel, Format: flagLogFormat}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	defer closeLogFile()
+
+	base, err := loadSharedConfiguration("docker", "コンテナのラベル")
+	if err != nil {
+		slog.Error("設定の読み込みに失敗したます",
+			"error", err,
+			"config_path", displayConfigPath(configPath),
+		)
+		return exitConfig
+	}
+	if err := initLogger(base.Log, logLocation(base)); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	slog.Info("コンテナのラベルを監視して更新するます",
+		"version", version,
+		"docker_host", dockerHost,
+	)
+
+	store, err := newStateStore(base)
+	if err != nil {
+		slog.Error("状態の保存先を準備できないます", "error", err)
+		return exitConfig
+	}
+	d := &dockerController{
+		base:   base,
+		client: newDuckDNSClient(base),
+		store:  store,
+	}
+	d.run(ctx, &docker.Watcher{Client: dockerClient})
+
+	slog.Info("コンテナの監視を終了するます")
+	return exitOK
+}
+
+// dockerController は、コンテナの一覧からスケジューラーをつくって実行するます。
+type dockerController struct {
+	base   *config.Config
+	client *duckdns.Client
+	store  *state.Store
+}
+
+// run は、ctx がキャンセルされるまでコンテナを監視して、ドメインが変わるたびにスケジューラーをつくり直すます。
+func (d *dockerController) run(ctx context.Context, watcher *docker.Watcher) {
+	// 最新の設定だけを残して、溜まった古い設定は捨てるます
+	updates := make(chan *config.Config, 1)
+	go watcher.Run(ctx, func(containers []docker.Container) {
+		next := d.resolve(containers)
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- next
+	})
+
+	var next *config.Config
+	select {
+	case <-ctx.Done():
+		return
+	case next = <-updates:
+	}
+
+	for {
+		runCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		schedulers := buildSchedulers(next, d.client, d.store, d.store, logLocation(d.base))
+		restorePublished(next, d.store, schedulers)
+
+		slog.Info("スケジューラーを起動するます", "domains", len(next.DuckDNS.Domains))
+		go func() {
+			scheduler.RunAll(runCtx, schedulers)
+			close(done)
+		}()
+
+		select {
+		case <-ctx.Done():
+			next = nil
+		case next = <-updates:
+			slog.Info("コンテナが変わったので、スケジューラーをつくり直すます")
+		}
+		stop()
+		<-done
+		if next == nil {
+			return
+		}
+	}
+}
+
+// resolve は、コンテナの一覧から更新する設定を組み立てるます。
+// ラベルが誤っているコンテナは、エラーのログを出して更新しないますね。
+// 同じドメインのコンテナが複数あるときは、コンテナの名前の順で最初のものだけを使うます。
+func (d *dockerController) resolve(containers []docker.Container) *config.Config {
+	cfg := *d.base
+	cfg.DuckDNS.Domains = nil
+	owners := make(map[string]string, len(containers))
+
+	for _, container := range containers {
+		domains, err := d.resolveContainer(container)
+		if err != nil {
+			slog.Error("コンテナのドメインを更新できないます",
+				"container", container.Name(),
+				"error", err,
+			)
+			continue
+		}
+		for _, domain := range domains {
+			if owner, ok := owners[domain.Name]; ok {
+				slog.Error("同じドメインのコンテナがすでにあるので、更新しないます",
+					"container", container.Name(),
+					"domain", domain.Name,
+					"owner", owner,
+				)
+				continue
+			}
+			owners[domain.Name] = container.Name()
+			cfg.DuckDNS.Domains = append(cfg.DuckDNS.Domains, domain)
+		}
+	}
+	return &cfg
+}
+
+// resolveContainer は、コンテナのラベルをドメインの設定にして、共通の設定と合わせて検証するます。
+func (d *dockerController) resolveContainer(container docker.Container) ([]config.DomainConfig, error) {
+	spec, err := docker.ParseLabels(container.Labels)
+	if err != nil {
+		return nil, err
+	}
+	token := firstNonEmpty(spec.Token, d.base.DuckDNS.Token)
+	if token == "" {
+		return nil, fmt.Errorf("トークンがありません (ラベル %s または設定ファイルの duckdns.token を指定してください)", docker.LabelToken)
+	}
+
+	domains := make([]config.DomainConfig, 0, len(spec.Domains))
+	for _, raw := range spec.Domains {
+		name, err := duckdns.NormalizeDomain(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ラベル %s の%w", docker.LabelDomain, err)
+		}
+		domains = append(domains, config.DomainConfig{
+			Name:     name,
+			Token:    token,
+			Interval: spec.Interval,
+			IPv6:     spec.IPv6,
+		})
+	}
+
+	// ほかのコンテナの誤りに巻き込まれないように、コンテナごとに検証するます
+	check := *d.base
+	check.DuckDNS.Domains = domains
+	if err := check.Validate(); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
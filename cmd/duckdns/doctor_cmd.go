@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/doctor"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/logger"
+)
+
+// runDoctorCommand は、"duckdns doctor" を実行するます。
+// 設定・IP取得ソース・名前解決・トークン・時刻のずれを確認して、結果の一覧を表示するますね。
+// 失敗した項目があれば終了コード 1 を返すます。
+func runDoctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	registerRunFlags(fs)
+	timeout := fs.Duration("timeout", 10*time.Second, "チェックごとのタイムアウト")
+	noColor := fs.Bool("no-color", false, "結果を色付けしない (環境変数 NO_COLOR でも無効化できます)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `使い方:
+  %s doctor [オプション]
+
+設定の検証、IP取得ソースへの接続と応答時間、%s の名前解決、
+トークンの有効性 (登録済みのIPアドレスで更新するため DNS レコードは変わりません)、
+時刻のずれを確認し、結果を表示します。失敗した項目がある場合は終了コード 1 で終了します。
+
+設定の読み込みには run と同じオプションが使えます (-config, -profile, -domain など)。
+
+オプション:
+`, os.Args[0], doctor.DuckDNSHost)
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	// 更新処理のログでレポートが読みにくくならないように、エラーだけ出すます
+	_ = logger.InitLogger("error", "text")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	var results []doctor.Result
+	check := func(fn func(ctx context.Context) doctor.Result) {
+		checkCtx, cancel := context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		results = append(results, fn(checkCtx))
+	}
+
+	// 1. 設定
+	cfg, err := loadConfiguration()
+	if err != nil {
+		results = append(results, doctor.Result{Name: "設定", Status: doctor.StatusFail, Detail: err.Error()})
+	} else {
+		results = append(results, doctor.Result{
+			Name:   "設定",
+			Status: doctor.StatusPass,
+			Detail: fmt.Sprintf("%s (ドメイン %d 件)", firstNonEmpty(displayConfigPath(configPath), "環境変数"), len(cfg.Targets())),
+		})
+	}
+
+	// 2. DuckDNS の名前解決と時刻のずれ
+	check(func(ctx context.Context) doctor.Result {
+		return doctor.CheckDNS(ctx, net.DefaultResolver, doctor.DuckDNSHost)
+	})
+	check(func(ctx context.Context) doctor.Result {
+		return doctor.CheckClockSkew(ctx, &http.Client{Timeout: *timeout}, "https://"+doctor.DuckDNSHost+"/", doctor.DefaultMaxClockSkew)
+	})
+
+	if cfg != nil {
+		// 3. IP取得ソース（ドメインごとのソースも重複なくまとめて確認するます）
+		results = append(results, doctor.CheckIPSources(ctx, configuredIPSources(cfg).URLs(), *timeout)...)
+
+		// 4. ドメインごとのトークン（DuckDNS 以外のプロバイダーのドメインは対象外ですね）
+		client := duckdns.NewClient()
+		for _, target := range cfg.Targets() {
+			if target.Provider != nil {
+				continue
+			}
+			check(func(ctx context.Context) doctor.Result {
+				return doctor.CheckToken(ctx, client, net.DefaultResolver, target.Domain, target.Token)
+			})
+		}
+	}
+
+	if jsonOutput() {
+		out := doctorOutput{
+			OK:      !doctor.Failed(results),
+			Version: version,
+			Commit:  commit,
+			OS:      runtime.GOOS,
+			Arch:    runtime.GOARCH,
+			Results: make([]doctorResultJSON, 0, len(results)),
+		}
+		for _, r := range results {
+			out.Results = append(out.Results, doctorResultJSON{
+				Name:      r.Name,
+				Status:    r.Status.String(),
+				Detail:    r.Detail,
+				LatencyMS: durationMillis(r.Latency),
+			})
+		}
+		if code := writeJSONOutput(out); code != 0 {
+			return code
+		}
+	} else {
+		printDoctorReport(results, !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout))
+	}
+
+	if doctor.Failed(results) {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// printDoctorReport は、診断結果を表にして表示するます。
+// color が true の場合は、PASS を緑、WARN を黄、FAIL を赤で表示するますね。
+func printDoctorReport(results []doctor.Result, color bool) {
+	fmt.Printf("DuckDNS 診断レポート (version %s, commit %s, %s/%s)\n\n", version, commit, runtime.GOOS, runtime.GOARCH)
+
+	counts := map[doctor.Status]int{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		counts[r.Status]++
+
+		detail := singleLine(r.Detail)
+		if r.Latency > 0 {
+			detail += fmt.Sprintf(" (%s)", r.Latency.Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", statusLabel(r.Status, color), r.Name, detail)
+	}
+	w.Flush()
+
+	fmt.Printf("\n結果: 成功 %d 件, 警告 %d 件, 失敗 %d 件\n",
+		counts[doctor.StatusPass], counts[doctor.StatusWarn], counts[doctor.StatusFail])
+}
+
+// statusLabel は、結果の種類を "[PASS]" のようなラベルにするます。
+func statusLabel(status doctor.Status, color bool) string {
+	label := "[" + status.String() + "]"
+	if !color {
+		return label
+	}
+
+	code := map[doctor.Status]string{
+		doctor.StatusPass: "32",
+		doctor.StatusWarn: "33",
+		doctor.StatusFail: "31",
+	}[status]
+	return "\x1b[" + code + "m" + label + "\x1b[0m"
+}
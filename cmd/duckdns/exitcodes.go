@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/horitaku/duckdns/internal/provider"
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// 終了コード
+// cron やラッパーが標準エラーを grep しなくても失敗の種類で分岐できるように、種類ごとに分けるます。
+// 値を変えると利用者のスクリプトが壊れるので、追加だけにしてくださいね。
+const (
+	// exitOK は、成功したことを表します
+	exitOK = 0
+
+	// exitFailure は、ほかに分類されない失敗（中断や出力の失敗など）を表します
+	exitFailure = 1
+
+	// exitUsage は、コマンドやフラグの指定の誤りを表します
+	exitUsage = 2
+
+	// exitConfig は、設定の読み込みや検証に失敗したことを表します
+	exitConfig = 3
+
+	// exitNetwork は、IPアドレスの取得や DuckDNS（プロバイダー）への接続に失敗したことを表します
+	exitNetwork = 4
+
+	// exitRejected は、DuckDNS（プロバイダー）が更新を拒否した（"KO" を返した）ことを表します
+	exitRejected = 5
+
+	// exitPartial は、複数ドメインのうち一部だけが失敗したことを表します
+	exitPartial = 6
+
+	// exitUnchanged は、IPアドレスが前回から変わっていないことを表します（update -exit-unchanged のときだけ）
+	exitUnchanged = 7
+)
+
+// exitCodeForError は、更新の失敗の種類に合った終了コードを返すます。
+func exitCodeForError(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitFailure
+	case errors.Is(err, provider.ErrRejected):
+		return exitRejected
+	default:
+		return exitNetwork
+	}
+}
+
+// exitCodeForResults は、update の結果から終了コードを決めるます。
+// 一部のドメインだけ失敗した場合は exitPartial、すべて失敗した場合は失敗の種類の終了コードを返すますね。
+// 失敗の種類が混ざっている場合は、設定の見直しが必要な exitRejected を優先するます。
+func exitCodeForResults(results []scheduler.Result) int {
+	failed := 0
+	code := exitOK
+	for _, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		failed++
+		if c := exitCodeForError(r.Err); code == exitOK || c == exitRejected {
+			code = c
+		}
+	}
+
+	if failed > 0 && failed < len(results) {
+		return exitPartial
+	}
+	return code
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/provider"
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// TestExitCodeForError_Clear は、clear の失敗の種類から、update と同じ終了コードを選ぶことをテストするます。
+func TestExitCodeForError_Clear(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("KO"))
+	}))
+	defer server.Close()
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	var p provider.Clearer = provider.NewDuckDNS(client, "test-token")
+
+	if got := exitCodeForError(p.Clear(context.Background(), []string{"home"})); got != exitRejected {
+		t.Errorf("KO の場合は exitRejected であるべきです: %d", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := exitCodeForError(p.Clear(ctx, []string{"home"})); got != exitFailure {
+		t.Errorf("中断した場合は exitFailure であるべきです: %d", got)
+	}
+}
+
+// TestExitCodeForResults_Partial は、ドメインごとの結果で一部だけ失敗した場合に exitPartial を返すことをテストするます。
+func TestExitCodeForResults_Partial(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []scheduler.Result
+		want    int
+	}{
+		{
+			name:    "すべて成功",
+			results: []scheduler.Result{{Domain: "a"}, {Domain: "b"}},
+			want:    exitOK,
+		},
+		{
+			name:    "一部のドメインだけ失敗",
+			results: []scheduler.Result{{Domain: "a"}, {Domain: "b", Err: provider.ErrRejected}},
+			want:    exitPartial,
+		},
+		{
+			name:    "すべて失敗して拒否が混ざる",
+			results: []scheduler.Result{{Domain: "a", Err: context.DeadlineExceeded}, {Domain: "b", Err: provider.ErrRejected}},
+			want:    exitRejected,
+		},
+		{
+			name:    "すべて接続の失敗",
+			results: []scheduler.Result{{Domain: "a", Err: context.DeadlineExceeded}},
+			want:    exitNetwork,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeForResults(tt.results); got != tt.want {
+				t.Errorf("exitCodeForResults() = %d, %d であるべきです", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/externaldns"
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// defaultWebhookListen は、external-dns の webhook プロバイダーが待ち受けるアドレスの既定値です。
+// external-dns のサイドカーとして動かすので、external-dns の既定 (localhost:8888) に合わせるます。
+const defaultWebhookListen = "127.0.0.1:8888"
+
+// webhookShutdownTimeout は、停止するときに処理中のリクエストを待つ時間です。
+const webhookShutdownTimeout = 10 * time.Second
+
+// runExternalDNSCommand は、"duckdns external-dns" を実行するます。
+// external-dns の webhook プロバイダーとして待ち受けて、設定ファイルの DuckDNS のドメインの A・AAAA・TXT レコードを読み書きするますね。
+func runExternalDNSCommand(args []string) int {
+	fs := flag.NewFlagSet("external-dns", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (duckdns のドメインとトークンを使う)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagDomain, "domain", "", "DuckDNS ドメイン名 (duckdns.domain を上書き)")
+	fs.StringVar(&flagToken, "token", "", "DuckDNS API トークン (duckdns.token を上書き)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	fs.StringVar(&flagLogFormat, "log-format", "", "ログ形式 (log.format を上書き)")
+	listen := fs.String("listen", "", "待ち受けるアドレス (デフォルト: "+defaultWebhookListen+") (環境変数: DUCKDNS_WEBHOOK_LISTEN)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s external-dns [オプション]\n\n"+
+			"Kubernetes の external-dns の webhook プロバイダーとして待ち受け、DuckDNS のドメインのレコードを管理します。\n"+
+			"管理するのは設定ファイルの duckdns のドメイン (domain, domains, accounts) で、A・AAAA・TXT レコードを書き込めます。\n"+
+			"DuckDNS には TXT レコードが1つしかないため、external-dns は --registry=noop で起動してください。\n\nオプション:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if err := initLogger(config.LogConfig{Level: flagLogLevel, Format: flagLogFormat}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	defer closeLogFile()
+
+	cfg, err := readConfiguration()
+	if err == nil {
+		// IPアドレスのチェックはしないので、更新間隔と IP取得ソースは省略できるます
+		if cfg.Update.Interval == 0 {
+			cfg.Update.Interval = config.DefaultInterval
+		}
+		if len(cfg.IPSources) == 0 {
+			cfg.IPSources = config.NewIPSources(config.DefaultIPSources)
+		}
+		if err = cfg.Validate(); err != nil {
+			err = fmt.Errorf("設定の検証に失敗: %w", err)
+		}
+	}
+	if err != nil {
+		slog.Error("設定の読み込みに失敗したます",
+			"error", err,
+			"config_path", displayConfigPath(configPath),
+		)
+		return exitConfig
+	}
+	if err := initLogger(cfg.Log, logLocation(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+
+	tokens := make(map[string]string)
+	for _, target := range cfg.Targets() {
+		if target.Provider == nil {
+			tokens[target.Domain] = target.Token
+		}
+	}
+	if len(tokens) == 0 {
+		slog.Error("管理する DuckDNS のドメインがないます (providers のドメインは管理できないます)")
+		return exitConfig
+	}
+
+	resolver, err := httpclient.NewResolver(cfg.Network.Resolvers)
+	if err != nil {
+		slog.Error("リゾルバーの設定を反映できないます", "error", err)
+		return exitConfig
+	}
+	provider := externaldns.NewProvider(newDuckDNSClient(cfg), tokens, resolver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	addr := firstNonEmpty(*listen, os.Getenv("DUCKDNS_WEBHOOK_LISTEN"), defaultWebhookListen)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           externaldns.NewHandler(provider),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// 停止するときは、処理中のリクエスト (DuckDNS への書き込み) が終わるのを待つます
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, stop := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer stop()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("external-dns の webhook プロバイダーとして待ち受けるます",
+		"version", version,
+		"listen", addr,
+		"domains", provider.DomainFilter().Include,
+	)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("待ち受けられなかったます", "listen", addr, "error", err)
+		return exitFailure
+	}
+	<-stopped
+	slog.Info("external-dns の webhook プロバイダーを終了するます")
+	return exitOK
+}
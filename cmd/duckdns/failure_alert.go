@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// failureAlertTimeout は、続けて失敗したことを webhook に知らせるときのタイムアウトです。
+const failureAlertTimeout = 10 * time.Second
+
+// failureAlertPayload は、続けて失敗したことと、そのあと復旧したことを webhook に POST する JSON です。
+type failureAlertPayload struct {
+	Event    string    `json:"event"`
+	Message  string    `json:"message"`
+	Domain   string    `json:"domain"`
+	Provider string    `json:"provider"`
+	Failures int       `json:"failures,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Version  string    `json:"version"`
+	Time     time.Time `json:"time"`
+}
+
+// failureAlertNotifier は、update.failure_alert.webhook に、続けて失敗したことと復旧したことを知らせる Notifier です。
+// 更新したことや1回だけの失敗は知らせないますね。
+type failureAlertNotifier struct {
+	cfg *config.Config
+}
+
+// Notify は、結果が続けて失敗したこと (Failing) か復旧したこと (Recovered) なら、webhook に POST するます。
+// チェックと更新を止めないように、ゴルーチンで送って、失敗したらログに出すますね。
+func (n failureAlertNotifier) Notify(ctx context.Context, r scheduler.Result) error {
+	payload := failureAlertPayload{
+		Domain:   r.Domain,
+		Provider: r.Provider,
+		Version:  version,
+		Time:     time.Now(),
+	}
+	var failing *scheduler.FailingError
+	switch {
+	case errors.As(r.Failing, &failing):
+		payload.Event = "failing"
+		payload.Message = "DuckDNS updates failing"
+		payload.Failures = failing.Count
+		payload.Error = failing.Err.Error()
+	case r.Recovered:
+		payload.Event = "recovered"
+		payload.Message = "DuckDNS updates recovered"
+	default:
+		return nil
+	}
+
+	webhook := n.cfg.Update.FailureAlert.Webhook
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), failureAlertTimeout)
+		defer cancel()
+		if err := postWebhook(ctx, n.cfg, webhook, payload); err != nil {
+			slog.Error("続けて失敗したことや復旧したことを webhook に知らせられなかったます",
+				"event", payload.Event,
+				"domain", r.Domain,
+				"webhook", config.RedactURL(webhook),
+				"error", err,
+			)
+			return
+		}
+		slog.Info("続けて失敗したことや復旧したことを webhook に知らせたます",
+			"event", payload.Event,
+			"domain", r.Domain,
+			"webhook", config.RedactURL(webhook),
+		)
+	}()
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/gops"
+)
+
+// startGops は、gops コマンドから接続できる診断用のエージェントを、ctx がキャンセルされるまで動かすます。
+// 離れた機器でも、作り直さずにゴルーチンや GC の統計、メモリの使用状況を確認できるますね。
+// 起動できなかった場合もエラーのログを出すだけで、定期チェックは続けるます。
+func startGops(ctx context.Context, cfg config.GopsConfig) {
+	agent, err := gops.Listen(cfg.Listen, "")
+	if err != nil {
+		slog.Error("gops のエージェントを起動できなかったます (定期チェックは続けるます)", "listen", cfg.Listen, "error", err)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		agent.Close()
+	}()
+
+	slog.Info("gops のエージェントを起動したます",
+		"listen", agent.Addr().String(),
+	)
+	if err := agent.Serve(); err != nil {
+		slog.Error("gops のエージェントが止まったます (定期チェックは続けるます)", "error", err)
+	}
+}
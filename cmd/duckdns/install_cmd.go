@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/launchd"
+	"github.com/horitaku/duckdns/internal/systemd"
+)
+
+// serviceCommandTimeout は、install と uninstall で systemctl や launchctl を実行するときのタイムアウトです。
+const serviceCommandTimeout = time.Minute
+
+// defaultSystemConfigPath は、install で設定ファイルが見つからない場合に使うパスです。
+const defaultSystemConfigPath = "/etc/duckdns/config.yaml"
+
+// installOptions は、install と uninstall のフラグの値です。
+type installOptions struct {
+	configPath string
+	envFile    string
+	name       string
+	unitDir    string
+	force      bool
+	noStart    bool
+	printOnly  bool
+	daemon     bool
+}
+
+// runInstallCommand は、"duckdns install -systemd" または "duckdns install -launchd" を実行するます。
+// 今の実行ファイルと設定ファイルを指すユニットファイル (plist) を書き込んで、有効にして起動するますね。
+func runInstallCommand(args []string) int {
+	var opts installOptions
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	useSystemd := fs.Bool("systemd", false, "systemd のサービスとしてインストールする")
+	useLaunchd := fs.Bool("launchd", false, "macOS の launchd のエージェント (-daemon の場合はデーモン) としてインストールする")
+	fs.StringVar(&opts.configPath, "config", "", "サービスが読み込む設定ファイルのパスまたは URL")
+	fs.StringVar(&opts.envFile, "env-file", systemd.DefaultEnvironmentFile, "トークンなどを環境変数で渡すファイル (systemd のみ)")
+	fs.StringVar(&opts.name, "name", "", "サービスの名前 (launchd の場合はラベル)")
+	fs.StringVar(&opts.unitDir, "unit-dir", "", "ユニットファイル (plist) を書き込むディレクトリ")
+	fs.BoolVar(&opts.force, "force", false, "既存のユニットファイル (plist) を上書きする")
+	fs.BoolVar(&opts.noStart, "no-start", false, "有効にするだけで、すぐには起動しない (systemd のみ)")
+	fs.BoolVar(&opts.printOnly, "print", false, "ユニットファイル (plist) を標準出力に書くだけで、インストールしない")
+	fs.BoolVar(&opts.daemon, "daemon", false, "ログインしていなくても動くシステム全体のデーモンにする (launchd のみ)")
+	fs.Usage = printInstallUsage
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	switch {
+	case *useSystemd && !*useLaunchd:
+		return installSystemd(opts)
+	case *useLaunchd && !*useSystemd:
+		return installLaunchd(opts)
+	}
+	fmt.Fprintln(os.Stderr, "インストール先を -systemd か -launchd のどちらか1つで指定してください")
+	printInstallUsage()
+	return exitUsage
+}
+
+// runUninstallCommand は、"duckdns uninstall -systemd" または "duckdns uninstall -launchd" を実行するます。
+// サービスを止めて無効にして、ユニットファイル (plist) を削除するます。設定ファイルや状態ファイルは残すますね。
+func runUninstallCommand(args []string) int {
+	var opts installOptions
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	useSystemd := fs.Bool("systemd", false, "systemd のサービスをアンインストールする")
+	useLaunchd := fs.Bool("launchd", false, "macOS の launchd のエージェント (-daemon の場合はデーモン) をアンインストールする")
+	fs.StringVar(&opts.name, "name", "", "サービスの名前 (launchd の場合はラベル)")
+	fs.StringVar(&opts.unitDir, "unit-dir", "", "ユニットファイル (plist) のあるディレクトリ")
+	fs.BoolVar(&opts.daemon, "daemon", false, "システム全体のデーモンをアンインストールする (launchd のみ)")
+	fs.Usage = printInstallUsage
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	switch {
+	case *useSystemd && !*useLaunchd:
+		return uninstallSystemd(opts)
+	case *useLaunchd && !*useSystemd:
+		return uninstallLaunchd(opts)
+	}
+	fmt.Fprintln(os.Stderr, "アンインストールする対象を -systemd か -launchd のどちらか1つで指定してください")
+	printInstallUsage()
+	return exitUsage
+}
+
+// installSystemd は、systemd のユニットファイルを書き込んで、有効にして起動するます。
+func installSystemd(opts installOptions) int {
+	unitDir := firstNonEmpty(opts.unitDir, systemd.DefaultUnitDir)
+	name := firstNonEmpty(opts.name, systemd.DefaultName)
+
+	binary, cfgPath, err := servicePaths(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	envFile := opts.envFile
+	if envFile != "" {
+		if envFile, err = filepath.Abs(envFile); err != nil {
+			fmt.Fprintf(os.Stderr, "環境変数ファイルのパスが分からないます: %v\n", err)
+			return exitFailure
+		}
+	}
+	unit := systemd.Unit{Binary: binary, ConfigPath: cfgPath, EnvironmentFile: envFile}
+	data, err := unit.Render()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	if opts.printOnly {
+		os.Stdout.Write(data)
+		return exitOK
+	}
+	if unitDir == systemd.DefaultUnitDir && os.Geteuid() != 0 {
+		fmt.Fprintln(os.Stderr, "systemd のサービスをインストールするには root で実行してください (sudo duckdns install -systemd)")
+		return exitFailure
+	}
+
+	unitPath := systemd.Path(unitDir, name)
+	if err := systemd.WriteFile(unitPath, data, opts.force); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("ユニットファイルを作成したます: %s\n", unitPath)
+
+	if unit.EnvironmentFile != "" {
+		created, err := systemd.WriteEnvironmentFile(unit.EnvironmentFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitFailure
+		}
+		if created {
+			fmt.Printf("環境変数ファイルを作成したます: %s (パーミッション 0600)\n", unit.EnvironmentFile)
+		}
+	}
+	warnUnreadableConfig(unit.ConfigPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceCommandTimeout)
+	defer cancel()
+	if err := systemd.Enable(ctx, nil, name, !opts.noStart); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	if opts.noStart {
+		fmt.Printf("%s を有効にしたます (次の起動から動くます)\n", systemd.ServiceName(name))
+	} else {
+		fmt.Printf("%s を有効にして起動したます\n", systemd.ServiceName(name))
+	}
+	fmt.Printf("ログは journalctl -u %s -f で確認できるますね\n", systemd.ServiceName(name))
+	return exitOK
+}
+
+// uninstallSystemd は、systemd のサービスを止めて無効にして、ユニットファイルを削除するます。
+func uninstallSystemd(opts installOptions) int {
+	name := firstNonEmpty(opts.name, systemd.DefaultName)
+	unitPath := systemd.Path(firstNonEmpty(opts.unitDir, systemd.DefaultUnitDir), name)
+	if _, err := os.Stat(unitPath); err != nil {
+		fmt.Fprintf(os.Stderr, "ユニットファイルが見つかりません: %s\n", unitPath)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceCommandTimeout)
+	defer cancel()
+	// すでに止まっていたり無効になっていたりしても、ユニットファイルは削除するます
+	if err := systemd.Disable(ctx, nil, name); err != nil {
+		fmt.Fprintf(os.Stderr, "サービスを無効にできなかったけど、ユニットファイルは削除するます: %v\n", err)
+	}
+	if err := os.Remove(unitPath); err != nil {
+		fmt.Fprintf(os.Stderr, "ユニットファイルを削除できなかったます: %v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("ユニットファイルを削除したます: %s\n", unitPath)
+	if err := systemd.Reload(ctx, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	fmt.Println("設定ファイル、環境変数ファイル、/var/lib/duckdns の状態ファイルは残しているので、いらなければ削除してください")
+	return exitOK
+}
+
+// installLaunchd は、launchd の plist を書き込んで、読み込んで起動するます。
+// -daemon ならシステム全体のデーモン (root で実行)、そうでなければログインしているユーザーのエージェントにするますね。
+func installLaunchd(opts installOptions) int {
+	dir, logPath, err := launchdPaths(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	binary, cfgPath, err := servicePaths(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	plist := launchd.Plist{
+		Label:      opts.name,
+		Binary:     binary,
+		ConfigPath: cfgPath,
+		LogPath:    logPath,
+	}
+	data, err := plist.Render()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	if opts.printOnly {
+		os.Stdout.Write(data)
+		return exitOK
+	}
+	if opts.daemon && os.Geteuid() != 0 {
+		fmt.Fprintln(os.Stderr, "launchd のデーモンをインストールするには root で実行してください (sudo duckdns install -launchd -daemon)")
+		return exitFailure
+	}
+
+	plistPath := launchd.Path(dir, opts.name)
+	if err := launchd.WriteFile(plistPath, data, opts.force); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("plist を作成したます: %s\n", plistPath)
+	if _, err := os.Stat(cfgPath); errors.Is(err, os.ErrNotExist) && !config.IsRemote(cfgPath) {
+		fmt.Printf("警告: 設定ファイルがまだないます: %s (duckdns config init -o %s で作成できるます)\n", cfgPath, cfgPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceCommandTimeout)
+	defer cancel()
+	if err := launchd.Load(ctx, nil, launchd.Domain(opts.daemon, os.Getuid()), opts.name, plistPath); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	fmt.Println("launchd に読み込んで起動したます")
+	fmt.Printf("ログは tail -f %s で確認できるますね\n", logPath)
+	return exitOK
+}
+
+// uninstallLaunchd は、launchd からジョブを取り除いて、plist を削除するます。
+func uninstallLaunchd(opts installOptions) int {
+	dir, _, err := launchdPaths(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	plistPath := launchd.Path(dir, opts.name)
+	if _, err := os.Stat(plistPath); err != nil {
+		fmt.Fprintf(os.Stderr, "plist が見つかりません: %s\n", plistPath)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serviceCommandTimeout)
+	defer cancel()
+	// すでに止まっていても、plist は削除するます
+	if err := launchd.Unload(ctx, nil, launchd.Domain(opts.daemon, os.Getuid()), opts.name); err != nil {
+		fmt.Fprintf(os.Stderr, "launchd から取り除けなかったけど、plist は削除するます: %v\n", err)
+	}
+	if err := os.Remove(plistPath); err != nil {
+		fmt.Fprintf(os.Stderr, "plist を削除できなかったます: %v\n", err)
+		return exitFailure
+	}
+	fmt.Printf("plist を削除したます: %s\n", plistPath)
+	fmt.Println("設定ファイル、ログファイル、状態ファイルは残しているので、いらなければ削除してください")
+	return exitOK
+}
+
+// launchdPaths は、plist を置くディレクトリとログファイルのパスを決めるます。
+// デーモンなら /Library の下、エージェントならホームディレクトリの ~/Library の下ですね。
+func launchdPaths(opts installOptions) (string, string, error) {
+	if opts.daemon {
+		return firstNonEmpty(opts.unitDir, launchd.DaemonDir), launchd.DaemonLogPath, nil
+	}
+	dir, err := launchd.AgentDir()
+	if err != nil {
+		return "", "", fmt.Errorf("ホームディレクトリが分からないます: %w", err)
+	}
+	logPath, err := launchd.AgentLogPath()
+	if err != nil {
+		return "", "", fmt.Errorf("ホームディレクトリが分からないます: %w", err)
+	}
+	return firstNonEmpty(opts.unitDir, dir), logPath, nil
+}
+
+// servicePaths は、サービスから起動する実行ファイルと、読み込む設定ファイルのパスを決めるます。
+// 設定ファイルを指定しなければ、標準パスから探して、見つからなければ /etc/duckdns/config.yaml にするますね。
+func servicePaths(cfgPath string) (string, string, error) {
+	binary, err := os.Executable()
+	if err != nil {
+		return "", "", fmt.Errorf("実行ファイルのパスが分からないます: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(binary); err == nil {
+		binary = resolved
+	}
+
+	if cfgPath == "" {
+		if found, ok := config.FindConfigFile(config.DefaultSearchPaths()); ok {
+			cfgPath = found
+		} else {
+			cfgPath = defaultSystemConfigPath
+		}
+	}
+	if !config.IsRemote(cfgPath) {
+		if cfgPath, err = filepath.Abs(cfgPath); err != nil {
+			return "", "", fmt.Errorf("設定ファイルのパスが分からないます: %w", err)
+		}
+	}
+	return binary, cfgPath, nil
+}
+
+// warnUnreadableConfig は、サービスのユーザーが設定ファイルを読めないときに警告するます。
+// DynamicUser で動くので、root だけが読める設定ファイル (config init は 0600 でつくる) は読めないますね。
+func warnUnreadableConfig(path string) {
+	if config.IsRemote(path) {
+		return
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("警告: 設定ファイルがまだないます: %s (duckdns config init -o %s で作成できるます)\n", path, path)
+		return
+	}
+	if err != nil || info.Mode().Perm()&0004 != 0 {
+		return
+	}
+	fmt.Printf("警告: サービスは専用のユーザー (DynamicUser) で動くので、%s を読めないます\n", path)
+	fmt.Printf("  トークンを環境変数ファイルの DUCKDNS_TOKEN に移してから、chmod 644 %s してくださいね\n", path)
+}
+
+// printInstallUsage は、install と uninstall のヘルプメッセージを表示するます。
+func printInstallUsage() {
+	fmt.Fprintf(os.Stderr, `使い方:
+  %s install -systemd [オプション]
+  %s install -launchd [-daemon] [オプション]
+  %s uninstall -systemd [オプション]
+  %s uninstall -launchd [-daemon] [オプション]
+
+install は、今の実行ファイルと設定ファイルを指すサービスの定義を書き込み、有効にして起動します。
+  -systemd    systemd のユニットファイルを書き込みます。サービスは DynamicUser の専用ユーザーで、
+              ProtectSystem=strict などで権限を制限して実行します。root で実行してください。
+  -launchd    macOS の launchd の plist (RunAtLoad, KeepAlive) を書き込みます。
+              ログインしているユーザーのエージェント (~/Library/LaunchAgents) として動きます。
+              -daemon を付けると、ログインしていなくても動くデーモン (/Library/LaunchDaemons) になります
+              (root で実行してください)。ログは ~/Library/Logs/duckdns.log
+              (デーモンの場合は /Library/Logs/duckdns.log) に書き込みます。
+uninstall は、サービスを停止して無効にし、定義のファイルを削除します
+(設定ファイル、環境変数ファイル、状態ファイル、ログファイルは残します)。
+
+オプション:
+  -config <path>    サービスが読み込む設定ファイルのパスまたは URL (install のみ)
+                    指定しない場合は標準パスから探し、見つからない場合は
+                    /etc/duckdns/config.yaml を使います
+  -env-file <path>  トークンなどを環境変数で渡すファイル (install -systemd のみ)
+                    デフォルト: %s (ない場合は 0600 で雛形を作成)
+  -name <name>      サービスの名前 (デフォルト: %s)
+                    launchd の場合はラベル (デフォルト: %s)
+  -unit-dir <dir>   ユニットファイル (plist) のディレクトリ
+                    デフォルト: %s (launchd の場合は上記)
+  -daemon           システム全体のデーモンにする (launchd のみ)
+  -force            既存のユニットファイル (plist) を上書きする (install のみ)
+  -no-start         有効にするだけで、すぐには起動しない (install -systemd のみ)
+  -print            ユニットファイル (plist) を標準出力に書くだけで、インストールしない (install のみ)
+
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], systemd.DefaultEnvironmentFile, systemd.DefaultName, launchd.DefaultLabel, systemd.DefaultUnitDir)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/logger"
+)
+
+// stringListFlag は、繰り返し指定やカンマ区切りで複数の値を受け取るフラグです。
+type stringListFlag []string
+
+func (l *stringListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringListFlag) Set(value string) error {
+	*l = append(*l, splitList(value)...)
+	return nil
+}
+
+// sourceFlags は、ip と test-sources コマンドで共通のフラグです。
+type sourceFlags struct {
+	// ipv6 は、IPv6アドレスを取得するかどうかです
+	ipv6 bool
+
+	// sources は、-source で指定されたIP取得ソースです
+	sources stringListFlag
+
+	// timeout は、ソースごとのタイムアウトです
+	timeout time.Duration
+}
+
+// register は、共通のフラグと ip_sources を読み込むための設定のフラグを登録するます。
+func (f *sourceFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.ipv6, "6", false, "IPv6アドレスを取得する")
+	fs.Bool("4", true, "IPv4アドレスを取得する (デフォルト)")
+	fs.Var(&f.sources, "source", "IP取得ソースのURL (繰り返し指定またはカンマ区切り)")
+	fs.DurationVar(&f.timeout, "timeout", ip.DefaultHTTPTimeout, "ソースごとのタイムアウト")
+	fs.StringVar(&configPath, "config", "", "ip_sources を読み込む設定ファイルのパスまたはURL")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagIPSources, "ip-sources", "", "IP取得ソースのURL、カンマ区切り (ip_sources を上書き)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "取得の経過を標準エラーにログ出力するログレベル (debug, info, warn, error)")
+}
+
+// family は、取得するIPアドレスの種類を返すます。
+func (f *sourceFlags) family() ip.Family {
+	if f.ipv6 {
+		return ip.IPv6
+	}
+	return ip.IPv4
+}
+
+// initQuietLogger は、-log-level を指定したときだけログを標準エラーに出すようにするます。
+// 結果はコマンドが表示するので、ふだんはログを出さないますね。
+func initQuietLogger() error {
+	var logOutput io.Writer = io.Discard
+	if flagLogLevel != "" {
+		logOutput = os.Stderr
+	}
+	return logger.InitLogger(firstNonEmpty(flagLogLevel, "error"), "text", logOutput)
+}
+
+// runIPCommand は、"duckdns ip" を実行するます。
+// DuckDNS には触らずに、IP取得ソースから取得したグローバルIPアドレスだけを表示するますね。
+// スクリプトから使えるように、標準出力にはIPアドレス (または JSON) だけを書くます。
+func runIPCommand(args []string) int {
+	fs := flag.NewFlagSet("ip", flag.ContinueOnError)
+	var sf sourceFlags
+	sf.register(fs)
+	asJSON := fs.Bool("json", false, "IPアドレス・種類・取得元のソースを JSON で出力する (--output json と同じ)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `使い方:
+  %s ip [-6] [-source <url>]... [-json] [オプション]
+
+IP取得ソースからグローバルIPアドレスを取得して表示します。DuckDNS は更新しません。
+上から順にソースを試し、最初に取得できたIPアドレスを表示します。
+
+使用するソースの優先度:
+  -source > -ip-sources > 設定ファイルと環境変数の ip_sources (IPv4 のみ) > 既定のソース
+
+既定のソース:
+  IPv4: %s
+  IPv6: %s
+
+取得できなかった場合は終了コード 4、設定を読み込めなかった場合は 3 で終了します。
+
+オプション:
+`, os.Args[0], strings.Join(ip.DefaultIPv4Sources, ", "), strings.Join(ip.DefaultIPv6Sources, ", "))
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if err := initQuietLogger(); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+
+	family := sf.family()
+	sources, err := resolveIPSources(family, sf.sources, func(cfg *config.Config) config.IPSources {
+		return cfg.IPSources
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfig
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	fetcher := ip.NewMultipleFetcherForFamily(sources.URLs(), family, sf.timeout)
+	fetcher.SourceOptions = sourceOptions(sources)
+	result, err := fetcher.FetchDetailed(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitCodeForError(err)
+	}
+
+	if *asJSON || jsonOutput() {
+		return writeJSONOutput(ipOutput{
+			IP:        result.IP,
+			Family:    family.String(),
+			Source:    result.Source,
+			LatencyMS: durationMillis(result.Latency),
+			Attempts:  result.Attempts,
+		})
+	}
+
+	fmt.Println(result.IP)
+	return exitOK
+}
+
+// resolveIPSources は、ip と test-sources コマンドで使うIP取得ソースを決めるます。
+// 優先度: sources (-source) > -ip-sources > fromConfig で選んだ設定のソース > 既定のソース
+// 設定の ip_sources は IPv4 のソースなので、-6 の場合は既定の IPv6 ソースを使うますね。
+func resolveIPSources(family ip.Family, sources []string, fromConfig func(cfg *config.Config) config.IPSources) (config.IPSources, error) {
+	if len(sources) > 0 {
+		return config.NewIPSources(sources), nil
+	}
+	if flagIPSources != "" {
+		return config.NewIPSources(splitList(flagIPSources)), nil
+	}
+	if family == ip.IPv6 {
+		return config.NewIPSources(family.DefaultSources()), nil
+	}
+
+	// ドメインやトークンは不要なので、検証せずに ip_sources だけ使うます
+	cfg, err := readConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	if configured := fromConfig(cfg); len(configured) > 0 {
+		return configured, nil
+	}
+	return config.NewIPSources(family.DefaultSources()), nil
+}
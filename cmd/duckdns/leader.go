@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/kube"
+	"github.com/horitaku/duckdns/internal/leader"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// newElector は、leader_election の設定からリーダー選出をつくるます。
+// backend が kubernetes の場合は、Pod のサービスアカウントで Lease を読み書きするますね。
+func newElector(cfg config.LeaderElectionConfig) (*leader.Elector, error) {
+	var lock leader.Lock
+	switch cfg.BackendOrDefault() {
+	case config.LeaderElectionKubernetes:
+		kubeCfg, err := kube.InCluster()
+		if err != nil {
+			return nil, err
+		}
+		client, err := kube.NewClient(kubeCfg)
+		if err != nil {
+			return nil, err
+		}
+		namespace := firstNonEmpty(cfg.Namespace, kubeCfg.Namespace)
+		if namespace == "" {
+			return nil, errors.New("Lease の名前空間がわからないます (leader_election.namespace を指定してください)")
+		}
+		lock = leader.NewKubernetesLock(client, namespace, cfg.NameOrDefault())
+	default:
+		lock = leader.NewFileLock(cfg.Path)
+	}
+
+	return &leader.Elector{
+		Lock:          lock,
+		Identity:      firstNonEmpty(cfg.Identity, leader.DefaultIdentity()),
+		LeaseDuration: cfg.LeaseDuration,
+		RetryPeriod:   cfg.RetryPeriod,
+	}, nil
+}
+
+// runAsLeader は、リーダーの間だけスケジューラーを実行するます。
+// リーダーでない間は設定を読み込んだまま待機して、リーダーが止まったらすぐに引き継ぐますね。
+// 停止するときの処理 (update.on_shutdown) は、停止するときにリーダーだった場合だけ実行するます。
+func runAsLeader(ctx context.Context, elector *leader.Elector, cfg *config.Config, client *duckdns.Client, store *state.Store, recorder scheduler.Recorder, reload <-chan struct{}, triggers *triggerTargets) {
+	slog.Info("リーダー選出をするます",
+		"identity", elector.Identity,
+		"backend", cfg.LeaderElection.BackendOrDefault(),
+		"lease", elector.Lock.String(),
+	)
+	elector.Run(ctx, func(leadCtx context.Context) {
+		cfg = runWithReload(leadCtx, cfg, client, store, recorder, reload, triggers)
+		triggers.clear()
+		slog.Info("スケジューラーが停止したます")
+
+		// 待機しているインスタンスが引き継ぐ前に、リースを持ったまま実行するますね
+		if ctx.Err() != nil {
+			runShutdownAction(cfg, client)
+		}
+	})
+}
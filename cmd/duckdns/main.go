@@ -8,16 +8,25 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/horitaku/duckdns/internal/config"
 	"github.com/horitaku/duckdns/internal/duckdns"
-	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/httpclient"
 	"github.com/horitaku/duckdns/internal/logger"
+	"github.com/horitaku/duckdns/internal/netcheck"
+	"github.com/horitaku/duckdns/internal/pidfile"
+	"github.com/horitaku/duckdns/internal/ratelimit"
 	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+	"github.com/horitaku/duckdns/internal/summary"
 )
 
 // バージョン情報（ビルド時に -ldflags で設定される想定）
@@ -25,61 +34,81 @@ var (
 	version = "dev"
 	commit  = "unknown"
 	date    = "unknown"
+
+	// updatePublicKey は、self-update で checksums.txt の署名を検証する Base64 の Ed25519 公開鍵です。
+	// リリースのビルドでは GoReleaser が設定するので、-pubkey を指定しなくても署名を検証するます。
+	// 空のままのビルドでは、-pubkey か -insecure を指定しないと self-update で置き換えないますね。
+	updatePublicKey = ""
 )
 
 // コマンドライン引数
 var (
-	configPath  string
-	showVersion bool
+	configPath       string
+	runOnce          bool
+	exitIfUnchanged  bool
+	allowUnknownKeys bool
+	profile          string
+	stateFile        string
+	checkCreds       bool
+
+	// リモート設定（-config に URL を指定した場合）の検証とキャッシュ
+	configSHA256   string
+	configPubKey   string
+	configCacheDir string
+
+	// 設定値を上書きするフラグ（優先度: フラグ > 環境変数 > 設定ファイル > 既定値）
+	flagDomain    string
+	flagToken     string
+	flagInterval  time.Duration
+	flagIPSources string
+	flagIPv6      bool
+	flagLogLevel  string
+	flagLogFormat string
+	flagLogFile   string
+
+	// バックグラウンドでの実行（run -detach）と PID ファイル
+	detach  bool
+	pidFile string
 )
 
-func init() {
+// registerRunFlags は、run と update コマンドで共通のフラグを登録するます。
+func registerRunFlags(fs *flag.FlagSet) {
 	// -config フラグ: 設定ファイルのパスを指定
-	flag.StringVar(&configPath, "config", "", "設定ファイルのパス (例: config.yaml)")
-
-	// -version フラグ: バージョン情報を表示
-	flag.BoolVar(&showVersion, "version", false, "バージョン情報を表示")
-
-	// ヘルプメッセージのカスタマイズ
-	flag.Usage = printUsage
-}
-
-// printUsage は、ヘルプメッセージを表示します
-func printUsage() {
-	fmt.Fprintf(os.Stderr, `DuckDNS 自動更新プログラム
+	fs.StringVar(&configPath, "config", "", "設定ファイルのパスまたはURL (例: config.yaml, https://..., s3://bucket/key, consul://host:8500/key)")
 
-使い方:
-  %s [オプション]
+	// リモート設定のフラグ: 取得した設定の検証とキャッシュ先を指定
+	fs.StringVar(&configSHA256, "config-sha256", "", "リモート設定の SHA-256 チェックサム (16進数)")
+	fs.StringVar(&configPubKey, "config-pubkey", "", "リモート設定の署名 (<URL>.sig) を検証する Ed25519 公開鍵 (Base64)")
+	fs.StringVar(&configCacheDir, "config-cache-dir", "", "リモート設定のキャッシュディレクトリ")
 
-オプション:
-  -config <path>    設定ファイルのパスを指定 (YAML形式)
-                    指定しない場合は環境変数から設定を読み込みます
+	// -profile フラグ: 適用するプロファイル名を指定
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
 
-  -version          バージョン情報を表示して終了
+	// -allow-unknown-keys フラグ: 設定ファイルの未知のキーを無視する
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
 
-  -h, -help         このヘルプメッセージを表示
+	// 設定値を上書きするフラグ: 設定ファイルなしの一時的な実行にも使えるます
+	fs.StringVar(&flagDomain, "domain", "", "DuckDNS ドメイン名 (duckdns.domain を上書き)")
+	fs.StringVar(&flagToken, "token", "", "DuckDNS API トークン (duckdns.token を上書き)")
+	fs.DurationVar(&flagInterval, "interval", 0, "更新チェック間隔 (update.interval を上書き, 例: 5m)")
+	fs.StringVar(&flagIPSources, "ip-sources", "", "IP取得ソースのURL、カンマ区切り (ip_sources を上書き)")
+	fs.BoolVar(&flagIPv6, "ipv6", false, "IPv6 アドレス (AAAA) も更新する (update.ipv6 を有効にする)")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	fs.StringVar(&flagLogFormat, "log-format", "", "ログ形式 (log.format を上書き)")
+	fs.StringVar(&flagLogFile, "log-file", "", "ログを追記するファイル (log.file を上書き)")
 
-環境変数:
-  DUCKDNS_DOMAIN    DuckDNS ドメイン名 (必須)
-  DUCKDNS_TOKEN     DuckDNS API トークン (必須)
-  DUCKDNS_INTERVAL  更新チェック間隔 (例: 5m, 1h) デフォルト: 5m
+	// -check-credentials フラグ: 起動時にトークンとドメインを確認して、拒否されたらすぐ終了する
+	fs.BoolVar(&checkCreds, "check-credentials", false, "起動時にトークンとドメインを確認し、拒否されたら終了する (DNS レコードは変えません)")
 
-例:
-  # 設定ファイルを使用して起動
-  %s -config /etc/duckdns/config.yaml
+	// -state-file フラグ: 更新状況と履歴を保存する状態ファイル
+	fs.StringVar(&stateFile, "state-file", "", "更新状況と履歴を保存する状態ファイル (環境変数: DUCKDNS_STATE_FILE)")
 
-  # 環境変数を使用して起動
-  export DUCKDNS_DOMAIN="your-domain"
-  export DUCKDNS_TOKEN="your-token"
-  %s
-
-  # バージョン情報を表示
-  %s -version
-
-詳細:
-  https://github.com/horitaku/duckdns
+	// -pid-file フラグ: 常駐するときに PID を書き込むファイル
+	fs.StringVar(&pidFile, "pid-file", "", "常駐するときに PID を書き込むファイル (run のみ)")
+}
 
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
 }
 
 // printVersion は、バージョン情報を表示します
@@ -113,36 +142,27 @@ func setupSignalHandler(cancel context.CancelFunc) {
 	}()
 }
 
-func main() {
-	// コマンドライン引数を解析
-	flag.Parse()
-
-	// -version フラグが指定された場合: バージョン情報を表示して終了
-	if showVersion {
-		printVersion()
-		os.Exit(0)
-	}
-
+// runDaemon は、設定を読み込んでスケジューラーを実行し、終了コードを返すます。
+// runOnce が true の場合は、1回だけ更新して結果を終了コードで返すますね。
+func runDaemon() int {
 	// ========== タスク6.2: ログの初期化 ==========
-	// デフォルトのログレベルはinfoにするます
-	logLevel := "info"
-	logFormat := "text"
-
-	// 環境変数からログレベルを取得するますよー
-	if level := os.Getenv("DUCKDNS_LOG_LEVEL"); level != "" {
-		logLevel = level
-	}
-
-	// 環境変数からログフォーマットを取得するますね
-	if format := os.Getenv("DUCKDNS_LOG_FORMAT"); format != "" {
-		logFormat = format
+	// 設定ファイルを読む前のログのために、フラグと環境変数で仮の初期化をするます
+	// 設定を読み込んだあとで、設定ファイルの log セクションも反映するますね
+	// 環境変数の解析エラーは、このあとの設定読み込みで報告されるます
+	envCfg, err := config.LoadFromEnv()
+	if err != nil {
+		envCfg = &config.Config{}
 	}
+	envCfg.ApplyOverrides(flagOverrides())
+	logLevel, logFormat := resolveLogSettings(envCfg.Log)
+	logPath := envCfg.Log.File
 
 	// ログシステムの初期化
-	if err := logger.InitLogger(logLevel, logFormat); err != nil {
+	if err := initLogger(envCfg.Log, nil); err != nil {
 		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
-		os.Exit(1)
+		return exitFailure
 	}
+	defer closeLogFile()
 
 	// ログを使って起動メッセージを出力するますよ
 	slog.Info("DuckDNS自動更新プログラムを起動するます",
@@ -150,7 +170,7 @@ func main() {
 		"commit", commit,
 		"log_level", logLevel,
 		"log_format", logFormat,
-		"config_path", configPath,
+		"config_path", displayConfigPath(configPath),
 	)
 
 	// ========== タスク6.3: シグナルハンドリング ==========
@@ -173,62 +193,268 @@ func main() {
 	if err != nil {
 		slog.Error("設定の読み込みに失敗したます",
 			"error", err,
-			"config_path", configPath,
+			"config_path", displayConfigPath(configPath),
 		)
-		os.Exit(1)
+		if runOnce && jsonOutput() {
+			writeJSONOutput(updateOutput{Results: []updateResultJSON{}, Error: err.Error()})
+		}
+		return exitConfig
 	}
 
-	slog.Info("設定を読み込みました",
-		"domain", cfg.DuckDNS.Domain,
-		"interval", cfg.Update.Interval.String(),
-		"ip_sources", len(cfg.IPSources),
-	)
+	// 設定ファイルのログ設定を反映するます（フラグ・環境変数が優先済み）
+	// timezone を指定したら、ログの時刻をそのタイムゾーンにするためにつくり直すます
+	if level, format := resolveLogSettings(cfg.Log); level != logLevel || format != logFormat || cfg.Log.File != logPath || cfg.Timezone != "" {
+		if err := initLogger(cfg.Log, logLocation(cfg)); err != nil {
+			fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+			return exitFailure
+		}
+	}
 
-	// ===== IP Fetcher の初期化 =====
-	slog.Info("IP Fetcher を初期化するます")
-	fetcher := ip.NewMultipleFetcher(cfg.IPSources)
-	slog.Info("IP Fetcher が初期化されたます",
-		"sources_count", len(cfg.IPSources),
-	)
+	logLoadedConfiguration(cfg)
+
+	// ===== PID ファイル =====
+	// 常駐するときは、停止や二重起動の確認のために PID ファイルを書くます (-pid-file, -detach)
+	if !runOnce {
+		if path := resolvePIDFile(); path != "" {
+			if err := pidfile.Write(path); err != nil {
+				slog.Error("PID ファイルを書けないので終了するます",
+					"pid_file", path,
+					"error", err,
+				)
+				return exitFailure
+			}
+			defer pidfile.Remove(path)
+			slog.Info("PID ファイルを書いたます", "pid_file", path)
+		}
+	}
 
 	// ===== DuckDNS Client の初期化 =====
+	// クライアントはすべてのドメインで共有するます
 	slog.Info("DuckDNS クライアントを初期化するます")
-	duckDNSClient := duckdns.NewClient()
+	duckDNSClient := newDuckDNSClient(cfg)
 	slog.Info("DuckDNS クライアントが初期化されたます")
 
-	// ===== Scheduler の初期化と実行 =====
-	slog.Info("スケジューラーを初期化するます")
-	sch := scheduler.NewScheduler(
-		cfg.Update.Interval,
-		fetcher,
-		duckDNSClient,
-		cfg.DuckDNS.Domain,
-		cfg.DuckDNS.Token,
-	)
-	slog.Info("スケジューラーが初期化されたます",
-		"interval", cfg.Update.Interval.String(),
+	// ===== 状態ファイル =====
+	// 更新状況と履歴を記録して、status や history コマンドから見られるようにするます
+	// state.backend が redis のときは、ほかのインスタンスと共有する Redis に記録するますね
+	store, err := newStateStore(cfg)
+	if err != nil {
+		slog.Error("状態の保存先を準備できないので終了するます", "error", err)
+		return exitConfig
+	}
+	slog.Info("状態ファイルに更新状況を記録するます",
+		"state_file", store.Path(),
 	)
 
+	// ===== ネットワークの準備を待つ =====
+	// 起動直後でネットワークがまだつながっていないときに、最初のチェックで失敗しないようにするます
+	waitForNetwork(ctx, cfg)
+	if ctx.Err() != nil {
+		slog.Info("ネットワークを待っている間に停止したので終了するます")
+		if runOnce {
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	// ===== ワンショット実行 =====
+	// update コマンド (-once) の場合は、1回だけ更新して結果を終了コードで返すますね
+	if runOnce {
+		return runUpdateOnce(ctx, cfg, duckDNSClient, store)
+	}
+
+	// ===== 認証情報の確認 =====
+	// トークンやドメインが間違っていたら、毎回のチェックで失敗し続ける前にすぐ終了するますね
+	if checkCreds {
+		if err := checkCredentials(ctx, cfg, duckDNSClient); err != nil {
+			slog.Error("認証情報の確認に失敗したので終了するます",
+				"error", err,
+			)
+			return exitRejected
+		}
+	}
+
+	// ===== 設定の再読み込み =====
+	// SIGHUP を受け取ったときと、KV ストアの設定キーが変わったときに再読み込みするます
+	reload := make(chan struct{}, 1)
+	setupReloadHandler(reload)
+	if config.IsWatchable(configPath) {
+		go watchRemoteConfig(ctx, configPath, reload)
+	}
+
+	// ===== 新しいバージョンの確認 =====
+	// release_check.enabled のときだけ、リリースを確認してログと status に出すます (設定の再読み込みでは変わらないますね)
+	if cfg.ReleaseCheck.Enabled {
+		go watchReleases(ctx, cfg, store)
+	}
+
+	// ===== 更新の通知の受け口 =====
+	// trigger.enabled のときだけ、ルーターなどからの通知で定期チェックを待たずに更新するます (設定の再読み込みでは変わらないますね)
+	var triggers *triggerTargets
+	if cfg.Trigger.Enabled {
+		triggers = &triggerTargets{}
+		go serveTrigger(ctx, cfg.Trigger, triggers)
+	}
+
+	// ===== メトリクス =====
+	// metrics.enabled のときだけ、連続して失敗した回数や最後に成功した時刻を Prometheus に公開するます (設定の再読み込みでは変わらないますね)
+	if cfg.Metrics.Enabled {
+		go serveMetrics(ctx, cfg.Metrics, store)
+	}
+
+	// ===== gops =====
+	// gops.enabled のときだけ、gops コマンドでゴルーチンやメモリを調べられるようにするます (設定の再読み込みでは変わらないますね)
+	if cfg.Gops.Enabled {
+		go startGops(ctx, cfg.Gops)
+	}
+
+	// ===== MQTT =====
+	// mqtt.enabled のときだけ、チェックの結果をドメインごとに MQTT ブローカーに送るます (設定の再読み込みでは変わらないますね)
+	// home_assistant.discovery も有効なら、Home Assistant にセンサーとして表示されるます
+	var recorder scheduler.Recorder = store
+	mqttDone := make(chan struct{})
+	if cfg.MQTT.Enabled {
+		pub, err := newMQTTPublisher(cfg, store)
+		if err != nil {
+			slog.Error("MQTT の接続を準備できないので終了するます", "error", err)
+			return exitConfig
+		}
+		recorder = recorders{store, pub}
+		slog.Info("更新状況を MQTT ブローカーに送るます",
+			"broker", cfg.MQTT.Broker,
+			"topic_prefix", pub.TopicPrefix,
+			"home_assistant", cfg.MQTT.HomeAssistant.Discovery,
+		)
+		go func() {
+			pub.Run(ctx)
+			close(mqttDone)
+		}()
+	} else {
+		close(mqttDone)
+	}
+
+	// ===== 動作の要約 =====
+	// log.summary_interval ごとに、チェックの回数や失敗の数、IP アドレスの変化を1行のログにまとめるます (設定の再読み込みでは変わらないますね)
+	if cfg.Log.SummaryInterval > 0 {
+		collector := summary.NewCollector()
+		recorder = withRecorder(recorder, collector)
+		go collector.Run(ctx, cfg.Log.SummaryInterval, summary.Log)
+	}
+
+	// ===== 1日ごとのまとめ =====
+	// update.digest.webhook のときだけ、1日の IP アドレスの変化や失敗の数をまとめて知らせるます (設定の再読み込みでは変わらないますね)
+	if cfg.Update.Digest.Webhook != "" {
+		collector := summary.NewCollector()
+		recorder = withRecorder(recorder, collector)
+		go runDigest(ctx, cfg, collector)
+	}
+
+	// ===== リーダー選出 =====
+	// leader_election.enabled のときは、リースを取得したインスタンスだけが更新するます (設定の再読み込みでは変わらないますね)
+	if cfg.LeaderElection.Enabled {
+		elector, err := newElector(cfg.LeaderElection)
+		if err != nil {
+			slog.Error("リーダー選出を始められないので終了するます", "error", err)
+			return exitConfig
+		}
+		runAsLeader(ctx, elector, cfg, duckDNSClient, store, recorder, reload, triggers)
+		<-mqttDone
+		slog.Info("DuckDNS自動更新プログラムを終了するます")
+		return exitOK
+	}
+
 	// スケジューラーを実行するます
-	// context がキャンセルされるまで実行し続けるますね
-	slog.Info("スケジューラーを起動するます")
-	sch.Run(ctx)
+	// context がキャンセルされるまで、再読み込みのたびにつくり直して実行し続けるますね
+	cfg = runWithReload(ctx, cfg, duckDNSClient, store, recorder, reload, triggers)
 
 	// ctx がキャンセルされたら、ここに制御が戻ります
 	slog.Info("スケジューラーが停止したます")
 
+	// 停止するときの処理 (update.on_shutdown) を実行するます
+	// 再読み込みのときは実行しないので、本当に停止するときだけですね
+	runShutdownAction(cfg, duckDNSClient)
+
+	// MQTT ブローカーに offline を送り終わるまで待つますね
+	<-mqttDone
+
 	// プログラム終了時のメッセージ
 	slog.Info("DuckDNS自動更新プログラムを終了するます")
+	return exitOK
+}
+
+// runUpdateOnce は、すべてのドメインで1回だけチェックと更新をして、終了コードを返すます。
+// 終了コードは失敗の種類で分けるます (exitcodes.go を見てくださいね)。
+// --output json の場合は、ドメインごとの結果 (更新前後のIPアドレス・所要時間・エラー) を JSON で出力するますね。
+func runUpdateOnce(ctx context.Context, cfg *config.Config, client *duckdns.Client, store *state.Store) int {
+	// 更新前のIPアドレスは、前回までに状態ファイルに記録されたものを使うます
+	previous := map[string]string{}
+	if st, err := store.Load(); err == nil {
+		for domain, status := range st.Domains {
+			previous[domain] = status.IP
+		}
+	}
+
+	// 停止を求められても、update.shutdown_grace の間は実行中の更新を中断しないます
+	work, cancel := scheduler.WithGrace(ctx, cfg.Update.ShutdownGrace)
+	defer cancel()
+	results := scheduler.CheckAllOnce(work, buildSchedulers(cfg, client, store, store, logLocation(cfg)))
+
+	out := updateOutput{OK: true, Results: make([]updateResultJSON, 0, len(results))}
+	anyChanged := false
+	for _, r := range results {
+		oldIP := firstNonEmpty(r.OldIP, previous[r.Domain])
+		changed := r.Err == nil && r.NewIP != oldIP
+		anyChanged = anyChanged || changed
+		if r.Err != nil {
+			out.OK = false
+			slog.Error("更新に失敗したドメインがあるます",
+				"domain", r.Domain,
+				"error", r.Err,
+			)
+		}
+		out.Results = append(out.Results, updateResultJSON{
+			Domain:     r.Domain,
+			Provider:   r.Provider,
+			OldIP:      oldIP,
+			NewIP:      r.NewIP,
+			Source:     r.Fetch.Source,
+			Changed:    changed,
+			Updated:    r.Updated,
+			Offline:    r.Offline,
+			IPv6:       newIPv6ResultJSON(r),
+			DurationMS: durationMillis(r.Duration),
+			Error:      errorString(r.Err),
+		})
+	}
+
+	if jsonOutput() {
+		if code := writeJSONOutput(out); code != exitOK {
+			return code
+		}
+	}
+	if code := exitCodeForResults(results); code != exitOK {
+		return code
+	}
+	slog.Info("すべてのドメインの更新が完了したます")
+
+	// -exit-unchanged の場合は、どのドメインもIPアドレスが変わっていなければ区別できる終了コードにするます
+	if exitIfUnchanged && !anyChanged {
+		return exitUnchanged
+	}
+	return exitOK
 }
 
 // loadConfiguration は、設定ファイルまたは環境変数から設定を読み込むます。
 // 優先度: 環境変数 > 設定ファイル
 func loadConfiguration() (*config.Config, error) {
-	// Load関数で統一的に設定を読み込む
-	// configPath が空文字列の場合は環境変数のみから読み込む
-	cfg, err := config.Load(configPath)
+	cfg, err := readConfiguration()
 	if err != nil {
-		return nil, fmt.Errorf("設定の読み込みに失敗: %w", err)
+		return nil, err
+	}
+
+	// ワンショット実行では更新間隔を使わないので、未設定なら既定値で補うます
+	if runOnce && cfg.Update.Interval == 0 {
+		cfg.Update.Interval = config.DefaultInterval
 	}
 
 	// バリデーション
@@ -238,3 +464,298 @@ func loadConfiguration() (*config.Config, error) {
 
 	return cfg, nil
 }
+
+// readConfiguration は、設定を読み込んでフラグの上書きまで反映するます（検証はしないます）。
+// ip コマンドのように、ドメインやトークンがなくても使えるコマンドから呼ぶますね。
+func readConfiguration() (*config.Config, error) {
+	// -config が指定されていない場合は標準パスから設定ファイルを探すます
+	if configPath == "" {
+		configPath = discoverConfigPath()
+	}
+
+	// URL が指定された場合は、取得・検証したローカルのキャッシュを読み込むます
+	loadPath := configPath
+	if config.IsRemote(configPath) {
+		path, err := fetchRemoteConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		loadPath = path
+	}
+
+	// Load関数で統一的に設定を読み込む
+	// loadPath が空文字列の場合は環境変数のみから読み込む
+	cfg, err := config.LoadWithOptions(loadPath, config.LoadOptions{
+		AllowUnknownKeys: allowUnknownKeys,
+		Profile:          profile,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("設定の読み込みに失敗: %w", err)
+	}
+
+	// コマンドラインフラグで指定された値で上書きするます（最優先）
+	cfg.ApplyOverrides(flagOverrides())
+
+	return cfg, nil
+}
+
+// fetchRemoteConfig は、リモートの設定ファイルを取得して、読み込むローカルパスを返すます。
+// 取得に失敗してもキャッシュがあれば、警告を出してキャッシュで起動するますね。
+func fetchRemoteConfig(rawURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.DefaultRemoteTimeout)
+	defer cancel()
+
+	file, err := config.FetchRemote(ctx, rawURL, config.RemoteOptions{
+		SHA256:    configSHA256,
+		PublicKey: configPubKey,
+		CacheDir:  configCacheDir,
+	})
+	if err != nil {
+		return "", fmt.Errorf("リモート設定の読み込みに失敗: %w", err)
+	}
+
+	if file.FromCache {
+		slog.Warn("リモート設定の取得に失敗したため、キャッシュを使うます",
+			"config_url", config.RedactURL(rawURL),
+			"cache_path", file.Path,
+			"error", file.FetchError,
+		)
+	} else {
+		slog.Info("リモート設定を取得したます",
+			"config_url", config.RedactURL(rawURL),
+			"cache_path", file.Path,
+		)
+	}
+	return file.Path, nil
+}
+
+// displayConfigPath は、ログに出す設定のパスを返すます。
+// URL の場合は、クエリ文字列や認証情報を取り除くますよ。
+func displayConfigPath(path string) string {
+	if config.IsRemote(path) {
+		return config.RedactURL(path)
+	}
+	return path
+}
+
+// discoverConfigPath は、標準パスから設定ファイルを探して、そのパスを返すます。
+// 見つからない場合は空文字列を返して、環境変数のみで動くようにするますね。
+func discoverConfigPath() string {
+	searchPaths := config.DefaultSearchPaths()
+	path, ok := config.FindConfigFile(searchPaths)
+	if !ok {
+		slog.Info("設定ファイルが見つからないため、環境変数のみから設定を読み込むます",
+			"search_paths", searchPaths,
+		)
+		return ""
+	}
+
+	slog.Info("設定ファイルを検出したます",
+		"config_path", path,
+	)
+	return path
+}
+
+// resolveStatePath は、状態ファイルのパスを決めるます。
+// 優先度: -state-file フラグ > 環境変数 DUCKDNS_STATE_FILE > 既定のパス
+func resolveStatePath() string {
+	return firstNonEmpty(stateFile, os.Getenv("DUCKDNS_STATE_FILE"), state.DefaultPath())
+}
+
+// activeProfile は、適用されたプロファイル名を返すます。
+// -profile フラグが優先で、なければ環境変数 DUCKDNS_PROFILE を見るますね。
+func activeProfile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("DUCKDNS_PROFILE")
+}
+
+// flagOverrides は、コマンドラインフラグで指定された上書き値を返すます。
+func flagOverrides() config.Overrides {
+	return config.Overrides{
+		Domain:    flagDomain,
+		Token:     flagToken,
+		Interval:  flagInterval,
+		IPSources: splitList(flagIPSources),
+		IPv6:      flagIPv6,
+		LogLevel:  flagLogLevel,
+		LogFormat: flagLogFormat,
+		LogFile:   flagLogFile,
+	}
+}
+
+// resolveLogSettings は、ログレベルとログ形式を決めるます。
+// logCfg はフラグ・環境変数・設定ファイルをマージ済みの設定で、空なら既定値 (info / text) を使うます。
+func resolveLogSettings(logCfg config.LogConfig) (string, string) {
+	return firstNonEmpty(logCfg.Level, "info"), firstNonEmpty(logCfg.Format, "text")
+}
+
+// logFile は、log.file で開いているログファイルです（標準エラー出力に書いている場合は nil）。
+var logFile *os.File
+
+// initLogger は、ログ設定でロガーを初期化するます。
+// log.file が指定されていたら、そのファイルに追記するますね。前に開いていたログファイルは閉じるます。
+// loc を渡したら、ログの時刻をそのタイムゾーンで出すます（nil ならホストのローカルタイムですね）。
+func initLogger(logCfg config.LogConfig, loc *time.Location) error {
+	level, format := resolveLogSettings(logCfg)
+	var output io.Writer = os.Stderr
+	var file *os.File
+	if logCfg.File != "" {
+		f, err := logger.OpenFile(logCfg.File)
+		if err != nil {
+			return err
+		}
+		file, output = f, f
+	}
+	if err := logger.InitLoggerInLocation(level, format, loc, output); err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}
+	closeLogFile()
+	logFile = file
+	return nil
+}
+
+// closeLogFile は、開いているログファイルを閉じるます。
+func closeLogFile() {
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
+
+// resolvePIDFile は、PID ファイルのパスを決めるます。
+// -pid-file が優先で、-detach でバックグラウンドに移ったときは状態ファイルと同じディレクトリの duckdns.pid にするますね。
+// どちらでもなければ空文字列を返して、PID ファイルは書かないます。
+func resolvePIDFile() string {
+	if pidFile != "" {
+		return pidFile
+	}
+	if detach {
+		return filepath.Join(filepath.Dir(resolveStatePath()), "duckdns.pid")
+	}
+	return ""
+}
+
+// logLocation は、ログの時刻に使う timezone のタイムゾーンを返すます。
+// UTC しか持たない機器でも、ログの時刻を見慣れたタイムゾーンで読めるようにするますね。
+// time.Local はほかのゴルーチンも読んでいるので書き換えず、ロガーに渡すます。
+// timezone を省略した場合は nil を返して、ホストのローカルタイムのままにするます。
+func logLocation(cfg *config.Config) *time.Location {
+	if cfg.Timezone == "" {
+		return nil
+	}
+	loc, err := cfg.Location()
+	if err != nil {
+		// 検証済みの設定なので通常は起きないますが、念のためホストのローカルタイムのままにするます
+		slog.Warn("タイムゾーンを反映できないます", "timezone", cfg.Timezone, "error", err)
+		return nil
+	}
+	return loc
+}
+
+// waitForNetwork は、update.wait_for_network が設定されている場合に、ネットワークにつながるまで待つます。
+// 上限まで待ってもつながらない場合は、警告を出してそのまま最初のチェックに進むますね。
+func waitForNetwork(ctx context.Context, cfg *config.Config) {
+	if cfg.Update.WaitForNetwork <= 0 {
+		return
+	}
+	prober := netcheck.NewProber(cfg.Network.ProbeURL, cfg.Update.Timeouts.Probe, sharedTransport(cfg))
+	slog.Info("ネットワークの準備ができるまで待つます",
+		"url", prober.URL,
+		"max_wait", cfg.Update.WaitForNetwork.String(),
+	)
+	if err := prober.Wait(ctx, cfg.Update.WaitForNetwork); err != nil && ctx.Err() == nil {
+		slog.Warn("ネットワークにつながったことを確認できなかったので、そのままチェックを始めるます",
+			"max_wait", cfg.Update.WaitForNetwork.String(),
+			"error", err,
+		)
+	}
+}
+
+// newRateLimiter は、DuckDNS API へのリクエスト頻度の制限を作るます。
+// 省略した項目は既定値（1分あたり30回）を使うますね。
+func newRateLimiter(rateCfg config.RateLimitConfig) *ratelimit.Limiter {
+	requests, per := rateCfg.Requests, rateCfg.Per
+	if requests == 0 {
+		requests = duckdns.DefaultRateLimitRequests
+	}
+	if per == 0 {
+		per = duckdns.DefaultRateLimitPer
+	}
+	slog.Info("DuckDNS へのリクエスト頻度を制限するます",
+		"requests", requests,
+		"per", per.String(),
+	)
+	return ratelimit.NewLimiter(requests, per)
+}
+
+// newDuckDNSClient は、設定の通信・リトライ・頻度の制限・サーキットブレーカーを反映した DuckDNS のクライアントをつくるます。
+// update.timeouts.update を指定したら、1回のリクエストもその時間まで待つますね。
+// duckdns.address_family を指定したら、IPv4 か IPv6 のどちらかだけで DuckDNS に接続するます。
+func newDuckDNSClient(cfg *config.Config) *duckdns.Client {
+	timeout := duckdns.DefaultHTTPTimeout
+	if cfg.Update.Timeouts.Update > 0 {
+		timeout = cfg.Update.Timeouts.Update
+	}
+	transport := sharedTransport(cfg)
+	// 設定は検証済みなので、エラーは起きないますね
+	if network, _ := httpclient.FamilyNetwork(cfg.DuckDNS.AddressFamily); network != "" {
+		transport = httpclient.ForceNetwork(transport, network)
+	}
+	return duckdns.NewClient(
+		duckdns.WithTimeout(timeout),
+		duckdns.WithTransport(transport),
+		duckdns.WithMaxResponseSize(cfg.Network.MaxResponseSize),
+		duckdns.WithRetry(newRetryConfig(cfg.Update.Retry)),
+		duckdns.WithRateLimiter(newRateLimiter(cfg.DuckDNS.RateLimit)),
+		duckdns.WithCircuitBreaker(duckdns.NewCircuitBreaker(cfg.DuckDNS.CircuitBreaker.FailureThreshold, cfg.DuckDNS.CircuitBreaker.Cooldown)),
+		duckdns.WithMiddleware(duckdns.LogRequests(slog.Default())),
+	)
+}
+
+// sharedTransport は、network の通信設定（プロキシなど）を反映した、プロセス全体で共有する Transport を返すます。
+// IP取得・DuckDNS・プロバイダー・接続の確認・Webhook がみんなこの Transport を使うので、接続を使い回せるますね。
+// ピン留め（ip_source_pins）はピン留めしたホストにしか効かないので、ほかの通信にもそのまま使えるます。
+// 設定は検証済みなので失敗しないはずですが、失敗したら既定の Transport を使うますね。
+func sharedTransport(cfg *config.Config) *http.Transport {
+	transport, err := httpclient.Shared(cfg.Network.IPSourceTransportOptions())
+	if err != nil {
+		slog.Error("通信設定を反映できないので、既定の設定で通信するます",
+			"error", err,
+		)
+		return http.DefaultTransport.(*http.Transport)
+	}
+	return transport
+}
+
+// newRetryConfig は、update.retry の設定からリトライの設定を作るます。
+// 省略されている場合は、既定のバックオフ（最大3回、1s/2s/4s）のままにするますね。
+func newRetryConfig(retryCfg config.RetryConfig) duckdns.RetryConfig {
+	if retryCfg.IsZero() {
+		return duckdns.RetryConfig{}
+	}
+	return duckdns.RetryConfig{
+		MaxRetries: retryCfg.MaxRetries,
+		Strategy: &duckdns.BackoffStrategy{
+			InitialInterval: retryCfg.InitialInterval,
+			Multiplier:      retryCfg.Multiplier,
+			MaxInterval:     retryCfg.MaxInterval,
+			Jitter:          retryCfg.Jitter,
+			MaxElapsedTime:  retryCfg.MaxElapsedTime,
+		},
+	}
+}
+
+// firstNonEmpty は、最初の空でない文字列を返すます。
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
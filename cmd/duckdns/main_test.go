@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// TestLogLocation は、timezone のタイムゾーンを time.Local を書き換えずに返すことをテストするます。
+func TestLogLocation(t *testing.T) {
+	local := time.Local
+
+	if loc := logLocation(&config.Config{}); loc != nil {
+		t.Errorf("timezone を省略したら nil であるべきです: %v", loc)
+	}
+	loc := logLocation(&config.Config{Timezone: "Asia/Tokyo"})
+	if loc == nil || loc.String() != "Asia/Tokyo" {
+		t.Errorf("timezone のタイムゾーンを返すべきです: %v", loc)
+	}
+	if time.Local != local {
+		t.Error("time.Local は書き換えないべきです")
+	}
+}
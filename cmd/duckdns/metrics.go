@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/metrics"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// buildInfo は、-ldflags で設定したバージョン情報を、メトリクスと GET /api/version で公開する形にするます。
+func buildInfo() metrics.BuildInfo {
+	return metrics.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// serveMetrics は、状態ファイルに記録した更新状況とビルドの情報を Prometheus のメトリクスとして、ctx がキャンセルされるまで公開するます。
+// リクエストのたびに状態を読み込むので、ほかのインスタンスが Redis に記録した状況も見えるますね。
+// 待ち受けられなかった場合もエラーのログを出すだけで、定期チェックは続けるます。
+func serveMetrics(ctx context.Context, cfg config.MetricsConfig, store *state.Store) {
+	addr := cfg.ListenOrDefault()
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           metrics.NewHandler(buildInfo(), store.Load),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, stop := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer stop()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("更新状況をメトリクスとして公開するます",
+		"listen", addr,
+		"path", "/metrics",
+	)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("メトリクスを公開できなかったます (定期チェックは続けるます)", "listen", addr, "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"os"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/httpclient"
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/mqtt"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// recorders は、チェックの結果を複数の scheduler.Recorder に記録する scheduler.Recorder ですね。
+type recorders []scheduler.Recorder
+
+// RecordResult は、チェックの結果をすべての Recorder に順番に記録するます。
+func (rs recorders) RecordResult(domain, ip, source string, updated bool, checkErr error) {
+	for _, r := range rs {
+		r.RecordResult(domain, ip, source, updated, checkErr)
+	}
+}
+
+// RecordSource は、IP取得ソースの状況を、ip.SourceRecorder でもある Recorder に順番に記録するます。
+func (rs recorders) RecordSource(url string, failures int, blacklistedUntil time.Time, sourceErr error) {
+	for _, r := range rs {
+		if sr, ok := r.(ip.SourceRecorder); ok {
+			sr.RecordSource(url, failures, blacklistedUntil, sourceErr)
+		}
+	}
+}
+
+// withRecorder は、Recorder に extra を加えた Recorder を返すます。
+func withRecorder(r scheduler.Recorder, extra scheduler.Recorder) scheduler.Recorder {
+	if rs, ok := r.(recorders); ok {
+		return append(rs[:len(rs):len(rs)], extra)
+	}
+	return recorders{r, extra}
+}
+
+// sourceRecorder は、IP取得ソースの状況を記録する ip.SourceRecorder を返すます。
+// recorder が ip.SourceRecorder なら recorder に (動作の要約にも数えるように)、そうでなければ store に記録するますね。
+func sourceRecorder(recorder scheduler.Recorder, store *state.Store) ip.SourceRecorder {
+	if sr, ok := recorder.(ip.SourceRecorder); ok {
+		return sr
+	}
+	return store
+}
+
+// newMQTTPublisher は、mqtt の設定から、更新状況を MQTT ブローカーに送る Publisher をつくるます。
+// 設定にあるドメインは、前回までに store に記録された IP アドレスと更新した時刻を復元しておくので、
+// 起動して最初のチェックが終わったときから、Home Assistant に前回の更新時刻も表示されるますね。
+func newMQTTPublisher(cfg *config.Config, store *state.Store) (*mqtt.Publisher, error) {
+	m := cfg.MQTT
+	opts := mqtt.Options{
+		Broker:    m.Broker,
+		ClientID:  m.ClientID,
+		Username:  m.Username,
+		Password:  m.Password,
+		KeepAlive: m.KeepAlive,
+	}
+	if opts.ClientID == "" {
+		// 同じブローカーにつなぐほかのインスタンスと重ならないように、ホスト名を付けるます
+		host, _ := os.Hostname()
+		opts.ClientID = "duckdns_" + firstNonEmpty(host, "local")
+	}
+	if _, secure, _ := mqtt.ParseBroker(m.Broker); secure && cfg.Network.CAFile != "" {
+		// network.ca_file の CA 証明書も信頼するます (プライベート CA のブローカー向けですね)
+		pool, err := httpclient.LoadCertPool(cfg.Network.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLS = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	var discovery *mqtt.Discovery
+	if m.HomeAssistant.Discovery {
+		discovery = &mqtt.Discovery{Prefix: m.HomeAssistant.DiscoveryPrefix, Version: version}
+	}
+	pub := mqtt.NewPublisher(opts, m.TopicPrefix, discovery)
+
+	if st, err := store.Load(); err == nil {
+		for _, target := range cfg.Targets() {
+			if d, ok := st.Domains[target.Domain]; ok {
+				pub.Restore(target.Domain, d.IP, d.LastUpdate)
+			}
+		}
+	}
+	return pub, nil
+}
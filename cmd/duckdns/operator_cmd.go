@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/kube"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// operatorAPITimeout は、Secret の読み込みや status の書き込みを1回するときのタイムアウトです。
+const operatorAPITimeout = 10 * time.Second
+
+// runOperatorCommand は、"duckdns operator" を実行するます。
+// Kubernetes の DuckDNSRecord を監視して、オブジェクトごとのドメインを更新するますね。
+// 設定ファイルは、ドメイン以外の共通の設定 (更新間隔や IP取得ソース、通信の設定など) に使うます。
+func runOperatorCommand(args []string) int {
+	fs := flag.NewFlagSet("operator", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", "", "共通の設定ファイルのパスまたはURL (ドメインの設定は使わない)")
+	fs.StringVar(&profile, "profile", "", "適用するプロファイル名 (環境変数: DUCKDNS_PROFILE)")
+	fs.BoolVar(&allowUnknownKeys, "allow-unknown-keys", false, "設定ファイルの未知のキーをエラーにせず無視する")
+	fs.StringVar(&flagLogLevel, "log-level", "", "ログレベル (log.level を上書き)")
+	fs.StringVar(&flagLogFormat, "log-format", "", "ログ形式 (log.format を上書き)")
+	fs.StringVar(&stateFile, "state-file", "", "更新状況と履歴を保存する状態ファイル (環境変数: DUCKDNS_STATE_FILE)")
+	namespace := fs.String("namespace", "", "監視する名前空間 (省略した場合はすべての名前空間)")
+	apiServer := fs.String("api-server", "", "API サーバーの URL (例: http://127.0.0.1:8001。省略した場合は Pod のサービスアカウントを使う)")
+	tokenFile := fs.String("token-file", "", "API サーバーの Bearer トークンのファイル (-api-server を指定した場合)")
+	caFile := fs.String("ca-file", "", "API サーバーの証明書を検証する CA 証明書のファイル (-api-server を指定した場合)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s operator [オプション]\n\n"+
+			"Kubernetes の DuckDNSRecord (%s/%s) を監視して、オブジェクトごとにドメインを更新します。\n"+
+			"トークンは spec.tokenSecretRef の Secret から読み込み、結果は status に書き込みます。\n"+
+			"設定ファイルのドメインとプロバイダーは使わず、更新間隔や IP取得ソースなどの共通の設定だけを使います。\n"+
+			"Secret のトークンを変えた場合は、DuckDNSRecord を編集するか再起動すると反映されます。\n\nオプション:\n",
+			os.Args[0], kube.Group, kube.Version)
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	kubeCfg := kube.Config{Host: *apiServer, TokenFile: *tokenFile, CAFile: *caFile}
+	if *apiServer == "" {
+		var err error
+		if kubeCfg, err = kube.InCluster(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+	kubeClient, err := kube.NewClient(kubeCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if err := initLogger(config.LogConfig{Level: flagLogLevel, Format: flagLogFormat}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	defer closeLogFile()
+
+	base, err := loadOperatorConfiguration()
+	if err != nil {
+		slog.Error("設定の読み込みに失敗したます",
+			"error", err,
+			"config_path", displayConfigPath(configPath),
+		)
+		return exitConfig
+	}
+	if err := initLogger(base.Log, logLocation(base)); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	slog.Info("DuckDNSRecord を監視して更新するます",
+		"version", version,
+		"api_server", kubeCfg.Host,
+		"namespace", firstNonEmpty(*namespace, "(すべて)"),
+	)
+
+	store, err := newStateStore(base)
+	if err != nil {
+		slog.Error("状態の保存先を準備できないます", "error", err)
+		return exitConfig
+	}
+	op := &operator{
+		base:     base,
+		kube:     kubeClient,
+		client:   newDuckDNSClient(base),
+		store:    store,
+		recorder: &statusRecorder{store: store, kube: kubeClient},
+	}
+	op.run(ctx, &kube.Informer{Client: kubeClient, Namespace: *namespace})
+
+	slog.Info("DuckDNSRecord の監視を終了するます")
+	return exitOK
+}
+
+// loadOperatorConfiguration は、operator で使う共通の設定を読み込むます。
+// トークンは DuckDNSRecord の Secret から読み込むので、設定ファイルのトークンも使わないますね。
+func loadOperatorConfiguration() (*config.Config, error) {
+	cfg, err := loadSharedConfiguration("operator", "DuckDNSRecord")
+	if err != nil {
+		return nil, err
+	}
+	cfg.DuckDNS.Token = ""
+	return cfg, nil
+}
+
+// loadSharedConfiguration は、ドメインをほかから決めるコマンドで使う共通の設定を読み込むます。
+// 設定ファイルのドメインとプロバイダーは警告して取り除くますね。
+// 更新間隔と IP取得ソースを省略した場合は、既定値で補うます。
+func loadSharedConfiguration(command, source string) (*config.Config, error) {
+	cfg, err := readConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(cfg.DuckDNS.Domain) != "" || len(cfg.DuckDNS.Domains) > 0 || len(cfg.DuckDNS.Accounts) > 0 || len(cfg.Providers) > 0 {
+		slog.Warn(fmt.Sprintf("%s では設定ファイルのドメインとプロバイダーは使わないます (%s で指定してください)", command, source))
+	}
+	cfg.DuckDNS.Domain = ""
+	cfg.DuckDNS.Domains = nil
+	cfg.DuckDNS.Accounts = nil
+	cfg.Providers = nil
+
+	if cfg.Update.Interval == 0 {
+		cfg.Update.Interval = config.DefaultInterval
+	}
+	if len(cfg.IPSources) == 0 {
+		cfg.IPSources = config.NewIPSources(config.DefaultIPSources)
+	}
+
+	// ドメインとトークンがないのは当然なので、それ以外の誤りだけを報告するます
+	if err := cfg.Validate(); err != nil {
+		var ve *config.ValidationError
+		if !errors.As(err, &ve) {
+			return nil, fmt.Errorf("設定の検証に失敗: %w", err)
+		}
+		rest := &config.ValidationError{}
+		for i, key := range ve.Keys {
+			if key == "duckdns.domain" || key == "duckdns.token" {
+				continue
+			}
+			rest.Keys = append(rest.Keys, key)
+			rest.Errors = append(rest.Errors, ve.Errors[i])
+		}
+		if len(rest.Keys) > 0 {
+			return nil, fmt.Errorf("設定の検証に失敗: %w", rest)
+		}
+	}
+	return cfg, nil
+}
+
+// operator は、DuckDNSRecord の一覧からスケジューラーをつくって実行するます。
+type operator struct {
+	base     *config.Config
+	kube     *kube.Client
+	client   *duckdns.Client
+	store    *state.Store
+	recorder *statusRecorder
+}
+
+// operatorConfig は、DuckDNSRecord の一覧から組み立てた設定と、ドメインごとのオブジェクトです。
+type operatorConfig struct {
+	cfg     *config.Config
+	records map[string]recordRef
+}
+
+// recordRef は、status を書き込む DuckDNSRecord です。
+type recordRef struct {
+	namespace  string
+	name       string
+	generation int64
+}
+
+// run は、ctx がキャンセルされるまで DuckDNSRecord を監視して、一覧が変わるたびにスケジューラーをつくり直すます。
+func (o *operator) run(ctx context.Context, informer *kube.Informer) {
+	// 最新の一覧だけを残して、溜まった古い一覧は捨てるます
+	updates := make(chan *operatorConfig, 1)
+	go informer.Run(ctx, func(records []kube.Record) {
+		next := o.resolve(ctx, records)
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- next
+	})
+
+	var next *operatorConfig
+	select {
+	case <-ctx.Done():
+		return
+	case next = <-updates:
+	}
+
+	for {
+		o.recorder.setRecords(next.records)
+		runCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		schedulers := buildSchedulers(next.cfg, o.client, o.store, o.recorder, logLocation(o.base))
+		restorePublished(next.cfg, o.store, schedulers)
+
+		slog.Info("スケジューラーを起動するます", "records", len(next.records))
+		go func() {
+			scheduler.RunAll(runCtx, schedulers)
+			close(done)
+		}()
+
+		select {
+		case <-ctx.Done():
+			next = nil
+		case next = <-updates:
+			slog.Info("DuckDNSRecord が変わったので、スケジューラーをつくり直すます")
+		}
+		stop()
+		<-done
+		if next == nil {
+			return
+		}
+	}
+}
+
+// resolve は、DuckDNSRecord の一覧から更新する設定を組み立てるます。
+// トークンを読み込めないものや設定が誤っているものは、status にエラーを書いて更新しないますね。
+// 同じドメインのオブジェクトが複数あるときは、"namespace/name" の順で最初のものだけを使うます。
+func (o *operator) resolve(ctx context.Context, records []kube.Record) *operatorConfig {
+	cfg := *o.base
+	cfg.DuckDNS.Domains = nil
+	result := &operatorConfig{cfg: &cfg, records: make(map[string]recordRef, len(records))}
+	owners := make(map[string]string, len(records))
+
+	for _, record := range records {
+		domain, err := o.resolveDomain(ctx, record)
+		if err == nil {
+			if owner, ok := owners[domain.Name]; ok {
+				err = fmt.Errorf("同じドメインの DuckDNSRecord %s がすでにあります", owner)
+			}
+		}
+		if err != nil {
+			slog.Error("DuckDNSRecord を更新できないます",
+				"record", record.Key(),
+				"error", err,
+			)
+			o.writeStatus(ctx, record, kube.RecordStatus{
+				Phase:              kube.PhaseError,
+				Message:            err.Error(),
+				ObservedGeneration: record.Metadata.Generation,
+			})
+			continue
+		}
+
+		owners[domain.Name] = record.Key()
+		cfg.DuckDNS.Domains = append(cfg.DuckDNS.Domains, domain)
+		result.records[domain.Name] = recordRef{
+			namespace:  record.Metadata.Namespace,
+			name:       record.Metadata.Name,
+			generation: record.Metadata.Generation,
+		}
+	}
+	return result
+}
+
+// resolveDomain は、DuckDNSRecord の spec をドメインの設定にして、共通の設定と合わせて検証するます。
+func (o *operator) resolveDomain(ctx context.Context, record kube.Record) (config.DomainConfig, error) {
+	spec := record.Spec
+	name, err := duckdns.NormalizeDomain(spec.Domain)
+	if err != nil {
+		return config.DomainConfig{}, fmt.Errorf("spec.domain の%w", err)
+	}
+	interval, err := spec.IntervalDuration()
+	if err != nil {
+		return config.DomainConfig{}, err
+	}
+	if spec.TokenSecretRef.Name == "" {
+		return config.DomainConfig{}, fmt.Errorf("spec.tokenSecretRef.name が指定されていません")
+	}
+
+	apiCtx, cancel := context.WithTimeout(ctx, operatorAPITimeout)
+	defer cancel()
+	token, err := o.kube.SecretValue(apiCtx, record.Metadata.Namespace, spec.TokenSecretRef.Name, spec.TokenSecretRef.KeyOrDefault())
+	if err != nil {
+		return config.DomainConfig{}, fmt.Errorf("トークンを読み込めません: %w", err)
+	}
+
+	domain := config.DomainConfig{
+		Name:     name,
+		Token:    token,
+		Interval: interval,
+		IPv6:     spec.IPv6,
+	}
+	if len(spec.IPSources) > 0 {
+		domain.IPSources = config.NewIPSources(spec.IPSources)
+	}
+	if len(spec.IPv6Sources) > 0 {
+		domain.IPv6Sources = config.NewIPSources(spec.IPv6Sources)
+	}
+
+	// ほかのオブジェクトの誤りに巻き込まれないように、1つずつ検証するます
+	check := *o.base
+	check.DuckDNS.Domains = []config.DomainConfig{domain}
+	if err := check.Validate(); err != nil {
+		return config.DomainConfig{}, err
+	}
+	return domain, nil
+}
+
+// writeStatus は、DuckDNSRecord の status を書き込むます。失敗したら警告するだけですね。
+func (o *operator) writeStatus(ctx context.Context, record kube.Record, status kube.RecordStatus) {
+	now := time.Now()
+	status.LastCheckTime = &now
+	apiCtx, cancel := context.WithTimeout(ctx, operatorAPITimeout)
+	defer cancel()
+	if err := o.kube.PatchRecordStatus(apiCtx, record.Metadata.Namespace, record.Metadata.Name, status); err != nil {
+		slog.Warn("DuckDNSRecord の status を書き込めなかったます",
+			"record", record.Key(),
+			"error", err,
+		)
+	}
+}
+
+// statusRecorder は、チェックの結果を状態ファイルと DuckDNSRecord の status に記録する scheduler.Recorder ですね。
+type statusRecorder struct {
+	store *state.Store
+	kube  *kube.Client
+
+	mu      sync.Mutex
+	records map[string]recordRef
+}
+
+// setRecords は、ドメインごとの DuckDNSRecord を入れ替えるます。
+func (r *statusRecorder) setRecords(records map[string]recordRef) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = records
+}
+
+// RecordResult は、チェックの結果を状態ファイルに記録して、ドメインの DuckDNSRecord の status に書き込むます。
+func (r *statusRecorder) RecordResult(domain, ip, source string, updated bool, checkErr error) {
+	r.store.RecordResult(domain, ip, source, updated, checkErr)
+
+	r.mu.Lock()
+	ref, ok := r.records[domain]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	status := kube.RecordStatus{
+		Phase:              kube.PhaseReady,
+		IP:                 ip,
+		LastCheckTime:      &now,
+		ObservedGeneration: ref.generation,
+	}
+	if updated {
+		status.LastUpdateTime = &now
+	}
+	if checkErr != nil {
+		status.Phase = kube.PhaseError
+		status.Message = checkErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operatorAPITimeout)
+	defer cancel()
+	if err := r.kube.PatchRecordStatus(ctx, ref.namespace, ref.name, status); err != nil {
+		slog.Warn("DuckDNSRecord の status を書き込めなかったます",
+			"record", ref.namespace+"/"+ref.name,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// 出力形式（--output で指定）
+const (
+	// outputText は、人が読むための表形式の出力です（デフォルト）
+	outputText = "text"
+
+	// outputJSON は、スクリプトや監視から読むための JSON の出力です
+	outputJSON = "json"
+)
+
+// outputFormat は、--output で指定された出力形式です。
+var outputFormat = outputText
+
+// extractOutputFlag は、引数のどこにあっても --output (-output) を取り出して、残りの引数を返すます。
+// コマンドの前後どちらに書いても同じように効くグローバルオプションにするためですね。
+// "--" より後ろは、コマンドの引数としてそのまま残すます。
+func extractOutputFlag(args []string) ([]string, string, error) {
+	format := outputText
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "output" {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("--output には出力形式 (text, json) を指定してください")
+			}
+			i++
+			value = args[i]
+		}
+
+		switch value {
+		case outputText, outputJSON:
+			format = value
+		default:
+			return nil, "", fmt.Errorf("不明な出力形式です: %s (text, json のどちらかを指定してください)", value)
+		}
+	}
+	return rest, format, nil
+}
+
+// jsonOutput は、JSON で出力するかどうかを返すます。
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// printJSON は、v をインデント付きの JSON で標準出力に書くます。
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// writeJSONOutput は、v を JSON で出力して終了コードを返すます。
+func writeJSONOutput(v any) int {
+	if err := printJSON(v); err != nil {
+		fmt.Fprintf(os.Stderr, "JSON の出力に失敗したます: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// durationMillis は、JSON に出力する所要時間をミリ秒で返すます。
+func durationMillis(d time.Duration) int64 {
+	return d.Milliseconds()
+}
+
+// errorString は、JSON に出力するエラーメッセージを返すます。nil の場合は空文字列ですね。
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// ipOutput は、ip コマンドの JSON 出力です。
+type ipOutput struct {
+	IP        string `json:"ip"`
+	Family    string `json:"family"`
+	Source    string `json:"source"`
+	LatencyMS int64  `json:"latency_ms"`
+	Attempts  int    `json:"attempts"`
+}
+
+// updateResultJSON は、update の JSON 出力の1ドメイン分の結果です。
+type updateResultJSON struct {
+	Domain     string `json:"domain"`
+	Provider   string `json:"provider"`
+	OldIP      string `json:"old_ip"`
+	NewIP      string `json:"new_ip"`
+	Source     string `json:"source,omitempty"`
+	Changed    bool   `json:"changed"`
+	Updated    bool   `json:"updated"`
+	Offline    bool   `json:"offline,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+
+	// IPv6 は、IPv6 の結果です (update.ipv6 が無効なら省略するます)
+	IPv6 *ipv6ResultJSON `json:"ipv6,omitempty"`
+}
+
+// ipv6ResultJSON は、update の JSON 出力の IPv6 (AAAA) の結果です。
+type ipv6ResultJSON struct {
+	OldIP   string `json:"old_ip"`
+	NewIP   string `json:"new_ip"`
+	Source  string `json:"source,omitempty"`
+	Updated bool   `json:"updated"`
+	Error   string `json:"error,omitempty"`
+}
+
+// newIPv6ResultJSON は、スケジューラーの結果から IPv6 の結果をつくるます。
+// IPv6 を更新しない場合は nil を返すますね。
+func newIPv6ResultJSON(r scheduler.Result) *ipv6ResultJSON {
+	if r.NewIPv6 == "" && r.OldIPv6 == "" && r.IPv6Err == nil {
+		return nil
+	}
+	return &ipv6ResultJSON{
+		OldIP:   r.OldIPv6,
+		NewIP:   r.NewIPv6,
+		Source:  r.FetchIPv6.Source,
+		Updated: r.UpdatedIPv6,
+		Error:   errorString(r.IPv6Err),
+	}
+}
+
+// updateOutput は、update の JSON 出力です。
+type updateOutput struct {
+	OK      bool               `json:"ok"`
+	Results []updateResultJSON `json:"results"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// doctorResultJSON は、doctor の JSON 出力の1チェック分の結果です。
+type doctorResultJSON struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Detail    string `json:"detail"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// doctorOutput は、doctor の JSON 出力です。
+type doctorOutput struct {
+	OK      bool               `json:"ok"`
+	Version string             `json:"version"`
+	Commit  string             `json:"commit"`
+	OS      string             `json:"os"`
+	Arch    string             `json:"arch"`
+	Results []doctorResultJSON `json:"results"`
+}
+
+// probeResultJSON は、test-sources の JSON 出力の1ソース分の結果です。
+type probeResultJSON struct {
+	URL       string `json:"url"`
+	Status    string `json:"status"`
+	IP        string `json:"ip"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// testSourcesOutput は、test-sources の JSON 出力です。
+type testSourcesOutput struct {
+	OK       bool              `json:"ok"`
+	Family   string            `json:"family"`
+	Majority string            `json:"majority"`
+	Sources  []probeResultJSON `json:"sources"`
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/selfupdate"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// releaseCheckTimeout は、新しいバージョンを1回確認するときのタイムアウトです。
+const releaseCheckTimeout = time.Minute
+
+// watchReleases は、release_check.interval ごとに新しいバージョンのリリースを確認して、ログと状態ファイルに残すます。
+// 確認するだけで、インストールはしないますね (インストールは self-update コマンドです)。
+// 再起動しても間隔どおりになるように、状態ファイルの前回の確認時刻から次の確認までの待ち時間を決めるます。
+func watchReleases(ctx context.Context, cfg *config.Config, store *state.Store) {
+	interval := cfg.ReleaseCheck.IntervalOrDefault()
+	source := selfupdate.Source{
+		Repository: cfg.ReleaseCheck.Repository,
+		APIURL:     os.Getenv("DUCKDNS_UPDATE_API_URL"),
+		Token:      os.Getenv("GITHUB_TOKEN"),
+		HTTPClient: &http.Client{
+			Transport: sharedTransport(cfg),
+			Timeout:   releaseCheckTimeout,
+		},
+		UserAgent: "duckdns-updater/" + version,
+	}
+
+	var delay time.Duration
+	if st, err := store.Load(); err == nil && st.Release != nil && st.Release.Current == version {
+		if next := time.Until(st.Release.CheckedAt.Add(interval)); next > 0 {
+			delay = next
+		}
+	}
+	slog.Info("新しいバージョンを定期的に確認するます",
+		"repo", firstNonEmpty(cfg.ReleaseCheck.Repository, selfupdate.DefaultRepository),
+		"interval", interval,
+		"next_check", time.Now().Add(delay).Format(time.RFC3339),
+	)
+
+	selfupdate.Watch(ctx, source, version, interval, delay, func(release *selfupdate.Release, newer bool, err error) {
+		var latest, url string
+		if release != nil {
+			latest, url = release.Version(), release.URL
+		}
+		switch {
+		case err != nil:
+			slog.Warn("新しいバージョンを確認できなかったます", "error", err)
+		case newer:
+			slog.Warn("新しいバージョンがあるます (duckdns self-update で更新できるます)",
+				"current", version,
+				"latest", latest,
+				"url", url,
+			)
+		default:
+			slog.Info("新しいバージョンはないます", "current", version, "latest", latest)
+		}
+		if recErr := store.RecordRelease(version, latest, url, newer, err); recErr != nil {
+			slog.Warn("状態ファイルへの記録に失敗したます",
+				"path", store.Path(),
+				"error", recErr,
+			)
+		}
+	})
+}
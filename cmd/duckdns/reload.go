@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/httpclient"
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/netcheck"
+	"github.com/horitaku/duckdns/internal/provider"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// setupReloadHandler は、SIGHUP を受け取ったら設定の再読み込みを要求するますね。
+func setupReloadHandler(reload chan<- struct{}) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		for range sigChan {
+			slog.Info("SIGHUP を受け取ったので、設定を再読み込みするます")
+			requestReload(reload)
+		}
+	}()
+}
+
+// watchRemoteConfig は、KV ストアの設定キーを監視して、変わったら再読み込みを要求するます。
+func watchRemoteConfig(ctx context.Context, rawURL string, reload chan<- struct{}) {
+	slog.Info("設定キーの監視を開始するます",
+		"config_url", config.RedactURL(rawURL),
+	)
+
+	err := config.WatchRemote(ctx, rawURL, config.RemoteOptions{}, func() {
+		requestReload(reload)
+	})
+	if err != nil {
+		slog.Error("設定キーの監視を開始できなかったます",
+			"config_url", config.RedactURL(rawURL),
+			"error", err,
+		)
+	}
+}
+
+// requestReload は、再読み込みを要求するます。
+// すでに要求が溜まっている場合は、まとめて1回の再読み込みにするますよ。
+func requestReload(reload chan<- struct{}) {
+	select {
+	case reload <- struct{}{}:
+	default:
+	}
+}
+
+// runWithReload は、ctx がキャンセルされるまでスケジューラーを実行するます。
+// 再読み込みを要求されたら、新しい設定を読み込んで検証し、
+// 成功したときだけスケジューラーをつくり直すます。失敗したら今の設定で動き続けるますね。
+// triggers がある場合は、更新の通知で使うスケジューラーも入れ替えるます。
+// チェックの結果は recorder に記録するます (mqtt を使わないときは store そのものですね)。
+// 停止したときは、最後に使っていた設定を返すます。
+func runWithReload(ctx context.Context, cfg *config.Config, client *duckdns.Client, store *state.Store, recorder scheduler.Recorder, reload <-chan struct{}, triggers *triggerTargets) *config.Config {
+	loc := logLocation(cfg)
+	for {
+		runCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		schedulers := buildSchedulers(cfg, client, store, recorder, loc)
+		restorePublished(cfg, store, schedulers)
+		triggers.set(schedulers)
+
+		slog.Info("スケジューラーを起動するます")
+		go func() {
+			scheduler.RunAll(runCtx, schedulers)
+			close(done)
+		}()
+
+		next := waitForReload(ctx, done, reload)
+		stop()
+		<-done
+
+		if next == nil {
+			return cfg
+		}
+		// タイムゾーンは1日ごとのまとめの時刻や、定期チェックの時間帯にも使うので、起動時にだけ反映するます
+		if next.Timezone != cfg.Timezone {
+			slog.Warn("timezone の変更は再起動するまで反映されないます",
+				"current", cfg.Timezone,
+				"next", next.Timezone,
+			)
+		}
+		cfg = next
+
+		// 新しいログ設定を反映するます（失敗したら今のロガーのまま）
+		if err := initLogger(cfg.Log, loc); err != nil {
+			slog.Warn("ログ設定の反映に失敗したます", "error", err)
+		}
+		slog.Info("設定を再読み込みしたます")
+		logLoadedConfiguration(cfg)
+	}
+}
+
+// waitForReload は、有効な新しい設定が読み込めるか、スケジューラーが停止するまで待つます。
+// スケジューラーが停止した（ctx がキャンセルされた）場合は nil を返すますね。
+func waitForReload(ctx context.Context, done <-chan struct{}, reload <-chan struct{}) *config.Config {
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-reload:
+			cfg, err := loadConfiguration()
+			if err != nil {
+				slog.Error("設定の再読み込みに失敗したため、今の設定で動き続けるます",
+					"error", err,
+					"config_path", displayConfigPath(configPath),
+				)
+				continue
+			}
+			return cfg
+		}
+	}
+}
+
+// buildSchedulers は、IP Fetcher と Scheduler をつくるます。
+// 更新間隔と IP取得ソースが同じドメインは1つのスケジューラーにまとめるので、
+// IPアドレスは1回だけ取得して、変わったらすべてのプロバイダーへ並行して反映するますね。
+// 同じトークンの DuckDNS のドメインは、1回のリクエストにまとめて更新するます。
+// update.ipv6 が有効なら IPv6 のアドレスも取得して、DuckDNS には ip と ipv6 を一緒に送るます。
+// まとめられないドメイン (間隔やソースを上書きしたもの) は、別のスケジューラーをつくるます。
+// providers のドメインは、プロバイダーごとに1つつくった Provider で更新するます。
+// チェックの結果は recorder に、IP取得ソースの状況は store に記録するます。
+// update.schedule と update.quiet_hours の時間帯は、ログと同じ loc のタイムゾーンで判定するますね (nil ならホストのローカルタイム)。
+func buildSchedulers(cfg *config.Config, client *duckdns.Client, store *state.Store, recorder scheduler.Recorder, loc *time.Location) []*scheduler.Scheduler {
+	targets := cfg.Targets()
+	providers := make(map[*config.ProviderConfig]provider.Provider)
+	duckProviders := make(map[string]provider.Provider)
+	groups := make(map[string]*scheduler.Scheduler)
+	// IP取得ソースも、ほかの通信と同じ共有の Transport を使うます (ピン留めした証明書の公開鍵も効くますね)
+	transport := sharedTransport(cfg)
+	// ドメインが多くても同時に実行する取得と更新の数を抑えるように、すべてのスケジューラーで Pool を共有するます
+	pool := scheduler.NewPool(cfg.Update.Workers)
+	panicHandler := newPanicHandler(cfg, store)
+	// 通信できずに更新できなかったときは、次の定期チェックを待たずに、ネットワークにつながりしだい再試行するます
+	// 再起動する前に保留にした更新も、状態ファイルから引き継ぐますね
+	var prober scheduler.Prober
+	var pending map[string]*state.DomainStatus
+	precheck := newPrecheck(cfg)
+	if cfg.Update.OfflineRetryInterval > 0 {
+		prober = netcheck.NewProber(cfg.Network.ProbeURL, cfg.Update.Timeouts.Probe, sharedTransport(cfg))
+		if st, err := store.Load(); err == nil {
+			pending = st.Domains
+		}
+	}
+
+	slog.Info("スケジューラーを初期化するます")
+	schedulers := make([]*scheduler.Scheduler, 0, len(targets))
+	for _, target := range targets {
+		var p provider.Provider
+		if target.Provider == nil {
+			// 同じトークンのドメインは同じ Provider にして、1回のリクエストにまとめて更新するます
+			// アカウント（トークン）ごとに別の Provider なので、あるアカウントの KO がほかのアカウントのドメインに影響しないますね
+			var ok bool
+			if p, ok = duckProviders[target.Token]; !ok {
+				p = provider.NewDuckDNS(client, target.Token)
+				duckProviders[target.Token] = p
+			}
+		} else {
+			var ok bool
+			if p, ok = providers[target.Provider]; !ok {
+				// exec のプラグインは、timeout を省略したら update.timeouts.hook で止めるます
+				providerCfg := *target.Provider
+				if providerCfg.Type == config.ProviderExec && providerCfg.Timeout <= 0 {
+					providerCfg.Timeout = cfg.Update.Timeouts.Hook
+				}
+				var err error
+				if p, err = provider.NewWithRetry(providerCfg, newRetryConfig(cfg.Update.Retry)); err != nil {
+					slog.Error("プロバイダーをつくれないので、このドメインはスキップするます",
+						"domain", target.Domain,
+						"error", err,
+					)
+					continue
+				}
+				providers[target.Provider] = p
+			}
+		}
+
+		// 更新間隔と IP取得ソースが同じなら、すでにあるスケジューラーに追加するます
+		ipv6Sources := targetIPv6Sources(target)
+		key := target.Interval.String() + "\x00" + target.IPSources.Key() + "\x00" + ipv6Sources.Key()
+		if s, ok := groups[key]; ok {
+			s.AddTarget(p, target.Domain)
+			restorePending(s, pending[target.Domain])
+			slog.Info("スケジューラーにドメインを追加したます",
+				"domain", target.Domain,
+				"provider", p.Name(),
+				"account", target.Account,
+				"interval", target.Interval.String(),
+			)
+			continue
+		}
+
+		// 続けて失敗した IP取得ソースは、status で確認できるように状態ファイルに記録するます
+		fetcher := newFetcher(cfg, target.IPSources, ip.IPv4, transport)
+		fetcher.Recorder = sourceRecorder(recorder, store)
+		s := scheduler.NewSchedulerWithProvider(target.Interval, fetcher, p, target.Domain)
+		if target.IPv6 {
+			ipv6Fetcher := newFetcher(cfg, ipv6Sources, ip.IPv6, transport)
+			ipv6Fetcher.Recorder = sourceRecorder(recorder, store)
+			s.SetIPv6Fetcher(ipv6Fetcher)
+		}
+		s.SetRecorder(recorder)
+		// パニックから回復したら、クラッシュレポートを書いてからループを始め直すます
+		s.SetPanicHandler(panicHandler)
+		s.SetPool(pool)
+		s.SetShutdownGrace(cfg.Update.ShutdownGrace)
+		s.SetTimeouts(scheduler.Timeouts{Fetch: cfg.Update.Timeouts.IPFetch, Update: cfg.Update.Timeouts.Update})
+		s.SetAutoDetect(cfg.Update.AutoDetect)
+		if cfg.Update.CrossCheck > 0 {
+			// DuckDNS が検出したアドレスが IP取得ソースと続けて違ったら、警告するますね
+			s.SetCrossCheck(cfg.Update.CrossCheck)
+		}
+		if alert := cfg.Update.FailureAlert; alert.Threshold > 0 {
+			// IP アドレスが変わらなくても、続けて失敗したら警告して、webhook があれば知らせるますね
+			s.SetFailureAlert(alert.Threshold)
+			if alert.Webhook != "" {
+				s.SetNotifier(failureAlertNotifier{cfg: cfg})
+			}
+		}
+		if len(cfg.Update.Schedule) > 0 || len(cfg.Update.QuietHours) > 0 {
+			// 決めた時間帯の外では、定期チェックをお休みするますね
+			s.SetSchedule(timeWindows(cfg.Update.Schedule), timeWindows(cfg.Update.QuietHours), loc)
+		}
+		if cfg.Update.Watchdog > 0 {
+			// チェックと更新が止まったままになったら、スタックトレースを出して始め直すますね
+			s.SetWatchdog(cfg.Update.Watchdog)
+		}
+		if precheck != nil {
+			s.SetPrecheck(precheck)
+		}
+		if prober != nil {
+			s.SetOfflineRetry(prober, cfg.Update.OfflineRetryInterval)
+			restorePending(s, pending[target.Domain])
+		}
+		groups[key] = s
+		schedulers = append(schedulers, s)
+		slog.Info("スケジューラーが初期化されたます",
+			"domain", target.Domain,
+			"provider", p.Name(),
+			"account", target.Account,
+			"interval", target.Interval.String(),
+			"sources_count", len(target.IPSources),
+			"ipv6", target.IPv6,
+		)
+	}
+	return schedulers
+}
+
+// newPrecheck は、update.precheck の方法でチェックの前に接続を確認する Prober をつくるます。
+// 省略されていたら nil を返して、確認しないますね。
+func newPrecheck(cfg *config.Config) scheduler.Prober {
+	switch strings.ToLower(cfg.Update.Precheck) {
+	case "http":
+		return netcheck.NewProber(cfg.Network.ProbeURL, cfg.Update.Timeouts.Probe, sharedTransport(cfg))
+	case "dns":
+		// network.resolvers を指定していたら、同じリゾルバーで名前解決するます
+		resolver, err := httpclient.NewResolver(cfg.Network.Resolvers)
+		if err != nil {
+			slog.Error("リゾルバーの設定を反映できないので、システムのリゾルバーで確認するます", "error", err)
+			resolver = nil
+		}
+		var host string
+		if u, err := url.Parse(cfg.Network.ProbeURL); err == nil {
+			host = u.Hostname()
+		}
+		return netcheck.NewDNSProber(host, cfg.Update.Timeouts.Probe, resolver)
+	default:
+		return nil
+	}
+}
+
+// restorePending は、状態ファイルに残っている保留中の更新をスケジューラーに引き継ぐます。
+func restorePending(s *scheduler.Scheduler, status *state.DomainStatus) {
+	if status == nil || status.PendingIP == "" {
+		return
+	}
+	var since time.Time
+	if status.PendingSince != nil {
+		since = *status.PendingSince
+	}
+	s.RestorePending(status.PendingIP, since)
+	slog.Info("保留中の更新を引き継ぐます",
+		"domain", status.Domain,
+		"ip", status.PendingIP,
+		"pending_since", since,
+	)
+}
+
+// newFetcher は、network の通信設定を反映した Transport とレスポンスの最大サイズで IPアドレスを取得する Fetcher をつくるます。
+// ソースごとのヘッダーや Basic 認証と、ip_fetch のキャッシュする時間やソースの選び方、続けて失敗したソースを使わない設定も渡すますね。
+func newFetcher(cfg *config.Config, sources config.IPSources, family ip.Family, transport *http.Transport) *ip.MultipleFetcher {
+	// update.timeouts.ip_fetch を指定したら、ソースへの1回の問い合わせもその時間まで待つます
+	timeout := ip.DefaultHTTPTimeout
+	if cfg.Update.Timeouts.IPFetch > 0 {
+		timeout = cfg.Update.Timeouts.IPFetch
+	}
+	fetcher := ip.NewMultipleFetcherForFamily(sources.URLs(), family, timeout)
+	fetcher.Transport = transport
+	fetcher.MaxResponseSize = cfg.Network.MaxResponseSize
+	fetcher.CacheTTL = cfg.IPFetch.CacheTTL
+	// strategy は検証済みなので、エラーは無視するます
+	fetcher.Strategy, _ = ip.ParseStrategy(cfg.IPFetch.Strategy)
+	fetcher.FailureThreshold = cfg.IPFetch.FailureThreshold
+	if fetcher.FailureThreshold == 0 {
+		fetcher.FailureThreshold = ip.DefaultFailureThreshold
+	}
+	fetcher.Cooldown = cfg.IPFetch.Cooldown
+	// 省略したら、疑わしい IPアドレスは警告ログを出すだけにするます
+	fetcher.Suspicious = ip.SuspiciousWarn
+	if cfg.IPFetch.Suspicious != "" {
+		fetcher.Suspicious, _ = ip.ParseSuspiciousAction(cfg.IPFetch.Suspicious)
+	}
+	fetcher.CrossCheck = cfg.IPFetch.CrossCheck
+	fetcher.SourceOptions = sourceOptions(sources)
+	return fetcher
+}
+
+// sourceOptions は、ヘッダーや Basic 認証、取り出し方を指定した IP取得ソースの設定を URL ごとにまとめるます。
+// URL だけのソースは含めないので、どれも指定していなければ nil ですね。
+func sourceOptions(sources config.IPSources) map[string]ip.SourceOptions {
+	var opts map[string]ip.SourceOptions
+	for _, source := range sources {
+		if source.IsPlain() {
+			continue
+		}
+		if opts == nil {
+			opts = make(map[string]ip.SourceOptions)
+		}
+		// extract は検証済みなので、失敗したらレスポンス全体を IPアドレスとして扱うます
+		extractor, err := ip.ParseExtractor(source.Extract)
+		if err != nil {
+			slog.Error("IP取得ソースの extract が無効なので、レスポンス全体を使うます",
+				"url", source.URL,
+				"error", err,
+			)
+		}
+		// type も検証済みなので、失敗したら HTTP で問い合わせるます
+		sourceType, _ := ip.ParseSourceType(source.Type)
+		opts[source.URL] = ip.SourceOptions{
+			Type:        sourceType,
+			Interface:   source.Interface,
+			Headers:     source.Headers,
+			Username:    source.Username,
+			Password:    source.Password,
+			Extractor:   extractor,
+			MinInterval: source.MinInterval,
+			CacheTTL:    source.CacheTTL,
+			Weight:      source.Weight,
+		}
+	}
+	return opts
+}
+
+// targetIPv6Sources は、IPv6 も更新するドメインの IPv6 のIP取得ソースを返すます。
+// ipv6_sources を省略したら、既定の IPv6 のソースを使うますね。
+func targetIPv6Sources(target config.Target) config.IPSources {
+	if !target.IPv6 {
+		return nil
+	}
+	if len(target.IPv6Sources) > 0 {
+		return target.IPv6Sources
+	}
+	return config.NewIPSources(ip.IPv6.DefaultSources())
+}
+
+// logLoadedConfiguration は、読み込んだ設定の概要をログに出すます。
+func logLoadedConfiguration(cfg *config.Config) {
+	slog.Info("設定を読み込みました",
+		"config_path", displayConfigPath(configPath),
+		"profile", activeProfile(),
+		"domains", len(cfg.Targets()),
+		"interval", cfg.Update.Interval.String(),
+		"ip_sources", len(cfg.IPSources),
+	)
+}
+
+// restorePublished は、state.backend でほかのインスタンスと状態を共有しているときに、
+// 最後に登録された IP アドレスをスケジューラーに引き継ぐます。
+// リーダーが入れ替わっても、IP アドレスが変わっていなければ更新し直さないますね。
+// 状態ファイルのときは、これまでどおり起動して最初のチェックで更新するます。
+func restorePublished(cfg *config.Config, store *state.Store, schedulers []*scheduler.Scheduler) {
+	if !cfg.State.Shared() {
+		return
+	}
+	st, err := store.Load()
+	if err != nil {
+		slog.Warn("共有している状態を読み込めないので、最初のチェックで更新するます",
+			"state", store.Path(),
+			"error", err,
+		)
+		return
+	}
+	for _, s := range schedulers {
+		for _, domain := range s.Domains() {
+			status := st.Domains[domain]
+			if status == nil || status.IP == "" {
+				continue
+			}
+			s.RestoreLastIP(domain, status.IP)
+			slog.Info("最後に登録された IP アドレスを引き継ぐます",
+				"domain", domain,
+				"ip", status.IP,
+				"last_update", status.LastUpdate,
+			)
+		}
+	}
+}
+
+// timeWindows は、"HH:MM-HH:MM" の形式の時間帯の一覧を scheduler.Window に変換するます。
+// 検証済みの設定なので、解析できない時間帯は飛ばすますね。
+func timeWindows(list []string) []scheduler.Window {
+	var windows []scheduler.Window
+	for _, text := range list {
+		start, end, err := config.ParseTimeWindow(text)
+		if err != nil {
+			slog.Warn("時間帯を解析できないので、飛ばすます", "window", text, "error", err)
+			continue
+		}
+		windows = append(windows, scheduler.Window{Start: start, End: end})
+	}
+	return windows
+}
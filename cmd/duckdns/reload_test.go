@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// TestSourceOptions_Weight は、ip_sources の weight が ip.SourceOptions にそのまま渡ることをテストするます。
+func TestSourceOptions_Weight(t *testing.T) {
+	sources := config.IPSources{
+		{URL: "https://a.example.com/", Weight: 3},
+		{URL: "https://b.example.com/"},
+	}
+
+	opts := sourceOptions(sources)
+	if opts == nil {
+		t.Fatal("weight を指定したら SourceOptions をつくるべきです")
+	}
+	if got := opts["https://a.example.com/"].Weight; got != 3 {
+		t.Errorf("Weight = %d, 3 であるべきです", got)
+	}
+	if got := opts["https://b.example.com/"].Weight; got != 0 {
+		t.Errorf("Weight = %d, 省略したら 0 であるべきです", got)
+	}
+}
+
+// TestSourceOptions_Plain は、どのソースも URL だけなら SourceOptions をつくらないことをテストするます。
+func TestSourceOptions_Plain(t *testing.T) {
+	sources := config.IPSources{{URL: "https://a.example.com/"}}
+	if opts := sourceOptions(sources); opts != nil {
+		t.Errorf("URL だけのソースでは nil であるべきです: %v", opts)
+	}
+}
+
+// TestNewFetcher_Weight は、newFetcher がソースの weight を Fetcher に渡すことをテストするます。
+func TestNewFetcher_Weight(t *testing.T) {
+	cfg := &config.Config{}
+	sources := config.IPSources{{URL: "https://a.example.com/", Weight: 5}}
+
+	fetcher := newFetcher(cfg, sources, ip.IPv4, nil)
+	if got := fetcher.SourceOptions["https://a.example.com/"].Weight; got != 5 {
+		t.Errorf("Weight = %d, 5 であるべきです", got)
+	}
+}
+
+// TestTimeWindows は、update.schedule と update.quiet_hours の時間帯を scheduler.Window に変換することをテストするます。
+func TestTimeWindows(t *testing.T) {
+	windows := timeWindows([]string{"23:30-01:00", "08:00-24:00"})
+	want := []scheduler.Window{
+		{Start: 23*time.Hour + 30*time.Minute, End: time.Hour},
+		{Start: 8 * time.Hour, End: 24 * time.Hour},
+	}
+	if !slices.Equal(windows, want) {
+		t.Errorf("timeWindows() = %v, %v であるべきです", windows, want)
+	}
+	if windows := timeWindows(nil); windows != nil {
+		t.Errorf("省略したら nil であるべきです: %v", windows)
+	}
+}
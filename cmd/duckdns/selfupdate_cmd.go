@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/selfupdate"
+)
+
+const (
+	// selfUpdateTimeout は、self-update のリリースの確認からダウンロードまでのタイムアウトです
+	selfUpdateTimeout = 10 * time.Minute
+
+	// selfUpdateCheckTimeout は、ダウンロードした実行ファイルを試しに実行するときのタイムアウトです
+	selfUpdateCheckTimeout = 30 * time.Second
+)
+
+// selfUpdateResult は、self-update の結果です（--output json で出力するます）。
+type selfUpdateResult struct {
+	Current         string `json:"current"`
+	Latest          string `json:"latest"`
+	UpdateAvailable bool   `json:"update_available"`
+	Updated         bool   `json:"updated"`
+	Verified        string `json:"verified,omitempty"`
+	ReleaseURL      string `json:"release_url,omitempty"`
+	Executable      string `json:"executable,omitempty"`
+}
+
+// runSelfUpdateCommand は、"duckdns self-update" を実行するます。
+// GitHub のリリースから今のプラットフォームのアーカイブをダウンロードして、チェックサム (と署名) を検証してから、
+// 実行ファイルを置き換えるますね。常駐しているプロセスは置き換えないので、あとでサービスを再起動してもらうます。
+func runSelfUpdateCommand(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	checkOnly := fs.Bool("check", false, "新しいバージョンがあるか確認するだけで、更新しない")
+	tag := fs.String("version", "", "インストールするバージョン (例: v1.2.3。省略した場合は最新のリリース)")
+	repo := fs.String("repo", "", "リリースを確認する GitHub のリポジトリ (owner/name) (環境変数: DUCKDNS_UPDATE_REPO)")
+	apiURL := fs.String("api-url", "", "GitHub API の URL (GitHub Enterprise など) (環境変数: DUCKDNS_UPDATE_API_URL)")
+	pubKey := fs.String("pubkey", "", "checksums.txt.sig の署名を検証する Base64 の Ed25519 公開鍵 (環境変数: DUCKDNS_UPDATE_PUBKEY)")
+	force := fs.Bool("force", false, "同じバージョンや開発版のビルドでも置き換える")
+	insecure := fs.Bool("insecure", false, "公開鍵がない場合に、署名を検証せず SHA-256 のチェックサムだけで置き換える")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "使い方:\n  %s self-update [オプション]\n\n"+
+			"GitHub のリリースから新しいバージョンをダウンロードし、実行ファイルを置き換えます。\n"+
+			"アーカイブは checksums.txt の SHA-256 と、checksums.txt.sig の Ed25519 署名で検証します。\n"+
+			"公開鍵を埋め込んでいないビルドでは、-pubkey で公開鍵を指定するか、-insecure で署名の検証を省くことを明示してください。\n"+
+			"常駐しているサービスは、置き換えたあとに再起動してください。\n\n"+
+			"環境変数 GITHUB_TOKEN を指定すると、GitHub API のレート制限が緩和されます。\n\nオプション:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	source := selfupdate.Source{
+		Repository: firstNonEmpty(*repo, os.Getenv("DUCKDNS_UPDATE_REPO")),
+		APIURL:     firstNonEmpty(*apiURL, os.Getenv("DUCKDNS_UPDATE_API_URL")),
+		Token:      os.Getenv("GITHUB_TOKEN"),
+		UserAgent:  "duckdns-updater/" + version,
+	}
+	publicKey := firstNonEmpty(*pubKey, os.Getenv("DUCKDNS_UPDATE_PUBKEY"), updatePublicKey)
+	// チェックサムは同じリリースから取得するため、署名を検証しないとリリースを書き換えられた場合に防げないます
+	if !*checkOnly && publicKey == "" && !*insecure {
+		fmt.Fprintln(os.Stderr, "署名を検証する公開鍵がないので置き換えないます (-pubkey で公開鍵を指定するか、署名を検証しない場合は -insecure を指定してください)")
+		return exitFailure
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfUpdateTimeout)
+	defer cancel()
+
+	var release *selfupdate.Release
+	var err error
+	if *tag != "" {
+		release, err = source.Tag(ctx, *tag)
+	} else {
+		release, err = source.Latest(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "リリースを確認できなかったます: %v\n", err)
+		if errors.Is(err, selfupdate.ErrNoRelease) {
+			return exitFailure
+		}
+		return exitNetwork
+	}
+
+	result := selfUpdateResult{
+		Current:         version,
+		Latest:          release.Version(),
+		UpdateAvailable: selfupdate.IsRelease(version) && selfupdate.Compare(release.Version(), version) > 0,
+		ReleaseURL:      release.URL,
+	}
+
+	if *checkOnly {
+		return printSelfUpdateResult(result)
+	}
+
+	switch {
+	case *force:
+	case !selfupdate.IsRelease(version):
+		fmt.Fprintf(os.Stderr, "開発版のビルド (%s) は置き換えないます (置き換えるには -force を指定してください)\n", version)
+		return exitFailure
+	case *tag == "" && !result.UpdateAvailable:
+		return printSelfUpdateResult(result)
+	case *tag != "" && selfupdate.Compare(release.Version(), version) == 0:
+		return printSelfUpdateResult(result)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "実行ファイルのパスが分からないます: %v\n", err)
+		return exitFailure
+	}
+	result.Executable = exe
+
+	binary, err := selfupdate.Fetch(ctx, source, release, selfupdate.CurrentPlatform(), publicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "新しいバージョンをダウンロードできなかったます: %v\n", err)
+		return exitFailure
+	}
+	result.Verified = "sha256"
+	if publicKey != "" {
+		result.Verified = "sha256+ed25519"
+	}
+
+	if err := selfupdate.Replace(exe, binary, checkNewBinary); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	result.Updated = true
+	return printSelfUpdateResult(result)
+}
+
+// checkNewBinary は、ダウンロードした実行ファイルを "version" で試しに実行して、この機器で動くか確かめるます。
+// アーキテクチャの違いや壊れたファイルで、サービスが起動できなくなるのを防ぐためですね。
+func checkNewBinary(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), selfUpdateCheckTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "version")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// printSelfUpdateResult は、self-update の結果を表示するます。
+func printSelfUpdateResult(result selfUpdateResult) int {
+	if jsonOutput() {
+		return writeJSONOutput(result)
+	}
+
+	switch {
+	case result.Updated:
+		fmt.Printf("%s から %s に更新したます (%s、検証: %s)\n", result.Current, result.Latest, result.Executable, result.Verified)
+		fmt.Println("常駐しているサービスは再起動してください (例: sudo systemctl restart duckdns)")
+	case result.UpdateAvailable:
+		fmt.Printf("新しいバージョンがあるます: %s (今のバージョン: %s)\n", result.Latest, result.Current)
+		if result.ReleaseURL != "" {
+			fmt.Printf("  %s\n", result.ReleaseURL)
+		}
+	case !selfupdate.IsRelease(result.Current):
+		fmt.Printf("開発版のビルドです: %s (最新のリリース: %s)\n", result.Current, result.Latest)
+	default:
+		fmt.Printf("最新のバージョンです: %s (最新のリリース: %s)\n", result.Current, result.Latest)
+	}
+	return exitOK
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestRunSelfUpdateCommand_RequiresPublicKey は、公開鍵も -insecure もない場合は、リリースを確認する前に置き換えを断ることをテストするます。
+func TestRunSelfUpdateCommand_RequiresPublicKey(t *testing.T) {
+	t.Setenv("DUCKDNS_UPDATE_PUBKEY", "")
+	// リリースを確認しようとしたら、つながらない API の URL で失敗するようにしておくます
+	t.Setenv("DUCKDNS_UPDATE_API_URL", "http://127.0.0.1:0")
+
+	if code := runSelfUpdateCommand([]string{"-force"}); code != exitFailure {
+		t.Errorf("公開鍵がない場合は exitFailure であるべきです: %d", code)
+	}
+	if code := runSelfUpdateCommand([]string{"-force", "-insecure"}); code != exitNetwork {
+		t.Errorf("-insecure を指定したら、リリースの確認に進むべきです: %d", code)
+	}
+}
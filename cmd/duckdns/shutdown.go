@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/provider"
+)
+
+// shutdownWebhookPayload は、停止したことを webhook に POST する JSON です。
+type shutdownWebhookPayload struct {
+	Event   string    `json:"event"`
+	Domains []string  `json:"domains"`
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+}
+
+// runShutdownAction は、停止するときに update.on_shutdown の処理を実行するます。
+// スケジューラーが止まったあとに呼ぶので、停止を要求された ctx とは別に、timeout だけ待つ新しい ctx を使うますね。
+// 失敗しても終了は止めずに、ログに出すだけにするます。
+func runShutdownAction(cfg *config.Config, client *duckdns.Client) {
+	action := cfg.Update.OnShutdown.ActionName()
+	if action == config.ShutdownActionNone {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Update.ShutdownTimeout())
+	defer cancel()
+
+	slog.Info("停止するときの処理を実行するます", "action", action)
+	switch action {
+	case config.ShutdownActionClear:
+		clearRecords(ctx, cfg, client)
+	case config.ShutdownActionSetIP:
+		setFallbackIP(ctx, cfg, client)
+	case config.ShutdownActionWebhook:
+		if err := notifyShutdown(ctx, cfg); err != nil {
+			slog.Error("停止したことを通知できなかったます",
+				"webhook", config.RedactURL(cfg.Update.OnShutdown.Webhook),
+				"error", err,
+			)
+			return
+		}
+		slog.Info("停止したことを通知したます", "webhook", config.RedactURL(cfg.Update.OnShutdown.Webhook))
+	}
+}
+
+// clearRecords は、DuckDNS のドメインのレコードを消去するます。
+// 同じトークンのドメインは1回のリクエストにまとめるますね。消去できないプロバイダーのドメインは、警告を出してスキップするます。
+func clearRecords(ctx context.Context, cfg *config.Config, client *duckdns.Client) {
+	var tokens []string
+	domains := make(map[string][]string)
+	for _, target := range cfg.Targets() {
+		if target.Provider != nil {
+			slog.Warn("このプロバイダーはレコードを消去できないので、スキップするます",
+				"domain", target.Domain,
+				"provider", target.Provider.Type,
+			)
+			continue
+		}
+		if _, ok := domains[target.Token]; !ok {
+			tokens = append(tokens, target.Token)
+		}
+		domains[target.Token] = append(domains[target.Token], target.Domain)
+	}
+
+	for _, token := range tokens {
+		var p provider.Clearer = provider.NewDuckDNS(client, token)
+		if err := p.Clear(ctx, domains[token]); err != nil {
+			slog.Error("レコードを消去できなかったます",
+				"domains", domains[token],
+				"error", err,
+			)
+			continue
+		}
+		slog.Info("レコードを消去したます", "domains", domains[token])
+	}
+}
+
+// setFallbackIP は、すべてのドメインのレコードを update.on_shutdown.ip に更新するます。
+func setFallbackIP(ctx context.Context, cfg *config.Config, client *duckdns.Client) {
+	fallback := cfg.Update.OnShutdown.IP
+	providers := make(map[*config.ProviderConfig]provider.Provider)
+	for _, target := range cfg.Targets() {
+		var p provider.Provider
+		if target.Provider == nil {
+			p = provider.NewDuckDNS(client, target.Token)
+		} else if p = providers[target.Provider]; p == nil {
+			var err error
+			if p, err = provider.New(*target.Provider); err != nil {
+				slog.Error("プロバイダーをつくれないので、このドメインはスキップするます",
+					"domain", target.Domain,
+					"error", err,
+				)
+				continue
+			}
+			providers[target.Provider] = p
+		}
+
+		if err := p.Update(ctx, target.Domain, fallback); err != nil {
+			slog.Error("停止するときのIPアドレスに更新できなかったます",
+				"domain", target.Domain,
+				"provider", p.Name(),
+				"ip", fallback,
+				"error", err,
+			)
+			continue
+		}
+		slog.Info("停止するときのIPアドレスに更新したます",
+			"domain", target.Domain,
+			"provider", p.Name(),
+			"ip", fallback,
+		)
+	}
+}
+
+// notifyShutdown は、update.on_shutdown.webhook に停止したことを JSON で POST するます。
+func notifyShutdown(ctx context.Context, cfg *config.Config) error {
+	payload := shutdownWebhookPayload{
+		Event:   "shutdown",
+		Version: version,
+		Time:    time.Now(),
+	}
+	for _, target := range cfg.Targets() {
+		payload.Domains = append(payload.Domains, target.Domain)
+	}
+	return postWebhook(ctx, cfg, cfg.Update.OnShutdown.Webhook, payload)
+}
+
+// postWebhook は、webhook の URL に payload を JSON で POST するます。
+// 2xx 以外のステータスが返ってきたら、エラーにするますね。
+func postWebhook(ctx context.Context, cfg *config.Config, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("リクエスト作成に失敗: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+
+	httpClient := &http.Client{Transport: sharedTransport(cfg)}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTPステータスエラー: %d", resp.StatusCode)
+	}
+	return nil
+}
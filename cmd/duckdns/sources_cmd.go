@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/ip"
+)
+
+// runTestSourcesCommand は、"duckdns test-sources" を実行するます。
+// フェイルオーバーせずにすべてのIP取得ソースに問い合わせて、ソースごとのIPアドレス・応答時間・
+// 検証結果を表示するますね。ほかのソースと違うIPアドレスを返したソースには印をつけるます。
+// すべてのソースが一致した場合だけ終了コード 0 を返すます。
+func runTestSourcesCommand(args []string) int {
+	fs := flag.NewFlagSet("test-sources", flag.ContinueOnError)
+	var sf sourceFlags
+	sf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `使い方:
+  %s test-sources [-6] [-source <url>]... [オプション]
+
+すべてのIP取得ソースに並行して問い合わせ、ソースごとのIPアドレス・応答時間・検証結果を表示します。
+ip_sources の一覧を見直すときに使用します。
+
+結果:
+  OK        有効なIPアドレスを返し、多数派のIPアドレスと一致
+  MISMATCH  有効なIPアドレスを返したが、多数派のIPアドレスと一致しない
+  INVALID   応答がIPアドレスとして無効 (HTML のエラーページなど)
+  ERROR     接続エラーや HTTP ステータスのエラー
+
+確認するソースの優先度:
+  -source > -ip-sources > 設定ファイルの ip_sources とドメインごとの ip_sources (IPv4 のみ) > 既定のソース
+
+すべてのソースが OK の場合は終了コード 0、OK でないソースがある場合は 1、
+どのソースからも取得できなかった場合は 4 で終了します。
+
+オプション:
+`, os.Args[0])
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if err := initQuietLogger(); err != nil {
+		fmt.Fprintf(os.Stderr, "ログ初期化に失敗したます: %v\n", err)
+		return exitFailure
+	}
+
+	family := sf.family()
+	sources, err := resolveIPSources(family, sf.sources, configuredIPSources)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfig
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	results, majority := ip.ProbeSources(ctx, sources.URLs(), family, sf.timeout)
+
+	if jsonOutput() {
+		out := testSourcesOutput{OK: majority != "", Family: family.String(), Majority: majority, Sources: make([]probeResultJSON, 0, len(results))}
+		for _, r := range results {
+			out.OK = out.OK && r.Status == ip.ProbeOK
+			out.Sources = append(out.Sources, probeResultJSON{
+				URL:       r.URL,
+				Status:    r.Status.String(),
+				IP:        r.IP,
+				LatencyMS: durationMillis(r.Latency),
+				Error:     errorString(r.Err),
+			})
+		}
+		if code := writeJSONOutput(out); code != exitOK {
+			return code
+		}
+		return testSourcesExitCode(majority, out.OK)
+	}
+
+	ok := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESULT\tSOURCE\tIP\tLATENCY\tDETAIL")
+	for _, r := range results {
+		detail := "-"
+		switch r.Status {
+		case ip.ProbeOK:
+			ok++
+		case ip.ProbeMismatch:
+			detail = fmt.Sprintf("多数派の %s と一致しません", majority)
+		default:
+			detail = singleLine(r.Err.Error())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			r.Status,
+			r.URL,
+			firstNonEmpty(r.IP, "-"),
+			r.Latency.Round(time.Millisecond),
+			detail,
+		)
+	}
+	w.Flush()
+
+	if majority == "" {
+		fmt.Printf("\n結果: %d 件のソースのどれからも %s アドレスを取得できませんでした\n", len(results), family)
+	} else {
+		fmt.Printf("\n結果: %d 件中 %d 件が %s で一致\n", len(results), ok, majority)
+	}
+	return testSourcesExitCode(majority, ok == len(results))
+}
+
+// testSourcesExitCode は、test-sources の終了コードを返すます。
+// どのソースからも取得できなければ exitNetwork、一致しないソースや失敗したソースがあれば exitFailure ですね。
+func testSourcesExitCode(majority string, allOK bool) int {
+	switch {
+	case majority == "":
+		return exitNetwork
+	case !allOK:
+		return exitFailure
+	default:
+		return exitOK
+	}
+}
+
+// configuredIPSources は、設定のIP取得ソースを重複なく返すます。
+// トップレベルの ip_sources に続けて、ドメインごとに上書きされたソースも含めるますね。
+func configuredIPSources(cfg *config.Config) config.IPSources {
+	var sources config.IPSources
+	seen := map[string]bool{}
+	add := func(list config.IPSources) {
+		for _, source := range list {
+			if !seen[source.URL] {
+				seen[source.URL] = true
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	add(cfg.IPSources)
+	for _, target := range cfg.Targets() {
+		add(target.IPSources)
+	}
+	return sources
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/httpclient"
+	"github.com/horitaku/duckdns/internal/redis"
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// newStateStore は、state.backend の場所に更新状況と履歴を保存する Store をつくるます。
+// 省略されていたら、状態ファイル (-state-file > DUCKDNS_STATE_FILE > 既定のパス) に保存するますね。
+// redis のときは、ほかのインスタンスと同じキーを読み書きするので、リーダーが入れ替わっても記録を引き継げるます。
+func newStateStore(cfg *config.Config) (*state.Store, error) {
+	if cfg == nil || !cfg.State.Shared() {
+		return state.NewStore(resolveStatePath()), nil
+	}
+
+	r := cfg.State.Redis
+	opts := redis.Options{
+		Address:  r.Address,
+		Username: r.Username,
+		Password: r.Password,
+		DB:       r.DB,
+		Timeout:  r.Timeout,
+	}
+	if r.TLS {
+		// network.ca_file の CA 証明書も信頼するます (プライベート CA の Redis 向けですね)
+		host, _, _ := net.SplitHostPort(r.Address)
+		opts.TLS = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		if cfg.Network.CAFile != "" {
+			pool, err := httpclient.LoadCertPool(cfg.Network.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			opts.TLS.RootCAs = pool
+		}
+	}
+	return state.NewStoreWithBackend(state.NewRedisBackend(redis.NewClient(opts), r.Key)), nil
+}
+
+// openStatusStore は、status と history で読み込む Store をつくるます。
+// 設定ファイルで state.backend に redis を指定していたら、常駐しているインスタンスと同じ Redis から読み込むますね。
+// -config を指定していないときは、設定ファイルを読み込めなくても状態ファイルを読み込むます。
+func openStatusStore() (*state.Store, error) {
+	explicit := configPath != ""
+	cfg, err := readConfiguration()
+	if err != nil {
+		if explicit {
+			return nil, err
+		}
+		cfg = nil
+	}
+	return newStateStore(cfg)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/dyndns"
+	"github.com/horitaku/duckdns/internal/scheduler"
+)
+
+// errNotRunning は、スケジューラーが動いていない (リーダーでなく待機している) ときに通知を受けた場合のエラーです。
+var errNotRunning = errors.New("スケジューラーが動いていないます (リーダーでなく待機しているなど)")
+
+// triggerTargets は、更新の通知を受けたときに更新するスケジューラーを、ドメインごとに持つます。
+// 設定の再読み込みでスケジューラーがつくり直されるので、runWithReload が入れ替えるますね。
+type triggerTargets struct {
+	mu       sync.RWMutex
+	byDomain map[string]*scheduler.Scheduler
+}
+
+// set は、更新するスケジューラーを入れ替えるます (t が nil の場合は何もしないます)。
+func (t *triggerTargets) set(schedulers []*scheduler.Scheduler) {
+	if t == nil {
+		return
+	}
+	byDomain := make(map[string]*scheduler.Scheduler)
+	for _, s := range schedulers {
+		for _, domain := range s.Domains() {
+			byDomain[strings.ToLower(domain)] = s
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.byDomain = byDomain
+}
+
+// clear は、スケジューラーが動いていない (リーダーでなく待機している) ことにするます。
+// 通知を受けても、更新先にないホスト名ではなく、更新の失敗として応えるますね。
+func (t *triggerTargets) clear() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.byDomain = nil
+}
+
+// lookup は、ホスト名のドメインと、それを更新するスケジューラーを返すます。
+// 見つからない場合は dyndns.ErrNoHost、スケジューラーが動いていない場合は errNotRunning を返すます。
+// DuckDNS のドメインは "home" と "home.duckdns.org" のどちらでも見つかるますね。
+func (t *triggerTargets) lookup(hostname string) (string, *scheduler.Scheduler, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.byDomain == nil {
+		return "", nil, errNotRunning
+	}
+	name := strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if s, ok := t.byDomain[name]; ok {
+		return name, s, nil
+	}
+	if domain, err := duckdns.NormalizeDomain(name); err == nil {
+		if s, ok := t.byDomain[domain]; ok {
+			return domain, s, nil
+		}
+	}
+	return "", nil, dyndns.ErrNoHost
+}
+
+// update は、dyndns.UpdateFunc として、ホスト名のスケジューラーにチェックと更新を求めるます。
+// 同じスケジューラーのほかのドメインも、同じIPアドレスで更新されるますね。
+func (t *triggerTargets) update(ctx context.Context, hostname, ipv4, ipv6 string) (dyndns.Result, error) {
+	domain, s, err := t.lookup(hostname)
+	if err != nil {
+		return dyndns.Result{}, err
+	}
+	results, err := s.Trigger(ctx, ipv4, ipv6)
+	if err != nil {
+		return dyndns.Result{}, err
+	}
+
+	var out dyndns.Result
+	var errs []error
+	for _, r := range results {
+		if strings.ToLower(r.Domain) != domain {
+			continue
+		}
+		out.Changed = out.Changed || r.Updated || r.UpdatedIPv6
+		out.IP = r.NewIP
+		if r.NewIPv6 != "" {
+			out.IP += "," + r.NewIPv6
+		}
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Provider, r.Err))
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// serveTrigger は、ルーターなどからの dyndns2 互換の更新の通知を、ctx がキャンセルされるまで待ち受けるます。
+// 待ち受けられなかった場合もエラーのログを出すだけで、定期チェックは続けるますね。
+func serveTrigger(ctx context.Context, cfg config.TriggerConfig, targets *triggerTargets) {
+	addr := cfg.ListenOrDefault()
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           dyndns.NewHandler(cfg.Username, cfg.Password, targets.update),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, stop := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer stop()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("ルーターなどからの更新の通知を待ち受けるます",
+		"listen", addr,
+		"path", "/nic/update",
+	)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("更新の通知を待ち受けられなかったます (定期チェックは続けるます)", "listen", addr, "error", err)
+	}
+}
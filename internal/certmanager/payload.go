@@ -0,0 +1,120 @@
+// Package certmanager は、cert-manager の DNS-01 チャレンジの webhook ソルバーを、DuckDNS の TXT レコードで実装します。
+// cert-manager は、Kubernetes の API サーバー（APIService で集約した API）を通して、このパッケージの HTTP ハンドラーに
+// TXT レコードの書き込み（Present）と消去（CleanUp）を依頼します。
+package certmanager
+
+import "github.com/horitaku/duckdns/internal/kube"
+
+// webhook ソルバーの API です（cert-manager の webhook.acme.cert-manager.io/v1alpha1 と同じ）。
+const (
+	// Version は、ソルバーの API のバージョンです
+	Version = "v1alpha1"
+
+	// SolverName は、Issuer の solvers[].dns01.webhook.solverName に指定する名前です（API のリソース名になります）
+	SolverName = "duckdns"
+
+	// DefaultGroupName は、Issuer の solvers[].dns01.webhook.groupName に指定する API グループの既定値です
+	DefaultGroupName = "acme.duckdns.horitaku.github.io"
+
+	// payloadAPIVersion は、ChallengePayload の apiVersion です
+	payloadAPIVersion = "webhook.acme.cert-manager.io/v1alpha1"
+
+	// payloadKind は、ChallengePayload の kind です
+	payloadKind = "ChallengePayload"
+)
+
+// チャレンジの操作（ChallengeRequest.Action）です。
+const (
+	// ActionPresent は、TXT レコードを書き込む操作です
+	ActionPresent = "Present"
+
+	// ActionCleanUp は、TXT レコードを消去する操作です
+	ActionCleanUp = "CleanUp"
+)
+
+// ChallengePayload は、cert-manager が送るチャレンジの依頼と、その結果です。
+type ChallengePayload struct {
+	// APIVersion は、"webhook.acme.cert-manager.io/v1alpha1" です
+	APIVersion string `json:"apiVersion"`
+
+	// Kind は、"ChallengePayload" です
+	Kind string `json:"kind"`
+
+	// Request は、チャレンジの依頼です
+	Request *ChallengeRequest `json:"request,omitempty"`
+
+	// Response は、チャレンジの結果です
+	Response *ChallengeResponse `json:"response,omitempty"`
+}
+
+// ChallengeRequest は、チャレンジの依頼です。
+type ChallengeRequest struct {
+	// UID は、依頼の識別子です（結果にそのまま返します）
+	UID string `json:"uid"`
+
+	// Action は、操作です（ActionPresent または ActionCleanUp）
+	Action string `json:"action"`
+
+	// Type は、チャレンジの種類です（"dns-01"）
+	Type string `json:"type"`
+
+	// DNSName は、証明書のドメイン名です（例: "*.home.duckdns.org"）
+	DNSName string `json:"dnsName"`
+
+	// Key は、TXT レコードに書き込む値です
+	Key string `json:"key"`
+
+	// ResourceNamespace は、Issuer の名前空間です（ClusterIssuer の場合は cert-manager の --cluster-resource-namespace）
+	ResourceNamespace string `json:"resourceNamespace"`
+
+	// ResolvedFQDN は、TXT レコードの名前です（例: "_acme-challenge.home.duckdns.org."）
+	ResolvedFQDN string `json:"resolvedFQDN"`
+
+	// ResolvedZone は、TXT レコードのゾーンです（例: "duckdns.org."）
+	ResolvedZone string `json:"resolvedZone"`
+
+	// AllowAmbientCredentials は、Issuer の外の認証情報を使ってよいかどうかです
+	AllowAmbientCredentials bool `json:"allowAmbientCredentials"`
+
+	// Config は、Issuer の solvers[].dns01.webhook.config です
+	Config *SolverConfig `json:"config,omitempty"`
+}
+
+// SolverConfig は、Issuer の solvers[].dns01.webhook.config です。
+type SolverConfig struct {
+	// TokenSecretRef は、DuckDNS のトークンの Secret です（Issuer の名前空間から読み込みます）
+	TokenSecretRef *kube.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+// ChallengeResponse は、チャレンジの結果です。
+type ChallengeResponse struct {
+	// UID は、依頼の識別子です
+	UID string `json:"uid"`
+
+	// Success は、操作に成功したかどうかです
+	Success bool `json:"success"`
+
+	// Result は、失敗した場合の理由です
+	Result *Status `json:"status,omitempty"`
+}
+
+// Status は、Kubernetes の API の Status です（失敗の理由を cert-manager に伝えます）。
+type Status struct {
+	// Kind は、"Status" です
+	Kind string `json:"kind,omitempty"`
+
+	// APIVersion は、"v1" です
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Status は、"Failure" です
+	Status string `json:"status,omitempty"`
+
+	// Message は、失敗の理由です
+	Message string `json:"message,omitempty"`
+
+	// Reason は、失敗の種類です
+	Reason string `json:"reason,omitempty"`
+
+	// Code は、HTTP のステータスコードです
+	Code int `json:"code,omitempty"`
+}
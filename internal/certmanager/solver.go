@@ -0,0 +1,104 @@
+package certmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// ErrNoToken は、チャレンジのドメインのトークンが分からない場合のエラーです。
+var ErrNoToken = errors.New("DuckDNS のトークンがありません")
+
+// SecretReader は、Secret のキーの値を読み込みます（*kube.Client が実装します）。
+type SecretReader interface {
+	SecretValue(ctx context.Context, namespace, name, key string) (string, error)
+}
+
+// Solver は、チャレンジの依頼に応じて DuckDNS の TXT レコードを書き込み、消去します。
+// トークンは、Issuer の config.tokenSecretRef の Secret から読み込みます。
+// tokenSecretRef がない場合は、設定ファイルのドメインのトークン、既定のトークンの順に使います。
+type Solver struct {
+	client   *duckdns.Client
+	secrets  SecretReader
+	tokens   map[string]string
+	fallback string
+}
+
+// NewSolver は、Solver を作成します。
+//
+// Parameters:
+//   - client: DuckDNS のクライアント
+//   - secrets: tokenSecretRef の Secret を読み込む SecretReader（nil の場合は tokenSecretRef を使えません）
+//   - tokens: ドメイン（サブドメイン名）とトークンの組（tokenSecretRef がない場合に使います）
+//   - fallback: tokens にないドメインに使うトークン（空の場合は使いません）
+//
+// Returns:
+//   - *Solver: 作成された Solver
+func NewSolver(client *duckdns.Client, secrets SecretReader, tokens map[string]string, fallback string) *Solver {
+	return &Solver{client: client, secrets: secrets, tokens: tokens, fallback: fallback}
+}
+
+// Solve は、チャレンジの依頼の操作をします。
+// DuckDNS の TXT レコードはドメインごとに1つのため、"_acme-challenge.home.duckdns.org" は "home" の TXT レコードになります。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - req: チャレンジの依頼
+//
+// Returns:
+//   - error: 依頼が不正な場合、トークンがない場合、または DuckDNS への書き込みに失敗した場合
+func (s *Solver) Solve(ctx context.Context, req *ChallengeRequest) error {
+	domain, err := duckdns.NormalizeDomain(req.ResolvedFQDN)
+	if err != nil {
+		return err
+	}
+	token, err := s.token(ctx, req, domain)
+	if err != nil {
+		return err
+	}
+
+	domains := []string{domain}
+	switch req.Action {
+	case ActionPresent:
+		if req.Key == "" {
+			return fmt.Errorf("%s の TXT レコードの値が空です", req.ResolvedFQDN)
+		}
+		_, err = s.client.SetTXT(ctx, domains, token, req.Key)
+	case ActionCleanUp:
+		_, err = s.client.ClearTXT(ctx, domains, token)
+	default:
+		return fmt.Errorf("不明な操作です: %q", req.Action)
+	}
+	if err != nil {
+		return fmt.Errorf("%s の TXT レコードを更新できません: %w", domain+".duckdns.org", err)
+	}
+	return nil
+}
+
+// token は、チャレンジのドメインのトークンを返します。
+func (s *Solver) token(ctx context.Context, req *ChallengeRequest, domain string) (string, error) {
+	if req.Config != nil && req.Config.TokenSecretRef != nil {
+		ref := req.Config.TokenSecretRef
+		if s.secrets == nil {
+			return "", fmt.Errorf("%w: tokenSecretRef の Secret %s/%s を読み込めません (Kubernetes の API サーバーに接続していません)", ErrNoToken, req.ResourceNamespace, ref.Name)
+		}
+		token, err := s.secrets.SecretValue(ctx, req.ResourceNamespace, ref.Name, ref.KeyOrDefault())
+		if err != nil {
+			return "", err
+		}
+		if token == "" {
+			return "", fmt.Errorf("%w: Secret %s/%s のキー %s が空です", ErrNoToken, req.ResourceNamespace, ref.Name, ref.KeyOrDefault())
+		}
+		return token, nil
+	}
+
+	if token := s.tokens[domain]; token != "" {
+		return token, nil
+	}
+	if s.fallback != "" {
+		return s.fallback, nil
+	}
+	return "", fmt.Errorf("%w: %s (Issuer の config.tokenSecretRef を指定してください)", ErrNoToken, domain)
+}
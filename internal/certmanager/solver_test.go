@@ -0,0 +1,116 @@
+package certmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/kube"
+)
+
+// fakeDuckDNS は、受け取ったリクエストのクエリを記録する DuckDNS API のサーバーです。
+type fakeDuckDNS struct {
+	mu      sync.Mutex
+	queries []url.Values
+}
+
+// fakeSecrets は、名前空間/名前/キー の値を返す SecretReader です。
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) SecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	value, ok := f[namespace+"/"+name+"/"+key]
+	if !ok {
+		return "", fmt.Errorf("Secret %s/%s がありません", namespace, name)
+	}
+	return value, nil
+}
+
+// newTestSolver は、fakeDuckDNS に接続する Solver を作成します。
+func newTestSolver(t *testing.T, secrets SecretReader, fallback string) (*Solver, *fakeDuckDNS) {
+	t.Helper()
+	fake := &fakeDuckDNS{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.queries = append(fake.queries, r.URL.Query())
+		fake.mu.Unlock()
+		w.Write([]byte("OK"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := duckdns.NewClientWithOptions(&http.Client{}, server.URL, duckdns.RetryConfig{})
+	return NewSolver(client, secrets, map[string]string{"home": "token-config"}, fallback), fake
+}
+
+// TestSolver_Solve は、Present で TXT レコードを書き込み、CleanUp で消去することをテストします。
+func TestSolver_Solve(t *testing.T) {
+	s, fake := newTestSolver(t, nil, "")
+	req := &ChallengeRequest{Action: ActionPresent, ResolvedFQDN: "_acme-challenge.home.duckdns.org.", Key: "abc"}
+	if err := s.Solve(context.Background(), req); err != nil {
+		t.Fatalf("Solve(Present) error = %v", err)
+	}
+	req.Action = ActionCleanUp
+	if err := s.Solve(context.Background(), req); err != nil {
+		t.Fatalf("Solve(CleanUp) error = %v", err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Fatalf("2回送るべき: %v", fake.queries)
+	}
+	if q := fake.queries[0]; q.Get("domains") != "home" || q.Get("token") != "token-config" || q.Get("txt") != "abc" {
+		t.Errorf("Present では TXT レコードを書き込むべき: %v", q)
+	}
+	if q := fake.queries[1]; q.Get("clear") != "true" || !q.Has("txt") {
+		t.Errorf("CleanUp では TXT レコードを消去するべき: %v", q)
+	}
+
+	if err := s.Solve(context.Background(), &ChallengeRequest{Action: "Unknown", ResolvedFQDN: "home.duckdns.org."}); err == nil {
+		t.Error("不明な操作はエラーを返すべき")
+	}
+	if err := s.Solve(context.Background(), &ChallengeRequest{Action: ActionPresent, ResolvedFQDN: "_acme-challenge.example.com."}); err == nil {
+		t.Error("DuckDNS ではないドメインはエラーを返すべき")
+	}
+}
+
+// TestSolver_Token は、トークンを tokenSecretRef、設定ファイル、既定のトークンの順に選ぶことをテストします。
+func TestSolver_Token(t *testing.T) {
+	secrets := fakeSecrets{"cert/duckdns/token": "token-secret", "cert/duckdns/empty": ""}
+	withRef := func(key string) *SolverConfig {
+		return &SolverConfig{TokenSecretRef: &kube.SecretKeySelector{Name: "duckdns", Key: key}}
+	}
+
+	tests := []struct {
+		name     string
+		secrets  SecretReader
+		fallback string
+		domain   string
+		config   *SolverConfig
+		want     string
+		wantErr  bool
+	}{
+		{"tokenSecretRef", secrets, "", "home", withRef(""), "token-secret", false},
+		{"設定ファイル", secrets, "token-default", "home", nil, "token-config", false},
+		{"既定のトークン", secrets, "token-default", "other", nil, "token-default", false},
+		{"トークンがない", secrets, "", "other", nil, "", true},
+		{"Secret のキーが空", secrets, "", "home", withRef("empty"), "", true},
+		{"Secret がない", secrets, "", "home", withRef("missing"), "", true},
+		{"API サーバーに接続していない", nil, "token-default", "home", withRef(""), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestSolver(t, tt.secrets, tt.fallback)
+			got, err := s.token(context.Background(), &ChallengeRequest{ResourceNamespace: "cert", Config: tt.config}, tt.domain)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("token() = %q, %v (期待値 %q, エラー %v)", got, err, tt.want, tt.wantErr)
+			}
+			if tt.want == "" && tt.config == nil && !errors.Is(err, ErrNoToken) {
+				t.Errorf("ErrNoToken を返すべき: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package certmanager
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// maxRequestSize は、API サーバーから受け取るリクエストボディの最大サイズです。
+const maxRequestSize = 1 << 20
+
+// NewHandler は、cert-manager の webhook ソルバーの API を提供する HTTP ハンドラーを作成します。
+// API サーバーは、APIService で集約した API（/apis/<groupName>/v1alpha1）へのリクエストをこのハンドラーに転送します。
+//
+//   - GET  /apis/<groupName>                     : API グループの情報を返します
+//   - GET  /apis/<groupName>/v1alpha1            : API のリソース（SolverName）の一覧を返します
+//   - POST /apis/<groupName>/v1alpha1/<SolverName> : ChallengePayload の依頼の操作をして、結果を返します
+//   - GET  /healthz                              : 動いているかどうかを返します
+//
+// Parameters:
+//   - groupName: Issuer の solvers[].dns01.webhook.groupName に指定する API グループ
+//   - s: 依頼の操作をする Solver
+//
+// Returns:
+//   - http.Handler: HTTP ハンドラー
+func NewHandler(groupName string, s *Solver) http.Handler {
+	mux := http.NewServeMux()
+	groupVersion := groupName + "/" + Version
+	versionInfo := map[string]string{"groupVersion": groupVersion, "version": Version}
+
+	mux.HandleFunc("GET /apis/"+groupName, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"kind":             "APIGroup",
+			"apiVersion":       "v1",
+			"name":             groupName,
+			"versions":         []map[string]string{versionInfo},
+			"preferredVersion": versionInfo,
+		})
+	})
+
+	mux.HandleFunc("GET /apis/"+groupVersion, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"kind":         "APIResourceList",
+			"apiVersion":   "v1",
+			"groupVersion": groupVersion,
+			"resources": []map[string]any{{
+				"name":         SolverName,
+				"singularName": SolverName,
+				"namespaced":   false,
+				"kind":         payloadKind,
+				"verbs":        []string{"create"},
+			}},
+		})
+	})
+
+	mux.HandleFunc("POST /apis/"+groupVersion+"/"+SolverName, func(w http.ResponseWriter, r *http.Request) {
+		var payload ChallengePayload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&payload); err != nil || payload.Request == nil {
+			message := "リクエストに request がありません"
+			if err != nil {
+				message = "リクエストの形式が不正です: " + err.Error()
+			}
+			writeJSON(w, http.StatusBadRequest, &Status{Kind: "Status", APIVersion: "v1", Status: "Failure", Message: message, Reason: "BadRequest", Code: http.StatusBadRequest})
+			return
+		}
+
+		req := payload.Request
+		response := &ChallengeResponse{UID: req.UID, Success: true}
+		if err := s.Solve(r.Context(), req); err != nil {
+			slog.Error("cert-manager: チャレンジの TXT レコードを更新できませんでした",
+				"action", req.Action,
+				"fqdn", req.ResolvedFQDN,
+				"namespace", req.ResourceNamespace,
+				"error", err,
+			)
+			response = &ChallengeResponse{
+				UID:     req.UID,
+				Success: false,
+				Result:  &Status{Kind: "Status", APIVersion: "v1", Status: "Failure", Message: err.Error(), Reason: "InternalError", Code: http.StatusInternalServerError},
+			}
+		} else {
+			slog.Info("cert-manager: チャレンジの TXT レコードを更新しました",
+				"action", req.Action,
+				"fqdn", req.ResolvedFQDN,
+				"dns_name", req.DNSName,
+			)
+		}
+		writeJSON(w, http.StatusCreated, &ChallengePayload{APIVersion: payloadAPIVersion, Kind: payloadKind, Response: response})
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+// RequireClientCert は、/healthz 以外へのリクエストに、検証済みのクライアント証明書を求めます。
+// API サーバーは、requestheader-client-ca-file の CA で署名された証明書で接続するため、
+// TLS の設定（ClientCAs と tls.VerifyClientCertIfGiven）と組み合わせて、API サーバー以外からの依頼を拒否します。
+//
+// Parameters:
+//   - next: 許可したリクエストを処理するハンドラー
+//   - allowedNames: 許可する証明書の CommonName（空の場合は CA で検証できた証明書をすべて許可します）
+//
+// Returns:
+//   - http.Handler: HTTP ハンドラー
+func RequireClientCert(next http.Handler, allowedNames []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "クライアント証明書が必要です", http.StatusUnauthorized)
+			return
+		}
+		if len(allowedNames) > 0 && !slices.Contains(allowedNames, r.TLS.VerifiedChains[0][0].Subject.CommonName) {
+			http.Error(w, "許可されていないクライアント証明書です", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON は、v を JSON で書き込みます。
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("cert-manager: レスポンスを書き込めませんでした", "error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package certmanager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandler は、webhook ソルバーの API の各エンドポイントをテストします。
+func TestHandler(t *testing.T) {
+	s, fake := newTestSolver(t, nil, "")
+	server := httptest.NewServer(NewHandler(DefaultGroupName, s))
+	defer server.Close()
+	base := server.URL + "/apis/" + DefaultGroupName + "/" + Version
+
+	resp, err := http.Get(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resources struct {
+		Resources []struct {
+			Name  string   `json:"name"`
+			Verbs []string `json:"verbs"`
+		} `json:"resources"`
+	}
+	json.NewDecoder(resp.Body).Decode(&resources)
+	resp.Body.Close()
+	if len(resources.Resources) != 1 || resources.Resources[0].Name != SolverName {
+		t.Errorf("リソースの一覧を返すべき: %+v", resources)
+	}
+
+	body := `{"apiVersion":"webhook.acme.cert-manager.io/v1alpha1","kind":"ChallengePayload","request":{"uid":"u1","action":"Present","resolvedFQDN":"_acme-challenge.home.duckdns.org.","key":"abc"}}`
+	resp, err = http.Post(base+"/"+SolverName, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload ChallengePayload
+	json.NewDecoder(resp.Body).Decode(&payload)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || payload.Response == nil || !payload.Response.Success || payload.Response.UID != "u1" {
+		t.Errorf("成功した結果を返すべき: %d %+v", resp.StatusCode, payload.Response)
+	}
+	if len(fake.queries) != 1 || fake.queries[0].Get("txt") != "abc" {
+		t.Errorf("TXT レコードを書き込むべき: %v", fake.queries)
+	}
+
+	// トークンがない場合は、失敗の理由を結果に入れて返します
+	body = `{"request":{"uid":"u2","action":"Present","resolvedFQDN":"_acme-challenge.other.duckdns.org.","key":"abc"}}`
+	resp, err = http.Post(base+"/"+SolverName, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload = ChallengePayload{}
+	json.NewDecoder(resp.Body).Decode(&payload)
+	resp.Body.Close()
+	if payload.Response == nil || payload.Response.Success || payload.Response.Result == nil || payload.Response.Result.Message == "" {
+		t.Errorf("失敗の理由を返すべき: %+v", payload.Response)
+	}
+
+	resp, err = http.Post(base+"/"+SolverName, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("request がない場合は 400 を返すべき: %d", resp.StatusCode)
+	}
+}
+
+// TestRequireClientCert は、検証済みのクライアント証明書がないリクエストを拒否することをテストします。
+func TestRequireClientCert(t *testing.T) {
+	handler := RequireClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), []string{"front-proxy-client"})
+
+	withCert := func(name string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/apis/"+DefaultGroupName+"/"+Version+"/"+SolverName, nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"許可された証明書", withCert("front-proxy-client"), http.StatusOK},
+		{"許可されていない証明書", withCert("someone"), http.StatusForbidden},
+		{"証明書なし", httptest.NewRequest(http.MethodPost, "/apis/"+DefaultGroupName, nil), http.StatusUnauthorized},
+		{"/healthz は証明書なしで許可", httptest.NewRequest(http.MethodGet, "/healthz", nil), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.want {
+				t.Errorf("ステータス = %d (期待値 %d)", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -3,13 +3,26 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/httpclient"
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/leader"
+	"github.com/horitaku/duckdns/internal/mqtt"
 )
 
 // Config は、DuckDNS自動更新プログラムの全体設定を保持する構造体です。
@@ -22,10 +35,61 @@ type Config struct {
 	Update UpdateConfig `yaml:"update"`
 
 	// IPSources は、グローバルIPアドレスを取得するためのURLリストです
-	IPSources []string `yaml:"ip_sources"`
+	IPSources IPSources `yaml:"ip_sources"`
+
+	// IPv6Sources は、IPv6アドレスを取得するためのURLリストです（update.ipv6 が true の場合に使用）
+	// 省略した場合は、既定の IPv6 のソースを使用します
+	IPv6Sources IPSources `yaml:"ipv6_sources,omitempty"`
+
+	// IPFetch は、IP取得ソースへの問い合わせ方の設定を保持します
+	IPFetch IPFetchConfig `yaml:"ip_fetch,omitempty"`
 
 	// Log は、ログ出力の設定を保持します
 	Log LogConfig `yaml:"log"`
+
+	// Timezone は、ログやステータスの時刻を表示するタイムゾーンです（IANA の名前。例: "Asia/Tokyo"）
+	// 省略した場合は、ホストのローカルタイム（環境変数 TZ や /etc/localtime）を使用します
+	Timezone string `yaml:"timezone,omitempty"`
+
+	// Network は、DuckDNS への更新と IP取得の通信設定（プロキシなど）を保持します
+	Network NetworkConfig `yaml:"network,omitempty"`
+
+	// ReleaseCheck は、新しいバージョンのリリースを定期的に確認する設定です（省略した場合は確認しません）
+	ReleaseCheck ReleaseCheckConfig `yaml:"release_check,omitempty"`
+
+	// Trigger は、ルーターなどから dyndns2 互換のリクエストで更新を求められるようにする設定です（省略した場合は待ち受けません）
+	Trigger TriggerConfig `yaml:"trigger,omitempty"`
+
+	// LeaderElection は、複数のインスタンスを動かすときに1つだけが更新するようにするリーダー選出の設定です（省略した場合はすべてのインスタンスが更新します）
+	LeaderElection LeaderElectionConfig `yaml:"leader_election,omitempty"`
+
+	// State は、更新状況と更新履歴を保存する場所の設定です（省略した場合は状態ファイルに保存します）
+	State StateConfig `yaml:"state,omitempty"`
+
+	// MQTT は、更新状況を MQTT ブローカーに送る設定です（省略した場合は送りません）
+	MQTT MQTTConfig `yaml:"mqtt,omitempty"`
+
+	// Metrics は、更新状況を Prometheus のメトリクスとして公開する設定です（省略した場合は公開しません）
+	Metrics MetricsConfig `yaml:"metrics,omitempty"`
+
+	// Gops は、gops コマンドから接続できる診断用のエージェントの設定です（省略した場合は起動しません）
+	Gops GopsConfig `yaml:"gops,omitempty"`
+
+	// CrashReport は、パニックから回復したときに書き出すクラッシュレポートの設定です
+	CrashReport CrashReportConfig `yaml:"crash_report,omitempty"`
+
+	// Providers は、DuckDNS 以外の DDNS プロバイダーの設定です
+	// DuckDNS と併用でき、providers だけを設定する場合は duckdns セクションを省略できます
+	Providers []ProviderConfig `yaml:"providers,omitempty"`
+
+	// Profiles は、名前付きのプロファイルです
+	// 選択されたプロファイルの内容は、共通の設定に再帰的にマージされます
+	Profiles map[string]*Config `yaml:"profiles,omitempty"`
+
+	// Include は、追加で読み込んでマージするYAMLファイルのリストです
+	// ファイル、ディレクトリ（conf.d 形式）、glob パターンを指定できます
+	// 相対パスは、include を記述したファイルのディレクトリを基準に解決されます
+	Include []string `yaml:"include,omitempty"`
 }
 
 // DuckDNSConfig は、DuckDNSサービスへの認証情報を保持する構造体です。
@@ -36,6 +100,115 @@ type DuckDNSConfig struct {
 	// Token は、DuckDNS APIの認証トークンです
 	// 環境変数 DUCKDNS_TOKEN からの読み込みを推奨します
 	Token string `yaml:"token"`
+
+	// Domains は、複数ドメインを更新する場合のドメインごとの設定です
+	// 各エントリは、トークン・更新間隔・IP取得ソースを個別に上書きできます
+	Domains []DomainConfig `yaml:"domains,omitempty"`
+
+	// Accounts は、別の DuckDNS アカウントのドメインも更新する場合の、トークンとドメインの組です
+	// 各アカウントのドメインは、そのアカウントのトークンでまとめて更新します
+	Accounts []AccountConfig `yaml:"accounts,omitempty"`
+
+	// RateLimit は、DuckDNS API へのリクエストの頻度の上限です（すべてのドメインで共有）
+	// 省略した項目には既定値（1分あたり30回）が使用されます
+	RateLimit RateLimitConfig `yaml:"rate_limit,omitempty"`
+
+	// CircuitBreaker は、DuckDNS の障害が続いた場合にリクエストを止める設定です
+	// 省略した項目には既定値（連続5回の失敗で5分間）が使用されます
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+
+	// AddressFamily は、DuckDNS への更新のリクエストを送るアドレスファミリーです（"ipv4" または "ipv6"）
+	// IPアドレスを省略して DuckDNS に送信元のアドレスを登録させる場合に、デュアルスタックのホストで使います
+	// 省略した場合は、先に接続できたほうで送信します
+	AddressFamily string `yaml:"address_family,omitempty"`
+}
+
+// CircuitBreakerConfig は、サーキットブレーカーの設定を保持する構造体です。
+type CircuitBreakerConfig struct {
+	// FailureThreshold は、サーキットを開くまでの連続失敗回数です
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+
+	// Cooldown は、サーキットを開いてから再び問い合わせるまでの時間です（例: "5m"）
+	Cooldown time.Duration `yaml:"cooldown,omitempty"`
+}
+
+// RateLimitConfig は、API へのリクエストの頻度の上限を保持する構造体です。
+// 期間（per）あたり requests 回までリクエストでき、超えた分は待ってから送信します。
+type RateLimitConfig struct {
+	// Requests は、期間あたりのリクエスト数の上限です
+	Requests int `yaml:"requests,omitempty"`
+
+	// Per は、リクエスト数を数える期間です（例: "1m"）
+	Per time.Duration `yaml:"per,omitempty"`
+}
+
+// AccountConfig は、1つの DuckDNS アカウントのトークンと、そのトークンで更新するドメインの組です。
+// 家族のアカウントのドメインなど、持ち主の異なるドメインを1つのデーモンで更新する場合に使用します。
+type AccountConfig struct {
+	// Name は、ログに出力するアカウントの名前です（省略可）
+	Name string `yaml:"name,omitempty"`
+
+	// Token は、このアカウントの DuckDNS APIトークンです
+	Token string `yaml:"token"`
+
+	// Domains は、このアカウントで更新するドメイン名です
+	// IP取得ソースは、トップレベルの設定を使用します
+	Domains []string `yaml:"domains"`
+
+	// Interval は、このアカウントのドメインの更新チェック間隔です（省略時は update.interval）
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// DomainConfig は、ドメインごとの設定を保持する構造体です。
+// 空の項目は、トップレベルの設定値を引き継ぎます。
+type DomainConfig struct {
+	// Name は、更新するDuckDNSのドメイン名です
+	Name string `yaml:"name"`
+
+	// Token は、このドメインで使用するDuckDNS APIトークンです（省略時は duckdns.token）
+	Token string `yaml:"token,omitempty"`
+
+	// Interval は、このドメインの更新チェック間隔です（省略時は update.interval）
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	// IPSources は、このドメインで使用するIP取得ソースです（省略時は ip_sources）
+	IPSources IPSources `yaml:"ip_sources,omitempty"`
+
+	// IPv6 は、このドメインで IPv6アドレスも更新するかどうかです（省略時は update.ipv6）
+	IPv6 *bool `yaml:"ipv6,omitempty"`
+
+	// IPv6Sources は、このドメインで使用する IPv6アドレスの取得ソースです（省略時は ipv6_sources）
+	IPv6Sources IPSources `yaml:"ipv6_sources,omitempty"`
+
+	// account は、duckdns.accounts から展開したドメインの場合のアカウントの名前です
+	account string
+}
+
+// Target は、ドメインごとの上書きを反映した、実際に更新する対象の設定です。
+type Target struct {
+	// Domain は、更新するドメイン名です
+	Domain string
+
+	// Token は、DuckDNS APIトークンです（プロバイダーの場合は空）
+	Token string
+
+	// Account は、duckdns.accounts のドメインの場合のアカウントの名前です（それ以外は空）
+	Account string
+
+	// Interval は、更新チェック間隔です
+	Interval time.Duration
+
+	// IPSources は、IP取得ソースのリストです
+	IPSources IPSources
+
+	// Provider は、DuckDNS 以外のプロバイダーの設定です（DuckDNS の場合は nil）
+	Provider *ProviderConfig
+
+	// IPv6 は、IPv4 に加えて IPv6アドレス（AAAA レコード）も更新するかどうかです
+	IPv6 bool
+
+	// IPv6Sources は、IPv6アドレスの取得ソースのリストです（空の場合は既定のソース）
+	IPv6Sources IPSources
 }
 
 // UpdateConfig は、DNS更新の実行間隔に関する設定を保持する構造体です。
@@ -43,6 +216,359 @@ type UpdateConfig struct {
 	// Interval は、IPアドレスのチェックと更新を実行する間隔です
 	// フォーマット例: "5m", "1h", "30s"
 	Interval time.Duration `yaml:"interval"`
+
+	// IPv6 は、IPv4 に加えて IPv6アドレスも更新するかどうかです
+	// DuckDNS では ip と ipv6 を1回のリクエストで送信し、A と AAAA がずれないようにします
+	IPv6 bool `yaml:"ipv6,omitempty"`
+
+	// Retry は、更新に失敗した場合のリトライの設定です
+	// 省略した項目には既定値（最大3回、1s から2倍ずつ）が使用されます
+	Retry RetryConfig `yaml:"retry,omitempty"`
+
+	// Workers は、すべてのドメインで同時に実行するIPアドレスの取得とプロバイダーの更新の数の上限です（省略時は 8）
+	// ドメインが多い場合でも、小さな機器で goroutine や接続が増えすぎないようにします
+	Workers int `yaml:"workers,omitempty"`
+
+	// WaitForNetwork は、起動してから最初のチェックの前に、ネットワークにつながるまで待つ時間の上限です（例: "2m"）
+	// 起動直後にネットワークの準備ができておらず、最初のチェックが失敗するのを避けます。省略した場合は待ちません
+	WaitForNetwork time.Duration `yaml:"wait_for_network,omitempty"`
+
+	// OfflineRetryInterval は、通信できずに更新できなかった場合に、ネットワークにつながったかどうかを確認する間隔です（例: "30s"）
+	// つながりしだい、次の定期チェックを待たずに保留中の更新を再試行します。省略した場合は次の定期チェックまで待ちます
+	OfflineRetryInterval time.Duration `yaml:"offline_retry_interval,omitempty"`
+
+	// Precheck は、チェックのたびに IPアドレスを取得する前に接続を確認する方法です
+	// "http"（network.probe_url に HEAD リクエスト）または "dns"（network.probe_url のホスト名を名前解決）です
+	// 確認に失敗した場合は、IP取得ソースやプロバイダーに問い合わせずにオフラインとして記録します。省略した場合は確認しません
+	Precheck string `yaml:"precheck,omitempty"`
+
+	// ShutdownGrace は、停止するとき（SIGTERM など）に、実行中のチェックと更新が終わるまで待つ時間の上限です（例: "10s"）
+	// 更新のリクエストが途中で切られないようにします。省略した場合はすぐに中断します
+	ShutdownGrace time.Duration `yaml:"shutdown_grace,omitempty"`
+
+	// OnShutdown は、停止するとき（SIGTERM など）に、レコードや通知先に対して実行する処理の設定です
+	// 使わなくなる IPアドレスをレコードが指したままにならないようにします。省略した場合は何もしません
+	OnShutdown ShutdownActionConfig `yaml:"on_shutdown,omitempty"`
+
+	// Timeouts は、IPアドレスの取得・更新・接続の確認・フックの段階ごとのタイムアウトです
+	// 回線が遅い環境で、段階ごとに待つ時間を変えられます。省略した段階は既定のタイムアウトを使います
+	Timeouts TimeoutsConfig `yaml:"timeouts,omitempty"`
+
+	// AutoDetect は、IP取得ソースに問い合わせずに、ip を省略した更新で DuckDNS にリクエストの送信元のアドレスを登録させるかどうかです
+	// 送信元のアドレスが登録したいアドレスの場合に、もっとも簡単に設定できます。DuckDNS のドメインだけで使用できます
+	// 変化したかどうかは更新するまでわからないため、チェックのたびに更新のリクエストを送ります
+	AutoDetect bool `yaml:"auto_detect,omitempty"`
+
+	// CrossCheck は、auto_detect で DuckDNS が検出したアドレスを IP取得ソースのアドレスと照らし合わせ、
+	// 続けて一致しなかった場合に警告するまでの回数です。プロキシや VPN の出口、CGNAT に気づけるようにします
+	// 省略した場合（0）は照らし合わせません
+	CrossCheck int `yaml:"cross_check,omitempty"`
+
+	// FailureAlert は、ドメインごとのチェックと更新に続けて失敗したときに、警告と通知をする設定です
+	// IPアドレスが変わらなくても、更新が黙って止まっていることに気づけるようにします。省略した場合は知らせません
+	FailureAlert FailureAlertConfig `yaml:"failure_alert,omitempty"`
+
+	// Digest は、1日ごとの IP アドレスの変化・失敗・動き続けている時間のまとめを知らせる設定です
+	// 1回ごとに知らせなくても、動き続けていることを確かめられるようにします。省略した場合は知らせません
+	Digest DigestConfig `yaml:"digest,omitempty"`
+
+	// Schedule は、定期チェックを実行する時間帯の一覧です（"HH:MM-HH:MM" の形式、timezone のタイムゾーン）
+	// 指定した場合は、どれかの時間帯に入っている間だけ定期チェックを実行します。省略した場合は終日実行します
+	Schedule []string `yaml:"schedule,omitempty"`
+
+	// QuietHours は、定期チェックを実行しない時間帯の一覧です（"HH:MM-HH:MM" の形式、timezone のタイムゾーン）
+	// 日付をまたぐ時間帯（例: "23:00-06:00"）も指定できます。schedule と重なった場合は、こちらを優先します
+	QuietHours []string `yaml:"quiet_hours,omitempty"`
+
+	// Watchdog は、定期チェックの間隔の何倍の時間チェックと更新が終わらなかったら、中断してループを始め直すかです
+	// タイムアウトを設定していても通信が止まってしまった場合に、更新が止まったままにならないようにします
+	// 省略した場合（0）は見張りません。指定する場合は 2 以上です
+	Watchdog int `yaml:"watchdog,omitempty"`
+}
+
+// FailureAlertConfig は、チェックと更新に続けて失敗したときに知らせる設定を保持する構造体です。
+type FailureAlertConfig struct {
+	// Threshold は、知らせるまでに続けて失敗するチェックの回数です（省略した場合（0）は知らせません）
+	// 事前の接続の確認に失敗してスキップしたチェックは数えません
+	Threshold int `yaml:"threshold,omitempty"`
+
+	// Webhook は、続けて失敗したときと、そのあと復旧したときに JSON を POST する URL です（省略した場合はログに出力するだけです）
+	Webhook string `yaml:"webhook,omitempty"`
+}
+
+// DigestTimeFormat は、update.digest.at と、update.schedule・update.quiet_hours の時間帯の時刻の形式です。
+const DigestTimeFormat = "15:04"
+
+// DigestConfig は、1日ごとのまとめ（ダイジェスト）を知らせる設定を保持する構造体です。
+type DigestConfig struct {
+	// Webhook は、1日ごとのまとめを JSON で POST する URL です（省略した場合は知らせません）
+	Webhook string `yaml:"webhook,omitempty"`
+
+	// At は、まとめを知らせる時刻です（"HH:MM" の形式、timezone のタイムゾーン）
+	// 省略した場合は、起動してから24時間ごとに知らせます
+	At string `yaml:"at,omitempty"`
+}
+
+// TimeOfDay は、まとめを知らせる時刻の時と分を返します。
+//
+// Returns:
+//   - int: 時
+//   - int: 分
+//   - bool: 時刻を指定した場合に true
+func (d DigestConfig) TimeOfDay() (int, int, bool) {
+	t, err := time.Parse(DigestTimeFormat, d.At)
+	if d.At == "" || err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// ParseTimeWindow は、"HH:MM-HH:MM" の形式の時間帯（update.schedule と update.quiet_hours）を解析します。
+// 終わりには、1日の終わりを表す "24:00" も指定できます。
+//
+// Parameters:
+//   - s: 解析する時間帯
+//
+// Returns:
+//   - time.Duration: 時間帯の始まりの、0時からの時間
+//   - time.Duration: 時間帯の終わりの、0時からの時間
+//   - error: 形式が不正な場合や、始まりと終わりが同じ時刻の場合
+func ParseTimeWindow(s string) (time.Duration, time.Duration, error) {
+	startText, endText, _ := strings.Cut(strings.TrimSpace(s), "-")
+	start, startErr := parseTimeOfDay(strings.TrimSpace(startText), false)
+	end, endErr := parseTimeOfDay(strings.TrimSpace(endText), true)
+	if startErr != nil || endErr != nil {
+		return 0, 0, fmt.Errorf("時間帯 \"%s\" は HH:MM-HH:MM の形式で指定してください (例: \"23:00-06:00\")", s)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("時間帯 \"%s\" の始まりと終わりが同じ時刻です。終日の場合は \"00:00-24:00\" を指定してください", s)
+	}
+	return start, end, nil
+}
+
+// parseTimeOfDay は、"HH:MM" の形式の時刻を、0時からの時間に変換します（内部用ヘルパー関数）
+// allowEnd が true の場合は、1日の終わりを表す "24:00" も受け付けます。
+func parseTimeOfDay(s string, allowEnd bool) (time.Duration, error) {
+	if allowEnd && s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse(DigestTimeFormat, s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// TimeoutsConfig は、段階ごとのタイムアウトの設定を保持する構造体です。
+// 指定した段階は、その段階のコンテキストの期限として適用します。
+type TimeoutsConfig struct {
+	// IPFetch は、IPアドレスの取得（ソースへのフォールバックを含む）のタイムアウトです（例: "30s"）
+	// IP取得ソースへの1回の問い合わせのタイムアウトも同じ値にします。省略した場合は、ソースごとに 10s です
+	IPFetch time.Duration `yaml:"ip_fetch,omitempty"`
+
+	// Update は、DNS レコードの1回の更新（リトライを含む）のタイムアウトです（例: "1m"）
+	// DuckDNS への1回のリクエストのタイムアウトも同じ値にします。省略した場合は、リクエストごとに 10s です
+	Update time.Duration `yaml:"update,omitempty"`
+
+	// Probe は、接続の確認（update.precheck と、保留中の更新を再試行する前の確認）の1回のタイムアウトです
+	// precheck が "dns" の場合は、名前解決のタイムアウトです。省略した場合は 5s です
+	Probe time.Duration `yaml:"probe,omitempty"`
+
+	// Hook は、exec プロバイダーのプラグインと、停止するときの処理（update.on_shutdown）のタイムアウトの既定値です
+	// それぞれの timeout を指定した場合は、そちらを使います。省略した場合は、exec が 30s、停止するときの処理が 10s です
+	Hook time.Duration `yaml:"hook,omitempty"`
+}
+
+// ShutdownTimeout は、停止するときの処理のタイムアウトを返します。
+// update.on_shutdown.timeout、update.timeouts.hook、DefaultShutdownActionTimeout の順に使います。
+func (u UpdateConfig) ShutdownTimeout() time.Duration {
+	if u.OnShutdown.Timeout <= 0 && u.Timeouts.Hook > 0 {
+		return u.Timeouts.Hook
+	}
+	return u.OnShutdown.TimeoutOrDefault()
+}
+
+// 停止するときに実行する処理の種類です。
+const (
+	// ShutdownActionNone は、何もしないことを表します（デフォルト）
+	ShutdownActionNone = "none"
+
+	// ShutdownActionClear は、DuckDNS のレコードを消去することを表します（DuckDNS 以外のプロバイダーは対象外）
+	ShutdownActionClear = "clear"
+
+	// ShutdownActionSetIP は、すべてのドメインのレコードを ip に更新することを表します
+	ShutdownActionSetIP = "set_ip"
+
+	// ShutdownActionWebhook は、webhook の URL に停止したことを JSON で POST することを表します
+	ShutdownActionWebhook = "webhook"
+)
+
+// DefaultShutdownActionTimeout は、停止するときの処理のタイムアウトを省略した場合の値です。
+const DefaultShutdownActionTimeout = 10 * time.Second
+
+// ShutdownActionConfig は、停止するときに実行する処理の設定を保持する構造体です。
+type ShutdownActionConfig struct {
+	// Action は、実行する処理です（"none", "clear", "set_ip", "webhook"。省略した場合は "none"）
+	Action string `yaml:"action,omitempty"`
+
+	// IP は、action が "set_ip" の場合にレコードに登録する IPv4アドレスです（例: "192.0.2.1"）
+	IP string `yaml:"ip,omitempty"`
+
+	// Webhook は、action が "webhook" の場合に通知する URL です
+	Webhook string `yaml:"webhook,omitempty"`
+
+	// Timeout は、処理全体のタイムアウトです（省略時は 10s）
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// ActionName は、小文字にした処理の種類を返します（省略した場合は "none"）。
+func (s ShutdownActionConfig) ActionName() string {
+	action := strings.ToLower(strings.TrimSpace(s.Action))
+	if action == "" {
+		return ShutdownActionNone
+	}
+	return action
+}
+
+// TimeoutOrDefault は、処理全体のタイムアウトを返します（省略した場合は DefaultShutdownActionTimeout）。
+func (s ShutdownActionConfig) TimeoutOrDefault() time.Duration {
+	if s.Timeout <= 0 {
+		return DefaultShutdownActionTimeout
+	}
+	return s.Timeout
+}
+
+// RetryConfig は、更新のリトライの回数と待ち時間の設定を保持する構造体です。
+// n 回目の失敗の後は initial_interval × multiplier^(n-1) だけ待ち、max_interval を上限とします。
+type RetryConfig struct {
+	// MaxRetries は、最初の試行に加えてリトライする最大回数です
+	MaxRetries int `yaml:"max_retries,omitempty"`
+
+	// InitialInterval は、最初の失敗の後の待ち時間です（例: "1s"）
+	InitialInterval time.Duration `yaml:"initial_interval,omitempty"`
+
+	// Multiplier は、失敗するたびに待ち時間を増やす倍率です（1 以上）
+	Multiplier float64 `yaml:"multiplier,omitempty"`
+
+	// MaxInterval は、待ち時間の上限です（例: "30s"）
+	MaxInterval time.Duration `yaml:"max_interval,omitempty"`
+
+	// Jitter は、待ち時間をランダムにずらす割合です（0〜1。0.2 の場合は ±20%）
+	Jitter float64 `yaml:"jitter,omitempty"`
+
+	// MaxElapsedTime は、最初の試行からリトライをあきらめるまでの時間です（例: "2m"）
+	MaxElapsedTime time.Duration `yaml:"max_elapsed_time,omitempty"`
+}
+
+// IsZero は、リトライの設定が省略されているかどうかを返します。
+func (r RetryConfig) IsZero() bool {
+	return r == RetryConfig{}
+}
+
+// IPFetchConfig は、IP取得ソースへの問い合わせ方の設定を保持する構造体です。
+type IPFetchConfig struct {
+	// CacheTTL は、最後に取得できたIPアドレスを、IP取得ソースに問い合わせずに使う時間です（例: "30s"）
+	// 短い間隔で続けて更新しても、外部のサービスに毎回問い合わせないようにします。省略した場合はキャッシュしません
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+
+	// Strategy は、最初に問い合わせる IP取得ソースの選び方です
+	// "sequential"（いつも先頭から、既定値）、"round_robin"（1つずつずらす）、"random"（weight の重みでランダム）、
+	// "fastest"（直近の応答時間の中央値が短い順）のいずれかです
+	// 特定のサービスに問い合わせが集中しないようにします。どの選び方でも、失敗したら残りのソースを試します
+	Strategy string `yaml:"strategy,omitempty"`
+
+	// FailureThreshold は、IP取得ソースをしばらく使わないようにするまでの連続失敗回数です（省略時は 3）
+	// 応答しないソースに、更新チェックのたびにタイムアウトまで待たないようにします
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+
+	// Cooldown は、続けて失敗した IP取得ソースを使わない時間です（省略時は 10分）
+	Cooldown time.Duration `yaml:"cooldown,omitempty"`
+
+	// Suspicious は、取得した IPアドレスが疑わしい場合の扱いです
+	// "warn"（警告ログを出してそのまま使う、既定値）、"reject"（使わずに次のソースを試す）、"ignore"（確認しない）のいずれかです
+	// LAN や CGNAT のアドレスと、cross_check で別のソースと大きく異なるアドレスを疑わしいとみなします
+	// キャプティブポータルなどが返す HTML は、この設定に関係なく使いません
+	Suspicious string `yaml:"suspicious,omitempty"`
+
+	// CrossCheck は、取得した IPアドレスを別の IP取得ソースの結果とも比べるかどうかです
+	// IPv4 は /16、IPv6 は /48 が異なる場合に疑わしいとみなします。問い合わせが1回増えます
+	CrossCheck bool `yaml:"cross_check,omitempty"`
+}
+
+// NetworkConfig は、DuckDNS への更新と IP取得の通信設定を保持する構造体です。
+type NetworkConfig struct {
+	// Proxy は、すべてのリクエストで使うプロキシの URL です
+	// 例: "http://proxy.example.com:8080", "socks5://127.0.0.1:9050"
+	// 省略した場合は環境変数 HTTP_PROXY / HTTPS_PROXY / NO_PROXY に従い、"direct" の場合はプロキシを使いません
+	Proxy string `yaml:"proxy,omitempty"`
+
+	// Resolvers は、接続先の名前解決に使う DNS サーバーのアドレスです（例: "1.1.1.1:53"）
+	// ローカルのリゾルバーが壊れていても更新できるようにします。省略した場合はシステムのリゾルバーを使います
+	Resolvers []string `yaml:"resolvers,omitempty"`
+
+	// DNSFallback は、名前解決に失敗した場合に DNS over HTTPS と、最後に接続できたアドレスで接続し直すかどうかです
+	DNSFallback bool `yaml:"dns_fallback,omitempty"`
+
+	// DoHServers は、DNSFallback で使う DNS over HTTPS の JSON API の URL です
+	// 省略した場合は Cloudflare（https://1.1.1.1/dns-query）と Google（https://8.8.8.8/resolve）を使います
+	DoHServers []string `yaml:"doh_servers,omitempty"`
+
+	// CAFile は、システムの証明書に加えて信頼する PEM 形式の CA 証明書のファイルのパスです
+	// TLS を復号する社内プロキシや、信頼ストアが古い機器で使います
+	CAFile string `yaml:"ca_file,omitempty"`
+
+	// TLSMinVersion は、TLS の最小バージョンです（"1.0", "1.1", "1.2", "1.3"）
+	// 省略した場合は Go の既定値（TLS 1.2）を使います
+	TLSMinVersion string `yaml:"tls_min_version,omitempty"`
+
+	// IPSourcePins は、IP取得ソースのホスト名ごとにピン留めする証明書の公開鍵です
+	// 値は "sha256/<base64>" 形式の SPKI のハッシュ値で、証明書チェーンのどれかと一致する場合だけ IPアドレスを取得します
+	// 例: {"api.ipify.org": ["sha256/..."]}
+	IPSourcePins map[string][]string `yaml:"ip_source_pins,omitempty"`
+
+	// MaxResponseSize は、DuckDNS と IP取得ソースから読み込むレスポンスボディの最大サイズ（バイト）です
+	// 省略した場合は 64 KiB です
+	MaxResponseSize int64 `yaml:"max_response_size,omitempty"`
+
+	// MaxIdleConns は、再利用するために保持しておくアイドル中の接続の最大数です
+	// メモリの少ない機器では小さくし、更新の間隔が短い場合は大きくします。省略した場合は 100 です
+	MaxIdleConns int `yaml:"max_idle_conns,omitempty"`
+
+	// IdleConnTimeout は、アイドル中の接続を閉じるまでの時間です（例: "30s"）
+	// 省略した場合は 90 秒です
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout,omitempty"`
+
+	// DisableKeepAlives は、接続を保持せずにリクエストごとに接続し直すかどうかです
+	// 更新の間隔が長く、ソケットを保持したくない場合に使います
+	DisableKeepAlives bool `yaml:"disable_keep_alives,omitempty"`
+
+	// ProbeURL は、ネットワークにつながっているかどうかの確認に HEAD リクエストを送る URL です
+	// 応答があればステータスコードに関係なくつながっているとみなします。省略した場合は https://www.duckdns.org/ です
+	ProbeURL string `yaml:"probe_url,omitempty"`
+}
+
+// TransportOptions は、通信設定を httpclient.NewTransport に渡すオプションにします。
+// IP取得ソースのピン留め（IPSourcePins）は含みません。IP取得には IPSourceTransportOptions を使います。
+func (n NetworkConfig) TransportOptions() httpclient.Options {
+	return httpclient.Options{
+		Proxy:             n.Proxy,
+		Resolvers:         n.Resolvers,
+		DNSFallback:       n.DNSFallback,
+		DoHServers:        n.DoHServers,
+		CAFile:            n.CAFile,
+		TLSMinVersion:     n.TLSMinVersion,
+		MaxIdleConns:      n.MaxIdleConns,
+		IdleConnTimeout:   n.IdleConnTimeout,
+		DisableKeepAlives: n.DisableKeepAlives,
+	}
+}
+
+// IPSourceTransportOptions は、IP取得ソースの通信に使うオプションにします。
+// TransportOptions に、IP取得ソースのピン留め（IPSourcePins）を加えます。
+func (n NetworkConfig) IPSourceTransportOptions() httpclient.Options {
+	opts := n.TransportOptions()
+	opts.Pins = n.IPSourcePins
+	return opts
 }
 
 // LogConfig は、ログ出力の形式とレベルに関する設定を保持する構造体です。
@@ -54,12 +580,285 @@ type LogConfig struct {
 	// Format は、ログ出力形式です
 	// 有効な値: "json", "text"
 	Format string `yaml:"format"`
+
+	// File は、ログを追記するファイルのパスです（省略時は標準エラー出力）
+	// サービスマネージャーのない環境で、-detach でバックグラウンドに移るときに使います
+	File string `yaml:"file,omitempty"`
+
+	// SummaryInterval は、動作の要約（チェックの回数・成功と失敗の数・IP アドレスの変化など）を1行のログに出力する間隔です
+	// 長期間のログの中から、動き続けているかどうかを手早く確かめるために使います（省略時は出力しません）
+	SummaryInterval time.Duration `yaml:"summary_interval,omitempty"`
+}
+
+// MinSummaryInterval は、log.summary_interval の下限です。
+const MinSummaryInterval = time.Minute
+
+// 新しいバージョンのリリースを確認する間隔の既定値と下限です。
+const (
+	// DefaultReleaseCheckInterval は、確認する間隔を省略した場合の値です
+	DefaultReleaseCheckInterval = 24 * time.Hour
+
+	// MinReleaseCheckInterval は、GitHub API のレート制限に触れないようにするための、確認する間隔の下限です
+	MinReleaseCheckInterval = time.Hour
+)
+
+// ReleaseCheckConfig は、新しいバージョンのリリースを定期的に確認する設定を保持する構造体です。
+// 確認するだけで、自動ではインストールしません（インストールは self-update コマンドで行います）。
+type ReleaseCheckConfig struct {
+	// Enabled は、常駐しているときに新しいバージョンを確認するかどうかです（省略時は false）
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Interval は、確認する間隔です（省略時は "24h"、"1h" 以上）
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	// Repository は、リリースを確認する GitHub のリポジトリです（"owner/name"。省略時は horitaku/duckdns）
+	Repository string `yaml:"repo,omitempty"`
+}
+
+// IntervalOrDefault は、確認する間隔を返します（省略した場合は DefaultReleaseCheckInterval）。
+func (r ReleaseCheckConfig) IntervalOrDefault() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultReleaseCheckInterval
+	}
+	return r.Interval
+}
+
+// DefaultTriggerListen は、trigger.listen を省略した場合の待ち受けるアドレスです。
+// ルーターから接続されるので、すべてのアドレスで待ち受けます。
+const DefaultTriggerListen = ":8245"
+
+// TriggerConfig は、ルーターなどから dyndns2 互換のリクエスト（GET /nic/update?hostname=&myip=）で
+// 更新を求められるようにする設定を保持する構造体です。
+// WAN のアドレスが変わったときにルーターが通知するため、定期チェックを待たずに更新できます。
+type TriggerConfig struct {
+	// Enabled は、常駐しているときにリクエストを待ち受けるかどうかです（省略時は false）
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Listen は、待ち受けるアドレスです（省略時は ":8245"）
+	Listen string `yaml:"listen,omitempty"`
+
+	// Username は、Basic 認証のユーザー名です（有効にする場合は必須）
+	Username string `yaml:"username,omitempty"`
+
+	// Password は、Basic 認証のパスワードです（有効にする場合は必須）
+	Password string `yaml:"password,omitempty"`
+}
+
+// ListenOrDefault は、待ち受けるアドレスを返します（省略した場合は DefaultTriggerListen）。
+func (t TriggerConfig) ListenOrDefault() string {
+	if t.Listen == "" {
+		return DefaultTriggerListen
+	}
+	return t.Listen
+}
+
+// リーダー選出のリースを保存する場所（leader_election.backend）です。
+const (
+	// LeaderElectionFile は、リースを共有ディレクトリのファイルに保存します
+	LeaderElectionFile = "file"
+
+	// LeaderElectionKubernetes は、リースを Kubernetes の Lease に保存します
+	LeaderElectionKubernetes = "kubernetes"
+)
+
+// GopsConfig は、gops コマンド（github.com/google/gops）から接続できる診断用のエージェントの設定を保持する構造体です。
+// 常駐しているプロセスのゴルーチン・GC の統計・メモリの使用状況を、離れた機器でも確認できるようにするために使います。
+type GopsConfig struct {
+	// Enabled は、常駐しているときにエージェントを起動するかどうかです（省略時は false）
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Listen は、待ち受けるアドレスです（省略時は "127.0.0.1:0"、ループバックアドレスの空いているポート）
+	Listen string `yaml:"listen,omitempty"`
+}
+
+// CrashReportConfig は、チェックと更新の途中でパニックが発生したときに書き出すクラッシュレポートの設定を保持する構造体です。
+// クラッシュレポートには、スタックトレース・ドメインごとの最新の状況・秘密の値を伏せた設定を書き出します。
+type CrashReportConfig struct {
+	// Dir は、クラッシュレポートを書き出すディレクトリです（省略時は状態ファイルの既定のディレクトリの crash）
+	Dir string `yaml:"dir,omitempty"`
+
+	// MaxReports は、残すクラッシュレポートの数です（省略時は 10）
+	MaxReports int `yaml:"max_reports,omitempty"`
+}
+
+// DefaultMetricsListen は、metrics.listen を省略した場合の待ち受けるアドレスです。
+// Prometheus から接続されるので、すべてのアドレスで待ち受けます。
+const DefaultMetricsListen = ":9245"
+
+// MetricsConfig は、ドメインごとの更新状況（連続して失敗した回数、最後に成功した時刻など）を
+// Prometheus のメトリクス（GET /metrics）として公開する設定を保持する構造体です。
+// 更新が黙って止まっていることに、しきい値のアラートで気づけるようにするために使います。
+type MetricsConfig struct {
+	// Enabled は、常駐しているときにメトリクスを公開するかどうかです（省略時は false）
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Listen は、待ち受けるアドレスです（省略時は ":9245"）
+	Listen string `yaml:"listen,omitempty"`
+}
+
+// ListenOrDefault は、待ち受けるアドレスを返します（省略した場合は DefaultMetricsListen）。
+func (m MetricsConfig) ListenOrDefault() string {
+	if m.Listen == "" {
+		return DefaultMetricsListen
+	}
+	return m.Listen
+}
+
+// DefaultLeaseName は、leader_election.name を省略した場合の Lease の名前です。
+const DefaultLeaseName = "duckdns"
+
+// LeaderElectionConfig は、複数のインスタンス（Kubernetes のレプリカや、冗長化した2台のサーバー）を動かすときに、
+// リースを取得した1つのインスタンスだけが更新するようにするリーダー選出の設定を保持する構造体です。
+// ほかのインスタンスは設定を読み込んだまま待機し、リーダーが止まると代わりに更新を始めます。
+type LeaderElectionConfig struct {
+	// Enabled は、リーダー選出をするかどうかです（省略時は false）
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Backend は、リースを保存する場所です（"file" または "kubernetes"。省略時は "file"）
+	Backend string `yaml:"backend,omitempty"`
+
+	// Identity は、インスタンスを区別する名前です（省略時は "ホスト名_PID"）
+	Identity string `yaml:"identity,omitempty"`
+
+	// LeaseDuration は、リーダーが止まってからほかのインスタンスが引き継ぐまでの時間です（省略時は "15s"）
+	LeaseDuration time.Duration `yaml:"lease_duration,omitempty"`
+
+	// RetryPeriod は、リースを取得・更新する間隔です（省略時は "2s"。lease_duration の 1/3 以下）
+	RetryPeriod time.Duration `yaml:"retry_period,omitempty"`
+
+	// Path は、リースのファイルのパスです（backend が "file" の場合は必須。すべてのインスタンスから見える共有ディレクトリに置きます）
+	Path string `yaml:"path,omitempty"`
+
+	// Namespace は、Lease の名前空間です（backend が "kubernetes" の場合。省略時は Pod の名前空間）
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Name は、Lease の名前です（backend が "kubernetes" の場合。省略時は "duckdns"）
+	Name string `yaml:"name,omitempty"`
+}
+
+// BackendOrDefault は、リースを保存する場所を返します（省略した場合は LeaderElectionFile）。
+func (l LeaderElectionConfig) BackendOrDefault() string {
+	if l.Backend == "" {
+		return LeaderElectionFile
+	}
+	return l.Backend
+}
+
+// NameOrDefault は、Lease の名前を返します（省略した場合は DefaultLeaseName）。
+func (l LeaderElectionConfig) NameOrDefault() string {
+	if l.Name == "" {
+		return DefaultLeaseName
+	}
+	return l.Name
+}
+
+// 更新状況と更新履歴を保存する場所（state.backend）です。
+const (
+	// StateBackendFile は、状態ファイルに保存します
+	StateBackendFile = "file"
+
+	// StateBackendRedis は、Redis に保存して、複数のインスタンスで共有します
+	StateBackendRedis = "redis"
+)
+
+// StateConfig は、更新状況と更新履歴（最後に登録した IP アドレスや、保留中の更新）を保存する場所の設定を保持する構造体です。
+// leader_election で冗長化する場合は Redis に保存すると、引き継いだインスタンスが前のリーダーの登録した IP アドレスから続けられます。
+type StateConfig struct {
+	// Backend は、保存する場所です（"file" または "redis"。省略時は "file"）
+	Backend string `yaml:"backend,omitempty"`
+
+	// Redis は、backend が "redis" の場合の接続先です
+	Redis RedisConfig `yaml:"redis,omitempty"`
+}
+
+// BackendOrDefault は、保存する場所を返します（省略した場合は StateBackendFile）。
+func (s StateConfig) BackendOrDefault() string {
+	if s.Backend == "" {
+		return StateBackendFile
+	}
+	return s.Backend
+}
+
+// Shared は、ほかのインスタンスと共有する場所に保存するかどうかを返します。
+func (s StateConfig) Shared() bool {
+	return s.BackendOrDefault() == StateBackendRedis
+}
+
+// RedisConfig は、状態を保存する Redis の接続先を保持する構造体です。
+type RedisConfig struct {
+	// Address は、Redis サーバーのアドレスです（host:port。backend が "redis" の場合は必須）
+	Address string `yaml:"address,omitempty"`
+
+	// Username は、ACL のユーザー名です（省略時はパスワードだけで認証します）
+	Username string `yaml:"username,omitempty"`
+
+	// Password は、パスワードです（省略時は認証しません）
+	Password string `yaml:"password,omitempty"`
+
+	// DB は、使用するデータベースの番号です（省略時は 0）
+	DB int `yaml:"db,omitempty"`
+
+	// Key は、状態を保存するキーです（省略時は "duckdns:state"）
+	Key string `yaml:"key,omitempty"`
+
+	// TLS は、TLS で接続するかどうかです（省略時は false）
+	TLS bool `yaml:"tls,omitempty"`
+
+	// Timeout は、接続と1回のコマンドのタイムアウトです（省略時は "5s"）
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// MQTTConfig は、更新状況をドメインごとに MQTT ブローカーに送る設定を保持する構造体です。
+// home_assistant.discovery を有効にすると、Home Assistant に IP アドレスや更新の状況がセンサーとして自動で表示されます。
+type MQTTConfig struct {
+	// Enabled は、MQTT ブローカーに送るかどうかです（省略時は false）
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Broker は、ブローカーの URL です（tcp://host:1883 や ssl://host:8883。enabled の場合は必須）
+	Broker string `yaml:"broker,omitempty"`
+
+	// Username は、ユーザー名です（省略時は認証しません）
+	Username string `yaml:"username,omitempty"`
+
+	// Password は、パスワードです
+	Password string `yaml:"password,omitempty"`
+
+	// ClientID は、クライアント ID です（省略時は "duckdns_" + ホスト名）
+	ClientID string `yaml:"client_id,omitempty"`
+
+	// TopicPrefix は、トピックの接頭辞です（省略時は "duckdns"）
+	TopicPrefix string `yaml:"topic_prefix,omitempty"`
+
+	// KeepAlive は、ブローカーとの生存確認の間隔です（省略時は "60s"）
+	KeepAlive time.Duration `yaml:"keep_alive,omitempty"`
+
+	// HomeAssistant は、Home Assistant の MQTT discovery の設定です
+	HomeAssistant HomeAssistantConfig `yaml:"home_assistant,omitempty"`
+}
+
+// HomeAssistantConfig は、Home Assistant の MQTT discovery の設定を保持する構造体です。
+type HomeAssistantConfig struct {
+	// Discovery は、discovery のメッセージを送るかどうかです（省略時は false）
+	Discovery bool `yaml:"discovery,omitempty"`
+
+	// DiscoveryPrefix は、discovery のトピックの接頭辞です（省略時は "homeassistant"）
+	DiscoveryPrefix string `yaml:"discovery_prefix,omitempty"`
 }
 
 // ValidationError は、設定のバリデーションエラーを保持する構造体です。
 // 複数のエラーメッセージを含むことができます。
 type ValidationError struct {
 	Errors []string
+
+	// Keys は、Errors の各エラーに対応する設定項目のキーです（例: "update.interval", "ip_sources[1]"）
+	// Errors と同じ順序・同じ長さで保持されます
+	Keys []string
+}
+
+// add は、設定項目のキーとエラーメッセージを追加します。
+func (ve *ValidationError) add(key, message string) {
+	ve.Keys = append(ve.Keys, key)
+	ve.Errors = append(ve.Errors, message)
 }
 
 // Error は ValidationError を error インターフェースに実装します。
@@ -76,45 +875,207 @@ func (ve *ValidationError) Error() string {
 // Returns:
 //   - error: バリデーションエラーがある場合
 func (c *Config) Validate() error {
-	var errors []string
+	ve := &ValidationError{}
 
 	// 必須項目チェック
-	if strings.TrimSpace(c.DuckDNS.Domain) == "" {
-		errors = append(errors, "DuckDNSドメイン名が設定されていません (設定項目: duckdns.domain または環境変数: DUCKDNS_DOMAIN)")
+	// ドメインごとの設定がすべて値を上書きしている場合は、トップレベルの値は省略できる
+	// providers だけを設定する場合は、duckdns セクションは省略できる
+	if strings.TrimSpace(c.DuckDNS.Domain) == "" && len(c.DuckDNS.allDomains()) == 0 && len(c.Providers) == 0 {
+		ve.add("duckdns.domain", "DuckDNSドメイン名が設定されていません (設定項目: duckdns.domain または環境変数: DUCKDNS_DOMAIN)")
+	}
+	if strings.TrimSpace(c.DuckDNS.Domain) != "" {
+		if _, err := duckdns.NormalizeDomain(c.DuckDNS.Domain); err != nil {
+			ve.add("duckdns.domain", err.Error())
+		}
 	}
-	if strings.TrimSpace(c.DuckDNS.Token) == "" {
-		errors = append(errors, "DuckDNS APIトークンが設定されていません (設定項目: duckdns.token または環境変数: DUCKDNS_TOKEN)")
+	if c.usesDuckDNS() && strings.TrimSpace(c.DuckDNS.Token) == "" && !c.allDomainsOverride(func(d DomainConfig) bool { return strings.TrimSpace(d.Token) != "" }) {
+		ve.add("duckdns.token", "DuckDNS APIトークンが設定されていません (設定項目: duckdns.token または環境変数: DUCKDNS_TOKEN)")
 	}
 
 	// 更新間隔のチェック
 	if c.Update.Interval == 0 {
-		errors = append(errors, "更新間隔が設定されていません (設定項目: update.interval または環境変数: DUCKDNS_INTERVAL, 例: \"5m\", \"1h\")")
+		if !c.allTargetsOverride(func(d DomainConfig) bool { return d.Interval != 0 }, func(p ProviderConfig) bool { return p.Interval != 0 }) {
+			ve.add("update.interval", "更新間隔が設定されていません (設定項目: update.interval または環境変数: DUCKDNS_INTERVAL, 例: \"5m\", \"1h\")")
+		}
 	} else if c.Update.Interval < 0 {
-		errors = append(errors, "更新間隔は正の値である必要があります")
+		ve.add("update.interval", "更新間隔は正の値である必要があります")
+	}
+	if c.Update.Workers < 0 {
+		ve.add("update.workers", "同時に実行する数は0以上で指定してください")
+	}
+	if c.Update.WaitForNetwork < 0 {
+		ve.add("update.wait_for_network", "ネットワークを待つ時間は0以上で指定してください")
+	}
+	if c.Update.OfflineRetryInterval < 0 {
+		ve.add("update.offline_retry_interval", "保留中の更新を再試行する間隔は0以上で指定してください")
+	}
+	if c.Update.ShutdownGrace < 0 {
+		ve.add("update.shutdown_grace", "停止するまで待つ時間は0以上で指定してください")
+	}
+	validateShutdownAction(ve, c.Update.OnShutdown)
+	validateTimeouts(ve, c.Update.Timeouts)
+	if c.Update.Precheck != "" {
+		validPrechecks := map[string]bool{"http": true, "dns": true}
+		if !validPrechecks[strings.ToLower(c.Update.Precheck)] {
+			ve.add("update.precheck", fmt.Sprintf("無効な接続の確認方法 \"%s\" です (有効な値: http, dns)", c.Update.Precheck))
+		}
+	}
+	if c.Update.AutoDetect && len(c.Providers) > 0 {
+		ve.add("update.auto_detect", "IPアドレスの自動検出は DuckDNS のドメインだけで使用できます (providers と一緒には指定できません)")
+	}
+	switch {
+	case c.Update.CrossCheck < 0:
+		ve.add("update.cross_check", "照らし合わせる回数は0以上で指定してください")
+	case c.Update.CrossCheck > 0 && !c.Update.AutoDetect:
+		ve.add("update.cross_check", "検出したアドレスの照らし合わせは update.auto_detect と一緒に指定してください")
+	}
+	validateFailureAlert(ve, c.Update.FailureAlert)
+	validateDigest(ve, c.Update.Digest)
+	validateTimeWindows(ve, "update.schedule", c.Update.Schedule)
+	validateTimeWindows(ve, "update.quiet_hours", c.Update.QuietHours)
+	if c.Update.Watchdog < 0 || c.Update.Watchdog == 1 {
+		ve.add("update.watchdog", "ループを始め直すまでの間隔の倍率は2以上で指定してください")
 	}
 
 	// IP取得ソースのバリデーション
 	if len(c.IPSources) == 0 {
-		errors = append(errors, "IP取得ソースが1つも設定されていません (設定項目: ip_sources)")
+		if !c.allTargetsOverride(func(d DomainConfig) bool { return len(d.IPSources) > 0 }, func(p ProviderConfig) bool { return len(p.IPSources) > 0 }) {
+			ve.add("ip_sources", "IP取得ソースが1つも設定されていません (設定項目: ip_sources)")
+		}
 	} else {
-		for i, source := range c.IPSources {
-			if strings.TrimSpace(source) == "" {
-				errors = append(errors, fmt.Sprintf("IP取得ソース[%d]が空です", i))
-				continue
-			}
+		validateIPSources(ve, "ip_sources", "IP取得ソース", c.IPSources)
+	}
 
-			// URLの妥当性をチェック
-			if !isValidURL(source) {
-				errors = append(errors, fmt.Sprintf("IP取得ソース[%d] \"%s\" が無効なURLです", i, source))
-			}
+	validateIPSources(ve, "ipv6_sources", "IPv6 のIP取得ソース", c.IPv6Sources)
+	if c.IPFetch.CacheTTL < 0 {
+		ve.add("ip_fetch.cache_ttl", "IPアドレスをキャッシュする時間は0以上で指定してください")
+	}
+	if _, err := ip.ParseStrategy(c.IPFetch.Strategy); err != nil {
+		ve.add("ip_fetch.strategy", err.Error())
+	}
+	if c.IPFetch.FailureThreshold < 0 {
+		ve.add("ip_fetch.failure_threshold", "連続失敗回数は0以上で指定してください")
+	}
+	if c.IPFetch.Cooldown < 0 {
+		ve.add("ip_fetch.cooldown", "IP取得ソースを使わない時間は0以上で指定してください")
+	}
+	if _, err := ip.ParseSuspiciousAction(c.IPFetch.Suspicious); err != nil {
+		ve.add("ip_fetch.suspicious", err.Error())
+	}
+
+	// ドメインごとの設定のバリデーション
+	for i, d := range c.DuckDNS.Domains {
+		if strings.TrimSpace(d.Name) == "" {
+			ve.add(fmt.Sprintf("duckdns.domains[%d].name", i), fmt.Sprintf("duckdns.domains[%d] のドメイン名が設定されていません (設定項目: name)", i))
+		} else if _, err := duckdns.NormalizeDomain(d.Name); err != nil {
+			ve.add(fmt.Sprintf("duckdns.domains[%d].name", i), fmt.Sprintf("duckdns.domains[%d] の%s", i, err.Error()))
+		}
+		if d.Interval < 0 {
+			ve.add(fmt.Sprintf("duckdns.domains[%d].interval", i), fmt.Sprintf("duckdns.domains[%d] の更新間隔は正の値である必要があります", i))
 		}
+		validateIPSources(ve, fmt.Sprintf("duckdns.domains[%d].ip_sources", i), fmt.Sprintf("duckdns.domains[%d] のIP取得ソース", i), d.IPSources)
+		validateIPSources(ve, fmt.Sprintf("duckdns.domains[%d].ipv6_sources", i), fmt.Sprintf("duckdns.domains[%d] の IPv6 のIP取得ソース", i), d.IPv6Sources)
+	}
+	validateAccounts(ve, c.DuckDNS.Accounts)
+
+	// リクエスト頻度の上限のバリデーション
+	if c.DuckDNS.RateLimit.Requests < 0 {
+		ve.add("duckdns.rate_limit.requests", "リクエスト数の上限は正の値である必要があります")
+	}
+	if c.DuckDNS.RateLimit.Per < 0 {
+		ve.add("duckdns.rate_limit.per", "リクエスト数を数える期間は正の値である必要があります")
+	}
+
+	// リトライの設定のバリデーション
+	validateRetry(ve, "update.retry", c.Update.Retry)
+
+	// サーキットブレーカーのバリデーション
+	if c.DuckDNS.CircuitBreaker.FailureThreshold < 0 {
+		ve.add("duckdns.circuit_breaker.failure_threshold", "連続失敗回数は正の値である必要があります")
+	}
+	if c.DuckDNS.CircuitBreaker.Cooldown < 0 {
+		ve.add("duckdns.circuit_breaker.cooldown", "送信を止める時間は正の値である必要があります")
+	}
+	if _, err := httpclient.FamilyNetwork(c.DuckDNS.AddressFamily); err != nil {
+		ve.add("duckdns.address_family", err.Error())
 	}
 
+	// プロバイダーの設定のバリデーション
+	validateProviders(ve, c.Providers)
+
+	// 通信設定のバリデーション
+	if _, err := httpclient.ProxyFunc(c.Network.Proxy); err != nil {
+		ve.add("network.proxy", err.Error())
+	}
+	for i, resolver := range c.Network.Resolvers {
+		if _, err := httpclient.ParseResolver(resolver); err != nil {
+			ve.add(fmt.Sprintf("network.resolvers[%d]", i), err.Error())
+		}
+	}
+	for i, server := range c.Network.DoHServers {
+		if err := httpclient.ValidateDoHServer(server); err != nil {
+			ve.add(fmt.Sprintf("network.doh_servers[%d]", i), err.Error())
+		}
+	}
+	if c.Network.CAFile != "" {
+		if _, err := httpclient.LoadCertPool(c.Network.CAFile); err != nil {
+			ve.add("network.ca_file", err.Error())
+		}
+	}
+	if _, err := httpclient.ParseTLSVersion(c.Network.TLSMinVersion); err != nil {
+		ve.add("network.tls_min_version", err.Error())
+	}
+	if c.Network.MaxResponseSize < 0 {
+		ve.add("network.max_response_size", "レスポンスの最大サイズは0以上で指定してください")
+	}
+	if c.Network.MaxIdleConns < 0 {
+		ve.add("network.max_idle_conns", "アイドル中の接続の最大数は0以上で指定してください")
+	}
+	if c.Network.IdleConnTimeout < 0 {
+		ve.add("network.idle_conn_timeout", "アイドル中の接続を閉じるまでの時間は0以上で指定してください")
+	}
+	if c.Network.ProbeURL != "" && !isValidURL(c.Network.ProbeURL) {
+		ve.add("network.probe_url", fmt.Sprintf("接続の確認に使う URL \"%s\" が無効です (http または https の URL を指定してください)", c.Network.ProbeURL))
+	}
+	for _, host := range slices.Sorted(maps.Keys(c.Network.IPSourcePins)) {
+		if err := httpclient.ValidatePins(host, c.Network.IPSourcePins[host]); err != nil {
+			ve.add(fmt.Sprintf("network.ip_source_pins[%s]", host), err.Error())
+		}
+	}
+
+	// 新しいバージョンの確認のバリデーション
+	validateReleaseCheck(ve, c.ReleaseCheck)
+
+	// 更新の通知の受け口のバリデーション
+	validateTrigger(ve, c.Trigger)
+
+	// メトリクスのバリデーション
+	validateMetrics(ve, c.Metrics)
+
+	if c.Gops.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Gops.Listen); err != nil {
+			ve.add("gops.listen", fmt.Sprintf("待ち受けるアドレス \"%s\" は host:port の形式で指定してください (例: \"127.0.0.1:6060\")", c.Gops.Listen))
+		}
+	}
+
+	if c.CrashReport.MaxReports < 0 {
+		ve.add("crash_report.max_reports", "残すクラッシュレポートの数は0以上で指定してください")
+	}
+
+	// リーダー選出のバリデーション
+	validateLeaderElection(ve, c.LeaderElection)
+
+	// 状態の保存先のバリデーション
+	validateState(ve, c.State)
+
+	// MQTT のバリデーション
+	validateMQTT(ve, c.MQTT)
+
 	// ログレベルのバリデーション
 	if c.Log.Level != "" {
 		validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 		if !validLevels[strings.ToLower(c.Log.Level)] {
-			errors = append(errors, fmt.Sprintf("無効なログレベル \"%s\" です (有効な値: debug, info, warn, error)", c.Log.Level))
+			ve.add("log.level", fmt.Sprintf("無効なログレベル \"%s\" です (有効な値: debug, info, warn, error)", c.Log.Level))
 		}
 	}
 
@@ -122,17 +1083,457 @@ func (c *Config) Validate() error {
 	if c.Log.Format != "" {
 		validFormats := map[string]bool{"json": true, "text": true}
 		if !validFormats[strings.ToLower(c.Log.Format)] {
-			errors = append(errors, fmt.Sprintf("無効なログフォーマット \"%s\" です (有効な値: json, text)", c.Log.Format))
+			ve.add("log.format", fmt.Sprintf("無効なログフォーマット \"%s\" です (有効な値: json, text)", c.Log.Format))
 		}
 	}
 
-	if len(errors) > 0 {
-		return &ValidationError{Errors: errors}
+	if c.Log.SummaryInterval != 0 && c.Log.SummaryInterval < MinSummaryInterval {
+		ve.add("log.summary_interval", fmt.Sprintf("動作の要約を出力する間隔は %s 以上で指定してください", MinSummaryInterval))
+	}
+
+	// タイムゾーンのバリデーション
+	if _, err := c.Location(); err != nil {
+		ve.add("timezone", err.Error())
+	}
+
+	if len(ve.Errors) > 0 {
+		return ve
 	}
 
 	return nil
 }
 
+// validateRetry は、リトライの設定を検証し、エラーを ve に追加します。
+func validateRetry(ve *ValidationError, key string, r RetryConfig) {
+	if r.MaxRetries < 0 {
+		ve.add(key+".max_retries", "リトライの最大回数は 0 以上である必要があります")
+	}
+	if r.InitialInterval < 0 {
+		ve.add(key+".initial_interval", "最初の待ち時間は正の値である必要があります")
+	}
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		ve.add(key+".multiplier", fmt.Sprintf("待ち時間の倍率 %g は 1 以上である必要があります", r.Multiplier))
+	}
+	if r.MaxInterval < 0 {
+		ve.add(key+".max_interval", "待ち時間の上限は正の値である必要があります")
+	} else if r.MaxInterval > 0 && r.InitialInterval > r.MaxInterval {
+		ve.add(key+".max_interval", "待ち時間の上限は最初の待ち時間以上である必要があります")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		ve.add(key+".jitter", fmt.Sprintf("待ち時間をずらす割合 %g は 0〜1 である必要があります", r.Jitter))
+	}
+	if r.MaxElapsedTime < 0 {
+		ve.add(key+".max_elapsed_time", "リトライをあきらめるまでの時間は正の値である必要があります")
+	}
+}
+
+// validateIPSources は、IP取得ソースのリストを検証し、エラーを ve に追加します。
+// key は設定項目のキー、label はエラーメッセージの先頭に付ける項目名です。
+func validateIPSources(ve *ValidationError, key, label string, sources IPSources) {
+	for i, source := range sources {
+		itemKey := fmt.Sprintf("%s[%d]", key, i)
+		if strings.TrimSpace(source.URL) == "" {
+			ve.add(itemKey, fmt.Sprintf("%s[%d]が空です", label, i))
+			continue
+		}
+
+		// URLの妥当性をチェック（file: のソースはファイルのパスを確認）
+		if ip.IsFileSource(source.URL) {
+			if _, err := ip.ParseFileSource(source.URL); err != nil {
+				ve.add(itemKey, fmt.Sprintf("%s[%d] が無効です: %v", label, i, err))
+			}
+		} else if !isValidURL(source.URL) {
+			ve.add(itemKey, fmt.Sprintf("%s[%d] \"%s\" が無効なURLです", label, i, source.URL))
+		}
+
+		// エラーの順番が毎回変わらないように、ヘッダー名の順で検証する
+		for _, name := range slices.Sorted(maps.Keys(source.Headers)) {
+			if !isValidHeaderName(name) {
+				ve.add(itemKey+".headers", fmt.Sprintf("%s[%d] のヘッダー名 \"%s\" が無効です", label, i, name))
+			}
+		}
+
+		sourceType, err := ip.ParseSourceType(source.Type)
+		if err != nil {
+			ve.add(itemKey+".type", fmt.Sprintf("%s[%d] の type が無効です: %v", label, i, err))
+		} else if sourceType.IsRouter() && ip.IsFileSource(source.URL) {
+			ve.add(itemKey+".type", fmt.Sprintf("%s[%d] の type が %s の場合は、url にルーターのアドレスを指定してください", label, i, sourceType))
+		}
+		if source.Interface != "" && sourceType != ip.SourceOpenWrt {
+			ve.add(itemKey+".interface", fmt.Sprintf("%s[%d] の interface は type が openwrt の場合だけ指定できます", label, i))
+		}
+
+		if _, err := ip.ParseExtractor(source.Extract); err != nil {
+			ve.add(itemKey+".extract", fmt.Sprintf("%s[%d] の extract が無効です: %v", label, i, err))
+		}
+		if source.MinInterval < 0 {
+			ve.add(itemKey+".min_interval", fmt.Sprintf("%s[%d] の min_interval は0以上で指定してください", label, i))
+		}
+		if source.CacheTTL < 0 {
+			ve.add(itemKey+".cache_ttl", fmt.Sprintf("%s[%d] の cache_ttl は0以上で指定してください", label, i))
+		}
+		if source.Weight < 0 {
+			ve.add(itemKey+".weight", fmt.Sprintf("%s[%d] の weight は0以上で指定してください", label, i))
+		}
+	}
+}
+
+// isValidHeaderName は、HTTP ヘッダーの名前として使える文字だけでできているかどうかを返します。
+func isValidHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// usesDuckDNS は、DuckDNS のドメインを更新するかどうかを返します。
+// providers だけが設定されている場合は false になります。
+func (c *Config) usesDuckDNS() bool {
+	return strings.TrimSpace(c.DuckDNS.Domain) != "" || len(c.DuckDNS.allDomains()) > 0 || len(c.Providers) == 0
+}
+
+// allTargetsOverride は、DuckDNS のドメインごとの設定とプロバイダーのすべてが
+// 条件を満たす（トップレベルの値を上書きしている）かどうかを返します。
+func (c *Config) allTargetsOverride(domain func(DomainConfig) bool, provider func(ProviderConfig) bool) bool {
+	if len(c.Providers) == 0 {
+		return c.allDomainsOverride(domain)
+	}
+	if strings.TrimSpace(c.DuckDNS.Domain) != "" {
+		return false
+	}
+	for _, d := range c.DuckDNS.allDomains() {
+		if !domain(d) {
+			return false
+		}
+	}
+	for _, p := range c.Providers {
+		if !provider(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// allDomains は、duckdns.domains に duckdns.accounts のドメインを加えた、ドメインごとの設定の一覧を返します。
+// アカウントのドメインは、そのアカウントのトークンを指定したドメインとして扱います。
+func (d DuckDNSConfig) allDomains() []DomainConfig {
+	if len(d.Accounts) == 0 {
+		return d.Domains
+	}
+	domains := slices.Clone(d.Domains)
+	for _, account := range d.Accounts {
+		for _, name := range account.Domains {
+			domains = append(domains, DomainConfig{Name: name, Token: account.Token, Interval: account.Interval, account: account.Name})
+		}
+	}
+	return domains
+}
+
+// validateAccounts は、duckdns.accounts を検証し、エラーを ve に追加します。
+func validateAccounts(ve *ValidationError, accounts []AccountConfig) {
+	for i, account := range accounts {
+		key := fmt.Sprintf("duckdns.accounts[%d]", i)
+		if strings.TrimSpace(account.Token) == "" {
+			ve.add(key+".token", fmt.Sprintf("%s の DuckDNS APIトークンが設定されていません (設定項目: token)", key))
+		}
+		if account.Interval < 0 {
+			ve.add(key+".interval", fmt.Sprintf("%s の更新間隔は正の値である必要があります", key))
+		}
+		if len(account.Domains) == 0 {
+			ve.add(key+".domains", fmt.Sprintf("%s の更新するドメイン名が設定されていません (設定項目: domains)", key))
+		}
+		for j, domain := range account.Domains {
+			domainKey := fmt.Sprintf("%s.domains[%d]", key, j)
+			if strings.TrimSpace(domain) == "" {
+				ve.add(domainKey, fmt.Sprintf("%s が空です", domainKey))
+			} else if _, err := duckdns.NormalizeDomain(domain); err != nil {
+				ve.add(domainKey, fmt.Sprintf("%s の%s", domainKey, err.Error()))
+			}
+		}
+	}
+}
+
+// allDomainsOverride は、ドメインごとの設定が1つ以上あり、
+// そのすべてが条件を満たす（トップレベルの値を上書きしている）かどうかを返します。
+// duckdns.domain が設定されている場合は、そのドメインがトップレベルの値を使うため false になります。
+func (c *Config) allDomainsOverride(overrides func(DomainConfig) bool) bool {
+	domains := c.DuckDNS.allDomains()
+	if len(domains) == 0 || strings.TrimSpace(c.DuckDNS.Domain) != "" {
+		return false
+	}
+	for _, d := range domains {
+		if !overrides(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// Targets は、ドメインごとの上書きを反映した更新対象の一覧を返します。
+// duckdns.domain が設定されている場合は、トップレベルの設定を使う対象として先頭に含めます。
+// DuckDNS のドメイン名は、"mydomain.duckdns.org" や URL もサブドメイン名（"mydomain"）に正規化します。
+// providers のドメインは、DuckDNS のドメインの後ろに含めます。
+//
+// Returns:
+//   - []Target: 更新対象の一覧
+func (c *Config) Targets() []Target {
+	var targets []Target
+
+	if strings.TrimSpace(c.DuckDNS.Domain) != "" {
+		targets = append(targets, Target{
+			Domain:      normalizeDomain(c.DuckDNS.Domain),
+			Token:       c.DuckDNS.Token,
+			Interval:    c.Update.Interval,
+			IPSources:   c.IPSources,
+			IPv6:        c.Update.IPv6,
+			IPv6Sources: c.IPv6Sources,
+		})
+	}
+
+	for _, d := range c.DuckDNS.allDomains() {
+		target := Target{
+			Domain:    normalizeDomain(d.Name),
+			Token:     d.Token,
+			Account:   d.account,
+			Interval:  d.Interval,
+			IPSources: d.IPSources,
+		}
+		if target.Token == "" {
+			target.Token = c.DuckDNS.Token
+		}
+		if target.Interval == 0 {
+			target.Interval = c.Update.Interval
+		}
+		if len(target.IPSources) == 0 {
+			target.IPSources = c.IPSources
+		}
+		target.IPv6, target.IPv6Sources = c.ipv6Override(d.IPv6, d.IPv6Sources)
+		targets = append(targets, target)
+	}
+
+	return append(targets, c.providerTargets()...)
+}
+
+// ipv6Override は、ドメインやプロバイダーごとの上書きを反映した、IPv6 を更新するかどうかと IPv6 の取得ソースを返します。
+// 上書きしていない項目は、update.ipv6 と ipv6_sources を引き継ぎます。
+func (c *Config) ipv6Override(enabled *bool, sources IPSources) (bool, IPSources) {
+	ipv6 := c.Update.IPv6
+	if enabled != nil {
+		ipv6 = *enabled
+	}
+	if len(sources) == 0 {
+		sources = c.IPv6Sources
+	}
+	return ipv6, sources
+}
+
+// normalizeDomain は、DuckDNS のドメイン名をサブドメイン名に正規化します。
+// 正規化できない場合は Validate がエラーを報告するため、元の値をそのまま返します。
+func normalizeDomain(domain string) string {
+	if name, err := duckdns.NormalizeDomain(domain); err == nil {
+		return name
+	}
+	return domain
+}
+
+// validateShutdownAction は、update.on_shutdown の設定を検証します。
+func validateShutdownAction(ve *ValidationError, s ShutdownActionConfig) {
+	switch s.ActionName() {
+	case ShutdownActionNone, ShutdownActionClear:
+	case ShutdownActionSetIP:
+		if s.IP == "" {
+			ve.add("update.on_shutdown.ip", "action が set_ip の場合は、登録する IPアドレスを指定してください")
+		} else if err := ip.IPv4.Validate(s.IP); err != nil {
+			ve.add("update.on_shutdown.ip", fmt.Sprintf("無効な IPv4アドレス \"%s\" です", s.IP))
+		}
+	case ShutdownActionWebhook:
+		if s.Webhook == "" {
+			ve.add("update.on_shutdown.webhook", "action が webhook の場合は、通知する URL を指定してください")
+		} else if !isValidURL(s.Webhook) {
+			ve.add("update.on_shutdown.webhook", fmt.Sprintf("無効な URL \"%s\" です", s.Webhook))
+		}
+	default:
+		ve.add("update.on_shutdown.action", fmt.Sprintf("無効な停止するときの処理 \"%s\" です (有効な値: none, clear, set_ip, webhook)", s.Action))
+	}
+	if s.Timeout < 0 {
+		ve.add("update.on_shutdown.timeout", "停止するときの処理のタイムアウトは0以上で指定してください")
+	}
+}
+
+// validateTimeouts は、update.timeouts の設定を検証します。
+func validateTimeouts(ve *ValidationError, t TimeoutsConfig) {
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"update.timeouts.ip_fetch", t.IPFetch},
+		{"update.timeouts.update", t.Update},
+		{"update.timeouts.probe", t.Probe},
+		{"update.timeouts.hook", t.Hook},
+	} {
+		if timeout.value < 0 {
+			ve.add(timeout.key, "タイムアウトは0以上で指定してください")
+		}
+	}
+}
+
+// validateReleaseCheck は、release_check の設定を検証します。
+func validateReleaseCheck(ve *ValidationError, r ReleaseCheckConfig) {
+	if r.Interval < 0 {
+		ve.add("release_check.interval", "新しいバージョンを確認する間隔は0以上で指定してください")
+	} else if r.Interval > 0 && r.Interval < MinReleaseCheckInterval {
+		ve.add("release_check.interval", fmt.Sprintf("新しいバージョンを確認する間隔は %s 以上で指定してください", MinReleaseCheckInterval))
+	}
+	if r.Repository != "" {
+		owner, name, ok := strings.Cut(r.Repository, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			ve.add("release_check.repo", fmt.Sprintf("リポジトリ \"%s\" は owner/name の形式で指定してください", r.Repository))
+		}
+	}
+}
+
+// validateTrigger は、trigger の設定を検証します。
+func validateTrigger(ve *ValidationError, t TriggerConfig) {
+	if t.Listen != "" {
+		if _, port, err := net.SplitHostPort(t.Listen); err != nil || port == "" {
+			ve.add("trigger.listen", fmt.Sprintf("待ち受けるアドレス \"%s\" は host:port の形式で指定してください (例: \":8245\")", t.Listen))
+		}
+	}
+	if !t.Enabled {
+		return
+	}
+	if t.Username == "" {
+		ve.add("trigger.username", "更新の通知を待ち受ける場合は、Basic 認証のユーザー名を指定してください")
+	}
+	if t.Password == "" {
+		ve.add("trigger.password", "更新の通知を待ち受ける場合は、Basic 認証のパスワードを指定してください")
+	}
+}
+
+// validateFailureAlert は、update.failure_alert の設定を検証します。
+func validateFailureAlert(ve *ValidationError, f FailureAlertConfig) {
+	switch {
+	case f.Threshold < 0:
+		ve.add("update.failure_alert.threshold", "知らせるまでに失敗する回数は0以上で指定してください")
+	case f.Threshold == 0 && f.Webhook != "":
+		ve.add("update.failure_alert.threshold", "webhook で知らせる場合は、知らせるまでに失敗する回数を指定してください")
+	}
+	if f.Webhook != "" && !isValidURL(f.Webhook) {
+		ve.add("update.failure_alert.webhook", fmt.Sprintf("無効な URL \"%s\" です", f.Webhook))
+	}
+}
+
+// validateDigest は、update.digest の設定を検証します。
+func validateDigest(ve *ValidationError, d DigestConfig) {
+	if d.Webhook != "" && !isValidURL(d.Webhook) {
+		ve.add("update.digest.webhook", fmt.Sprintf("無効な URL \"%s\" です", d.Webhook))
+	}
+	if d.At == "" {
+		return
+	}
+	if _, err := time.Parse(DigestTimeFormat, d.At); err != nil {
+		ve.add("update.digest.at", fmt.Sprintf("知らせる時刻 \"%s\" は HH:MM の形式で指定してください (例: \"08:00\")", d.At))
+	} else if d.Webhook == "" {
+		ve.add("update.digest.webhook", "知らせる時刻を指定した場合は、webhook を指定してください")
+	}
+}
+
+// validateTimeWindows は、update.schedule と update.quiet_hours の時間帯を検証します。
+func validateTimeWindows(ve *ValidationError, key string, windows []string) {
+	for i, w := range windows {
+		if _, _, err := ParseTimeWindow(w); err != nil {
+			ve.add(fmt.Sprintf("%s[%d]", key, i), err.Error())
+		}
+	}
+}
+
+// validateMetrics は、metrics の設定を検証します。
+func validateMetrics(ve *ValidationError, m MetricsConfig) {
+	if m.Listen != "" {
+		if _, port, err := net.SplitHostPort(m.Listen); err != nil || port == "" {
+			ve.add("metrics.listen", fmt.Sprintf("待ち受けるアドレス \"%s\" は host:port の形式で指定してください (例: \":9245\")", m.Listen))
+		}
+	}
+}
+
+// validateLeaderElection は、leader_election の設定を検証します。
+func validateLeaderElection(ve *ValidationError, l LeaderElectionConfig) {
+	switch l.BackendOrDefault() {
+	case LeaderElectionFile:
+		if l.Enabled && l.Path == "" {
+			ve.add("leader_election.path", "リースをファイルに保存する場合は、すべてのインスタンスから見えるファイルのパスを指定してください")
+		}
+	case LeaderElectionKubernetes:
+	default:
+		ve.add("leader_election.backend", fmt.Sprintf("リースを保存する場所 \"%s\" は file または kubernetes で指定してください", l.Backend))
+	}
+
+	if l.LeaseDuration < 0 {
+		ve.add("leader_election.lease_duration", "リースの期限は0以上で指定してください")
+	}
+	if l.RetryPeriod < 0 {
+		ve.add("leader_election.retry_period", "リースを取得・更新する間隔は0以上で指定してください")
+	}
+	lease, retry := l.LeaseDuration, l.RetryPeriod
+	if lease == 0 {
+		lease = leader.DefaultLeaseDuration
+	}
+	if retry == 0 {
+		retry = leader.DefaultRetryPeriod
+	}
+	if lease > 0 && retry > 0 && retry*3 > lease {
+		ve.add("leader_election.retry_period", fmt.Sprintf("リースを取得・更新する間隔 (%s) は、リースの期限 (%s) の 1/3 以下で指定してください", retry, lease))
+	}
+}
+
+// validateState は、state の設定を検証します。
+func validateState(ve *ValidationError, s StateConfig) {
+	switch s.BackendOrDefault() {
+	case StateBackendFile:
+	case StateBackendRedis:
+		if s.Redis.Address == "" {
+			ve.add("state.redis.address", "状態を Redis に保存する場合は、Redis サーバーのアドレスを指定してください")
+		} else if _, port, err := net.SplitHostPort(s.Redis.Address); err != nil || port == "" {
+			ve.add("state.redis.address", fmt.Sprintf("Redis サーバーのアドレス \"%s\" は host:port の形式で指定してください (例: \"localhost:6379\")", s.Redis.Address))
+		}
+	default:
+		ve.add("state.backend", fmt.Sprintf("状態を保存する場所 \"%s\" は file または redis で指定してください", s.Backend))
+	}
+
+	if s.Redis.DB < 0 {
+		ve.add("state.redis.db", "データベースの番号は0以上で指定してください")
+	}
+	if s.Redis.Timeout < 0 {
+		ve.add("state.redis.timeout", "タイムアウトは0以上で指定してください")
+	}
+}
+
+// validateMQTT は、mqtt の設定を検証します。
+func validateMQTT(ve *ValidationError, m MQTTConfig) {
+	if m.Broker == "" {
+		if m.Enabled {
+			ve.add("mqtt.broker", "MQTT ブローカーに送る場合は、ブローカーの URL を指定してください (例: \"tcp://localhost:1883\")")
+		}
+	} else if _, _, err := mqtt.ParseBroker(m.Broker); err != nil {
+		ve.add("mqtt.broker", err.Error())
+	}
+
+	if m.KeepAlive < 0 || m.KeepAlive > 65535*time.Second {
+		ve.add("mqtt.keep_alive", "生存確認の間隔は0以上 65535 秒以下で指定してください")
+	}
+	if strings.ContainsAny(m.TopicPrefix, "+#") {
+		ve.add("mqtt.topic_prefix", fmt.Sprintf("トピックの接頭辞 \"%s\" には + や # を含めないでください", m.TopicPrefix))
+	}
+	if strings.ContainsAny(m.HomeAssistant.DiscoveryPrefix, "+#") {
+		ve.add("mqtt.home_assistant.discovery_prefix", fmt.Sprintf("discovery のトピックの接頭辞 \"%s\" には + や # を含めないでください", m.HomeAssistant.DiscoveryPrefix))
+	}
+}
+
 // isValidURL はURLが有効かどうかを確認します。
 func isValidURL(urlStr string) bool {
 	// URLをパース
@@ -159,8 +1560,21 @@ func isValidURL(urlStr string) bool {
 	return true
 }
 
+// LoadOptions は、設定ファイル読み込み時の動作を制御するオプションです。
+// ゼロ値は厳格モード（未知のキーをエラーにする）を意味します。
+type LoadOptions struct {
+	// AllowUnknownKeys が true の場合、設定ファイル内の未知のキーを無視します。
+	// false（デフォルト）の場合、"intervall:" のようなタイプミスをエラーとして報告します。
+	AllowUnknownKeys bool
+
+	// Profile は、適用するプロファイル名です（profiles セクションのキー）
+	// 空文字列の場合はプロファイルを適用せず、共通の設定のみを使用します
+	Profile string
+}
+
 // LoadFromFile は、指定されたYAMLファイルから設定を読み込みます。
 // ファイルが存在しない場合やYAMLの解析に失敗した場合はエラーを返します。
+// 未知のキーが含まれる場合もエラーになります（厳格モード）。
 //
 // Parameters:
 //   - path: 読み込むYAML設定ファイルのパス
@@ -169,22 +1583,34 @@ func isValidURL(urlStr string) bool {
 //   - *Config: 読み込まれた設定
 //   - error: エラーが発生した場合
 func LoadFromFile(path string) (*Config, error) {
-	// ファイルの存在確認と読み込み
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("設定ファイルが見つかりません: %s", path)
-		}
-		return nil, fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
-	}
+	return LoadFromFileWithOptions(path, LoadOptions{})
+}
 
-	// YAMLのパース
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("YAML解析に失敗しました: %w", err)
-	}
+// LoadFromFileWithOptions は、オプションを指定してYAMLファイルから設定を読み込みます。
+//
+// Parameters:
+//   - path: 読み込むYAML設定ファイルのパス
+//   - opts: 読み込みオプション
+//
+// Returns:
+//   - *Config: 読み込まれた設定
+//   - error: エラーが発生した場合
+func LoadFromFileWithOptions(path string, opts LoadOptions) (*Config, error) {
+	// include の解決とプロファイルの適用を行い、最終的な設定を組み立てる
+	return loadMerged(path, opts)
+}
+
+// decodeYAML は、オプションに従ってYAMLを構造体にデコードします。
+// 厳格モードでは、構造体に存在しないキーをエラーとして扱います。
+func decodeYAML(data []byte, out interface{}, opts LoadOptions) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(!opts.AllowUnknownKeys)
 
-	return &cfg, nil
+	// 空のファイルは空の設定として扱う（yaml.Unmarshal と同じ挙動）
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
 }
 
 // LoadFromEnv は、環境変数から設定を読み込みます。
@@ -194,6 +1620,12 @@ func LoadFromFile(path string) (*Config, error) {
 //   - DUCKDNS_DOMAIN: DuckDNSのドメイン名
 //   - DUCKDNS_TOKEN: DuckDNS APIトークン
 //   - DUCKDNS_INTERVAL: 更新間隔（例: "5m", "1h"）
+//   - DUCKDNS_IP_SOURCES: IP取得ソースのURL（カンマ区切り）
+//   - DUCKDNS_IPV6: IPv6アドレスも更新するかどうか（"true" など）
+//   - DUCKDNS_IPV6_SOURCES: IPv6 のIP取得ソースのURL（カンマ区切り）
+//   - DUCKDNS_LOG_LEVEL: ログ出力レベル
+//   - DUCKDNS_LOG_FORMAT: ログ出力形式
+//   - DUCKDNS_LOG_FILE: ログを追記するファイル
 //
 // Returns:
 //   - *Config: 環境変数から読み込まれた設定
@@ -218,9 +1650,44 @@ func LoadFromEnv() (*Config, error) {
 		cfg.Update.Interval = duration
 	}
 
+	// IP取得ソースの読み込み（カンマ区切り、空要素は無視）
+	cfg.IPSources = NewIPSources(splitEnvList("DUCKDNS_IP_SOURCES"))
+	cfg.IPv6Sources = NewIPSources(splitEnvList("DUCKDNS_IPV6_SOURCES"))
+
+	// IPv6 の更新の有無の読み込み
+	if v := os.Getenv("DUCKDNS_IPV6"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("DUCKDNS_IPV6 の解析に失敗しました: %w", err)
+		}
+		cfg.Update.IPv6 = enabled
+	}
+
+	// ログ設定の読み込み
+	if level := os.Getenv("DUCKDNS_LOG_LEVEL"); level != "" {
+		cfg.Log.Level = level
+	}
+	if format := os.Getenv("DUCKDNS_LOG_FORMAT"); format != "" {
+		cfg.Log.Format = format
+	}
+	if file := os.Getenv("DUCKDNS_LOG_FILE"); file != "" {
+		cfg.Log.File = file
+	}
+
 	return cfg, nil
 }
 
+// splitEnvList は、カンマ区切りの環境変数を空要素を除いたリストにします。
+func splitEnvList(name string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // Load は、YAMLファイルと環境変数から設定を読み込みます。
 // 環境変数の値は、YAMLファイルの値より優先されます。
 //
@@ -231,16 +1698,40 @@ func LoadFromEnv() (*Config, error) {
 //   - *Config: 読み込まれた設定
 //   - error: エラーが発生した場合
 func Load(path string) (*Config, error) {
+	return LoadWithOptions(path, LoadOptions{})
+}
+
+// LoadWithOptions は、オプションを指定してYAMLファイルと環境変数から設定を読み込みます。
+// 環境変数の値は、YAMLファイルの値より優先されます。
+// opts.Profile が空の場合は、環境変数 DUCKDNS_PROFILE のプロファイルを適用します。
+//
+// Parameters:
+//   - path: 読み込むYAML設定ファイルのパス（空文字列の場合は環境変数のみ）
+//   - opts: 読み込みオプション
+//
+// Returns:
+//   - *Config: 読み込まれた設定
+//   - error: エラーが発生した場合
+func LoadWithOptions(path string, opts LoadOptions) (*Config, error) {
 	var cfg *Config
 	var err error
 
+	// プロファイルが指定されていない場合は環境変数 DUCKDNS_PROFILE を使用
+	if opts.Profile == "" {
+		opts.Profile = os.Getenv("DUCKDNS_PROFILE")
+	}
+
 	// YAMLファイルからの読み込み
 	if path != "" {
-		cfg, err = LoadFromFile(path)
+		cfg, err = LoadFromFileWithOptions(path, opts)
 		if err != nil {
 			return nil, err
 		}
 	} else {
+		// プロファイルは設定ファイルの中で定義されるため、ファイルなしでは使用できない
+		if opts.Profile != "" {
+			return nil, fmt.Errorf("プロファイル \"%s\" を使用するには設定ファイルが必要です", opts.Profile)
+		}
 		// ファイルパスが指定されていない場合は空の設定から開始
 		cfg = &Config{}
 	}
@@ -261,6 +1752,24 @@ func Load(path string) (*Config, error) {
 	if envCfg.Update.Interval != 0 {
 		cfg.Update.Interval = envCfg.Update.Interval
 	}
+	if len(envCfg.IPSources) > 0 {
+		cfg.IPSources = envCfg.IPSources
+	}
+	if len(envCfg.IPv6Sources) > 0 {
+		cfg.IPv6Sources = envCfg.IPv6Sources
+	}
+	if os.Getenv("DUCKDNS_IPV6") != "" {
+		cfg.Update.IPv6 = envCfg.Update.IPv6
+	}
+	if envCfg.Log.Level != "" {
+		cfg.Log.Level = envCfg.Log.Level
+	}
+	if envCfg.Log.Format != "" {
+		cfg.Log.Format = envCfg.Log.Format
+	}
+	if envCfg.Log.File != "" {
+		cfg.Log.File = envCfg.Log.File
+	}
 
 	return cfg, nil
 }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -96,6 +99,65 @@ func TestLoadFromFile_InvalidYAML(t *testing.T) {
 	}
 }
 
+// TestLoadFromFile_UnknownKey は、未知のキーが厳格モードでエラーになることをテストします。
+func TestLoadFromFile_UnknownKey(t *testing.T) {
+	tmpFile := t.TempDir() + "/typo.yaml"
+	content := `duckdns:
+  domain: "test-domain"
+  token: "test-token"
+update:
+  intervall: "5m"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	_, err := LoadFromFile(tmpFile)
+	if err == nil {
+		t.Fatal("未知のキーがある場合はエラーが返されるべき")
+	}
+	if !strings.Contains(err.Error(), "intervall") {
+		t.Errorf("エラーメッセージに未知のキー名が含まれるべき: %v", err)
+	}
+}
+
+// TestLoadFromFileWithOptions_AllowUnknownKeys は、緩和モードで未知のキーが無視されることをテストします。
+func TestLoadFromFileWithOptions_AllowUnknownKeys(t *testing.T) {
+	tmpFile := t.TempDir() + "/typo.yaml"
+	content := `duckdns:
+  domain: "test-domain"
+  token: "test-token"
+  unknown: "value"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	cfg, err := LoadFromFileWithOptions(tmpFile, LoadOptions{AllowUnknownKeys: true})
+	if err != nil {
+		t.Fatalf("緩和モードではエラーにならないはず: %v", err)
+	}
+	if cfg.DuckDNS.Domain != "test-domain" {
+		t.Errorf("ドメイン名が一致しません。期待: test-domain, 実際: %s", cfg.DuckDNS.Domain)
+	}
+}
+
+// TestLoadFromFile_Empty は、空のファイルが空の設定として読み込まれることをテストします。
+func TestLoadFromFile_Empty(t *testing.T) {
+	tmpFile := t.TempDir() + "/empty.yaml"
+	if err := os.WriteFile(tmpFile, nil, 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("空のファイルでエラーになるべきではありません: %v", err)
+	}
+	if cfg.DuckDNS.Domain != "" {
+		t.Errorf("ドメイン名は空であるべき。実際: %s", cfg.DuckDNS.Domain)
+	}
+}
+
 // TestLoadFromEnv は、環境変数からの読み込みをテストします。
 func TestLoadFromEnv(t *testing.T) {
 	tests := []struct {
@@ -307,9 +369,9 @@ func TestValidate_Success(t *testing.T) {
 		Update: UpdateConfig{
 			Interval: 5 * time.Minute,
 		},
-		IPSources: []string{
-			"https://api.ipify.org",
-			"https://ifconfig.me",
+		IPSources: IPSources{
+			{URL: "https://api.ipify.org"},
+			{URL: "https://ifconfig.me"},
 		},
 		Log: LogConfig{
 			Level:  "info",
@@ -332,7 +394,7 @@ func TestValidate_MissingDomain(t *testing.T) {
 		Update: UpdateConfig{
 			Interval: 5 * time.Minute,
 		},
-		IPSources: []string{"https://api.ipify.org"},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
 		Log: LogConfig{
 			Level:  "info",
 			Format: "json",
@@ -354,7 +416,7 @@ func TestValidate_MissingToken(t *testing.T) {
 		Update: UpdateConfig{
 			Interval: 5 * time.Minute,
 		},
-		IPSources: []string{"https://api.ipify.org"},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
 		Log: LogConfig{
 			Level:  "info",
 			Format: "json",
@@ -401,7 +463,7 @@ func TestValidate_InvalidInterval(t *testing.T) {
 				Update: UpdateConfig{
 					Interval: tt.interval,
 				},
-				IPSources: []string{"https://api.ipify.org"},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
 				Log: LogConfig{
 					Level:  "info",
 					Format: "json",
@@ -426,7 +488,7 @@ func TestValidate_NoIPSources(t *testing.T) {
 		Update: UpdateConfig{
 			Interval: 5 * time.Minute,
 		},
-		IPSources: []string{},
+		IPSources: IPSources{},
 		Log: LogConfig{
 			Level:  "info",
 			Format: "json",
@@ -488,7 +550,7 @@ func TestValidate_InvalidURL(t *testing.T) {
 				Update: UpdateConfig{
 					Interval: 5 * time.Minute,
 				},
-				IPSources: tt.ipSources,
+				IPSources: NewIPSources(tt.ipSources),
 				Log: LogConfig{
 					Level:  "info",
 					Format: "json",
@@ -552,7 +614,7 @@ func TestValidate_InvalidLogLevel(t *testing.T) {
 				Update: UpdateConfig{
 					Interval: 5 * time.Minute,
 				},
-				IPSources: []string{"https://api.ipify.org"},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
 				Log: LogConfig{
 					Level:  tt.level,
 					Format: "json",
@@ -606,7 +668,7 @@ func TestValidate_InvalidLogFormat(t *testing.T) {
 				Update: UpdateConfig{
 					Interval: 5 * time.Minute,
 				},
-				IPSources: []string{"https://api.ipify.org"},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
 				Log: LogConfig{
 					Level:  "info",
 					Format: tt.format,
@@ -665,3 +727,1424 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// TestTargets_DomainOverrides は、ドメインごとの上書きがトップレベルの値より優先されることをテストします。
+func TestTargets_DomainOverrides(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Domain: "main",
+			Token:  "shared-token",
+			Domains: []DomainConfig{
+				{
+					Name:      "fast",
+					Interval:  time.Minute,
+					IPSources: IPSources{{URL: "https://icanhazip.com"}},
+				},
+				{
+					Name:  "other-account",
+					Token: "other-token",
+				},
+			},
+		},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 3 {
+		t.Fatalf("更新対象の数が一致しません。期待: 3, 実際: %d", len(targets))
+	}
+
+	if targets[0].Domain != "main" || targets[0].Token != "shared-token" || targets[0].Interval != 5*time.Minute {
+		t.Errorf("トップレベルの対象が一致しません: %+v", targets[0])
+	}
+	if targets[1].Interval != time.Minute || targets[1].IPSources[0].URL != "https://icanhazip.com" || targets[1].Token != "shared-token" {
+		t.Errorf("間隔とIP取得ソースの上書きが反映されていません: %+v", targets[1])
+	}
+	if targets[2].Token != "other-token" || targets[2].Interval != 5*time.Minute || targets[2].IPSources[0].URL != "https://api.ipify.org" {
+		t.Errorf("トークンの上書きが反映されていません: %+v", targets[2])
+	}
+}
+
+// TestTargets_DomainIPv6Overrides は、ドメインやプロバイダーごとに IPv6 の更新と取得ソースを上書きできることをテストします。
+func TestTargets_DomainIPv6Overrides(t *testing.T) {
+	enabled, disabled := true, false
+	content := `duckdns:
+  domain: "main"
+  token: "test-token"
+  domains:
+    - name: "lan-v6"
+      ipv6_sources:
+        - "file:/run/eth0_ipv6"
+    - name: "v4-only"
+      ipv6: false
+update:
+  interval: "5m"
+  ipv6: true
+ip_sources:
+  - "https://api.ipify.org"
+ipv6_sources:
+  - "https://api6.ipify.org"
+providers:
+  - type: noip
+    username: "user"
+    password: "pass"
+    domains: ["a.ddns.net"]
+    ipv6: false
+`
+	tmpFile := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(tmpFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if cfg.DuckDNS.Domains[1].IPv6 == nil || *cfg.DuckDNS.Domains[1].IPv6 != disabled {
+		t.Fatalf("ipv6: false が読み込まれていません: %+v", cfg.DuckDNS.Domains[1])
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 4 {
+		t.Fatalf("更新対象の数が一致しません。期待: 4, 実際: %d", len(targets))
+	}
+	if !targets[0].IPv6 || targets[0].IPv6Sources[0].URL != "https://api6.ipify.org" {
+		t.Errorf("トップレベルの IPv6 の設定を引き継いでいません: %+v", targets[0])
+	}
+	if targets[1].IPv6 != enabled || targets[1].IPv6Sources[0].URL != "file:/run/eth0_ipv6" {
+		t.Errorf("IPv6 の取得ソースの上書きが反映されていません: %+v", targets[1])
+	}
+	if targets[2].IPv6 || targets[3].IPv6 {
+		t.Errorf("ipv6: false の上書きが反映されていません: %+v, %+v", targets[2], targets[3])
+	}
+
+	cfg.DuckDNS.Domains[0].IPv6Sources = IPSources{{URL: "not-a-url"}}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || strings.Join(ve.Keys, ",") != "duckdns.domains[0].ipv6_sources[0]" {
+		t.Errorf("ドメインごとの ipv6_sources も検証するべき: %v", err)
+	}
+}
+
+// TestValidate_DomainsOnly は、すべてのドメインが値を上書きしていればトップレベルを省略できることをテストします。
+func TestValidate_DomainsOnly(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Domains: []DomainConfig{
+				{Name: "a", Token: "token-a", Interval: time.Minute, IPSources: IPSources{{URL: "https://api.ipify.org"}}},
+				{Name: "b", Token: "token-b", Interval: time.Hour, IPSources: IPSources{{URL: "https://icanhazip.com"}}},
+			},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("バリデーションが失敗しました: %v", err)
+	}
+}
+
+// TestValidate_DomainsMissingValues は、上書きもトップレベルの値もない場合にエラーになることをテストします。
+func TestValidate_DomainsMissingValues(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Domains: []DomainConfig{
+				{Name: "a", Token: "token-a", Interval: time.Minute, IPSources: IPSources{{URL: "https://api.ipify.org"}}},
+				{Name: "", IPSources: IPSources{{URL: "not-a-url"}}},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("不足している値がある場合、エラーが返されるべき")
+	}
+
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("ValidationError が返されるべき: %T", err)
+	}
+	// トークン・更新間隔の不足、ドメイン名の欠落、無効なURL
+	if len(ve.Errors) != 4 {
+		t.Errorf("エラーの数が一致しません。期待: 4, 実際: %d (%v)", len(ve.Errors), ve.Errors)
+	}
+}
+
+// TestLoadFromFile_Domains は、YAMLからドメインごとの設定を読み込めることをテストします。
+func TestLoadFromFile_Domains(t *testing.T) {
+	tmpFile := t.TempDir() + "/domains.yaml"
+	content := `duckdns:
+  token: "shared"
+  domains:
+    - name: "home"
+      interval: "1m"
+    - name: "vps"
+      token: "vps-token"
+      ip_sources:
+        - "https://icanhazip.com"
+update:
+  interval: "10m"
+ip_sources:
+  - "https://api.ipify.org"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("バリデーションが失敗しました: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 2 {
+		t.Fatalf("更新対象の数が一致しません。期待: 2, 実際: %d", len(targets))
+	}
+	if targets[0].Interval != time.Minute {
+		t.Errorf("home の更新間隔が一致しません。期待: 1m, 実際: %v", targets[0].Interval)
+	}
+	if targets[1].Token != "vps-token" || targets[1].Interval != 10*time.Minute {
+		t.Errorf("vps の設定が一致しません: %+v", targets[1])
+	}
+}
+
+// TestLoadFromEnv_IPSourcesAndLog は、IP取得ソースとログ設定の環境変数をテストします。
+func TestLoadFromEnv_IPSourcesAndLog(t *testing.T) {
+	t.Setenv("DUCKDNS_IP_SOURCES", " https://api.ipify.org, ,https://icanhazip.com ")
+	t.Setenv("DUCKDNS_LOG_LEVEL", "debug")
+	t.Setenv("DUCKDNS_LOG_FORMAT", "json")
+	t.Setenv("DUCKDNS_LOG_FILE", "/var/log/duckdns.log")
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+
+	want := []string{"https://api.ipify.org", "https://icanhazip.com"}
+	if len(cfg.IPSources) != len(want) {
+		t.Fatalf("IP取得ソースの数が一致しません。期待: %v, 実際: %v", want, cfg.IPSources)
+	}
+	for i := range want {
+		if cfg.IPSources[i].URL != want[i] {
+			t.Errorf("IP取得ソース[%d]が一致しません。期待: %s, 実際: %s", i, want[i], cfg.IPSources[i])
+		}
+	}
+	if cfg.Log.Level != "debug" || cfg.Log.Format != "json" || cfg.Log.File != "/var/log/duckdns.log" {
+		t.Errorf("ログ設定が一致しません: %+v", cfg.Log)
+	}
+}
+
+// TestLoad_EnvOverridesIPSourcesAndLog は、環境変数がファイルのIP取得ソースとログ設定を上書きすることをテストします。
+func TestLoad_EnvOverridesIPSourcesAndLog(t *testing.T) {
+	tmpFile := t.TempDir() + "/config.yaml"
+	content := `duckdns:
+  domain: "file-domain"
+  token: "file-token"
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+log:
+  level: "info"
+  format: "text"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	t.Setenv("DUCKDNS_DOMAIN", "")
+	t.Setenv("DUCKDNS_TOKEN", "")
+	t.Setenv("DUCKDNS_INTERVAL", "")
+	t.Setenv("DUCKDNS_IP_SOURCES", "https://icanhazip.com")
+	t.Setenv("DUCKDNS_LOG_LEVEL", "warn")
+	t.Setenv("DUCKDNS_LOG_FORMAT", "")
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+
+	if len(cfg.IPSources) != 1 || cfg.IPSources[0].URL != "https://icanhazip.com" {
+		t.Errorf("IP取得ソースが環境変数で上書きされていません: %v", cfg.IPSources)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("ログレベルが環境変数で上書きされていません: %s", cfg.Log.Level)
+	}
+	if cfg.Log.Format != "text" {
+		t.Errorf("未設定の環境変数はファイルの値を維持するべき: %s", cfg.Log.Format)
+	}
+}
+
+// TestLoad_IPv6 は、update.ipv6 と ipv6_sources の読み込みと、更新対象への反映をテストします。
+func TestLoad_IPv6(t *testing.T) {
+	tmpFile := t.TempDir() + "/config.yaml"
+	content := `
+duckdns:
+  domain: "home"
+  token: "test-token"
+update:
+  interval: "5m"
+  ipv6: true
+ip_sources:
+  - "https://api.ipify.org"
+ipv6_sources:
+  - "https://api6.ipify.org"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テストファイルの作成に失敗しました: %v", err)
+	}
+	t.Setenv("DUCKDNS_IPV6", "")
+	t.Setenv("DUCKDNS_IPV6_SOURCES", "https://ipv6.icanhazip.com")
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("検証に失敗しました: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 1 || !targets[0].IPv6 {
+		t.Fatalf("更新対象で IPv6 が有効になるべき: %+v", targets)
+	}
+	if len(targets[0].IPv6Sources) != 1 || targets[0].IPv6Sources[0].URL != "https://ipv6.icanhazip.com" {
+		t.Errorf("IPv6 のIP取得ソースが環境変数で上書きされていません: %v", targets[0].IPv6Sources)
+	}
+
+	// 環境変数で無効にできる
+	t.Setenv("DUCKDNS_IPV6", "false")
+	if cfg, err = Load(tmpFile); err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if cfg.Update.IPv6 {
+		t.Error("DUCKDNS_IPV6=false で IPv6 が無効になるべき")
+	}
+
+	t.Setenv("DUCKDNS_IPV6", "maybe")
+	if _, err := Load(tmpFile); err == nil {
+		t.Error("DUCKDNS_IPV6 が真偽値でない場合はエラーになるべき")
+	}
+}
+
+// TestValidate_InvalidIPv6Sources は、ipv6_sources の URL を検証することをテストします。
+func TestValidate_InvalidIPv6Sources(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:     DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:      UpdateConfig{Interval: 5 * time.Minute, IPv6: true},
+		IPSources:   IPSources{{URL: "https://api.ipify.org"}},
+		IPv6Sources: IPSources{{URL: "not-a-url"}},
+	}
+
+	err := cfg.Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "ipv6_sources[0]" {
+		t.Errorf("ipv6_sources[0] のエラーになるべき: %v", err)
+	}
+}
+
+// TestTargets_NormalizeDomain は、FQDN や URL で指定したドメイン名がサブドメイン名に正規化されることをテストします。
+func TestTargets_NormalizeDomain(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Domain:  "Home.duckdns.org",
+			Token:   "test-token",
+			Domains: []DomainConfig{{Name: "https://vps.duckdns.org/"}},
+		},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("検証に失敗しました: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 2 || targets[0].Domain != "home" || targets[1].Domain != "vps" {
+		t.Errorf("ドメイン名が正規化されていません: %+v", targets)
+	}
+}
+
+// TestValidate_InvalidDomain は、DuckDNS のドメインとして使えない値を設定項目のキー付きで報告することをテストします。
+func TestValidate_InvalidDomain(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Domain:  "home.duckdns.com",
+			Token:   "test-token",
+			Domains: []DomainConfig{{Name: "my_domain"}},
+		},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "duckdns.domain,duckdns.domains[0].name" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+	if !strings.Contains(ve.Errors[0], "DuckDNS のドメインではありません") {
+		t.Errorf("エラーメッセージが分かりにくい: %s", ve.Errors[0])
+	}
+}
+
+// TestLoadFromFile_RateLimit は、duckdns.rate_limit の読み込みと検証をテストします。
+func TestLoadFromFile_RateLimit(t *testing.T) {
+	tmpFile := t.TempDir() + "/config.yaml"
+	content := `duckdns:
+  domain: "home"
+  token: "test-token"
+  rate_limit:
+    requests: 5
+    per: 10s
+update:
+  interval: 5m
+ip_sources:
+  - "https://api.ipify.org"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0600); err != nil {
+		t.Fatalf("設定ファイルの作成に失敗しました: %v", err)
+	}
+
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if cfg.DuckDNS.RateLimit.Requests != 5 || cfg.DuckDNS.RateLimit.Per != 10*time.Second {
+		t.Errorf("rate_limit = %+v, 期待: 10秒あたり5回", cfg.DuckDNS.RateLimit)
+	}
+
+	cfg.DuckDNS.RateLimit = RateLimitConfig{Requests: -1, Per: -time.Second}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "duckdns.rate_limit.requests,duckdns.rate_limit.per" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
+
+// TestValidate_InvalidCircuitBreaker は、duckdns.circuit_breaker の負の値がエラーになることをテストします。
+func TestValidate_InvalidCircuitBreaker(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Domain:         "home",
+			Token:          "test-token",
+			CircuitBreaker: CircuitBreakerConfig{FailureThreshold: -1, Cooldown: -time.Minute},
+		},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "duckdns.circuit_breaker.failure_threshold,duckdns.circuit_breaker.cooldown" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
+
+// TestValidate_InvalidRetry は、update.retry の不正な値を設定項目のキー付きで報告することをテストします。
+func TestValidate_InvalidRetry(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update: UpdateConfig{
+			Interval: 5 * time.Minute,
+			Retry: RetryConfig{
+				MaxRetries:      -1,
+				InitialInterval: 10 * time.Second,
+				Multiplier:      0.5,
+				MaxInterval:     time.Second,
+				Jitter:          1.5,
+			},
+		},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	want := "update.retry.max_retries,update.retry.multiplier,update.retry.max_interval,update.retry.jitter"
+	if strings.Join(ve.Keys, ",") != want {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
+
+// TestValidate_InvalidProxy は、network.proxy に対応していないプロキシを指定するとエラーになることをテストします。
+func TestValidate_InvalidProxy(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network:   NetworkConfig{Proxy: "socks4://127.0.0.1:1080"},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "network.proxy" {
+		t.Errorf("network.proxy のエラーになるべき: %v", err)
+	}
+
+	for _, proxy := range []string{"", "direct", "socks5://127.0.0.1:9050", "http://proxy.example.com:8080"} {
+		cfg.Network.Proxy = proxy
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("network.proxy %q は有効であるべき: %v", proxy, err)
+		}
+	}
+}
+
+// TestValidate_InvalidResolvers は、network.resolvers に IPアドレス以外を指定するとエラーになることをテストします。
+func TestValidate_InvalidResolvers(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network:   NetworkConfig{Resolvers: []string{"1.1.1.1:53", "dns.google"}},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "network.resolvers[1]" {
+		t.Errorf("network.resolvers[1] のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_InvalidDoHServers は、network.doh_servers に https 以外の URL を指定するとエラーになることをテストします。
+func TestValidate_InvalidDoHServers(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network: NetworkConfig{
+			DNSFallback: true,
+			DoHServers:  []string{"https://1.1.1.1/dns-query", "http://8.8.8.8/resolve"},
+		},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "network.doh_servers[1]" {
+		t.Errorf("network.doh_servers[1] のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_InvalidTLS は、network.ca_file と network.tls_min_version の無効な値がエラーになることをテストします。
+func TestValidate_InvalidTLS(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network: NetworkConfig{
+			CAFile:        t.TempDir() + "/missing.pem",
+			TLSMinVersion: "1.4",
+		},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "network.ca_file,network.tls_min_version" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
+
+// TestValidate_InvalidIPSourcePins は、network.ip_source_pins の無効なピンがエラーになることをテストします。
+func TestValidate_InvalidIPSourcePins(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network: NetworkConfig{
+			IPSourcePins: map[string][]string{
+				"api.ipify.org":    {"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="},
+				"icanhazip.com":    {"not-a-pin"},
+				"ifconfig.example": {},
+			},
+		},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "network.ip_source_pins[icanhazip.com],network.ip_source_pins[ifconfig.example]" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+
+	if opts := cfg.Network.TransportOptions(); opts.Pins != nil {
+		t.Error("DuckDNS の通信にはピン留めを使わないべき")
+	}
+	if opts := cfg.Network.IPSourceTransportOptions(); len(opts.Pins) != 3 {
+		t.Errorf("IP取得のピン留め = %v", opts.Pins)
+	}
+}
+
+// TestValidate_NegativeMaxResponseSize は、network.max_response_size に負の値を指定するとエラーになることをテストします。
+func TestValidate_NegativeMaxResponseSize(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network:   NetworkConfig{MaxResponseSize: -1},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "network.max_response_size" {
+		t.Errorf("network.max_response_size のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_IdleConns は、network.max_idle_conns と network.idle_conn_timeout に負の値を指定するとエラーになることをテストします。
+func TestValidate_IdleConns(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network:   NetworkConfig{MaxIdleConns: -1, IdleConnTimeout: -time.Second},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || strings.Join(ve.Keys, ",") != "network.max_idle_conns,network.idle_conn_timeout" {
+		t.Errorf("network.max_idle_conns と network.idle_conn_timeout のエラーになるべき: %v", err)
+	}
+
+	cfg.Network = NetworkConfig{MaxIdleConns: 4, IdleConnTimeout: 30 * time.Second, DisableKeepAlives: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("検証に失敗しました: %v", err)
+	}
+	opts := cfg.Network.TransportOptions()
+	if opts.MaxIdleConns != 4 || opts.IdleConnTimeout != 30*time.Second || !opts.DisableKeepAlives {
+		t.Errorf("接続の再利用の設定が通信設定に反映されるべき: %+v", opts)
+	}
+}
+
+// TestValidate_AddressFamily は、duckdns.address_family に ipv4 / ipv6 以外を指定するとエラーになることをテストします。
+func TestValidate_AddressFamily(t *testing.T) {
+	for family, valid := range map[string]bool{"": true, "ipv4": true, "ipv6": true, "IPv6": true, "tcp4": false} {
+		cfg := &Config{
+			DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token", AddressFamily: family},
+			Update:    UpdateConfig{Interval: 5 * time.Minute},
+			IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		}
+
+		var ve *ValidationError
+		err := cfg.Validate()
+		if valid && err != nil {
+			t.Errorf("%q: 検証に成功するべき: %v", family, err)
+		}
+		if !valid && (!errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "duckdns.address_family") {
+			t.Errorf("%q: duckdns.address_family のエラーになるべき: %v", family, err)
+		}
+	}
+}
+
+// TestLoadFromFile_IPFetch は、ip_fetch を読み込み、無効な値がエラーになることをテストします。
+func TestLoadFromFile_IPFetch(t *testing.T) {
+	tmpFile := t.TempDir() + "/config.yaml"
+	content := `duckdns:
+  domain: "home"
+  token: "test-token"
+update:
+  interval: 5m
+ip_sources:
+  - "https://api.ipify.org"
+ip_fetch:
+  cache_ttl: 30s
+  strategy: round_robin
+  failure_threshold: 5
+  cooldown: 15m
+  suspicious: reject
+  cross_check: true
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0600); err != nil {
+		t.Fatalf("設定ファイルの作成に失敗しました: %v", err)
+	}
+
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	want := IPFetchConfig{CacheTTL: 30 * time.Second, Strategy: "round_robin", FailureThreshold: 5, Cooldown: 15 * time.Minute, Suspicious: "reject", CrossCheck: true}
+	if cfg.IPFetch != want {
+		t.Errorf("ip_fetch = %+v, 期待値 %+v", cfg.IPFetch, want)
+	}
+
+	cfg.IPFetch = IPFetchConfig{CacheTTL: -time.Second, Strategy: "slowest", FailureThreshold: -1, Cooldown: -time.Second, Suspicious: "block"}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || strings.Join(ve.Keys, ",") != "ip_fetch.cache_ttl,ip_fetch.strategy,ip_fetch.failure_threshold,ip_fetch.cooldown,ip_fetch.suspicious" {
+		t.Errorf("ip_fetch のすべての項目のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_FileIPSources は、file: のソースは絶対パスの場合だけ有効になることをテストします。
+func TestValidate_FileIPSources(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:  UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{
+			{URL: "file:/run/wan_ip"},
+			{URL: "file:///run/wan_ip"},
+			{URL: "file:wan_ip"},
+			{URL: "file://router/run/wan_ip"},
+		},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "ip_sources[2],ip_sources[3]" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
+
+// TestValidate_UpdateWorkers は、update.workers に負の値を指定するとエラーになることをテストします。
+func TestValidate_UpdateWorkers(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute, Workers: 4},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("update.workers が正の値の場合は有効であるべき: %v", err)
+	}
+
+	cfg.Update.Workers = -1
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "update.workers" {
+		t.Errorf("update.workers のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_ShutdownGrace は、update.shutdown_grace のバリデーションをテストします。
+func TestValidate_ShutdownGrace(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute, ShutdownGrace: 10 * time.Second},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("update.shutdown_grace が正の値の場合は有効であるべき: %v", err)
+	}
+
+	cfg.Update.ShutdownGrace = -time.Second
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "update.shutdown_grace" {
+		t.Errorf("update.shutdown_grace のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_OnShutdown は、停止するときの処理（update.on_shutdown）のバリデーションをテストします。
+func TestValidate_OnShutdown(t *testing.T) {
+	tests := []struct {
+		name       string
+		onShutdown ShutdownActionConfig
+		wantKeys   []string
+	}{
+		{name: "省略", onShutdown: ShutdownActionConfig{}},
+		{name: "消去", onShutdown: ShutdownActionConfig{Action: "Clear", Timeout: 5 * time.Second}},
+		{name: "IPアドレスに更新", onShutdown: ShutdownActionConfig{Action: "set_ip", IP: "192.0.2.1"}},
+		{name: "通知", onShutdown: ShutdownActionConfig{Action: "webhook", Webhook: "https://example.com/hook"}},
+		{name: "無効な処理", onShutdown: ShutdownActionConfig{Action: "delete"}, wantKeys: []string{"update.on_shutdown.action"}},
+		{name: "IPアドレスがない", onShutdown: ShutdownActionConfig{Action: "set_ip"}, wantKeys: []string{"update.on_shutdown.ip"}},
+		{name: "IPv6アドレス", onShutdown: ShutdownActionConfig{Action: "set_ip", IP: "2001:db8::1"}, wantKeys: []string{"update.on_shutdown.ip"}},
+		{name: "URL がない", onShutdown: ShutdownActionConfig{Action: "webhook"}, wantKeys: []string{"update.on_shutdown.webhook"}},
+		{name: "無効な URL", onShutdown: ShutdownActionConfig{Action: "webhook", Webhook: "example.com"}, wantKeys: []string{"update.on_shutdown.webhook"}},
+		{name: "負のタイムアウト", onShutdown: ShutdownActionConfig{Timeout: -time.Second}, wantKeys: []string{"update.on_shutdown.timeout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute, OnShutdown: tt.onShutdown},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestShutdownActionConfig_Defaults は、停止するときの処理の既定値をテストします。
+func TestShutdownActionConfig_Defaults(t *testing.T) {
+	var s ShutdownActionConfig
+	if s.ActionName() != ShutdownActionNone {
+		t.Errorf("省略した場合は none であるべき。実際: %s", s.ActionName())
+	}
+	if s.TimeoutOrDefault() != DefaultShutdownActionTimeout {
+		t.Errorf("省略した場合は既定のタイムアウトであるべき。実際: %v", s.TimeoutOrDefault())
+	}
+	s = ShutdownActionConfig{Action: " SET_IP ", Timeout: 3 * time.Second}
+	if s.ActionName() != ShutdownActionSetIP || s.TimeoutOrDefault() != 3*time.Second {
+		t.Errorf("指定した値を返すべき: %s %v", s.ActionName(), s.TimeoutOrDefault())
+	}
+}
+
+// TestValidate_ReleaseCheck は、新しいバージョンの確認（release_check）のバリデーションをテストします。
+func TestValidate_ReleaseCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		releaseCheck ReleaseCheckConfig
+		wantKeys     []string
+	}{
+		{name: "省略", releaseCheck: ReleaseCheckConfig{}},
+		{name: "有効", releaseCheck: ReleaseCheckConfig{Enabled: true, Interval: 12 * time.Hour, Repository: "owner/fork"}},
+		{name: "短すぎる間隔", releaseCheck: ReleaseCheckConfig{Enabled: true, Interval: 10 * time.Minute}, wantKeys: []string{"release_check.interval"}},
+		{name: "負の間隔", releaseCheck: ReleaseCheckConfig{Interval: -time.Hour}, wantKeys: []string{"release_check.interval"}},
+		{name: "無効なリポジトリ", releaseCheck: ReleaseCheckConfig{Repository: "duckdns"}, wantKeys: []string{"release_check.repo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:      DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:       UpdateConfig{Interval: 5 * time.Minute},
+				IPSources:    IPSources{{URL: "https://api.ipify.org"}},
+				ReleaseCheck: tt.releaseCheck,
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+
+	if got := (ReleaseCheckConfig{}).IntervalOrDefault(); got != DefaultReleaseCheckInterval {
+		t.Errorf("省略した場合は既定の間隔であるべき。実際: %v", got)
+	}
+}
+
+// TestValidate_Trigger は、更新の通知の受け口（trigger）のバリデーションをテストします。
+func TestValidate_Trigger(t *testing.T) {
+	tests := []struct {
+		name     string
+		trigger  TriggerConfig
+		wantKeys []string
+	}{
+		{name: "省略", trigger: TriggerConfig{}},
+		{name: "有効", trigger: TriggerConfig{Enabled: true, Listen: "192.168.1.10:8245", Username: "router", Password: "secret"}},
+		{name: "認証なし", trigger: TriggerConfig{Enabled: true}, wantKeys: []string{"trigger.username", "trigger.password"}},
+		{name: "無効なアドレス", trigger: TriggerConfig{Listen: "8245"}, wantKeys: []string{"trigger.listen"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+				Trigger:   tt.trigger,
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+
+	if got := (TriggerConfig{}).ListenOrDefault(); got != DefaultTriggerListen {
+		t.Errorf("省略した場合は既定のアドレスであるべき。実際: %v", got)
+	}
+}
+
+// TestValidate_Metrics は、メトリクスの公開（metrics）のバリデーションをテストします。
+func TestValidate_Metrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		metrics  MetricsConfig
+		wantKeys []string
+	}{
+		{name: "省略", metrics: MetricsConfig{}},
+		{name: "有効", metrics: MetricsConfig{Enabled: true, Listen: "127.0.0.1:9245"}},
+		{name: "無効なアドレス", metrics: MetricsConfig{Enabled: true, Listen: "9245"}, wantKeys: []string{"metrics.listen"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+				Metrics:   tt.metrics,
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+
+	if got := (MetricsConfig{}).ListenOrDefault(); got != DefaultMetricsListen {
+		t.Errorf("省略した場合は既定のアドレスであるべき。実際: %v", got)
+	}
+}
+
+// TestValidate_LeaderElection は、リーダー選出（leader_election）のバリデーションをテストします。
+func TestValidate_LeaderElection(t *testing.T) {
+	tests := []struct {
+		name     string
+		election LeaderElectionConfig
+		wantKeys []string
+	}{
+		{name: "省略", election: LeaderElectionConfig{}},
+		{name: "ファイル", election: LeaderElectionConfig{Enabled: true, Path: "/mnt/shared/duckdns.lease"}},
+		{name: "Kubernetes", election: LeaderElectionConfig{Enabled: true, Backend: "kubernetes", LeaseDuration: 30 * time.Second, RetryPeriod: 10 * time.Second}},
+		{name: "ファイルのパスなし", election: LeaderElectionConfig{Enabled: true}, wantKeys: []string{"leader_election.path"}},
+		{name: "不明な保存先", election: LeaderElectionConfig{Backend: "redis"}, wantKeys: []string{"leader_election.backend"}},
+		{name: "間隔が長すぎる", election: LeaderElectionConfig{LeaseDuration: 10 * time.Second, RetryPeriod: 5 * time.Second}, wantKeys: []string{"leader_election.retry_period"}},
+		{name: "負の期限", election: LeaderElectionConfig{LeaseDuration: -time.Second}, wantKeys: []string{"leader_election.lease_duration"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:        DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:         UpdateConfig{Interval: 5 * time.Minute},
+				IPSources:      IPSources{{URL: "https://api.ipify.org"}},
+				LeaderElection: tt.election,
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestValidate_State は、state の設定のバリデーションをテストします。
+func TestValidate_State(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    StateConfig
+		wantKeys []string
+	}{
+		{name: "省略", state: StateConfig{}},
+		{name: "ファイル", state: StateConfig{Backend: "file"}},
+		{name: "Redis", state: StateConfig{Backend: "redis", Redis: RedisConfig{Address: "redis:6379", DB: 1, Timeout: time.Second}}},
+		{name: "Redis のアドレスなし", state: StateConfig{Backend: "redis"}, wantKeys: []string{"state.redis.address"}},
+		{name: "ポートのないアドレス", state: StateConfig{Backend: "redis", Redis: RedisConfig{Address: "redis"}}, wantKeys: []string{"state.redis.address"}},
+		{name: "不明な保存先", state: StateConfig{Backend: "etcd"}, wantKeys: []string{"state.backend"}},
+		{name: "負の番号とタイムアウト", state: StateConfig{Redis: RedisConfig{DB: -1, Timeout: -time.Second}}, wantKeys: []string{"state.redis.db", "state.redis.timeout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+				State:     tt.state,
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestValidate_WaitForNetwork は、接続の確認に関する設定（update.wait_for_network など）のバリデーションをテストします。
+func TestValidate_WaitForNetwork(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute, WaitForNetwork: 2 * time.Minute, Precheck: "DNS"},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Network:   NetworkConfig{ProbeURL: "http://192.168.1.1/"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("有効な設定はエラーにならないべき: %v", err)
+	}
+
+	cfg.Update.WaitForNetwork = -time.Second
+	cfg.Update.OfflineRetryInterval = -time.Second
+	cfg.Update.Precheck = "ping"
+	cfg.Network.ProbeURL = "ftp://example.com/"
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"update.wait_for_network", "update.offline_retry_interval", "update.precheck", "network.probe_url"}) {
+		t.Errorf("update.wait_for_network、update.offline_retry_interval、update.precheck と network.probe_url のエラーになるべき: %v", err)
+	}
+}
+
+// TestTargets_Accounts は、duckdns.accounts のドメインをアカウントのトークンで更新することをテストします。
+func TestTargets_Accounts(t *testing.T) {
+	content := `duckdns:
+  domain: "mine"
+  token: "my-token"
+  accounts:
+    - name: "parents"
+      token: "parents-token"
+      domains: ["parents-house", "parents-nas.duckdns.org"]
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+`
+	tmpFile := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(tmpFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 3 {
+		t.Fatalf("更新対象の数が一致しません。期待: 3, 実際: %d", len(targets))
+	}
+	if targets[0].Token != "my-token" || targets[0].Account != "" {
+		t.Errorf("トップレベルの対象が一致しません: %+v", targets[0])
+	}
+	for _, target := range targets[1:] {
+		if target.Token != "parents-token" || target.Account != "parents" || target.Interval != 5*time.Minute {
+			t.Errorf("アカウントの対象が一致しません: %+v", target)
+		}
+	}
+	if targets[2].Domain != "parents-nas" {
+		t.Errorf("ドメイン名が正規化されていません: %q", targets[2].Domain)
+	}
+}
+
+// TestTargets_AccountInterval は、アカウントごとに更新間隔を上書きでき、トップレベルの update.interval を省略できることをテストします。
+func TestTargets_AccountInterval(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Accounts: []AccountConfig{
+				{Token: "token-a", Domains: []string{"fast"}, Interval: time.Minute},
+				{Token: "token-b", Domains: []string{"slow"}, Interval: time.Hour},
+			},
+		},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("すべてのアカウントが更新間隔を上書きしていれば有効であるべき: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 2 || targets[0].Interval != time.Minute || targets[1].Interval != time.Hour {
+		t.Errorf("アカウントの更新間隔が反映されていません: %+v", targets)
+	}
+
+	cfg.DuckDNS.Accounts[1].Interval = -time.Minute
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Contains(ve.Keys, "duckdns.accounts[1].interval") {
+		t.Errorf("負の更新間隔はエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_Accounts は、アカウントだけでトップレベルのトークンを省略でき、無効なアカウントがエラーになることをテストします。
+func TestValidate_Accounts(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{
+			Accounts: []AccountConfig{
+				{Name: "a", Token: "token-a", Domains: []string{"home"}},
+				{Name: "b", Token: "token-b", Domains: []string{"parents"}},
+			},
+		},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("アカウントだけの設定は有効であるべき: %v", err)
+	}
+
+	cfg.DuckDNS.Accounts = append(cfg.DuckDNS.Accounts,
+		AccountConfig{Name: "c"},
+		AccountConfig{Token: "token-d", Domains: []string{"", "bad.example.com"}},
+	)
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	want := "duckdns.accounts[2].token,duckdns.accounts[2].domains,duckdns.accounts[3].domains[0],duckdns.accounts[3].domains[1]"
+	if strings.Join(ve.Keys, ",") != want {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
+
+// TestValidate_MQTT は、mqtt の設定のバリデーションをテストします。
+func TestValidate_MQTT(t *testing.T) {
+	tests := []struct {
+		name     string
+		mqtt     MQTTConfig
+		wantKeys []string
+	}{
+		{name: "省略", mqtt: MQTTConfig{}},
+		{name: "Home Assistant", mqtt: MQTTConfig{Enabled: true, Broker: "tcp://mosquitto:1883", KeepAlive: 30 * time.Second, HomeAssistant: HomeAssistantConfig{Discovery: true}}},
+		{name: "TLS", mqtt: MQTTConfig{Enabled: true, Broker: "mqtts://broker.example.com", TopicPrefix: "home/duckdns"}},
+		{name: "ブローカーなし", mqtt: MQTTConfig{Enabled: true}, wantKeys: []string{"mqtt.broker"}},
+		{name: "不明なスキーム", mqtt: MQTTConfig{Enabled: true, Broker: "http://mosquitto"}, wantKeys: []string{"mqtt.broker"}},
+		{name: "長すぎる間隔", mqtt: MQTTConfig{KeepAlive: 24 * time.Hour}, wantKeys: []string{"mqtt.keep_alive"}},
+		{name: "ワイルドカード", mqtt: MQTTConfig{TopicPrefix: "duckdns/#", HomeAssistant: HomeAssistantConfig{DiscoveryPrefix: "+"}}, wantKeys: []string{"mqtt.topic_prefix", "mqtt.home_assistant.discovery_prefix"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+				MQTT:      tt.mqtt,
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestValidate_Timeouts は、update.timeouts の段階ごとのタイムアウトの検証をテストします。
+func TestValidate_Timeouts(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute, Timeouts: TimeoutsConfig{IPFetch: 30 * time.Second, Update: time.Minute}},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("エラーにならないべき: %v", err)
+	}
+
+	cfg.Update.Timeouts = TimeoutsConfig{Probe: -time.Second, Hook: -time.Second}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"update.timeouts.probe", "update.timeouts.hook"}) {
+		t.Errorf("負のタイムアウトはエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_AutoDetect は、update.auto_detect は DuckDNS のドメインだけで使用でき、providers と一緒に指定するとエラーになることをテストします。
+func TestValidate_AutoDetect(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute, AutoDetect: true},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("エラーにならないべき: %v", err)
+	}
+
+	cfg.Providers = []ProviderConfig{{Type: ProviderNoIP, Username: "user", Password: "pass", Domains: []string{"home.example.com"}}}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"update.auto_detect"}) {
+		t.Errorf("providers と一緒に指定するとエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_CrossCheck は、update.cross_check は0以上で、update.auto_detect と一緒に指定する必要があることをテストします。
+func TestValidate_CrossCheck(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute, AutoDetect: true, CrossCheck: 3},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("エラーにならないべき: %v", err)
+	}
+
+	for _, update := range []UpdateConfig{
+		{Interval: 5 * time.Minute, AutoDetect: true, CrossCheck: -1},
+		{Interval: 5 * time.Minute, CrossCheck: 3},
+	} {
+		cfg.Update = update
+		var ve *ValidationError
+		if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"update.cross_check"}) {
+			t.Errorf("%+v: update.cross_check のエラーになるべき: %v", update, err)
+		}
+	}
+}
+
+// TestValidate_FailureAlert は、続けて失敗したときに知らせる設定（update.failure_alert）のバリデーションをテストします。
+func TestValidate_FailureAlert(t *testing.T) {
+	tests := []struct {
+		name     string
+		alert    FailureAlertConfig
+		wantKeys []string
+	}{
+		{name: "省略", alert: FailureAlertConfig{}},
+		{name: "ログだけ", alert: FailureAlertConfig{Threshold: 3}},
+		{name: "webhook", alert: FailureAlertConfig{Threshold: 3, Webhook: "https://example.com/hook"}},
+		{name: "負の回数", alert: FailureAlertConfig{Threshold: -1}, wantKeys: []string{"update.failure_alert.threshold"}},
+		{name: "回数なし", alert: FailureAlertConfig{Webhook: "https://example.com/hook"}, wantKeys: []string{"update.failure_alert.threshold"}},
+		{name: "無効な URL", alert: FailureAlertConfig{Threshold: 3, Webhook: "example.com/hook"}, wantKeys: []string{"update.failure_alert.webhook"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute, FailureAlert: tt.alert},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+			}
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestValidate_Watchdog は、update.watchdog のバリデーションをテストします。
+func TestValidate_Watchdog(t *testing.T) {
+	for _, tt := range []struct {
+		watchdog int
+		wantErr  bool
+	}{
+		{watchdog: 0},
+		{watchdog: 2},
+		{watchdog: 5},
+		{watchdog: 1, wantErr: true},
+		{watchdog: -1, wantErr: true},
+	} {
+		cfg := &Config{
+			DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+			Update:    UpdateConfig{Interval: 5 * time.Minute, Watchdog: tt.watchdog},
+			IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		}
+		err := cfg.Validate()
+		var ve *ValidationError
+		switch {
+		case !tt.wantErr && err != nil:
+			t.Errorf("watchdog=%d: エラーにならないべき: %v", tt.watchdog, err)
+		case tt.wantErr && (!errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"update.watchdog"})):
+			t.Errorf("watchdog=%d: update.watchdog のエラーになるべき: %v", tt.watchdog, err)
+		}
+	}
+}
+
+// TestValidate_Digest は、update.digest のバリデーションをテストします。
+func TestValidate_Digest(t *testing.T) {
+	tests := []struct {
+		name     string
+		digest   DigestConfig
+		wantKeys []string
+	}{
+		{"省略", DigestConfig{}, nil},
+		{"webhook だけ", DigestConfig{Webhook: "https://example.com/hooks/duckdns"}, nil},
+		{"時刻も指定", DigestConfig{Webhook: "https://example.com/hooks/duckdns", At: "08:00"}, nil},
+		{"無効な URL", DigestConfig{Webhook: "not a url"}, []string{"update.digest.webhook"}},
+		{"無効な時刻", DigestConfig{Webhook: "https://example.com/hooks/duckdns", At: "25:00"}, []string{"update.digest.at"}},
+		{"webhook がない", DigestConfig{At: "08:00"}, []string{"update.digest.webhook"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute, Digest: tt.digest},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+			}
+			err := cfg.Validate()
+			if tt.wantKeys == nil {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestValidate_TimeWindows は、update.schedule と update.quiet_hours の時間帯の検証をテストします。
+func TestValidate_TimeWindows(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedule   []string
+		quietHours []string
+		wantKeys   []string
+	}{
+		{"省略", nil, nil, nil},
+		{"日付をまたぐ時間帯", nil, []string{"23:00-06:00"}, nil},
+		{"1日の終わりまで", []string{"08:00-24:00"}, nil, nil},
+		{"形式が違う", []string{"8時から17時"}, nil, []string{"update.schedule[0]"}},
+		{"区切りがない", nil, []string{"23:00"}, []string{"update.quiet_hours[0]"}},
+		{"無効な時刻", nil, []string{"01:00-02:00", "22:00-25:00"}, []string{"update.quiet_hours[1]"}},
+		{"始まりに 24:00", []string{"24:00-06:00"}, nil, []string{"update.schedule[0]"}},
+		{"始まりと終わりが同じ", nil, []string{"03:00-03:00"}, []string{"update.quiet_hours[0]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute, Schedule: tt.schedule, QuietHours: tt.quietHours},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+			}
+			err := cfg.Validate()
+			if tt.wantKeys == nil {
+				if err != nil {
+					t.Errorf("エラーにならないべき: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !slices.Equal(ve.Keys, tt.wantKeys) {
+				t.Errorf("%v のエラーになるべき: %v", tt.wantKeys, err)
+			}
+		})
+	}
+}
+
+// TestParseTimeWindow は、時間帯の始まりと終わりを 0時からの時間に変換することをテストします。
+func TestParseTimeWindow(t *testing.T) {
+	start, end, err := ParseTimeWindow(" 23:30 - 06:00 ")
+	if err != nil || start != 23*time.Hour+30*time.Minute || end != 6*time.Hour {
+		t.Errorf("ParseTimeWindow() = %s, %s, %v, want 23h30m, 6h, nil", start, end, err)
+	}
+	if _, end, err := ParseTimeWindow("00:00-24:00"); err != nil || end != 24*time.Hour {
+		t.Errorf("終わりの 24:00 は 24h であるべき: %s, %v", end, err)
+	}
+}
+
+// TestDigestConfig_TimeOfDay は、まとめを知らせる時刻の時と分をテストします。
+func TestDigestConfig_TimeOfDay(t *testing.T) {
+	if h, m, ok := (DigestConfig{At: "08:30"}).TimeOfDay(); !ok || h != 8 || m != 30 {
+		t.Errorf("TimeOfDay() = %d, %d, %v, want 8, 30, true", h, m, ok)
+	}
+	if _, _, ok := (DigestConfig{}).TimeOfDay(); ok {
+		t.Error("時刻を省略した場合は false になるべき")
+	}
+}
+
+// TestValidate_SummaryInterval は、log.summary_interval のバリデーションをテストします。
+func TestValidate_SummaryInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		wantErr  bool
+	}{
+		{"省略", 0, false},
+		{"6時間", 6 * time.Hour, false},
+		{"下限", MinSummaryInterval, false},
+		{"短すぎる", 30 * time.Second, true},
+		{"負の値", -time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+				Update:    UpdateConfig{Interval: 5 * time.Minute},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+				Log:       LogConfig{SummaryInterval: tt.interval},
+			}
+			err := cfg.Validate()
+			var ve *ValidationError
+			if tt.wantErr && (!errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"log.summary_interval"})) {
+				t.Errorf("log.summary_interval のエラーになるべき: %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("エラーにならないべき: %v", err)
+			}
+		})
+	}
+}
+
+// TestValidate_Gops は、gops のバリデーションをテストします。
+func TestValidate_Gops(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Gops:      GopsConfig{Enabled: true, Listen: "127.0.0.1:6060"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("エラーにならないべき: %v", err)
+	}
+
+	cfg.Gops.Listen = "6060"
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"gops.listen"}) {
+		t.Errorf("gops.listen のエラーになるべき: %v", err)
+	}
+}
+
+// TestValidate_CrashReport は、crash_report のバリデーションをテストします。
+func TestValidate_CrashReport(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:     DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:      UpdateConfig{Interval: 5 * time.Minute},
+		IPSources:   IPSources{{URL: "https://api.ipify.org"}},
+		CrashReport: CrashReportConfig{Dir: "/var/lib/duckdns/crash", MaxReports: 5},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("エラーにならないべき: %v", err)
+	}
+
+	cfg.CrashReport.MaxReports = -1
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || !slices.Equal(ve.Keys, []string{"crash_report.max_reports"}) {
+		t.Errorf("crash_report.max_reports のエラーになるべき: %v", err)
+	}
+}
+
+// TestUpdateConfig_ShutdownTimeout は、停止するときの処理のタイムアウトが on_shutdown.timeout、timeouts.hook、既定値の順になることをテストします。
+func TestUpdateConfig_ShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		update UpdateConfig
+		want   time.Duration
+	}{
+		{name: "省略", want: DefaultShutdownActionTimeout},
+		{name: "フック", update: UpdateConfig{Timeouts: TimeoutsConfig{Hook: time.Minute}}, want: time.Minute},
+		{name: "on_shutdown を優先", update: UpdateConfig{OnShutdown: ShutdownActionConfig{Timeout: 5 * time.Second}, Timeouts: TimeoutsConfig{Hook: time.Minute}}, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.update.ShutdownTimeout(); got != tt.want {
+				t.Errorf("ShutdownTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// configFileName は、標準パスで探索する設定ファイル名です。
+const configFileName = "config.yaml"
+
+// DefaultSearchPaths は、-config が指定されなかった場合に
+// 設定ファイルを探索する標準パスの一覧を優先度順に返します。
+//
+// 探索順:
+//  1. $XDG_CONFIG_HOME/duckdns/config.yaml（未設定時は ~/.config/duckdns/config.yaml）
+//  2. /etc/duckdns/config.yaml
+//  3. カレントディレクトリの config.yaml
+//
+// Returns:
+//   - []string: 探索対象のパス一覧
+func DefaultSearchPaths() []string {
+	var paths []string
+
+	// XDG Base Directory に従ったユーザー設定ディレクトリ
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil && home != "" {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "duckdns", configFileName))
+	}
+
+	// システム全体の設定ディレクトリ
+	paths = append(paths, filepath.Join("/etc", "duckdns", configFileName))
+
+	// カレントディレクトリ
+	if wd, err := os.Getwd(); err == nil {
+		paths = append(paths, filepath.Join(wd, configFileName))
+	}
+
+	return paths
+}
+
+// FindConfigFile は、指定されたパスを順番に確認し、
+// 最初に見つかった通常ファイルのパスを返します。
+//
+// Parameters:
+//   - paths: 探索するパスの一覧（優先度順）
+//
+// Returns:
+//   - string: 見つかった設定ファイルのパス
+//   - bool: 設定ファイルが見つかった場合は true
+func FindConfigFile(paths []string) (string, bool) {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		return path, true
+	}
+	return "", false
+}
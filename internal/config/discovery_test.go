@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDefaultSearchPaths は、XDG_CONFIG_HOME が探索パスの先頭になることをテストします。
+func TestDefaultSearchPaths(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	paths := DefaultSearchPaths()
+	if len(paths) < 2 {
+		t.Fatalf("探索パスが不足しています: %v", paths)
+	}
+
+	want := filepath.Join(xdg, "duckdns", "config.yaml")
+	if paths[0] != want {
+		t.Errorf("先頭の探索パスが一致しません。期待: %s, 実際: %s", want, paths[0])
+	}
+	if paths[1] != "/etc/duckdns/config.yaml" {
+		t.Errorf("2番目の探索パスが一致しません。期待: /etc/duckdns/config.yaml, 実際: %s", paths[1])
+	}
+}
+
+// TestFindConfigFile は、最初に存在するファイルが選ばれることをテストします。
+func TestFindConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.yaml")
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+
+	for _, p := range []string{first, second} {
+		if err := os.WriteFile(p, []byte("duckdns: {}\n"), 0600); err != nil {
+			t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+		}
+	}
+
+	path, ok := FindConfigFile([]string{missing, dir, first, second})
+	if !ok {
+		t.Fatal("設定ファイルが見つかるべきです")
+	}
+	if path != first {
+		t.Errorf("選ばれたパスが一致しません。期待: %s, 実際: %s", first, path)
+	}
+}
+
+// TestFindConfigFile_NotFound は、どのパスにも存在しない場合をテストします。
+func TestFindConfigFile_NotFound(t *testing.T) {
+	dir := t.TempDir()
+
+	if path, ok := FindConfigFile([]string{filepath.Join(dir, "a.yaml"), dir}); ok {
+		t.Errorf("設定ファイルは見つからないはずです: %s", path)
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth は、include のネストの最大深さです。
+// 循環参照の検出とは別に、異常に深いネストを防ぎます。
+const maxIncludeDepth = 8
+
+// loadMerged は、指定されたファイルを起点に include を解決し、
+// すべての断片をマージしてからプロファイルを適用した設定を返します。
+//
+// マージの規則:
+//   - include に列挙された断片は、記述されたファイルの内容の後に順番に適用されます
+//   - 選択されたプロファイルは、すべての断片をマージした後に適用されます
+//   - マップ（duckdns, update など）は再帰的にマージされます
+//   - スカラー値とリスト（ip_sources など）は後から読み込んだ値で置き換えられます
+func loadMerged(path string, opts LoadOptions) (*Config, error) {
+	merged, err := loadMergedMap(path, opts, map[string]bool{}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyProfile(merged, opts.Profile); err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("マージした設定の変換に失敗しました: %w", err)
+	}
+
+	var cfg Config
+	if err := decodeYAML(data, &cfg, opts); err != nil {
+		return nil, fmt.Errorf("マージした設定の解析に失敗しました: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// loadMergedMap は、1つのファイルとその include 先を読み込み、
+// マージ済みの汎用マップとして返します。
+func loadMergedMap(path string, opts LoadOptions, visiting map[string]bool, depth int) (map[string]interface{}, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("include のネストが深すぎます (最大 %d): %s", maxIncludeDepth, path)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("パスの解決に失敗しました (%s): %w", path, err)
+	}
+	if visiting[absPath] {
+		return nil, fmt.Errorf("include が循環しています: %s", path)
+	}
+	visiting[absPath] = true
+	defer delete(visiting, absPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("設定ファイルが見つかりません: %s", path)
+		}
+		return nil, fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	// 構造体へのデコードで、断片ごとにキーの妥当性を検証する
+	var fragment Config
+	if err := decodeYAML(data, &fragment, opts); err != nil {
+		return nil, fmt.Errorf("YAML解析に失敗しました (%s): %w", path, err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("YAML解析に失敗しました (%s): %w", path, err)
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	delete(raw, "include")
+
+	for _, pattern := range fragment.Include {
+		files, err := resolveInclude(filepath.Dir(path), pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			child, err := loadMergedMap(file, opts, visiting, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			mergeMaps(raw, child)
+		}
+	}
+
+	return raw, nil
+}
+
+// resolveInclude は、include の1エントリを実際のファイルパスのリストに展開します。
+// ディレクトリの場合は直下の *.yaml / *.yml を名前順に、
+// glob パターンの場合は一致したファイルを名前順に返します。
+func resolveInclude(baseDir, pattern string) ([]string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("include に空のエントリがあります")
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+
+	// ディレクトリ（conf.d 形式）
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include ディレクトリの読み込みに失敗しました (%s): %w", pattern, err)
+		}
+		var files []string
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			files = append(files, filepath.Join(pattern, entry.Name()))
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	// glob パターン
+	if strings.ContainsAny(pattern, "*?[") {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include のパターンが不正です (%s): %w", pattern, err)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	// 通常のファイル（存在しない場合は読み込み時にエラーになる）
+	return []string{pattern}, nil
+}
+
+// mergeMaps は、src の内容を dst に再帰的にマージします。
+// 両方がマップの場合のみ再帰し、それ以外は src の値で置き換えます。
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = srcValue
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile はテスト用のファイルを作成するヘルパーです。
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("ディレクトリの作成に失敗: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("ファイルの作成に失敗: %v", err)
+	}
+}
+
+// TestLoadFromFile_IncludeFiles は、include したファイルが順番にマージされることをテストします。
+func TestLoadFromFile_IncludeFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "config.yaml")
+	writeFile(t, main, `include:
+  - secrets.yaml
+  - tuning.yaml
+duckdns:
+  domain: "main-domain"
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+`)
+	writeFile(t, filepath.Join(dir, "secrets.yaml"), `duckdns:
+  token: "secret-token"
+`)
+	writeFile(t, filepath.Join(dir, "tuning.yaml"), `update:
+  interval: "1m"
+ip_sources:
+  - "https://icanhazip.com"
+`)
+
+	cfg, err := LoadFromFile(main)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+
+	if cfg.DuckDNS.Domain != "main-domain" {
+		t.Errorf("ドメイン名が一致しません。期待: main-domain, 実際: %s", cfg.DuckDNS.Domain)
+	}
+	if cfg.DuckDNS.Token != "secret-token" {
+		t.Errorf("トークンがマージされていません。期待: secret-token, 実際: %s", cfg.DuckDNS.Token)
+	}
+	if cfg.Update.Interval != time.Minute {
+		t.Errorf("更新間隔が上書きされていません。期待: 1m, 実際: %v", cfg.Update.Interval)
+	}
+	if len(cfg.IPSources) != 1 || cfg.IPSources[0].URL != "https://icanhazip.com" {
+		t.Errorf("ip_sources はリストごと置き換えられるべき。実際: %v", cfg.IPSources)
+	}
+}
+
+// TestLoadFromFile_IncludeDirectory は、conf.d 形式のディレクトリを名前順にマージすることをテストします。
+func TestLoadFromFile_IncludeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "config.yaml")
+	writeFile(t, main, `include: ["conf.d"]
+duckdns:
+  domain: "main-domain"
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "20-override.yaml"), `duckdns:
+  domain: "override-domain"
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "10-token.yml"), `duckdns:
+  token: "dir-token"
+  domain: "first-domain"
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "README.txt"), "not yaml")
+
+	cfg, err := LoadFromFile(main)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+
+	if cfg.DuckDNS.Domain != "override-domain" {
+		t.Errorf("名前順の後のファイルが優先されるべき。実際: %s", cfg.DuckDNS.Domain)
+	}
+	if cfg.DuckDNS.Token != "dir-token" {
+		t.Errorf("トークンがマージされていません。実際: %s", cfg.DuckDNS.Token)
+	}
+}
+
+// TestLoadFromFile_IncludeUnknownKey は、断片ファイルの未知のキーもエラーになることをテストします。
+func TestLoadFromFile_IncludeUnknownKey(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "config.yaml")
+	writeFile(t, main, `include: ["extra.yaml"]
+`)
+	writeFile(t, filepath.Join(dir, "extra.yaml"), `update:
+  intervall: "1m"
+`)
+
+	_, err := LoadFromFile(main)
+	if err == nil {
+		t.Fatal("断片ファイルの未知のキーでエラーが返されるべき")
+	}
+	if !strings.Contains(err.Error(), "extra.yaml") {
+		t.Errorf("エラーメッセージに断片ファイル名が含まれるべき: %v", err)
+	}
+}
+
+// TestLoadFromFile_IncludeCycle は、循環した include がエラーになることをテストします。
+func TestLoadFromFile_IncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), `include: ["b.yaml"]
+`)
+	writeFile(t, filepath.Join(dir, "b.yaml"), `include: ["a.yaml"]
+`)
+
+	if _, err := LoadFromFile(filepath.Join(dir, "a.yaml")); err == nil {
+		t.Error("循環した include でエラーが返されるべき")
+	}
+}
+
+// TestLoadFromFile_IncludeMissing は、存在しない include ファイルがエラーになることをテストします。
+func TestLoadFromFile_IncludeMissing(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), `include: ["missing.yaml"]
+`)
+
+	if _, err := LoadFromFile(filepath.Join(dir, "config.yaml")); err == nil {
+		t.Error("存在しない include ファイルでエラーが返されるべき")
+	}
+}
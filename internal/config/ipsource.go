@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ipSourceKeys は、ip_sources のエントリーをオブジェクトで書く場合に使えるキーです。
+var ipSourceKeys = []string{"url", "type", "interface", "headers", "username", "password", "extract", "min_interval", "cache_ttl", "weight"}
+
+// IPSource は、ip_sources の1つのエントリー（IP取得ソース）です。
+// URL だけの文字列か、ヘッダーや Basic 認証、レスポンスからの取り出し方を指定したオブジェクトで書けます。
+// 認証や API キーが必要な自前の IP エコーサーバーや、JSON・HTML を返すサービスも使えるようにします。
+//
+//	ip_sources:
+//	  - "https://api.ipify.org"
+//	  - url: "https://ip.example.com/"
+//	    headers:
+//	      X-API-Key: "secret"
+//	    username: "user"
+//	    password: "pass"
+//	  - url: "https://ipinfo.io/json"
+//	    extract: "json:ip"
+type IPSource struct {
+	// URL は、IPアドレスを取得するエンドポイントです
+	URL string `yaml:"url"`
+
+	// Type は、ソースへの問い合わせ方です（"http", "fritzbox", "openwrt"。省略時は "http"）
+	// "fritzbox" と "openwrt" では、URL にルーターのアドレスを指定し、ルーターに直接 WAN の IPアドレスを問い合わせます
+	Type string `yaml:"type,omitempty"`
+
+	// Interface は、Type が "openwrt" の場合に IPアドレスを取得するインターフェースの名前です（省略時は "wan"、IPv6 は "wan6"）
+	Interface string `yaml:"interface,omitempty"`
+
+	// Headers は、リクエストに付けるヘッダーです
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Username は、Basic 認証のユーザー名です（ルーターの場合はログインのユーザー名）
+	Username string `yaml:"username,omitempty"`
+
+	// Password は、Basic 認証のパスワードです（ルーターの場合はログインのパスワード）
+	Password string `yaml:"password,omitempty"`
+
+	// Extract は、レスポンスから IPアドレスを取り出す方法です（例: "json:ip", "regex:WAN IP: ([0-9.]+)"）
+	// 省略した場合は、レスポンス全体を IPアドレスとして扱います
+	Extract string `yaml:"extract,omitempty"`
+
+	// MinInterval は、このソースに問い合わせる最小の間隔です
+	// 更新チェックの間隔が短くても、公開されている IP エコーサービスに問い合わせすぎないようにします
+	MinInterval time.Duration `yaml:"min_interval,omitempty"`
+
+	// CacheTTL は、このソースから取得した結果を再利用する時間です（省略時は min_interval と同じ）
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+
+	// Weight は、ip_fetch.strategy が "random" の場合に、最初に問い合わせるソースを選ぶ重みです（省略時は 1）
+	Weight int `yaml:"weight,omitempty"`
+}
+
+// UnmarshalYAML は、文字列（URL だけ）またはオブジェクトの ip_sources のエントリーを読み込みます。
+func (s *IPSource) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = IPSource{}
+		return node.Decode(&s.URL)
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: IP取得ソースは URL の文字列か、url を含むオブジェクトで指定してください", node.Line)
+	}
+
+	// オブジェクトの中のキーも、設定ファイル全体と同じように厳格に確認する
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !containsString(ipSourceKeys, key.Value) {
+			return fmt.Errorf("line %d: IP取得ソースのキー %q は使用できません (有効なキー: %s)", key.Line, key.Value, strings.Join(ipSourceKeys, ", "))
+		}
+	}
+	type plain IPSource
+	return node.Decode((*plain)(s))
+}
+
+// MarshalYAML は、URL だけのエントリーを文字列として書き出します。
+func (s IPSource) MarshalYAML() (interface{}, error) {
+	if s.IsPlain() {
+		return s.URL, nil
+	}
+	type plain IPSource
+	return plain(s), nil
+}
+
+// IsPlain は、URL 以外の設定がないかどうかを返します。
+func (s IPSource) IsPlain() bool {
+	return s.Type == "" && s.Interface == "" && len(s.Headers) == 0 && s.Username == "" && s.Password == "" && s.Extract == "" && s.MinInterval == 0 && s.CacheTTL == 0 && s.Weight == 0
+}
+
+// String は、IP取得ソースの URL を返します（認証情報は含みません）。
+func (s IPSource) String() string {
+	return s.URL
+}
+
+// IPSources は、IP取得ソースのリストです。
+type IPSources []IPSource
+
+// NewIPSources は、URL のリストから IPSources を作成します（環境変数やフラグで指定した場合）。
+//
+// Parameters:
+//   - urls: IP取得ソースの URL のリスト
+//
+// Returns:
+//   - IPSources: URL だけの IP取得ソースのリスト（urls が空の場合は nil）
+func NewIPSources(urls []string) IPSources {
+	if len(urls) == 0 {
+		return nil
+	}
+	sources := make(IPSources, len(urls))
+	for i, url := range urls {
+		sources[i] = IPSource{URL: url}
+	}
+	return sources
+}
+
+// URLs は、IP取得ソースの URL のリストを返します。
+func (l IPSources) URLs() []string {
+	if l == nil {
+		return nil
+	}
+	urls := make([]string, len(l))
+	for i, source := range l {
+		urls[i] = source.URL
+	}
+	return urls
+}
+
+// Key は、ヘッダーや認証情報を含めて IP取得ソースのリストを区別する文字列を返します。
+// 同じ IP取得ソースを使うドメインを1つのスケジューラーにまとめるために使います。
+func (l IPSources) Key() string {
+	var b strings.Builder
+	for _, source := range l {
+		b.WriteString(source.URL)
+		if source.Type != "" || source.Interface != "" {
+			fmt.Fprintf(&b, "\x06%s/%s", source.Type, source.Interface)
+		}
+		names := make([]string, 0, len(source.Headers))
+		for name := range source.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "\x01%s=%s", name, source.Headers[name])
+		}
+		if source.Username != "" || source.Password != "" {
+			fmt.Fprintf(&b, "\x02%s:%s", source.Username, source.Password)
+		}
+		if source.Extract != "" {
+			fmt.Fprintf(&b, "\x03%s", source.Extract)
+		}
+		if source.MinInterval != 0 || source.CacheTTL != 0 {
+			fmt.Fprintf(&b, "\x04%s/%s", source.MinInterval, source.CacheTTL)
+		}
+		if source.Weight != 0 {
+			fmt.Fprintf(&b, "\x05%d", source.Weight)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
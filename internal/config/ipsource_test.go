@@ -0,0 +1,205 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestLoadFromFile_IPSourceObjects は、ip_sources に文字列とオブジェクトを混ぜて書けることをテストします。
+func TestLoadFromFile_IPSourceObjects(t *testing.T) {
+	tmpFile := t.TempDir() + "/config.yaml"
+	content := `duckdns:
+  domain: "home"
+  token: "test-token"
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+  - url: "https://ip.example.com/"
+    headers:
+      X-API-Key: "secret"
+    username: "user"
+    password: "pass"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("バリデーションが失敗しました: %v", err)
+	}
+
+	if got := strings.Join(cfg.IPSources.URLs(), ","); got != "https://api.ipify.org,https://ip.example.com/" {
+		t.Errorf("URLs() = %s", got)
+	}
+	source := cfg.IPSources[1]
+	if !cfg.IPSources[0].IsPlain() || source.IsPlain() {
+		t.Error("URL だけのソースとオブジェクトのソースを区別できるべき")
+	}
+	if source.Headers["X-API-Key"] != "secret" || source.Username != "user" || source.Password != "pass" {
+		t.Errorf("オブジェクトのソースの設定が一致しません: %+v", source)
+	}
+}
+
+// TestIPSource_UnknownKey は、IP取得ソースのオブジェクトに未知のキーがあるとエラーになることをテストします。
+func TestIPSource_UnknownKey(t *testing.T) {
+	var sources IPSources
+	err := yaml.Unmarshal([]byte("- url: \"https://ip.example.com/\"\n  header:\n    X-API-Key: secret\n"), &sources)
+	if err == nil || !strings.Contains(err.Error(), `"header"`) {
+		t.Errorf("未知のキーはエラーになるべき: %v", err)
+	}
+}
+
+// TestIPSource_MarshalYAML は、URL だけのソースを文字列として書き出すことをテストします。
+func TestIPSource_MarshalYAML(t *testing.T) {
+	sources := IPSources{
+		{URL: "https://api.ipify.org"},
+		{URL: "https://ip.example.com/", Username: "user"},
+	}
+	data, err := yaml.Marshal(sources)
+	if err != nil {
+		t.Fatalf("書き出しに失敗しました: %v", err)
+	}
+	want := "- https://api.ipify.org\n- url: https://ip.example.com/\n  username: user\n"
+	if string(data) != want {
+		t.Errorf("書き出し結果が一致しません:\n%s", data)
+	}
+}
+
+// TestValidate_IPSourceHeaders は、IP取得ソースのヘッダー名を検証することをテストします。
+func TestValidate_IPSourceHeaders(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:  UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{
+			{URL: "https://api.ipify.org"},
+			{URL: "https://ip.example.com/", Headers: map[string]string{"X-API-Key": "secret", "Bad Header": "x"}},
+		},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "ip_sources[1].headers" {
+		t.Errorf("ip_sources[1].headers のエラーになるべき: %v", err)
+	}
+}
+
+// TestIPSources_Key は、ヘッダーや認証情報が違うソースのリストを区別できることをテストします。
+func TestIPSources_Key(t *testing.T) {
+	plain := IPSources{{URL: "https://ip.example.com/"}}
+	withAuth := IPSources{{URL: "https://ip.example.com/", Username: "user", Password: "pass"}}
+	withHeader := IPSources{{URL: "https://ip.example.com/", Headers: map[string]string{"X-API-Key": "secret"}}}
+
+	if plain.Key() == withAuth.Key() || plain.Key() == withHeader.Key() || withAuth.Key() == withHeader.Key() {
+		t.Error("ヘッダーや認証情報が違うソースは別のキーになるべき")
+	}
+	if plain.Key() != NewIPSources([]string{"https://ip.example.com/"}).Key() {
+		t.Error("同じソースは同じキーになるべき")
+	}
+}
+
+// TestValidate_IPSourceExtract は、IP取得ソースの extract を検証することをテストします。
+func TestValidate_IPSourceExtract(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:  UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{
+			{URL: "https://ipinfo.io/json", Extract: "json:ip"},
+			{URL: "https://ifconfig.co/json", Extract: "json:"},
+			{URL: "http://192.168.1.1/status", Extract: `regex:WAN IP: ([0-9.]+)`},
+			{URL: "http://192.168.1.1/status", Extract: "regex:(["},
+		},
+	}
+
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "ip_sources[1].extract,ip_sources[3].extract" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+	if cfg.IPSources[0].IsPlain() {
+		t.Error("extract を指定したソースは URL だけのソースではないべき")
+	}
+}
+
+// TestLoadFromFile_IPSourceMinInterval は、IP取得ソースの min_interval と cache_ttl を読み込めることをテストします。
+func TestLoadFromFile_IPSourceMinInterval(t *testing.T) {
+	var sources IPSources
+	content := "- url: \"https://api.ipify.org\"\n  min_interval: \"5m\"\n  cache_ttl: \"30s\"\n"
+	if err := yaml.Unmarshal([]byte(content), &sources); err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if sources[0].MinInterval != 5*time.Minute || sources[0].CacheTTL != 30*time.Second {
+		t.Errorf("min_interval / cache_ttl = %s / %s", sources[0].MinInterval, sources[0].CacheTTL)
+	}
+
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org", MinInterval: -time.Second, CacheTTL: -time.Second}},
+	}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || strings.Join(ve.Keys, ",") != "ip_sources[0].min_interval,ip_sources[0].cache_ttl" {
+		t.Errorf("負の min_interval と cache_ttl はエラーになるべき: %v", err)
+	}
+}
+
+// TestLoadFromFile_IPSourceWeight は、IP取得ソースの weight を読み込み、負の値がエラーになることをテストします。
+func TestLoadFromFile_IPSourceWeight(t *testing.T) {
+	var sources IPSources
+	if err := yaml.Unmarshal([]byte("- url: \"https://api.ipify.org\"\n  weight: 3\n"), &sources); err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if sources[0].Weight != 3 || sources[0].IsPlain() {
+		t.Errorf("weight = %d, IsPlain() = %v; 期待値 3, false", sources[0].Weight, sources[0].IsPlain())
+	}
+
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org", Weight: -1}},
+	}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || strings.Join(ve.Keys, ",") != "ip_sources[0].weight" {
+		t.Errorf("負の weight はエラーになるべき: %v", err)
+	}
+}
+
+// TestLoadFromFile_IPSourceRouter は、IP取得ソースの type と interface を読み込み、無効な組み合わせがエラーになることをテストします。
+func TestLoadFromFile_IPSourceRouter(t *testing.T) {
+	var sources IPSources
+	content := "- url: \"http://192.168.1.1\"\n  type: openwrt\n  interface: pppoe\n  username: root\n  password: secret\n"
+	if err := yaml.Unmarshal([]byte(content), &sources); err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if sources[0].Type != "openwrt" || sources[0].Interface != "pppoe" || sources[0].IsPlain() {
+		t.Errorf("type / interface = %q / %q, IsPlain() = %v", sources[0].Type, sources[0].Interface, sources[0].IsPlain())
+	}
+
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:  UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{
+			{URL: "http://fritz.box:49000", Type: "fritzbox"},
+			{URL: "http://192.168.1.1", Type: "upnp"},
+			{URL: "file:/run/wan_ip", Type: "fritzbox"},
+			{URL: "https://api.ipify.org", Interface: "wan"},
+		},
+	}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("ValidationError が返されるべき: %v", err)
+	}
+	if strings.Join(ve.Keys, ",") != "ip_sources[1].type,ip_sources[2].type,ip_sources[3].interface" {
+		t.Errorf("エラーのキーが一致しません: %v", ve.Keys)
+	}
+}
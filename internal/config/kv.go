@@ -0,0 +1,456 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// consulWaitTime は、Consul のブロッキングクエリで待つ最大時間です。
+const consulWaitTime = 5 * time.Minute
+
+// kvWatchRetryMin と kvWatchRetryMax は、監視が失敗したときの再試行間隔の範囲です。
+const (
+	kvWatchRetryMin = 5 * time.Second
+	kvWatchRetryMax = time.Minute
+)
+
+// errKVNotFound は、キーが存在しない場合のエラーです。
+var errKVNotFound = errors.New("キーが見つかりません")
+
+// kvBackend は、設定を保存したキーの読み込みと変更の待機を行うバックエンドです。
+type kvBackend interface {
+	// get は、キーの値と現在のインデックス（リビジョン）を返します
+	get(ctx context.Context) ([]byte, uint64, error)
+
+	// wait は、インデックスより新しい変更があるまで待ち、変更後の値とインデックスを返します
+	// 待機時間内に変更がなかった場合は、同じインデックスを返します
+	wait(ctx context.Context, index uint64) ([]byte, uint64, error)
+}
+
+// isKV は、設定のパスが Consul または etcd の URL かどうかを返します。
+func isKV(path string) bool {
+	_, ok := kvScheme(path)
+	return ok
+}
+
+// kvScheme は、URL のスキームから KV ストアの種類（consul, etcd）を返します。
+func kvScheme(path string) (string, bool) {
+	lower := strings.ToLower(path)
+	for _, kind := range []string{"consul", "etcd"} {
+		if strings.HasPrefix(lower, kind+"://") || strings.HasPrefix(lower, kind+"+https://") {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// IsWatchable は、設定のパスが変更を監視できる KV ストアの URL かどうかを返します。
+func IsWatchable(path string) bool {
+	return isKV(path)
+}
+
+// WatchRemote は、KV ストアの設定キーを監視し、値が変わるたびに onChange を呼び出します。
+// 監視に失敗した場合は、間隔を空けて再試行します。ctx がキャンセルされるまで戻りません。
+//
+// Parameters:
+//   - ctx: 監視を停止するためのコンテキスト
+//   - rawURL: 設定キーの URL（consul://host:8500/key, etcd://host:2379/key）
+//   - opts: 取得のオプション（HTTPClient のみ使用）
+//   - onChange: 値が変わったときに呼び出す関数
+//
+// Returns:
+//   - error: URL が不正な場合（ctx のキャンセル時は nil）
+func WatchRemote(ctx context.Context, rawURL string, opts RemoteOptions, onChange func()) error {
+	client := opts.HTTPClient
+	if client == nil {
+		// ブロッキングクエリを使うため、タイムアウトはリクエストごとのコンテキストで制御する
+		client = &http.Client{}
+	}
+
+	backend, err := newKVBackend(rawURL, client)
+	if err != nil {
+		return err
+	}
+
+	var (
+		last    []byte
+		index   uint64
+		known   bool // last が有効な値かどうか
+		started bool // 変更の待機を始めてよいかどうか
+		retry   = kvWatchRetryMin
+	)
+
+	for {
+		var (
+			data []byte
+			next uint64
+			err  error
+		)
+		if started {
+			data, next, err = backend.wait(ctx, index)
+		} else {
+			data, next, err = backend.get(ctx)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			slog.Warn("設定キーの監視に失敗しました。再試行します",
+				"config_url", RedactURL(rawURL),
+				"retry_in", retry.String(),
+				"error", err,
+			)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, kvWatchRetryMax)
+			started = false
+			continue
+		}
+		retry = kvWatchRetryMin
+
+		// 監視の開始時は比較対象がないため、値を記録するだけにする
+		// （再接続時は、切断中に変わっていれば通知する）
+		if known && next != index && !bytes.Equal(data, last) {
+			slog.Info("設定キーの変更を検出しました",
+				"config_url", RedactURL(rawURL),
+				"index", next,
+			)
+			onChange()
+		}
+
+		last, index, known, started = data, next, true, true
+	}
+}
+
+// newKVBackend は、URL のスキームに応じた KV ストアのバックエンドを作成します。
+//
+// 対応する URL:
+//   - consul://host:8500/path/to/key（consul+https:// で HTTPS）
+//   - etcd://host:2379/path/to/key（etcd+https:// で HTTPS）
+func newKVBackend(rawURL string, client *http.Client) (kvBackend, error) {
+	kind, ok := kvScheme(rawURL)
+	if !ok {
+		return nil, fmt.Errorf("KV ストアの URL ではありません: %s", RedactURL(rawURL))
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("設定キーの URL が不正です: %w", err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("設定キーの URL は %s://host:port/key の形式で指定してください: %s", kind, RedactURL(rawURL))
+	}
+
+	scheme := "http"
+	if strings.HasSuffix(strings.ToLower(u.Scheme), "+https") {
+		scheme = "https"
+	}
+	base := scheme + "://" + u.Host
+
+	switch kind {
+	case "consul":
+		token := os.Getenv("CONSUL_HTTP_TOKEN")
+		if u.User != nil {
+			if p, ok := u.User.Password(); ok {
+				token = p
+			}
+		}
+		return &consulBackend{
+			client: client,
+			base:   base,
+			key:    key,
+			query:  u.Query(),
+			token:  token,
+		}, nil
+	default:
+		// etcd のキーは先頭の "/" を含めてそのまま使う（例: /duckdns/config）
+		backend := &etcdBackend{client: client, base: base, key: u.Path}
+		if u.User != nil {
+			backend.username = u.User.Username()
+			backend.password, _ = u.User.Password()
+		}
+		return backend, nil
+	}
+}
+
+// consulBackend は、Consul の KV API を使うバックエンドです。
+type consulBackend struct {
+	client *http.Client
+	base   string
+	key    string
+	query  url.Values
+	token  string
+}
+
+// get は、キーの値と X-Consul-Index を返します。
+func (b *consulBackend) get(ctx context.Context) ([]byte, uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultRemoteTimeout)
+	defer cancel()
+	return b.request(ctx, nil)
+}
+
+// wait は、ブロッキングクエリでキーの変更を待ちます。
+func (b *consulBackend) wait(ctx context.Context, index uint64) ([]byte, uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, consulWaitTime+DefaultRemoteTimeout)
+	defer cancel()
+
+	data, next, err := b.request(ctx, url.Values{
+		"index": {strconv.FormatUint(index, 10)},
+		"wait":  {consulWaitTime.String()},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	// インデックスが戻った場合は、Consul の推奨どおり最初から数え直す
+	if next < index {
+		next = 0
+	}
+	return data, next, nil
+}
+
+// request は、?raw を付けてキーの値を取得します。
+func (b *consulBackend) request(ctx context.Context, extra url.Values) ([]byte, uint64, error) {
+	query := url.Values{}
+	for k, v := range b.query {
+		query[k] = v
+	}
+	for k, v := range extra {
+		query[k] = v
+	}
+	query.Set("raw", "")
+
+	endpoint := b.base + "/v1/kv/" + escapeKeyPath(b.key) + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+	if b.token != "" {
+		req.Header.Set("X-Consul-Token", b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Consul への接続に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, 0, fmt.Errorf("Consul のキー %q: %w", b.key, errKVNotFound)
+	default:
+		return nil, 0, fmt.Errorf("Consul からHTTPステータスエラー: %d", resp.StatusCode)
+	}
+
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Consul の X-Consul-Index が不正です: %q", resp.Header.Get("X-Consul-Index"))
+	}
+	return data, index, nil
+}
+
+// etcdBackend は、etcd v3 の JSON ゲートウェイ（/v3/kv/range, /v3/watch）を使うバックエンドです。
+type etcdBackend struct {
+	client   *http.Client
+	base     string
+	key      string
+	username string
+	password string
+}
+
+// etcdKeyValue は、etcd の JSON ゲートウェイが返すキーと値です。
+type etcdKeyValue struct {
+	Value       string `json:"value"`
+	ModRevision string `json:"mod_revision"`
+}
+
+// get は、キーの値と更新リビジョンを返します。
+func (b *etcdBackend) get(ctx context.Context) ([]byte, uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultRemoteTimeout)
+	defer cancel()
+
+	var resp struct {
+		KVs []etcdKeyValue `json:"kvs"`
+	}
+	if err := b.call(ctx, "/v3/kv/range", map[string]string{"key": b.encodedKey()}, &resp); err != nil {
+		return nil, 0, err
+	}
+	if len(resp.KVs) == 0 {
+		return nil, 0, fmt.Errorf("etcd のキー %q: %w", b.key, errKVNotFound)
+	}
+	return decodeEtcdValue(resp.KVs[0])
+}
+
+// maxWatchMessageSize は、etcd の watch API のストリームから1件として読み込む最大サイズです。
+// 設定の値は Base64 で送られるため、リモート設定の最大サイズの2倍にします。
+const maxWatchMessageSize = 2 * maxRemoteConfigSize
+
+// wait は、watch API でリビジョンより新しい変更を待ちます。
+// キーが削除された場合は、エラーとして扱い、現在の設定を使い続けます。
+func (b *etcdBackend) wait(ctx context.Context, index uint64) ([]byte, uint64, error) {
+	body, err := json.Marshal(map[string]any{
+		"create_request": map[string]string{
+			"key":            b.encodedKey(),
+			"start_revision": strconv.FormatUint(index+1, 10),
+		},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := b.post(ctx, "/v3/watch", body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	// watch API は、{"result": {...}} を1行に1つずつストリームで返す
+	decoder := httpclient.NewEventDecoder(resp.Body, maxWatchMessageSize)
+	for {
+		var msg struct {
+			Result struct {
+				Events []struct {
+					Type string       `json:"type"`
+					KV   etcdKeyValue `json:"kv"`
+				} `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, httpclient.ErrResponseTooLarge) {
+				return nil, 0, fmt.Errorf("etcd の監視のメッセージを読み込めません: %w", err)
+			}
+			return nil, 0, fmt.Errorf("etcd の監視が切断されました: %w", err)
+		}
+		if msg.Error != nil {
+			return nil, 0, fmt.Errorf("etcd の監視でエラー: %s", msg.Error.Message)
+		}
+
+		for _, event := range msg.Result.Events {
+			if event.Type == "DELETE" {
+				return nil, 0, fmt.Errorf("etcd のキー %q が削除されました: %w", b.key, errKVNotFound)
+			}
+		}
+		if n := len(msg.Result.Events); n > 0 {
+			return decodeEtcdValue(msg.Result.Events[n-1].KV)
+		}
+	}
+}
+
+// encodedKey は、JSON ゲートウェイに渡す Base64 のキーを返します。
+func (b *etcdBackend) encodedKey() string {
+	return base64.StdEncoding.EncodeToString([]byte(b.key))
+}
+
+// call は、JSON ゲートウェイの API を呼び出して、レスポンスをデコードします。
+func (b *etcdBackend) call(ctx context.Context, path string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	resp, err := b.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("etcd のレスポンスの解析に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// post は、認証ヘッダーを付けて JSON ゲートウェイに POST します。
+// ユーザー名が指定されている場合は、先に認証トークンを取得します。
+func (b *etcdBackend) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	var token string
+	if b.username != "" && path != "/v3/auth/authenticate" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		if err := b.call(ctx, "/v3/auth/authenticate", map[string]string{
+			"name":     b.username,
+			"password": b.password,
+		}, &auth); err != nil {
+			return nil, fmt.Errorf("etcd の認証に失敗しました: %w", err)
+		}
+		token = auth.Token
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("etcd への接続に失敗しました: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("etcd からHTTPステータスエラー: %d (%s)", resp.StatusCode, path)
+	}
+	return resp, nil
+}
+
+// decodeEtcdValue は、Base64 の値と文字列のリビジョンをデコードします。
+func decodeEtcdValue(kv etcdKeyValue) ([]byte, uint64, error) {
+	data, err := base64.StdEncoding.DecodeString(kv.Value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("etcd の値のデコードに失敗しました: %w", err)
+	}
+	revision, err := strconv.ParseUint(kv.ModRevision, 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("etcd のリビジョンが不正です: %q", kv.ModRevision)
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, 0, fmt.Errorf("リモート設定が大きすぎます (最大 %d バイト)", maxRemoteConfigSize)
+	}
+	return data, revision, nil
+}
+
+// readLimited は、最大サイズを超えないようにレスポンスボディを読み込みます。
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := httpclient.ReadBody(r, maxRemoteConfigSize)
+	if err != nil {
+		return nil, fmt.Errorf("リモート設定の読み込みに失敗しました: %w", err)
+	}
+	return data, nil
+}
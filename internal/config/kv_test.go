@@ -0,0 +1,238 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// fakeConsul は、テスト用の Consul KV API です。
+type fakeConsul struct {
+	mu      sync.Mutex
+	value   string
+	index   uint64
+	changed chan struct{}
+	token   string
+}
+
+// set は、キーの値を更新して、待機中のブロッキングクエリを起こします。
+func (f *fakeConsul) set(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = value
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// ServeHTTP は、/v1/kv/<key>?raw とブロッキングクエリ（index, wait）を処理します。
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.token = r.Header.Get("X-Consul-Token")
+	if r.URL.Path != "/v1/kv/duckdns/config" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	changed := f.changed
+	waiting := r.URL.Query().Get("index") == fmt.Sprint(f.index)
+	f.mu.Unlock()
+
+	if waiting {
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Consul-Index", fmt.Sprint(f.index))
+	_, _ = w.Write([]byte(f.value))
+}
+
+// TestFetchRemote_Consul は、Consul のキーから設定を読み込めることをテストします。
+func TestFetchRemote_Consul(t *testing.T) {
+	consul := &fakeConsul{value: remoteTestConfig, index: 7, changed: make(chan struct{})}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+	t.Setenv("CONSUL_HTTP_TOKEN", "acl-token")
+
+	url := "consul://" + strings.TrimPrefix(server.URL, "http://") + "/duckdns/config"
+	if !IsRemote(url) || !IsWatchable(url) {
+		t.Fatalf("consul:// はリモートかつ監視可能と判定されるべき: %s", url)
+	}
+
+	file, err := FetchRemote(context.Background(), url, RemoteOptions{CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Consul からの取得に失敗しました: %v", err)
+	}
+	cfg, err := LoadFromFile(file.Path)
+	if err != nil {
+		t.Fatalf("設定の読み込みに失敗しました: %v", err)
+	}
+	if cfg.DuckDNS.Domain != "remote-domain" {
+		t.Errorf("ドメイン名が一致しません。実際: %s", cfg.DuckDNS.Domain)
+	}
+	if consul.token != "acl-token" {
+		t.Errorf("CONSUL_HTTP_TOKEN が送信されるべき。実際: %q", consul.token)
+	}
+
+	// 存在しないキーはエラーになる
+	missing := "consul://" + strings.TrimPrefix(server.URL, "http://") + "/other"
+	if _, err := FetchRemote(context.Background(), missing, RemoteOptions{CacheDir: t.TempDir()}); err == nil {
+		t.Error("存在しないキーはエラーになるべき")
+	}
+}
+
+// TestWatchRemote_Consul は、Consul のキーの変更で onChange が呼ばれることをテストします。
+func TestWatchRemote_Consul(t *testing.T) {
+	consul := &fakeConsul{value: remoteTestConfig, index: 1, changed: make(chan struct{})}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notified := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	url := "consul://" + strings.TrimPrefix(server.URL, "http://") + "/duckdns/config"
+	go func() {
+		done <- WatchRemote(ctx, url, RemoteOptions{}, func() { notified <- struct{}{} })
+	}()
+
+	// 監視が始まるのを待ってから値を変更する
+	time.Sleep(50 * time.Millisecond)
+	consul.set(strings.Replace(remoteTestConfig, "remote-domain", "changed-domain", 1))
+
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Fatal("キーの変更が通知されません")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("キャンセル時は nil を返すべき: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WatchRemote がキャンセル後も終了しません")
+	}
+}
+
+// fakeEtcd は、テスト用の etcd v3 JSON ゲートウェイです。
+type fakeEtcd struct {
+	value    string
+	revision int
+	events   chan string
+}
+
+// ServeHTTP は、/v3/kv/range と /v3/watch を処理します。
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		if req["key"] != base64.StdEncoding.EncodeToString([]byte("/duckdns/config")) {
+			_, _ = w.Write([]byte(`{"header":{}}`))
+			return
+		}
+		fmt.Fprintf(w, `{"kvs":[{"value":%q,"mod_revision":"%d"}]}`,
+			base64.StdEncoding.EncodeToString([]byte(f.value)), f.revision)
+	case "/v3/watch":
+		flusher := w.(http.Flusher)
+		_, _ = w.Write([]byte(`{"result":{"created":true}}` + "\n"))
+		flusher.Flush()
+		select {
+		case value := <-f.events:
+			f.revision++
+			fmt.Fprintf(w, `{"result":{"events":[{"kv":{"value":%q,"mod_revision":"%d"}}]}}`+"\n",
+				base64.StdEncoding.EncodeToString([]byte(value)), f.revision)
+			flusher.Flush()
+			<-r.Context().Done()
+		case <-r.Context().Done():
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// TestEtcdBackend は、etcd からの取得と watch による変更の待機をテストします。
+func TestEtcdBackend(t *testing.T) {
+	etcd := &fakeEtcd{value: remoteTestConfig, revision: 3, events: make(chan string, 1)}
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	url := "etcd://" + strings.TrimPrefix(server.URL, "http://") + "/duckdns/config"
+	backend, err := newKVBackend(url, server.Client())
+	if err != nil {
+		t.Fatalf("バックエンドの作成に失敗しました: %v", err)
+	}
+
+	data, revision, err := backend.get(context.Background())
+	if err != nil {
+		t.Fatalf("etcd からの取得に失敗しました: %v", err)
+	}
+	if string(data) != remoteTestConfig || revision != 3 {
+		t.Errorf("取得した値が一致しません。revision: %d", revision)
+	}
+
+	etcd.events <- "duckdns:\n  domain: \"new\"\n"
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	data, revision, err = backend.wait(ctx, revision)
+	if err != nil {
+		t.Fatalf("変更の待機に失敗しました: %v", err)
+	}
+	if !strings.Contains(string(data), "new") || revision != 4 {
+		t.Errorf("変更後の値が一致しません: %q (revision: %d)", data, revision)
+	}
+
+	// 存在しないキーはエラーになる
+	other, _ := newKVBackend("etcd://"+strings.TrimPrefix(server.URL, "http://")+"/other", server.Client())
+	if _, _, err := other.get(context.Background()); err == nil {
+		t.Error("存在しないキーはエラーになるべき")
+	}
+}
+
+// TestEtcdBackend_WatchTooLarge は、上限を超える watch のメッセージで、読み込みを止めてエラーを返すことをテストします。
+func TestEtcdBackend_WatchTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"result":{"created":true}}` + "\n"))
+		_, _ = w.Write([]byte(`{"result":{"events":[{"kv":{"value":"` + strings.Repeat("a", maxWatchMessageSize) + `"}}]}}` + "\n"))
+	}))
+	defer server.Close()
+
+	backend, err := newKVBackend("etcd://"+strings.TrimPrefix(server.URL, "http://")+"/duckdns/config", server.Client())
+	if err != nil {
+		t.Fatalf("バックエンドの作成に失敗しました: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, _, err := backend.wait(ctx, 1); !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("上限を超えるメッセージは ErrResponseTooLarge を返すべき: %v", err)
+	}
+}
+
+// TestNewKVBackend_InvalidURL は、キーのない URL がエラーになることをテストします。
+func TestNewKVBackend_InvalidURL(t *testing.T) {
+	for _, url := range []string{"consul://localhost:8500", "etcd://localhost:2379/", "https://example.com/x"} {
+		if _, err := newKVBackend(url, http.DefaultClient); err == nil {
+			t.Errorf("不正な URL はエラーになるべき: %s", url)
+		}
+	}
+}
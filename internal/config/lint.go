@@ -0,0 +1,336 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem は、設定ファイルの問題を1件表す構造体です。
+// 行番号・列番号は、位置が特定できない場合は 0 になります。
+type Problem struct {
+	// File は、問題が見つかったファイルのパスです
+	File string
+
+	// Line は、問題の位置の行番号（1始まり）です
+	Line int
+
+	// Column は、問題の位置の列番号（1始まり）です
+	Column int
+
+	// Key は、問題のある設定項目のキーです（例: "update.intervall"）
+	Key string
+
+	// Message は、問題の内容です
+	Message string
+}
+
+// String は、"ファイル:行:列: キー: メッセージ" 形式の文字列を返します。
+// コンパイラやリンターと同じ形式のため、エディタや CI で位置を辿れます。
+func (p Problem) String() string {
+	var b strings.Builder
+	b.WriteString(p.File)
+	if p.Line > 0 {
+		fmt.Fprintf(&b, ":%d", p.Line)
+		if p.Column > 0 {
+			fmt.Fprintf(&b, ":%d", p.Column)
+		}
+	}
+	b.WriteString(": ")
+	if p.Key != "" {
+		b.WriteString(p.Key)
+		b.WriteString(": ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// yamlLinePattern は、yaml.v3 のエラーメッセージから行番号を取り出す正規表現です。
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// durationType は、スカラー値として扱う time.Duration の型です。
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ValidateFile は、設定ファイルを解析・検証し、見つかった問題を位置付きで返します。
+// YAML の構文エラー、未知のキー（厳格モード時）、値の検証エラーを報告します。
+// 問題がない場合は空のスライスを返します。
+//
+// Parameters:
+//   - path: 検証する設定ファイルのパス
+//   - opts: 読み込みオプション（未知のキーの扱いとプロファイル）
+//
+// Returns:
+//   - []Problem: 見つかった問題の一覧
+func ValidateFile(path string, opts LoadOptions) []Problem {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []Problem{{File: path, Message: fmt.Sprintf("設定ファイルの読み込みに失敗しました: %v", err)}}
+	}
+
+	// 構文エラーは位置情報付きで報告して終了
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return yamlProblems(path, err)
+	}
+
+	// 未知のキーは、ノードの位置を使ってすべて報告する
+	if !opts.AllowUnknownKeys {
+		if problems := unknownKeys(path, &root); len(problems) > 0 {
+			return problems
+		}
+	}
+
+	// include・プロファイルを含めて読み込む（型の不一致などはここで検出される）
+	cfg, err := LoadFromFileWithOptions(path, opts)
+	if err != nil {
+		return yamlProblems(path, err)
+	}
+
+	return validationProblems(path, &root, cfg.Validate())
+}
+
+// validationProblems は、Validate が返したエラーを位置付きの Problem に変換します。
+// root は位置を探すための YAML ノードで、nil の場合は位置なしで報告します。
+func validationProblems(path string, root *yaml.Node, err error) []Problem {
+	if err == nil {
+		return nil
+	}
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+
+	problems := make([]Problem, 0, len(ve.Errors))
+	for i, message := range ve.Errors {
+		problem := Problem{File: path, Message: message}
+		if i < len(ve.Keys) {
+			problem.Key = ve.Keys[i]
+			if root != nil {
+				problem.Line, problem.Column = locateKey(root, problem.Key)
+			}
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// yamlProblems は、yaml.v3 のエラーを行番号付きの Problem に変換します。
+// 型の不一致など複数のエラーを含む場合は、それぞれを別の Problem にします。
+func yamlProblems(path string, err error) []Problem {
+	var typeErr *yaml.TypeError
+	messages := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	problems := make([]Problem, 0, len(messages))
+	for _, message := range messages {
+		problem := Problem{File: path, Message: message}
+		if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+			problem.Line, _ = strconv.Atoi(m[1])
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// unknownKeys は、YAML ノードを Config の構造と照らし合わせ、未知のキーを報告します。
+func unknownKeys(path string, root *yaml.Node) []Problem {
+	var problems []Problem
+	walkUnknownKeys(path, root, reflect.TypeOf(Config{}), "", &problems)
+	return problems
+}
+
+// walkUnknownKeys は、ノードと型を再帰的にたどり、構造体に存在しないキーを収集します。
+func walkUnknownKeys(path string, node *yaml.Node, t reflect.Type, prefix string, problems *[]Problem) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			walkUnknownKeys(path, child, t, prefix, problems)
+		}
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && t != durationType:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := joinKey(prefix, keyNode.Value)
+			fieldType, ok := fields[keyNode.Value]
+			if !ok {
+				message := "未知のキーです"
+				if suggestion := closestKey(keyNode.Value, fields); suggestion != "" {
+					message = fmt.Sprintf("未知のキーです (\"%s\" の誤りではありませんか?)", suggestion)
+				}
+				*problems = append(*problems, Problem{
+					File:    path,
+					Line:    keyNode.Line,
+					Column:  keyNode.Column,
+					Key:     key,
+					Message: message,
+				})
+				continue
+			}
+			walkUnknownKeys(path, valueNode, fieldType, key, problems)
+		}
+
+	case t.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkUnknownKeys(path, node.Content[i+1], t.Elem(), joinKey(prefix, node.Content[i].Value), problems)
+		}
+
+	case t.Kind() == reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, child := range node.Content {
+			walkUnknownKeys(path, child, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), problems)
+		}
+	}
+}
+
+// yamlFields は、構造体の YAML キー名とフィールドの型の対応表を返します。
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// closestKey は、タイプミスと思われるキーに最も近い既知のキーを返します。
+// 編集距離が 2 以下の候補がない場合は空文字列を返します。
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if d := editDistance(key, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance は、2つの文字列のレーベンシュタイン距離を返します。
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// locateKey は、"duckdns.domains[0].name" 形式のキーに対応するノードの位置を返します。
+// キーが存在しない場合は、存在する最も深い親の位置を返します。
+func locateKey(root *yaml.Node, key string) (int, int) {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line, column := 0, 0
+
+	for _, segment := range splitKey(key) {
+		next, keyNode := childNode(node, segment)
+		if next == nil {
+			break
+		}
+		// マップのキーはキー自体の位置、リストの要素は値の位置を使う
+		if keyNode != nil {
+			line, column = keyNode.Line, keyNode.Column
+		} else {
+			line, column = next.Line, next.Column
+		}
+		node = next
+	}
+	return line, column
+}
+
+// childNode は、マップのキーまたはリストのインデックスに対応する子ノードを返します。
+// マップの場合は、キーのノードも返します。
+func childNode(node *yaml.Node, segment string) (*yaml.Node, *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				return node.Content[i+1], node.Content[i]
+			}
+		}
+	case yaml.SequenceNode:
+		if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index], nil
+		}
+	}
+	return nil, nil
+}
+
+// splitKey は、"a.b[0].c" 形式のキーを ["a", "b", "0", "c"] に分割します。
+func splitKey(key string) []string {
+	var segments []string
+	for _, part := range strings.Split(key, ".") {
+		for part != "" {
+			open := strings.IndexByte(part, '[')
+			if open < 0 {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			end := strings.IndexByte(part, ']')
+			if end < open {
+				break
+			}
+			segments = append(segments, part[open+1:end])
+			part = part[end+1:]
+		}
+	}
+	return segments
+}
+
+// joinKey は、親のキーと子のキーをドットで連結します。
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateFile_Valid は、正しい設定ファイルで問題が報告されないことをテストします。
+func TestValidateFile_Valid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `duckdns:
+  domain: "test-domain"
+  token: "test-token"
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+`)
+
+	if problems := ValidateFile(path, LoadOptions{}); len(problems) != 0 {
+		t.Errorf("問題は報告されないはずです: %v", problems)
+	}
+}
+
+// TestValidateFile_UnknownKey は、未知のキーが行・列・キー名付きで報告されることをテストします。
+func TestValidateFile_UnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `duckdns:
+  domain: "test-domain"
+  token: "test-token"
+update:
+  intervall: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+`)
+
+	problems := ValidateFile(path, LoadOptions{})
+	if len(problems) != 1 {
+		t.Fatalf("問題の数が一致しません。期待: 1, 実際: %d (%v)", len(problems), problems)
+	}
+
+	p := problems[0]
+	if p.Line != 5 || p.Column != 3 {
+		t.Errorf("位置が一致しません。期待: 5:3, 実際: %d:%d", p.Line, p.Column)
+	}
+	if p.Key != "update.intervall" {
+		t.Errorf("キーが一致しません。期待: update.intervall, 実際: %s", p.Key)
+	}
+	if !strings.Contains(p.Message, "\"interval\"") {
+		t.Errorf("近いキーの候補が提示されるべき: %s", p.Message)
+	}
+	if !strings.HasPrefix(p.String(), path+":5:3: update.intervall: ") {
+		t.Errorf("文字列表現が一致しません: %s", p.String())
+	}
+}
+
+// TestValidateFile_SyntaxError は、構文エラーが行番号付きで報告されることをテストします。
+func TestValidateFile_SyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `duckdns:
+  domain: "test-domain"
+  token: [unclosed
+`)
+
+	problems := ValidateFile(path, LoadOptions{})
+	if len(problems) == 0 {
+		t.Fatal("構文エラーが報告されるべき")
+	}
+	if problems[0].Line == 0 {
+		t.Errorf("行番号が含まれるべき: %v", problems[0])
+	}
+}
+
+// TestValidateFile_ValidationErrors は、値の検証エラーが該当するキーの位置で報告されることをテストします。
+func TestValidateFile_ValidationErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `duckdns:
+  domain: "test-domain"
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+  - "ftp://example.com"
+log:
+  level: "verbose"
+`)
+
+	problems := ValidateFile(path, LoadOptions{})
+	byKey := map[string]Problem{}
+	for _, p := range problems {
+		byKey[p.Key] = p
+	}
+
+	if p, ok := byKey["ip_sources[1]"]; !ok || p.Line != 7 {
+		t.Errorf("ip_sources[1] が7行目で報告されるべき: %+v", p)
+	}
+	if p, ok := byKey["log.level"]; !ok || p.Line != 9 {
+		t.Errorf("log.level が9行目で報告されるべき: %+v", p)
+	}
+	// 存在しないキーは、最も近い親（duckdns）の位置で報告される
+	if p, ok := byKey["duckdns.token"]; !ok || p.Line != 1 {
+		t.Errorf("duckdns.token が親の位置（1行目）で報告されるべき: %+v", p)
+	}
+}
+
+// TestValidateFile_TypeError は、型の不一致が行番号付きで報告されることをテストします。
+func TestValidateFile_TypeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `duckdns:
+  domain: "test-domain"
+update:
+  interval: "five minutes"
+`)
+
+	problems := ValidateFile(path, LoadOptions{})
+	if len(problems) == 0 || problems[0].Line != 4 {
+		t.Errorf("型の不一致が4行目で報告されるべき: %v", problems)
+	}
+}
+
+// TestSplitKey は、キーの分割をテストします。
+func TestSplitKey(t *testing.T) {
+	got := strings.Join(splitKey("duckdns.domains[1].ip_sources[0]"), "/")
+	want := "duckdns/domains/1/ip_sources/0"
+	if got != want {
+		t.Errorf("分割結果が一致しません。期待: %s, 実際: %s", want, got)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import "time"
+
+// Overrides は、コマンドラインフラグなどで指定された、設定を上書きする値です。
+// ゼロ値の項目は上書きしません。
+//
+// 設定値の優先度は次のとおりです（上ほど優先）:
+//  1. コマンドラインフラグ（Overrides）
+//  2. 環境変数
+//  3. 設定ファイル
+//  4. 既定値
+type Overrides struct {
+	// Domain は、duckdns.domain を上書きします
+	Domain string
+
+	// Token は、duckdns.token を上書きします
+	Token string
+
+	// Interval は、update.interval を上書きします
+	Interval time.Duration
+
+	// IPSources は、ip_sources を上書きします
+	IPSources []string
+
+	// IPv6 は、true の場合に update.ipv6 を有効にします
+	IPv6 bool
+
+	// LogLevel は、log.level を上書きします
+	LogLevel string
+
+	// LogFormat は、log.format を上書きします
+	LogFormat string
+
+	// LogFile は、log.file を上書きします
+	LogFile string
+}
+
+// ApplyOverrides は、ゼロ値でない上書き値を設定に反映します。
+//
+// Parameters:
+//   - o: 上書きする値
+func (c *Config) ApplyOverrides(o Overrides) {
+	if o.Domain != "" {
+		c.DuckDNS.Domain = o.Domain
+	}
+	if o.Token != "" {
+		c.DuckDNS.Token = o.Token
+	}
+	if o.Interval != 0 {
+		c.Update.Interval = o.Interval
+	}
+	if len(o.IPSources) > 0 {
+		c.IPSources = NewIPSources(o.IPSources)
+	}
+	if o.IPv6 {
+		c.Update.IPv6 = true
+	}
+	if o.LogLevel != "" {
+		c.Log.Level = o.LogLevel
+	}
+	if o.LogFormat != "" {
+		c.Log.Format = o.LogFormat
+	}
+	if o.LogFile != "" {
+		c.Log.File = o.LogFile
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestApplyOverrides は、ゼロ値でない項目だけが上書きされることをテストします。
+func TestApplyOverrides(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "file-domain", Token: "file-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Log:       LogConfig{Level: "info", Format: "text"},
+	}
+
+	cfg.ApplyOverrides(Overrides{
+		Domain:    "flag-domain",
+		Interval:  time.Minute,
+		IPSources: []string{"https://icanhazip.com"},
+		LogFormat: "json",
+		LogFile:   "/var/log/duckdns.log",
+	})
+
+	if cfg.DuckDNS.Domain != "flag-domain" {
+		t.Errorf("ドメイン名が上書きされていません。実際: %s", cfg.DuckDNS.Domain)
+	}
+	if cfg.DuckDNS.Token != "file-token" {
+		t.Errorf("未指定のトークンは維持されるべき。実際: %s", cfg.DuckDNS.Token)
+	}
+	if cfg.Update.Interval != time.Minute {
+		t.Errorf("更新間隔が上書きされていません。実際: %v", cfg.Update.Interval)
+	}
+	if len(cfg.IPSources) != 1 || cfg.IPSources[0].URL != "https://icanhazip.com" {
+		t.Errorf("IP取得ソースが上書きされていません。実際: %v", cfg.IPSources)
+	}
+	if cfg.Log.Level != "info" || cfg.Log.Format != "json" || cfg.Log.File != "/var/log/duckdns.log" {
+		t.Errorf("ログ設定の上書きが一致しません。実際: %+v", cfg.Log)
+	}
+}
+
+// TestApplyOverrides_Empty は、空の上書きで設定が変わらないことをテストします。
+func TestApplyOverrides_Empty(t *testing.T) {
+	cfg := &Config{
+		DuckDNS: DuckDNSConfig{Domain: "file-domain", Token: "file-token"},
+		Update:  UpdateConfig{Interval: 5 * time.Minute},
+	}
+
+	cfg.ApplyOverrides(Overrides{})
+
+	if cfg.DuckDNS.Domain != "file-domain" || cfg.DuckDNS.Token != "file-token" || cfg.Update.Interval != 5*time.Minute {
+		t.Errorf("空の上書きで設定が変わっています: %+v", cfg)
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// applyProfile は、マージ済みの設定マップに指定されたプロファイルを適用します。
+// プロファイルの内容は共通の設定に再帰的にマージされ、profiles セクションは取り除かれます。
+// profile が空文字列の場合は、profiles セクションを取り除くだけです。
+func applyProfile(merged map[string]interface{}, profile string) error {
+	profiles, _ := merged["profiles"].(map[string]interface{})
+	delete(merged, "profiles")
+
+	if profile == "" {
+		return nil
+	}
+
+	selected, ok := profiles[profile]
+	if !ok {
+		return fmt.Errorf("プロファイル \"%s\" が見つかりません (定義済み: %s)", profile, strings.Join(profileNames(profiles), ", "))
+	}
+
+	// 空のプロファイル（"home: {}" や "home:"）は共通の設定をそのまま使う
+	if selected == nil {
+		return nil
+	}
+	selectedMap, ok := selected.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("プロファイル \"%s\" はマップである必要があります", profile)
+	}
+
+	// プロファイルの中で include や profiles を使うことはできない
+	for _, key := range []string{"include", "profiles"} {
+		if _, exists := selectedMap[key]; exists {
+			return fmt.Errorf("プロファイル \"%s\" の中で %s は使用できません", profile, key)
+		}
+	}
+
+	mergeMaps(merged, selectedMap)
+	return nil
+}
+
+// profileNames は、定義済みのプロファイル名を名前順に返します。
+func profileNames(profiles map[string]interface{}) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"なし"}
+	}
+	return names
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// profileConfig は、プロファイルのテストで使用する設定ファイルの内容です。
+const profileConfig = `duckdns:
+  token: "shared-token"
+update:
+  interval: "5m"
+ip_sources:
+  - "https://api.ipify.org"
+log:
+  level: "info"
+profiles:
+  home:
+    duckdns:
+      domain: "home-server"
+    log:
+      level: "debug"
+  vps:
+    duckdns:
+      domain: "vps-server"
+      token: "vps-token"
+    update:
+      interval: "1m"
+`
+
+// TestLoadFromFileWithOptions_Profile は、選択したプロファイルが共通の設定にマージされることをテストします。
+func TestLoadFromFileWithOptions_Profile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, profileConfig)
+
+	tests := []struct {
+		profile      string
+		wantDomain   string
+		wantToken    string
+		wantInterval time.Duration
+		wantLevel    string
+	}{
+		{"home", "home-server", "shared-token", 5 * time.Minute, "debug"},
+		{"vps", "vps-server", "vps-token", time.Minute, "info"},
+		{"", "", "shared-token", 5 * time.Minute, "info"},
+	}
+
+	for _, tt := range tests {
+		t.Run("profile="+tt.profile, func(t *testing.T) {
+			cfg, err := LoadFromFileWithOptions(path, LoadOptions{Profile: tt.profile})
+			if err != nil {
+				t.Fatalf("読み込みに失敗しました: %v", err)
+			}
+
+			if cfg.DuckDNS.Domain != tt.wantDomain {
+				t.Errorf("ドメイン名が一致しません。期待: %s, 実際: %s", tt.wantDomain, cfg.DuckDNS.Domain)
+			}
+			if cfg.DuckDNS.Token != tt.wantToken {
+				t.Errorf("トークンが一致しません。期待: %s, 実際: %s", tt.wantToken, cfg.DuckDNS.Token)
+			}
+			if cfg.Update.Interval != tt.wantInterval {
+				t.Errorf("更新間隔が一致しません。期待: %v, 実際: %v", tt.wantInterval, cfg.Update.Interval)
+			}
+			if cfg.Log.Level != tt.wantLevel {
+				t.Errorf("ログレベルが一致しません。期待: %s, 実際: %s", tt.wantLevel, cfg.Log.Level)
+			}
+			if len(cfg.Profiles) != 0 {
+				t.Errorf("読み込み後の設定に profiles が残っています: %v", cfg.Profiles)
+			}
+		})
+	}
+}
+
+// TestLoadFromFileWithOptions_UnknownProfile は、未定義のプロファイルがエラーになることをテストします。
+func TestLoadFromFileWithOptions_UnknownProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, profileConfig)
+
+	_, err := LoadFromFileWithOptions(path, LoadOptions{Profile: "office"})
+	if err == nil {
+		t.Fatal("未定義のプロファイルでエラーが返されるべき")
+	}
+	if !strings.Contains(err.Error(), "home, vps") {
+		t.Errorf("エラーメッセージに定義済みのプロファイルが含まれるべき: %v", err)
+	}
+}
+
+// TestLoadFromFile_ProfileUnknownKey は、プロファイル内の未知のキーもエラーになることをテストします。
+func TestLoadFromFile_ProfileUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `profiles:
+  home:
+    update:
+      intervall: "1m"
+`)
+
+	if _, err := LoadFromFile(path); err == nil {
+		t.Error("プロファイル内の未知のキーでエラーが返されるべき")
+	}
+}
+
+// TestLoadWithOptions_ProfileFromEnv は、DUCKDNS_PROFILE でプロファイルを選択できることをテストします。
+func TestLoadWithOptions_ProfileFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, profileConfig)
+
+	t.Setenv("DUCKDNS_PROFILE", "vps")
+	t.Setenv("DUCKDNS_DOMAIN", "")
+	t.Setenv("DUCKDNS_TOKEN", "")
+	t.Setenv("DUCKDNS_INTERVAL", "")
+
+	cfg, err := LoadWithOptions(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if cfg.DuckDNS.Domain != "vps-server" {
+		t.Errorf("環境変数のプロファイルが適用されていません。実際: %s", cfg.DuckDNS.Domain)
+	}
+
+	// オプションで指定したプロファイルが環境変数より優先される
+	cfg, err = LoadWithOptions(path, LoadOptions{Profile: "home"})
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if cfg.DuckDNS.Domain != "home-server" {
+		t.Errorf("オプションのプロファイルが優先されていません。実際: %s", cfg.DuckDNS.Domain)
+	}
+}
+
+// TestLoadWithOptions_ProfileWithoutFile は、設定ファイルなしでプロファイルを指定するとエラーになることをテストします。
+func TestLoadWithOptions_ProfileWithoutFile(t *testing.T) {
+	if _, err := LoadWithOptions("", LoadOptions{Profile: "home"}); err == nil {
+		t.Error("設定ファイルなしでプロファイルを指定した場合はエラーが返されるべき")
+	}
+}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// プロバイダーの種類（providers[].type に指定する値）
+const (
+	// ProviderCloudflare は、Cloudflare DNS の API でレコードを更新するプロバイダーです
+	ProviderCloudflare = "cloudflare"
+
+	// ProviderNoIP は、No-IP (dynupdate.no-ip.com) で更新するプロバイダーです
+	ProviderNoIP = "noip"
+
+	// ProviderDynu は、Dynu (api.dynu.com) で更新するプロバイダーです
+	ProviderDynu = "dynu"
+
+	// ProviderCustom は、テンプレートで指定した URL にリクエストを送って更新するプロバイダーです
+	ProviderCustom = "custom"
+
+	// ProviderExec は、外部コマンド（プラグイン）に JSON を渡して更新するプロバイダーです
+	ProviderExec = "exec"
+
+	// ProviderDynDNS2 は、dyndns2 プロトコル（/nic/update）に対応した任意のサーバーで更新するプロバイダーです
+	ProviderDynDNS2 = "dyndns2"
+)
+
+// ProviderTypes は、providers[].type に指定できる値の一覧です。
+var ProviderTypes = []string{ProviderCloudflare, ProviderNoIP, ProviderDynu, ProviderCustom, ProviderExec, ProviderDynDNS2}
+
+// CustomMethods は、custom プロバイダーの method に指定できる値の一覧です。
+var CustomMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch}
+
+// ProviderConfig は、DuckDNS 以外の DDNS プロバイダーの設定を保持する構造体です。
+// 1つのプロバイダーで複数のドメイン（ホスト名）を更新できます。
+// 空の interval と ip_sources は、トップレベルの設定値を引き継ぎます。
+type ProviderConfig struct {
+	// Type は、プロバイダーの種類です（cloudflare, noip, dynu）
+	Type string `yaml:"type"`
+
+	// Domains は、更新するドメイン名（ホスト名）のリストです
+	// cloudflare の場合は、ゾーン内のレコード名（"home" または "home.example.com"）です
+	Domains []string `yaml:"domains"`
+
+	// Interval は、このプロバイダーの更新チェック間隔です（省略時は update.interval）
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	// IPSources は、このプロバイダーで使用するIP取得ソースです（省略時は ip_sources）
+	IPSources IPSources `yaml:"ip_sources,omitempty"`
+
+	// IPv6 は、このプロバイダーのドメインで IPv6アドレスも更新するかどうかです（省略時は update.ipv6）
+	IPv6 *bool `yaml:"ipv6,omitempty"`
+
+	// IPv6Sources は、このプロバイダーで使用する IPv6アドレスの取得ソースです（省略時は ipv6_sources）
+	IPv6Sources IPSources `yaml:"ipv6_sources,omitempty"`
+
+	// Username は、noip・dynu・dyndns2 のユーザー名です
+	Username string `yaml:"username,omitempty"`
+
+	// Password は、noip・dynu・dyndns2 のパスワード（または更新用のトークン）です
+	Password string `yaml:"password,omitempty"`
+
+	// Server は、dyndns2 の更新サーバーです（"members.dyndns.org" または更新 URL）
+	// noip と dynu で指定した場合は、既定の更新サーバーの代わりに使用します
+	Server string `yaml:"server,omitempty"`
+
+	// APIToken は、cloudflare の API トークンです（Zone.DNS の編集権限が必要）
+	APIToken string `yaml:"api_token,omitempty"`
+
+	// Zone は、cloudflare のゾーン名です（例: "example.com"）
+	Zone string `yaml:"zone,omitempty"`
+
+	// ZoneID は、cloudflare のゾーンIDです（指定した場合はゾーンの検索を省略します）
+	// API トークンにゾーンの読み取り権限がない場合に指定します
+	ZoneID string `yaml:"zone_id,omitempty"`
+
+	// Proxied は、cloudflare のプロキシ（オレンジの雲）を有効にするかどうかです
+	// 省略時は、既存のレコードの設定を変更しません（新しく作成する場合は無効）
+	Proxied *bool `yaml:"proxied,omitempty"`
+
+	// TTL は、cloudflare のレコードの TTL（秒）です（省略時または 1 は自動）
+	TTL int `yaml:"ttl,omitempty"`
+
+	// URL は、custom の更新リクエストの URL テンプレートです
+	// 例: "https://dyn.example.com/update?host={{.Domain}}&ip={{.IP}}"
+	URL string `yaml:"url,omitempty"`
+
+	// Method は、custom の HTTP メソッドです（省略時は GET）
+	Method string `yaml:"method,omitempty"`
+
+	// Headers は、custom のリクエストに付けるヘッダーです（値はテンプレート）
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Body は、custom のリクエストボディのテンプレートです（省略時はボディなし）
+	Body string `yaml:"body,omitempty"`
+
+	// SuccessMatch は、custom の成功とみなすレスポンスボディの正規表現です
+	// 省略時は、2xx のステータスコードだけで成功とみなします
+	SuccessMatch string `yaml:"success_match,omitempty"`
+
+	// Command は、exec で実行するプラグインのコマンドと引数です
+	// 例: ["/usr/local/lib/duckdns/plugins/gandi", "--verbose"]
+	Command []string `yaml:"command,omitempty"`
+
+	// Options は、exec のプラグインにそのまま渡す設定です（API キーなど）
+	Options map[string]string `yaml:"options,omitempty"`
+
+	// Timeout は、exec のプラグインの実行のタイムアウトです（省略時は 30 秒）
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// CustomTemplateData は、custom プロバイダーのテンプレートで使える値です。
+type CustomTemplateData struct {
+	// Domain は、更新するドメイン名です
+	Domain string
+
+	// IP は、登録するIPアドレスです
+	IP string
+
+	// Type は、IPアドレスの種類に合ったレコードの種類です（"A" または "AAAA"）
+	Type string
+
+	// Username は、providers[].username の値です
+	Username string
+
+	// Password は、providers[].password の値です
+	Password string
+}
+
+// ParseCustomTemplate は、custom プロバイダーのテンプレートを解析します。
+// 存在しない値（{{.Domian}} などのタイプミス）を設定の検証で見つけるため、
+// 解析後にサンプルの値で一度実行して確認します。
+//
+// Parameters:
+//   - name: テンプレートの名前（エラーメッセージに使用）
+//   - text: テンプレートの文字列
+//
+// Returns:
+//   - *template.Template: 解析されたテンプレート
+//   - error: 構文エラーまたは実行エラーがある場合
+func ParseCustomTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := CustomTemplateData{Domain: "example", IP: "192.0.2.1", Type: "A", Username: "user", Password: "pass"}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// validateProviders は、プロバイダーの設定を検証し、エラーを ve に追加します。
+func validateProviders(ve *ValidationError, providers []ProviderConfig) {
+	for i, p := range providers {
+		key := fmt.Sprintf("providers[%d]", i)
+
+		if !isProviderType(p.Type) {
+			ve.add(key+".type", fmt.Sprintf("%s の種類 \"%s\" が無効です (有効な値: %s)", key, p.Type, strings.Join(ProviderTypes, ", ")))
+		}
+
+		if len(p.Domains) == 0 {
+			ve.add(key+".domains", fmt.Sprintf("%s の更新するドメイン名が設定されていません (設定項目: domains)", key))
+		}
+		for j, domain := range p.Domains {
+			if strings.TrimSpace(domain) == "" {
+				ve.add(fmt.Sprintf("%s.domains[%d]", key, j), fmt.Sprintf("%s.domains[%d] が空です", key, j))
+			}
+		}
+
+		if p.Interval < 0 {
+			ve.add(key+".interval", fmt.Sprintf("%s の更新間隔は正の値である必要があります", key))
+		}
+		validateIPSources(ve, key+".ip_sources", key+" のIP取得ソース", p.IPSources)
+		validateIPSources(ve, key+".ipv6_sources", key+" の IPv6 のIP取得ソース", p.IPv6Sources)
+
+		// 種類ごとに必要な認証情報
+		required := map[string]string{}
+		switch p.Type {
+		case ProviderCloudflare:
+			required["api_token"] = p.APIToken
+			if strings.TrimSpace(p.ZoneID) == "" {
+				required["zone"] = p.Zone
+			}
+			if p.TTL != 0 && p.TTL != 1 && (p.TTL < 60 || p.TTL > 86400) {
+				ve.add(key+".ttl", fmt.Sprintf("%s の ttl は 1（自動）または 60〜86400 の秒数である必要があります", key))
+			}
+		case ProviderNoIP, ProviderDynu, ProviderDynDNS2:
+			required["username"] = p.Username
+			required["password"] = p.Password
+			if p.Type == ProviderDynDNS2 {
+				required["server"] = p.Server
+			}
+			if strings.TrimSpace(p.Server) != "" && !isValidServer(p.Server) {
+				ve.add(key+".server", fmt.Sprintf("%s の server \"%s\" が無効です (ホスト名または http(s):// の URL)", key, p.Server))
+			}
+		case ProviderCustom:
+			required["url"] = p.URL
+			validateCustomProvider(ve, key, p)
+		case ProviderExec:
+			if len(p.Command) == 0 || strings.TrimSpace(p.Command[0]) == "" {
+				ve.add(key+".command", fmt.Sprintf("%s (%s) の command が設定されていません", key, p.Type))
+			}
+			if p.Timeout < 0 {
+				ve.add(key+".timeout", fmt.Sprintf("%s のタイムアウトは正の値である必要があります", key))
+			}
+		}
+		for _, name := range []string{"api_token", "zone", "username", "password", "server", "url"} {
+			if value, ok := required[name]; ok && strings.TrimSpace(value) == "" {
+				ve.add(key+"."+name, fmt.Sprintf("%s (%s) の %s が設定されていません", key, p.Type, name))
+			}
+		}
+	}
+}
+
+// validateCustomProvider は、custom プロバイダーのテンプレート・メソッド・正規表現を検証します。
+func validateCustomProvider(ve *ValidationError, key string, p ProviderConfig) {
+	if strings.TrimSpace(p.URL) != "" {
+		if _, err := ParseCustomTemplate("url", p.URL); err != nil {
+			ve.add(key+".url", fmt.Sprintf("%s の url のテンプレートが無効です: %v", key, err))
+		}
+	}
+
+	if p.Method != "" && !containsString(CustomMethods, strings.ToUpper(p.Method)) {
+		ve.add(key+".method", fmt.Sprintf("%s の method \"%s\" が無効です (有効な値: %s)", key, p.Method, strings.Join(CustomMethods, ", ")))
+	}
+
+	// エラーの順番が毎回変わらないように、ヘッダー名の順で検証する
+	names := make([]string, 0, len(p.Headers))
+	for name := range p.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := ParseCustomTemplate("headers."+name, p.Headers[name]); err != nil {
+			ve.add(key+".headers."+name, fmt.Sprintf("%s のヘッダー %s のテンプレートが無効です: %v", key, name, err))
+		}
+	}
+
+	if p.Body != "" {
+		if _, err := ParseCustomTemplate("body", p.Body); err != nil {
+			ve.add(key+".body", fmt.Sprintf("%s の body のテンプレートが無効です: %v", key, err))
+		}
+	}
+
+	if p.SuccessMatch != "" {
+		if _, err := regexp.Compile(p.SuccessMatch); err != nil {
+			ve.add(key+".success_match", fmt.Sprintf("%s の success_match の正規表現が無効です: %v", key, err))
+		}
+	}
+}
+
+// isValidServer は、dyndns2 の server がホスト名（ポート付きも可）または http(s) の URL かどうかを返します。
+func isValidServer(server string) bool {
+	if strings.Contains(server, "://") {
+		return isValidURL(server)
+	}
+	return !strings.ContainsAny(server, " /?#@")
+}
+
+// containsString は、values に value が含まれるかどうかを返します。
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isProviderType は、プロバイダーの種類が有効かどうかを返します。
+func isProviderType(t string) bool {
+	return containsString(ProviderTypes, t)
+}
+
+// providerTargets は、プロバイダーの設定から更新対象の一覧を作成します。
+func (c *Config) providerTargets() []Target {
+	var targets []Target
+	for i := range c.Providers {
+		p := c.Providers[i]
+		interval := p.Interval
+		if interval == 0 {
+			interval = c.Update.Interval
+		}
+		sources := p.IPSources
+		if len(sources) == 0 {
+			sources = c.IPSources
+		}
+
+		ipv6, ipv6Sources := c.ipv6Override(p.IPv6, p.IPv6Sources)
+
+		for _, domain := range p.Domains {
+			targets = append(targets, Target{
+				Domain:      domain,
+				Interval:    interval,
+				IPSources:   sources,
+				Provider:    &p,
+				IPv6:        ipv6,
+				IPv6Sources: ipv6Sources,
+			})
+		}
+	}
+	return targets
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadFromFile_Providers は、YAMLからプロバイダーの設定を読み込めることをテストします。
+func TestLoadFromFile_Providers(t *testing.T) {
+	tmpFile := t.TempDir() + "/providers.yaml"
+	content := `providers:
+  - type: cloudflare
+    api_token: "cf-token"
+    zone: "example.com"
+    domains:
+      - "home"
+      - "vpn.example.com"
+  - type: noip
+    username: "user"
+    password: "pass"
+    interval: "1m"
+    domains:
+      - "myhost.ddns.net"
+update:
+  interval: "10m"
+ip_sources:
+  - "https://api.ipify.org"
+`
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	cfg, err := LoadFromFile(tmpFile)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("providers だけの設定でバリデーションが失敗しました: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 3 {
+		t.Fatalf("更新対象の数が一致しません。期待: 3, 実際: %d", len(targets))
+	}
+	if targets[0].Domain != "home" || targets[0].Provider == nil || targets[0].Provider.Type != ProviderCloudflare {
+		t.Errorf("cloudflare の対象が一致しません: %+v", targets[0])
+	}
+	if targets[0].Interval != 10*time.Minute || targets[0].IPSources[0].URL != "https://api.ipify.org" {
+		t.Errorf("トップレベルの更新間隔とIP取得ソースが引き継がれていません: %+v", targets[0])
+	}
+	if targets[2].Domain != "myhost.ddns.net" || targets[2].Interval != time.Minute || targets[2].Provider.Username != "user" {
+		t.Errorf("noip の対象が一致しません: %+v", targets[2])
+	}
+}
+
+// TestTargets_DuckDNSAndProviders は、DuckDNS のドメインの後ろにプロバイダーのドメインが続くことをテストします。
+func TestTargets_DuckDNSAndProviders(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "main", Token: "token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Providers: []ProviderConfig{
+			{Type: ProviderDynu, Username: "user", Password: "pass", Domains: []string{"a.dynu.net"}},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("バリデーションが失敗しました: %v", err)
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 2 {
+		t.Fatalf("更新対象の数が一致しません。期待: 2, 実際: %d", len(targets))
+	}
+	if targets[0].Provider != nil || targets[0].Token != "token" {
+		t.Errorf("DuckDNS の対象にはプロバイダーが設定されないべき: %+v", targets[0])
+	}
+	if targets[1].Provider == nil || targets[1].Provider.Type != ProviderDynu || targets[1].Token != "" {
+		t.Errorf("dynu の対象が一致しません: %+v", targets[1])
+	}
+}
+
+// TestValidate_Providers は、プロバイダーの設定の検証をテストします。
+func TestValidate_Providers(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider ProviderConfig
+		wantKeys []string
+	}{
+		{
+			name:     "有効な cloudflare",
+			provider: ProviderConfig{Type: ProviderCloudflare, APIToken: "t", Zone: "example.com", Domains: []string{"home"}},
+		},
+		{
+			name:     "不明な種類",
+			provider: ProviderConfig{Type: "unknown", Domains: []string{"home"}},
+			wantKeys: []string{"providers[0].type"},
+		},
+		{
+			name:     "zone_id だけの cloudflare",
+			provider: ProviderConfig{Type: ProviderCloudflare, APIToken: "t", ZoneID: "023e105f4ecef8ad9ca31a8372d0c353", Domains: []string{"home.example.com"}},
+		},
+		{
+			name:     "cloudflare の無効な ttl",
+			provider: ProviderConfig{Type: ProviderCloudflare, APIToken: "t", Zone: "example.com", TTL: 30, Domains: []string{"home"}},
+			wantKeys: []string{"providers[0].ttl"},
+		},
+		{
+			name:     "cloudflare の認証情報が不足",
+			provider: ProviderConfig{Type: ProviderCloudflare, Domains: []string{"home"}},
+			wantKeys: []string{"providers[0].api_token", "providers[0].zone"},
+		},
+		{
+			name:     "noip のパスワードが不足",
+			provider: ProviderConfig{Type: ProviderNoIP, Username: "user", Domains: []string{"h.ddns.net"}},
+			wantKeys: []string{"providers[0].password"},
+		},
+		{
+			name: "有効な custom",
+			provider: ProviderConfig{Type: ProviderCustom, URL: "https://dyn.example.com/update?host={{.Domain}}&ip={{.IP}}", Method: "post",
+				Headers: map[string]string{"Authorization": "Bearer {{.Password}}"}, SuccessMatch: "^(good|nochg)", Domains: []string{"home"}},
+		},
+		{
+			name:     "custom の url が不足",
+			provider: ProviderConfig{Type: ProviderCustom, Domains: []string{"home"}},
+			wantKeys: []string{"providers[0].url"},
+		},
+		{
+			name: "custom のテンプレート・メソッド・正規表現が無効",
+			provider: ProviderConfig{Type: ProviderCustom, URL: "https://dyn.example.com/?h={{.Domian}}", Method: "DELETE",
+				Headers: map[string]string{"B": "{{.IP}}", "A": "{{"}, Body: "{{.Nope}}", SuccessMatch: "(", Domains: []string{"home"}},
+			wantKeys: []string{"providers[0].url", "providers[0].method", "providers[0].headers.A", "providers[0].body", "providers[0].success_match"},
+		},
+		{
+			name:     "有効な exec",
+			provider: ProviderConfig{Type: ProviderExec, Command: []string{"/usr/local/bin/ddns-plugin", "-v"}, Options: map[string]string{"key": "v"}, Domains: []string{"home"}},
+		},
+		{
+			name:     "exec の command が不足と負のタイムアウト",
+			provider: ProviderConfig{Type: ProviderExec, Command: []string{""}, Timeout: -time.Second, Domains: []string{"home"}},
+			wantKeys: []string{"providers[0].command", "providers[0].timeout"},
+		},
+		{
+			name:     "有効な dyndns2",
+			provider: ProviderConfig{Type: ProviderDynDNS2, Server: "members.dyndns.org", Username: "u", Password: "p", Domains: []string{"home.dyndns.org"}},
+		},
+		{
+			name:     "dyndns2 の server が不足",
+			provider: ProviderConfig{Type: ProviderDynDNS2, Username: "u", Password: "p", Domains: []string{"home.dyndns.org"}},
+			wantKeys: []string{"providers[0].server"},
+		},
+		{
+			name:     "無効な server",
+			provider: ProviderConfig{Type: ProviderNoIP, Server: "ftp://example.com", Username: "u", Password: "p", Domains: []string{"h.ddns.net"}},
+			wantKeys: []string{"providers[0].server"},
+		},
+		{
+			name:     "ドメインがない",
+			provider: ProviderConfig{Type: ProviderDynu, Username: "user", Password: "pass"},
+			wantKeys: []string{"providers[0].domains"},
+		},
+		{
+			name:     "空のドメインと負の更新間隔",
+			provider: ProviderConfig{Type: ProviderDynu, Username: "user", Password: "pass", Domains: []string{" "}, Interval: -time.Minute},
+			wantKeys: []string{"providers[0].domains[0]", "providers[0].interval"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Update:    UpdateConfig{Interval: 5 * time.Minute},
+				IPSources: IPSources{{URL: "https://api.ipify.org"}},
+				Providers: []ProviderConfig{tt.provider},
+			}
+
+			err := cfg.Validate()
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Errorf("バリデーションが失敗しました: %v", err)
+				}
+				return
+			}
+
+			ve, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("ValidationError が返されるべき: %T (%v)", err, err)
+			}
+			if strings.Join(ve.Keys, ",") != strings.Join(tt.wantKeys, ",") {
+				t.Errorf("エラーのキーが一致しません。期待: %v, 実際: %v (%v)", tt.wantKeys, ve.Keys, ve.Errors)
+			}
+		})
+	}
+}
+
+// TestValidate_ProvidersIntervalOverride は、すべてのプロバイダーが更新間隔を上書きしていれば
+// update.interval を省略できることをテストします。
+func TestValidate_ProvidersIntervalOverride(t *testing.T) {
+	cfg := &Config{
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Providers: []ProviderConfig{
+			{Type: ProviderNoIP, Username: "u", Password: "p", Interval: time.Minute, Domains: []string{"h.ddns.net"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("バリデーションが失敗しました: %v", err)
+	}
+
+	cfg.Providers = append(cfg.Providers, ProviderConfig{Type: ProviderDynu, Username: "u", Password: "p", Domains: []string{"d.dynu.net"}})
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "更新間隔が設定されていません") {
+		t.Errorf("更新間隔を上書きしないプロバイダーがある場合はエラーになるべき: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue は、伏せた秘密の値の代わりに書き出す文字列です。
+const redactedValue = "REDACTED"
+
+// secretKeys は、値を伏せる設定項目の名前に含まれる文字列です（小文字）。
+var secretKeys = []string{"token", "password", "secret", "api_key", "apikey", "authorization", "credential"}
+
+// Redacted は、トークンやパスワードなどの秘密の値を伏せた設定を YAML で返します。
+// クラッシュレポートなど、設定をファイルに書き出す場合に使います。
+// 名前に token や password を含む項目と、ヘッダー（headers）・プラグインの設定（options）の値を伏せ、URL からは認証情報とクエリ文字列を取り除きます。
+//
+// Returns:
+//   - []byte: 秘密の値を伏せた YAML
+//   - error: YAML に変換できなかった場合
+func (c *Config) Redacted() ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return nil, err
+	}
+	redactNode(&node)
+	return yaml.Marshal(&node)
+}
+
+// redactNode は、YAML のノードの秘密の値を再帰的に伏せます。
+func redactNode(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			switch {
+			case isSecretKey(key.Value):
+				redactAll(value)
+			case strings.EqualFold(key.Value, "headers"), strings.EqualFold(key.Value, "options"):
+				// ヘッダーとプラグインの設定は、名前に関係なく認証情報を含むことがあるため、すべて伏せる
+				redactAll(value)
+			default:
+				redactNode(value)
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range n.Content {
+			redactNode(child)
+		}
+	case yaml.ScalarNode:
+		if strings.Contains(n.Value, "://") {
+			n.Value = RedactURL(n.Value)
+		}
+	}
+}
+
+// redactAll は、ノードに含まれる空でない値をすべて伏せます（マッピングのキーは残します）。
+func redactAll(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			redactAll(n.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, child := range n.Content {
+			redactAll(child)
+		}
+	case yaml.ScalarNode:
+		if n.Value != "" {
+			n.Value, n.Tag, n.Style = redactedValue, "!!str", 0
+		}
+	}
+}
+
+// isSecretKey は、設定項目の名前が秘密の値を表すかどうかを返します。
+func isSecretKey(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretKeys {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
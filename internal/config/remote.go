@@ -0,0 +1,383 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRemoteTimeout は、リモート設定取得のデフォルトタイムアウトです。
+const DefaultRemoteTimeout = 30 * time.Second
+
+// maxRemoteConfigSize は、リモート設定ファイルの最大サイズ（1 MiB）です。
+const maxRemoteConfigSize = 1 << 20
+
+// RemoteOptions は、リモート設定の取得と検証に関するオプションです。
+type RemoteOptions struct {
+	// SHA256 は、設定ファイルの期待する SHA-256 チェックサム（16進数）です
+	// 空の場合はチェックサムを検証しません
+	SHA256 string
+
+	// PublicKey は、署名の検証に使う Ed25519 公開鍵（Base64）です
+	// 指定した場合、"<URL>.sig" から取得した署名（Base64）を検証します
+	PublicKey string
+
+	// CacheDir は、取得した設定を保存するディレクトリです
+	// 空の場合は os.UserCacheDir() 配下の duckdns ディレクトリを使用します
+	CacheDir string
+
+	// HTTPClient は、取得に使う HTTP クライアントです（nil の場合は既定のクライアント）
+	HTTPClient *http.Client
+}
+
+// RemoteFile は、リモート設定の取得結果です。
+type RemoteFile struct {
+	// Path は、読み込みに使うローカルのキャッシュファイルのパスです
+	Path string
+
+	// FromCache は、取得に失敗してキャッシュにフォールバックした場合に true です
+	FromCache bool
+
+	// FetchError は、キャッシュにフォールバックした原因のエラーです
+	FetchError error
+}
+
+// IsRemote は、設定のパスがリモートの URL（http, https, s3, consul, etcd）かどうかを返します。
+func IsRemote(path string) bool {
+	if isKV(path) {
+		return true
+	}
+	for _, prefix := range []string{"http://", "https://", "s3://"} {
+		if strings.HasPrefix(strings.ToLower(path), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchRemote は、リモートの設定ファイルを取得・検証し、ローカルのキャッシュに保存します。
+// 取得または検証に失敗した場合は、前回保存したキャッシュにフォールバックします。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - rawURL: 設定ファイルの URL（http://, https://, s3://bucket/key, consul://, etcd://）
+//   - opts: 検証とキャッシュのオプション
+//
+// Returns:
+//   - *RemoteFile: 取得結果（読み込むローカルファイルのパス）
+//   - error: 取得に失敗し、使用できるキャッシュもない場合
+func FetchRemote(ctx context.Context, rawURL string, opts RemoteOptions) (*RemoteFile, error) {
+	cachePath, err := remoteCachePath(rawURL, opts.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, fetchErr := fetchAndVerify(ctx, rawURL, opts)
+	if fetchErr == nil {
+		if err := writeFileAtomic(cachePath, data); err != nil {
+			return nil, fmt.Errorf("リモート設定のキャッシュ保存に失敗しました: %w", err)
+		}
+		return &RemoteFile{Path: cachePath}, nil
+	}
+
+	// 取得に失敗した場合はキャッシュを使う（チェックサム指定時はキャッシュも検証する）
+	cached, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, fmt.Errorf("リモート設定の取得に失敗し、キャッシュもありません: %w", fetchErr)
+	}
+	if err := verifyChecksum(cached, opts.SHA256); err != nil {
+		return nil, fmt.Errorf("リモート設定の取得に失敗し、キャッシュも検証に失敗しました: %w (キャッシュ: %v)", fetchErr, err)
+	}
+
+	return &RemoteFile{Path: cachePath, FromCache: true, FetchError: fetchErr}, nil
+}
+
+// fetchAndVerify は、リモートの設定を取得し、チェックサムと署名を検証します。
+func fetchAndVerify(ctx context.Context, rawURL string, opts RemoteOptions) ([]byte, error) {
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultRemoteTimeout}
+	}
+
+	data, err := fetchRemoteObject(ctx, client, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksum(data, opts.SHA256); err != nil {
+		return nil, err
+	}
+
+	if opts.PublicKey != "" {
+		sig, err := fetchRemoteObject(ctx, client, signatureURL(rawURL))
+		if err != nil {
+			return nil, fmt.Errorf("署名の取得に失敗しました: %w", err)
+		}
+		if err := verifySignature(data, sig, opts.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// fetchRemoteObject は、HTTP(S)、S3、または KV ストアの URL から内容を取得します。
+func fetchRemoteObject(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	if isKV(rawURL) {
+		backend, err := newKVBackend(rawURL, client)
+		if err != nil {
+			return nil, err
+		}
+		data, _, err := backend.get(ctx)
+		return data, err
+	}
+
+	req, err := newRemoteRequest(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("リモート設定の取得に失敗しました (%s): %w", RedactURL(rawURL), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("リモート設定の取得でHTTPステータスエラー: %d (%s)", resp.StatusCode, RedactURL(rawURL))
+	}
+
+	return readLimited(resp.Body)
+}
+
+// newRemoteRequest は、URL のスキームに応じた GET リクエストを作成します。
+// s3:// の場合は、環境変数の認証情報があれば AWS Signature Version 4 で署名します。
+func newRemoteRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("リモート設定の URL が不正です: %w", err)
+	}
+
+	if strings.ToLower(u.Scheme) != "s3" {
+		return http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	}
+
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("S3 の URL は s3://bucket/key の形式で指定してください: %s", rawURL)
+	}
+
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// エンドポイントが指定された場合（MinIO など）はパス形式でアクセスする
+	var objectURL string
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		objectURL = strings.TrimRight(endpoint, "/") + "/" + bucket + "/" + escapeKeyPath(key)
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapeKeyPath(key))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey != "" && secretKey != "" {
+		if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+			req.Header.Set("X-Amz-Security-Token", token)
+		}
+		signS3Request(req, accessKey, secretKey, region, time.Now().UTC())
+	}
+
+	return req, nil
+}
+
+// emptyPayloadHash は、空のリクエストボディの SHA-256 ハッシュです。
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3Request は、リクエストに AWS Signature Version 4 の Authorization ヘッダーを付与します。
+// Host、X-Amz-* と既に設定されているヘッダーを署名対象にします。
+func signS3Request(req *http.Request, accessKey, secretKey, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	// 署名対象のヘッダー（小文字・名前順）
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "user-agent" {
+			continue
+		}
+		headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := shortDate + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), shortDate)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 は、HMAC-SHA256 を計算します。
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapeKeyPath は、S3 や Consul のキーを "/" を残して URL エンコードします。
+func escapeKeyPath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// verifyChecksum は、内容の SHA-256 が期待値と一致するか確認します。
+// expected が空の場合は検証しません。
+func verifyChecksum(data []byte, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if expected == "" {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("リモート設定のチェックサムが一致しません (期待: %s, 実際: %s)", expected, actual)
+	}
+	return nil
+}
+
+// verifySignature は、Ed25519 署名（Base64）を公開鍵（Base64）で検証します。
+func verifySignature(data, sig []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("署名検証用の公開鍵が不正です (Base64 の Ed25519 公開鍵を指定してください)")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("署名の形式が不正です: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, signature) {
+		return fmt.Errorf("リモート設定の署名検証に失敗しました")
+	}
+	return nil
+}
+
+// signatureURL は、設定ファイルの URL に対応する署名ファイルの URL を返します。
+// クエリ文字列がある場合は、パスの末尾に ".sig" を付けます。
+func signatureURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL + ".sig"
+	}
+	u.Path += ".sig"
+	u.RawPath = ""
+	return u.String()
+}
+
+// remoteCachePath は、URL ごとのキャッシュファイルのパスを返します。
+func remoteCachePath(rawURL, cacheDir string) (string, error) {
+	if cacheDir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("キャッシュディレクトリを決定できません (-config-cache-dir で指定してください): %w", err)
+		}
+		cacheDir = filepath.Join(base, "duckdns")
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(cacheDir, "config-"+hex.EncodeToString(sum[:8])+".yaml"), nil
+}
+
+// writeFileAtomic は、一時ファイルに書き込んでから置き換えることで、
+// 途中までしか書かれていないファイルが残らないように保存します。
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RedactURL は、ログやエラーメッセージ用に URL のクエリ文字列と認証情報を取り除きます。
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+// firstEnv は、最初に設定されている環境変数の値を返します。
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
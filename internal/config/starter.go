@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultIPSources は、雛形の設定ファイルに記載する既定のIP取得ソースです。
+var DefaultIPSources = []string{
+	"https://api.ipify.org",
+	"https://ifconfig.me/ip",
+	"https://icanhazip.com",
+}
+
+// DefaultInterval は、雛形の設定ファイルに記載する既定の更新間隔です。
+const DefaultInterval = 5 * time.Minute
+
+// StarterOptions は、雛形の設定ファイルに埋め込む値を保持する構造体です。
+// 空の項目には既定値が使用されます。
+type StarterOptions struct {
+	// Domain は、DuckDNSのドメイン名です
+	Domain string
+
+	// Token は、DuckDNS APIトークンです
+	Token string
+
+	// Interval は、更新チェック間隔です
+	Interval time.Duration
+
+	// IPSources は、IP取得ソースのURLリストです
+	IPSources []string
+
+	// LogLevel は、ログ出力レベルです
+	LogLevel string
+
+	// LogFormat は、ログ出力形式です
+	LogFormat string
+}
+
+// withDefaults は、空の項目に既定値を補完したコピーを返します。
+func (o StarterOptions) withDefaults() StarterOptions {
+	if o.Domain == "" {
+		o.Domain = "your-domain"
+	}
+	if o.Token == "" {
+		o.Token = "your-token-here"
+	}
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if len(o.IPSources) == 0 {
+		o.IPSources = DefaultIPSources
+	}
+	if o.LogLevel == "" {
+		o.LogLevel = "info"
+	}
+	if o.LogFormat == "" {
+		o.LogFormat = "text"
+	}
+	return o
+}
+
+// starterTemplate は、コメント付きの雛形設定ファイルのテンプレートです。
+var starterTemplate = template.Must(template.New("starter").Funcs(template.FuncMap{
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+}).Parse(`# DuckDNS 自動更新プログラム - 設定ファイル
+# "duckdns config init" で生成されました。
+# 各項目は環境変数（DUCKDNS_DOMAIN など）で上書きすることもできます。
+
+# ========== DuckDNS 設定 ==========
+duckdns:
+  # DuckDNS のドメイン名（.duckdns.org は不要）
+  domain: {{ quote .Domain }}
+
+  # DuckDNS API のトークン
+  # このファイルのパーミッションは 600 に保ってください
+  # 環境変数 DUCKDNS_TOKEN で指定することもできます
+  token: {{ quote .Token }}
+
+# ========== 更新設定 ==========
+update:
+  # IP アドレス変更チェックの実行間隔（例: 5m, 1h, 30s）
+  interval: {{ .Interval }}
+
+# ========== グローバルIP取得ソース ==========
+# 上から順に試行され、最初に成功したものを使用します
+ip_sources:
+{{- range .IPSources }}
+  - {{ quote . }}
+{{- end }}
+
+# ========== ログ設定 ==========
+log:
+  # ログ出力レベル: debug, info, warn, error
+  level: {{ quote .LogLevel }}
+
+  # ログ出力形式: text, json
+  format: {{ quote .LogFormat }}
+`))
+
+// RenderStarter は、コメント付きの雛形設定ファイルの内容を生成します。
+//
+// Parameters:
+//   - opts: 雛形に埋め込む値（空の項目は既定値）
+//
+// Returns:
+//   - []byte: 生成されたYAML
+//   - error: 生成に失敗した場合
+func RenderStarter(opts StarterOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := starterTemplate.Execute(&buf, opts.withDefaults()); err != nil {
+		return nil, fmt.Errorf("雛形の生成に失敗しました: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteStarter は、雛形の設定ファイルをパーミッション 0600 で書き込みます。
+// 親ディレクトリが存在しない場合はパーミッション 0700 で作成します。
+// force が false の場合、既存のファイルは上書きしません。
+//
+// Parameters:
+//   - path: 書き込み先のパス
+//   - data: 書き込む内容
+//   - force: 既存のファイルを上書きする場合は true
+//
+// Returns:
+//   - error: 書き込みに失敗した場合
+func WriteStarter(path string, data []byte, force bool) error {
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("書き込み先のパスが指定されていません")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %w", err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("設定ファイルが既に存在します (上書きするには -force を指定してください): %s", path)
+		}
+		return fmt.Errorf("設定ファイルの作成に失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	// 既存のファイルを上書きした場合もパーミッションを 0600 にそろえる
+	if err := file.Chmod(0600); err != nil {
+		return fmt.Errorf("パーミッションの設定に失敗しました: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("設定ファイルの書き込みに失敗しました: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRenderStarter は、生成された雛形が厳格モードで読み込めて検証を通ることをテストします。
+func TestRenderStarter(t *testing.T) {
+	data, err := RenderStarter(StarterOptions{
+		Domain:   "my-home",
+		Token:    "abc\"123",
+		Interval: 10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("雛形の生成に失敗しました: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("ファイルの作成に失敗: %v", err)
+	}
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("生成された雛形の読み込みに失敗しました: %v\n%s", err, data)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("生成された雛形が検証を通りません: %v", err)
+	}
+
+	if cfg.DuckDNS.Domain != "my-home" || cfg.DuckDNS.Token != "abc\"123" {
+		t.Errorf("ドメインまたはトークンが一致しません: %+v", cfg.DuckDNS)
+	}
+	if cfg.Update.Interval != 10*time.Minute {
+		t.Errorf("更新間隔が一致しません。期待: 10m, 実際: %v", cfg.Update.Interval)
+	}
+	if len(cfg.IPSources) != len(DefaultIPSources) {
+		t.Errorf("既定のIP取得ソースが使われるべき。実際: %v", cfg.IPSources)
+	}
+	if cfg.Log.Level != "info" || cfg.Log.Format != "text" {
+		t.Errorf("既定のログ設定が使われるべき。実際: %+v", cfg.Log)
+	}
+}
+
+// TestWriteStarter は、0600 で書き込まれ、既存ファイルを上書きしないことをテストします。
+func TestWriteStarter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+
+	if err := WriteStarter(path, []byte("first\n"), false); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("ファイルが作成されていません: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("パーミッションが一致しません。期待: 0600, 実際: %o", info.Mode().Perm())
+	}
+
+	err = WriteStarter(path, []byte("second\n"), false)
+	if err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("既存ファイルがある場合は -force を案内するエラーになるべき: %v", err)
+	}
+
+	if err := WriteStarter(path, []byte("second\n"), true); err != nil {
+		t.Fatalf("force 指定時は上書きできるべき: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "second\n" {
+		t.Errorf("上書き後の内容が一致しません: %q", data)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// UTC しか持たない機器（zoneinfo のない小さな Linux など）でもタイムゾーンを解決できるように、タイムゾーンのデータベースを埋め込みます
+	_ "time/tzdata"
+)
+
+// Location は、timezone に設定したタイムゾーンを返します。
+// 省略した場合は、ホストのローカルタイム（time.Local）を返します。
+//
+// Returns:
+//   - *time.Location: 時刻の表示に使うタイムゾーン
+//   - error: タイムゾーンの名前が不正な場合
+func (c *Config) Location() (*time.Location, error) {
+	name := strings.TrimSpace(c.Timezone)
+	if name == "" {
+		return time.Local, nil
+	}
+	// "Local" はホストの設定に従うという意味で、timezone の指定としては紛らわしいため受け付けない
+	if name == "Local" {
+		return nil, fmt.Errorf("タイムゾーン \"%s\" は指定できません。省略するとホストのローカルタイムを使用します", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("無効なタイムゾーン \"%s\" です (例: \"Asia/Tokyo\", \"UTC\"): %w", name, err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLocation は、timezone からタイムゾーンを解決することをテストします。
+func TestLocation(t *testing.T) {
+	cfg := &Config{}
+	loc, err := cfg.Location()
+	if err != nil || loc != time.Local {
+		t.Errorf("timezone を省略した場合は time.Local になるべき: %v, %v", loc, err)
+	}
+
+	cfg.Timezone = "Asia/Tokyo"
+	loc, err = cfg.Location()
+	if err != nil {
+		t.Fatalf("Asia/Tokyo は有効であるべき: %v", err)
+	}
+	if loc.String() != "Asia/Tokyo" {
+		t.Errorf("タイムゾーンが一致しません。期待: Asia/Tokyo, 実際: %s", loc)
+	}
+	if _, offset := time.Date(2026, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != 9*60*60 {
+		t.Errorf("Asia/Tokyo のオフセットは +9時間であるべき: %d", offset)
+	}
+
+	for _, name := range []string{"Mars/Olympus", "Local"} {
+		cfg.Timezone = name
+		if _, err := cfg.Location(); err == nil {
+			t.Errorf("%s は無効であるべき", name)
+		}
+	}
+}
+
+// TestValidate_Timezone は、timezone のバリデーションをテストします。
+func TestValidate_Timezone(t *testing.T) {
+	cfg := &Config{
+		DuckDNS:   DuckDNSConfig{Domain: "home", Token: "test-token"},
+		Update:    UpdateConfig{Interval: 5 * time.Minute},
+		IPSources: IPSources{{URL: "https://api.ipify.org"}},
+		Timezone:  "Europe/Berlin",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("有効なタイムゾーンはエラーにならないべき: %v", err)
+	}
+
+	cfg.Timezone = "Europe/Nowhere"
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) != 1 || ve.Keys[0] != "timezone" {
+		t.Errorf("timezone のエラーになるべき: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Package crashreport は、パニックから回復したときのクラッシュレポートをファイルに書き出します。
+// まれにしか起きないパニックで更新が止まっても、あとから原因を調べられるように、
+// スタックトレース・ドメインごとの最新の状況・秘密の値を伏せた設定を1つのファイルにまとめます。
+package crashreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// DefaultMaxReports は、残すクラッシュレポートの数を省略した場合の値です。
+const DefaultMaxReports = 10
+
+// filePrefix と fileSuffix は、クラッシュレポートのファイル名の先頭と末尾です。
+const (
+	filePrefix = "crash-"
+	fileSuffix = ".txt"
+)
+
+// Report は、1回のパニックのクラッシュレポートです。
+type Report struct {
+	// Time は、パニックから回復した時刻です（ファイル名にも使います）
+	Time time.Time
+
+	// Version は、動いていたバージョンです
+	Version string
+
+	// Where は、パニックが発生した場所です（"loop"、"update" など）
+	Where string
+
+	// Panic は、panic に渡された値です
+	Panic any
+
+	// Domains は、パニックが発生したスケジューラーの更新先のドメイン名です
+	Domains []string
+
+	// Stack は、パニックが発生したゴルーチンのスタックトレースです
+	Stack []byte
+
+	// Status は、ドメインごとの最新の状況です（読み込めなかった場合は nil）
+	Status []*state.DomainStatus
+
+	// Config は、秘密の値を伏せた設定の YAML です（ない場合は空）
+	Config []byte
+}
+
+// Write は、クラッシュレポートを dir に "crash-<時刻>.txt" の名前で書き出します。
+// dir がない場合は作成し、古いクラッシュレポートは maxReports 個だけ残して削除します。
+//
+// Parameters:
+//   - dir: 書き出すディレクトリ
+//   - maxReports: 残すクラッシュレポートの数（0 以下の場合は DefaultMaxReports）
+//   - r: クラッシュレポート
+//
+// Returns:
+//   - string: 書き出したファイルのパス
+//   - error: 書き出せなかった場合
+func Write(dir string, maxReports int, r Report) (string, error) {
+	if maxReports <= 0 {
+		maxReports = DefaultMaxReports
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("ディレクトリを作成できません: %w", err)
+	}
+
+	path := filepath.Join(dir, filePrefix+r.Time.UTC().Format("20060102T150405.000000000Z")+fileSuffix)
+	if err := os.WriteFile(path, r.format(), 0o600); err != nil {
+		return "", err
+	}
+	prune(dir, maxReports)
+	return path, nil
+}
+
+// format は、クラッシュレポートを読みやすいテキストにします。
+func (r Report) format() []byte {
+	var b strings.Builder
+	fmt.Fprintln(&b, "duckdns クラッシュレポート")
+	fmt.Fprintf(&b, "時刻: %s\n", r.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "バージョン: %s\n", r.Version)
+	fmt.Fprintf(&b, "場所: %s\n", r.Where)
+	fmt.Fprintf(&b, "パニック: %v\n", r.Panic)
+	fmt.Fprintf(&b, "ドメイン: %s\n", strings.Join(r.Domains, ", "))
+
+	fmt.Fprintln(&b, "\n===== スタックトレース =====")
+	b.Write(r.Stack)
+
+	fmt.Fprintln(&b, "\n===== ドメインごとの最新の状況 =====")
+	if status, err := json.MarshalIndent(r.Status, "", "  "); err == nil && r.Status != nil {
+		b.Write(status)
+		b.WriteString("\n")
+	} else {
+		fmt.Fprintln(&b, "(読み込めませんでした)")
+	}
+
+	fmt.Fprintln(&b, "\n===== 設定（秘密の値は伏せています） =====")
+	if len(r.Config) > 0 {
+		b.Write(r.Config)
+	} else {
+		fmt.Fprintln(&b, "(ありません)")
+	}
+	return []byte(b.String())
+}
+
+// prune は、古いクラッシュレポートを maxReports 個だけ残して削除します。
+// 削除に失敗しても、クラッシュレポートは書き出せているので無視します。
+func prune(dir string, maxReports int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if name := e.Name(); !e.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for len(names) > maxReports {
+		os.Remove(filepath.Join(dir, names[0]))
+		names = names[1:]
+	}
+}
@@ -0,0 +1,74 @@
+package crashreport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// TestWrite は、クラッシュレポートにスタックトレース・状況・設定を書き出すことをテストします。
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crash")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	path, err := Write(dir, 0, Report{
+		Time:    now,
+		Version: "v1.2.3",
+		Where:   "loop",
+		Panic:   "壊れています",
+		Domains: []string{"home"},
+		Stack:   []byte("goroutine 1 [running]:\n"),
+		Status:  []*state.DomainStatus{{Domain: "home", IP: "192.0.2.1", ConsecutiveFailures: 2}},
+		Config:  []byte("duckdns:\n    token: REDACTED\n"),
+	})
+	if err != nil {
+		t.Fatalf("書き出しに失敗しました: %v", err)
+	}
+	if filepath.Base(path) != "crash-20260102T030405.000000000Z.txt" {
+		t.Errorf("ファイル名は時刻にするべき: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	for _, want := range []string{"パニック: 壊れています", "バージョン: v1.2.3", "goroutine 1 [running]:", `"consecutive_failures": 2`, "token: REDACTED"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%q を含むべき:\n%s", want, data)
+		}
+	}
+}
+
+// TestWrite_Prune は、古いクラッシュレポートを残す数だけ残して削除することをテストします。
+func TestWrite_Prune(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "other.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 4; i++ {
+		path, err := Write(dir, 2, Report{Time: start.Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("書き出しに失敗しました: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 3 {
+		t.Fatalf("新しい2つとほかのファイルだけが残るべき: %v", names)
+	}
+	for _, path := range paths[2:] {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("新しいクラッシュレポートは残すべき: %v", err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package docker は、ローカルの Docker デーモンのコンテナのラベルから DuckDNS のドメインを見つけるための、最小限の API クライアントを提供します。
+// Docker の SDK には依存せず、Engine API（コンテナの一覧とイベント）だけを使います。
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// DefaultHost は、Docker デーモンのアドレスの既定値です（DOCKER_HOST と同じ形式）。
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// maxResponseSize は、コンテナの一覧として読み込む最大サイズです。
+const maxResponseSize = 16 << 20
+
+// maxEventSize は、イベントのストリームから1件のイベントとして読み込む最大サイズです。
+const maxEventSize = 1 << 20
+
+// Container は、コンテナの一覧の1つのコンテナです（Engine API の /containers/json の項目のうち、使うものだけ）。
+type Container struct {
+	// ID は、コンテナの ID です
+	ID string `json:"Id"`
+
+	// Names は、コンテナの名前です（"/name" の形式）
+	Names []string `json:"Names"`
+
+	// Labels は、コンテナのラベルです
+	Labels map[string]string `json:"Labels"`
+
+	// State は、コンテナの状態です（"running" など）
+	State string `json:"State"`
+}
+
+// Name は、コンテナの名前を返します（名前がない場合は ID の先頭12文字）。
+func (c Container) Name() string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
+
+// WatchedActions は、コンテナの一覧が変わる可能性があるイベントの種類です。
+// ヘルスチェックの exec_start などは一覧に影響しないので、受け取りません。
+var WatchedActions = []string{"start", "die", "destroy", "rename"}
+
+// Event は、Docker デーモンのイベントです（Engine API の /events の項目のうち、使うものだけ）。
+type Event struct {
+	// Type は、イベントの対象の種類です（"container" など）
+	Type string `json:"Type"`
+
+	// Action は、イベントの種類です（"start", "die", "destroy" など）
+	Action string `json:"Action"`
+
+	// Actor は、イベントの対象です
+	Actor struct {
+		// ID は、対象の ID です
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+// Client は、Docker デーモンの Engine API のクライアントです。
+type Client struct {
+	base       string
+	httpClient *http.Client
+}
+
+// NewClient は、Docker デーモンのアドレスから Client を作成します。
+//
+// Parameters:
+//   - host: Docker デーモンのアドレス（"unix:///var/run/docker.sock"、"tcp://127.0.0.1:2375" など。空の場合は DefaultHost）
+//
+// Returns:
+//   - *Client: 作成された Client
+//   - error: アドレスの形式が不正な場合
+func NewClient(host string) (*Client, error) {
+	if host == "" {
+		host = DefaultHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("Docker デーモンのアドレスが不正です: %s", host)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	base := ""
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("Docker デーモンのソケットのパスがありません: %s", host)
+		}
+		socket := u.Path
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		base = "http://docker"
+	case "tcp", "http":
+		if u.Host == "" {
+			return nil, fmt.Errorf("Docker デーモンのアドレスが不正です: %s", host)
+		}
+		base = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("Docker デーモンのアドレスは unix:// または tcp:// で指定してください: %s", host)
+	}
+
+	return &Client{base: base, httpClient: &http.Client{Transport: transport}}, nil
+}
+
+// ListContainers は、ラベル label が付いた実行中のコンテナの一覧を取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - label: コンテナを絞り込むラベルの名前
+//
+// Returns:
+//   - []Container: コンテナの一覧
+//   - error: 取得に失敗した場合
+func (c *Client) ListContainers(ctx context.Context, label string) ([]Container, error) {
+	filters, _ := json.Marshal(map[string][]string{"label": {label}})
+	resp, err := c.get(ctx, "/containers/json?filters="+url.QueryEscape(string(filters)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var containers []Container
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("コンテナの一覧の解析に失敗しました: %w", err)
+	}
+	return containers, nil
+}
+
+// WatchEvents は、ラベル label が付いたコンテナの起動や停止などのイベントを監視し、イベントごとに fn を呼び出します。
+// since 以降のイベントから受け取るので、一覧を取得してから監視を始めるまでのイベントも取りこぼしません。
+// ctx がキャンセルされるか、接続が切れるまで戻りません。
+//
+// Parameters:
+//   - ctx: 監視を止めるためのコンテキスト
+//   - label: コンテナを絞り込むラベルの名前
+//   - since: この時刻以降のイベントを受け取ります（ゼロ値の場合は今から）
+//   - fn: イベントごとに呼び出す関数
+//
+// Returns:
+//   - error: 接続に失敗した場合、または接続が切れた場合
+func (c *Client) WatchEvents(ctx context.Context, label string, since time.Time, fn func(Event)) error {
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"label": {label},
+		"event": WatchedActions,
+	})
+	query := "filters=" + url.QueryEscape(string(filters))
+	if !since.IsZero() {
+		query += "&since=" + strconv.FormatInt(since.Unix(), 10)
+	}
+	resp, err := c.get(ctx, "/events?"+query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := httpclient.NewEventDecoder(resp.Body, maxEventSize)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("Docker デーモンがイベントの接続を閉じました")
+			}
+			return fmt.Errorf("イベントの読み込みに失敗しました: %w", err)
+		}
+		fn(event)
+	}
+}
+
+// get は、GET リクエストを送ります。2xx 以外のステータスの場合はエラーを返します。
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗しました: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Docker デーモンに接続できません: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		_ = json.Unmarshal(data, &body)
+		return nil, fmt.Errorf("Docker API のエラー: %d: %s", resp.StatusCode, body.Message)
+	}
+	return resp, nil
+}
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// newTestClient は、テスト用のサーバーに接続する Client を作成します。
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// TestNewClient は、Docker デーモンのアドレスの解釈をテストします。
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		host     string
+		wantBase string
+		wantErr  bool
+	}{
+		{"", "http://docker", false},
+		{"unix:///run/user/1000/docker.sock", "http://docker", false},
+		{"tcp://127.0.0.1:2375", "http://127.0.0.1:2375", false},
+		{"unix://", "", true},
+		{"tcp://", "", true},
+		{"ssh://user@host", "", true},
+	}
+	for _, tt := range tests {
+		client, err := NewClient(tt.host)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewClient(%q) のエラー = %v (エラーを期待: %v)", tt.host, err, tt.wantErr)
+			continue
+		}
+		if err == nil && client.base != tt.wantBase {
+			t.Errorf("NewClient(%q) の base = %q (期待値 %q)", tt.host, client.base, tt.wantBase)
+		}
+	}
+}
+
+// TestClient_ListContainers は、ラベルで絞り込んだコンテナの一覧を取得することをテストします。
+func TestClient_ListContainers(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			t.Errorf("パス = %s", r.URL.Path)
+		}
+		var filters map[string][]string
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil || filters["label"][0] != LabelDomain {
+			t.Errorf("ラベルで絞り込むべき: %q", r.URL.Query().Get("filters"))
+		}
+		w.Write([]byte(`[{"Id":"abc","Names":["/web"],"Labels":{"duckdns.domain":"home"},"State":"running"}]`))
+	})
+
+	containers, err := client.ListContainers(context.Background(), LabelDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers[0].Name() != "web" || containers[0].Labels[LabelDomain] != "home" {
+		t.Errorf("一覧 = %+v", containers)
+	}
+}
+
+// TestClient_Error は、Docker API のエラーのメッセージを返すことをテストします。
+func TestClient_Error(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message":"daemon is broken"}`))
+	})
+
+	_, err := client.ListContainers(context.Background(), LabelDomain)
+	if err == nil || !strings.Contains(err.Error(), "daemon is broken") {
+		t.Errorf("Docker API のメッセージを含むエラーを返すべき: %v", err)
+	}
+}
+
+// TestClient_WatchEvents は、イベントを順に受け取り、since を渡すことをテストします。
+func TestClient_WatchEvents(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("since"); got != "1700000000" {
+			t.Errorf("since = %q", got)
+		}
+		w.Write([]byte(`{"Type":"container","Action":"start","Actor":{"ID":"abc"}}
+{"Type":"container","Action":"die","Actor":{"ID":"abc"}}
+`))
+	})
+
+	var actions []string
+	err := client.WatchEvents(context.Background(), LabelDomain, since, func(e Event) {
+		actions = append(actions, e.Action)
+	})
+	if err == nil {
+		t.Error("接続が閉じられた場合はエラーを返すべき")
+	}
+	if strings.Join(actions, ",") != "start,die" {
+		t.Errorf("イベント = %v", actions)
+	}
+}
+
+// TestClient_WatchEvents_TooLarge は、上限を超えるイベントで、読み込みを止めてエラーを返すことをテストします。
+func TestClient_WatchEvents_TooLarge(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Type":"container","Action":"start","Actor":{"ID":"abc"}}` + "\n"))
+		w.Write([]byte(`{"Type":"container","Action":"` + strings.Repeat("a", maxEventSize) + `"}` + "\n"))
+	})
+
+	var actions []string
+	err := client.WatchEvents(context.Background(), LabelDomain, time.Time{}, func(e Event) {
+		actions = append(actions, e.Action)
+	})
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("上限を超えるイベントは ErrResponseTooLarge を返すべき: %v", err)
+	}
+	if strings.Join(actions, ",") != "start" {
+		t.Errorf("上限より前のイベントだけを渡すべき: %v", actions)
+	}
+}
+
+// TestContainer_Name は、名前がない場合に ID の先頭を返すことをテストします。
+func TestContainer_Name(t *testing.T) {
+	c := Container{ID: "0123456789abcdef"}
+	if got := c.Name(); got != "0123456789ab" {
+		t.Errorf("Name() = %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// コンテナのラベルです。
+const (
+	// LabelDomain は、更新する DuckDNS のドメイン名のラベルです（例: "home" または "home,nas"）
+	LabelDomain = "duckdns.domain"
+
+	// LabelToken は、DuckDNS のトークンのラベルです（省略時は設定ファイルの duckdns.token）
+	LabelToken = "duckdns.token"
+
+	// LabelInterval は、更新チェックの間隔のラベルです（例: "5m"。省略時は設定ファイルの update.interval）
+	LabelInterval = "duckdns.interval"
+
+	// LabelIPv6 は、IPv6アドレスも更新するかどうかのラベルです（"true" または "false"）
+	LabelIPv6 = "duckdns.ipv6"
+)
+
+// Spec は、コンテナのラベルから読み取った更新するレコードの定義です。
+type Spec struct {
+	// Domains は、更新するドメイン名です（ラベルに書かれたまま）
+	Domains []string
+
+	// Token は、DuckDNS のトークンです（空の場合は設定ファイルのトークン）
+	Token string
+
+	// Interval は、更新チェックの間隔です（0 の場合は設定ファイルの update.interval）
+	Interval time.Duration
+
+	// IPv6 は、IPv6アドレスも更新するかどうかです（nil の場合は設定ファイルの update.ipv6）
+	IPv6 *bool
+}
+
+// ParseLabels は、コンテナのラベルから更新するレコードの定義を読み取ります。
+//
+// Parameters:
+//   - labels: コンテナのラベル
+//
+// Returns:
+//   - Spec: 更新するレコードの定義
+//   - error: ドメイン名がない場合や、ラベルの値が不正な場合
+func ParseLabels(labels map[string]string) (Spec, error) {
+	var spec Spec
+	for _, d := range strings.Split(labels[LabelDomain], ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			spec.Domains = append(spec.Domains, d)
+		}
+	}
+	if len(spec.Domains) == 0 {
+		return Spec{}, fmt.Errorf("ラベル %s にドメイン名がありません", LabelDomain)
+	}
+
+	spec.Token = strings.TrimSpace(labels[LabelToken])
+
+	if v := strings.TrimSpace(labels[LabelInterval]); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Spec{}, fmt.Errorf("ラベル %s の形式が不正です: %q", LabelInterval, v)
+		}
+		spec.Interval = d
+	}
+
+	if v := strings.TrimSpace(labels[LabelIPv6]); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Spec{}, fmt.Errorf("ラベル %s は true または false で指定してください: %q", LabelIPv6, v)
+		}
+		spec.IPv6 = &b
+	}
+	return spec, nil
+}
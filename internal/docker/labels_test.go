@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestParseLabels は、コンテナのラベルの読み取りをテストします。
+func TestParseLabels(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		want    Spec
+		wantErr bool
+	}{
+		{
+			name:   "ドメインだけ",
+			labels: map[string]string{LabelDomain: "home"},
+			want:   Spec{Domains: []string{"home"}},
+		},
+		{
+			name: "すべてのラベル",
+			labels: map[string]string{
+				LabelDomain:   " home , nas.duckdns.org ,",
+				LabelToken:    "token",
+				LabelInterval: "1m",
+				LabelIPv6:     "true",
+			},
+			want: Spec{Domains: []string{"home", "nas.duckdns.org"}, Token: "token", Interval: time.Minute, IPv6: &yes},
+		},
+		{name: "ドメインが空", labels: map[string]string{LabelDomain: " , "}, wantErr: true},
+		{name: "間隔の誤り", labels: map[string]string{LabelDomain: "home", LabelInterval: "soon"}, wantErr: true},
+		{name: "IPv6 の誤り", labels: map[string]string{LabelDomain: "home", LabelIPv6: "yes please"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLabels(tt.labels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("エラー = %v (エラーを期待: %v)", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLabels() = %+v (期待値 %+v)", got, tt.want)
+			}
+		})
+	}
+}
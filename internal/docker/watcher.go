@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultRetryInterval は、一覧の取得や監視に失敗した場合に、やり直すまで待つ時間の既定値です。
+const DefaultRetryInterval = 5 * time.Second
+
+// Watcher は、ラベル duckdns.domain が付いた実行中のコンテナの一覧を取得してからイベントを監視し、一覧が変わるたびに最新の一覧を渡します。
+type Watcher struct {
+	// Client は、Docker デーモンのクライアントです
+	Client *Client
+
+	// RetryInterval は、失敗した場合にやり直すまで待つ時間です（0 の場合は DefaultRetryInterval）
+	RetryInterval time.Duration
+}
+
+// Run は、ctx がキャンセルされるまでコンテナを監視し、一覧が変わるたびに onChange を呼び出します。
+// 最初に一覧を取得したときは、空でも必ず onChange を呼び出します。
+// onChange には、コンテナの名前の順に並べた一覧を渡します。
+//
+// Parameters:
+//   - ctx: 監視を止めるためのコンテキスト
+//   - onChange: 一覧が変わるたびに呼び出す関数
+func (w *Watcher) Run(ctx context.Context, onChange func(containers []Container)) {
+	retry := w.RetryInterval
+	if retry <= 0 {
+		retry = DefaultRetryInterval
+	}
+
+	var current []Container
+	first := true
+	// list は、一覧を取得し直して、変わっていれば onChange を呼び出します
+	list := func() bool {
+		containers, err := w.Client.ListContainers(ctx, LabelDomain)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("コンテナの一覧を取得できませんでした",
+					"retry_in", retry,
+					"error", err,
+				)
+			}
+			return false
+		}
+		sortContainers(containers)
+		if first || !sameContainers(current, containers) {
+			first = false
+			current = containers
+			onChange(containers)
+		}
+		return true
+	}
+
+	for ctx.Err() == nil {
+		// 一覧を取得する前の時刻からイベントを受け取り、その間の変更を取りこぼさないようにします
+		since := time.Now()
+		if !list() {
+			sleep(ctx, retry)
+			continue
+		}
+
+		err := w.Client.WatchEvents(ctx, LabelDomain, since, func(event Event) {
+			slog.Debug("コンテナのイベントを受け取りました",
+				"action", event.Action,
+				"container", event.Actor.ID,
+			)
+			list()
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("コンテナの監視が途切れたため、一覧を取得し直します",
+			"retry_in", retry,
+			"error", err,
+		)
+		sleep(ctx, retry)
+	}
+}
+
+// sortContainers は、一覧をコンテナの名前の順に並べます。
+func sortContainers(containers []Container) {
+	slices.SortFunc(containers, func(a, b Container) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+}
+
+// sameContainers は、2つの一覧のコンテナの ID と名前とラベルが同じかどうかを返します。
+func sameContainers(a, b []Container) bool {
+	return slices.EqualFunc(a, b, func(x, y Container) bool {
+		return x.ID == y.ID && x.Name() == y.Name() && maps.Equal(x.Labels, y.Labels)
+	})
+}
+
+// sleep は、d だけ待ちます。ctx がキャンセルされた場合はすぐに戻ります。
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWatcher_Run は、一覧を渡してから、イベントで一覧が変わった場合だけ onChange を呼び出すことをテストします。
+func TestWatcher_Run(t *testing.T) {
+	var lists atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			switch lists.Add(1) {
+			case 1:
+				w.Write([]byte(`[{"Id":"b","Names":["/web"],"Labels":{"duckdns.domain":"web"}}]`))
+			case 2:
+				// 一覧が変わらないイベントでは onChange を呼び出しません
+				w.Write([]byte(`[{"Id":"b","Names":["/web"],"Labels":{"duckdns.domain":"web"}}]`))
+			default:
+				w.Write([]byte(`[{"Id":"b","Names":["/web"],"Labels":{"duckdns.domain":"web"}},
+					{"Id":"a","Names":["/app"],"Labels":{"duckdns.domain":"app"}}]`))
+			}
+		case "/events":
+			w.Write([]byte(`{"Type":"container","Action":"die","Actor":{"ID":"x"}}
+{"Type":"container","Action":"start","Actor":{"ID":"a"}}
+`))
+			w.(http.Flusher).Flush()
+			// テストが終わるまで接続を閉じません
+			<-r.Context().Done()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []Container, 10)
+	done := make(chan struct{})
+	go func() {
+		(&Watcher{Client: client, RetryInterval: 10 * time.Millisecond}).Run(ctx, func(containers []Container) {
+			changes <- containers
+		})
+		close(done)
+	}()
+
+	want := [][]string{{"web"}, {"app", "web"}}
+	for i, names := range want {
+		select {
+		case containers := <-changes:
+			if len(containers) != len(names) {
+				t.Fatalf("%d 回目: 一覧 = %+v (期待値 %v)", i+1, containers, names)
+			}
+			for j, c := range containers {
+				if c.Name() != names[j] {
+					t.Errorf("%d 回目: %d 番目 = %s (期待値 %s)", i+1, j, c.Name(), names[j])
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d 回目の一覧が渡されなかった", i+1)
+		}
+	}
+	select {
+	case containers := <-changes:
+		t.Errorf("一覧が変わらない場合は onChange を呼び出すべきでない: %+v", containers)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("キャンセルしても Run が戻らなかった")
+	}
+}
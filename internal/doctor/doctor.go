@@ -0,0 +1,276 @@
+// Package doctor は、doctor コマンドで実行する診断チェックを提供します。
+// 設定・IP取得ソース・名前解決・トークン・時刻のずれを確認し、
+// サポート依頼に添付できる結果の一覧を作成します。
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/ip"
+)
+
+// DuckDNSHost は、名前解決と時刻の確認に使う DuckDNS のホスト名です。
+const DuckDNSHost = "www.duckdns.org"
+
+// DefaultMaxClockSkew は、警告せずに許容する時刻のずれです。
+const DefaultMaxClockSkew = 30 * time.Second
+
+// Status は、チェックの結果の種類です。
+type Status int
+
+const (
+	// StatusPass は、問題がないことを表します
+	StatusPass Status = iota
+
+	// StatusWarn は、動作はするが確認が必要なことを表します
+	StatusWarn
+
+	// StatusFail は、更新に失敗する原因となる問題があることを表します
+	StatusFail
+)
+
+// String は、結果の種類を表示用の文字列で返します。
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "PASS"
+	case StatusWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result は、1つのチェックの結果です。
+type Result struct {
+	// Name は、チェックの名前です
+	Name string
+
+	// Status は、結果の種類です
+	Status Status
+
+	// Detail は、結果の詳細（取得したIPアドレスやエラーメッセージ）です
+	Detail string
+
+	// Latency は、チェックにかかった時間です（計測しない場合は 0）
+	Latency time.Duration
+}
+
+// Resolver は、ホスト名を解決するインターフェースです。
+// *net.Resolver がこのインターフェースを満たします。
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Failed は、結果の中に失敗が含まれるかどうかを返します。
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIPSources は、IP取得ソースごとにIPアドレスを取得し、応答時間を計測します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - sources: IP取得ソースのURL一覧
+//   - timeout: ソースごとのタイムアウト
+//
+// Returns:
+//   - []Result: ソースごとの結果（すべて失敗した場合は最後に失敗の結果を追加）
+func CheckIPSources(ctx context.Context, sources []string, timeout time.Duration) []Result {
+	results := make([]Result, 0, len(sources)+1)
+	reachable := 0
+
+	for _, source := range sources {
+		start := time.Now()
+		addr, err := ip.NewSourceFetcher(source, ip.IPv4, timeout).Fetch(ctx)
+		latency := time.Since(start)
+
+		if err != nil {
+			// 1つのソースの失敗はフェイルオーバーで補えるため警告とする
+			results = append(results, Result{
+				Name:    "IP取得ソース " + source,
+				Status:  StatusWarn,
+				Detail:  err.Error(),
+				Latency: latency,
+			})
+			continue
+		}
+
+		reachable++
+		results = append(results, Result{
+			Name:    "IP取得ソース " + source,
+			Status:  StatusPass,
+			Detail:  addr,
+			Latency: latency,
+		})
+	}
+
+	if reachable == 0 {
+		results = append(results, Result{
+			Name:   "IP取得ソース",
+			Status: StatusFail,
+			Detail: "IPアドレスを取得できるソースがありません",
+		})
+	}
+	return results
+}
+
+// CheckDNS は、ホスト名を名前解決できるかどうかを確認します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - resolver: 名前解決に使う Resolver
+//   - host: 解決するホスト名
+//
+// Returns:
+//   - Result: チェックの結果
+func CheckDNS(ctx context.Context, resolver Resolver, host string) Result {
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, host)
+	latency := time.Since(start)
+
+	result := Result{Name: "名前解決 " + host, Latency: latency}
+	if err != nil {
+		result.Status = StatusFail
+		result.Detail = err.Error()
+		return result
+	}
+	if len(addrs) == 0 {
+		result.Status = StatusFail
+		result.Detail = "アドレスが見つかりません"
+		return result
+	}
+
+	result.Status = StatusPass
+	result.Detail = strings.Join(addrs, ", ")
+	return result
+}
+
+// CheckClockSkew は、サーバーの Date ヘッダーと比べて、ローカルの時刻のずれを確認します。
+// 時刻が大きくずれていると、TLS 証明書の検証などに失敗します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - client: HTTP クライアント
+//   - url: Date ヘッダーを返すサーバーの URL
+//   - maxSkew: 許容する時刻のずれ（超えた場合は警告）
+//
+// Returns:
+//   - Result: チェックの結果
+func CheckClockSkew(ctx context.Context, client *http.Client, url string, maxSkew time.Duration) Result {
+	result := Result{Name: "時刻のずれ"}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		result.Status = StatusWarn
+		result.Detail = err.Error()
+		return result
+	}
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	end := time.Now()
+	if err != nil {
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("サーバーの時刻を取得できません: %v", err)
+		return result
+	}
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		result.Status = StatusWarn
+		result.Detail = "サーバーが Date ヘッダーを返しませんでした"
+		return result
+	}
+
+	// リクエストの往復の中間をローカルの時刻とする（Date ヘッダーは秒単位のため 1 秒の誤差を許容）
+	local := start.Add(end.Sub(start) / 2)
+	skew := local.Sub(serverTime).Truncate(time.Second)
+	result.Latency = end.Sub(start)
+	result.Detail = fmt.Sprintf("ずれ %s (サーバー: %s)", skew, serverTime.UTC().Format(time.RFC3339))
+
+	if skew.Abs() > maxSkew+time.Second {
+		result.Status = StatusWarn
+		result.Detail += "。NTP などで時刻を合わせてください"
+		return result
+	}
+	result.Status = StatusPass
+	return result
+}
+
+// CheckToken は、ドメインに登録済みのIPアドレスで更新して、トークンが有効かどうかを確認します。
+// 登録済みのIPアドレスをそのまま送るため、DNS レコードは変わりません。
+// 拒否された場合は FAIL、DuckDNS に接続できない場合は判定できないため WARN になります。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - client: DuckDNS クライアント
+//   - resolver: 登録済みのIPアドレスを調べるための Resolver
+//   - domain: DuckDNS ドメイン名
+//   - token: DuckDNS API トークン
+//
+// Returns:
+//   - Result: チェックの結果
+func CheckToken(ctx context.Context, client *duckdns.Client, resolver Resolver, domain, token string) Result {
+	result := Result{Name: "トークン " + domain}
+
+	host := Hostname(domain)
+	addrs, err := resolver.LookupHost(ctx, host)
+	current := ""
+	if err == nil {
+		for _, addr := range addrs {
+			if parsed := net.ParseIP(addr); parsed != nil && parsed.To4() != nil {
+				current = addr
+				break
+			}
+		}
+	}
+	if current == "" {
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("%s の登録済みIPアドレスを取得できないため、確認をスキップしました", host)
+		return result
+	}
+
+	start := time.Now()
+	_, err = client.Update(ctx, duckdns.UpdateRequest{Domains: []string{domain}, Token: token, IPv4: current})
+	result.Latency = time.Since(start)
+	if errors.Is(err, duckdns.ErrRejected) {
+		result.Status = StatusFail
+		result.Detail = fmt.Sprintf("更新を拒否されました。ドメイン名とトークンを確認してください (%v)", err)
+		return result
+	}
+	if err != nil {
+		// 接続できない場合は、トークンの誤りかどうか分からないため警告にとどめる
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("DuckDNS に接続できないため、確認できませんでした (%v)", err)
+		return result
+	}
+
+	result.Status = StatusPass
+	result.Detail = fmt.Sprintf("有効 (登録済みの %s で確認)", current)
+	return result
+}
+
+// Hostname は、DuckDNS ドメイン名の完全なホスト名を返します。
+// "example" と "example.duckdns.org" のどちらを指定しても "example.duckdns.org" になります。
+func Hostname(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if strings.HasSuffix(domain, ".duckdns.org") {
+		return domain
+	}
+	return domain + ".duckdns.org"
+}
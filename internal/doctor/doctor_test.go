@@ -0,0 +1,147 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// fakeResolver は、テスト用の Resolver です。
+type fakeResolver map[string][]string
+
+// LookupHost は、登録されたアドレスを返します。
+func (f fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+// TestStatus_String は、結果の種類の文字列をテストします。
+func TestStatus_String(t *testing.T) {
+	tests := map[Status]string{StatusPass: "PASS", StatusWarn: "WARN", StatusFail: "FAIL"}
+	for status, want := range tests {
+		if got := status.String(); got != want {
+			t.Errorf("String() = %s, 期待: %s", got, want)
+		}
+	}
+}
+
+// TestCheckIPSources は、ソースごとの結果と、すべて失敗した場合の結果をテストします。
+func TestCheckIPSources(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("203.0.113.5\n"))
+	}))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	results := CheckIPSources(context.Background(), []string{good.URL, bad.URL}, time.Second)
+	if len(results) != 2 {
+		t.Fatalf("結果は2件であるべき。実際: %d", len(results))
+	}
+	if results[0].Status != StatusPass || results[0].Detail != "203.0.113.5" {
+		t.Errorf("取得できたソースは PASS であるべき: %+v", results[0])
+	}
+	if results[1].Status != StatusWarn {
+		t.Errorf("失敗したソースは WARN であるべき: %+v", results[1])
+	}
+	if Failed(results) {
+		t.Error("1つでも取得できれば失敗ではないはず")
+	}
+
+	results = CheckIPSources(context.Background(), []string{bad.URL}, time.Second)
+	if !Failed(results) {
+		t.Error("すべてのソースが失敗した場合は FAIL を含むべき")
+	}
+}
+
+// TestCheckDNS は、名前解決の成功と失敗をテストします。
+func TestCheckDNS(t *testing.T) {
+	resolver := fakeResolver{DuckDNSHost: {"198.51.100.1", "198.51.100.2"}}
+
+	if r := CheckDNS(context.Background(), resolver, DuckDNSHost); r.Status != StatusPass || !strings.Contains(r.Detail, "198.51.100.2") {
+		t.Errorf("名前解決できる場合は PASS であるべき: %+v", r)
+	}
+	if r := CheckDNS(context.Background(), resolver, "unknown.example"); r.Status != StatusFail {
+		t.Errorf("名前解決できない場合は FAIL であるべき: %+v", r)
+	}
+}
+
+// TestCheckClockSkew は、Date ヘッダーとの時刻のずれの判定をテストします。
+func TestCheckClockSkew(t *testing.T) {
+	offset := time.Duration(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	if r := CheckClockSkew(context.Background(), server.Client(), server.URL, DefaultMaxClockSkew); r.Status != StatusPass {
+		t.Errorf("ずれがない場合は PASS であるべき: %+v", r)
+	}
+
+	offset = 5 * time.Minute
+	if r := CheckClockSkew(context.Background(), server.Client(), server.URL, DefaultMaxClockSkew); r.Status != StatusWarn {
+		t.Errorf("大きくずれている場合は WARN であるべき: %+v", r)
+	}
+}
+
+// TestCheckToken は、登録済みのIPアドレスでの更新結果によるトークンの判定をテストします。
+func TestCheckToken(t *testing.T) {
+	var gotIP string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIP = r.URL.Query().Get("ip")
+		if r.URL.Query().Get("token") != "valid-token" {
+			_, _ = w.Write([]byte("KO"))
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	resolver := fakeResolver{"example.duckdns.org": {"2001:db8::1", "203.0.113.5"}}
+
+	r := CheckToken(context.Background(), client, resolver, "example", "valid-token")
+	if r.Status != StatusPass {
+		t.Errorf("有効なトークンは PASS であるべき: %+v", r)
+	}
+	if gotIP != "203.0.113.5" {
+		t.Errorf("登録済みの IPv4 アドレスで更新するべき。実際: %s", gotIP)
+	}
+
+	if r := CheckToken(context.Background(), client, resolver, "example", "wrong-token"); r.Status != StatusFail {
+		t.Errorf("拒否された場合は FAIL であるべき: %+v", r)
+	}
+
+	if r := CheckToken(context.Background(), client, resolver, "unregistered", "valid-token"); r.Status != StatusWarn {
+		t.Errorf("登録済みIPがない場合は WARN であるべき: %+v", r)
+	}
+
+	server.Close()
+	if r := CheckToken(context.Background(), client, resolver, "example", "valid-token"); r.Status != StatusWarn {
+		t.Errorf("DuckDNS に接続できない場合は WARN であるべき: %+v", r)
+	}
+}
+
+// TestHostname は、ドメイン名から完全なホスト名への変換をテストします。
+func TestHostname(t *testing.T) {
+	tests := map[string]string{
+		"example":               "example.duckdns.org",
+		"Example.duckdns.org":   "example.duckdns.org",
+		" example.duckdns.org.": "example.duckdns.org",
+	}
+	for input, want := range tests {
+		if got := Hostname(input); got != want {
+			t.Errorf("Hostname(%q) = %s, 期待: %s", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package duckdns
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultBackoffMultiplier は、BackoffStrategy の倍率の既定値です。
+const DefaultBackoffMultiplier = 2.0
+
+// BackoffStrategy は、リトライの待ち時間を指数関数的に増やす戦略です。
+// n 回目の失敗の後は InitialInterval × Multiplier^(n-1) だけ待ち、MaxInterval を上限とします。
+// Jitter を指定すると、複数のクライアントが同時に再試行しないように待ち時間をランダムにずらします。
+type BackoffStrategy struct {
+	// InitialInterval は、最初の失敗の後の待ち時間です（省略時は 1 秒）
+	InitialInterval time.Duration
+
+	// Multiplier は、失敗するたびに待ち時間を増やす倍率です（1 未満の場合は 2）
+	Multiplier float64
+
+	// MaxInterval は、待ち時間の上限です（0 の場合は上限なし）
+	MaxInterval time.Duration
+
+	// Jitter は、待ち時間をずらす割合です（0〜1。0.2 の場合は ±20%）
+	Jitter float64
+
+	// MaxElapsedTime は、最初の試行からリトライをあきらめるまでの時間です（0 の場合は MaxRetries だけで判断）
+	MaxElapsedTime time.Duration
+}
+
+// Delay は、attempt 回目の失敗の後に待つ時間を返します。
+// Strategy が設定されている場合はそれに従い、ない場合は Backoff のリストの値（範囲外の場合は最後の値）を返します。
+//
+// Parameters:
+//   - attempt: 失敗した試行の回数（1 から）
+//
+// Returns:
+//   - time.Duration: 次の試行までの待ち時間
+func (r RetryConfig) Delay(attempt int) time.Duration {
+	if r.Strategy != nil {
+		return r.Strategy.delay(attempt)
+	}
+
+	backoff := r.Backoff
+	if len(backoff) == 0 {
+		backoff = DefaultBackoff
+	}
+	index := attempt - 1
+	if index >= len(backoff) {
+		index = len(backoff) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	return backoff[index]
+}
+
+// MaxElapsedTime は、最初の試行からリトライをあきらめるまでの時間を返します（0 の場合は制限なし）。
+func (r RetryConfig) MaxElapsedTime() time.Duration {
+	if r.Strategy == nil {
+		return 0
+	}
+	return r.Strategy.MaxElapsedTime
+}
+
+// delay は、attempt 回目の失敗の後の待ち時間を計算します。
+func (s *BackoffStrategy) delay(attempt int) time.Duration {
+	initial := s.InitialInterval
+	if initial <= 0 {
+		initial = DefaultBackoff[0]
+	}
+	multiplier := s.Multiplier
+	if multiplier < 1 {
+		multiplier = DefaultBackoffMultiplier
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if s.MaxInterval > 0 && d > float64(s.MaxInterval) {
+		d = float64(s.MaxInterval)
+	}
+	if d > math.MaxInt64 {
+		d = math.MaxInt64
+	}
+
+	if jitter := math.Min(s.Jitter, 1); jitter > 0 {
+		// [1-jitter, 1+jitter) の範囲でずらす
+		d *= 1 + jitter*(2*rand.Float64()-1)
+		if d > math.MaxInt64 {
+			d = math.MaxInt64
+		}
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,87 @@
+package duckdns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRetryConfig_Delay_Backoff は、Strategy がない場合に Backoff のリストの値を使うことをテストします。
+func TestRetryConfig_Delay_Backoff(t *testing.T) {
+	r := RetryConfig{Backoff: []time.Duration{time.Second, 3 * time.Second}}
+	want := []time.Duration{time.Second, 3 * time.Second, 3 * time.Second}
+	for i, w := range want {
+		if got := r.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, 期待: %v", i+1, got, w)
+		}
+	}
+}
+
+// TestRetryConfig_Delay_Strategy は、待ち時間が倍率で増え、MaxInterval で頭打ちになることをテストします。
+func TestRetryConfig_Delay_Strategy(t *testing.T) {
+	r := RetryConfig{Strategy: &BackoffStrategy{
+		InitialInterval: 500 * time.Millisecond,
+		Multiplier:      3,
+		MaxInterval:     5 * time.Second,
+	}}
+	want := []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond, 4500 * time.Millisecond, 5 * time.Second}
+	for i, w := range want {
+		if got := r.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, 期待: %v", i+1, got, w)
+		}
+	}
+
+	// 省略した項目は既定値（1s から2倍ずつ）
+	r = RetryConfig{Strategy: &BackoffStrategy{}}
+	if got := r.Delay(3); got != 4*time.Second {
+		t.Errorf("既定値の Delay(3) = %v, 期待: 4s", got)
+	}
+}
+
+// TestRetryConfig_Delay_Jitter は、Jitter で待ち時間が指定した割合の範囲でずれることをテストします。
+func TestRetryConfig_Delay_Jitter(t *testing.T) {
+	r := RetryConfig{Strategy: &BackoffStrategy{InitialInterval: 10 * time.Second, Jitter: 0.2}}
+	varied := false
+	for i := 0; i < 100; i++ {
+		got := r.Delay(1)
+		if got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("Delay(1) = %v は 8s〜12s の範囲であるべき", got)
+		}
+		if got != 10*time.Second {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Error("Jitter を指定した場合は待ち時間がずれるべき")
+	}
+}
+
+// TestClient_UpdateWithRetry_MaxElapsedTime は、MaxElapsedTime を過ぎる場合はリトライをあきらめることをテストします。
+func TestClient_UpdateWithRetry_MaxElapsedTime(t *testing.T) {
+	attemptCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attemptCount++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{
+		MaxRetries: 10,
+		Strategy: &BackoffStrategy{
+			InitialInterval: 20 * time.Millisecond,
+			Multiplier:      2,
+			MaxElapsedTime:  100 * time.Millisecond,
+		},
+	})
+
+	_, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if err == nil {
+		t.Fatal("エラーが返されるべき")
+	}
+	// 待ち時間は 20ms, 40ms と増え、次の 80ms を待つと 100ms を過ぎるため3回目であきらめる
+	if attemptCount != 3 {
+		t.Errorf("試行回数 = %d, 期待: 3", attemptCount)
+	}
+}
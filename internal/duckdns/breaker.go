@@ -0,0 +1,167 @@
+package duckdns
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerThreshold は、サーキットを開くまでの連続失敗回数の既定値です。
+const DefaultBreakerThreshold = 5
+
+// DefaultBreakerCooldown は、サーキットを開いてから再び DuckDNS に問い合わせるまでの時間の既定値です。
+const DefaultBreakerCooldown = 5 * time.Minute
+
+// ErrCircuitOpen は、DuckDNS の障害が続いているためサーキットが開いており、リクエストを送信しなかったことを表すエラーです。
+// 再試行しても待ち時間が過ぎるまでは成功しないため、IsTemporary は false を返します。
+var ErrCircuitOpen = errors.New("DuckDNS の障害が続いているため、リクエストを送信しませんでした")
+
+// BreakerState は、サーキットブレーカーの状態です。
+type BreakerState int
+
+const (
+	// BreakerClosed は、通常どおりリクエストを送信する状態です
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen は、障害が続いているためリクエストを送信しない状態です
+	BreakerOpen
+
+	// BreakerHalfOpen は、待ち時間が過ぎて、1件だけ試しにリクエストを送信している状態です
+	BreakerHalfOpen
+)
+
+// String は、状態の名前を返します（ログやメトリクスのラベルに使用します）。
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker は、DuckDNS への一時的な失敗が続いた場合に、しばらくリクエストを止めるサーキットブレーカーです。
+// 連続で threshold 回失敗するとサーキットを開き、cooldown の間はリクエストを送信せずに ErrCircuitOpen を返します。
+// cooldown が過ぎると1件だけ試しに送信し、成功すれば閉じ、失敗すれば再び cooldown の間開きます。
+// 数えるのは通信の失敗や 5xx などの一時的な失敗（IsTemporary）だけで、"KO" による拒否は DuckDNS の障害ではないため数えません。
+// 複数の goroutine から同時に使用できます。
+type CircuitBreaker struct {
+	mu sync.Mutex
+
+	// threshold は、サーキットを開くまでの連続失敗回数です
+	threshold int
+
+	// cooldown は、サーキットを開いてから試しに送信するまでの時間です
+	cooldown time.Duration
+
+	// state は、現在の状態です
+	state BreakerState
+
+	// failures は、連続した一時的な失敗の回数です
+	failures int
+
+	// openedAt は、サーキットを開いた時刻です
+	openedAt time.Time
+
+	// now は、現在時刻を返す関数です（テストで差し替えます）
+	now func() time.Time
+}
+
+// NewCircuitBreaker は、サーキットブレーカーを作成します。
+// 引数がゼロ以下の場合は既定値（連続5回の失敗で5分間）を使用します。
+//
+// Parameters:
+//   - threshold: サーキットを開くまでの連続失敗回数
+//   - cooldown: サーキットを開いてから試しに送信するまでの時間
+//
+// Returns:
+//   - *CircuitBreaker: 作成されたサーキットブレーカー
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// State は、現在の状態を返します。
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow は、リクエストを送信してよいかを確認します。
+// サーキットが開いている間は、残りの待ち時間を含む ErrCircuitOpen を返します。
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		remaining := b.cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return fmt.Errorf("%w (あと %s)", ErrCircuitOpen, remaining.Round(time.Second))
+		}
+		// 待ち時間が過ぎたので、1件だけ試しに送信する
+		b.state = BreakerHalfOpen
+		slog.Info("DuckDNS への送信を再開し、復旧したか確認します",
+			"breaker_state", b.state.String(),
+		)
+		return nil
+	case BreakerHalfOpen:
+		// 試しの送信の結果が出るまでは、ほかのリクエストを送信しない
+		return fmt.Errorf("%w (復旧の確認中)", ErrCircuitOpen)
+	default:
+		return nil
+	}
+}
+
+// record は、送信したリクエストの結果を記録し、状態を更新します。
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// キャンセルは DuckDNS の状態と関係ないため数えない（試しの送信だった場合は、次のリクエストで試し直す）
+	if errors.Is(err, ErrCancelled) {
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+			b.openedAt = b.now().Add(-b.cooldown)
+		}
+		return
+	}
+
+	if !IsTemporary(err) {
+		// 成功と、DuckDNS の障害ではない失敗（"KO" など）は、DuckDNS に届いたことを表す
+		if b.state != BreakerClosed {
+			slog.Info("DuckDNS が復旧したため、サーキットを閉じました",
+				"breaker_state", BreakerClosed.String(),
+			)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		slog.Warn("DuckDNS への失敗が続いているため、しばらく送信を止めます",
+			"breaker_state", b.state.String(),
+			"consecutive_failures", b.failures,
+			"cooldown", b.cooldown.String(),
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,102 @@
+package duckdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreaker は、連続した一時的な失敗でサーキットが開き、待ち時間の後に1件だけ試して閉じることをテストします。
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	outage := &StatusError{StatusCode: http.StatusBadGateway}
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("%d回目: サーキットはまだ閉じているべき: %v", i+1, err)
+		}
+		b.record(outage)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("3回連続の失敗でサーキットが開くべき: %s", b.State())
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("待ち時間の間は ErrCircuitOpen が返されるべき: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("待ち時間の後は1件だけ送信できるべき: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("復旧の確認中はほかのリクエストを送信しないべき: %v", err)
+	}
+
+	b.record(nil)
+	if b.State() != BreakerClosed {
+		t.Errorf("試しの送信が成功したらサーキットが閉じるべき: %s", b.State())
+	}
+}
+
+// TestCircuitBreaker_HalfOpenFailure は、試しの送信が失敗したら再びサーキットが開くことをテストします。
+func TestCircuitBreaker_HalfOpenFailure(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.record(ErrNetwork)
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("待ち時間の後は1件だけ送信できるべき: %v", err)
+	}
+	b.record(ErrNetwork)
+	if b.State() != BreakerOpen {
+		t.Errorf("試しの送信が失敗したら再びサーキットが開くべき: %s", b.State())
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("再び待ち時間の間は ErrCircuitOpen が返されるべき: %v", err)
+	}
+}
+
+// TestCircuitBreaker_IgnoresRejectedAndCancelled は、"KO" とキャンセルを DuckDNS の障害として数えないことをテストします。
+func TestCircuitBreaker_IgnoresRejectedAndCancelled(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Minute)
+
+	b.record(ErrNetwork)
+	b.record(fmt.Errorf("%w: レスポンス=KO", ErrRejected))
+	b.record(ErrNetwork)
+	b.record(fmt.Errorf("%w: %w", ErrCancelled, context.Canceled))
+	if b.State() != BreakerClosed {
+		t.Errorf("\"KO\" で連続失敗の回数がリセットされ、キャンセルは数えないべき: %s", b.State())
+	}
+}
+
+// TestClient_CircuitBreaker は、サーキットが開いている間はリクエストを送信せず、UpdateWithRetry もリトライしないことをテストします。
+func TestClient_CircuitBreaker(t *testing.T) {
+	requestCount := 0
+	client := NewClient(
+		WithHTTPClient(&MockHTTPDoer{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				requestCount++
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))}, nil
+			},
+		}),
+		WithRetry(RetryConfig{MaxRetries: 3, Backoff: []time.Duration{time.Millisecond}}),
+		WithCircuitBreaker(NewCircuitBreaker(2, time.Hour)),
+	)
+
+	_, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("サーキットが開いたら ErrCircuitOpen が返されるべき: %v", err)
+	}
+	if requestCount != 2 {
+		t.Errorf("サーキットが開いた後は送信しないべき。送信回数: %d", requestCount)
+	}
+}
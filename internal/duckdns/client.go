@@ -4,24 +4,40 @@ package duckdns
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+	"github.com/horitaku/duckdns/internal/ratelimit"
 )
 
 // defaultBaseURL は DuckDNS の更新APIエンドポイントです。
 const defaultBaseURL = "https://www.duckdns.org/update"
 
+// DefaultUserAgent は、リクエストの User-Agent ヘッダーのデフォルト値です。
+const DefaultUserAgent = "duckdns-updater/1.0"
+
 // DefaultHTTPTimeout は HTTPクライアントのデフォルトタイムアウトです。
 const DefaultHTTPTimeout = 10 * time.Second
 
 // DefaultMaxRetries はリトライのデフォルト最大回数です。
 const DefaultMaxRetries = 3
 
+// MaxRetryAfter は、UpdateWithRetry が Retry-After に従って待つ最大の時間です。
+// これより長い待ち時間を指定された場合は、更新のサイクルが止まらないように、待たずに失敗を返します。
+const MaxRetryAfter = 5 * time.Minute
+
+// DefaultRateLimitRequests は、DuckDNS API へのリクエスト数の上限の既定値です（DefaultRateLimitPer あたり）。
+const DefaultRateLimitRequests = 30
+
+// DefaultRateLimitPer は、リクエスト数の上限を数える期間の既定値です。
+const DefaultRateLimitPer = time.Minute
+
 // DefaultBackoff はリトライ時のデフォルトのバックオフ時間です。
 var DefaultBackoff = []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
 
@@ -33,9 +49,13 @@ type HTTPDoer interface {
 
 // RetryConfig はリトライの設定を表します。
 // 最大リトライ回数とバックオフ時間のリストを持ちます。
+// Strategy を指定した場合は、Backoff のリストの代わりに Strategy で待ち時間を計算します。
 type RetryConfig struct {
 	MaxRetries int
 	Backoff    []time.Duration
+
+	// Strategy は、待ち時間を指数関数的に増やす戦略です（nil の場合は Backoff を使用）
+	Strategy *BackoffStrategy
 }
 
 // Client は DuckDNS API への更新リクエストを実行するためのクライアントです。
@@ -44,72 +64,260 @@ type Client struct {
 	httpClient HTTPDoer
 	baseURL    string
 	retry      RetryConfig
+
+	// userAgent は、リクエストの User-Agent ヘッダーの値です
+	userAgent string
+
+	// limiter は、すべてのドメインで共有するリクエスト頻度の制限です（nil の場合は制限しない）
+	limiter *ratelimit.Limiter
+
+	// breaker は、DuckDNS の障害が続いた場合にリクエストを止めるサーキットブレーカーです（nil の場合は使用しない）
+	breaker *CircuitBreaker
+
+	// maxResponseSize は、読み込むレスポンスボディの最大サイズです（0 以下の場合は httpclient.DefaultMaxResponseSize）
+	maxResponseSize int64
 }
 
-// NewClient は既定値で初期化された DuckDNS クライアントを作成します。
+// NewClient は DuckDNS クライアントを作成します。
+// オプションを指定しない場合は、次の既定値で初期化されます。
 // - HTTPタイムアウト: 10秒
 // - ベースURL: https://www.duckdns.org/update
 // - リトライ: 最大3回、1s/2s/4s のバックオフ
-func NewClient() *Client {
-	return &Client{
-		httpClient: &http.Client{Timeout: DefaultHTTPTimeout},
-		baseURL:    defaultBaseURL,
-		retry: RetryConfig{
-			MaxRetries: DefaultMaxRetries,
-			Backoff:    append([]time.Duration(nil), DefaultBackoff...),
-		},
+// - User-Agent: duckdns-updater/1.0
+// - レスポンスボディの最大サイズ: 64 KiB
+//
+// Parameters:
+//   - opts: 既定値を変更するオプション（WithHTTPClient、WithBaseURL、WithRetry など）
+//
+// Returns:
+//   - *Client: 作成されたクライアント
+func NewClient(opts ...Option) *Client {
+	o := options{
+		baseURL:   defaultBaseURL,
+		timeout:   DefaultHTTPTimeout,
+		userAgent: DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(&o)
 	}
-}
 
-// NewClientWithOptions は指定の HTTP クライアント、ベースURL、リトライ設定で
-// DuckDNS クライアントを作成します。引数がゼロ値の場合は適切に既定値を適用します。
-func NewClientWithOptions(httpClient HTTPDoer, baseURL string, retry RetryConfig) *Client {
+	httpClient := o.httpClient
 	if httpClient == nil {
-		httpClient = &http.Client{Timeout: DefaultHTTPTimeout}
-	}
-	if baseURL == "" {
-		baseURL = defaultBaseURL
+		httpClient = &http.Client{Timeout: o.timeout, Transport: o.transport}
 	}
+
 	// リトライ設定の既定値補完
+	retry := o.retry
 	if retry.MaxRetries <= 0 {
 		retry.MaxRetries = DefaultMaxRetries
 	}
-	if len(retry.Backoff) == 0 {
+	if len(retry.Backoff) == 0 && retry.Strategy == nil {
 		retry.Backoff = append([]time.Duration(nil), DefaultBackoff...)
 	}
 
 	return &Client{
-		httpClient: httpClient,
-		baseURL:    baseURL,
+		httpClient: chain(httpClient, o.middlewares),
+		baseURL:    o.baseURL,
 		retry:      retry,
+		userAgent:  o.userAgent,
+		limiter:    o.limiter,
+		breaker:    o.breaker,
+
+		maxResponseSize: o.maxResponseSize,
+	}
+}
+
+// NewClientWithOptions は指定の HTTP クライアント、ベースURL、リトライ設定で
+// DuckDNS クライアントを作成します。引数がゼロ値の場合は適切に既定値を適用します。
+// NewClient(WithHTTPClient(httpClient), WithBaseURL(baseURL), WithRetry(retry)) と同じです。
+func NewClientWithOptions(httpClient HTTPDoer, baseURL string, retry RetryConfig) *Client {
+	return NewClient(WithHTTPClient(httpClient), WithBaseURL(baseURL), WithRetry(retry))
+}
+
+// UpdateRequest は、DuckDNS API への1回の更新リクエストのパラメーターです。
+// DuckDNS のパラメーターが増えても呼び出し側を変えずに済むように、フィールドで指定します（ゼロ値のフィールドは送信しません）。
+type UpdateRequest struct {
+	// Domains は、更新するドメイン名の一覧です（同じトークンのもの。複数の場合はカンマ区切りの1回のリクエストにまとめます）
+	Domains []string
+
+	// Token は、DuckDNS API の認証トークンです
+	Token string
+
+	// IPv4 は、A レコードに登録する IPv4アドレスです（空の場合は、DuckDNS がリクエストの送信元のアドレスを登録します）
+	IPv4 string
+
+	// IPv6 は、AAAA レコードに登録する IPv6アドレスです（空の場合は AAAA レコードを変えません）
+	IPv6 string
+
+	// TXT は、TXT レコードの値です（空でない場合は、A と AAAA のレコードを変えずに TXT レコードだけを書き換えます）
+	TXT string
+
+	// HasTXT は、TXT が空でも TXT レコードのリクエストにする場合に true にします（Clear と組み合わせて TXT レコードを消去します）
+	HasTXT bool
+
+	// Clear は、レコードを消去する場合に true にします（TXT レコードのリクエストの場合は TXT レコード、それ以外は A と AAAA）
+	Clear bool
+
+	// Verbose は、DuckDNS に詳しい応答（"OK"、登録した IPv4・IPv6 アドレス、"UPDATED" または "NOCHANGE" の行）を求める場合に true にします
+	Verbose bool
+}
+
+// isTXT は、TXT レコードのリクエストかどうかを返します。
+func (r UpdateRequest) isTXT() bool {
+	return r.HasTXT || r.TXT != ""
+}
+
+// validate は、パラメーターの組み合わせが DuckDNS API で送れるかどうかを確認します。
+func (r UpdateRequest) validate() error {
+	switch {
+	case len(r.Domains) == 0:
+		return errors.New("更新するドメインを指定してください")
+	case r.isTXT() && (r.IPv4 != "" || r.IPv6 != ""):
+		return errors.New("TXT レコードと IP アドレスは同じリクエストで更新できません")
+	case r.Clear && (r.IPv4 != "" || r.IPv6 != ""):
+		return errors.New("レコードの消去と IP アドレスの更新は同じリクエストでできません")
+	}
+	return nil
+}
+
+// params は、DuckDNS API のクエリーパラメーターを返します。
+func (r UpdateRequest) params() url.Values {
+	params := url.Values{}
+	params.Set("domains", strings.Join(r.Domains, ","))
+	params.Set("token", r.Token)
+	switch {
+	case r.isTXT():
+		// txt パラメーターを送ると、DuckDNS は A と AAAA のレコードを変えずに TXT レコードだけを書き換えます
+		params.Set("txt", r.TXT)
+	case !r.Clear:
+		params.Set("ip", r.IPv4)
+	}
+	if r.IPv6 != "" {
+		params.Set("ipv6", r.IPv6)
+	}
+	if r.Clear {
+		params.Set("clear", "true")
+	}
+	if r.Verbose {
+		params.Set("verbose", "true")
 	}
+	return params
 }
 
 // Update は DuckDNS API を呼び出してDNSレコードを更新します。
-// domain, token, ip を指定してGETリクエストを送信し、レスポンスボディを返します。
+// req の Domains を1回の GET リクエストで更新し、レスポンスボディを返します。
+//
+// Example:
+//
+//	client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: token, IPv4: "192.0.2.1"})
 //
 // Parameters:
 //   - ctx: キャンセルやタイムアウトを制御するコンテキスト
-//   - domain: 更新するDuckDNSドメイン名（例: "your-domain"）
+//   - req: 更新するドメインとトークン、登録する値
+//
+// Returns:
+//   - string: レスポンスボディ（"OK" または "KO"。Verbose の場合は複数行）
+//   - error: エラーが発生した場合（パラメーターの組み合わせが誤っている場合はリクエストを送信しません）
+func (c *Client) Update(ctx context.Context, req UpdateRequest) (string, error) {
+	if err := req.validate(); err != nil {
+		return "", err
+	}
+	return c.call(ctx, strings.Join(req.Domains, ","), req.params())
+}
+
+// Clear は、DuckDNS API の clear=true で、ドメインの A と AAAA のレコードを消去します。
+// 停止するときに、これから使わなくなる IPアドレスを指したままにしないために使います。
+// Update(ctx, UpdateRequest{Domains: domains, Token: token, Clear: true}) と同じです。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - domains: 消去するDuckDNSドメイン名の一覧（同じトークンのもの）
 //   - token: DuckDNS APIの認証トークン
-//   - ip: 更新するIPアドレス（IPv4形式）
 //
 // Returns:
 //   - string: レスポンスボディ（"OK" または "KO"）
 //   - error: エラーが発生した場合
-func (c *Client) Update(ctx context.Context, domain, token, ip string) (string, error) {
-	// クエリパラメータの構築
-	params := url.Values{}
-	params.Set("domains", domain)
-	params.Set("token", token)
-	params.Set("ip", ip)
+func (c *Client) Clear(ctx context.Context, domains []string, token string) (string, error) {
+	return c.Update(ctx, UpdateRequest{Domains: domains, Token: token, Clear: true})
+}
+
+// SetTXT は、ドメインの TXT レコードを書き換えます（A と AAAA のレコードは変わりません）。
+// ACME の DNS-01 チャレンジなどで、ドメインの所有を確認するために使います。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - domains: 書き換えるDuckDNSドメイン名の一覧（同じトークンのもの）
+//   - token: DuckDNS APIの認証トークン
+//   - txt: TXT レコードの値
+//
+// Returns:
+//   - string: レスポンスボディ（"OK" または "KO"）
+//   - error: エラーが発生した場合
+func (c *Client) SetTXT(ctx context.Context, domains []string, token, txt string) (string, error) {
+	return c.Update(ctx, UpdateRequest{Domains: domains, Token: token, TXT: txt, HasTXT: true})
+}
+
+// ClearTXT は、ドメインの TXT レコードを消去します（A と AAAA のレコードは変わりません）。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - domains: 消去するDuckDNSドメイン名の一覧（同じトークンのもの）
+//   - token: DuckDNS APIの認証トークン
+//
+// Returns:
+//   - string: レスポンスボディ（"OK" または "KO"）
+//   - error: エラーが発生した場合
+func (c *Client) ClearTXT(ctx context.Context, domains []string, token string) (string, error) {
+	return c.Update(ctx, UpdateRequest{Domains: domains, Token: token, HasTXT: true, Clear: true})
+}
+
+// call は、DuckDNS API にリクエストを送信します。
+// サーキットブレーカーが設定されている場合は、送信してよいかを確認し、結果を記録します。
+func (c *Client) call(ctx context.Context, domain string, params url.Values) (string, error) {
+	if c.breaker == nil {
+		return c.send(ctx, domain, params)
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		slog.Warn("DuckDNS の障害が続いているため、更新リクエストを送信しません",
+			"domain", domain,
+			"breaker_state", c.breaker.State().String(),
+			"error", err,
+		)
+		return "", err
+	}
+	response, err := c.send(ctx, domain, params)
+	c.breaker.record(err)
+	return response, err
+}
+
+// send は、DuckDNS API に更新リクエストを1回送信します。
+func (c *Client) send(ctx context.Context, domain string, params url.Values) (string, error) {
+	ip, ipv6, clear := params.Get("ip"), params.Get("ipv6"), params.Get("clear") == "true"
 
 	// URL構築
 	reqURL := c.baseURL + "?" + params.Encode()
 
+	// リクエスト頻度の制限を超える場合は、トークンが補充されるまで待つ
+	if c.limiter != nil {
+		waited, err := c.limiter.Wait(ctx)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrCancelled, err)
+		}
+		if waited > 0 {
+			slog.Warn("DuckDNS へのリクエストが多すぎるため、待機しました",
+				"domain", domain,
+				"waited", waited.String(),
+			)
+		}
+	}
+
 	slog.Info("DuckDNS更新リクエスト送信",
 		"domain", domain,
 		"ip", ip,
+		"ipv6", ipv6,
+		"txt", params.Has("txt"),
+		"clear", clear,
 		"url", c.baseURL,
 	)
 
@@ -120,7 +328,7 @@ func (c *Client) Update(ctx context.Context, domain, token, ip string) (string,
 	}
 
 	// User-Agent設定
-	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+	req.Header.Set("User-Agent", c.userAgent)
 
 	// HTTPリクエスト送信
 	resp, err := c.httpClient.Do(req)
@@ -129,33 +337,47 @@ func (c *Client) Update(ctx context.Context, domain, token, ip string) (string,
 			"domain", domain,
 			"error", err,
 		)
-		return "", fmt.Errorf("HTTPリクエスト実行に失敗しました: %w", err)
+		return "", requestError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	// ステータスコード確認
 	if resp.StatusCode != http.StatusOK {
-		slog.Error("DuckDNS APIステータスエラー",
-			"domain", domain,
-			"status_code", resp.StatusCode,
-		)
-		return "", fmt.Errorf("HTTPステータスエラー: %d", resp.StatusCode)
+		statusErr := &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: ratelimit.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			slog.Warn("DuckDNS のリクエスト数の制限を超えました",
+				"domain", domain,
+				"status_code", resp.StatusCode,
+				"retry_after", statusErr.RetryAfter.String(),
+			)
+		} else {
+			slog.Error("DuckDNS APIステータスエラー",
+				"domain", domain,
+				"status_code", resp.StatusCode,
+			)
+		}
+		return "", statusErr
 	}
 
 	// レスポンスボディ読み込み
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpclient.ReadBody(resp.Body, c.maxResponseSize)
 	if err != nil {
-		return "", fmt.Errorf("レスポンス読み込みに失敗しました: %w", err)
+		return "", fmt.Errorf("%w: レスポンス読み込みに失敗しました: %w", ErrNetwork, err)
 	}
 
 	// レスポンス文字列の取得（空白・改行を削除）
 	response := strings.TrimSpace(string(body))
 
-	// レスポンス解析："OK" / "KO" の判定
-	if response == "OK" {
+	// レスポンス解析："OK" / "KO" の判定（verbose=true の場合は1行目）
+	status, _, _ := strings.Cut(response, "\n")
+	if strings.TrimSpace(status) == "OK" {
 		slog.Info("DuckDNS更新成功",
 			"domain", domain,
 			"ip", ip,
+			"ipv6", ipv6,
 			"response", response,
 		)
 		return response, nil
@@ -167,25 +389,32 @@ func (c *Client) Update(ctx context.Context, domain, token, ip string) (string,
 		"ip", ip,
 		"response", response,
 	)
-	return response, fmt.Errorf("DuckDNS更新に失敗しました: レスポンス=%s", response)
+	return response, fmt.Errorf("%w: レスポンス=%s", ErrRejected, response)
 }
 
 // UpdateWithRetry は指数バックオフアルゴリズムでリトライしながら
 // DuckDNS API を呼び出してDNSレコードを更新します。
-// 最大リトライ回数と各リトライ間のバックオフ時間は Client の retry 設定に従います。
+// 最大リトライ回数と各リトライ間のバックオフ時間（最大経過時間）は Client の retry 設定に従います。
+// リトライするのは通信の失敗と 5xx などの一時的な失敗（IsTemporary）だけで、
+// "KO" による拒否や 5xx 以外のステータスはリトライせずにすぐ返します。
+// 429 や 503 で Retry-After が指定された場合は、バックオフ時間の代わりにその時間だけ待ってから再試行します。
 //
 // Parameters:
 //   - ctx: キャンセルやタイムアウトを制御するコンテキスト
-//   - domain: 更新するDuckDNSドメイン名（例: "your-domain"）
-//   - token: DuckDNS APIの認証トークン
-//   - ip: 更新するIPアドレス（IPv4形式）
+//   - req: 更新するドメインとトークン、登録する値
 //
 // Returns:
 //   - string: レスポンスボディ（"OK" または "KO"）
 //   - error: すべてのリトライが失敗した場合
-func (c *Client) UpdateWithRetry(ctx context.Context, domain, token, ip string) (string, error) {
+func (c *Client) UpdateWithRetry(ctx context.Context, req UpdateRequest) (string, error) {
+	if err := req.validate(); err != nil {
+		return "", err
+	}
+	domain, ip := strings.Join(req.Domains, ","), req.IPv4
+
 	var lastErr error
 	maxAttempts := c.retry.MaxRetries + 1 // 最初の試行 + リトライ回数
+	start := time.Now()
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		// コンテキストがキャンセルされているか確認
@@ -196,7 +425,7 @@ func (c *Client) UpdateWithRetry(ctx context.Context, domain, token, ip string)
 				"attempt", attempt,
 				"error", ctx.Err(),
 			)
-			return "", fmt.Errorf("更新がキャンセルされました: %w", ctx.Err())
+			return "", fmt.Errorf("%w: %w", ErrCancelled, ctx.Err())
 		default:
 		}
 
@@ -217,7 +446,7 @@ func (c *Client) UpdateWithRetry(ctx context.Context, domain, token, ip string)
 		}
 
 		// 更新を試行
-		response, err := c.Update(ctx, domain, token, ip)
+		response, err := c.Update(ctx, req)
 		if err == nil {
 			// 成功
 			if attempt > 1 {
@@ -230,17 +459,46 @@ func (c *Client) UpdateWithRetry(ctx context.Context, domain, token, ip string)
 			return response, nil
 		}
 
+		// "KO" などの再試行しても成功しない失敗は、リトライせずにすぐ返す
+		if !IsTemporary(err) {
+			slog.Error("DuckDNS更新が一時的ではない理由で失敗したため、リトライしません",
+				"domain", domain,
+				"attempt", attempt,
+				"error", err,
+			)
+			return "", err
+		}
+
 		// エラーを記録
 		lastErr = err
 
 		// 最後の試行でない場合はバックオフ
 		if attempt < maxAttempts {
-			// バックオフ時間を取得（範囲外の場合は最後の値を使用）
-			backoffIndex := attempt - 1
-			if backoffIndex >= len(c.retry.Backoff) {
-				backoffIndex = len(c.retry.Backoff) - 1
+			backoffDuration := c.retry.Delay(attempt)
+
+			// Retry-After で待ち時間を指定された場合は、それより早く再試行しない
+			if retryAfter := RetryAfter(err); retryAfter > backoffDuration {
+				if retryAfter > MaxRetryAfter {
+					slog.Error("Retry-After の待ち時間が長すぎるため、リトライしません",
+						"domain", domain,
+						"attempt", attempt,
+						"retry_after", retryAfter.String(),
+						"max_retry_after", MaxRetryAfter.String(),
+					)
+					return "", err
+				}
+				backoffDuration = retryAfter
+			}
+
+			// 次の試行が MaxElapsedTime を過ぎる場合は、リトライをあきらめる
+			if maxElapsed := c.retry.MaxElapsedTime(); maxElapsed > 0 && time.Since(start)+backoffDuration > maxElapsed {
+				slog.Warn("リトライの最大経過時間を過ぎるため、リトライをあきらめます",
+					"domain", domain,
+					"attempt", attempt,
+					"max_elapsed_time", maxElapsed.String(),
+				)
+				return "", fmt.Errorf("DuckDNS更新に失敗しました（%d回試行、最大経過時間 %s）: %w", attempt, maxElapsed, lastErr)
 			}
-			backoffDuration := c.retry.Backoff[backoffIndex]
 
 			slog.Warn("DuckDNS更新が失敗、バックオフ後にリトライ",
 				"domain", domain,
@@ -258,7 +516,7 @@ func (c *Client) UpdateWithRetry(ctx context.Context, domain, token, ip string)
 					"domain", domain,
 					"error", ctx.Err(),
 				)
-				return "", fmt.Errorf("バックオフ中にキャンセルされました: %w", ctx.Err())
+				return "", fmt.Errorf("%w (バックオフ中): %w", ErrCancelled, ctx.Err())
 			}
 		}
 	}
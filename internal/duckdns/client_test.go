@@ -2,12 +2,15 @@ package duckdns
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/horitaku/duckdns/internal/ratelimit"
 )
 
 // MockHTTPDoer はテスト用のモック HTTP クライアントです。
@@ -95,7 +98,7 @@ func TestClient_Update_Success(t *testing.T) {
 	defer server.Close()
 
 	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
-	response, err := client.Update(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	response, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
@@ -106,6 +109,149 @@ func TestClient_Update_Success(t *testing.T) {
 	}
 }
 
+// TestClient_Update_Domains は、複数のドメインをカンマ区切りで1回のリクエストにまとめることをテストします。
+func TestClient_Update_Domains(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.URL.Query().Get("domains"); got != "first,second" {
+			t.Errorf("domains パラメータが一致しません。期待: first,second, 実際: %s", got)
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	response, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"first", "second"}, Token: "test-token", IPv4: "192.168.1.1"})
+	if err != nil || response != "OK" {
+		t.Errorf("更新に失敗しました: %s %v", response, err)
+	}
+	if requests != 1 {
+		t.Errorf("リクエストは1回であるべき。実際: %d", requests)
+	}
+}
+
+// TestClient_Update_DualStack は、ip と ipv6 を1回のリクエストで送信することをテストします。
+func TestClient_Update_DualStack(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if query.Get("domains") != "first,second" || query.Get("ip") != "192.168.1.1" || query.Get("ipv6") != "2001:db8::1" {
+			t.Errorf("パラメータが一致しません: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	response, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"first", "second"}, Token: "test-token", IPv4: "192.168.1.1", IPv6: "2001:db8::1"})
+	if err != nil || response != "OK" {
+		t.Errorf("更新に失敗しました: %s %v", response, err)
+	}
+	if requests != 1 {
+		t.Errorf("リクエストは1回であるべき。実際: %d", requests)
+	}
+}
+
+// TestClient_Update_Verbose は、verbose=true を送信し、複数行の応答の1行目で成功を判定することをテストします。
+func TestClient_Update_Verbose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("verbose") != "true" {
+			t.Errorf("verbose パラメータを送信するべき: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("OK\n192.0.2.1\n\nUPDATED"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	response, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1", Verbose: true})
+	if err != nil {
+		t.Fatalf("1行目が OK の場合は成功するべき: %v", err)
+	}
+	if response != "OK\n192.0.2.1\n\nUPDATED" {
+		t.Errorf("詳しい応答をそのまま返すべき: %q", response)
+	}
+}
+
+// TestClient_Update_InvalidRequest は、DuckDNS API で送れないパラメーターの組み合わせは、送信せずにエラーを返すことをテストします。
+func TestClient_Update_InvalidRequest(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	for name, req := range map[string]UpdateRequest{
+		"ドメインなし":        {Token: "test-token", IPv4: "192.0.2.1"},
+		"TXT と IP アドレス": {Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1", TXT: "challenge"},
+		"消去と IP アドレス":   {Domains: []string{"home"}, Token: "test-token", IPv6: "2001:db8::1", Clear: true},
+	} {
+		if _, err := client.UpdateWithRetry(context.Background(), req); err == nil || IsTemporary(err) {
+			t.Errorf("%s: 再試行しないエラーになるべき: %v", name, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("リクエストを送信しないべき。実際: %d", requests)
+	}
+}
+
+// TestClient_Clear は、clear=true を送信し、ip を送信しないことをテストします。
+func TestClient_Clear(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("domains") != "first,second" || query.Get("clear") != "true" || query.Has("ip") || query.Has("ipv6") {
+			t.Errorf("パラメータが一致しません: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	response, err := client.Clear(context.Background(), []string{"first", "second"}, "test-token")
+	if err != nil || response != "OK" {
+		t.Errorf("消去に失敗しました: %s %v", response, err)
+	}
+}
+
+// TestClient_SetTXT は、txt を送信し、ip を送信しないことをテストします。
+func TestClient_SetTXT(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("domains") != "home" || query.Get("txt") != "challenge" || query.Has("ip") || query.Has("clear") {
+			t.Errorf("パラメータが一致しません: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	response, err := client.SetTXT(context.Background(), []string{"home"}, "test-token", "challenge")
+	if err != nil || response != "OK" {
+		t.Errorf("TXT レコードの書き換えに失敗しました: %s %v", response, err)
+	}
+}
+
+// TestClient_ClearTXT は、空の txt と clear=true を送信し、ip を送信しないことをテストします。
+func TestClient_ClearTXT(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has("txt") || query.Get("txt") != "" || query.Get("clear") != "true" || query.Has("ip") {
+			t.Errorf("パラメータが一致しません: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	response, err := client.ClearTXT(context.Background(), []string{"home"}, "test-token")
+	if err != nil || response != "OK" {
+		t.Errorf("TXT レコードの消去に失敗しました: %s %v", response, err)
+	}
+}
+
 // TestClient_Update_Failure は、更新失敗をテストします。
 func TestClient_Update_Failure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -117,11 +263,14 @@ func TestClient_Update_Failure(t *testing.T) {
 	defer server.Close()
 
 	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
-	_, err := client.Update(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err == nil {
 		t.Error("エラーが返されるべきですが、nilが返されました")
 	}
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("KO の場合は ErrRejected を返すべきです: %v", err)
+	}
 }
 
 // TestClient_Update_StatusError は、ステータスコードエラーをテストします。
@@ -132,11 +281,14 @@ func TestClient_Update_StatusError(t *testing.T) {
 	defer server.Close()
 
 	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
-	_, err := client.Update(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err == nil {
 		t.Error("エラーが返されるべきですが、nilが返されました")
 	}
+	if errors.Is(err, ErrRejected) {
+		t.Errorf("ステータスエラーは ErrRejected ではないべきです: %v", err)
+	}
 }
 
 // TestClient_Update_ContextCancelled は、キャンセルされたコンテキストをテストします。
@@ -154,13 +306,42 @@ func TestClient_Update_ContextCancelled(t *testing.T) {
 	cancel()
 
 	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
-	_, err := client.Update(ctx, "test-domain", "test-token", "192.168.1.1")
+	_, err := client.Update(ctx, UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err == nil {
 		t.Error("キャンセルエラーが返されるべき")
 	}
 }
 
+// TestClient_Update_RateLimiter は、Limiter の制限を超えたリクエストがトークンの補充を待ち、
+// 待機中にキャンセルされた場合は送信せずに ErrCancelled を返すことをテストします。
+func TestClient_Update_RateLimiter(t *testing.T) {
+	requestCount := 0
+	client := NewClient(
+		WithHTTPClient(&MockHTTPDoer{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				requestCount++
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK"))}, nil
+			},
+		}),
+		WithRateLimiter(ratelimit.NewLimiter(1, time.Hour)),
+	)
+
+	if _, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"}); err != nil {
+		t.Fatalf("1回目は待たずに送信されるべき: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := client.Update(ctx, UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
+	if !errors.Is(err, ErrCancelled) {
+		t.Errorf("ErrCancelled が返されるべき: %v", err)
+	}
+	if requestCount != 1 {
+		t.Errorf("制限を超えたリクエストは送信されないべき。送信回数: %d", requestCount)
+	}
+}
+
 // TestClient_Update_WithWhitespace は、ホワイトスペース付きのレスポンスをテストします。
 func TestClient_Update_WithWhitespace(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -172,7 +353,7 @@ func TestClient_Update_WithWhitespace(t *testing.T) {
 	defer server.Close()
 
 	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
-	response, err := client.Update(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	response, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
@@ -200,7 +381,7 @@ func TestClient_UpdateWithRetry_Success(t *testing.T) {
 		Backoff:    []time.Duration{10 * time.Millisecond},
 	})
 
-	response, err := client.UpdateWithRetry(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	response, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
@@ -236,7 +417,7 @@ func TestClient_UpdateWithRetry_SuccessAfterRetry(t *testing.T) {
 		Backoff:    []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
 	})
 
-	response, err := client.UpdateWithRetry(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	response, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
@@ -265,7 +446,7 @@ func TestClient_UpdateWithRetry_AllFail(t *testing.T) {
 		Backoff:    []time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
 	})
 
-	_, err := client.UpdateWithRetry(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	_, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err == nil {
 		t.Error("エラーが返されるべきですが、nilが返されました")
@@ -297,7 +478,7 @@ func TestClient_UpdateWithRetry_ContextCancelled(t *testing.T) {
 		cancel()
 	}()
 
-	_, err := client.UpdateWithRetry(ctx, "test-domain", "test-token", "192.168.1.1")
+	_, err := client.UpdateWithRetry(ctx, UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err == nil {
 		t.Error("キャンセルエラーが返されるべき")
@@ -331,7 +512,7 @@ func TestClient_UpdateWithRetry_BackoffWait(t *testing.T) {
 		Backoff:    []time.Duration{100 * time.Millisecond},
 	})
 
-	response, err := client.UpdateWithRetry(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	response, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
@@ -361,7 +542,7 @@ func TestClient_Update_UserAgent(t *testing.T) {
 	defer server.Close()
 
 	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
-	_, err := client.Update(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
@@ -394,7 +575,7 @@ func TestClient_UpdateWithRetry_RecoveryMessage(t *testing.T) {
 		Backoff:    []time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
 	})
 
-	response, err := client.UpdateWithRetry(context.Background(), "test-domain", "test-token", "192.168.1.1")
+	response, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.168.1.1"})
 
 	if err != nil {
 		t.Errorf("エラーが発生しました: %v", err)
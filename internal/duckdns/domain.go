@@ -0,0 +1,73 @@
+package duckdns
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// domainSuffix は、DuckDNS のドメインの末尾です。
+const domainSuffix = ".duckdns.org"
+
+// maxDomainLength は、DuckDNS のサブドメイン名（DNS のラベル）の最大長です。
+const maxDomainLength = 63
+
+// NormalizeDomain は、設定されたドメイン名を DuckDNS API に渡すサブドメイン名にします。
+// "mydomain"、"mydomain.duckdns.org"、"https://mydomain.duckdns.org/" のいずれも "mydomain" になります。
+// "www.mydomain.duckdns.org" のようなサブドメインは、DuckDNS では "mydomain" と同じレコードのため "mydomain" になります。
+//
+// Parameters:
+//   - domain: 設定されたドメイン名（サブドメイン名、FQDN、または URL）
+//
+// Returns:
+//   - string: 正規化したサブドメイン名（小文字）
+//   - error: DuckDNS のドメインではない場合、または使用できない文字や長さの場合
+func NormalizeDomain(domain string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(domain))
+	if name == "" {
+		return "", fmt.Errorf("ドメイン名が空です")
+	}
+
+	// URL を貼り付けた場合は、ホスト名だけを取り出す
+	if strings.Contains(name, "://") {
+		u, err := url.Parse(name)
+		if err != nil || u.Hostname() == "" {
+			return "", fmt.Errorf("ドメイン名 %q を URL として解析できません", domain)
+		}
+		name = u.Hostname()
+	}
+	name = strings.TrimSuffix(name, ".")
+
+	if strings.Contains(name, ".") {
+		if !strings.HasSuffix(name, domainSuffix) {
+			return "", fmt.Errorf("ドメイン名 %q は DuckDNS のドメインではありません (\"mydomain\" または \"mydomain.duckdns.org\" の形式で指定してください)", domain)
+		}
+		labels := strings.Split(strings.TrimSuffix(name, domainSuffix), ".")
+		name = labels[len(labels)-1]
+	}
+
+	if err := validateSubdomain(name); err != nil {
+		return "", fmt.Errorf("ドメイン名 %q が無効です: %w", domain, err)
+	}
+	return name, nil
+}
+
+// validateSubdomain は、サブドメイン名の文字と長さを検証します。
+// DuckDNS のサブドメイン名に使えるのは英小文字・数字・ハイフンで、先頭と末尾にハイフンは使えません。
+func validateSubdomain(name string) error {
+	if name == "" {
+		return fmt.Errorf("サブドメイン名が空です")
+	}
+	if len(name) > maxDomainLength {
+		return fmt.Errorf("サブドメイン名が長すぎます (%d 文字、上限 %d 文字)", len(name), maxDomainLength)
+	}
+	for i, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return fmt.Errorf("使用できない文字 %q が含まれています (%d 文字目。英数字とハイフンのみ使用できます)", r, i+1)
+		}
+	}
+	if strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") {
+		return fmt.Errorf("先頭と末尾にハイフンは使用できません")
+	}
+	return nil
+}
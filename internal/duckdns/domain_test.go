@@ -0,0 +1,53 @@
+package duckdns
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalizeDomain は、さまざまな形式のドメイン名をサブドメイン名に正規化することをテストします。
+func TestNormalizeDomain(t *testing.T) {
+	tests := map[string]string{
+		"mydomain":                       "mydomain",
+		"  MyDomain ":                    "mydomain",
+		"mydomain.duckdns.org":           "mydomain",
+		"mydomain.duckdns.org.":          "mydomain",
+		"https://mydomain.duckdns.org/":  "mydomain",
+		"http://MyDomain.DuckDNS.org:80": "mydomain",
+		"www.mydomain.duckdns.org":       "mydomain",
+		"my-domain-2":                    "my-domain-2",
+	}
+	for in, want := range tests {
+		got, err := NormalizeDomain(in)
+		if err != nil {
+			t.Errorf("NormalizeDomain(%q) でエラーが発生しました: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizeDomain(%q) = %q, 期待: %q", in, got, want)
+		}
+	}
+}
+
+// TestNormalizeDomain_Invalid は、DuckDNS のドメインとして使えない値がエラーになることをテストします。
+func TestNormalizeDomain_Invalid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "空です"},
+		{"mydomain.duckdns.com", "DuckDNS のドメインではありません"},
+		{"home.example.com", "DuckDNS のドメインではありません"},
+		{"my_domain", "使用できない文字 '_'"},
+		{"a,b", "使用できない文字 ','"},
+		{"-mydomain", "ハイフン"},
+		{".duckdns.org", "空です"},
+		{strings.Repeat("a", 64), "長すぎます"},
+	}
+	for _, tt := range tests {
+		_, err := NormalizeDomain(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NormalizeDomain(%q) のエラーに %q が含まれるべき: %v", tt.in, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package duckdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// クライアントが返すエラーの種類です。
+// 呼び出し側（スケジューラーや終了コード）は、メッセージの文字列ではなく errors.Is で失敗の種類を判定できます。
+var (
+	// ErrRejected は、DuckDNS が更新を拒否した（"KO" などを返した）ことを表すエラーです。
+	// ドメイン名やトークンの誤りが原因のため、接続エラーと区別するために errors.Is で判定できます。
+	ErrRejected = errors.New("DuckDNS更新に失敗しました")
+
+	// ErrServerStatus は、DuckDNS が 200 以外の HTTP ステータスを返したことを表すエラーです。
+	// ステータスコードは errors.As で StatusError を取り出して確認できます。
+	ErrServerStatus = errors.New("DuckDNS が HTTP エラーを返しました")
+
+	// ErrRateLimited は、DuckDNS が 429 (Too Many Requests) を返したことを表すエラーです。
+	// ErrServerStatus の一種で、待ち時間は RetryAfter で取り出せます。
+	ErrRateLimited = errors.New("DuckDNS のリクエスト数の制限を超えました")
+
+	// ErrNetwork は、名前解決・接続・タイムアウトなどで DuckDNS と通信できなかったことを表すエラーです。
+	ErrNetwork = errors.New("DuckDNS と通信できませんでした")
+
+	// ErrCancelled は、コンテキストのキャンセルにより更新を中断したことを表すエラーです。
+	// 元の context.Canceled（または context.DeadlineExceeded）も errors.Is で判定できます。
+	ErrCancelled = errors.New("更新がキャンセルされました")
+)
+
+// StatusError は、DuckDNS が 200 以外の HTTP ステータスを返したことを表すエラーです。
+// errors.Is(err, ErrServerStatus) で判定できます。
+type StatusError struct {
+	// StatusCode は、DuckDNS が返した HTTP ステータスコードです
+	StatusCode int
+
+	// RetryAfter は、Retry-After ヘッダーで指定された待ち時間です（指定がない場合は 0）
+	RetryAfter time.Duration
+}
+
+// Error は、ステータスコードを含むエラーメッセージを返します。
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("HTTPステータスエラー: %d (Retry-After: %s)", e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("HTTPステータスエラー: %d", e.StatusCode)
+}
+
+// Is は、target が ErrServerStatus の場合、または 429 で target が ErrRateLimited の場合に true を返します。
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrServerStatus:
+		return true
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// RetryAfter は、エラーに含まれる Retry-After の待ち時間を返します。
+// 429 や 503 で DuckDNS が待ち時間を指定した場合は、次の再試行までその時間だけ待つ必要があります。
+//
+// Parameters:
+//   - err: クライアントが返したエラー
+//
+// Returns:
+//   - time.Duration: 待ち時間（指定がない場合は 0）
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// requestError は、HTTP リクエストの失敗を種類付きのエラーにします。
+// コンテキストのキャンセルによる失敗は ErrCancelled、それ以外は ErrNetwork になります。
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ErrCancelled, ctx.Err())
+	}
+	return fmt.Errorf("%w: HTTPリクエスト実行に失敗しました: %w", ErrNetwork, err)
+}
+
+// IsTemporary は、再試行すると成功する可能性がある一時的な失敗かどうかを返します。
+// 通信の失敗（タイムアウトを含む）、5xx と 429 は一時的な失敗です。
+// "KO"（ErrRejected）やそれ以外のステータス、キャンセル、サーキットが開いている場合（ErrCircuitOpen）は、
+// 再試行しても結果が変わらないため false になります。
+//
+// Parameters:
+//   - err: クライアントが返したエラー
+//
+// Returns:
+//   - bool: 再試行するべき一時的な失敗の場合 true
+func IsTemporary(err error) bool {
+	var statusErr *StatusError
+	switch {
+	case err == nil, errors.Is(err, ErrCancelled), errors.Is(err, ErrRejected), errors.Is(err, ErrCircuitOpen):
+		return false
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	default:
+		return errors.Is(err, ErrNetwork)
+	}
+}
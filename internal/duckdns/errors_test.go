@@ -0,0 +1,192 @@
+package duckdns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestClient_Update_TypedErrors は、失敗の種類ごとに errors.Is で判定できるエラーを返すことをテストします。
+func TestClient_Update_TypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("domains") {
+		case "rejected":
+			w.Write([]byte("KO"))
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("OK"))
+		}
+	}))
+	client := NewClientWithOptions(server.Client(), server.URL, RetryConfig{})
+
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"rejected"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("KO は ErrRejected と判定されるべき: %v", err)
+	}
+
+	_, err = client.Update(context.Background(), UpdateRequest{Domains: []string{"unavailable"}, Token: "test-token", IPv4: "192.0.2.1"})
+	var statusErr *StatusError
+	if !errors.Is(err, ErrServerStatus) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("503 は ErrServerStatus と判定され、ステータスコードを取り出せるべき: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Update(ctx, UpdateRequest{Domains: []string{"ok"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("キャンセルは ErrCancelled と context.Canceled の両方で判定できるべき: %v", err)
+	}
+
+	server.Close()
+	_, err = client.Update(context.Background(), UpdateRequest{Domains: []string{"ok"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrNetwork) || errors.Is(err, ErrCancelled) {
+		t.Errorf("接続できない場合は ErrNetwork と判定されるべき: %v", err)
+	}
+}
+
+// TestClient_UpdateWithRetry_CancelledError は、リトライ中のキャンセルが ErrCancelled と判定できることをテストします。
+func TestClient_UpdateWithRetry_CancelledError(t *testing.T) {
+	client := NewClientWithOptions(&http.Client{}, "http://127.0.0.1:0", RetryConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.UpdateWithRetry(ctx, UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"}); !errors.Is(err, ErrCancelled) {
+		t.Errorf("キャンセルは ErrCancelled と判定されるべき: %v", err)
+	}
+}
+
+// TestIsTemporary は、一時的な失敗と恒久的な失敗の判定をテストします。
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"KO", ErrRejected, false},
+		{"キャンセル", ErrCancelled, false},
+		{"通信の失敗", ErrNetwork, true},
+		{"503", &StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"404", &StatusError{StatusCode: http.StatusNotFound}, false},
+		{"不明なエラー", errors.New("リクエスト作成に失敗しました"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTemporary(tt.err); got != tt.want {
+			t.Errorf("%s: IsTemporary = %v, 期待: %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestClient_UpdateWithRetry_NoRetryOnRejected は、"KO" と 4xx をリトライせずにすぐ返すことをテストします。
+func TestClient_UpdateWithRetry_NoRetryOnRejected(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"KO", http.StatusOK, "KO"},
+		{"400", http.StatusBadRequest, ""},
+	} {
+		attemptCount := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attemptCount++
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+
+		client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{
+			MaxRetries: 3,
+			Backoff:    []time.Duration{10 * time.Millisecond},
+		})
+		if _, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"}); err == nil {
+			t.Errorf("%s: エラーが返されるべき", tt.name)
+		}
+		if attemptCount != 1 {
+			t.Errorf("%s: リトライしないべき。試行回数: %d", tt.name, attemptCount)
+		}
+		server.Close()
+	}
+}
+
+// TestClient_Update_RateLimited は、429 が ErrRateLimited として判定でき、Retry-After を取り出せることをテストします。
+func TestClient_Update_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{})
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("ErrRateLimited として判定できるべき: %v", err)
+	}
+	if !errors.Is(err, ErrServerStatus) {
+		t.Errorf("ErrServerStatus としても判定できるべき: %v", err)
+	}
+	if got := RetryAfter(err); got != 30*time.Second {
+		t.Errorf("RetryAfter = %v, 期待: 30s", got)
+	}
+	if !IsTemporary(err) {
+		t.Error("429 は一時的な失敗として扱うべき")
+	}
+}
+
+// TestClient_UpdateWithRetry_RetryAfter は、Retry-After の待ち時間だけ待ってから再試行することをテストします。
+func TestClient_UpdateWithRetry_RetryAfter(t *testing.T) {
+	attemptCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attemptCount++
+		if attemptCount == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{
+		MaxRetries: 1,
+		Backoff:    []time.Duration{10 * time.Millisecond},
+	})
+
+	start := time.Now()
+	if _, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"}); err != nil {
+		t.Fatalf("リトライで成功するべき: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retry-After の1秒を待つべき。経過時間: %v", elapsed)
+	}
+	if attemptCount != 2 {
+		t.Errorf("試行回数 = %d, 期待: 2", attemptCount)
+	}
+}
+
+// TestClient_UpdateWithRetry_RetryAfterTooLong は、Retry-After が長すぎる場合は待たずに失敗を返すことをテストします。
+func TestClient_UpdateWithRetry_RetryAfterTooLong(t *testing.T) {
+	attemptCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attemptCount++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(&http.Client{}, server.URL, RetryConfig{
+		MaxRetries: 3,
+		Backoff:    []time.Duration{10 * time.Millisecond},
+	})
+
+	_, err := client.UpdateWithRetry(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("ErrRateLimited が返されるべき: %v", err)
+	}
+	if attemptCount != 1 {
+		t.Errorf("待たずに失敗を返すべき。試行回数: %d", attemptCount)
+	}
+}
@@ -0,0 +1,99 @@
+package duckdns
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Middleware は、DuckDNS へのリクエストの送信を包む処理です。
+// メトリクス・トレース・ログなどを、すべてのリクエストに同じように適用するために使用します。
+// next を呼び出さずにエラーを返すと、リクエストを送信しないようにできます。
+type Middleware func(next HTTPDoer) HTTPDoer
+
+// HTTPDoerFunc は、関数を HTTPDoer として使うためのアダプターです。
+type HTTPDoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do は、f(req) を呼び出します。
+func (f HTTPDoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware は、リクエストの送信を包む Middleware を指定します。
+// 複数指定した場合は、先に指定したものが外側になります（最初に呼び出され、最後に結果を受け取ります）。
+// 複数回指定した場合は、後の指定が内側に追加されます。
+//
+// Parameters:
+//   - middlewares: リクエストの送信を包む Middleware
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// chain は、doer を middlewares で包んだ HTTPDoer を返します。
+func chain(doer HTTPDoer, middlewares []Middleware) HTTPDoer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			doer = middlewares[i](doer)
+		}
+	}
+	return doer
+}
+
+// LogRequests は、送信したリクエストとその結果を Debug レベルでログに出力する Middleware を返します。
+// URL のトークンは RedactURL で伏せて出力します。
+//
+// Parameters:
+//   - logger: 出力先のロガー（nil の場合は slog.Default()）
+//
+// Returns:
+//   - Middleware: リクエストをログに出力する Middleware
+func LogRequests(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next HTTPDoer) HTTPDoer {
+		return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			attrs := []any{
+				"method", req.Method,
+				"url", RedactURL(req.URL),
+				"duration", time.Since(start).String(),
+			}
+			if err != nil {
+				logger.Debug("DuckDNS へのリクエストが失敗しました", append(attrs, "error", err)...)
+				return resp, err
+			}
+			logger.Debug("DuckDNS へのリクエストを送信しました", append(attrs, "status_code", resp.StatusCode)...)
+			return resp, nil
+		})
+	}
+}
+
+// RedactURL は、クエリパラメーターの token を伏せた URL の文字列を返します。
+// ログやエラーメッセージに DuckDNS のトークンが出力されないようにするために使用します。
+//
+// Parameters:
+//   - u: リクエストの URL
+//
+// Returns:
+//   - string: token を "REDACTED" に置き換えた URL
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	query := u.Query()
+	if !query.Has("token") {
+		return u.String()
+	}
+	query.Set("token", "REDACTED")
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
@@ -0,0 +1,110 @@
+package duckdns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestWithMiddleware は、Middleware が指定した順にリクエストを包むことをテストします。
+func TestWithMiddleware(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next HTTPDoer) HTTPDoer {
+			return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+":前")
+				resp, err := next.Do(req)
+				calls = append(calls, name+":後")
+				return resp, err
+			})
+		}
+	}
+	mock := &MockHTTPDoer{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, "送信")
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK"))}, nil
+		},
+	}
+
+	client := NewClient(WithHTTPClient(mock), WithMiddleware(record("外"), record("内")))
+	if _, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"}); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+
+	want := "外:前,内:前,送信,内:後,外:後"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("呼び出し順 = %s, 期待: %s", got, want)
+	}
+}
+
+// TestWithMiddleware_ShortCircuit は、Middleware がエラーを返した場合はリクエストを送信しないことをテストします。
+func TestWithMiddleware_ShortCircuit(t *testing.T) {
+	sent := false
+	mock := &MockHTTPDoer{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			sent = true
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK"))}, nil
+		},
+	}
+	deny := func(next HTTPDoer) HTTPDoer {
+		return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("送信を禁止しました")
+		})
+	}
+
+	client := NewClient(WithHTTPClient(mock), WithMiddleware(deny))
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, ErrNetwork) {
+		t.Errorf("Middleware のエラーは ErrNetwork として返されるべき: %v", err)
+	}
+	if sent {
+		t.Error("リクエストは送信されないべき")
+	}
+}
+
+// TestLogRequests は、リクエストのログにトークンが含まれないことをテストします。
+func TestLogRequests(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	mock := &MockHTTPDoer{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK"))}, nil
+		},
+	}
+
+	client := NewClient(WithHTTPClient(mock), WithMiddleware(LogRequests(logger)))
+	if _, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "secret-token", IPv4: "192.0.2.1"}); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "secret-token") {
+		t.Errorf("ログにトークンが含まれています: %s", out)
+	}
+	if !strings.Contains(out, "token=REDACTED") || !strings.Contains(out, "status_code=200") {
+		t.Errorf("ログに伏せた URL とステータスが含まれるべき: %s", out)
+	}
+}
+
+// TestRedactURL は、URL の token だけを伏せることをテストします。
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("https://www.duckdns.org/update?domains=home&token=secret&ip=192.0.2.1")
+	got := RedactURL(u)
+	if strings.Contains(got, "secret") || !strings.Contains(got, "token=REDACTED") || !strings.Contains(got, "domains=home") {
+		t.Errorf("RedactURL = %s", got)
+	}
+	if u.Query().Get("token") != "secret" {
+		t.Error("元の URL は変更されないべき")
+	}
+
+	u, _ = url.Parse("https://api.ipify.org")
+	if got := RedactURL(u); got != "https://api.ipify.org" {
+		t.Errorf("token がない URL はそのまま返すべき: %s", got)
+	}
+}
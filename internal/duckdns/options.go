@@ -0,0 +1,163 @@
+package duckdns
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/ratelimit"
+)
+
+// Option は、NewClient で作成するクライアントの既定値を変更するオプションです。
+type Option func(*options)
+
+// options は、NewClient に渡されたオプションの値です。
+type options struct {
+	httpClient HTTPDoer
+	baseURL    string
+	retry      RetryConfig
+	transport  http.RoundTripper
+	timeout    time.Duration
+	userAgent  string
+	limiter    *ratelimit.Limiter
+	breaker    *CircuitBreaker
+
+	// maxResponseSize は、読み込むレスポンスボディの最大サイズです（0 以下の場合は既定値）
+	maxResponseSize int64
+
+	// middlewares は、リクエストの送信を包む Middleware です（先頭が外側）
+	middlewares []Middleware
+}
+
+// WithHTTPClient は、リクエストの送信に使う HTTP クライアントを指定します。
+// 指定した場合、WithTimeout と WithTransport は使用されません（httpClient 側で設定してください）。
+// nil の場合は既定の HTTP クライアントを使用します。
+//
+// Parameters:
+//   - httpClient: http.Client の Do メソッド互換の HTTP クライアント
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithHTTPClient(httpClient HTTPDoer) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithBaseURL は、DuckDNS の更新APIのエンドポイントを指定します（テストや互換サーバー向け）。
+// 空の場合は https://www.duckdns.org/update を使用します。
+//
+// Parameters:
+//   - baseURL: 更新APIのURL
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		if baseURL != "" {
+			o.baseURL = baseURL
+		}
+	}
+}
+
+// WithRetry は、UpdateWithRetry の最大リトライ回数とバックオフを指定します。
+// ゼロ値の項目には既定値（最大3回、1s/2s/4s）を使用します。
+//
+// Parameters:
+//   - retry: リトライの設定
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithRetry(retry RetryConfig) Option {
+	return func(o *options) {
+		o.retry = retry
+	}
+}
+
+// WithTransport は、既定の HTTP クライアントが使う Transport を指定します。
+// プロキシなどの通信設定を反映した Transport を渡します。WithHTTPClient を指定した場合は使用されません。
+//
+// Parameters:
+//   - transport: 通信に使う Transport（nil の場合は http.DefaultTransport）
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout は、既定の HTTP クライアントのタイムアウトを指定します。
+// 0 以下の場合は既定値（10秒）を使用します。
+//
+// Parameters:
+//   - timeout: 1回のリクエストのタイムアウト
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.timeout = timeout
+		}
+	}
+}
+
+// WithUserAgent は、リクエストの User-Agent ヘッダーの値を指定します。
+// 空の場合は "duckdns-updater/1.0" を使用します。
+//
+// Parameters:
+//   - userAgent: User-Agent ヘッダーの値
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		if userAgent != "" {
+			o.userAgent = userAgent
+		}
+	}
+}
+
+// WithRateLimiter は、DuckDNS API へのリクエストの頻度を制限する Limiter を指定します。
+// 更新間隔の設定ミスやフックによる更新の集中で API を呼び出しすぎて、トークンが制限されることを防ぎます。
+// Limiter はクライアントを使用するすべてのドメインで共有されます。
+//
+// Parameters:
+//   - limiter: リクエスト頻度の制限（nil の場合は制限しない）
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithCircuitBreaker は、DuckDNS の障害が続いた場合にリクエストを止めるサーキットブレーカーを指定します。
+// サーキットが開いている間は、リクエストを送信せずに ErrCircuitOpen を返します。
+//
+// Parameters:
+//   - breaker: サーキットブレーカー（nil の場合は使用しない）
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(o *options) {
+		o.breaker = breaker
+	}
+}
+
+// WithMaxResponseSize は、読み込むレスポンスボディの最大サイズを指定します。
+// 最大サイズを超えた場合は、httpclient.ErrResponseTooLarge を含む ErrNetwork を返します。
+//
+// Parameters:
+//   - size: 最大サイズ（バイト）。0 以下の場合は httpclient.DefaultMaxResponseSize（64 KiB）
+//
+// Returns:
+//   - Option: クライアントのオプション
+func WithMaxResponseSize(size int64) Option {
+	return func(o *options) {
+		o.maxResponseSize = size
+	}
+}
@@ -0,0 +1,83 @@
+package duckdns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// TestNewClient_Options は、オプションで既定値を変更できることをテストします。
+func TestNewClient_Options(t *testing.T) {
+	var gotURL, gotUserAgent string
+	mock := &MockHTTPDoer{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			gotURL = req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+			gotUserAgent = req.Header.Get("User-Agent")
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK"))}, nil
+		},
+	}
+
+	client := NewClient(
+		WithHTTPClient(mock),
+		WithBaseURL("https://custom.example.com/api"),
+		WithRetry(RetryConfig{MaxRetries: 5}),
+		WithUserAgent("my-updater/2.0"),
+	)
+	if _, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"test-domain"}, Token: "test-token", IPv4: "192.0.2.1"}); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+
+	if gotURL != "https://custom.example.com/api" {
+		t.Errorf("送信先が一致しません: %s", gotURL)
+	}
+	if gotUserAgent != "my-updater/2.0" {
+		t.Errorf("User-Agent が一致しません: %s", gotUserAgent)
+	}
+	if client.retry.MaxRetries != 5 || len(client.retry.Backoff) != len(DefaultBackoff) {
+		t.Errorf("リトライ設定が一致しません: %+v", client.retry)
+	}
+}
+
+// TestNewClient_WithTimeout は、WithTimeout で既定の HTTP クライアントのタイムアウトを変更できることをテストします。
+func TestNewClient_WithTimeout(t *testing.T) {
+	client := NewClient(WithTimeout(3 * time.Second))
+	httpClient, ok := client.httpClient.(*http.Client)
+	if !ok || httpClient.Timeout != 3*time.Second {
+		t.Errorf("タイムアウトが 3s になるべき: %+v", client.httpClient)
+	}
+
+	// ゼロ値のオプションは既定値のまま
+	client = NewClient(WithTimeout(0), WithBaseURL(""), WithUserAgent(""), WithHTTPClient(nil))
+	if httpClient, ok := client.httpClient.(*http.Client); !ok || httpClient.Timeout != DefaultHTTPTimeout {
+		t.Errorf("タイムアウトが既定値になるべき: %+v", client.httpClient)
+	}
+	if client.baseURL != defaultBaseURL || client.userAgent != DefaultUserAgent {
+		t.Errorf("ベースURLと User-Agent が既定値になるべき: %s, %s", client.baseURL, client.userAgent)
+	}
+}
+
+// TestNewClient_WithMaxResponseSize は、最大サイズを超えるレスポンスがエラーになることをテストします。
+func TestNewClient_WithMaxResponseSize(t *testing.T) {
+	mock := &MockHTTPDoer{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK\n" + strings.Repeat("x", 100)))}, nil
+		},
+	}
+
+	client := NewClient(WithHTTPClient(mock), WithMaxResponseSize(16))
+	_, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"home"}, Token: "token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) || !errors.Is(err, ErrNetwork) {
+		t.Errorf("ErrResponseTooLarge と ErrNetwork が返されるべき: %v", err)
+	}
+
+	client = NewClient(WithHTTPClient(mock))
+	if _, err := client.Update(context.Background(), UpdateRequest{Domains: []string{"home"}, Token: "token", IPv4: "192.0.2.1"}); errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("既定の上限（64 KiB）以下のレスポンスは読み込めるべき: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package duckdns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// VerboseResponse は、verbose=true で求めた DuckDNS の詳しい応答です。
+type VerboseResponse struct {
+	// IPv4 は、DuckDNS が A レコードに登録している IPv4アドレスです
+	// ip を省略した場合は、DuckDNS がリクエストの送信元から検出したアドレスです
+	IPv4 string
+
+	// IPv6 は、DuckDNS が AAAA レコードに登録している IPv6アドレスです（ない場合は空）
+	IPv6 string
+
+	// Changed は、このリクエストでレコードが変わった（"UPDATED" が返された）場合に true です
+	// 変わらなかった場合は "NOCHANGE" が返されます
+	Changed bool
+}
+
+// ParseVerboseResponse は、verbose=true で求めた DuckDNS の詳しい応答を解析します。
+// 応答は "OK"、IPv4アドレス、IPv6アドレス（ない場合は空行）、"UPDATED" または "NOCHANGE" の4行です。
+//
+// Parameters:
+//   - body: Update が返したレスポンスボディ
+//
+// Returns:
+//   - VerboseResponse: 解析した応答
+//   - error: "OK" で始まらない場合、または行の数やIPアドレスが正しくない場合
+func ParseVerboseResponse(body string) (VerboseResponse, error) {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(body), "\r\n", "\n"), "\n")
+	if len(lines) != 4 || strings.TrimSpace(lines[0]) != "OK" {
+		return VerboseResponse{}, fmt.Errorf("DuckDNS の詳しい応答を解析できません: %q", body)
+	}
+
+	resp := VerboseResponse{
+		IPv4: strings.TrimSpace(lines[1]),
+		IPv6: strings.TrimSpace(lines[2]),
+	}
+	if parsed := net.ParseIP(resp.IPv4); resp.IPv4 != "" && (parsed == nil || parsed.To4() == nil) {
+		return VerboseResponse{}, fmt.Errorf("DuckDNS の応答の IPv4アドレス %q が無効です", resp.IPv4)
+	}
+	if parsed := net.ParseIP(resp.IPv6); resp.IPv6 != "" && (parsed == nil || parsed.To4() != nil) {
+		return VerboseResponse{}, fmt.Errorf("DuckDNS の応答の IPv6アドレス %q が無効です", resp.IPv6)
+	}
+	switch status := strings.TrimSpace(lines[3]); status {
+	case "UPDATED":
+		resp.Changed = true
+	case "NOCHANGE":
+	default:
+		return VerboseResponse{}, fmt.Errorf("DuckDNS の応答の更新の有無 %q が不明です", status)
+	}
+	return resp, nil
+}
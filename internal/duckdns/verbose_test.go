@@ -0,0 +1,36 @@
+package duckdns
+
+import "testing"
+
+// TestParseVerboseResponse は、DuckDNS の詳しい応答から登録したIPアドレスと更新の有無を取り出すことをテストします。
+func TestParseVerboseResponse(t *testing.T) {
+	tests := map[string]VerboseResponse{
+		"OK\n192.0.2.1\n\nUPDATED":                   {IPv4: "192.0.2.1", Changed: true},
+		"OK\r\n192.0.2.1\r\n2001:db8::1\r\nNOCHANGE": {IPv4: "192.0.2.1", IPv6: "2001:db8::1"},
+	}
+	for body, want := range tests {
+		got, err := ParseVerboseResponse(body)
+		if err != nil {
+			t.Errorf("%q: 解析に失敗しました: %v", body, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: 解析結果 = %+v, %+v であるべき", body, got, want)
+		}
+	}
+}
+
+// TestParseVerboseResponse_Invalid は、詳しい応答ではない場合にエラーになることをテストします。
+func TestParseVerboseResponse_Invalid(t *testing.T) {
+	for _, body := range []string{
+		"OK",
+		"KO\n192.0.2.1\n\nUPDATED",
+		"OK\n2001:db8::1\n\nUPDATED",
+		"OK\n192.0.2.1\n192.0.2.2\nUPDATED",
+		"OK\n192.0.2.1\n\nUNKNOWN",
+	} {
+		if _, err := ParseVerboseResponse(body); err == nil {
+			t.Errorf("%q: エラーになるべき", body)
+		}
+	}
+}
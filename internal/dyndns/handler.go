@@ -0,0 +1,161 @@
+// Package dyndns は、ルーターなどが WAN のアドレスの変更を通知する dyndns2 プロトコル（GET /nic/update）の受け口を提供します。
+// inadyn や ddclient、dyndns2 に対応したルーターから、定期チェックを待たずに更新を求められます。
+package dyndns
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dyndns2 プロトコルの応答です（ホスト名ごとに1行）。
+const (
+	// ResponseGood は、レコードを更新したことを表します（"good <IPアドレス>"）
+	ResponseGood = "good"
+
+	// ResponseNoChange は、IPアドレスが変わっていないため更新しなかったことを表します（"nochg <IPアドレス>"）
+	ResponseNoChange = "nochg"
+
+	// ResponseBadAuth は、ユーザー名またはパスワードが誤っていることを表します
+	ResponseBadAuth = "badauth"
+
+	// ResponseNotFQDN は、ホスト名が指定されていないことを表します
+	ResponseNotFQDN = "notfqdn"
+
+	// ResponseNoHost は、更新先にないホスト名であることを表します
+	ResponseNoHost = "nohost"
+
+	// ResponseNumHost は、1回のリクエストのホスト名が多すぎることを表します
+	ResponseNumHost = "numhost"
+
+	// ResponseServerError は、更新に失敗したことを表します
+	ResponseServerError = "911"
+)
+
+// MaxHosts は、1回のリクエストで指定できるホスト名の数の上限です（dyndns2 と同じ）。
+const MaxHosts = 20
+
+// updateTimeout は、1回のリクエストでレコードを更新するときのタイムアウトです。
+const updateTimeout = 2 * time.Minute
+
+// ErrNoHost は、更新先にないホスト名の場合に UpdateFunc が返すエラーです。
+var ErrNoHost = errors.New("更新先にないホスト名です")
+
+// Result は、1つのホスト名の更新の結果です。
+type Result struct {
+	// Changed は、レコードを更新した場合に true です
+	Changed bool
+
+	// IP は、登録されているIPアドレスです（IPv6 も更新した場合は "IPv4,IPv6"）
+	IP string
+}
+
+// UpdateFunc は、ホスト名のレコードを更新する関数です。
+// ipv4 と ipv6 は通知されたIPアドレスで、空の場合は IP取得ソースなどから決めます。
+// 更新先にないホスト名の場合は ErrNoHost を返します。
+type UpdateFunc func(ctx context.Context, hostname, ipv4, ipv6 string) (Result, error)
+
+// NewHandler は、dyndns2 プロトコルの受け口の HTTP ハンドラーを作成します。
+//
+//   - GET /nic/update?hostname=<ホスト名>[,<ホスト名>...]&myip=<IPアドレス> : レコードを更新します（Basic 認証）
+//   - GET /healthz : 動いているかどうかを返します
+//
+// myip を省略した場合や、IPアドレスとして解釈できない場合は、通知されたアドレスを使わずに update に任せます。
+// myip には "IPv4,IPv6" のように両方を指定でき、myipv6 でも IPv6アドレスを指定できます。
+//
+// Parameters:
+//   - username: Basic 認証のユーザー名
+//   - password: Basic 認証のパスワード
+//   - update: ホスト名のレコードを更新する関数
+//
+// Returns:
+//   - http.Handler: HTTP ハンドラー
+func NewHandler(username, password string, update UpdateFunc) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /nic/update", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		user, pass, ok := r.BasicAuth()
+		if !ok || !equal(user, username) || !equal(pass, password) {
+			slog.Warn("dyndns: 認証に失敗しました", "remote_addr", r.RemoteAddr, "user", user)
+			w.Header().Set("WWW-Authenticate", `Basic realm="duckdns"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(ResponseBadAuth + "\n"))
+			return
+		}
+
+		query := r.URL.Query()
+		var hostnames []string
+		for _, h := range strings.Split(query.Get("hostname"), ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hostnames = append(hostnames, h)
+			}
+		}
+		switch {
+		case len(hostnames) == 0:
+			w.Write([]byte(ResponseNotFQDN + "\n"))
+			return
+		case len(hostnames) > MaxHosts:
+			w.Write([]byte(ResponseNumHost + "\n"))
+			return
+		}
+
+		ipv4, ipv6 := parseMyIP(query.Get("myip"), query.Get("myipv6"))
+		ctx, cancel := context.WithTimeout(r.Context(), updateTimeout)
+		defer cancel()
+
+		lines := make([]string, 0, len(hostnames))
+		for _, hostname := range hostnames {
+			result, err := update(ctx, hostname, ipv4, ipv6)
+			switch {
+			case errors.Is(err, ErrNoHost):
+				lines = append(lines, ResponseNoHost)
+			case err != nil:
+				slog.Error("dyndns: 通知を受けた更新に失敗しました", "hostname", hostname, "error", err)
+				lines = append(lines, ResponseServerError)
+			case result.Changed:
+				lines = append(lines, ResponseGood+" "+result.IP)
+			default:
+				lines = append(lines, ResponseNoChange+" "+result.IP)
+			}
+		}
+		w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+// parseMyIP は、myip と myipv6 から IPv4 と IPv6 のアドレスを取り出します。
+// IPアドレスとして解釈できない値は、警告のログを出して使いません（dyndns2 と同じく、アドレスを推測して更新します）。
+func parseMyIP(myip, myipv6 string) (ipv4, ipv6 string) {
+	for _, value := range append(strings.Split(myip, ","), myipv6) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		ip := net.ParseIP(value)
+		switch {
+		case ip == nil:
+			slog.Warn("dyndns: IP アドレスとして解釈できないため、通知されたアドレスを使いません", "myip", value)
+		case ip.To4() != nil:
+			ipv4 = ip.String()
+		default:
+			ipv6 = ip.String()
+		}
+	}
+	return ipv4, ipv6
+}
+
+// equal は、a と b が等しいかどうかを、処理時間から推測されないように比べます。
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,93 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHandler は、dyndns2 プロトコルの応答をテストします。
+func TestHandler(t *testing.T) {
+	type call struct{ hostname, ipv4, ipv6 string }
+	var calls []call
+	handler := NewHandler("router", "secret", func(ctx context.Context, hostname, ipv4, ipv6 string) (Result, error) {
+		calls = append(calls, call{hostname, ipv4, ipv6})
+		switch hostname {
+		case "home.duckdns.org":
+			return Result{Changed: true, IP: "192.0.2.1"}, nil
+		case "same.duckdns.org":
+			return Result{IP: "192.0.2.1"}, nil
+		case "broken.duckdns.org":
+			return Result{}, errors.New("接続できません")
+		}
+		return Result{}, ErrNoHost
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(query, user, pass string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/nic/update?"+query, nil)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		user, pass string
+		wantStatus int
+		wantBody   string
+	}{
+		{"更新", "hostname=home.duckdns.org&myip=192.0.2.1", "router", "secret", http.StatusOK, "good 192.0.2.1\n"},
+		{"変更なし", "hostname=same.duckdns.org", "router", "secret", http.StatusOK, "nochg 192.0.2.1\n"},
+		{"複数のホスト名", "hostname=home.duckdns.org,unknown.example.com,broken.duckdns.org", "router", "secret", http.StatusOK, "good 192.0.2.1\nnohost\n911\n"},
+		{"ホスト名なし", "myip=192.0.2.1", "router", "secret", http.StatusOK, "notfqdn\n"},
+		{"パスワードの誤り", "hostname=home.duckdns.org", "router", "wrong", http.StatusUnauthorized, "badauth\n"},
+		{"認証なし", "hostname=home.duckdns.org", "", "", http.StatusUnauthorized, "badauth\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(tt.query, tt.user, tt.pass)
+			if status != tt.wantStatus || body != tt.wantBody {
+				t.Errorf("応答 = %d %q (期待値 %d %q)", status, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	if calls[0] != (call{"home.duckdns.org", "192.0.2.1", ""}) {
+		t.Errorf("通知されたアドレスを渡すべき: %+v", calls[0])
+	}
+}
+
+// TestParseMyIP は、myip と myipv6 から IPv4 と IPv6 のアドレスを取り出すことをテストします。
+func TestParseMyIP(t *testing.T) {
+	tests := []struct {
+		myip, myipv6 string
+		wantV4       string
+		wantV6       string
+	}{
+		{"192.0.2.1", "", "192.0.2.1", ""},
+		{"192.0.2.1,2001:db8::1", "", "192.0.2.1", "2001:db8::1"},
+		{"192.0.2.1", "2001:DB8::2", "192.0.2.1", "2001:db8::2"},
+		{"not-an-ip", "", "", ""},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		v4, v6 := parseMyIP(tt.myip, tt.myipv6)
+		if v4 != tt.wantV4 || v6 != tt.wantV6 {
+			t.Errorf("parseMyIP(%q, %q) = %q, %q (期待値 %q, %q)", tt.myip, tt.myipv6, v4, v6, tt.wantV4, tt.wantV6)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package externaldns は、Kubernetes の external-dns の webhook プロバイダーの API を、DuckDNS で実装します。
+// external-dns は、このパッケージの HTTP ハンドラーにレコードの一覧と変更を問い合わせます。
+package externaldns
+
+// MediaType は、webhook プロバイダーの API のメディアタイプです（Content-Type と Accept に使います）。
+const MediaType = "application/external.dns.webhook+json;version=1"
+
+// レコードの種類（recordType）です。DuckDNS のドメインごとに1つずつ登録できます。
+const (
+	// RecordTypeA は、IPv4アドレスのレコードです
+	RecordTypeA = "A"
+
+	// RecordTypeAAAA は、IPv6アドレスのレコードです
+	RecordTypeAAAA = "AAAA"
+
+	// RecordTypeTXT は、TXT レコードです
+	RecordTypeTXT = "TXT"
+)
+
+// DefaultTTL は、DuckDNS のレコードの TTL（秒）です。DuckDNS では変更できません。
+const DefaultTTL = 60
+
+// Endpoint は、external-dns のレコードです（external-dns の endpoint.Endpoint と同じ JSON）。
+type Endpoint struct {
+	// DNSName は、レコードの名前です（例: "home.duckdns.org"）
+	DNSName string `json:"dnsName"`
+
+	// Targets は、レコードの値です
+	Targets []string `json:"targets"`
+
+	// RecordType は、レコードの種類です（"A", "AAAA", "TXT"）
+	RecordType string `json:"recordType"`
+
+	// SetIdentifier は、同じ名前のレコードを区別する識別子です（DuckDNS では使いません）
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+
+	// RecordTTL は、レコードの TTL（秒）です
+	RecordTTL int64 `json:"recordTTL,omitempty"`
+
+	// Labels は、external-dns がレコードに付けるラベルです
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ProviderSpecific は、プロバイダー固有の設定です（DuckDNS では使いません）
+	ProviderSpecific []ProviderSpecificProperty `json:"providerSpecific,omitempty"`
+}
+
+// ProviderSpecificProperty は、プロバイダー固有の設定の名前と値です。
+type ProviderSpecificProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Changes は、external-dns が反映を求めるレコードの変更です（external-dns の plan.Changes と同じ JSON）。
+type Changes struct {
+	// Create は、追加するレコードです
+	Create []*Endpoint `json:"Create"`
+
+	// UpdateOld は、変更する前のレコードです
+	UpdateOld []*Endpoint `json:"UpdateOld"`
+
+	// UpdateNew は、変更したあとのレコードです
+	UpdateNew []*Endpoint `json:"UpdateNew"`
+
+	// Delete は、削除するレコードです
+	Delete []*Endpoint `json:"Delete"`
+}
+
+// DomainFilter は、このプロバイダーが管理するドメインです（external-dns の endpoint.DomainFilter と同じ JSON）。
+type DomainFilter struct {
+	// Include は、管理するドメインです
+	Include []string `json:"include,omitempty"`
+
+	// Exclude は、管理しないドメインです
+	Exclude []string `json:"exclude,omitempty"`
+}
@@ -0,0 +1,332 @@
+package externaldns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// domainSuffix は、DuckDNS のドメインの末尾です。
+const domainSuffix = ".duckdns.org"
+
+// DefaultCacheTTL は、レコードの値を DNS で問い合わせ直すまでの時間の既定値です。
+// DuckDNS のレコードの TTL と同じにします。
+const DefaultCacheTTL = DefaultTTL * time.Second
+
+// ErrUnsupported は、DuckDNS では登録できないレコードの変更を求められた場合のエラーです。
+var ErrUnsupported = errors.New("DuckDNS では登録できないレコードです")
+
+// Records は、1つのドメインの A・AAAA・TXT レコードの値です（ない場合は空）。
+type Records struct {
+	A    string
+	AAAA string
+	TXT  string
+}
+
+// Provider は、DuckDNS のドメインのレコードを external-dns のレコードとして読み書きします。
+// DuckDNS の API はレコードを読み出せないため、レコードの一覧は DNS で問い合わせ、
+// このプロバイダーが書き込んだ値は CacheTTL の間だけ覚えておきます。
+type Provider struct {
+	client   *duckdns.Client
+	tokens   map[string]string
+	resolver *net.Resolver
+	cacheTTL time.Duration
+
+	// lookup は、ドメインのレコードを DNS で問い合わせる関数です（テストで差し替えます）
+	lookup func(ctx context.Context, fqdn string) (Records, error)
+
+	mu    sync.Mutex
+	cache map[string]cachedRecords
+}
+
+// cachedRecords は、ドメインのレコードと、その値を得た時刻です。
+type cachedRecords struct {
+	records Records
+	at      time.Time
+}
+
+// NewProvider は、Provider を作成します。
+//
+// Parameters:
+//   - client: DuckDNS のクライアント
+//   - tokens: 管理するドメイン（サブドメイン名）とトークンの組
+//   - resolver: レコードの一覧を問い合わせるリゾルバー（nil の場合は net.DefaultResolver）
+//
+// Returns:
+//   - *Provider: 作成された Provider
+func NewProvider(client *duckdns.Client, tokens map[string]string, resolver *net.Resolver) *Provider {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	p := &Provider{
+		client:   client,
+		tokens:   tokens,
+		resolver: resolver,
+		cacheTTL: DefaultCacheTTL,
+		cache:    make(map[string]cachedRecords),
+	}
+	p.lookup = p.lookupDNS
+	return p
+}
+
+// DomainFilter は、管理するドメインの FQDN を返します。
+func (p *Provider) DomainFilter() DomainFilter {
+	return DomainFilter{Include: p.fqdns()}
+}
+
+// fqdns は、管理するドメインの FQDN を名前の順に返します。
+func (p *Provider) fqdns() []string {
+	names := make([]string, 0, len(p.tokens))
+	for domain := range p.tokens {
+		names = append(names, domain+domainSuffix)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Records は、管理するドメインの A・AAAA・TXT レコードを返します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - []*Endpoint: レコードの一覧
+//   - error: DNS の問い合わせに失敗した場合
+func (p *Provider) Records(ctx context.Context) ([]*Endpoint, error) {
+	endpoints := []*Endpoint{}
+	for _, fqdn := range p.fqdns() {
+		records, err := p.current(ctx, strings.TrimSuffix(fqdn, domainSuffix))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range []struct{ recordType, value string }{
+			{RecordTypeA, records.A},
+			{RecordTypeAAAA, records.AAAA},
+			{RecordTypeTXT, records.TXT},
+		} {
+			if r.value == "" {
+				continue
+			}
+			endpoints = append(endpoints, &Endpoint{
+				DNSName:    fqdn,
+				Targets:    []string{r.value},
+				RecordType: r.recordType,
+				RecordTTL:  DefaultTTL,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+// AdjustEndpoints は、external-dns が作ろうとしているレコードを DuckDNS で登録できる形にします。
+// DuckDNS の TTL は変えられないため、すべて DefaultTTL にします。
+func (p *Provider) AdjustEndpoints(endpoints []*Endpoint) []*Endpoint {
+	for _, e := range endpoints {
+		e.RecordTTL = DefaultTTL
+	}
+	return endpoints
+}
+
+// ApplyChanges は、レコードの変更を DuckDNS に反映します。
+// 変更はドメインごとにまとめて、A と AAAA は1回のリクエストで、TXT は別のリクエストで書き込みます。
+// DuckDNS では、AAAA だけのレコードは登録できません（ip を省略すると、リクエストの送信元のアドレスになるため）。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - changes: レコードの変更
+//
+// Returns:
+//   - error: 管理していないドメインや登録できないレコードの場合、または反映に失敗した場合
+func (p *Provider) ApplyChanges(ctx context.Context, changes *Changes) error {
+	desired := make(map[string]Records)
+	var order []string
+	apply := func(e *Endpoint, remove bool) error {
+		domain, err := p.domainOf(e.DNSName)
+		if err != nil {
+			return err
+		}
+		records, ok := desired[domain]
+		if !ok {
+			if records, err = p.current(ctx, domain); err != nil {
+				return err
+			}
+			order = append(order, domain)
+		}
+		value := ""
+		if !remove {
+			if len(e.Targets) != 1 {
+				return fmt.Errorf("%w: %s %s の値は1つだけ指定してください (%d 個)", ErrUnsupported, e.DNSName, e.RecordType, len(e.Targets))
+			}
+			value = e.Targets[0]
+		}
+		switch e.RecordType {
+		case RecordTypeA:
+			if value != "" && !isIP(value, false) {
+				return fmt.Errorf("%w: %s の A レコードの値が IPv4アドレスではありません: %s", ErrUnsupported, e.DNSName, value)
+			}
+			records.A = value
+		case RecordTypeAAAA:
+			if value != "" && !isIP(value, true) {
+				return fmt.Errorf("%w: %s の AAAA レコードの値が IPv6アドレスではありません: %s", ErrUnsupported, e.DNSName, value)
+			}
+			records.AAAA = value
+		case RecordTypeTXT:
+			records.TXT = strings.Trim(value, `"`)
+		default:
+			return fmt.Errorf("%w: %s の %s レコード", ErrUnsupported, e.DNSName, e.RecordType)
+		}
+		desired[domain] = records
+		return nil
+	}
+
+	// 削除してから追加と変更を反映するので、変更の前の値（UpdateOld）は使いません
+	for _, e := range changes.Delete {
+		if err := apply(e, true); err != nil {
+			return err
+		}
+	}
+	for _, list := range [][]*Endpoint{changes.Create, changes.UpdateNew} {
+		for _, e := range list {
+			if err := apply(e, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, domain := range order {
+		if err := p.write(ctx, domain, desired[domain]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write は、ドメインのレコードを今の値から want に書き換えます。
+func (p *Provider) write(ctx context.Context, domain string, want Records) error {
+	got, err := p.current(ctx, domain)
+	if err != nil {
+		return err
+	}
+	token := p.tokens[domain]
+	domains := []string{domain}
+
+	if want.A != got.A || want.AAAA != got.AAAA {
+		switch {
+		case want.A == "" && want.AAAA != "":
+			return fmt.Errorf("%w: %s の AAAA レコードだけを登録することはできません (A レコードも指定してください)", ErrUnsupported, domain+domainSuffix)
+		case want.A == "" || (want.AAAA == "" && got.AAAA != ""):
+			// DuckDNS は ipv6 を省略しても AAAA を消さないため、いったん消去してから登録します
+			if _, err := p.client.Clear(ctx, domains, token); err != nil {
+				return fmt.Errorf("%s のレコードを消去できません: %w", domain+domainSuffix, err)
+			}
+			p.remember(domain, Records{TXT: got.TXT})
+		}
+		if want.A != "" {
+			if _, err := p.client.Update(ctx, duckdns.UpdateRequest{Domains: domains, Token: token, IPv4: want.A, IPv6: want.AAAA}); err != nil {
+				return fmt.Errorf("%s のレコードを更新できません: %w", domain+domainSuffix, err)
+			}
+		}
+		p.remember(domain, Records{A: want.A, AAAA: want.AAAA, TXT: got.TXT})
+	}
+
+	if want.TXT != got.TXT {
+		if want.TXT == "" {
+			_, err = p.client.ClearTXT(ctx, domains, token)
+		} else {
+			_, err = p.client.SetTXT(ctx, domains, token, want.TXT)
+		}
+		if err != nil {
+			return fmt.Errorf("%s の TXT レコードを更新できません: %w", domain+domainSuffix, err)
+		}
+		p.remember(domain, want)
+	}
+	return nil
+}
+
+// domainOf は、レコードの名前から管理しているドメインのサブドメイン名を返します。
+// "www.home.duckdns.org" のような名前は、DuckDNS では "home.duckdns.org" と同じレコードになるため使えません。
+func (p *Provider) domainOf(dnsName string) (string, error) {
+	name := strings.TrimSuffix(strings.ToLower(dnsName), ".")
+	domain, ok := strings.CutSuffix(name, domainSuffix)
+	if !ok || strings.Contains(domain, ".") {
+		return "", fmt.Errorf("%w: %s は \"<ドメイン>.duckdns.org\" の形式ではありません", ErrUnsupported, dnsName)
+	}
+	if _, ok := p.tokens[domain]; !ok {
+		return "", fmt.Errorf("%s は管理しているドメインではありません", dnsName)
+	}
+	return domain, nil
+}
+
+// current は、ドメインの今のレコードを返します。覚えている値が古い場合は DNS で問い合わせます。
+func (p *Provider) current(ctx context.Context, domain string) (Records, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[domain]
+	p.mu.Unlock()
+	if ok && time.Since(cached.at) < p.cacheTTL {
+		return cached.records, nil
+	}
+
+	records, err := p.lookup(ctx, domain+domainSuffix)
+	if err != nil {
+		return Records{}, err
+	}
+	p.remember(domain, records)
+	return records, nil
+}
+
+// remember は、ドメインのレコードの値を覚えておきます。
+func (p *Provider) remember(domain string, records Records) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache[domain] = cachedRecords{records: records, at: time.Now()}
+}
+
+// lookupDNS は、ドメインの A・AAAA・TXT レコードを DNS で問い合わせます。
+// レコードがない場合は、エラーにせず空の値にします。
+func (p *Provider) lookupDNS(ctx context.Context, fqdn string) (Records, error) {
+	var records Records
+	addrs, err := p.resolver.LookupIPAddr(ctx, fqdn)
+	if err != nil && !isNotFound(err) {
+		return Records{}, fmt.Errorf("%s の名前解決に失敗しました: %w", fqdn, err)
+	}
+	for _, addr := range addrs {
+		if v4 := addr.IP.To4(); v4 != nil {
+			if records.A == "" {
+				records.A = v4.String()
+			}
+		} else if records.AAAA == "" {
+			records.AAAA = addr.IP.String()
+		}
+	}
+
+	txts, err := p.resolver.LookupTXT(ctx, fqdn)
+	if err != nil && !isNotFound(err) {
+		return Records{}, fmt.Errorf("%s の TXT レコードの問い合わせに失敗しました: %w", fqdn, err)
+	}
+	if len(txts) > 0 {
+		records.TXT = txts[0]
+	}
+	return records, nil
+}
+
+// isNotFound は、err がレコードがないことを表す DNS のエラーかどうかを返します。
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// isIP は、value が IPv4（ipv6 が true の場合は IPv6）のアドレスかどうかを返します。
+func isIP(value string, ipv6 bool) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	return (ip.To4() == nil) == ipv6
+}
@@ -0,0 +1,139 @@
+package externaldns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// fakeDuckDNS は、受け取ったリクエストのクエリを記録する DuckDNS API のサーバーです。
+type fakeDuckDNS struct {
+	mu      sync.Mutex
+	queries []url.Values
+}
+
+// newTestProvider は、fakeDuckDNS に接続し、DNS の代わりに records を返す Provider を作成します。
+func newTestProvider(t *testing.T, records map[string]Records) (*Provider, *fakeDuckDNS) {
+	t.Helper()
+	fake := &fakeDuckDNS{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.queries = append(fake.queries, r.URL.Query())
+		fake.mu.Unlock()
+		w.Write([]byte("OK"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := duckdns.NewClientWithOptions(&http.Client{}, server.URL, duckdns.RetryConfig{})
+	p := NewProvider(client, map[string]string{"home": "token-a", "other": "token-b"}, nil)
+	p.lookup = func(ctx context.Context, fqdn string) (Records, error) {
+		return records[fqdn], nil
+	}
+	return p, fake
+}
+
+// TestProvider_Records は、レコードがある種類だけを一覧にすることをテストします。
+func TestProvider_Records(t *testing.T) {
+	p, _ := newTestProvider(t, map[string]Records{
+		"home.duckdns.org": {A: "192.0.2.1", TXT: "hello"},
+	})
+	endpoints, err := p.Records(context.Background())
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	var got []string
+	for _, e := range endpoints {
+		got = append(got, e.DNSName+" "+e.RecordType+" "+e.Targets[0])
+	}
+	want := []string{"home.duckdns.org A 192.0.2.1", "home.duckdns.org TXT hello"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Records() = %v (期待値 %v)", got, want)
+	}
+
+	if filter := p.DomainFilter(); !slices.Equal(filter.Include, []string{"home.duckdns.org", "other.duckdns.org"}) {
+		t.Errorf("DomainFilter() = %v", filter.Include)
+	}
+}
+
+// TestProvider_ApplyChanges は、ドメインごとに変更をまとめて DuckDNS に送ることをテストします。
+func TestProvider_ApplyChanges(t *testing.T) {
+	p, fake := newTestProvider(t, map[string]Records{
+		"home.duckdns.org": {A: "192.0.2.1"},
+	})
+	err := p.ApplyChanges(context.Background(), &Changes{
+		Create: []*Endpoint{
+			{DNSName: "home.duckdns.org", RecordType: RecordTypeAAAA, Targets: []string{"2001:db8::1"}},
+			{DNSName: "other.duckdns.org", RecordType: RecordTypeTXT, Targets: []string{`"heritage=external-dns"`}},
+		},
+		UpdateOld: []*Endpoint{{DNSName: "home.duckdns.org", RecordType: RecordTypeA, Targets: []string{"192.0.2.1"}}},
+		UpdateNew: []*Endpoint{{DNSName: "home.duckdns.org", RecordType: RecordTypeA, Targets: []string{"192.0.2.2"}}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+
+	if len(fake.queries) != 2 {
+		t.Fatalf("リクエストは2回であるべき: %v", fake.queries)
+	}
+	if q := fake.queries[0]; q.Get("domains") != "home" || q.Get("token") != "token-a" || q.Get("ip") != "192.0.2.2" || q.Get("ipv6") != "2001:db8::1" {
+		t.Errorf("A と AAAA を1回で送るべき: %v", q)
+	}
+	if q := fake.queries[1]; q.Get("domains") != "other" || q.Get("token") != "token-b" || q.Get("txt") != "heritage=external-dns" || q.Has("ip") {
+		t.Errorf("TXT だけを送るべき: %v", q)
+	}
+
+	// 書き込んだ値は、DNS に反映される前でも一覧に含めます
+	endpoints, _ := p.Records(context.Background())
+	if len(endpoints) != 3 {
+		t.Errorf("書き込んだ値を一覧に含めるべき: %d 件", len(endpoints))
+	}
+}
+
+// TestProvider_ApplyChanges_Delete は、AAAA を消すときはいったん消去してから A を登録し直すことをテストします。
+func TestProvider_ApplyChanges_Delete(t *testing.T) {
+	p, fake := newTestProvider(t, map[string]Records{
+		"home.duckdns.org": {A: "192.0.2.1", AAAA: "2001:db8::1", TXT: "keep"},
+	})
+	err := p.ApplyChanges(context.Background(), &Changes{
+		Delete: []*Endpoint{{DNSName: "home.duckdns.org", RecordType: RecordTypeAAAA, Targets: []string{"2001:db8::1"}}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+	if len(fake.queries) != 2 || fake.queries[0].Get("clear") != "true" || fake.queries[1].Get("ip") != "192.0.2.1" || fake.queries[1].Has("ipv6") {
+		t.Errorf("消去してから A を登録し直すべき: %v", fake.queries)
+	}
+}
+
+// TestProvider_ApplyChanges_Unsupported は、DuckDNS で登録できない変更をエラーにすることをテストします。
+func TestProvider_ApplyChanges_Unsupported(t *testing.T) {
+	tests := map[string]*Endpoint{
+		"CNAME":       {DNSName: "home.duckdns.org", RecordType: "CNAME", Targets: []string{"example.com"}},
+		"サブドメイン":      {DNSName: "www.home.duckdns.org", RecordType: RecordTypeA, Targets: []string{"192.0.2.1"}},
+		"AAAA だけ":     {DNSName: "other.duckdns.org", RecordType: RecordTypeAAAA, Targets: []string{"2001:db8::1"}},
+		"A に IPv6":    {DNSName: "home.duckdns.org", RecordType: RecordTypeA, Targets: []string{"2001:db8::1"}},
+		"値が複数":        {DNSName: "home.duckdns.org", RecordType: RecordTypeA, Targets: []string{"192.0.2.1", "192.0.2.2"}},
+		"DuckDNS 以外":  {DNSName: "example.com", RecordType: RecordTypeA, Targets: []string{"192.0.2.1"}},
+		"管理していないドメイン": {DNSName: "unknown.duckdns.org", RecordType: RecordTypeA, Targets: []string{"192.0.2.1"}},
+	}
+	for name, e := range tests {
+		p, fake := newTestProvider(t, nil)
+		err := p.ApplyChanges(context.Background(), &Changes{Create: []*Endpoint{e}})
+		if err == nil {
+			t.Errorf("%s: エラーを返すべき", name)
+		}
+		if name != "管理していないドメイン" && !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: ErrUnsupported を返すべき: %v", name, err)
+		}
+		if len(fake.queries) != 0 {
+			t.Errorf("%s: DuckDNS に送るべきではない: %v", name, fake.queries)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package externaldns
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// maxRequestSize は、external-dns から受け取るリクエストボディの最大サイズです。
+const maxRequestSize = 1 << 20
+
+// NewHandler は、external-dns の webhook プロバイダーの API を提供する HTTP ハンドラーを作成します。
+//
+//   - GET  /                : 管理するドメイン（DomainFilter）を返します（external-dns の起動時の確認）
+//   - GET  /records         : レコードの一覧を返します
+//   - POST /records         : レコードの変更（Changes）を反映します
+//   - POST /adjustendpoints : 作ろうとしているレコードを DuckDNS で登録できる形にして返します
+//   - GET  /healthz         : 動いているかどうかを返します
+//
+// Parameters:
+//   - p: レコードを読み書きする Provider
+//
+// Returns:
+//   - http.Handler: HTTP ハンドラー
+func NewHandler(p *Provider) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.DomainFilter())
+	})
+
+	mux.HandleFunc("GET /records", func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := p.Records(r.Context())
+		if err != nil {
+			slog.Error("external-dns: レコードの一覧を取得できませんでした", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, endpoints)
+	})
+
+	mux.HandleFunc("POST /records", func(w http.ResponseWriter, r *http.Request) {
+		var changes Changes
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&changes); err != nil {
+			http.Error(w, "リクエストの形式が不正です: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Info("external-dns: レコードの変更を反映します",
+			"create", len(changes.Create),
+			"update", len(changes.UpdateNew),
+			"delete", len(changes.Delete),
+		)
+		if err := p.ApplyChanges(r.Context(), &changes); err != nil {
+			slog.Error("external-dns: レコードの変更を反映できませんでした", "error", err)
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrUnsupported) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /adjustendpoints", func(w http.ResponseWriter, r *http.Request) {
+		var endpoints []*Endpoint
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&endpoints); err != nil {
+			http.Error(w, "リクエストの形式が不正です: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, p.AdjustEndpoints(endpoints))
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+// writeJSON は、v を webhook のメディアタイプの JSON で書き込みます。
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", MediaType)
+	w.Header().Set("Vary", "Content-Type")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("external-dns: レスポンスを書き込めませんでした", "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package externaldns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandler は、webhook プロバイダーの API の各エンドポイントをテストします。
+func TestHandler(t *testing.T) {
+	p, fake := newTestProvider(t, map[string]Records{"home.duckdns.org": {A: "192.0.2.1"}})
+	server := httptest.NewServer(NewHandler(p))
+	defer server.Close()
+
+	// 起動時の確認では、管理するドメインを返します
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filter DomainFilter
+	json.NewDecoder(resp.Body).Decode(&filter)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != MediaType || len(filter.Include) != 2 {
+		t.Errorf("DomainFilter を返すべき: %s %v", resp.Header.Get("Content-Type"), filter)
+	}
+
+	resp, err = http.Get(server.URL + "/records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var endpoints []Endpoint
+	json.NewDecoder(resp.Body).Decode(&endpoints)
+	resp.Body.Close()
+	if len(endpoints) != 1 || endpoints[0].Targets[0] != "192.0.2.1" || endpoints[0].RecordTTL != DefaultTTL {
+		t.Errorf("レコードの一覧を返すべき: %+v", endpoints)
+	}
+
+	body := `{"Create":[{"dnsName":"home.duckdns.org","recordType":"TXT","targets":["hello"]}]}`
+	resp, err = http.Post(server.URL+"/records", MediaType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || len(fake.queries) != 1 || fake.queries[0].Get("txt") != "hello" {
+		t.Errorf("変更を反映して 204 を返すべき: %d %v", resp.StatusCode, fake.queries)
+	}
+
+	body = `{"Create":[{"dnsName":"home.duckdns.org","recordType":"CNAME","targets":["example.com"]}]}`
+	resp, err = http.Post(server.URL+"/records", MediaType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("登録できない変更では 400 を返すべき: %d", resp.StatusCode)
+	}
+
+	body = `[{"dnsName":"home.duckdns.org","recordType":"A","targets":["192.0.2.9"],"recordTTL":300}]`
+	resp, err = http.Post(server.URL+"/adjustendpoints", MediaType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints = nil
+	json.NewDecoder(resp.Body).Decode(&endpoints)
+	resp.Body.Close()
+	if len(endpoints) != 1 || endpoints[0].RecordTTL != DefaultTTL {
+		t.Errorf("TTL を DuckDNS の値にするべき: %+v", endpoints)
+	}
+
+	resp, err = http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/healthz は 200 を返すべき: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,253 @@
+// Package gops は、gops コマンド（github.com/google/gops）から接続できる診断用のエージェントです。
+// 常駐しているプロセスのゴルーチンのスタックトレース・GC の統計・メモリの使用状況を、
+// pprof を公開するように作り直さなくても、離れた機器で確認できるようにします。
+// 外部のパッケージに依存しないように、gops のエージェントと同じプロトコルを標準ライブラリだけで実装しています。
+package gops
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultListen は、待ち受けるアドレスを省略した場合の値です。
+// 診断の情報を公開しないように、ループバックアドレスの空いているポートで待ち受けます。
+const DefaultListen = "127.0.0.1:0"
+
+// gops のエージェントが受け付けるコマンドです（1回の接続で1バイトのコマンドを1つだけ受け付けます）。
+const (
+	cmdStackTrace   = byte(0x1)
+	cmdGC           = byte(0x2)
+	cmdMemStats     = byte(0x3)
+	cmdVersion      = byte(0x4)
+	cmdHeapProfile  = byte(0x5)
+	cmdCPUProfile   = byte(0x6)
+	cmdStats        = byte(0x7)
+	cmdTrace        = byte(0x8)
+	cmdBinaryDump   = byte(0x9)
+	cmdSetGCPercent = byte(0x10)
+)
+
+// cpuProfileDuration と traceDuration は、CPU プロファイルとトレースを取る時間です（gops のエージェントと同じ値です）。
+const (
+	cpuProfileDuration = 30 * time.Second
+	traceDuration      = 5 * time.Second
+)
+
+// commandTimeout は、接続してからコマンドを送ってくるまで待つ時間の上限です。
+const commandTimeout = 10 * time.Second
+
+// Agent は、gops コマンドからの接続を受け付ける診断用のエージェントです。
+type Agent struct {
+	listener net.Listener
+	portFile string
+
+	// mu は、同時に1つのコマンドだけを処理するためのロックです（CPU プロファイルとトレースは同時に取れないため）
+	mu sync.Mutex
+}
+
+// ConfigDir は、gops コマンドがエージェントのポート番号を探すディレクトリを返します。
+// 環境変数 GOPS_CONFIG_DIR があればそのディレクトリ、なければユーザーの設定ディレクトリの gops です。
+//
+// Returns:
+//   - string: ポート番号のファイルを置くディレクトリ
+//   - error: ユーザーの設定ディレクトリがわからない場合
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("GOPS_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gops"), nil
+}
+
+// Listen は、addr で待ち受けるエージェントを作成します。
+// gops コマンドがプロセス ID からエージェントを見つけられるように、configDir に "<プロセス ID>" の名前でポート番号を書き込みます。
+// 接続を受け付けるには Serve を呼び出してください。
+//
+// Parameters:
+//   - addr: 待ち受けるアドレス（空の場合は DefaultListen）
+//   - configDir: ポート番号のファイルを置くディレクトリ（空の場合は ConfigDir）
+//
+// Returns:
+//   - *Agent: 作成したエージェント
+//   - error: 待ち受けられなかった場合、ポート番号を書き込めなかった場合
+func Listen(addr, configDir string) (*Agent, error) {
+	if addr == "" {
+		addr = DefaultListen
+	}
+	if configDir == "" {
+		dir, err := ConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("gops の設定ディレクトリがわかりません: %w", err)
+		}
+		configDir = dir
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("gops の設定ディレクトリを作成できません: %w", err)
+	}
+	portFile := filepath.Join(configDir, strconv.Itoa(os.Getpid()))
+	port := listener.Addr().(*net.TCPAddr).Port
+	if err := os.WriteFile(portFile, []byte(strconv.Itoa(port)), 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("ポート番号を書き込めません: %w", err)
+	}
+	return &Agent{listener: listener, portFile: portFile}, nil
+}
+
+// Addr は、エージェントが待ち受けているアドレスを返します。
+func (a *Agent) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+// Serve は、Close が呼び出されるまで gops コマンドからの接続を受け付けます。
+//
+// Returns:
+//   - error: 接続を受け付けられなくなった場合（Close した場合は nil）
+func (a *Agent) Serve() error {
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go a.serveConn(conn)
+	}
+}
+
+// Close は、待ち受けをやめて、ポート番号のファイルを削除します。
+func (a *Agent) Close() error {
+	err := a.listener.Close()
+	if rmErr := os.Remove(a.portFile); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// serveConn は、1つの接続からコマンドを読み込んで処理します。
+func (a *Agent) serveConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(commandTimeout))
+	var cmd [1]byte
+	if _, err := io.ReadFull(conn, cmd[:]); err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := handle(conn, cmd[0]); err != nil {
+		slog.Debug("gops のコマンドを処理できませんでした",
+			"command", cmd[0],
+			"remote", conn.RemoteAddr().String(),
+			"error", err,
+		)
+	}
+}
+
+// handle は、gops のコマンドを処理して、結果を conn に書き込みます。
+func handle(conn net.Conn, cmd byte) error {
+	switch cmd {
+	case cmdStackTrace:
+		return pprof.Lookup("goroutine").WriteTo(conn, 2)
+	case cmdGC:
+		runtime.GC()
+		_, err := conn.Write([]byte("ok"))
+		return err
+	case cmdMemStats:
+		return writeMemStats(conn)
+	case cmdVersion:
+		_, err := fmt.Fprintf(conn, "%v\n", runtime.Version())
+		return err
+	case cmdHeapProfile:
+		return pprof.WriteHeapProfile(conn)
+	case cmdCPUProfile:
+		if err := pprof.StartCPUProfile(conn); err != nil {
+			return err
+		}
+		time.Sleep(cpuProfileDuration)
+		pprof.StopCPUProfile()
+		return nil
+	case cmdStats:
+		_, err := fmt.Fprintf(conn, "goroutines: %v\nOS threads: %v\nGOMAXPROCS: %v\nnum CPU: %v\n",
+			runtime.NumGoroutine(), pprof.Lookup("threadcreate").Count(), runtime.GOMAXPROCS(0), runtime.NumCPU())
+		return err
+	case cmdTrace:
+		if err := trace.Start(conn); err != nil {
+			return err
+		}
+		time.Sleep(traceDuration)
+		trace.Stop()
+		return nil
+	case cmdBinaryDump:
+		return writeBinary(conn)
+	case cmdSetGCPercent:
+		var percent int64
+		if err := binary.Read(conn, binary.LittleEndian, &percent); err != nil {
+			return err
+		}
+		previous := debug.SetGCPercent(int(percent))
+		_, err := fmt.Fprintf(conn, "New GC percent set to %v. Previous value was %v.\n", percent, previous)
+		return err
+	default:
+		return fmt.Errorf("未知のコマンドです: %#x", cmd)
+	}
+}
+
+// writeMemStats は、メモリの使用状況と GC の統計を gops のエージェントと同じ形式で書き込みます。
+func writeMemStats(w io.Writer) error {
+	var s runtime.MemStats
+	runtime.ReadMemStats(&s)
+	_, err := fmt.Fprintf(w,
+		"alloc: %v bytes\ntotal-alloc: %v bytes\nsys: %v bytes\nlookups: %v\nmallocs: %v\nfrees: %v\n"+
+			"heap-alloc: %v bytes\nheap-sys: %v bytes\nheap-idle: %v bytes\nheap-in-use: %v bytes\nheap-released: %v bytes\nheap-objects: %v\n"+
+			"stack-in-use: %v bytes\nstack-sys: %v bytes\nstack-mspan-inuse: %v bytes\nstack-mspan-sys: %v bytes\n"+
+			"stack-mcache-inuse: %v bytes\nstack-mcache-sys: %v bytes\nother-sys: %v bytes\ngc-sys: %v bytes\n"+
+			"next-gc: when heap-alloc >= %v bytes\nlast-gc: %v\ngc-pause-total: %v\ngc-pause: %v\ngc-pause-end: %v\n"+
+			"num-gc: %v\nnum-forced-gc: %v\ngc-cpu-fraction: %v\nenable-gc: %v\ndebug-gc: %v\n",
+		s.Alloc, s.TotalAlloc, s.Sys, s.Lookups, s.Mallocs, s.Frees,
+		s.HeapAlloc, s.HeapSys, s.HeapIdle, s.HeapInuse, s.HeapReleased, s.HeapObjects,
+		s.StackInuse, s.StackSys, s.MSpanInuse, s.MSpanSys,
+		s.MCacheInuse, s.MCacheSys, s.OtherSys, s.GCSys,
+		s.NextGC, time.Unix(0, int64(s.LastGC)), time.Duration(s.PauseTotalNs),
+		s.PauseNs[(s.NumGC+255)%256], s.PauseEnd[(s.NumGC+255)%256],
+		s.NumGC, s.NumForcedGC, s.GCCPUFraction, s.EnableGC, s.DebugGC,
+	)
+	return err
+}
+
+// writeBinary は、実行しているバイナリを書き込みます（gops コマンドが pprof でシンボルを解決するために使います）。
+func writeBinary(w io.Writer) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,102 @@
+package gops
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// startAgent は、テスト用のエージェントを起動します。
+func startAgent(t *testing.T) (*Agent, string) {
+	t.Helper()
+	dir := t.TempDir()
+	a, err := Listen("", dir)
+	if err != nil {
+		t.Fatalf("待ち受けに失敗しました: %v", err)
+	}
+	go a.Serve()
+	t.Cleanup(func() { a.Close() })
+	return a, dir
+}
+
+// send は、エージェントにコマンドを送り、応答を返します。
+func send(t *testing.T, a *Agent, cmd byte, args ...byte) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("接続に失敗しました: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(append([]byte{cmd}, args...)); err != nil {
+		t.Fatalf("コマンドを送れませんでした: %v", err)
+	}
+	out, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("応答を読み込めませんでした: %v", err)
+	}
+	return string(out)
+}
+
+// TestListen_PortFile は、gops コマンドが見つけられるように、プロセス ID の名前でポート番号を書き込み、Close で削除することをテストします。
+func TestListen_PortFile(t *testing.T) {
+	a, dir := startAgent(t)
+	if host, _, _ := net.SplitHostPort(a.Addr().String()); host != "127.0.0.1" {
+		t.Errorf("既定ではループバックアドレスで待ち受けるべき: %s", a.Addr())
+	}
+
+	portFile := filepath.Join(dir, strconv.Itoa(os.Getpid()))
+	data, err := os.ReadFile(portFile)
+	if err != nil {
+		t.Fatalf("ポート番号のファイルを書き込むべき: %v", err)
+	}
+	if want := strconv.Itoa(a.Addr().(*net.TCPAddr).Port); string(data) != want {
+		t.Errorf("ポート番号 = %q, want %q", data, want)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close に失敗しました: %v", err)
+	}
+	if _, err := os.Stat(portFile); !os.IsNotExist(err) {
+		t.Errorf("Close でポート番号のファイルを削除するべき: %v", err)
+	}
+}
+
+// TestAgent_Commands は、gops のコマンドに応答することをテストします。
+func TestAgent_Commands(t *testing.T) {
+	a, _ := startAgent(t)
+
+	if got := send(t, a, cmdVersion); got != runtime.Version()+"\n" {
+		t.Errorf("version = %q, want %q", got, runtime.Version())
+	}
+	if got := send(t, a, cmdStats); !strings.Contains(got, "goroutines: ") || !strings.Contains(got, "GOMAXPROCS: ") {
+		t.Errorf("stats にはゴルーチンの数を含むべき: %q", got)
+	}
+	if got := send(t, a, cmdMemStats); !strings.Contains(got, "heap-alloc: ") || !strings.Contains(got, "num-gc: ") {
+		t.Errorf("memstats にはヒープと GC の統計を含むべき: %q", got)
+	}
+	if got := send(t, a, cmdGC); got != "ok" {
+		t.Errorf("gc = %q, want ok", got)
+	}
+	if got := send(t, a, cmdStackTrace); !strings.Contains(got, "goroutine ") {
+		t.Errorf("stack にはゴルーチンのスタックトレースを含むべき: %q", got)
+	}
+}
+
+// TestAgent_SetGCPercent は、GC の割合を変えられることをテストします。
+func TestAgent_SetGCPercent(t *testing.T) {
+	a, _ := startAgent(t)
+	previous := debug.SetGCPercent(100)
+	t.Cleanup(func() { debug.SetGCPercent(previous) })
+
+	arg := binary.LittleEndian.AppendUint64(nil, 50)
+	if got := send(t, a, cmdSetGCPercent, arg...); got != "New GC percent set to 50. Previous value was 100.\n" {
+		t.Errorf("setgc = %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxResponseSize は、読み込むレスポンスボディの最大サイズの既定値（64 KiB）です。
+// IPアドレスや DuckDNS の "OK" / "KO" の応答には十分な大きさです。
+const DefaultMaxResponseSize int64 = 64 << 10
+
+// ErrResponseTooLarge は、レスポンスボディが最大サイズを超えたことを表すエラーです。
+// 上限は errors.As で ResponseTooLargeError を取り出して確認できます。
+var ErrResponseTooLarge = errors.New("レスポンスが大きすぎます")
+
+// ResponseTooLargeError は、レスポンスボディが最大サイズを超えたことを表すエラーです。
+// errors.Is(err, ErrResponseTooLarge) で判定できます。
+type ResponseTooLargeError struct {
+	// Limit は、読み込むレスポンスボディの最大サイズ（バイト）です
+	Limit int64
+}
+
+// Error は、上限を含むエラーメッセージを返します。
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%v (上限 %d バイト)", ErrResponseTooLarge, e.Limit)
+}
+
+// Is は、target が ErrResponseTooLarge の場合に true を返します。
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// ReadBody は、レスポンスボディを最大サイズまで読み込みます。
+// 誤動作や悪意のあるサーバーが巨大な応答を返しても、メモリを使い切らないようにします。
+//
+// Parameters:
+//   - r: レスポンスボディ
+//   - limit: 読み込む最大サイズ（バイト）。0 以下の場合は DefaultMaxResponseSize
+//
+// Returns:
+//   - []byte: 読み込んだレスポンスボディ
+//   - error: 読み込みに失敗した場合、または最大サイズを超えた場合（*ResponseTooLargeError）
+func ReadBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	return body, nil
+}
+
+// DecodeBody は、Content-Encoding に合わせてレスポンスボディを展開するリーダーを返します。
+// http.Transport は自分で Accept-Encoding を付けたリクエストの gzip しか展開しないため、
+// DisableCompression を設定した Transport や、要求していないのに gzip で返すサーバー・プロキシの応答もここで展開します。
+// 展開後のサイズは ReadBody で制限してください。
+//
+// Parameters:
+//   - resp: HTTP レスポンス
+//
+// Returns:
+//   - io.Reader: 展開したレスポンスボディ（圧縮されていない場合は resp.Body）
+//   - error: gzip のヘッダーが壊れている場合、または対応していない Content-Encoding の場合
+func DecodeBody(resp *http.Response) (io.Reader, error) {
+	if resp.Uncompressed {
+		return resp.Body, nil
+	}
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip のレスポンスを展開できません: %w", err)
+		}
+		return reader, nil
+	default:
+		return nil, fmt.Errorf("対応していない Content-Encoding です: %s", encoding)
+	}
+}
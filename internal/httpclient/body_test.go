@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestReadBody は、最大サイズまでのレスポンスボディを読み込めることをテストします。
+func TestReadBody(t *testing.T) {
+	body, err := ReadBody(strings.NewReader("192.0.2.1\n"), 10)
+	if err != nil || string(body) != "192.0.2.1\n" {
+		t.Errorf("ReadBody() = %q, %v", body, err)
+	}
+}
+
+// TestReadBody_TooLarge は、最大サイズを超えるレスポンスボディが ResponseTooLargeError になることをテストします。
+func TestReadBody_TooLarge(t *testing.T) {
+	_, err := ReadBody(strings.NewReader("192.0.2.10\n"), 10)
+	var tooLarge *ResponseTooLargeError
+	if !errors.Is(err, ErrResponseTooLarge) || !errors.As(err, &tooLarge) || tooLarge.Limit != 10 {
+		t.Fatalf("ResponseTooLargeError になるべき: %v", err)
+	}
+
+	// 上限を指定しない場合は既定の上限を使う
+	_, err = ReadBody(strings.NewReader(strings.Repeat("a", int(DefaultMaxResponseSize)+1)), 0)
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != DefaultMaxResponseSize {
+		t.Errorf("既定の上限で ResponseTooLargeError になるべき: %v", err)
+	}
+}
+
+// TestDecodeBody は、Content-Encoding に合わせてレスポンスボディを展開することをテストします。
+func TestDecodeBody(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("192.0.2.1"))
+	gz.Close()
+
+	tests := []struct {
+		encoding string
+		body     []byte
+		want     string
+		wantErr  bool
+	}{
+		{"", []byte("192.0.2.1"), "192.0.2.1", false},
+		{"identity", []byte("192.0.2.1"), "192.0.2.1", false},
+		{"GZIP", buf.Bytes(), "192.0.2.1", false},
+		{"gzip", []byte("not gzip"), "", true},
+		{"br", []byte("192.0.2.1"), "", true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{
+			Header: http.Header{"Content-Encoding": {tt.encoding}},
+			Body:   io.NopCloser(bytes.NewReader(tt.body)),
+		}
+		reader, err := DecodeBody(resp)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: エラーになるべき", tt.encoding)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: エラー: %v", tt.encoding, err)
+			continue
+		}
+		if got, _ := ReadBody(reader, 0); string(got) != tt.want {
+			t.Errorf("%q: 展開結果 = %q, 期待値 %q", tt.encoding, got, tt.want)
+		}
+	}
+}
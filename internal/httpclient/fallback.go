@@ -0,0 +1,229 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDoHServers は、名前解決のフォールバックに使う DNS over HTTPS の JSON API の既定値です。
+// DoH サーバー自体の名前解決が不要になるように、IPアドレスで指定しています。
+var DefaultDoHServers = []string{
+	"https://1.1.1.1/dns-query",
+	"https://8.8.8.8/resolve",
+}
+
+// dohTimeout は、DNS over HTTPS の問い合わせ1件あたりのタイムアウトです。
+const dohTimeout = 5 * time.Second
+
+// maxDoHResponseSize は、DNS over HTTPS の応答として読み込む最大のサイズです。
+const maxDoHResponseSize = 64 << 10
+
+// ValidateDoHServer は、DNS over HTTPS の JSON API の URL を検証します。
+//
+// Parameters:
+//   - server: DoH サーバーの URL（例: "https://1.1.1.1/dns-query"）
+//
+// Returns:
+//   - error: https の URL ではない場合
+func ValidateDoHServer(server string) error {
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("DNS over HTTPS のサーバー %q は https:// の URL で指定してください", server)
+	}
+	return nil
+}
+
+// fallbackDialer は、名前解決に失敗した場合に別の方法で調べたアドレスで接続し直すダイヤラーです。
+// DNS over HTTPS で調べたアドレス、次に最後に接続できたアドレスの順に試します。
+// ローカルの DNS が壊れていても、それを直すための DDNS の更新が止まらないようにします。
+type fallbackDialer struct {
+	// dial は、通常の接続に使う関数です
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// doh は、DNS over HTTPS のリゾルバーです
+	doh *dohResolver
+
+	mu sync.Mutex
+
+	// lastKnown は、ホスト名ごとの最後に接続できたIPアドレスです
+	lastKnown map[string]string
+}
+
+// newFallbackDialer は、dial で接続できない場合に DoH と最後に接続できたアドレスを試すダイヤラーを作成します。
+func newFallbackDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error), doh *dohResolver) *fallbackDialer {
+	return &fallbackDialer{
+		dial:      dial,
+		doh:       doh,
+		lastKnown: make(map[string]string),
+	}
+}
+
+// DialContext は、addr に接続します。名前解決に失敗した場合は、DoH と最後に接続できたアドレスで接続し直します。
+func (d *fallbackDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, splitErr := net.SplitHostPort(addr)
+	conn, err := d.dial(ctx, network, addr)
+	if err == nil {
+		if splitErr == nil && net.ParseIP(host) == nil {
+			d.remember(host, conn.RemoteAddr())
+		}
+		return conn, nil
+	}
+
+	var dnsErr *net.DNSError
+	if splitErr != nil || !errors.As(err, &dnsErr) {
+		return nil, err
+	}
+
+	for _, candidate := range d.candidates(ctx, network, host) {
+		fallbackConn, fallbackErr := d.dial(ctx, network, net.JoinHostPort(candidate.ip, port))
+		if fallbackErr != nil {
+			continue
+		}
+		slog.Warn("名前解決に失敗したため、別の方法で調べたアドレスで接続しました",
+			"host", host,
+			"ip", candidate.ip,
+			"source", candidate.source,
+			"error", err,
+		)
+		d.remember(host, fallbackConn.RemoteAddr())
+		return fallbackConn, nil
+	}
+	return nil, err
+}
+
+// fallbackCandidate は、フォールバックで接続を試すアドレスと、その調べ方です。
+type fallbackCandidate struct {
+	ip     string
+	source string
+}
+
+// candidates は、DoH で調べたアドレスと最後に接続できたアドレスを、試す順に返します。
+func (d *fallbackDialer) candidates(ctx context.Context, network, host string) []fallbackCandidate {
+	var candidates []fallbackCandidate
+	if d.doh != nil {
+		ips, err := d.doh.lookup(ctx, host, network == "tcp6")
+		if err != nil {
+			slog.Warn("DNS over HTTPS での名前解決に失敗しました",
+				"host", host,
+				"error", err,
+			)
+		}
+		for _, ip := range ips {
+			candidates = append(candidates, fallbackCandidate{ip: ip, source: "doh"})
+		}
+	}
+
+	d.mu.Lock()
+	ip, ok := d.lastKnown[host]
+	d.mu.Unlock()
+	if ok {
+		candidates = append(candidates, fallbackCandidate{ip: ip, source: "last-known"})
+	}
+	return candidates
+}
+
+// remember は、ホスト名に接続できたアドレスを記録します。
+func (d *fallbackDialer) remember(host string, addr net.Addr) {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	d.lastKnown[host] = tcpAddr.IP.String()
+	d.mu.Unlock()
+}
+
+// dohResolver は、DNS over HTTPS の JSON API で名前解決します。
+type dohResolver struct {
+	servers []string
+	client  *http.Client
+}
+
+// dohResponse は、DNS over HTTPS の JSON API の応答です。
+type dohResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// DNS レコードの種類です。
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// lookup は、DoH サーバーに順に問い合わせ、最初に答えが得られたアドレスを返します。
+func (r *dohResolver) lookup(ctx context.Context, host string, ipv6 bool) ([]string, error) {
+	qtype := dnsTypeA
+	if ipv6 {
+		qtype = dnsTypeAAAA
+	}
+
+	var errs []error
+	for _, server := range r.servers {
+		ips, err := r.query(ctx, server, host, qtype)
+		if err == nil && len(ips) > 0 {
+			return ips, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s: アドレスが見つかりません", server)
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// query は、1つの DoH サーバーに問い合わせます。
+func (r *dohResolver) query(ctx context.Context, server, host string, qtype int) ([]string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("name", host)
+	q.Set("type", fmt.Sprint(qtype))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", server, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTPステータスエラー: %d", server, resp.StatusCode)
+	}
+
+	var answer dohResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDoHResponseSize)).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("%s: 応答を解析できません: %w", server, err)
+	}
+	if answer.Status != 0 {
+		return nil, fmt.Errorf("%s: 名前解決に失敗しました (Status: %d)", server, answer.Status)
+	}
+
+	var ips []string
+	for _, a := range answer.Answer {
+		if a.Type == qtype && net.ParseIP(strings.TrimSpace(a.Data)) != nil {
+			ips = append(ips, strings.TrimSpace(a.Data))
+		}
+	}
+	return ips, nil
+}
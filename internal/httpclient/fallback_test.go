@@ -0,0 +1,123 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// brokenDNSDial は、IPアドレス以外の接続先を名前解決の失敗にするテスト用のダイヤラーです。
+func brokenDNSDial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	if net.ParseIP(host) == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}}
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// listen は、接続を受け付けるだけのテスト用のサーバーを起動し、そのポートを返します。
+func listen(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("サーバーを起動できません: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+// serveDoH は、すべての A レコードの問い合わせに ip を返すテスト用の DoH サーバーを起動します。
+func serveDoH(t *testing.T, ip string) (*dohResolver, *atomic.Int32) {
+	t.Helper()
+	queries := new(atomic.Int32)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		if r.Header.Get("Accept") != "application/dns-json" || r.URL.Query().Get("type") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-json")
+		w.Write([]byte(`{"Status":0,"Answer":[{"name":"` + r.URL.Query().Get("name") + `.","type":5,"data":"alias.example."},{"type":1,"data":"` + ip + `"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return &dohResolver{servers: []string{server.URL + "/dns-query"}, client: server.Client()}, queries
+}
+
+// TestFallbackDialer_DoH は、名前解決に失敗した場合に DoH で調べたアドレスで接続することをテストします。
+func TestFallbackDialer_DoH(t *testing.T) {
+	port := listen(t)
+	doh, queries := serveDoH(t, "127.0.0.1")
+	dialer := newFallbackDialer(brokenDNSDial, doh)
+
+	conn, err := dialer.DialContext(context.Background(), "tcp", "www.duckdns.test:"+port)
+	if err != nil {
+		t.Fatalf("DoH で調べたアドレスで接続できるべき: %v", err)
+	}
+	conn.Close()
+	if queries.Load() != 1 {
+		t.Errorf("DoH の問い合わせ回数 = %d, 期待値 1", queries.Load())
+	}
+}
+
+// TestFallbackDialer_LastKnown は、DoH も使えない場合に最後に接続できたアドレスで接続することをテストします。
+func TestFallbackDialer_LastKnown(t *testing.T) {
+	port := listen(t)
+	var broken atomic.Bool
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if broken.Load() {
+			return brokenDNSDial(ctx, network, addr)
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, strings.Replace(addr, "www.duckdns.test", "127.0.0.1", 1))
+	}
+	doh := &dohResolver{servers: []string{"https://127.0.0.1:1/dns-query"}, client: &http.Client{}}
+	dialer := newFallbackDialer(dial, doh)
+
+	conn, err := dialer.DialContext(context.Background(), "tcp", "www.duckdns.test:"+port)
+	if err != nil {
+		t.Fatalf("接続に失敗しました: %v", err)
+	}
+	conn.Close()
+
+	broken.Store(true)
+	conn, err = dialer.DialContext(context.Background(), "tcp", "www.duckdns.test:"+port)
+	if err != nil {
+		t.Fatalf("最後に接続できたアドレスで接続できるべき: %v", err)
+	}
+	conn.Close()
+}
+
+// TestFallbackDialer_NoFallback は、フォールバック先がない場合に元のエラーを返すことをテストします。
+func TestFallbackDialer_NoFallback(t *testing.T) {
+	dialer := newFallbackDialer(brokenDNSDial, nil)
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "www.duckdns.test:443")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Fatalf("元の名前解決のエラーを返すべき: %v", err)
+	}
+}
+
+// TestNewTransport_InvalidDoHServer は、https ではない DoH サーバーがエラーになることをテストします。
+func TestNewTransport_InvalidDoHServer(t *testing.T) {
+	_, err := NewTransport(Options{DNSFallback: true, DoHServers: []string{"http://1.1.1.1/dns-query"}})
+	if err == nil || !strings.Contains(err.Error(), "https://") {
+		t.Errorf("https ではない DoH サーバーはエラーになるべき: %v", err)
+	}
+}
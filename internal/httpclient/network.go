@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ForceNetwork は、base を複製して、常に network で接続する http.Transport を作成します。
+// デュアルスタックのホストで、IPv4 と IPv6 のどちらで接続するかを固定する場合に使います。
+// 複製した Transport は base とは別に接続を保持します。
+//
+// Parameters:
+//   - base: プロキシなどの通信設定を反映した Transport（nil の場合は http.DefaultTransport）
+//   - network: 接続に使うネットワーク（"tcp4" または "tcp6"）
+//
+// Returns:
+//   - *http.Transport: network で接続する Transport
+func ForceNetwork(base *http.Transport, network string) *http.Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dial(ctx, network, addr)
+	}
+	return transport
+}
+
+// FamilyNetwork は、アドレスファミリーの名前を ForceNetwork に渡すネットワークにします。
+//
+// Parameters:
+//   - family: "ipv4"、"ipv6"、または空（固定しない）
+//
+// Returns:
+//   - string: "tcp4"、"tcp6"、または空（固定しない場合）
+//   - error: 不明なアドレスファミリーの場合
+func FamilyNetwork(family string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(family)) {
+	case "":
+		return "", nil
+	case "ipv4":
+		return "tcp4", nil
+	case "ipv6":
+		return "tcp6", nil
+	default:
+		return "", fmt.Errorf("アドレスファミリー %q に対応していません (ipv4 または ipv6 を指定してください)", family)
+	}
+}
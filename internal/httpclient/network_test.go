@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestForceNetwork は、指定したネットワークで接続することをテストします。
+func TestForceNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	// テスト用のサーバーは 127.0.0.1 で待ち受けるため、IPv4 なら接続でき、IPv6 では接続できない
+	client := &http.Client{Transport: ForceNetwork(nil, "tcp4")}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("IPv4 で接続できるべき: %v", err)
+	}
+	resp.Body.Close()
+
+	client = &http.Client{Transport: ForceNetwork(nil, "tcp6")}
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("IPv6 で IPv4 のアドレスに接続できないべき")
+	}
+}
+
+// TestFamilyNetwork は、アドレスファミリーの名前をネットワークにすることをテストします。
+func TestFamilyNetwork(t *testing.T) {
+	tests := map[string]string{"": "", "ipv4": "tcp4", "IPv6": "tcp6"}
+	for in, want := range tests {
+		if got, err := FamilyNetwork(in); err != nil || got != want {
+			t.Errorf("FamilyNetwork(%q) = %q (エラー: %v), %q であるべき", in, got, err, want)
+		}
+	}
+	if _, err := FamilyNetwork("ipv5"); err == nil {
+		t.Error("不明なアドレスファミリーはエラーになるべき")
+	}
+}
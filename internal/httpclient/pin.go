@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrPinMismatch は、接続先の証明書がピン留めした公開鍵のどれとも一致しない場合のエラーです。
+var ErrPinMismatch = errors.New("証明書の公開鍵がピン留めした値と一致しません")
+
+// pinPrefix は、SPKI のピンの接頭辞です（HPKP と同じ "sha256/<base64>" の形式）。
+const pinPrefix = "sha256/"
+
+// ParsePin は、SPKI のピンを解析して SHA-256 のハッシュ値を返します。
+//
+// Parameters:
+//   - pin: "sha256/<base64>" 形式のピン（証明書の SubjectPublicKeyInfo の SHA-256 ハッシュ）
+//
+// Returns:
+//   - []byte: SHA-256 のハッシュ値
+//   - error: 形式が無効な場合
+func ParsePin(pin string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(pin), pinPrefix)
+	if !ok {
+		return nil, fmt.Errorf("ピン %q は %s<base64> の形式で指定してください", pin, pinPrefix)
+	}
+	hash, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("ピン %q は SHA-256 のハッシュ値を base64 で指定してください", pin)
+	}
+	return hash, nil
+}
+
+// SPKIPin は、証明書の SubjectPublicKeyInfo から "sha256/<base64>" 形式のピンを計算します。
+//
+// Parameters:
+//   - rawSubjectPublicKeyInfo: 証明書の RawSubjectPublicKeyInfo
+//
+// Returns:
+//   - string: "sha256/<base64>" 形式のピン
+func SPKIPin(rawSubjectPublicKeyInfo []byte) string {
+	hash := sha256.Sum256(rawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// pinVerifier は、ホスト名ごとにピン留めした公開鍵で接続先の証明書を検証します。
+type pinVerifier map[string][][]byte
+
+// ValidatePins は、1つのホストのピン留めの設定を検証します。
+//
+// Parameters:
+//   - host: ピン留めするホスト名
+//   - pins: "sha256/<base64>" 形式のピン
+//
+// Returns:
+//   - error: ホスト名が空かIPアドレスの場合、ピンがない場合、またはピンの形式が無効な場合
+func ValidatePins(host string, pins []string) error {
+	_, err := parseHostPins(host, pins)
+	return err
+}
+
+// parseHostPins は、1つのホストのピンを解析します。
+func parseHostPins(host string, pins []string) ([][]byte, error) {
+	if host == "" {
+		return nil, errors.New("ピン留めするホスト名が空です")
+	}
+	if net.ParseIP(host) != nil {
+		// IPアドレスの接続先には SNI を送らないため、接続時にホストを判別できない
+		return nil, fmt.Errorf("IPアドレス %q はピン留めできません (ホスト名で指定してください)", host)
+	}
+	if len(pins) == 0 {
+		return nil, fmt.Errorf("ホスト %q のピンが1つもありません", host)
+	}
+	hashes := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		hash, err := ParsePin(pin)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// newPinVerifier は、ホスト名ごとのピンから pinVerifier を作成します。
+func newPinVerifier(pins map[string][]string) (pinVerifier, error) {
+	verifier := make(pinVerifier, len(pins))
+	for host, hostPins := range pins {
+		host = strings.ToLower(strings.TrimSpace(host))
+		hashes, err := parseHostPins(host, hostPins)
+		if err != nil {
+			return nil, err
+		}
+		verifier[host] = append(verifier[host], hashes...)
+	}
+	return verifier, nil
+}
+
+// verifyConnection は、tls.Config の VerifyConnection に設定する関数です。
+// 通常の証明書の検証に加えて、ピン留めしたホストでは証明書チェーンのどれかの公開鍵がピンと一致することを確認します。
+// 中間 CA の公開鍵をピン留めすれば、サーバー証明書の更新でピンを変えずに済みます。
+func (v pinVerifier) verifyConnection(cs tls.ConnectionState) error {
+	hashes, ok := v[strings.ToLower(cs.ServerName)]
+	if !ok {
+		return nil
+	}
+	for _, cert := range cs.PeerCertificates {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, want := range hashes {
+			if bytes.Equal(hash[:], want) {
+				return nil
+			}
+		}
+	}
+
+	var got string
+	if len(cs.PeerCertificates) > 0 {
+		got = SPKIPin(cs.PeerCertificates[0].RawSubjectPublicKeyInfo)
+	}
+	return fmt.Errorf("%s: %w (サーバー証明書のピン: %s)", cs.ServerName, ErrPinMismatch, got)
+}
@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNewTransport_Pins は、ピン留めした公開鍵と一致するサーバーにだけ接続することをテストします。
+func TestNewTransport_Pins(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("192.0.2.1"))
+	}))
+	defer server.Close()
+	caFile := writeCA(t, server)
+	// httptest の証明書は example.com の名前でも有効
+	url := strings.Replace(server.URL, "127.0.0.1", "example.com", 1)
+	pin := SPKIPin(server.Certificate().RawSubjectPublicKeyInfo)
+	wrong := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name    string
+		pins    map[string][]string
+		wantErr bool
+	}{
+		{"一致するピン", map[string][]string{"example.com": {wrong, pin}}, false},
+		{"一致しないピン", map[string][]string{"Example.com": {wrong}}, true},
+		{"別のホストのピン", map[string][]string{"api.ipify.org": {wrong}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTransport(Options{Proxy: ProxyDirect, CAFile: caFile, Pins: tt.pins})
+			if err != nil {
+				t.Fatalf("Transport の作成に失敗しました: %v", err)
+			}
+			transport.DialContext = redirectDial(server.Listener.Addr().String())
+
+			resp, err := (&http.Client{Transport: transport}).Get(url)
+			if tt.wantErr {
+				if !errors.Is(err, ErrPinMismatch) {
+					t.Errorf("ErrPinMismatch になるべき: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("接続できるべき: %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
+
+// redirectDial は、接続先に関係なく addr に接続するテスト用のダイヤラーを返します。
+func redirectDial(addr string) func(ctx context.Context, network, _ string) (net.Conn, error) {
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// TestParsePin は、無効なピンがエラーになることをテストします。
+func TestParsePin(t *testing.T) {
+	valid := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	if _, err := ParsePin(valid); err != nil {
+		t.Errorf("%s は有効であるべき: %v", valid, err)
+	}
+	for _, pin := range []string{"", "sha1/AAAA", "sha256/not-base64!", "sha256/AAAA"} {
+		if _, err := ParsePin(pin); err == nil {
+			t.Errorf("%q はエラーになるべき", pin)
+		}
+	}
+}
+
+// TestNewTransport_InvalidPins は、無効なピン留めの設定がエラーになることをテストします。
+func TestNewTransport_InvalidPins(t *testing.T) {
+	valid := "sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	tests := map[string]map[string][]string{
+		"ホスト名が空":       {"": {valid}},
+		"ピンが1つもありません":  {"api.ipify.org": {}},
+		"ピン留めできません":    {"1.1.1.1": {valid}},
+		"の形式で指定してください": {"api.ipify.org": {"AAAA"}},
+	}
+	for want, pins := range tests {
+		if _, err := NewTransport(Options{Pins: pins}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: エラー = %v, 期待値 %q を含む", pins, err, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDNSPort は、リゾルバーのポートを省略した場合に使うポートです。
+const defaultDNSPort = "53"
+
+// ParseResolver は、リゾルバーのアドレスを "IPアドレス:ポート" の形式にします。
+// ポートを省略した場合は 53 を使用します。
+//
+// Parameters:
+//   - resolver: リゾルバーのアドレス（例: "1.1.1.1", "1.1.1.1:53", "[2606:4700:4700::1111]:53"）
+//
+// Returns:
+//   - string: "IPアドレス:ポート" の形式のアドレス
+//   - error: IPアドレスではない場合、またはポートが無効な場合
+func ParseResolver(resolver string) (string, error) {
+	resolver = strings.TrimSpace(resolver)
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil {
+		// ポートなしの IPv4 / IPv6 アドレス
+		host, port = strings.Trim(resolver, "[]"), defaultDNSPort
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("リゾルバー %q は IPアドレスで指定してください (例: \"1.1.1.1:53\")", resolver)
+	}
+	if p, err := net.LookupPort("udp", port); err != nil || p <= 0 {
+		return "", fmt.Errorf("リゾルバー %q のポートが無効です", resolver)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// NewResolver は、network.resolvers の設定で名前解決する net.Resolver を返します。
+// HTTP 以外で名前解決する場合（接続の確認など）も、Transport と同じリゾルバーを使うために使用します。
+//
+// Parameters:
+//   - resolvers: DNS サーバーのアドレス（空の場合はシステムのリゾルバー）
+//
+// Returns:
+//   - *net.Resolver: 名前解決に使う Resolver
+//   - error: アドレスが無効な場合
+func NewResolver(resolvers []string) (*net.Resolver, error) {
+	if len(resolvers) == 0 {
+		return net.DefaultResolver, nil
+	}
+	return newResolver(resolvers)
+}
+
+// newResolver は、指定したリゾルバーに問い合わせる net.Resolver を作成します。
+// 問い合わせるたびに次のリゾルバーを使うため、応答しないリゾルバーがあっても再試行で別のリゾルバーに切り替わります。
+func newResolver(resolvers []string) (*net.Resolver, error) {
+	addrs := make([]string, 0, len(resolvers))
+	for _, r := range resolvers {
+		addr, err := ParseResolver(r)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+
+	var next atomic.Uint32
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			addr := addrs[int(next.Add(1)-1)%len(addrs)]
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}, nil
+}
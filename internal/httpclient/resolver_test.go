@@ -0,0 +1,106 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// serveDNS は、すべての A レコードの問い合わせに 127.0.0.1 を返すテスト用の DNS サーバーを起動します。
+func serveDNS(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("DNS サーバーを起動できません: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	queries := new(atomic.Int32)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			queries.Add(1)
+			query := buf[:n]
+			// ヘッダー（12バイト）の後の質問セクションの終わりを探す
+			end := 12
+			for end < n && query[end] != 0 {
+				end += int(query[end]) + 1
+			}
+			end += 5 // 名前の終端 + QTYPE + QCLASS
+			qtype := binary.BigEndian.Uint16(query[end-4 : end-2])
+
+			resp := append([]byte(nil), query[:end]...)
+			resp[2], resp[3] = 0x81, 0x80 // 応答、再帰可能、NOERROR
+			binary.BigEndian.PutUint16(resp[6:8], 0)
+			binary.BigEndian.PutUint16(resp[8:10], 0)
+			binary.BigEndian.PutUint16(resp[10:12], 0)
+			if qtype == 1 {
+				binary.BigEndian.PutUint16(resp[6:8], 1)
+				resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), queries
+}
+
+// TestNewTransport_Resolvers は、指定したリゾルバーで接続先の名前を解決することをテストします。
+func TestNewTransport_Resolvers(t *testing.T) {
+	resolver, queries := serveDNS(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("192.0.2.1"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	transport, err := NewTransport(Options{Proxy: ProxyDirect, Resolvers: []string{resolver}})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://ip-source.test:"+port+"/", nil)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("指定したリゾルバーで名前解決して接続できるべき: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "192.0.2.1" {
+		t.Errorf("レスポンス = %q", body)
+	}
+	if queries.Load() == 0 {
+		t.Error("指定したリゾルバーに問い合わせるべき")
+	}
+}
+
+// TestParseResolver は、リゾルバーのアドレスの形式をテストします。
+func TestParseResolver(t *testing.T) {
+	tests := map[string]string{
+		"1.1.1.1":                   "1.1.1.1:53",
+		"8.8.8.8:5353":              "8.8.8.8:5353",
+		"2606:4700:4700::1111":      "[2606:4700:4700::1111]:53",
+		"[2606:4700:4700::1111]:53": "[2606:4700:4700::1111]:53",
+		" [2606:4700:4700::1111] ":  "[2606:4700:4700::1111]:53",
+	}
+	for in, want := range tests {
+		got, err := ParseResolver(in)
+		if err != nil || got != want {
+			t.Errorf("ParseResolver(%q) = %q, %v, 期待: %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"dns.google", "1.1.1.1:dns-port", ""} {
+		if _, err := ParseResolver(in); err == nil {
+			t.Errorf("ParseResolver(%q) はエラーになるべき", in)
+		}
+	}
+}
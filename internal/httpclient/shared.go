@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// shared は、プロセス全体で共有する Transport です。
+var shared struct {
+	mu sync.Mutex
+
+	// key は、transport を作成した通信設定を文字列にしたものです
+	key string
+
+	// transport は、最後に Shared で作成した Transport です
+	transport *http.Transport
+}
+
+// Shared は、プロセス全体で共有する、通信設定を反映した http.Transport を返します。
+// 同じ通信設定で呼び出した場合は同じ Transport を返すため、IP取得・DuckDNS・プロバイダーなどの通信で接続を再利用できます。
+// 設定の再読み込みなどで通信設定が変わった場合は新しい Transport を作成し、古い Transport のアイドル中の接続を閉じます。
+//
+// Parameters:
+//   - opts: 通信設定
+//
+// Returns:
+//   - *http.Transport: 共有する Transport
+//   - error: 設定が無効な場合（共有する Transport は変わりません）
+func Shared(opts Options) (*http.Transport, error) {
+	key := fmt.Sprintf("%#v", opts)
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.transport != nil && shared.key == key {
+		return shared.transport, nil
+	}
+
+	transport, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	if shared.transport != nil {
+		shared.transport.CloseIdleConnections()
+	}
+	shared.key = key
+	shared.transport = transport
+	return transport, nil
+}
+
+// SharedRoundTripper は、リクエストごとに、最後に Shared で作成した Transport で送信する http.RoundTripper です。
+// Shared をまだ呼び出していない場合は http.DefaultTransport で送信します。
+// 作成した後に通信設定が変わっても、新しい Transport で送信します。
+var SharedRoundTripper http.RoundTripper = sharedRoundTripper{}
+
+// sharedRoundTripper は、SharedRoundTripper の実装です。
+type sharedRoundTripper struct{}
+
+// RoundTrip は、共有する Transport でリクエストを送信します。
+func (sharedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return current().RoundTrip(req)
+}
+
+// CloseIdleConnections は、共有する Transport のアイドル中の接続を閉じます。
+func (sharedRoundTripper) CloseIdleConnections() {
+	current().CloseIdleConnections()
+}
+
+// current は、最後に Shared で作成した Transport を返します（まだない場合は http.DefaultTransport）。
+func current() *http.Transport {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.transport == nil {
+		return http.DefaultTransport.(*http.Transport)
+	}
+	return shared.transport
+}
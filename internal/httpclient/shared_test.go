@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestShared は、同じ通信設定では同じ Transport を返し、設定が変わると作り直すことをテストします。
+func TestShared(t *testing.T) {
+	first, err := Shared(Options{Proxy: "direct"})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	again, err := Shared(Options{Proxy: "direct"})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	if again != first {
+		t.Error("同じ通信設定では同じ Transport を返すべき")
+	}
+
+	changed, err := Shared(Options{Proxy: "http://proxy.example.com:8080"})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	if changed == first {
+		t.Error("通信設定が変わったら Transport を作り直すべき")
+	}
+	if current() != changed {
+		t.Error("SharedRoundTripper は最後に作成した Transport を使うべき")
+	}
+
+	if _, err := Shared(Options{Proxy: "ftp://proxy.example.com"}); err == nil {
+		t.Error("無効な通信設定はエラーになるべき")
+	}
+	if current() != changed {
+		t.Error("無効な通信設定では共有する Transport を変えないべき")
+	}
+}
+
+// TestSharedRoundTripper は、SharedRoundTripper が共有する Transport でリクエストを送信することをテストします。
+func TestSharedRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	if _, err := Shared(Options{Proxy: "direct"}); err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	client := &http.Client{Transport: SharedRoundTripper}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("リクエストに失敗しました: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("ステータス = %d, 200 であるべき", resp.StatusCode)
+	}
+	client.CloseIdleConnections()
+}
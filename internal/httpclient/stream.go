@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// EventDecoder は、watch API などの1行に1つの JSON を書くストリームを、1件ずつ最大サイズまで読み込んでデコードします。
+// 接続を開いたまま読み続けるストリームでも、誤動作や悪意のあるサーバーの巨大なイベントでメモリを使い切らないようにします。
+type EventDecoder struct {
+	scanner *bufio.Scanner
+	limit   int64
+}
+
+// NewEventDecoder は、1件ごとの最大サイズを limit にした EventDecoder をつくります。
+//
+// Parameters:
+//   - r: ストリームのレスポンスボディ
+//   - limit: 1件の JSON の最大サイズ（バイト）。0 以下の場合は DefaultMaxResponseSize
+//
+// Returns:
+//   - *EventDecoder: ストリームを1件ずつデコードする EventDecoder
+func NewEventDecoder(r io.Reader, limit int64) *EventDecoder {
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	scanner := bufio.NewScanner(r)
+	// 改行も含めて1件が収まるように、上限より1バイト大きくする（最初のバッファーも上限を超えないようにする）
+	size := int(limit) + 1
+	scanner.Buffer(make([]byte, 0, min(size, 4096)), size)
+	return &EventDecoder{scanner: scanner, limit: limit}
+}
+
+// Decode は、ストリームの次の1件を v にデコードします。空の行は読み飛ばします。
+//
+// Parameters:
+//   - v: デコード先
+//
+// Returns:
+//   - error: ストリームが終わった場合は io.EOF、1件が最大サイズを超えた場合は *ResponseTooLargeError、
+//     読み込みやデコードに失敗した場合はそのエラー
+func (d *EventDecoder) Decode(v any) error {
+	for d.scanner.Scan() {
+		line := bytes.TrimSpace(d.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return json.Unmarshal(line, v)
+	}
+	if err := d.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return &ResponseTooLargeError{Limit: d.limit}
+		}
+		return err
+	}
+	return io.EOF
+}
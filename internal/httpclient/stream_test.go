@@ -0,0 +1,39 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestEventDecoder は、1行に1つの JSON を順にデコードし、終わったら io.EOF を返すことをテストします。
+func TestEventDecoder(t *testing.T) {
+	d := NewEventDecoder(strings.NewReader("{\"n\":1}\n\n{\"n\":2}\n"), 0)
+	for want := 1; want <= 2; want++ {
+		var v struct{ N int }
+		if err := d.Decode(&v); err != nil || v.N != want {
+			t.Fatalf("Decode() = %+v, %v (期待: %d)", v, err, want)
+		}
+	}
+	var v struct{}
+	if err := d.Decode(&v); !errors.Is(err, io.EOF) {
+		t.Errorf("ストリームが終わったら io.EOF を返すべき: %v", err)
+	}
+}
+
+// TestEventDecoder_TooLarge は、最大サイズを超えるイベントが ResponseTooLargeError になることをテストします。
+func TestEventDecoder_TooLarge(t *testing.T) {
+	stream := "{\"n\":1}\n{\"s\":\"" + strings.Repeat("a", 100) + "\"}\n"
+	d := NewEventDecoder(strings.NewReader(stream), 20)
+
+	var v map[string]any
+	if err := d.Decode(&v); err != nil {
+		t.Fatalf("上限より小さいイベントはデコードできるべき: %v", err)
+	}
+	err := d.Decode(&v)
+	var tooLarge *ResponseTooLargeError
+	if !errors.Is(err, ErrResponseTooLarge) || !errors.As(err, &tooLarge) || tooLarge.Limit != 20 {
+		t.Errorf("ResponseTooLargeError になるべき: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tlsVersions は、TLSMinVersion に指定できる TLS のバージョンです。
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion は、TLS のバージョンの文字列を解析します。
+//
+// Parameters:
+//   - version: TLS のバージョン（"1.0", "1.1", "1.2", "1.3"）。空の場合は 0
+//
+// Returns:
+//   - uint16: crypto/tls の TLS のバージョン（空の場合は 0 で、Go の既定値を使います）
+//   - error: 対応していないバージョンの場合
+func ParseTLSVersion(version string) (uint16, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "TLS")
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("TLS のバージョン %q に対応していません (1.0, 1.1, 1.2, 1.3 のいずれかを指定してください)", version)
+	}
+	return v, nil
+}
+
+// LoadCertPool は、システムの証明書に PEM 形式の CA 証明書のファイルを追加した証明書プールを作成します。
+// TLS を復号するプロキシの CA や、信頼ストアが古い機器でも接続できるようにします。
+//
+// Parameters:
+//   - caFile: PEM 形式の CA 証明書のファイルのパス
+//
+// Returns:
+//   - *x509.CertPool: システムの証明書と caFile の証明書を含む証明書プール
+//   - error: ファイルを読み込めない場合、または証明書が含まれていない場合
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("CA 証明書のファイルを読み込めません: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA 証明書のファイル %q に PEM 形式の証明書が含まれていません", caFile)
+	}
+	return pool, nil
+}
+
+// newTLSConfig は、CA 証明書と TLS の最小バージョンを反映した tls.Config を作成します。
+// どちらも指定されていない場合は nil を返し、Go の既定の設定を使います。
+func newTLSConfig(caFile, minVersion string) (*tls.Config, error) {
+	if caFile == "" && minVersion == "" {
+		return nil, nil
+	}
+
+	version, err := ParseTLSVersion(minVersion)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: version}
+	if caFile != "" {
+		if config.RootCAs, err = LoadCertPool(caFile); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,111 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCA は、テスト用の TLS サーバーの証明書を PEM 形式のファイルに書き出します。
+func writeCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0o600); err != nil {
+		t.Fatalf("CA 証明書を書き出せません: %v", err)
+	}
+	return caFile
+}
+
+// TestNewTransport_CAFile は、指定した CA 証明書で署名されたサーバーに接続できることをテストします。
+func TestNewTransport_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	transport, err := NewTransport(Options{Proxy: ProxyDirect})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	if _, err := (&http.Client{Transport: transport}).Get(server.URL); err == nil {
+		t.Fatal("CA 証明書を指定しない場合は証明書の検証に失敗するべき")
+	}
+
+	transport, err = NewTransport(Options{Proxy: ProxyDirect, CAFile: writeCA(t, server)})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("指定した CA 証明書で接続できるべき: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// TestNewTransport_TLSMinVersion は、TLS の最小バージョンより古いサーバーに接続できないことをテストします。
+func TestNewTransport_TLSMinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	caFile := writeCA(t, server)
+
+	transport, err := NewTransport(Options{Proxy: ProxyDirect, CAFile: caFile, TLSMinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	if _, err := (&http.Client{Transport: transport}).Get(server.URL); err == nil {
+		t.Error("TLS 1.2 までのサーバーには接続できないべき")
+	}
+
+	transport, err = NewTransport(Options{Proxy: ProxyDirect, CAFile: caFile, TLSMinVersion: "1.2"})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("TLS 1.2 のサーバーに接続できるべき: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// TestNewTransport_InvalidTLS は、無効な TLS の設定がエラーになることをテストします。
+func TestNewTransport_InvalidTLS(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0o600)
+
+	tests := map[string]Options{
+		"に対応していません":           {TLSMinVersion: "1.4"},
+		"読み込めません":             {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"PEM 形式の証明書が含まれていません": {CAFile: notPEM},
+	}
+	for want, opts := range tests {
+		if _, err := NewTransport(opts); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: エラー = %v, 期待値 %q を含む", opts, err, want)
+		}
+	}
+}
+
+// TestParseTLSVersion は、TLS のバージョンの表記を解析できることをテストします。
+func TestParseTLSVersion(t *testing.T) {
+	tests := map[string]uint16{
+		"":       0,
+		"1.2":    tls.VersionTLS12,
+		"TLS1.3": tls.VersionTLS13,
+		" 1.3 ":  tls.VersionTLS13,
+	}
+	for input, want := range tests {
+		got, err := ParseTLSVersion(input)
+		if err != nil || got != want {
+			t.Errorf("ParseTLSVersion(%q) = %x, %v; 期待値 %x", input, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package httpclient は、DuckDNS クライアントと IP取得で共有する HTTP の通信設定を提供します。
+// プロキシや DNS のリゾルバー、TLS などのネットワークの設定を1か所で http.Transport に反映します。
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProxyDirect は、環境変数 HTTP_PROXY / HTTPS_PROXY を無視して直接接続する場合に Proxy に指定する値です。
+const ProxyDirect = "direct"
+
+// Options は、HTTP の通信設定です。
+type Options struct {
+	// Proxy は、すべてのリクエストで使うプロキシの URL です（http / https / socks5 / socks5h）
+	// 空の場合は環境変数 HTTP_PROXY / HTTPS_PROXY / NO_PROXY に従い、"direct" の場合はプロキシを使いません
+	Proxy string
+
+	// Resolvers は、接続先の名前解決に使う DNS サーバーのアドレスです（例: "1.1.1.1:53"）
+	// 空の場合はシステムのリゾルバーを使います
+	Resolvers []string
+
+	// DNSFallback は、名前解決に失敗した場合に DNS over HTTPS と、最後に接続できたアドレスで接続し直すかどうかです
+	DNSFallback bool
+
+	// DoHServers は、DNSFallback で使う DNS over HTTPS の JSON API の URL です（空の場合は DefaultDoHServers）
+	DoHServers []string
+
+	// CAFile は、システムの証明書に加えて信頼する PEM 形式の CA 証明書のファイルです
+	// TLS を復号する社内プロキシや、信頼ストアが古い機器で使います
+	CAFile string
+
+	// TLSMinVersion は、TLS の最小バージョンです（"1.0", "1.1", "1.2", "1.3"。空の場合は Go の既定値）
+	TLSMinVersion string
+
+	// Pins は、ホスト名ごとにピン留めする証明書の公開鍵です（"sha256/<base64>" 形式の SPKI のハッシュ値）
+	// ピン留めしたホストには、証明書チェーンのどれかの公開鍵が一致する場合だけ接続します
+	Pins map[string][]string
+
+	// MaxIdleConns は、すべてのホストで保持するアイドル中の接続の最大数です（0 の場合は Go の既定値の 100）
+	MaxIdleConns int
+
+	// IdleConnTimeout は、アイドル中の接続を閉じるまでの時間です（0 の場合は Go の既定値の 90 秒）
+	IdleConnTimeout time.Duration
+
+	// DisableKeepAlives は、接続を再利用せずにリクエストごとに接続し直すかどうかです
+	DisableKeepAlives bool
+}
+
+// NewTransport は、通信設定を反映した http.Transport を作成します。
+// http.DefaultTransport の複製に設定を反映するため、指定していないタイムアウトや接続の再利用は既定の動作のままです。
+//
+// Parameters:
+//   - opts: 通信設定
+//
+// Returns:
+//   - *http.Transport: 作成された Transport
+//   - error: 設定が無効な場合
+func NewTransport(opts Options) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxy, err := ProxyFunc(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+		// ホストごとの上限（既定値は2）も、全体の上限を超えないようにする
+		if opts.MaxIdleConns < http.DefaultMaxIdleConnsPerHost {
+			transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+		}
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+
+	tlsConfig, err := newTLSConfig(opts.CAFile, opts.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Pins) > 0 {
+		verifier, err := newPinVerifier(opts.Pins)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.VerifyConnection = verifier.verifyConnection
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if len(opts.Resolvers) > 0 {
+		if dialer.Resolver, err = newResolver(opts.Resolvers); err != nil {
+			return nil, err
+		}
+	}
+	transport.DialContext = dialer.DialContext
+
+	if opts.DNSFallback {
+		servers := opts.DoHServers
+		if len(servers) == 0 {
+			servers = DefaultDoHServers
+		}
+		for _, server := range servers {
+			if err := ValidateDoHServer(server); err != nil {
+				return nil, err
+			}
+		}
+		// DNS over HTTPS の問い合わせは、フォールバックなしの Transport で送る
+		doh := &dohResolver{servers: servers, client: &http.Client{Timeout: dohTimeout, Transport: transport.Clone()}}
+		transport.DialContext = newFallbackDialer(dialer.DialContext, doh).DialContext
+	}
+
+	return transport, nil
+}
+
+// ProxyFunc は、プロキシの設定から http.Transport の Proxy に設定する関数を返します。
+//
+// Parameters:
+//   - proxy: プロキシの URL、"direct"、または空（環境変数に従う）
+//
+// Returns:
+//   - func(*http.Request) (*url.URL, error): リクエストごとにプロキシを決める関数（直接接続の場合は nil）
+//   - error: URL が無効な場合、または対応していないスキームの場合
+func ProxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	proxy = strings.TrimSpace(proxy)
+	switch {
+	case proxy == "":
+		return http.ProxyFromEnvironment, nil
+	case strings.EqualFold(proxy, ProxyDirect):
+		return nil, nil
+	}
+
+	u, err := ParseProxy(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(u), nil
+}
+
+// ParseProxy は、プロキシの URL を解析して検証します。
+//
+// Parameters:
+//   - proxy: プロキシの URL（例: "http://proxy.example.com:8080", "socks5://127.0.0.1:9050"）
+//
+// Returns:
+//   - *url.URL: 解析した URL
+//   - error: URL が無効な場合、または対応していないスキームの場合
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("プロキシの URL %q を解析できません: %w", proxy, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("プロキシの URL %q のスキームに対応していません (http, https, socks5, socks5h のいずれかを指定してください)", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("プロキシの URL %q にホストがありません", proxy)
+	}
+	return u, nil
+}
@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewTransport_Proxy は、プロキシの設定がすべてのリクエストに使われることをテストします。
+func TestNewTransport_Proxy(t *testing.T) {
+	for _, proxy := range []string{"http://proxy.example.com:8080", "socks5://127.0.0.1:9050"} {
+		transport, err := NewTransport(Options{Proxy: proxy})
+		if err != nil {
+			t.Fatalf("%s: Transport の作成に失敗しました: %v", proxy, err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "https://www.duckdns.org/update", nil)
+		got, err := transport.Proxy(req)
+		if err != nil || got == nil || got.String() != proxy {
+			t.Errorf("%s: プロキシ = %v (エラー: %v)", proxy, got, err)
+		}
+	}
+}
+
+// TestNewTransport_Direct は、"direct" の場合は環境変数のプロキシも使わないことをテストします。
+func TestNewTransport_Direct(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy.example.com:3128")
+
+	transport, err := NewTransport(Options{Proxy: "direct"})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	if transport.Proxy != nil {
+		t.Error("direct の場合はプロキシを使わないべき")
+	}
+}
+
+// TestNewTransport_IdleConns は、アイドル中の接続と keep-alive の設定が反映されることをテストします。
+func TestNewTransport_IdleConns(t *testing.T) {
+	transport, err := NewTransport(Options{})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	defaults := http.DefaultTransport.(*http.Transport)
+	if transport.MaxIdleConns != defaults.MaxIdleConns || transport.IdleConnTimeout != defaults.IdleConnTimeout || transport.DisableKeepAlives {
+		t.Error("指定しない場合は既定の設定のままにするべき")
+	}
+
+	transport, err = NewTransport(Options{MaxIdleConns: 1, IdleConnTimeout: 10 * time.Second, DisableKeepAlives: true})
+	if err != nil {
+		t.Fatalf("Transport の作成に失敗しました: %v", err)
+	}
+	if transport.MaxIdleConns != 1 || transport.MaxIdleConnsPerHost != 1 {
+		t.Errorf("アイドル中の接続の最大数 = %d (ホストごと %d), 1 であるべき", transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 10*time.Second {
+		t.Errorf("アイドル中の接続を閉じるまでの時間 = %v, 10s であるべき", transport.IdleConnTimeout)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("keep-alive を無効にするべき")
+	}
+}
+
+// TestParseProxy_Invalid は、対応していないプロキシの URL がエラーになることをテストします。
+func TestParseProxy_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"ftp://proxy.example.com": "スキームに対応していません",
+		"proxy.example.com:8080":  "スキームに対応していません",
+		"http://":                 "ホストがありません",
+	}
+	for in, want := range tests {
+		if _, err := ParseProxy(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseProxy(%q) のエラーに %q が含まれるべき: %v", in, want, err)
+		}
+	}
+}
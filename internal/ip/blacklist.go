@@ -0,0 +1,112 @@
+package ip
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DefaultFailureThreshold は、ソースを一時的に使わないようにするまでの連続失敗回数の既定値です。
+const DefaultFailureThreshold = 3
+
+// DefaultCooldown は、連続で失敗したソースを一時的に使わない時間の既定値です。
+const DefaultCooldown = 10 * time.Minute
+
+// SourceRecorder は、IP取得ソースごとの連続失敗回数と、一時的に使わない期限を記録するインターフェースです。
+// status コマンドなどで、使えなくなっているソースを確認できるようにするために使用します。
+type SourceRecorder interface {
+	// RecordSource は、ソースの状況が変わったときに呼び出されます
+	// blacklistedUntil は一時的に使わない期限（使える場合はゼロ値）、err は最後の失敗のエラーです（成功した場合は nil）
+	RecordSource(url string, failures int, blacklistedUntil time.Time, err error)
+}
+
+// blacklistWait は、連続で失敗したため一時的に使わないソースの、残りの時間を返します（使える場合は 0）。
+func (mf *MultipleFetcher) blacklistWait(url string) time.Duration {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	until, ok := mf.blacklistedUntil[url]
+	if !ok {
+		return 0
+	}
+	if wait := time.Until(until); wait > 0 {
+		return wait
+	}
+	delete(mf.blacklistedUntil, url)
+	return 0
+}
+
+// allBlacklisted は、すべてのソースを一時的に使わないようにしているかどうかを返します。
+// すべてのソースを使わないと IPアドレスを取得できないため、その場合は期限を無視してすべて試します。
+func (mf *MultipleFetcher) allBlacklisted() bool {
+	if mf.FailureThreshold <= 0 {
+		return false
+	}
+	for _, url := range mf.URLs {
+		if mf.blacklistWait(url) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// recordFailures は、ソースの連続失敗回数を数え、FailureThreshold 回続いたら Cooldown の間使わないようにします。
+// 期限が過ぎて試したソースがまた失敗した場合は、すぐに使わないようにします。
+// キャンセルによる失敗は、ソースの状態と関係ないため数えません。
+func (mf *MultipleFetcher) recordFailures(ctx context.Context, url string, err error) {
+	if mf.FailureThreshold <= 0 || (err != nil && ctx.Err() != nil) {
+		return
+	}
+
+	mf.mu.Lock()
+	previous := mf.failures[url]
+	if err == nil {
+		delete(mf.failures, url)
+		delete(mf.blacklistedUntil, url)
+		mf.mu.Unlock()
+		if previous > 0 {
+			if previous >= mf.FailureThreshold {
+				slog.Info("IP取得ソースが復旧したため、また使います",
+					"url", url,
+				)
+			}
+			mf.recordSource(url, 0, time.Time{}, nil)
+		}
+		return
+	}
+
+	if mf.failures == nil {
+		mf.failures = make(map[string]int)
+	}
+	failures := previous + 1
+	mf.failures[url] = failures
+	var until time.Time
+	if failures >= mf.FailureThreshold {
+		cooldown := mf.Cooldown
+		if cooldown <= 0 {
+			cooldown = DefaultCooldown
+		}
+		until = time.Now().Add(cooldown)
+		if mf.blacklistedUntil == nil {
+			mf.blacklistedUntil = make(map[string]time.Time)
+		}
+		mf.blacklistedUntil[url] = until
+	}
+	mf.mu.Unlock()
+
+	if !until.IsZero() {
+		slog.Warn("IP取得ソースが続けて失敗したため、しばらく使いません",
+			"url", url,
+			"failures", failures,
+			"until", until.Format(time.RFC3339),
+		)
+	}
+	mf.recordSource(url, failures, until, err)
+}
+
+// recordSource は、Recorder が設定されていれば、ソースの状況を記録します。
+func (mf *MultipleFetcher) recordSource(url string, failures int, blacklistedUntil time.Time, err error) {
+	if mf.Recorder != nil {
+		mf.Recorder.RecordSource(url, failures, blacklistedUntil, err)
+	}
+}
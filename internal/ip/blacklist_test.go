@@ -0,0 +1,142 @@
+package ip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sourceRecord は、テスト用の SourceRecorder が記録した1件です。
+type sourceRecord struct {
+	url              string
+	failures         int
+	blacklistedUntil time.Time
+	err              error
+}
+
+// fakeSourceRecorder は、記録した内容を保持するテスト用の SourceRecorder です。
+type fakeSourceRecorder struct {
+	mu      sync.Mutex
+	records []sourceRecord
+}
+
+func (r *fakeSourceRecorder) RecordSource(url string, failures int, blacklistedUntil time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, sourceRecord{url, failures, blacklistedUntil, err})
+}
+
+// newFailingServer は、リクエストを数えて 500 を返すテスト用のサーバーを作成します。
+func newFailingServer(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestMultipleFetcher_Blacklist は、連続で失敗したソースを Cooldown の間スキップすることをテストします。
+func TestMultipleFetcher_Blacklist(t *testing.T) {
+	var hits atomic.Int32
+	failing := newFailingServer(t, &hits)
+	urls := append([]string{failing.URL}, newIPServers(t, "198.51.100.7")...)
+
+	recorder := &fakeSourceRecorder{}
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.FailureThreshold = 2
+	fetcher.Cooldown = time.Hour
+	fetcher.Recorder = recorder
+	for i := 0; i < 5; i++ {
+		ip, err := fetcher.Fetch(context.Background())
+		if err != nil || ip != "198.51.100.7" {
+			t.Fatalf("%d 回目: Fetch() = %q, %v", i+1, ip, err)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("失敗するソースへの問い合わせ回数 = %d, 期待値 2", hits.Load())
+	}
+
+	if len(recorder.records) != 2 {
+		t.Fatalf("記録の件数 = %d, 期待値 2", len(recorder.records))
+	}
+	last := recorder.records[1]
+	if last.url != failing.URL || last.failures != 2 || last.blacklistedUntil.IsZero() || last.err == nil {
+		t.Errorf("最後の記録 = %+v, 期待: 2回失敗して使わない期限がある", last)
+	}
+}
+
+// TestMultipleFetcher_BlacklistExpired は、Cooldown が過ぎたらまた問い合わせ、成功したら連続失敗回数を戻すことをテストします。
+func TestMultipleFetcher_BlacklistExpired(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+	urls := append([]string{server.URL}, newIPServers(t, "198.51.100.8")...)
+
+	recorder := &fakeSourceRecorder{}
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.FailureThreshold = 1
+	fetcher.Cooldown = 20 * time.Millisecond
+	fetcher.Recorder = recorder
+	if ip, _ := fetcher.Fetch(context.Background()); ip != "198.51.100.8" {
+		t.Fatalf("Fetch() = %q, 期待値 198.51.100.8", ip)
+	}
+
+	fail.Store(false)
+	time.Sleep(30 * time.Millisecond)
+	if ip, _ := fetcher.Fetch(context.Background()); ip != "198.51.100.7" {
+		t.Fatalf("Cooldown の後の Fetch() = %q, 期待値 198.51.100.7", ip)
+	}
+
+	last := recorder.records[len(recorder.records)-1]
+	if last.failures != 0 || !last.blacklistedUntil.IsZero() || last.err != nil {
+		t.Errorf("最後の記録 = %+v, 期待: 復旧した", last)
+	}
+}
+
+// TestMultipleFetcher_BlacklistAll は、すべてのソースを使わないようにしている場合は、期限を無視して試すことをテストします。
+func TestMultipleFetcher_BlacklistAll(t *testing.T) {
+	var hits atomic.Int32
+	failing := newFailingServer(t, &hits)
+
+	fetcher := NewMultipleFetcher([]string{failing.URL})
+	fetcher.FailureThreshold = 1
+	fetcher.Cooldown = time.Hour
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.Fetch(context.Background()); err == nil {
+			t.Fatalf("%d 回目: 失敗するべき", i+1)
+		}
+	}
+	if hits.Load() != 3 {
+		t.Errorf("問い合わせ回数 = %d, 期待値 3", hits.Load())
+	}
+}
+
+// TestMultipleFetcher_BlacklistDisabled は、FailureThreshold が 0 の場合は失敗したソースもスキップしないことをテストします。
+func TestMultipleFetcher_BlacklistDisabled(t *testing.T) {
+	var hits atomic.Int32
+	failing := newFailingServer(t, &hits)
+	urls := append([]string{failing.URL}, newIPServers(t, "198.51.100.7")...)
+
+	fetcher := NewMultipleFetcher(urls)
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.Fetch(context.Background()); err != nil {
+			t.Fatalf("%d 回目: 取得に失敗しました: %v", i+1, err)
+		}
+	}
+	if hits.Load() != 3 {
+		t.Errorf("問い合わせ回数 = %d, 期待値 3", hits.Load())
+	}
+}
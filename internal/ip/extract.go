@@ -0,0 +1,144 @@
+package ip
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Extractor は、IP取得ソースのレスポンスボディから IPアドレスの文字列を取り出します。
+// IPアドレスだけを返さないソース（JSON や HTML など）を使えるようにします。
+type Extractor interface {
+	// Extract は、レスポンスボディから IPアドレスの文字列を取り出します。
+	//
+	// Parameters:
+	//   - body: レスポンスボディ
+	//   - family: 取得するIPアドレスの種類（候補が複数ある場合に選ぶために使います）
+	//
+	// Returns:
+	//   - string: 取り出した文字列（検証は呼び出し側で行います）
+	//   - error: 取り出せなかった場合
+	Extract(body []byte, family Family) (string, error)
+}
+
+// ParseExtractor は、"json:<パス>" または "regex:<正規表現>" 形式の指定から Extractor を作成します。
+//
+// Parameters:
+//   - spec: 取り出し方の指定（例: "json:ip", "json:data.addresses.0", `regex:WAN IP: ([0-9.]+)`）
+//
+// Returns:
+//   - Extractor: 作成された Extractor（spec が空の場合は nil で、ボディ全体を IPアドレスとして扱います）
+//   - error: 形式が無効な場合
+func ParseExtractor(spec string) (Extractor, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("取り出し方 %q は json:<パス> または regex:<正規表現> の形式で指定してください", spec)
+	}
+	switch kind {
+	case "json":
+		return newJSONPath(arg)
+	case "regex":
+		return newRegexExtractor(arg)
+	default:
+		return nil, fmt.Errorf("取り出し方 %q の種類 %q に対応していません (json, regex のいずれかを指定してください)", spec, kind)
+	}
+}
+
+// jsonPath は、JSON のレスポンスから "." 区切りのパスのフィールドを取り出す Extractor です。
+// 配列の要素は番号で指定します（例: "data.addresses.0"）。
+type jsonPath []string
+
+// newJSONPath は、"." 区切りのパスから jsonPath を作成します。
+func newJSONPath(path string) (jsonPath, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("JSON のパスが空です")
+	}
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("JSON のパス %q に空の要素があります", path)
+		}
+	}
+	return jsonPath(keys), nil
+}
+
+// Extract は、JSON のレスポンスからパスのフィールドの文字列を取り出します。
+func (p jsonPath) Extract(body []byte, _ Family) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("レスポンスを JSON として解析できません: %w", err)
+	}
+
+	for i, key := range p {
+		switch v := value.(type) {
+		case map[string]any:
+			field, ok := v[key]
+			if !ok {
+				return "", fmt.Errorf("JSON のフィールド %q が見つかりません", strings.Join(p[:i+1], "."))
+			}
+			value = field
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return "", fmt.Errorf("JSON の配列の要素 %q が見つかりません", strings.Join(p[:i+1], "."))
+			}
+			value = v[index]
+		default:
+			return "", fmt.Errorf("JSON の %q はオブジェクトでも配列でもありません", strings.Join(p[:i], "."))
+		}
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("JSON のフィールド %q は文字列ではありません", strings.Join(p, "."))
+	}
+	return s, nil
+}
+
+// regexExtractor は、正規表現に一致した部分から IPアドレスを取り出す Extractor です。
+// ルーターの状態ページなど、HTML や文章を返すソースに使います。
+// 1つ目のキャプチャーグループ（ない場合は一致した部分全体）のうち、最初に有効な IPアドレスを使います。
+type regexExtractor struct {
+	re *regexp.Regexp
+}
+
+// newRegexExtractor は、正規表現から regexExtractor を作成します。
+func newRegexExtractor(pattern string) (*regexExtractor, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("正規表現が空です")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("正規表現 %q が無効です: %w", pattern, err)
+	}
+	return &regexExtractor{re: re}, nil
+}
+
+// Extract は、正規表現に一致した部分のうち、最初に有効な IPアドレスを返します。
+func (e *regexExtractor) Extract(body []byte, family Family) (string, error) {
+	matches := e.re.FindAllSubmatch(body, -1)
+	if len(matches) == 0 {
+		return "", fmt.Errorf("正規表現 %q に一致する部分がありません", e.re)
+	}
+
+	for _, match := range matches {
+		candidate := match[0]
+		if len(match) > 1 {
+			candidate = match[1]
+		}
+		if ip := strings.TrimSpace(string(candidate)); family.Validate(ip) == nil {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("正規表現 %q に一致する部分に有効な %s アドレスがありません", e.re, family)
+}
@@ -0,0 +1,123 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseExtractor_JSON は、JSON のパスのフィールドを取り出せることをテストします。
+func TestParseExtractor_JSON(t *testing.T) {
+	body := []byte(`{"ip":"203.0.113.5","data":{"addresses":["2001:db8::1","198.51.100.1"],"port":443}}`)
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{"json:ip", "203.0.113.5", ""},
+		{"json:data.addresses.1", "198.51.100.1", ""},
+		{"json:country", "", "見つかりません"},
+		{"json:data.addresses.5", "", "見つかりません"},
+		{"json:data.port", "", "文字列ではありません"},
+		{"json:ip.value", "", "オブジェクトでも配列でもありません"},
+	}
+	for _, tt := range tests {
+		extractor, err := ParseExtractor(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: Extractor の作成に失敗しました: %v", tt.spec, err)
+		}
+		got, err := extractor.Extract(body, IPv4)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: エラー = %v, 期待値 %q を含む", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Extract() = %q, %v; 期待値 %q", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+// TestParseExtractor_Invalid は、無効な取り出し方の指定がエラーになることをテストします。
+func TestParseExtractor_Invalid(t *testing.T) {
+	if extractor, err := ParseExtractor(""); extractor != nil || err != nil {
+		t.Errorf("空の指定は nil であるべき: %v, %v", extractor, err)
+	}
+	for _, spec := range []string{"ip", "json:", "json:data..ip", "xml:ip", "regex:", "regex:(["} {
+		if _, err := ParseExtractor(spec); err == nil {
+			t.Errorf("%q はエラーになるべき", spec)
+		}
+	}
+}
+
+// TestHTTPFetcher_JSONExtractor は、JSON を返すソースから IPアドレスを取り出して検証することをテストします。
+func TestHTTPFetcher_JSONExtractor(t *testing.T) {
+	body := `{"ip":"203.0.113.5","country":"JP"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(server.URL)
+	if _, err := fetcher.Fetch(context.Background()); !errors.Is(err, ErrInvalidIP) {
+		t.Fatalf("取り出し方を指定しない場合は ErrInvalidIP になるべき: %v", err)
+	}
+
+	extractor, _ := ParseExtractor("json:ip")
+	fetcher.Options = SourceOptions{Extractor: extractor}
+	ip, err := fetcher.Fetch(context.Background())
+	if err != nil || ip != "203.0.113.5" {
+		t.Errorf("Fetch() = %q, %v; 期待値 203.0.113.5", ip, err)
+	}
+
+	// 取り出した値も IPアドレスとして検証する
+	extractor, _ = ParseExtractor("json:country")
+	fetcher.Options = SourceOptions{Extractor: extractor}
+	if _, err := fetcher.Fetch(context.Background()); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("IPアドレスではない値は ErrInvalidIP になるべき: %v", err)
+	}
+}
+
+// TestParseExtractor_Regex は、正規表現に一致した部分から最初に有効な IPアドレスを取り出すことをテストします。
+func TestParseExtractor_Regex(t *testing.T) {
+	body := []byte(`<tr><td>LAN IP</td><td>192.168.1.1</td></tr>
+<tr><td>WAN IP</td><td>999.1.1.1</td></tr>
+<tr><td>WAN IP</td><td>203.0.113.5</td></tr>
+<tr><td>WAN IPv6</td><td>2001:db8::5</td></tr>`)
+
+	tests := []struct {
+		spec    string
+		family  Family
+		want    string
+		wantErr bool
+	}{
+		// キャプチャーグループの値のうち、無効な IPアドレスは飛ばす
+		{`regex:WAN IP</td><td>([^<]+)<`, IPv4, "203.0.113.5", false},
+		// キャプチャーグループがない場合は一致した部分全体を使い、種類が違うものは飛ばす
+		{`regex:[0-9a-f:.]*:[0-9a-f:.]+`, IPv6, "2001:db8::5", false},
+		{`regex:\d+\.\d+\.\d+\.\d+`, IPv4, "192.168.1.1", false},
+		{`regex:External: (\S+)`, IPv4, "", true},
+		{`regex:WAN IP</td><td>([^<]+)<`, IPv6, "", true},
+	}
+	for _, tt := range tests {
+		extractor, err := ParseExtractor(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: Extractor の作成に失敗しました: %v", tt.spec, err)
+		}
+		got, err := extractor.Extract(body, tt.family)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s (%s): エラーになるべき: %q", tt.spec, tt.family, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s (%s): Extract() = %q, %v; 期待値 %q", tt.spec, tt.family, got, err, tt.want)
+		}
+	}
+}
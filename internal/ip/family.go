@@ -0,0 +1,116 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// Family は、取得するIPアドレスの種類（IPv4 または IPv6）です。
+type Family int
+
+const (
+	// IPv4 は、IPv4アドレスを取得することを表します（デフォルト）
+	IPv4 Family = iota
+
+	// IPv6 は、IPv6アドレスを取得することを表します
+	IPv6
+)
+
+// DefaultIPv4Sources は、IP取得ソースが指定されていない場合に使うIPv4のソースです。
+var DefaultIPv4Sources = []string{
+	"https://api.ipify.org",
+	"https://ifconfig.me/ip",
+	"https://icanhazip.com",
+}
+
+// DefaultIPv6Sources は、IP取得ソースが指定されていない場合に使うIPv6のソースです。
+// いずれも IPv6 でのみ接続できるホスト名を使用します。
+var DefaultIPv6Sources = []string{
+	"https://api6.ipify.org",
+	"https://ipv6.icanhazip.com",
+	"https://v6.ident.me",
+}
+
+// String は、種類を "ipv4" または "ipv6" で返します。
+func (f Family) String() string {
+	if f == IPv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// DefaultSources は、この種類の既定のIP取得ソースを返します。
+//
+// Returns:
+//   - []string: 既定のIP取得ソースのURLリスト（呼び出し側で変更しても影響しないコピー）
+func (f Family) DefaultSources() []string {
+	if f == IPv6 {
+		return append([]string(nil), DefaultIPv6Sources...)
+	}
+	return append([]string(nil), DefaultIPv4Sources...)
+}
+
+// Validate は、IPアドレスがこの種類のアドレスとして有効かどうかを確認します。
+//
+// Parameters:
+//   - ip: 検証するIPアドレス文字列
+//
+// Returns:
+//   - error: 無効なIPアドレスの場合
+func (f Family) Validate(ip string) error {
+	if f == IPv6 {
+		return ValidateIPv6(ip)
+	}
+	return ValidateIPv4(ip)
+}
+
+// ValidateIPv6 は、IPv6アドレスが有効かどうかを確認します。
+// IPv4アドレスや IPv4射影アドレス (::ffff:192.0.2.1) はエラーになります。
+//
+// Parameters:
+//   - ip: 検証するIPアドレス文字列
+//
+// Returns:
+//   - error: 無効なIPアドレスの場合
+func ValidateIPv6(ip string) error {
+	if ip == "" {
+		return fmt.Errorf("IPアドレスが空です")
+	}
+
+	// ゾーン付きのアドレス (fe80::1%eth0) はグローバルアドレスではないため受け付けない
+	if strings.Contains(ip, "%") {
+		return fmt.Errorf("ゾーン付きのアドレスは使用できません")
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return fmt.Errorf("net.ParseIP による検証に失敗しました")
+	}
+
+	if parsedIP.To4() != nil {
+		return fmt.Errorf("IPv4ではなくIPv6である必要があります")
+	}
+
+	return nil
+}
+
+// newHTTPClient は、種類に合わせたHTTPクライアントを作成します。
+// IPv6 の場合は、ソースが IPv6 で見た送信元アドレスを返すように IPv6 でのみ接続します。
+// base はプロキシなどの通信設定を反映した Transport で、nil の場合は http.DefaultTransport を使用します。
+func newHTTPClient(family Family, timeout time.Duration, base *http.Transport) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if base != nil {
+		client.Transport = base
+	}
+	if family != IPv6 {
+		return client
+	}
+
+	client.Transport = httpclient.ForceNetwork(base, "tcp6")
+	return client
+}
@@ -0,0 +1,142 @@
+package ip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestValidateIPv6 は、IPv6アドレスの検証をテストします。
+func TestValidateIPv6(t *testing.T) {
+	tests := []struct {
+		name    string
+		ip      string
+		wantErr bool
+	}{
+		{name: "有効なIPv6: 2001:db8::1", ip: "2001:db8::1", wantErr: false},
+		{name: "有効なIPv6: 省略なし", ip: "2001:0db8:0000:0000:0000:0000:0000:0001", wantErr: false},
+		{name: "有効なIPv6: ::1", ip: "::1", wantErr: false},
+		{name: "空文字列", ip: "", wantErr: true},
+		{name: "無効: IPv4", ip: "192.168.1.1", wantErr: true},
+		{name: "無効: IPv4射影アドレス", ip: "::ffff:192.0.2.1", wantErr: true},
+		{name: "無効: ゾーン付き", ip: "fe80::1%eth0", wantErr: true},
+		{name: "無効: not-an-ip", ip: "not-an-ip", wantErr: true},
+		{name: "無効: 2001:db8:::1", ip: "2001:db8:::1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIPv6(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIPv6(%q) エラー = %v, 期待するエラー有無 = %v", tt.ip, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestFamily_Validate は、種類ごとの検証をテストします。
+func TestFamily_Validate(t *testing.T) {
+	if err := IPv4.Validate("192.0.2.1"); err != nil {
+		t.Errorf("IPv4 で IPv4アドレスがエラーになりました: %v", err)
+	}
+	if err := IPv4.Validate("2001:db8::1"); err == nil {
+		t.Error("IPv4 で IPv6アドレスがエラーになりませんでした")
+	}
+	if err := IPv6.Validate("2001:db8::1"); err != nil {
+		t.Errorf("IPv6 で IPv6アドレスがエラーになりました: %v", err)
+	}
+	if err := IPv6.Validate("192.0.2.1"); err == nil {
+		t.Error("IPv6 で IPv4アドレスがエラーになりませんでした")
+	}
+}
+
+// TestFamily_String は、種類の文字列表現をテストします。
+func TestFamily_String(t *testing.T) {
+	if IPv4.String() != "ipv4" {
+		t.Errorf("IPv4.String() = %s, 期待: ipv4", IPv4.String())
+	}
+	if IPv6.String() != "ipv6" {
+		t.Errorf("IPv6.String() = %s, 期待: ipv6", IPv6.String())
+	}
+}
+
+// TestFamily_DefaultSources は、既定のソースがコピーで返されることをテストします。
+func TestFamily_DefaultSources(t *testing.T) {
+	sources := IPv6.DefaultSources()
+	if len(sources) != len(DefaultIPv6Sources) {
+		t.Fatalf("ソースの数が一致しません。期待: %d, 実際: %d", len(DefaultIPv6Sources), len(sources))
+	}
+
+	sources[0] = "https://changed.example.com"
+	if DefaultIPv6Sources[0] == "https://changed.example.com" {
+		t.Error("返されたソースを変更すると既定値が変わってしまいます")
+	}
+
+	if got := IPv4.DefaultSources(); got[0] != DefaultIPv4Sources[0] {
+		t.Errorf("IPv4 の既定のソースが一致しません: %v", got)
+	}
+}
+
+// TestHTTPFetcher_Fetch_IPv6Response は、IPv4 の取得で IPv6アドレスが返された場合にエラーになることをテストします。
+func TestHTTPFetcher_Fetch_IPv6Response(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("2001:db8::1\n"))
+	}))
+	defer server.Close()
+
+	if _, err := NewHTTPFetcherForFamily(server.URL, IPv4, time.Second).Fetch(context.Background()); err == nil {
+		t.Error("IPv4 の取得で IPv6アドレスがエラーになりませんでした")
+	}
+}
+
+// TestMultipleFetcher_FetchWithSource_IPv6 は、IPv6 で取得に成功したソースが返されることをテストします。
+func TestMultipleFetcher_FetchWithSource_IPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 のループバックが使えません: %v", err)
+	}
+
+	ipv4Only := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("192.0.2.1"))
+	}))
+	defer ipv4Only.Close()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("2001:db8::1"))
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	fetcher := NewMultipleFetcherForFamily([]string{ipv4Only.URL, server.URL}, IPv6, time.Second)
+	ip, source, err := fetcher.FetchWithSource(context.Background())
+	if err != nil {
+		t.Fatalf("エラーが発生しました: %v", err)
+	}
+	if ip != "2001:db8::1" {
+		t.Errorf("IPが一致しません。期待: 2001:db8::1, 実際: %s", ip)
+	}
+	if source != server.URL {
+		t.Errorf("ソースが一致しません。期待: %s, 実際: %s", server.URL, source)
+	}
+}
+
+// TestMultipleFetcher_FetchWithSource_AllFail は、すべて失敗した場合にソースが空になることをテストします。
+func TestMultipleFetcher_FetchWithSource_AllFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ip, source, err := NewMultipleFetcher([]string{server.URL}).FetchWithSource(context.Background())
+	if err == nil {
+		t.Fatal("エラーが発生しませんでした")
+	}
+	if ip != "" || source != "" {
+		t.Errorf("失敗時は空が返される必要があります。ip: %q, source: %q", ip, source)
+	}
+}
@@ -4,19 +4,54 @@ package ip
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+	"github.com/horitaku/duckdns/internal/ratelimit"
 )
 
 // DefaultHTTPTimeout は、HTTPリクエストのデフォルトタイムアウト設定です。
 const DefaultHTTPTimeout = 10 * time.Second
 
+// ErrInvalidIP は、ソースの応答がIPアドレスとして無効だったことを表すエラーです。
+// 接続やHTTPステータスのエラーと区別するために errors.Is で判定できます。
+var ErrInvalidIP = errors.New("無効なIPアドレス")
+
+// ErrRateLimited は、ソースが 429 (Too Many Requests) を返したことを表すエラーです。
+// 待ち時間は errors.As で RateLimitError を取り出して確認できます。
+var ErrRateLimited = errors.New("IP取得ソースのリクエスト数の制限を超えました")
+
+// RateLimitError は、ソースが 429 を返したことを表すエラーです。
+// errors.Is(err, ErrRateLimited) で判定できます。
+type RateLimitError struct {
+	// URL は、429 を返したソースのURLです
+	URL string
+
+	// RetryAfter は、Retry-After ヘッダーで指定された待ち時間です（指定がない場合は 0）
+	RetryAfter time.Duration
+}
+
+// Error は、ソースのURLと待ち時間を含むエラーメッセージを返します。
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v (URL: %s, Retry-After: %s)", ErrRateLimited, e.URL, e.RetryAfter)
+	}
+	return fmt.Sprintf("%v (URL: %s)", ErrRateLimited, e.URL)
+}
+
+// Is は、target が ErrRateLimited の場合に true を返します。
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
 // Fetcher は、グローバルIPアドレスを取得するためのインターフェースです。
 // 異なるIPソースの実装をサポートするために設計されています。
 type Fetcher interface {
@@ -39,6 +74,15 @@ type HTTPFetcher struct {
 	// URL は、IPアドレスを取得するエンドポイントです
 	URL string
 
+	// Family は、取得するIPアドレスの種類です（デフォルトは IPv4）
+	Family Family
+
+	// MaxResponseSize は、読み込むレスポンスボディの最大サイズ（バイト）です（0 の場合は httpclient.DefaultMaxResponseSize）
+	MaxResponseSize int64
+
+	// Options は、ヘッダーや Basic 認証などのこのソースへのリクエストの設定です
+	Options SourceOptions
+
 	// client は、タイムアウト設定付きのHTTPクライアントです
 	client *http.Client
 }
@@ -77,6 +121,39 @@ func NewHTTPFetcherWithTimeout(url string, timeout time.Duration) *HTTPFetcher {
 	}
 }
 
+// NewHTTPFetcherForFamily は、指定した種類のIPアドレスを取得するHTTPFetcherを作成します。
+// IPv6 の場合は IPv6 でのみ接続し、レスポンスをIPv6アドレスとして検証します。
+//
+// Parameters:
+//   - url: IPアドレスを取得するエンドポイントのURL
+//   - family: 取得するIPアドレスの種類
+//   - timeout: HTTPリクエストのタイムアウト
+//
+// Returns:
+//   - *HTTPFetcher: 作成されたHTTPFetcher
+func NewHTTPFetcherForFamily(url string, family Family, timeout time.Duration) *HTTPFetcher {
+	return NewHTTPFetcherWithTransport(url, family, timeout, nil)
+}
+
+// NewHTTPFetcherWithTransport は、プロキシなどの通信設定を反映した Transport で
+// 指定した種類のIPアドレスを取得するHTTPFetcherを作成します。
+//
+// Parameters:
+//   - url: IPアドレスを取得するエンドポイントのURL
+//   - family: 取得するIPアドレスの種類
+//   - timeout: HTTPリクエストのタイムアウト
+//   - transport: 通信に使う Transport（nil の場合は http.DefaultTransport）
+//
+// Returns:
+//   - *HTTPFetcher: 作成されたHTTPFetcher
+func NewHTTPFetcherWithTransport(url string, family Family, timeout time.Duration, transport *http.Transport) *HTTPFetcher {
+	return &HTTPFetcher{
+		URL:    url,
+		Family: family,
+		client: newHTTPClient(family, timeout, transport),
+	}
+}
+
 // Fetch は、HTTPリクエストを使ってIPアドレスを取得します。
 // コンテキストがキャンセルされた場合は、リクエストもキャンセルされます。
 //
@@ -95,6 +172,9 @@ func (f *HTTPFetcher) Fetch(ctx context.Context) (string, error) {
 
 	// User-Agent設定
 	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+	// gzip でしか返さないソースにも対応するため、Transport の設定に関係なく gzip を受け付けて DecodeBody で展開する
+	req.Header.Set("Accept-Encoding", "gzip")
+	f.Options.apply(req)
 
 	// リクエスト実行
 	resp, err := f.client.Do(req)
@@ -104,26 +184,49 @@ func (f *HTTPFetcher) Fetch(ctx context.Context) (string, error) {
 	defer resp.Body.Close()
 
 	// ステータスコード確認
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", &RateLimitError{
+			URL:        f.URL,
+			RetryAfter: ratelimit.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("HTTPステータスエラー: %d (URL: %s)", resp.StatusCode, f.URL)
 	}
 
 	// レスポンスボディを読み込み
-	body, err := io.ReadAll(resp.Body)
+	// gzip で返された場合は展開してから、展開後のサイズを制限して読み込む
+	reader, err := httpclient.DecodeBody(resp)
+	if err != nil {
+		return "", fmt.Errorf("レスポンス読み込みに失敗しました (URL: %s): %w", f.URL, err)
+	}
+	body, err := httpclient.ReadBody(reader, f.MaxResponseSize)
 	if err != nil {
-		return "", fmt.Errorf("レスポンス読み込みに失敗しました: %w", err)
+		return "", fmt.Errorf("レスポンス読み込みに失敗しました (URL: %s): %w", f.URL, err)
+	}
+
+	// JSON や HTML などの場合は、指定された方法で取り出す
+	text := string(body)
+	if f.Options.Extractor != nil {
+		if text, err = f.Options.Extractor.Extract(body, f.Family); err != nil {
+			return "", fmt.Errorf("%w: %w (URL: %s)", ErrInvalidIP, err, f.URL)
+		}
 	}
 
 	// IPアドレス抽出（空白やタブ、改行を削除）
-	ip := strings.TrimSpace(string(body))
+	ip := strings.TrimSpace(text)
 
 	if ip == "" {
 		return "", fmt.Errorf("レスポンスが空です (URL: %s)", f.URL)
 	}
 
-	// IPv4アドレスのバリデーション
-	if err := ValidateIPv4(ip); err != nil {
-		return "", fmt.Errorf("無効なIPアドレス: %s (URL: %s, エラー: %w)", ip, f.URL, err)
+	// 種類に合わせたIPアドレスのバリデーション
+	// HTML が返された場合は、キャプティブポータルに横取りされている可能性がある
+	if err := f.Family.Validate(ip); err != nil {
+		if f.Options.Extractor == nil && looksLikeHTML(resp.Header.Get("Content-Type"), body) {
+			return "", &SuspiciousIPError{Source: f.URL, Reason: "IPアドレスではなく HTML が返されました (キャプティブポータルなどに横取りされている可能性があります)"}
+		}
+		return "", fmt.Errorf("%w: %s (URL: %s, エラー: %w)", ErrInvalidIP, ip, f.URL, err)
 	}
 
 	return ip, nil
@@ -168,12 +271,96 @@ func ValidateIPv4(ip string) error {
 
 // MultipleFetcher は、複数のIPソースからIPアドレスを順次試行して取得する構造体です。
 // フェイルオーバー機能を提供し、最初に成功したソースのIPアドレスを返します。
+// Strategy を指定すると、最初に問い合わせるソースをずらして、特定のソースに問い合わせが集中しないようにします。
 type MultipleFetcher struct {
 	// URLs は、試行するIPアドレス取得エンドポイントのURLリストです
 	URLs []string
 
+	// Family は、取得するIPアドレスの種類です（デフォルトは IPv4）
+	Family Family
+
+	// Transport は、プロキシなどの通信設定を反映した Transport です（nil の場合は http.DefaultTransport）
+	Transport *http.Transport
+
+	// MaxResponseSize は、各ソースから読み込むレスポンスボディの最大サイズ（バイト）です（0 の場合は httpclient.DefaultMaxResponseSize）
+	MaxResponseSize int64
+
+	// SourceOptions は、URL ごとのヘッダーや Basic 認証などのリクエストの設定です（指定がない URL はそのまま取得します）
+	SourceOptions map[string]SourceOptions
+
+	// CacheTTL は、最後に取得できたIPアドレスを、ソースに問い合わせずに返す時間です（0 の場合はキャッシュしません）
+	// 短い間隔で何度も取得を要求されても、外部のサービスに毎回問い合わせないようにします
+	CacheTTL time.Duration
+
+	// Strategy は、最初に問い合わせるソースの選び方です（空の場合は StrategySequential）
+	Strategy Strategy
+
+	// FailureThreshold は、ソースを一時的に使わないようにするまでの連続失敗回数です（0 の場合は使わないようにしません）
+	// 応答しないソースに毎回タイムアウトまで待たないようにします
+	FailureThreshold int
+
+	// Cooldown は、連続で失敗したソースを使わない時間です（0 の場合は DefaultCooldown）
+	Cooldown time.Duration
+
+	// Recorder は、ソースごとの連続失敗回数と使わない期限を記録します（nil の場合は記録しません）
+	Recorder SourceRecorder
+
+	// Suspicious は、取得したIPアドレスが疑わしい（LAN のアドレスなど）場合の扱いです（空の場合は確認しません）
+	Suspicious SuspiciousAction
+
+	// CrossCheck は、取得したIPアドレスを別のソースの結果とも比べるかどうかです（Suspicious を指定した場合だけ）
+	CrossCheck bool
+
 	// timeout は、各HTTPリクエストのタイムアウト設定です
 	timeout time.Duration
+
+	// mu は、limitedUntil と history、last、next、failures、blacklistedUntil、latencies を保護します
+	mu sync.Mutex
+
+	// limitedUntil は、429 の Retry-After で待つように指定されたソースと、再び問い合わせられる時刻です
+	limitedUntil map[string]time.Time
+
+	// history は、ソースごとの最後の問い合わせと取得結果です（MinInterval と CacheTTL に使います）
+	history map[string]sourceHistory
+
+	// last は、CacheTTL のために記録する、最後に取得できた結果です
+	last lastResult
+
+	// next は、StrategyRoundRobin で次に最初に問い合わせるソースのインデックスです
+	next int
+
+	// failures は、ソースごとの連続失敗回数です（FailureThreshold に使います）
+	failures map[string]int
+
+	// blacklistedUntil は、連続で失敗したため使わないソースと、また使う時刻です
+	blacklistedUntil map[string]time.Time
+
+	// latencies は、ソースごとの直近の応答時間です（Latencies と StrategyFastest に使います）
+	latencies map[string]*latencySamples
+}
+
+// lastResult は、MultipleFetcher が最後に取得できたIPアドレスと、そのソースです。
+type lastResult struct {
+	// ip は、最後に取得できたIPアドレスです
+	ip string
+
+	// source は、ip を取得したソースのURLです
+	source string
+
+	// fetchedAt は、ip を取得した時刻です
+	fetchedAt time.Time
+}
+
+// sourceHistory は、ソースへの最後の問い合わせと、最後に取得できた結果です。
+type sourceHistory struct {
+	// queriedAt は、最後に問い合わせた時刻です
+	queriedAt time.Time
+
+	// ip は、最後に取得できたIPアドレスです
+	ip string
+
+	// fetchedAt は、ip を取得した時刻です
+	fetchedAt time.Time
 }
 
 // NewMultipleFetcher は、複数のURLから順次IPアドレスを取得する
@@ -204,8 +391,28 @@ func NewMultipleFetcherWithTimeout(urls []string, timeout time.Duration) *Multip
 	}
 }
 
+// NewMultipleFetcherForFamily は、指定した種類のIPアドレスを
+// 複数のURLから順次取得するMultipleFetcherを作成します。
+//
+// Parameters:
+//   - urls: 試行するIPアドレス取得エンドポイントのURLリスト
+//   - family: 取得するIPアドレスの種類
+//   - timeout: 各HTTPリクエストのタイムアウト
+//
+// Returns:
+//   - *MultipleFetcher: 作成されたMultipleFetcher
+func NewMultipleFetcherForFamily(urls []string, family Family, timeout time.Duration) *MultipleFetcher {
+	return &MultipleFetcher{
+		URLs:    urls,
+		Family:  family,
+		timeout: timeout,
+	}
+}
+
 // Fetch は、複数のIPソースから順次試行してIPアドレスを取得します。
 // 最初に成功したソースのIPアドレスを返します。
+// 429 の Retry-After で待つように指定されたソースは、その時間が過ぎるまで問い合わせずに次のソースを試します。
+// CacheTTL を指定した場合は、その時間内に取得できた結果をソースに問い合わせずに返します。
 // すべての試行に失敗した場合は、詳細なエラーメッセージを返します。
 //
 // Parameters:
@@ -215,18 +422,62 @@ func NewMultipleFetcherWithTimeout(urls []string, timeout time.Duration) *Multip
 //   - string: 取得したIPアドレス
 //   - error: すべてのソースから取得できなかった場合
 func (mf *MultipleFetcher) Fetch(ctx context.Context) (string, error) {
+	ip, _, err := mf.FetchWithSource(ctx)
+	return ip, err
+}
+
+// FetchWithSource は、Fetch と同様にIPアドレスを取得し、取得に成功したソースのURLも返します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - string: 取得したIPアドレス
+//   - string: 取得に成功したソースのURL
+//   - error: すべてのソースから取得できなかった場合
+func (mf *MultipleFetcher) FetchWithSource(ctx context.Context) (string, string, error) {
+	result, err := mf.FetchDetailed(ctx)
+	return result.IP, result.Source, err
+}
+
+// FetchDetailed は、Fetch と同様にIPアドレスを取得し、応答したソースと応答時間、問い合わせたソースの数を返します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - FetchResult: 取得の詳細な結果（失敗した場合も Attempts は設定されます）
+//   - error: すべてのソースから取得できなかった場合
+func (mf *MultipleFetcher) FetchDetailed(ctx context.Context) (FetchResult, error) {
 	if len(mf.URLs) == 0 {
-		return "", fmt.Errorf("IP取得ソースが設定されていません")
+		return FetchResult{}, fmt.Errorf("IP取得ソースが設定されていません")
+	}
+
+	// CacheTTL の間に取得できた結果があれば、どのソースにも問い合わせずに返す
+	if cached, source, age, ok := mf.lastCached(); ok {
+		slog.Debug("キャッシュしたIPアドレスを使用",
+			"url", source,
+			"ip", cached,
+			"age", age.Round(time.Second).String(),
+		)
+		return FetchResult{IP: cached, Source: source, Cached: true}, nil
 	}
 
 	// 各試行のエラーを記録
-	var errors []string
+	var failures []string
+	var result FetchResult
+
+	// すべてのソースが連続で失敗している場合は、期限を無視してすべて試す
+	skipBlacklisted := !mf.allBlacklisted()
+
+	// Strategy で選んだソースから順次試行
+	order := mf.order()
+	for n, i := range order {
+		url := mf.URLs[i]
 
-	// 各URLを順次試行
-	for i, url := range mf.URLs {
 		// 空のURLをスキップ
 		if strings.TrimSpace(url) == "" {
-			errors = append(errors, fmt.Sprintf("[%d] URLが空です", i))
+			failures = append(failures, fmt.Sprintf("[%d] URLが空です", i))
 			slog.Warn("IPソースURLが空のためスキップ",
 				"index", i,
 				"url", url,
@@ -234,29 +485,95 @@ func (mf *MultipleFetcher) Fetch(ctx context.Context) (string, error) {
 			continue
 		}
 
+		// Retry-After の待ち時間が過ぎていないソースをスキップ
+		if wait := mf.rateLimitWait(url); wait > 0 {
+			failures = append(failures, fmt.Sprintf("[%d] %s: リクエスト数の制限中のためスキップしました (あと %s)", i, url, wait.Round(time.Second)))
+			slog.Warn("IPソースがリクエスト数の制限中のためスキップ",
+				"index", i,
+				"url", url,
+				"retry_after", wait.String(),
+			)
+			continue
+		}
+
+		// 連続で失敗したため、しばらく使わないソースをスキップ
+		if skipBlacklisted {
+			if wait := mf.blacklistWait(url); wait > 0 {
+				failures = append(failures, fmt.Sprintf("[%d] %s: 続けて失敗したためスキップしました (あと %s)", i, url, wait.Round(time.Second)))
+				slog.Info("IPソースが続けて失敗したためスキップ",
+					"index", i,
+					"url", url,
+					"wait", wait.String(),
+				)
+				continue
+			}
+		}
+
+		// 最小の間隔が過ぎていないソースは、キャッシュした結果を使うか、スキップする
+		opts := mf.SourceOptions[url]
+		if cached, age, ok := mf.cachedResult(url, opts); ok {
+			slog.Info("IP取得ソースの前回の結果を使用",
+				"index", i,
+				"url", url,
+				"ip", cached,
+				"age", age.Round(time.Second).String(),
+			)
+			result.IP, result.Source, result.Cached = cached, url, true
+			return result, nil
+		}
+		if wait := mf.minIntervalWait(url, opts); wait > 0 {
+			failures = append(failures, fmt.Sprintf("[%d] %s: 最小の問い合わせ間隔が過ぎていないためスキップしました (あと %s)", i, url, wait.Round(time.Second)))
+			slog.Info("IPソースの最小の問い合わせ間隔が過ぎていないためスキップ",
+				"index", i,
+				"url", url,
+				"wait", wait.String(),
+			)
+			continue
+		}
+
 		// 試行開始ログ
 		slog.Info("IP取得を試行",
 			"index", i,
 			"url", url,
 			"timeout", mf.timeout.String(),
+			"family", mf.Family.String(),
 		)
 
-		// HTTPFetcherで取得を試行
-		fetcher := NewHTTPFetcherWithTimeout(url, mf.timeout)
+		// URL に合った Fetcher（HTTP または file:）で取得を試行
+		fetcher := newSourceFetcher(url, mf.Family, mf.timeout, mf.Transport, mf.MaxResponseSize, opts)
+		start := time.Now()
+		result.Attempts++
 		ip, err := fetcher.Fetch(ctx)
+		latency := time.Since(start)
+		// LAN のアドレスや、別のソースと大きく異なるIPアドレスは、設定によってはこのソースの失敗として扱う
+		if err == nil {
+			err = mf.checkSuspicious(ctx, ip, url, order[n+1:])
+		}
+		mf.record(url, opts, ip, err)
+		mf.recordFailures(ctx, url, err)
 
 		// 成功時はIPを返す
 		if err == nil {
+			mf.observeLatency(url, latency)
 			slog.Info("IP取得に成功",
 				"index", i,
 				"url", url,
 				"ip", ip,
+				"latency", latency.String(),
 			)
-			return ip, nil
+			mf.setLast(ip, url)
+			result.IP, result.Source, result.Latency = ip, url, latency
+			return result, nil
+		}
+
+		// 429 の場合は、Retry-After の待ち時間が過ぎるまでこのソースを使わない
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			mf.setRateLimited(url, rateLimitErr.RetryAfter)
 		}
 
 		// 失敗をログに記録
-		errors = append(errors, fmt.Sprintf("[%d] %s: %v", i, url, err))
+		failures = append(failures, fmt.Sprintf("[%d] %s: %v", i, url, err))
 		slog.Warn("IP取得に失敗",
 			"index", i,
 			"url", url,
@@ -265,9 +582,131 @@ func (mf *MultipleFetcher) Fetch(ctx context.Context) (string, error) {
 	}
 
 	// すべての試行が失敗した場合
-	errorMessage := "すべてのIP取得ソースから取得に失敗しました:\n  - " + strings.Join(errors, "\n  - ")
+	errorMessage := "すべてのIP取得ソースから取得に失敗しました:\n  - " + strings.Join(failures, "\n  - ")
 	slog.Error("IP取得ソースの全試行が失敗",
-		"errors", errors,
+		"errors", failures,
 	)
-	return "", fmt.Errorf("%s", errorMessage)
+	return result, fmt.Errorf("%s", errorMessage)
+}
+
+// rateLimitWait は、ソースが Retry-After で指定した待ち時間の残りを返します（待つ必要がない場合は 0）。
+func (mf *MultipleFetcher) rateLimitWait(url string) time.Duration {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	until, ok := mf.limitedUntil[url]
+	if !ok {
+		return 0
+	}
+	if wait := time.Until(until); wait > 0 {
+		return wait
+	}
+	delete(mf.limitedUntil, url)
+	return 0
+}
+
+// setRateLimited は、ソースを Retry-After の待ち時間が過ぎるまで使わないように記録します。
+func (mf *MultipleFetcher) setRateLimited(url string, retryAfter time.Duration) {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	if mf.limitedUntil == nil {
+		mf.limitedUntil = make(map[string]time.Time)
+	}
+	mf.limitedUntil[url] = time.Now().Add(retryAfter)
+}
+
+// cachedResult は、CacheTTL（省略時は MinInterval）の間に取得できた結果があれば、その IPアドレスと経過時間を返します。
+func (mf *MultipleFetcher) cachedResult(url string, opts SourceOptions) (string, time.Duration, bool) {
+	ttl := opts.cacheTTL()
+	if ttl <= 0 {
+		return "", 0, false
+	}
+
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	h, ok := mf.history[url]
+	if !ok || h.ip == "" {
+		return "", 0, false
+	}
+	age := time.Since(h.fetchedAt)
+	if age >= ttl {
+		return "", 0, false
+	}
+	return h.ip, age, true
+}
+
+// minIntervalWait は、MinInterval が過ぎるまでの残りの時間を返します（問い合わせてよい場合は 0）。
+func (mf *MultipleFetcher) minIntervalWait(url string, opts SourceOptions) time.Duration {
+	if opts.MinInterval <= 0 {
+		return 0
+	}
+
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	h, ok := mf.history[url]
+	if !ok {
+		return 0
+	}
+	if wait := opts.MinInterval - time.Since(h.queriedAt); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// record は、ソースへの問い合わせの時刻と、取得できた場合はその結果を記録します。
+// MinInterval と CacheTTL のどちらも指定されていないソースは記録しません。
+func (mf *MultipleFetcher) record(url string, opts SourceOptions, ip string, err error) {
+	if opts.MinInterval <= 0 && opts.CacheTTL <= 0 {
+		return
+	}
+
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	if mf.history == nil {
+		mf.history = make(map[string]sourceHistory)
+	}
+	now := time.Now()
+	h := mf.history[url]
+	h.queriedAt = now
+	if err == nil {
+		h.ip = ip
+		h.fetchedAt = now
+	}
+	mf.history[url] = h
+}
+
+// lastCached は、CacheTTL の間に取得できた結果があれば、そのIPアドレスとソース、経過時間を返します。
+func (mf *MultipleFetcher) lastCached() (string, string, time.Duration, bool) {
+	if mf.CacheTTL <= 0 {
+		return "", "", 0, false
+	}
+
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	if mf.last.ip == "" {
+		return "", "", 0, false
+	}
+	age := time.Since(mf.last.fetchedAt)
+	if age >= mf.CacheTTL {
+		return "", "", 0, false
+	}
+	return mf.last.ip, mf.last.source, age, true
+}
+
+// setLast は、CacheTTL のために、取得できたIPアドレスとソースを記録します。
+// ソースごとのキャッシュから返した結果は、取得した時刻を延ばさないように記録しません。
+func (mf *MultipleFetcher) setLast(ip, source string) {
+	if mf.CacheTTL <= 0 {
+		return
+	}
+
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	mf.last = lastResult{ip: ip, source: source, fetchedAt: time.Now()}
 }
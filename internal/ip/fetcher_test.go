@@ -1,11 +1,18 @@
 package ip
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
 )
 
 // TestValidateIPv4 は、IPv4アドレスの検証をテストします。
@@ -443,3 +450,218 @@ func TestHTTPFetcher_UserAgent(t *testing.T) {
 		t.Errorf("User-Agentが一致しません。期待: %s, 実際: %s", expectedAgent, receivedAgent)
 	}
 }
+
+// TestMultipleFetcher_RateLimited は、429 を返したソースを Retry-After の間スキップし、次のソースを使うことをテストします。
+func TestMultipleFetcher_RateLimited(t *testing.T) {
+	limitedCount := 0
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limitedCount++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("192.0.2.1"))
+	}))
+	defer fallback.Close()
+
+	mf := NewMultipleFetcher([]string{limited.URL, fallback.URL})
+	for i := 0; i < 2; i++ {
+		ip, err := mf.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("%d回目: 次のソースから取得できるべき: %v", i+1, err)
+		}
+		if ip != "192.0.2.1" {
+			t.Errorf("%d回目: IP = %s, 期待: 192.0.2.1", i+1, ip)
+		}
+	}
+	if limitedCount != 1 {
+		t.Errorf("Retry-After の間は 429 を返したソースに問い合わせないべき。問い合わせ回数: %d", limitedCount)
+	}
+}
+
+// TestHTTPFetcher_RateLimited は、429 が ErrRateLimited として判定できることをテストします。
+func TestHTTPFetcher_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPFetcher(server.URL).Fetch(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("ErrRateLimited として判定できるべき: %v", err)
+	}
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 5*time.Second {
+		t.Errorf("Retry-After の5秒を取り出せるべき: %v", err)
+	}
+}
+
+// TestMultipleFetcher_Transport は、Transport の通信設定（プロキシ）でソースに接続することをテストします。
+func TestMultipleFetcher_Transport(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("192.0.2.9"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+
+	mf := NewMultipleFetcher([]string{"http://ip-source.invalid/"})
+	mf.Transport = transport
+	ip, err := mf.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("プロキシ経由で取得できるべき: %v", err)
+	}
+	if ip != "192.0.2.9" || proxied != "http://ip-source.invalid/" {
+		t.Errorf("IP = %s, プロキシへのリクエスト = %s", ip, proxied)
+	}
+}
+
+// TestHTTPFetcher_ResponseTooLarge は、最大サイズを超えるレスポンスが ErrResponseTooLarge になることをテストします。
+func TestHTTPFetcher_ResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("1", int(httpclient.DefaultMaxResponseSize)+1)))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPFetcher(server.URL).Fetch(context.Background())
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("ErrResponseTooLarge が返されるべき: %v", err)
+	}
+}
+
+// TestMultipleFetcher_MaxResponseSize は、最大サイズを超えたソースをスキップして次のソースを試すことをテストします。
+func TestMultipleFetcher_MaxResponseSize(t *testing.T) {
+	large := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>captive portal</html>"))
+	}))
+	defer large.Close()
+	small := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("10.0.0.1"))
+	}))
+	defer small.Close()
+
+	fetcher := NewMultipleFetcher([]string{large.URL, small.URL})
+	fetcher.MaxResponseSize = 16
+	ip, err := fetcher.Fetch(context.Background())
+	if err != nil || ip != "10.0.0.1" {
+		t.Errorf("Fetch() = %q, %v; 期待値 10.0.0.1", ip, err)
+	}
+}
+
+// serveGzip は、Accept-Encoding に関係なく gzip で圧縮したIPアドレスを返すテスト用のサーバーを起動します。
+func serveGzip(t *testing.T, body string) (*httptest.Server, *string) {
+	t.Helper()
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(body))
+		_ = gz.Close()
+	}))
+	t.Cleanup(server.Close)
+	return server, &acceptEncoding
+}
+
+// TestHTTPFetcher_Gzip は、gzip で返されたIPアドレスを展開して取得できることをテストします。
+func TestHTTPFetcher_Gzip(t *testing.T) {
+	server, acceptEncoding := serveGzip(t, "203.0.113.5\n")
+
+	// 圧縮を無効にした Transport でも、gzip を受け付けて展開する
+	for _, transport := range []*http.Transport{nil, {DisableCompression: true}} {
+		ip, err := NewHTTPFetcherWithTransport(server.URL, IPv4, DefaultHTTPTimeout, transport).Fetch(context.Background())
+		if err != nil || ip != "203.0.113.5" {
+			t.Errorf("Fetch() = %q, %v; 期待値 203.0.113.5", ip, err)
+		}
+		if *acceptEncoding != "gzip" {
+			t.Errorf("Accept-Encoding = %q, 期待値 gzip", *acceptEncoding)
+		}
+	}
+}
+
+// TestHTTPFetcher_GzipTooLarge は、展開後のサイズで最大サイズを制限することをテストします。
+func TestHTTPFetcher_GzipTooLarge(t *testing.T) {
+	server, _ := serveGzip(t, strings.Repeat("0", 1<<20))
+
+	_, err := NewHTTPFetcher(server.URL).Fetch(context.Background())
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("展開後のサイズが大きすぎる場合は ErrResponseTooLarge が返されるべき: %v", err)
+	}
+}
+
+// TestMultipleFetcher_CacheTTLResult は、CacheTTL の間は最後に取得できた結果をソースに問い合わせずに返すことをテストします。
+func TestMultipleFetcher_CacheTTLResult(t *testing.T) {
+	var hits atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+
+	fetcher := NewMultipleFetcher([]string{failing.URL, server.URL})
+	fetcher.CacheTTL = time.Hour
+	for i := 0; i < 3; i++ {
+		ip, source, err := fetcher.FetchWithSource(context.Background())
+		if err != nil || ip != "198.51.100.7" || source != server.URL {
+			t.Fatalf("%d 回目: FetchWithSource() = %q, %q, %v", i+1, ip, source, err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("問い合わせ回数 = %d, 期待値 1", hits.Load())
+	}
+}
+
+// TestMultipleFetcher_CacheTTLExpired は、CacheTTL が過ぎたら、またソースに問い合わせることをテストします。
+func TestMultipleFetcher_CacheTTLExpired(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+
+	fetcher := NewMultipleFetcher([]string{server.URL})
+	fetcher.CacheTTL = 20 * time.Millisecond
+	if _, err := fetcher.Fetch(context.Background()); err != nil {
+		t.Fatalf("取得に失敗しました: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := fetcher.Fetch(context.Background()); err != nil {
+		t.Fatalf("取得に失敗しました: %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("問い合わせ回数 = %d, 期待値 2", hits.Load())
+	}
+}
+
+// TestMultipleFetcher_CacheTTLFailure は、取得に失敗した結果はキャッシュしないことをテストします。
+func TestMultipleFetcher_CacheTTLFailure(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	fetcher := NewMultipleFetcher([]string{server.URL})
+	fetcher.CacheTTL = time.Hour
+	for i := 0; i < 2; i++ {
+		if _, err := fetcher.Fetch(context.Background()); err == nil {
+			t.Fatalf("%d 回目: 失敗するべき", i+1)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("問い合わせ回数 = %d, 期待値 2", hits.Load())
+	}
+}
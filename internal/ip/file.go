@@ -0,0 +1,176 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// FileScheme は、ローカルのファイルからIPアドレスを読み込むソースの URL の先頭です（例: "file:/run/wan_ip"）。
+const FileScheme = "file:"
+
+// IsFileSource は、ソースがローカルのファイルからIPアドレスを読み込む "file:" のソースかどうかを返します。
+func IsFileSource(source string) bool {
+	return len(source) >= len(FileScheme) && strings.EqualFold(source[:len(FileScheme)], FileScheme)
+}
+
+// ParseFileSource は、"file:" のソースから読み込むファイルのパスを取り出します。
+// "file:/run/wan_ip" と "file:///run/wan_ip" のどちらの書き方にも対応します。
+//
+// Parameters:
+//   - source: "file:" で始まるソース
+//
+// Returns:
+//   - string: 読み込むファイルの絶対パス
+//   - error: "file:" のソースでない場合、またはパスが絶対パスでない場合
+func ParseFileSource(source string) (string, error) {
+	if !IsFileSource(source) {
+		return "", fmt.Errorf("%q は file: のソースではありません", source)
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("file: のソース %q を解析できません: %w", source, err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file: のソース %q にはホストを指定できません", source)
+	}
+	if u.Opaque != "" || !filepath.IsAbs(u.Path) {
+		return "", fmt.Errorf("file: のソース %q は絶対パスで指定してください (例: file:/run/wan_ip)", source)
+	}
+	return u.Path, nil
+}
+
+// FileFetcher は、ローカルのファイルからIPアドレスを読み込む Fetcher です。
+// ルーターや PPPoE のスクリプトが WAN の IPアドレスを書き出している環境で、外部のサービスに問い合わせずに済みます。
+// ファイルは Fetch のたびに読み込み直します。
+type FileFetcher struct {
+	// Path は、IPアドレスを読み込むファイルのパスです
+	Path string
+
+	// Family は、読み込むIPアドレスの種類です（デフォルトは IPv4）
+	Family Family
+
+	// MaxResponseSize は、読み込むファイルの最大サイズ（バイト）です（0 の場合は httpclient.DefaultMaxResponseSize）
+	MaxResponseSize int64
+
+	// Extractor は、ファイルの内容から IPアドレスを取り出す方法です（nil の場合は内容全体を IPアドレスとして扱います）
+	Extractor Extractor
+}
+
+// NewFileFetcher は、ファイルからIPアドレスを読み込む FileFetcher を作成します。
+//
+// Parameters:
+//   - path: IPアドレスを読み込むファイルのパス
+//   - family: 読み込むIPアドレスの種類
+//
+// Returns:
+//   - *FileFetcher: 作成された FileFetcher
+func NewFileFetcher(path string, family Family) *FileFetcher {
+	return &FileFetcher{Path: path, Family: family}
+}
+
+// Fetch は、ファイルを読み込んでIPアドレスを返します。
+//
+// Parameters:
+//   - ctx: キャンセルを制御するコンテキスト
+//
+// Returns:
+//   - string: 読み込んだIPアドレス
+//   - error: ファイルを読み込めない場合、または内容が有効なIPアドレスでない場合
+func (f *FileFetcher) Fetch(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("ファイルの読み込みに失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	body, err := httpclient.ReadBody(file, f.MaxResponseSize)
+	if err != nil {
+		return "", fmt.Errorf("ファイルの読み込みに失敗しました (%s): %w", f.Path, err)
+	}
+
+	text := string(body)
+	if f.Extractor != nil {
+		if text, err = f.Extractor.Extract(body, f.Family); err != nil {
+			return "", fmt.Errorf("%w: %w (ファイル: %s)", ErrInvalidIP, err, f.Path)
+		}
+	}
+
+	ip := strings.TrimSpace(text)
+	if ip == "" {
+		return "", fmt.Errorf("ファイルが空です (%s)", f.Path)
+	}
+	if err := f.Family.Validate(ip); err != nil {
+		return "", fmt.Errorf("%w: %s (ファイル: %s, エラー: %w)", ErrInvalidIP, ip, f.Path, err)
+	}
+	return ip, nil
+}
+
+// NewSourceFetcher は、ソースの URL に合った Fetcher を作成します。
+// "file:" のソースはファイルから読み込み、それ以外は HTTP で取得します。
+//
+// Parameters:
+//   - source: ソースの URL（"https://..." または "file:/..."）
+//   - family: 取得するIPアドレスの種類
+//   - timeout: HTTPリクエストのタイムアウト（"file:" のソースでは使いません）
+//
+// Returns:
+//   - Fetcher: 作成された Fetcher
+func NewSourceFetcher(source string, family Family, timeout time.Duration) Fetcher {
+	return newSourceFetcher(source, family, timeout, nil, 0, SourceOptions{})
+}
+
+// newSourceFetcher は、通信設定とソースごとの設定を反映して、ソースの URL と種類に合った Fetcher を作成します。
+// "file:" のパスが無効な場合は、Fetch でそのエラーを返す Fetcher を返します。
+func newSourceFetcher(source string, family Family, timeout time.Duration, transport *http.Transport, maxResponseSize int64, opts SourceOptions) Fetcher {
+	if IsFileSource(source) {
+		path, err := ParseFileSource(source)
+		if err != nil {
+			return errFetcher{err}
+		}
+		fetcher := NewFileFetcher(path, family)
+		fetcher.MaxResponseSize = maxResponseSize
+		fetcher.Extractor = opts.Extractor
+		return fetcher
+	}
+
+	switch opts.Type {
+	case SourceFritzBox:
+		fetcher := NewFritzBoxFetcher(source, family, timeout, transport)
+		fetcher.Username, fetcher.Password = opts.Username, opts.Password
+		fetcher.MaxResponseSize = maxResponseSize
+		return fetcher
+	case SourceOpenWrt:
+		fetcher := NewOpenWrtFetcher(source, family, timeout, transport)
+		fetcher.Username, fetcher.Password = opts.Username, opts.Password
+		fetcher.Interface = opts.Interface
+		fetcher.MaxResponseSize = maxResponseSize
+		return fetcher
+	}
+
+	fetcher := NewHTTPFetcherWithTransport(source, family, timeout, transport)
+	fetcher.MaxResponseSize = maxResponseSize
+	fetcher.Options = opts
+	return fetcher
+}
+
+// errFetcher は、いつも同じエラーを返す Fetcher です（無効なソースに使います）。
+type errFetcher struct {
+	err error
+}
+
+// Fetch は、エラーを返します。
+func (f errFetcher) Fetch(ctx context.Context) (string, error) {
+	return "", f.err
+}
@@ -0,0 +1,125 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseFileSource は、file: のソースからファイルのパスを取り出せることをテストします。
+func TestParseFileSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{source: "file:/run/wan_ip", want: "/run/wan_ip"},
+		{source: "file:///run/wan_ip", want: "/run/wan_ip"},
+		{source: "FILE:/run/wan_ip", want: "/run/wan_ip"},
+		{source: "file://localhost/run/wan_ip", want: "/run/wan_ip"},
+		{source: "file:wan_ip", wantErr: true},
+		{source: "file://router/run/wan_ip", wantErr: true},
+		{source: "https://api.ipify.org", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFileSource(tt.source)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFileSource(%q) のエラー = %v, wantErr %v", tt.source, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFileSource(%q) = %q, 期待値 %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestFileFetcher_Fetch は、ファイルの内容を取得のたびに読み込み直すことをテストします。
+func TestFileFetcher_Fetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wan_ip")
+	if err := os.WriteFile(path, []byte("198.51.100.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := NewFileFetcher(path, IPv4)
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.1" {
+		t.Fatalf("Fetch() = %q, %v", ip, err)
+	}
+
+	if err := os.WriteFile(path, []byte("198.51.100.2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.2" {
+		t.Errorf("書き換えた後の Fetch() = %q, %v", ip, err)
+	}
+}
+
+// TestFileFetcher_Errors は、ファイルがない場合や内容が無効な場合にエラーになることをテストします。
+func TestFileFetcher_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewFileFetcher(filepath.Join(dir, "missing"), IPv4).Fetch(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ファイルがない場合は os.ErrNotExist になるべき: %v", err)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileFetcher(empty, IPv4).Fetch(context.Background()); err == nil {
+		t.Error("空のファイルはエラーになるべき")
+	}
+
+	v6 := filepath.Join(dir, "v6")
+	if err := os.WriteFile(v6, []byte("2001:db8::1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileFetcher(v6, IPv4).Fetch(context.Background()); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("IPv4 のソースに IPv6 アドレスがある場合は ErrInvalidIP になるべき: %v", err)
+	}
+}
+
+// TestFileFetcher_Extractor は、ファイルの内容から extract で IPアドレスを取り出せることをテストします。
+func TestFileFetcher_Extractor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pppoe.status")
+	if err := os.WriteFile(path, []byte("iface=ppp0\nWAN IP: 198.51.100.7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	extractor, err := ParseExtractor(`regex:WAN IP: ([0-9.]+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := NewFileFetcher(path, IPv4)
+	fetcher.Extractor = extractor
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.7" {
+		t.Errorf("Fetch() = %q, %v", ip, err)
+	}
+}
+
+// TestMultipleFetcher_FileSource は、MultipleFetcher で file: のソースと HTTP のソースを混ぜて使えることをテストします。
+func TestMultipleFetcher_FileSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wan_ip")
+	if err := os.WriteFile(path, []byte("198.51.100.1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	urls := newIPServers(t, "198.51.100.2")
+
+	fetcher := NewMultipleFetcher([]string{"file:" + path, urls[0]})
+	result, err := fetcher.FetchDetailed(context.Background())
+	if err != nil || result.IP != "198.51.100.1" || result.Source != "file:"+path {
+		t.Fatalf("FetchDetailed() = %+v, %v", result, err)
+	}
+
+	// ファイルがなくなったら次のソースを使う
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	result, err = fetcher.FetchDetailed(context.Background())
+	if err != nil || result.IP != "198.51.100.2" || result.Attempts != 2 {
+		t.Errorf("ファイルがない場合の FetchDetailed() = %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,258 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FRITZ!Box の IPアドレスを問い合わせる SOAP のサービスとアクションです。
+// 認証情報がない場合は、認証なしで使える UPnP（IGD）のサービスに問い合わせます。
+// IPv6 のアドレスは IGD のサービスでしか取得できないため、いつも IGD に問い合わせます。
+const (
+	fritzBoxIGDControl = "/igdupnp/control/WANIPConn1"
+	fritzBoxIGDService = "urn:schemas-upnp-org:service:WANIPConnection:1"
+
+	fritzBoxTR064Control = "/upnp/control/wanipconnection1"
+	fritzBoxTR064Service = "urn:dslforum-org:service:WANIPConnection:1"
+
+	fritzBoxIPv4Action = "GetExternalIPAddress"
+	fritzBoxIPv4Field  = "NewExternalIPAddress"
+	fritzBoxIPv6Action = "X_AVM_DE_GetExternalIPv6Address"
+	fritzBoxIPv6Field  = "NewExternalIPv6Address"
+)
+
+// FritzBoxFetcher は、FRITZ!Box に TR-064 / UPnP の SOAP で WAN の IPアドレスを問い合わせる Fetcher です。
+// 外部のサービスに問い合わせずに、ルーターが PPPoE などで割り当てられたアドレスを取得できます。
+type FritzBoxFetcher struct {
+	// URL は、FRITZ!Box の TR-064 のアドレスです（例: "http://fritz.box:49000"）
+	URL string
+
+	// Family は、取得するIPアドレスの種類です
+	Family Family
+
+	// Username は、TR-064 の認証に使うユーザー名です（空の場合は認証なしで UPnP に問い合わせます）
+	Username string
+
+	// Password は、TR-064 の認証に使うパスワードです
+	Password string
+
+	// MaxResponseSize は、読み込むレスポンスの最大サイズ（バイト）です（0 の場合は httpclient.DefaultMaxResponseSize）
+	MaxResponseSize int64
+
+	// client は、FRITZ!Box に問い合わせる HTTP クライアントです
+	client *http.Client
+}
+
+// NewFritzBoxFetcher は、FRITZ!Box に問い合わせる FritzBoxFetcher を作成します。
+//
+// Parameters:
+//   - url: FRITZ!Box の TR-064 のアドレス（例: "http://fritz.box:49000"）
+//   - family: 取得するIPアドレスの種類
+//   - timeout: HTTPリクエストのタイムアウト
+//   - transport: 通信に使う Transport（nil の場合は http.DefaultTransport）
+//
+// Returns:
+//   - *FritzBoxFetcher: 作成された FritzBoxFetcher
+func NewFritzBoxFetcher(url string, family Family, timeout time.Duration, transport *http.Transport) *FritzBoxFetcher {
+	return &FritzBoxFetcher{
+		URL:    url,
+		Family: family,
+		client: newRouterClient(timeout, transport),
+	}
+}
+
+// Fetch は、FRITZ!Box から WAN の IPアドレスを取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - string: 取得したIPアドレス
+//   - error: 問い合わせに失敗した場合、または応答が有効なIPアドレスでない場合
+func (f *FritzBoxFetcher) Fetch(ctx context.Context) (string, error) {
+	control, service := fritzBoxIGDControl, fritzBoxIGDService
+	action, field := fritzBoxIPv4Action, fritzBoxIPv4Field
+	if f.Family == IPv6 {
+		action, field = fritzBoxIPv6Action, fritzBoxIPv6Field
+	} else if f.Username != "" {
+		control, service = fritzBoxTR064Control, fritzBoxTR064Service
+	}
+	endpoint := strings.TrimRight(f.URL, "/") + control
+
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+		`<s:Body><u:%s xmlns:u="%s"/></s:Body></s:Envelope>`, action, service)
+	header := http.Header{}
+	header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	header.Set("SOAPAction", fmt.Sprintf("%q", service+"#"+action))
+
+	resp, data, err := postRouter(ctx, f.client, endpoint, header, []byte(body), f.MaxResponseSize)
+	if err != nil {
+		return "", err
+	}
+
+	// TR-064 はダイジェスト認証を求めるため、チャレンジに答えて送り直す
+	if resp.StatusCode == http.StatusUnauthorized && f.Username != "" {
+		authorization, err := digestAuthorization(resp.Header.Get("WWW-Authenticate"), http.MethodPost, endpoint, f.Username, f.Password)
+		if err != nil {
+			return "", fmt.Errorf("FRITZ!Box の認証に失敗しました (URL: %s): %w", endpoint, err)
+		}
+		header.Set("Authorization", authorization)
+		if resp, data, err = postRouter(ctx, f.client, endpoint, header, []byte(body), f.MaxResponseSize); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return "", fmt.Errorf("FRITZ!Box の認証に失敗しました (URL: %s): ユーザー名とパスワードを確認してください", endpoint)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("HTTPステータスエラー: %d (URL: %s)", resp.StatusCode, endpoint)
+	}
+
+	ip, err := soapField(data, field)
+	if err != nil {
+		return "", fmt.Errorf("FRITZ!Box の応答を解析できません (URL: %s): %w", endpoint, err)
+	}
+	if ip == "" {
+		return "", fmt.Errorf("FRITZ!Box に WAN の IPアドレスがありません (URL: %s)", endpoint)
+	}
+	if err := f.Family.Validate(ip); err != nil {
+		return "", fmt.Errorf("%w: %s (URL: %s, エラー: %w)", ErrInvalidIP, ip, endpoint, err)
+	}
+	return ip, nil
+}
+
+// soapField は、SOAP の応答から指定した名前の要素の値を取り出します。
+func soapField(data []byte, field string) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%s がありません", field)
+		}
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == field {
+			var value string
+			if err := decoder.DecodeElement(&value, &start); err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(value), nil
+		}
+	}
+}
+
+// digestAuthorization は、WWW-Authenticate のダイジェスト認証のチャレンジに答える Authorization ヘッダーの値を作成します（RFC 7616 の MD5）。
+func digestAuthorization(challenge, method, endpoint, username, password string) (string, error) {
+	scheme, params, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return "", fmt.Errorf("ダイジェスト認証ではありません: %q", challenge)
+	}
+	values := parseAuthParams(params)
+	if algorithm := values["algorithm"]; algorithm != "" && !strings.EqualFold(algorithm, "MD5") {
+		return "", fmt.Errorf("ダイジェスト認証のアルゴリズム %q には対応していません", algorithm)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	uri := u.RequestURI()
+	realm, nonce := values["realm"], values["nonce"]
+	ha1 := md5Hex(username + ":" + realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+
+	fields := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+	}
+	if containsToken(values["qop"], "auth") {
+		cnonce := make([]byte, 8)
+		if _, err := rand.Read(cnonce); err != nil {
+			return "", err
+		}
+		nc, cn := "00000001", hex.EncodeToString(cnonce)
+		fields = append(fields,
+			"qop=auth",
+			"nc="+nc,
+			fmt.Sprintf("cnonce=%q", cn),
+			fmt.Sprintf("response=%q", md5Hex(ha1+":"+nonce+":"+nc+":"+cn+":auth:"+ha2)),
+		)
+	} else {
+		fields = append(fields, fmt.Sprintf("response=%q", md5Hex(ha1+":"+nonce+":"+ha2)))
+	}
+	if opaque, ok := values["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+	if values["algorithm"] != "" {
+		fields = append(fields, "algorithm=MD5")
+	}
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// parseAuthParams は、`realm="x", nonce="y"` 形式の認証パラメーターを解析します。
+func parseAuthParams(s string) map[string]string {
+	values := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			// 引用符の中のカンマで区切らないように、閉じる引用符まで読む
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value, rest = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+			rest = "," + rest
+		}
+		values[name] = value
+
+		_, rest, _ = strings.Cut(rest, ",")
+		s = rest
+	}
+	return values
+}
+
+// containsToken は、カンマ区切りのリストに token が含まれるかどうかを返します。
+func containsToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// md5Hex は、文字列の MD5 を16進数で返します。
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
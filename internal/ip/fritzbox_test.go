@@ -0,0 +1,107 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fritzBoxResponse は、FRITZ!Box の SOAP の応答を作成します。
+func fritzBoxResponse(action, field, value string) string {
+	return fmt.Sprintf(`<?xml version="1.0"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+		`<u:%sResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1"><%s>%s</%s></u:%sResponse>`+
+		`</s:Body></s:Envelope>`, action, field, value, field, action)
+}
+
+// TestFritzBoxFetcher_IGD は、認証情報がない場合に UPnP（IGD）で WAN の IPアドレスを取得することをテストします。
+func TestFritzBoxFetcher_IGD(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method != http.MethodPost || r.URL.Path != fritzBoxIGDControl:
+			http.NotFound(w, r)
+		case r.Header.Get("SOAPAction") == `"`+fritzBoxIGDService+"#"+fritzBoxIPv4Action+`"` && strings.Contains(string(body), fritzBoxIPv4Action):
+			_, _ = io.WriteString(w, fritzBoxResponse(fritzBoxIPv4Action, fritzBoxIPv4Field, "198.51.100.1"))
+		case r.Header.Get("SOAPAction") == `"`+fritzBoxIGDService+"#"+fritzBoxIPv6Action+`"`:
+			_, _ = io.WriteString(w, fritzBoxResponse(fritzBoxIPv6Action, fritzBoxIPv6Field, "2001:db8::1"))
+		default:
+			http.Error(w, "unknown action", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	if ip, err := NewFritzBoxFetcher(server.URL, IPv4, time.Second, nil).Fetch(context.Background()); err != nil || ip != "198.51.100.1" {
+		t.Errorf("IPv4 の Fetch() = %q, %v", ip, err)
+	}
+	if ip, err := NewFritzBoxFetcher(server.URL+"/", IPv6, time.Second, nil).Fetch(context.Background()); err != nil || ip != "2001:db8::1" {
+		t.Errorf("IPv6 の Fetch() = %q, %v", ip, err)
+	}
+}
+
+// TestFritzBoxFetcher_TR064Digest は、認証情報がある場合に TR-064 にダイジェスト認証で問い合わせることをテストします。
+func TestFritzBoxFetcher_TR064Digest(t *testing.T) {
+	const realm, nonce = "F!Box SOAP-Auth", "ABCDEF0123456789"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fritzBoxTR064Control {
+			http.NotFound(w, r)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm=%q, nonce=%q, algorithm=MD5, qop="auth"`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := parseAuthParams(strings.TrimPrefix(auth, "Digest "))
+		ha1 := md5Hex("admin:" + realm + ":secret")
+		ha2 := md5Hex(r.Method + ":" + r.URL.RequestURI())
+		want := md5Hex(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if params["username"] != "admin" || params["response"] != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, fritzBoxResponse(fritzBoxIPv4Action, fritzBoxIPv4Field, "198.51.100.2"))
+	}))
+	defer server.Close()
+
+	fetcher := NewFritzBoxFetcher(server.URL, IPv4, time.Second, nil)
+	fetcher.Username, fetcher.Password = "admin", "secret"
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.2" {
+		t.Errorf("Fetch() = %q, %v", ip, err)
+	}
+
+	fetcher.Password = "wrong"
+	if _, err := fetcher.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "認証に失敗") {
+		t.Errorf("パスワードが違う場合は認証のエラーになるべき: %v", err)
+	}
+}
+
+// TestFritzBoxFetcher_NoAddress は、WAN が接続されていない場合にエラーになることをテストします。
+func TestFritzBoxFetcher_NoAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, fritzBoxResponse(fritzBoxIPv4Action, fritzBoxIPv4Field, ""))
+	}))
+	defer server.Close()
+
+	if _, err := NewFritzBoxFetcher(server.URL, IPv4, time.Second, nil).Fetch(context.Background()); err == nil {
+		t.Error("IPアドレスがない場合はエラーになるべき")
+	}
+}
+
+// TestParseAuthParams は、引用符の中のカンマを含む認証パラメーターを解析できることをテストします。
+func TestParseAuthParams(t *testing.T) {
+	params := parseAuthParams(`realm="a, b", nonce=xyz, qop="auth,auth-int", opaque=""`)
+	if params["realm"] != "a, b" || params["nonce"] != "xyz" || params["qop"] != "auth,auth-int" {
+		t.Errorf("parseAuthParams() = %v", params)
+	}
+	if value, ok := params["opaque"]; !ok || value != "" {
+		t.Errorf("空の opaque がありません: %v", params)
+	}
+}
@@ -0,0 +1,133 @@
+package ip
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// latencyWindow は、ソースごとに覚えておく直近の応答時間の数です。
+const latencyWindow = 32
+
+// LatencyStats は、ソースごとの直近の応答時間の統計です。
+type LatencyStats struct {
+	// Count は、統計に含めた応答時間の数です（最大で直近の 32 回）
+	Count int
+
+	// P50 は、応答時間の中央値です
+	P50 time.Duration
+
+	// P90 は、応答時間の 90 パーセンタイルです
+	P90 time.Duration
+
+	// Max は、応答時間の最大値です
+	Max time.Duration
+}
+
+// latencySamples は、ソースの直近の応答時間を古いものから上書きして覚えておくリングバッファーです。
+type latencySamples struct {
+	// samples は、記録した応答時間です（最大 latencyWindow 個）
+	samples []time.Duration
+
+	// next は、次に上書きする samples のインデックスです
+	next int
+}
+
+// add は、応答時間を記録します。
+func (l *latencySamples) add(latency time.Duration) {
+	if len(l.samples) < latencyWindow {
+		l.samples = append(l.samples, latency)
+		return
+	}
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % latencyWindow
+}
+
+// stats は、記録した応答時間の統計を返します。
+func (l *latencySamples) stats() LatencyStats {
+	sorted := slices.Clone(l.samples)
+	slices.Sort(sorted)
+	return LatencyStats{
+		Count: len(sorted),
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// percentile は、昇順に並べた応答時間の p パーセンタイルを返します（最近傍法）。
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i]
+}
+
+// observeLatency は、ソースから取得できたときの応答時間を記録します。
+// 失敗した場合の時間はタイムアウトまでの時間になることが多いため、記録しません。
+func (mf *MultipleFetcher) observeLatency(url string, latency time.Duration) {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	if mf.latencies == nil {
+		mf.latencies = make(map[string]*latencySamples)
+	}
+	samples, ok := mf.latencies[url]
+	if !ok {
+		samples = &latencySamples{}
+		mf.latencies[url] = samples
+	}
+	samples.add(latency)
+}
+
+// Latencies は、取得できたことがあるソースごとの、直近の応答時間の統計を返します。
+//
+// Returns:
+//   - map[string]LatencyStats: ソースの URL ごとの応答時間の統計
+func (mf *MultipleFetcher) Latencies() map[string]LatencyStats {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	stats := make(map[string]LatencyStats, len(mf.latencies))
+	for url, samples := range mf.latencies {
+		stats[url] = samples.stats()
+	}
+	return stats
+}
+
+// fastestOrder は、StrategyFastest で問い合わせる順番（URLs のインデックス）を返します。
+// 失敗が続いていないソースを先に、応答時間の中央値が短い順に並べます。
+// まだ応答時間がわからないソースは、計測するために先に試します。
+func (mf *MultipleFetcher) fastestOrder() []int {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	type entry struct {
+		index   int
+		failing bool
+		p50     time.Duration
+	}
+	entries := make([]entry, len(mf.URLs))
+	for i, url := range mf.URLs {
+		entries[i] = entry{index: i, failing: mf.failures[url] > 0}
+		if samples, ok := mf.latencies[url]; ok {
+			entries[i].p50 = samples.stats().P50
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		if a.failing != b.failing {
+			if a.failing {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.p50, b.p50)
+	})
+
+	indexes := make([]int, len(entries))
+	for i, e := range entries {
+		indexes[i] = e.index
+	}
+	return indexes
+}
@@ -0,0 +1,79 @@
+package ip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestLatencySamples_Stats は、直近の応答時間の中央値と 90 パーセンタイル、最大値をテストします。
+func TestLatencySamples_Stats(t *testing.T) {
+	var samples latencySamples
+	for i := 1; i <= 10; i++ {
+		samples.add(time.Duration(i) * time.Millisecond)
+	}
+
+	got := samples.stats()
+	want := LatencyStats{Count: 10, P50: 5 * time.Millisecond, P90: 9 * time.Millisecond, Max: 10 * time.Millisecond}
+	if got != want {
+		t.Errorf("stats() = %+v, 期待値 %+v", got, want)
+	}
+}
+
+// TestLatencySamples_Window は、直近の latencyWindow 回だけを覚えておくことをテストします。
+func TestLatencySamples_Window(t *testing.T) {
+	var samples latencySamples
+	for i := 0; i < latencyWindow; i++ {
+		samples.add(time.Second)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		samples.add(time.Millisecond)
+	}
+
+	if got := samples.stats(); got.Count != latencyWindow || got.Max != time.Millisecond {
+		t.Errorf("stats() = %+v, 古い応答時間は含まないべき", got)
+	}
+}
+
+// TestMultipleFetcher_StrategyFastest は、応答時間を計測して、速いソースから問い合わせるようになることをテストします。
+func TestMultipleFetcher_StrategyFastest(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("198.51.100.1"))
+	}))
+	defer slow.Close()
+	fast := newIPServers(t, "198.51.100.2")[0]
+
+	fetcher := NewMultipleFetcher([]string{slow.URL, fast})
+	fetcher.Strategy = StrategyFastest
+	// 1回目と2回目は、まだ応答時間がわからないソースを順に計測する
+	for i, want := range []string{slow.URL, fast, fast, fast} {
+		_, source, err := fetcher.FetchWithSource(context.Background())
+		if err != nil || source != want {
+			t.Fatalf("%d 回目: ソース = %q, %v; 期待値 %q", i+1, source, err, want)
+		}
+	}
+
+	stats := fetcher.Latencies()
+	if stats[slow.URL].Count != 1 || stats[fast].Count != 3 {
+		t.Errorf("計測した回数 = %d / %d, 期待値 1 / 3", stats[slow.URL].Count, stats[fast].Count)
+	}
+	if stats[slow.URL].P50 < 50*time.Millisecond {
+		t.Errorf("遅いソースの中央値 = %s, 期待値 50ms 以上", stats[slow.URL].P50)
+	}
+}
+
+// TestMultipleFetcher_StrategyFastestFailing は、失敗が続いているソースは速くても後に回すことをテストします。
+func TestMultipleFetcher_StrategyFastestFailing(t *testing.T) {
+	urls := newIPServers(t, "198.51.100.1", "198.51.100.2")
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.Strategy = StrategyFastest
+	fetcher.FailureThreshold = 3
+	fetcher.failures = map[string]int{urls[0]: 1}
+
+	if _, source, err := fetcher.FetchWithSource(context.Background()); err != nil || source != urls[1] {
+		t.Errorf("ソース = %q, %v; 期待値 %q", source, err, urls[1])
+	}
+}
@@ -0,0 +1,196 @@
+package ip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// openWrtAnonymousSession は、ログインせずに ubus を呼び出す場合のセッション ID です。
+const openWrtAnonymousSession = "00000000000000000000000000000000"
+
+// OpenWrtFetcher は、OpenWrt に ubus の JSON-RPC（LuCI の /ubus）で WAN インターフェースの IPアドレスを問い合わせる Fetcher です。
+// 外部のサービスに問い合わせずに、ルーターが PPPoE などで割り当てられたアドレスを取得できます。
+type OpenWrtFetcher struct {
+	// URL は、ubus の JSON-RPC のエンドポイントです（例: "http://192.168.1.1/ubus"。パスを省略した場合は /ubus）
+	URL string
+
+	// Family は、取得するIPアドレスの種類です
+	Family Family
+
+	// Username は、ubus にログインするユーザー名です（空の場合はログインせずに呼び出します）
+	Username string
+
+	// Password は、ubus にログインするパスワードです
+	Password string
+
+	// Interface は、IPアドレスを取得するインターフェースの名前です（空の場合は IPv4 は "wan"、IPv6 は "wan6"）
+	Interface string
+
+	// MaxResponseSize は、読み込むレスポンスの最大サイズ（バイト）です（0 の場合は httpclient.DefaultMaxResponseSize）
+	MaxResponseSize int64
+
+	// client は、OpenWrt に問い合わせる HTTP クライアントです
+	client *http.Client
+}
+
+// NewOpenWrtFetcher は、OpenWrt に問い合わせる OpenWrtFetcher を作成します。
+//
+// Parameters:
+//   - url: ubus の JSON-RPC のエンドポイント（例: "http://192.168.1.1/ubus"）
+//   - family: 取得するIPアドレスの種類
+//   - timeout: HTTPリクエストのタイムアウト
+//   - transport: 通信に使う Transport（nil の場合は http.DefaultTransport）
+//
+// Returns:
+//   - *OpenWrtFetcher: 作成された OpenWrtFetcher
+func NewOpenWrtFetcher(url string, family Family, timeout time.Duration, transport *http.Transport) *OpenWrtFetcher {
+	return &OpenWrtFetcher{
+		URL:    url,
+		Family: family,
+		client: newRouterClient(timeout, transport),
+	}
+}
+
+// openWrtInterfaceStatus は、network.interface.<名前> の status の応答のうち、使う部分です。
+type openWrtInterfaceStatus struct {
+	Up            bool `json:"up"`
+	IPv4Addresses []struct {
+		Address string `json:"address"`
+	} `json:"ipv4-address"`
+	IPv6Addresses []struct {
+		Address string `json:"address"`
+	} `json:"ipv6-address"`
+}
+
+// Fetch は、OpenWrt から WAN インターフェースの IPアドレスを取得します。
+// ログインしてから、インターフェースの状態を問い合わせます。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - string: 取得したIPアドレス
+//   - error: 問い合わせに失敗した場合、またはインターフェースに有効なIPアドレスがない場合
+func (f *OpenWrtFetcher) Fetch(ctx context.Context) (string, error) {
+	endpoint := f.endpoint()
+
+	session := openWrtAnonymousSession
+	if f.Username != "" {
+		var login struct {
+			Session string `json:"ubus_rpc_session"`
+		}
+		args := map[string]string{"username": f.Username, "password": f.Password}
+		if err := f.call(ctx, endpoint, openWrtAnonymousSession, "session", "login", args, &login); err != nil {
+			return "", fmt.Errorf("OpenWrt へのログインに失敗しました (URL: %s): %w", endpoint, err)
+		}
+		session = login.Session
+	}
+
+	iface := f.Interface
+	if iface == "" {
+		iface = "wan"
+		if f.Family == IPv6 {
+			iface = "wan6"
+		}
+	}
+	var status openWrtInterfaceStatus
+	if err := f.call(ctx, endpoint, session, "network.interface."+iface, "status", struct{}{}, &status); err != nil {
+		return "", fmt.Errorf("OpenWrt のインターフェース %s の状態を取得できません (URL: %s): %w", iface, endpoint, err)
+	}
+	if !status.Up {
+		return "", fmt.Errorf("OpenWrt のインターフェース %s が接続されていません (URL: %s)", iface, endpoint)
+	}
+
+	var addresses []string
+	if f.Family == IPv6 {
+		for _, addr := range status.IPv6Addresses {
+			addresses = append(addresses, addr.Address)
+		}
+	} else {
+		for _, addr := range status.IPv4Addresses {
+			addresses = append(addresses, addr.Address)
+		}
+	}
+	if len(addresses) == 0 {
+		return "", fmt.Errorf("OpenWrt のインターフェース %s に IPアドレスがありません (URL: %s)", iface, endpoint)
+	}
+	for _, addr := range addresses {
+		if f.Family.Validate(addr) == nil {
+			return addr, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s (URL: %s, インターフェース: %s)", ErrInvalidIP, strings.Join(addresses, ", "), endpoint, iface)
+}
+
+// endpoint は、ubus の JSON-RPC のエンドポイントを返します（パスを省略した場合は /ubus）。
+func (f *OpenWrtFetcher) endpoint() string {
+	u, err := url.Parse(f.URL)
+	if err != nil || (u.Path != "" && u.Path != "/") {
+		return f.URL
+	}
+	u.Path = "/ubus"
+	return u.String()
+}
+
+// call は、ubus のオブジェクトのメソッドを JSON-RPC で呼び出し、結果を result に読み込みます。
+func (f *OpenWrtFetcher) call(ctx context.Context, endpoint, session, object, method string, args, result any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "call",
+		"params":  []any{session, object, method, args},
+	})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	resp, data, err := postRouter(ctx, f.client, endpoint, header, body, f.MaxResponseSize)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTPステータスエラー: %d", resp.StatusCode)
+	}
+
+	// 結果は [ステータスコード, 値] の配列で、0 以外は失敗（6 は権限がない）
+	var reply struct {
+		Result []json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return fmt.Errorf("応答を解析できません: %w", err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("ubus のエラー: %s (コード: %d)", reply.Error.Message, reply.Error.Code)
+	}
+	if len(reply.Result) == 0 {
+		return fmt.Errorf("ubus の応答に結果がありません")
+	}
+	var code int
+	if err := json.Unmarshal(reply.Result[0], &code); err != nil {
+		return fmt.Errorf("応答を解析できません: %w", err)
+	}
+	if code != 0 {
+		if code == 6 {
+			return fmt.Errorf("ubus の呼び出しが拒否されました (コード: 6)。ユーザー名とパスワード、ACL の設定を確認してください")
+		}
+		return fmt.Errorf("ubus の呼び出しに失敗しました (コード: %d)", code)
+	}
+	if len(reply.Result) < 2 {
+		return fmt.Errorf("ubus の応答に値がありません")
+	}
+	if err := json.Unmarshal(reply.Result[1], result); err != nil {
+		return fmt.Errorf("応答を解析できません: %w", err)
+	}
+	return nil
+}
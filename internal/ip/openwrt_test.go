@@ -0,0 +1,134 @@
+package ip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newUbusServer は、ログインと network.interface の status に答える ubus のテスト用サーバーを作成します。
+func newUbusServer(t *testing.T, status map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ubus" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) != 4 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var session, object string
+		_ = json.Unmarshal(req.Params[0], &session)
+		_ = json.Unmarshal(req.Params[1], &object)
+
+		var result []any
+		switch {
+		case object == "session":
+			var args map[string]string
+			_ = json.Unmarshal(req.Params[3], &args)
+			if args["username"] != "root" || args["password"] != "secret" {
+				result = []any{6}
+				break
+			}
+			result = []any{0, map[string]string{"ubus_rpc_session": "session-1"}}
+		case session != "session-1":
+			result = []any{6}
+		case strings.HasPrefix(object, "network.interface."):
+			s, ok := status[strings.TrimPrefix(object, "network.interface.")]
+			if !ok {
+				result = []any{4}
+				break
+			}
+			result = []any{0, s}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestOpenWrtFetcher_Fetch は、ログインしてから WAN インターフェースの IPアドレスを取得することをテストします。
+func TestOpenWrtFetcher_Fetch(t *testing.T) {
+	server := newUbusServer(t, map[string]any{
+		"wan": map[string]any{
+			"up":           true,
+			"ipv4-address": []map[string]any{{"address": "198.51.100.3", "mask": 24}},
+		},
+		"wan6": map[string]any{
+			"up":           true,
+			"ipv6-address": []map[string]any{{"address": "2001:db8::3", "mask": 64}},
+		},
+		"pppoe": map[string]any{
+			"up":           true,
+			"ipv4-address": []map[string]any{{"address": "198.51.100.4", "mask": 32}},
+		},
+	})
+
+	fetcher := NewOpenWrtFetcher(server.URL, IPv4, time.Second, nil)
+	fetcher.Username, fetcher.Password = "root", "secret"
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.3" {
+		t.Errorf("IPv4 の Fetch() = %q, %v", ip, err)
+	}
+
+	fetcher.Family = IPv6
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "2001:db8::3" {
+		t.Errorf("IPv6 の Fetch() = %q, %v", ip, err)
+	}
+
+	fetcher.Family, fetcher.Interface = IPv4, "pppoe"
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.4" {
+		t.Errorf("インターフェースを指定した Fetch() = %q, %v", ip, err)
+	}
+}
+
+// TestOpenWrtFetcher_Errors は、ログインの失敗やインターフェースの状態をエラーとして返すことをテストします。
+func TestOpenWrtFetcher_Errors(t *testing.T) {
+	server := newUbusServer(t, map[string]any{
+		"wan":  map[string]any{"up": false},
+		"lan6": map[string]any{"up": true, "ipv4-address": []map[string]any{{"address": "192.0.2.1"}}},
+	})
+
+	fetcher := NewOpenWrtFetcher(server.URL+"/ubus", IPv4, time.Second, nil)
+	fetcher.Username, fetcher.Password = "root", "wrong"
+	if _, err := fetcher.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "ログイン") {
+		t.Errorf("パスワードが違う場合はログインのエラーになるべき: %v", err)
+	}
+
+	fetcher.Password = "secret"
+	if _, err := fetcher.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "接続されていません") {
+		t.Errorf("インターフェースが down の場合はエラーになるべき: %v", err)
+	}
+
+	fetcher.Interface = "missing"
+	if _, err := fetcher.Fetch(context.Background()); err == nil {
+		t.Error("インターフェースがない場合はエラーになるべき")
+	}
+
+	fetcher.Family, fetcher.Interface = IPv6, "lan6"
+	if _, err := fetcher.Fetch(context.Background()); err == nil {
+		t.Error("IPv6 アドレスがない場合はエラーになるべき")
+	}
+}
+
+// TestMultipleFetcher_RouterSource は、SourceOptions の Type でルーターに問い合わせることをテストします。
+func TestMultipleFetcher_RouterSource(t *testing.T) {
+	server := newUbusServer(t, map[string]any{
+		"wan": map[string]any{"up": true, "ipv4-address": []map[string]any{{"address": "198.51.100.5"}}},
+	})
+
+	fetcher := NewMultipleFetcher([]string{server.URL})
+	fetcher.SourceOptions = map[string]SourceOptions{
+		server.URL: {Type: SourceOpenWrt, Username: "root", Password: "secret"},
+	}
+	if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.5" {
+		t.Errorf("Fetch() = %q, %v", ip, err)
+	}
+}
@@ -0,0 +1,132 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ProbeStatus は、ソースを確認した結果の種類です。
+type ProbeStatus int
+
+const (
+	// ProbeOK は、有効なIPアドレスを取得でき、多数派のIPアドレスと一致したことを表します
+	ProbeOK ProbeStatus = iota
+
+	// ProbeMismatch は、有効なIPアドレスを取得できたが、多数派のIPアドレスと一致しないことを表します
+	ProbeMismatch
+
+	// ProbeInvalid は、応答がIPアドレスとして無効だったことを表します
+	ProbeInvalid
+
+	// ProbeError は、接続やHTTPステータスのエラーで取得できなかったことを表します
+	ProbeError
+)
+
+// String は、結果の種類を表示用の文字列で返します。
+func (s ProbeStatus) String() string {
+	switch s {
+	case ProbeOK:
+		return "OK"
+	case ProbeMismatch:
+		return "MISMATCH"
+	case ProbeInvalid:
+		return "INVALID"
+	default:
+		return "ERROR"
+	}
+}
+
+// ProbeResult は、1つのソースを確認した結果です。
+type ProbeResult struct {
+	// URL は、確認したソースのURLです
+	URL string
+
+	// IP は、取得したIPアドレスです（取得できなかった場合は空）
+	IP string
+
+	// Latency は、応答までにかかった時間です
+	Latency time.Duration
+
+	// Status は、結果の種類です
+	Status ProbeStatus
+
+	// Err は、取得できなかった場合のエラーです
+	Err error
+}
+
+// ProbeSources は、すべてのソースに並行して問い合わせ、ソースごとの結果を返します。
+// フェイルオーバーせずにすべてのソースを確認するため、ソースの一覧を選ぶときに使います。
+// 取得できたIPアドレスのうち最も多いものを多数派とし、異なるものは ProbeMismatch になります。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - urls: 確認するIP取得ソースのURLリスト
+//   - family: 取得するIPアドレスの種類
+//   - timeout: ソースごとのタイムアウト
+//
+// Returns:
+//   - []ProbeResult: ソースごとの結果（urls と同じ順）
+//   - string: 多数派のIPアドレス（1つも取得できなかった場合は空）
+func ProbeSources(ctx context.Context, urls []string, family Family, timeout time.Duration) ([]ProbeResult, string) {
+	results := make([]ProbeResult, len(urls))
+
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeSource(ctx, url, family, timeout)
+		}()
+	}
+	wg.Wait()
+
+	majority := MajorityIP(results)
+	for i := range results {
+		if results[i].Err == nil && results[i].IP != majority {
+			results[i].Status = ProbeMismatch
+		}
+	}
+	return results, majority
+}
+
+// probeSource は、1つのソースからIPアドレスを取得して、結果の種類を判定します。
+func probeSource(ctx context.Context, url string, family Family, timeout time.Duration) ProbeResult {
+	start := time.Now()
+	addr, err := NewSourceFetcher(url, family, timeout).Fetch(ctx)
+	result := ProbeResult{URL: url, IP: addr, Latency: time.Since(start), Err: err}
+
+	switch {
+	case err == nil:
+		result.Status = ProbeOK
+	case errors.Is(err, ErrInvalidIP):
+		result.Status = ProbeInvalid
+	default:
+		result.Status = ProbeError
+	}
+	return result
+}
+
+// MajorityIP は、取得できたIPアドレスのうち最も多くのソースが返したものを返します。
+// 同数の場合は、先に現れたIPアドレスを返します。
+//
+// Parameters:
+//   - results: ソースごとの結果
+//
+// Returns:
+//   - string: 多数派のIPアドレス（1つも取得できなかった場合は空）
+func MajorityIP(results []ProbeResult) string {
+	counts := map[string]int{}
+	majority := ""
+	for _, r := range results {
+		if r.Err != nil || r.IP == "" {
+			continue
+		}
+		counts[r.IP]++
+		if counts[r.IP] > counts[majority] {
+			majority = r.IP
+		}
+	}
+	return majority
+}
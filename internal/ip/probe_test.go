@@ -0,0 +1,82 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newIPServer は、固定のレスポンスを返すテスト用のIP取得ソースを作成します。
+func newIPServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestProbeSources は、ソースごとの結果の種類と多数派のIPアドレスをテストします。
+func TestProbeSources(t *testing.T) {
+	agree1 := newIPServer(t, http.StatusOK, "203.0.113.5\n")
+	agree2 := newIPServer(t, http.StatusOK, "203.0.113.5")
+	mismatch := newIPServer(t, http.StatusOK, "198.51.100.7")
+	invalid := newIPServer(t, http.StatusOK, "<html>error</html>")
+	failing := newIPServer(t, http.StatusServiceUnavailable, "")
+
+	urls := []string{agree1.URL, mismatch.URL, invalid.URL, agree2.URL, failing.URL}
+	results, majority := ProbeSources(context.Background(), urls, IPv4, time.Second)
+
+	if majority != "203.0.113.5" {
+		t.Errorf("多数派のIPアドレスが一致しません。期待: 203.0.113.5, 実際: %s", majority)
+	}
+	if len(results) != len(urls) {
+		t.Fatalf("結果の数が一致しません。期待: %d, 実際: %d", len(urls), len(results))
+	}
+
+	want := []ProbeStatus{ProbeOK, ProbeMismatch, ProbeInvalid, ProbeOK, ProbeError}
+	for i, r := range results {
+		if r.URL != urls[i] {
+			t.Errorf("[%d] URLの順番が一致しません。期待: %s, 実際: %s", i, urls[i], r.URL)
+		}
+		if r.Status != want[i] {
+			t.Errorf("[%d] 結果の種類が一致しません。期待: %s, 実際: %s (エラー: %v)", i, want[i], r.Status, r.Err)
+		}
+	}
+
+	if results[1].IP != "198.51.100.7" {
+		t.Errorf("不一致のソースのIPアドレスが記録されていません: %q", results[1].IP)
+	}
+	if !errors.Is(results[2].Err, ErrInvalidIP) {
+		t.Errorf("無効な応答のエラーが ErrInvalidIP ではありません: %v", results[2].Err)
+	}
+}
+
+// TestProbeSources_AllFail は、すべて失敗した場合に多数派が空になることをテストします。
+func TestProbeSources_AllFail(t *testing.T) {
+	failing := newIPServer(t, http.StatusInternalServerError, "")
+
+	results, majority := ProbeSources(context.Background(), []string{failing.URL}, IPv4, time.Second)
+	if majority != "" {
+		t.Errorf("多数派のIPアドレスが空ではありません: %s", majority)
+	}
+	if results[0].Status != ProbeError {
+		t.Errorf("結果の種類が一致しません。期待: ERROR, 実際: %s", results[0].Status)
+	}
+}
+
+// TestMajorityIP_Tie は、同数の場合に先に現れたIPアドレスが選ばれることをテストします。
+func TestMajorityIP_Tie(t *testing.T) {
+	results := []ProbeResult{
+		{IP: "192.0.2.1"},
+		{IP: "192.0.2.2"},
+		{Err: errors.New("失敗")},
+	}
+	if got := MajorityIP(results); got != "192.0.2.1" {
+		t.Errorf("多数派のIPアドレスが一致しません。期待: 192.0.2.1, 実際: %s", got)
+	}
+}
@@ -0,0 +1,56 @@
+package ip
+
+import (
+	"context"
+	"time"
+)
+
+// FetchResult は、IPアドレスの取得の詳細な結果です。
+// どのソースが応答したかをログや履歴で推測しなくてよいように、取得したIPアドレスと一緒に返します。
+type FetchResult struct {
+	// IP は、取得したIPアドレスです
+	IP string
+
+	// Source は、IPアドレスを返したソースのURLです（わからない場合は空）
+	Source string
+
+	// Latency は、IPアドレスを返したソースの応答までにかかった時間です（キャッシュした結果の場合は 0）
+	Latency time.Duration
+
+	// Attempts は、問い合わせたソースの数です（スキップしたソースは含みません）
+	Attempts int
+
+	// Cached は、ソースに問い合わせずに、キャッシュした結果を返した場合に true です
+	Cached bool
+}
+
+// DetailedFetcher は、取得したIPアドレスと一緒に詳細な結果を返す Fetcher です。
+type DetailedFetcher interface {
+	Fetcher
+
+	// FetchDetailed は、Fetch と同様にIPアドレスを取得し、応答したソースなどの詳細な結果を返します。
+	FetchDetailed(ctx context.Context) (FetchResult, error)
+}
+
+// FetchDetailed は、Fetcher からIPアドレスを取得して、詳細な結果を返します。
+// DetailedFetcher でない Fetcher の場合は、Fetch にかかった時間を Latency として、1回問い合わせたものとします。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - f: IPアドレスを取得する Fetcher
+//
+// Returns:
+//   - FetchResult: 取得の詳細な結果
+//   - error: 取得できなかった場合
+func FetchDetailed(ctx context.Context, f Fetcher) (FetchResult, error) {
+	if d, ok := f.(DetailedFetcher); ok {
+		return d.FetchDetailed(ctx)
+	}
+
+	start := time.Now()
+	addr, err := f.Fetch(ctx)
+	if err != nil {
+		return FetchResult{Attempts: 1}, err
+	}
+	return FetchResult{IP: addr, Latency: time.Since(start), Attempts: 1}, nil
+}
@@ -0,0 +1,72 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fetcherFunc は、関数を Fetcher として使うテスト用の型です。
+type fetcherFunc func(ctx context.Context) (string, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// TestMultipleFetcher_FetchDetailed は、応答したソースと問い合わせたソースの数が返されることをテストします。
+func TestMultipleFetcher_FetchDetailed(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	urls := append([]string{failing.URL}, newIPServers(t, "198.51.100.7")...)
+
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.CacheTTL = time.Hour
+	result, err := fetcher.FetchDetailed(context.Background())
+	if err != nil {
+		t.Fatalf("取得に失敗しました: %v", err)
+	}
+	if result.IP != "198.51.100.7" || result.Source != urls[1] || result.Attempts != 2 || result.Latency <= 0 || result.Cached {
+		t.Errorf("FetchDetailed() = %+v", result)
+	}
+
+	// キャッシュした結果は、問い合わせずに返す
+	result, err = fetcher.FetchDetailed(context.Background())
+	if err != nil || !result.Cached || result.Attempts != 0 || result.Source != urls[1] {
+		t.Errorf("キャッシュした結果の FetchDetailed() = %+v, %v", result, err)
+	}
+}
+
+// TestMultipleFetcher_FetchDetailedFailure は、すべて失敗した場合も問い合わせたソースの数が返されることをテストします。
+func TestMultipleFetcher_FetchDetailedFailure(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	result, err := NewMultipleFetcher([]string{failing.URL, failing.URL}).FetchDetailed(context.Background())
+	if err == nil || result.Attempts != 2 || result.IP != "" {
+		t.Errorf("FetchDetailed() = %+v, %v; 期待: 2回問い合わせて失敗", result, err)
+	}
+}
+
+// TestFetchDetailed_PlainFetcher は、DetailedFetcher でない Fetcher も詳細な結果にできることをテストします。
+func TestFetchDetailed_PlainFetcher(t *testing.T) {
+	result, err := FetchDetailed(context.Background(), fetcherFunc(func(ctx context.Context) (string, error) {
+		return "198.51.100.7", nil
+	}))
+	if err != nil || result.IP != "198.51.100.7" || result.Attempts != 1 || result.Source != "" {
+		t.Errorf("FetchDetailed() = %+v, %v", result, err)
+	}
+
+	wantErr := errors.New("fetch failed")
+	if _, err := FetchDetailed(context.Background(), fetcherFunc(func(ctx context.Context) (string, error) {
+		return "", wantErr
+	})); !errors.Is(err, wantErr) {
+		t.Errorf("エラー = %v, 期待値 %v", err, wantErr)
+	}
+}
@@ -0,0 +1,97 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// SourceType は、IP取得ソースへの問い合わせ方です。
+type SourceType string
+
+const (
+	// SourceHTTP は、URL に GET リクエストを送り、レスポンスを IPアドレスとして扱うことを表します（デフォルト）
+	SourceHTTP SourceType = "http"
+
+	// SourceFritzBox は、FRITZ!Box に TR-064 / UPnP の SOAP で WAN の IPアドレスを問い合わせることを表します
+	SourceFritzBox SourceType = "fritzbox"
+
+	// SourceOpenWrt は、OpenWrt に ubus の JSON-RPC で WAN インターフェースの IPアドレスを問い合わせることを表します
+	SourceOpenWrt SourceType = "openwrt"
+)
+
+// SourceTypes は、指定できる IP取得ソースへの問い合わせ方です。
+var SourceTypes = []SourceType{SourceHTTP, SourceFritzBox, SourceOpenWrt}
+
+// ParseSourceType は、IP取得ソースへの問い合わせ方の文字列を解析します。
+//
+// Parameters:
+//   - s: 問い合わせ方（"http", "fritzbox", "openwrt"。空の場合は "http"）
+//
+// Returns:
+//   - SourceType: 解析した問い合わせ方
+//   - error: 対応していない問い合わせ方の場合
+func ParseSourceType(s string) (SourceType, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return SourceHTTP, nil
+	}
+	for _, t := range SourceTypes {
+		if s == string(t) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("IP取得ソースの種類 %q には対応していません (http, fritzbox, openwrt のいずれかを指定してください)", s)
+}
+
+// IsRouter は、ルーターに直接問い合わせる種類かどうかを返します。
+func (t SourceType) IsRouter() bool {
+	return t == SourceFritzBox || t == SourceOpenWrt
+}
+
+// newRouterClient は、ルーターに問い合わせる HTTP クライアントを作成します。
+// ルーターには LAN の IPv4 で接続することが多いため、IPv6 のアドレスを問い合わせる場合も IPv6 での接続に限定しません。
+func newRouterClient(timeout time.Duration, transport *http.Transport) *http.Client {
+	return newHTTPClient(IPv4, timeout, transport)
+}
+
+// postRouter は、ルーターに POST リクエストを送り、ステータスとレスポンスボディを返します。
+// 認証の失敗に対応できるように、200 以外のステータスもエラーにせずに返します。
+//
+// Parameters:
+//   - ctx: キャンセルを制御するコンテキスト
+//   - client: 問い合わせに使う HTTP クライアント
+//   - url: 送信先の URL
+//   - header: リクエストに付けるヘッダー
+//   - body: リクエストボディ
+//   - maxResponseSize: 読み込むレスポンスの最大サイズ（0 の場合は httpclient.DefaultMaxResponseSize）
+//
+// Returns:
+//   - *http.Response: レスポンス（ボディは読み込み済みで閉じています）
+//   - []byte: レスポンスボディ
+//   - error: 通信に失敗した場合
+func postRouter(ctx context.Context, client *http.Client, url string, header http.Header, body []byte, maxResponseSize int64) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("リクエスト作成に失敗しました: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ルーターへのリクエストに失敗しました (%s): %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := httpclient.ReadBody(resp.Body, maxResponseSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("レスポンス読み込みに失敗しました (URL: %s): %w", url, err)
+	}
+	return resp, data, nil
+}
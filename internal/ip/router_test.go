@@ -0,0 +1,29 @@
+package ip
+
+import "testing"
+
+// TestParseSourceType は、IP取得ソースの種類を解析できることをテストします。
+func TestParseSourceType(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    SourceType
+		wantErr bool
+	}{
+		{input: "", want: SourceHTTP},
+		{input: "http", want: SourceHTTP},
+		{input: " FritzBox ", want: SourceFritzBox},
+		{input: "openwrt", want: SourceOpenWrt},
+		{input: "upnp", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSourceType(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSourceType(%q) のエラー = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSourceType(%q) = %q, 期待値 %q", tt.input, got, tt.want)
+		}
+	}
+}
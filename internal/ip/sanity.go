@@ -0,0 +1,195 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+)
+
+// ErrSuspiciousIP は、ソースの応答が疑わしい（キャプティブポータルの HTML や LAN のアドレスなど）ことを表すエラーです。
+var ErrSuspiciousIP = errors.New("疑わしいIPアドレス")
+
+// SuspiciousIPError は、ソースの応答が疑わしいため使わなかったことを表すエラーです。
+// errors.Is で ErrSuspiciousIP と ErrInvalidIP のどちらにも一致します。
+type SuspiciousIPError struct {
+	// IP は、ソースが返したIPアドレスです（HTML の場合は空）
+	IP string
+
+	// Source は、疑わしい応答を返したソースのURLです
+	Source string
+
+	// Reason は、疑わしいと判断した理由です
+	Reason string
+}
+
+// Error は、疑わしいと判断した理由を含むエラーメッセージを返します。
+func (e *SuspiciousIPError) Error() string {
+	if e.IP == "" {
+		return fmt.Sprintf("%s: %s (URL: %s)", ErrSuspiciousIP, e.Reason, e.Source)
+	}
+	return fmt.Sprintf("%s %s: %s (URL: %s)", ErrSuspiciousIP, e.IP, e.Reason, e.Source)
+}
+
+// Is は、ErrSuspiciousIP と ErrInvalidIP に一致します。
+func (e *SuspiciousIPError) Is(target error) bool {
+	return target == ErrSuspiciousIP || target == ErrInvalidIP
+}
+
+// SuspiciousAction は、取得したIPアドレスが疑わしい場合の扱いです。
+type SuspiciousAction string
+
+const (
+	// SuspiciousIgnore は、疑わしいかどうかを確認しないことを表します（MultipleFetcher のデフォルト）
+	SuspiciousIgnore SuspiciousAction = "ignore"
+
+	// SuspiciousWarn は、疑わしいIPアドレスを警告ログに出して、そのまま使うことを表します
+	SuspiciousWarn SuspiciousAction = "warn"
+
+	// SuspiciousReject は、疑わしいIPアドレスを使わずに、そのソースの失敗として次のソースを試すことを表します
+	SuspiciousReject SuspiciousAction = "reject"
+)
+
+// SuspiciousActions は、指定できる疑わしいIPアドレスの扱いです。
+var SuspiciousActions = []SuspiciousAction{SuspiciousIgnore, SuspiciousWarn, SuspiciousReject}
+
+// ParseSuspiciousAction は、疑わしいIPアドレスの扱いの文字列を解析します。
+//
+// Parameters:
+//   - s: 疑わしいIPアドレスの扱い（"ignore", "warn", "reject"。空の場合は "ignore"）
+//
+// Returns:
+//   - SuspiciousAction: 解析した扱い
+//   - error: 対応していない扱いの場合
+func ParseSuspiciousAction(s string) (SuspiciousAction, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return SuspiciousIgnore, nil
+	}
+	for _, action := range SuspiciousActions {
+		if s == string(action) {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("疑わしいIPアドレスの扱い %q には対応していません (ignore, warn, reject のいずれかを指定してください)", s)
+}
+
+// NonGlobalReason は、IPアドレスがインターネットから届かないアドレスの場合に、その種類を返します。
+// プライベートアドレスや CGNAT（100.64.0.0/10）、ループバックなどは、DNS に登録しても外から接続できません。
+//
+// Parameters:
+//   - ip: 確認するIPアドレス
+//
+// Returns:
+//   - string: アドレスの種類（グローバルアドレスの場合は空）
+func NonGlobalReason(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	switch {
+	case addr.IsUnspecified():
+		return "未指定のアドレス"
+	case addr.IsLoopback():
+		return "ループバックアドレス"
+	case addr.IsPrivate():
+		return "プライベートアドレス"
+	case addr.IsLinkLocalUnicast():
+		return "リンクローカルアドレス"
+	case addr.IsMulticast():
+		return "マルチキャストアドレス"
+	case cgnatPrefix.Contains(addr):
+		return "CGNAT のアドレス"
+	}
+	return ""
+}
+
+// cgnatPrefix は、キャリアグレード NAT で使う共有アドレス空間（RFC 6598）です。
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// looksLikeHTML は、応答が HTML（キャプティブポータルのログインページなど）に見えるかどうかを返します。
+func looksLikeHTML(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(string(body)), "<")
+}
+
+// samePrefix は、2つのIPアドレスが同じネットワークにあるとみなせるかどうかを返します。
+// IPv4 は /16、IPv6 は /48 が同じなら、ソースによる違いの範囲とみなします。
+func samePrefix(a, b string) bool {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	addrA, addrB = addrA.Unmap(), addrB.Unmap()
+	if addrA.Is4() != addrB.Is4() {
+		return false
+	}
+	bits := 48
+	if addrA.Is4() {
+		bits = 16
+	}
+	prefixA, _ := addrA.Prefix(bits)
+	prefixB, _ := addrB.Prefix(bits)
+	return prefixA == prefixB
+}
+
+// checkSuspicious は、取得したIPアドレスが疑わしいかどうかを確認します。
+// CrossCheck が有効な場合は、remaining のうち最初に取得できたソースの結果とも比べます。
+// 疑わしい場合は、SuspiciousWarn なら警告ログを出して nil を、SuspiciousReject なら SuspiciousIPError を返します。
+func (mf *MultipleFetcher) checkSuspicious(ctx context.Context, addr, source string, remaining []int) error {
+	if mf.Suspicious == "" || mf.Suspicious == SuspiciousIgnore {
+		return nil
+	}
+
+	reason := NonGlobalReason(addr)
+	if reason != "" {
+		reason = "インターネットから届かない" + reason + "です"
+	} else if mf.CrossCheck {
+		reason = mf.crossCheck(ctx, addr, source, remaining)
+	}
+	if reason == "" {
+		return nil
+	}
+
+	if mf.Suspicious == SuspiciousReject {
+		return &SuspiciousIPError{IP: addr, Source: source, Reason: reason}
+	}
+	slog.Warn("IP取得ソースの結果が疑わしいですが、そのまま使います",
+		"url", source,
+		"ip", addr,
+		"reason", reason,
+	)
+	return nil
+}
+
+// crossCheck は、別のソースからもIPアドレスを取得して比べ、大きく異なる場合はその理由を返します。
+// 別のソースから取得できなかった場合は、比べられないため疑わしいとはしません。
+func (mf *MultipleFetcher) crossCheck(ctx context.Context, addr, source string, remaining []int) string {
+	for _, i := range remaining {
+		url := mf.URLs[i]
+		if url == source || strings.TrimSpace(url) == "" || mf.rateLimitWait(url) > 0 || mf.blacklistWait(url) > 0 {
+			continue
+		}
+
+		fetcher := newSourceFetcher(url, mf.Family, mf.timeout, mf.Transport, mf.MaxResponseSize, mf.SourceOptions[url])
+		other, err := fetcher.Fetch(ctx)
+		if err != nil {
+			slog.Debug("確認に使う IP取得ソースから取得できませんでした",
+				"url", url,
+				"error", err,
+			)
+			continue
+		}
+		if samePrefix(addr, other) {
+			return ""
+		}
+		return fmt.Sprintf("別のソース %s が返した %s と大きく異なります", url, other)
+	}
+	return ""
+}
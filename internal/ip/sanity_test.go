@@ -0,0 +1,137 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseSuspiciousAction は、疑わしいIPアドレスの扱いの解析をテストします。
+func TestParseSuspiciousAction(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    SuspiciousAction
+		wantErr bool
+	}{
+		{"", SuspiciousIgnore, false},
+		{"warn", SuspiciousWarn, false},
+		{" Reject ", SuspiciousReject, false},
+		{"block", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSuspiciousAction(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSuspiciousAction(%q) = %q, %v; 期待値 %q (エラー: %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestNonGlobalReason は、インターネットから届かないアドレスの判定をテストします。
+func TestNonGlobalReason(t *testing.T) {
+	tests := []struct {
+		ip        string
+		nonGlobal bool
+	}{
+		{"198.51.100.7", false},
+		{"8.8.8.8", false},
+		{"2001:db8::1", false},
+		{"192.168.1.10", true},
+		{"10.0.0.1", true},
+		{"172.16.5.4", true},
+		{"100.64.1.1", true},
+		{"127.0.0.1", true},
+		{"169.254.1.1", true},
+		{"0.0.0.0", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::1", true},
+		{"::ffff:192.168.1.1", true},
+	}
+
+	for _, tt := range tests {
+		if got := NonGlobalReason(tt.ip) != ""; got != tt.nonGlobal {
+			t.Errorf("NonGlobalReason(%q) が空でない = %v, 期待値 %v", tt.ip, got, tt.nonGlobal)
+		}
+	}
+}
+
+// TestSamePrefix は、同じネットワークとみなすアドレスの判定をテストします。
+func TestSamePrefix(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"198.51.100.7", "198.51.100.7", true},
+		{"198.51.100.7", "198.51.5.1", true},
+		{"198.51.100.7", "203.0.113.7", false},
+		{"2001:db8:1::1", "2001:db8:1:ff::2", true},
+		{"2001:db8:1::1", "2001:db8:2::1", false},
+		{"198.51.100.7", "2001:db8::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := samePrefix(tt.a, tt.b); got != tt.want {
+			t.Errorf("samePrefix(%q, %q) = %v, 期待値 %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestHTTPFetcher_CaptivePortal は、HTML が返された場合に疑わしい応答として扱うことをテストします。
+func TestHTTPFetcher_CaptivePortal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html><body>Welcome to Hotel WiFi</body></html>"))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPFetcher(server.URL).Fetch(context.Background())
+	var suspicious *SuspiciousIPError
+	if !errors.As(err, &suspicious) || !errors.Is(err, ErrSuspiciousIP) || !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("疑わしい応答のエラーになるべき: %v", err)
+	}
+}
+
+// TestMultipleFetcher_SuspiciousLAN は、LAN のアドレスを返すソースの扱いをテストします。
+func TestMultipleFetcher_SuspiciousLAN(t *testing.T) {
+	urls := newIPServers(t, "192.168.1.10", "198.51.100.7")
+
+	tests := []struct {
+		action SuspiciousAction
+		want   string
+	}{
+		{SuspiciousIgnore, "192.168.1.10"},
+		{SuspiciousWarn, "192.168.1.10"},
+		{SuspiciousReject, "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		fetcher := NewMultipleFetcher(urls)
+		fetcher.Suspicious = tt.action
+		if got, err := fetcher.Fetch(context.Background()); err != nil || got != tt.want {
+			t.Errorf("%s: Fetch() = %q, %v; 期待値 %q", tt.action, got, err, tt.want)
+		}
+	}
+}
+
+// TestMultipleFetcher_CrossCheck は、別のソースと大きく異なるIPアドレスを使わないことをテストします。
+func TestMultipleFetcher_CrossCheck(t *testing.T) {
+	urls := newIPServers(t, "203.0.113.50", "198.51.100.7", "198.51.100.8")
+
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.Suspicious = SuspiciousReject
+	fetcher.CrossCheck = true
+	result, err := fetcher.FetchDetailed(context.Background())
+	if err != nil || result.IP != "198.51.100.7" || result.Source != urls[1] {
+		t.Errorf("FetchDetailed() = %+v, %v; 期待: 2つ目のソースの結果", result, err)
+	}
+
+	// 同じネットワークのアドレスなら、そのまま使う
+	fetcher = NewMultipleFetcher(urls[1:])
+	fetcher.Suspicious = SuspiciousReject
+	fetcher.CrossCheck = true
+	if got, err := fetcher.Fetch(context.Background()); err != nil || got != "198.51.100.7" {
+		t.Errorf("Fetch() = %q, %v; 期待値 198.51.100.7", got, err)
+	}
+}
@@ -0,0 +1,64 @@
+package ip
+
+import (
+	"net/http"
+	"time"
+)
+
+// SourceOptions は、IP取得ソースごとのリクエストの設定です。
+// 認証や API キーが必要な自前の IP エコーサーバーを使う場合に指定します。
+type SourceOptions struct {
+	// Type は、ソースへの問い合わせ方です（空の場合は SourceHTTP）
+	Type SourceType
+
+	// Interface は、Type が SourceOpenWrt の場合に IPアドレスを取得するインターフェースの名前です
+	Interface string
+
+	// Headers は、リクエストに付けるヘッダーです（User-Agent も上書きできます）
+	Headers map[string]string
+
+	// Username は、Basic 認証のユーザー名です（ルーターの場合はログインのユーザー名）
+	Username string
+
+	// Password は、Basic 認証のパスワードです（ルーターの場合はログインのパスワード）
+	Password string
+
+	// Extractor は、レスポンスボディから IPアドレスを取り出す方法です（nil の場合はボディ全体を IPアドレスとして扱います）
+	Extractor Extractor
+
+	// MinInterval は、このソースに問い合わせる最小の間隔です（0 の場合は制限しません）
+	// 前回の問い合わせからこの時間が経っていない場合は、キャッシュした結果を使うか、次のソースを試します
+	MinInterval time.Duration
+
+	// CacheTTL は、このソースから取得した結果を再利用する時間です（0 の場合は MinInterval と同じ）
+	CacheTTL time.Duration
+
+	// Weight は、StrategyRandom で最初に問い合わせるソースを選ぶ重みです（0 の場合は 1）
+	Weight int
+}
+
+// weight は、StrategyRandom で使う重みを返します。
+func (o SourceOptions) weight() int {
+	if o.Weight > 0 {
+		return o.Weight
+	}
+	return 1
+}
+
+// cacheTTL は、取得した結果を再利用する時間を返します。
+func (o SourceOptions) cacheTTL() time.Duration {
+	if o.CacheTTL > 0 {
+		return o.CacheTTL
+	}
+	return o.MinInterval
+}
+
+// apply は、ヘッダーと Basic 認証をリクエストに設定します。
+func (o SourceOptions) apply(req *http.Request) {
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	if o.Username != "" || o.Password != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+}
@@ -0,0 +1,130 @@
+package ip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMultipleFetcher_SourceOptions は、ソースごとのヘッダーと Basic 認証がリクエストに付くことをテストします。
+func TestMultipleFetcher_SourceOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if r.Header.Get("X-API-Key") != "secret" || !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+
+	fetcher := NewMultipleFetcher([]string{server.URL})
+	if _, err := fetcher.Fetch(context.Background()); err == nil {
+		t.Fatal("認証なしでは取得できないべき")
+	}
+
+	fetcher.SourceOptions = map[string]SourceOptions{
+		server.URL: {
+			Headers:  map[string]string{"X-API-Key": "secret"},
+			Username: "user",
+			Password: "pass",
+		},
+	}
+	ip, err := fetcher.Fetch(context.Background())
+	if err != nil || ip != "198.51.100.7" {
+		t.Errorf("Fetch() = %q, %v; 期待値 198.51.100.7", ip, err)
+	}
+}
+
+// TestHTTPFetcher_OptionsUserAgent は、ヘッダーで User-Agent を上書きできることをテストします。
+func TestHTTPFetcher_OptionsUserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(server.URL)
+	fetcher.Options = SourceOptions{Headers: map[string]string{"User-Agent": "curl/8.0"}}
+	if _, err := fetcher.Fetch(context.Background()); err != nil {
+		t.Fatalf("取得に失敗しました: %v", err)
+	}
+	if got != "curl/8.0" {
+		t.Errorf("User-Agent = %q, 期待値 curl/8.0", got)
+	}
+}
+
+// TestMultipleFetcher_MinInterval は、最小の間隔の間は前回の結果を使い、ソースに問い合わせないことをテストします。
+func TestMultipleFetcher_MinInterval(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+
+	fetcher := NewMultipleFetcher([]string{server.URL})
+	fetcher.SourceOptions = map[string]SourceOptions{server.URL: {MinInterval: time.Hour}}
+	for i := 0; i < 3; i++ {
+		ip, err := fetcher.Fetch(context.Background())
+		if err != nil || ip != "198.51.100.7" {
+			t.Fatalf("%d 回目: Fetch() = %q, %v", i+1, ip, err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("問い合わせ回数 = %d, 期待値 1", hits.Load())
+	}
+}
+
+// TestMultipleFetcher_MinIntervalSkip は、最小の間隔の間に使える結果がないソースをスキップすることをテストします。
+func TestMultipleFetcher_MinIntervalSkip(t *testing.T) {
+	var failing atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failing.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer good.Close()
+
+	fetcher := NewMultipleFetcher([]string{bad.URL, good.URL})
+	fetcher.SourceOptions = map[string]SourceOptions{bad.URL: {MinInterval: time.Hour}}
+	for i := 0; i < 2; i++ {
+		if ip, err := fetcher.Fetch(context.Background()); err != nil || ip != "198.51.100.7" {
+			t.Fatalf("%d 回目: Fetch() = %q, %v", i+1, ip, err)
+		}
+	}
+	if failing.Load() != 1 {
+		t.Errorf("失敗したソースへの問い合わせ回数 = %d, 期待値 1", failing.Load())
+	}
+}
+
+// TestMultipleFetcher_CacheTTL は、CacheTTL が過ぎたら再びソースに問い合わせることをテストします。
+func TestMultipleFetcher_CacheTTL(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("198.51.100.7"))
+	}))
+	defer server.Close()
+
+	fetcher := NewMultipleFetcher([]string{server.URL})
+	fetcher.SourceOptions = map[string]SourceOptions{server.URL: {CacheTTL: 50 * time.Millisecond}}
+	fetcher.Fetch(context.Background())
+	fetcher.Fetch(context.Background())
+	if hits.Load() != 1 {
+		t.Fatalf("CacheTTL の間の問い合わせ回数 = %d, 期待値 1", hits.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	fetcher.Fetch(context.Background())
+	if hits.Load() != 2 {
+		t.Errorf("CacheTTL が過ぎた後の問い合わせ回数 = %d, 期待値 2", hits.Load())
+	}
+}
@@ -0,0 +1,99 @@
+package ip
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Strategy は、MultipleFetcher が最初に問い合わせるソースの選び方です。
+// どの選び方でも、失敗したら残りのソースを順番に試します。
+type Strategy string
+
+const (
+	// StrategySequential は、いつも URLs の先頭のソースから問い合わせることを表します（デフォルト）
+	StrategySequential Strategy = "sequential"
+
+	// StrategyRoundRobin は、取得するたびに最初に問い合わせるソースを1つずつずらすことを表します
+	StrategyRoundRobin Strategy = "round_robin"
+
+	// StrategyRandom は、最初に問い合わせるソースを SourceOptions の Weight の重みでランダムに選ぶことを表します
+	StrategyRandom Strategy = "random"
+
+	// StrategyFastest は、応答時間の中央値が短いソースから問い合わせることを表します
+	// 失敗が続いているソースは後に回し、まだ応答時間がわからないソースは計測するために先に試します
+	StrategyFastest Strategy = "fastest"
+)
+
+// Strategies は、指定できるソースの選び方です。
+var Strategies = []Strategy{StrategySequential, StrategyRoundRobin, StrategyRandom, StrategyFastest}
+
+// ParseStrategy は、ソースの選び方の文字列を解析します。
+//
+// Parameters:
+//   - s: ソースの選び方（"sequential", "round_robin", "random", "fastest"。空の場合は "sequential"）
+//
+// Returns:
+//   - Strategy: 解析したソースの選び方
+//   - error: 対応していない選び方の場合
+func ParseStrategy(s string) (Strategy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return StrategySequential, nil
+	}
+	for _, strategy := range Strategies {
+		if s == string(strategy) {
+			return strategy, nil
+		}
+	}
+	names := make([]string, len(Strategies))
+	for i, strategy := range Strategies {
+		names[i] = string(strategy)
+	}
+	return "", fmt.Errorf("ソースの選び方 %q には対応していません (%s のいずれかを指定してください)", s, strings.Join(names, ", "))
+}
+
+// order は、ソースを問い合わせる順番（URLs のインデックス）を返します。
+// 最初のソースを Strategy で選び、そこから順番に残りのソースを並べます（StrategyFastest は応答時間の順）。
+func (mf *MultipleFetcher) order() []int {
+	if mf.Strategy == StrategyFastest {
+		return mf.fastestOrder()
+	}
+
+	n := len(mf.URLs)
+	start := 0
+	switch mf.Strategy {
+	case StrategyRoundRobin:
+		mf.mu.Lock()
+		start = mf.next % n
+		mf.next = (mf.next + 1) % n
+		mf.mu.Unlock()
+	case StrategyRandom:
+		start = mf.weightedStart()
+	}
+
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = (start + i) % n
+	}
+	return indexes
+}
+
+// weightedStart は、SourceOptions の Weight の重みでランダムに最初のソースを選びます。
+func (mf *MultipleFetcher) weightedStart() int {
+	weights := make([]int, len(mf.URLs))
+	total := 0
+	for i, url := range mf.URLs {
+		weights[i] = mf.SourceOptions[url].weight()
+		total += weights[i]
+	}
+
+	r := rand.IntN(total)
+	for i, weight := range weights {
+		if r < weight {
+			return i
+		}
+		r -= weight
+	}
+	return 0
+}
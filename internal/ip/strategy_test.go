@@ -0,0 +1,111 @@
+package ip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseStrategy は、ソースの選び方の解析をテストします。
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Strategy
+		wantErr bool
+	}{
+		{"", StrategySequential, false},
+		{"sequential", StrategySequential, false},
+		{"round_robin", StrategyRoundRobin, false},
+		{" Random ", StrategyRandom, false},
+		{"weighted", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStrategy(%q) のエラー = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStrategy(%q) = %q, 期待値 %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+// newIPServers は、それぞれ別の IPアドレスを返すテスト用のサーバーを作成します。
+func newIPServers(t *testing.T, ips ...string) []string {
+	t.Helper()
+	urls := make([]string, len(ips))
+	for i, ip := range ips {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(ip))
+		}))
+		t.Cleanup(server.Close)
+		urls[i] = server.URL
+	}
+	return urls
+}
+
+// TestMultipleFetcher_StrategySequential は、既定ではいつも先頭のソースから問い合わせることをテストします。
+func TestMultipleFetcher_StrategySequential(t *testing.T) {
+	urls := newIPServers(t, "198.51.100.1", "198.51.100.2")
+	fetcher := NewMultipleFetcher(urls)
+	for i := 0; i < 3; i++ {
+		_, source, err := fetcher.FetchWithSource(context.Background())
+		if err != nil || source != urls[0] {
+			t.Fatalf("%d 回目: ソース = %q, %v; 期待値 %q", i+1, source, err, urls[0])
+		}
+	}
+}
+
+// TestMultipleFetcher_StrategyRoundRobin は、取得するたびに最初に問い合わせるソースがずれることをテストします。
+func TestMultipleFetcher_StrategyRoundRobin(t *testing.T) {
+	urls := newIPServers(t, "198.51.100.1", "198.51.100.2", "198.51.100.3")
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.Strategy = StrategyRoundRobin
+	for i := 0; i < 6; i++ {
+		_, source, err := fetcher.FetchWithSource(context.Background())
+		if err != nil || source != urls[i%3] {
+			t.Fatalf("%d 回目: ソース = %q, %v; 期待値 %q", i+1, source, err, urls[i%3])
+		}
+	}
+}
+
+// TestMultipleFetcher_StrategyRoundRobinFailover は、最初のソースが失敗したら次のソースを試すことをテストします。
+func TestMultipleFetcher_StrategyRoundRobinFailover(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	urls := append(newIPServers(t, "198.51.100.1"), failing.URL)
+
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.Strategy = StrategyRoundRobin
+	for i := 0; i < 2; i++ {
+		_, source, err := fetcher.FetchWithSource(context.Background())
+		if err != nil || source != urls[0] {
+			t.Fatalf("%d 回目: ソース = %q, %v; 期待値 %q", i+1, source, err, urls[0])
+		}
+	}
+}
+
+// TestMultipleFetcher_StrategyRandom は、重みに応じて最初に問い合わせるソースを選ぶことをテストします。
+func TestMultipleFetcher_StrategyRandom(t *testing.T) {
+	urls := newIPServers(t, "198.51.100.1", "198.51.100.2")
+	fetcher := NewMultipleFetcher(urls)
+	fetcher.Strategy = StrategyRandom
+	fetcher.SourceOptions = map[string]SourceOptions{urls[1]: {Weight: 1000}}
+
+	counts := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		_, source, err := fetcher.FetchWithSource(context.Background())
+		if err != nil {
+			t.Fatalf("取得に失敗しました: %v", err)
+		}
+		counts[source]++
+	}
+	if counts[urls[1]] < 40 {
+		t.Errorf("重みの大きいソースが選ばれた回数 = %d, 期待値 40 以上", counts[urls[1]])
+	}
+}
@@ -0,0 +1,365 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// serviceAccountDir は、Pod にマウントされるサービスアカウントのトークンと CA 証明書のディレクトリです。
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// maxResponseSize は、API サーバーのレスポンス（watch 以外）として読み込む最大サイズです。
+const maxResponseSize = 16 << 20
+
+// maxEventSize は、watch のストリームから1件のイベントとして読み込む最大サイズです。
+// API サーバーが保存できるオブジェクトの大きさ（etcd の既定では 1.5 MiB）を収められる大きさにします。
+const maxEventSize = 2 << 20
+
+// ErrGone は、watch を再開する resourceVersion が古すぎる（410 Gone）場合のエラーです。
+// 一覧を取得し直してから watch をやり直します。
+var ErrGone = errors.New("resourceVersion が古すぎます (410 Gone)")
+
+// StatusError は、API サーバーが 2xx 以外のステータスを返した場合のエラーです。
+type StatusError struct {
+	// Code は、HTTP のステータスコードです
+	Code int
+
+	// Message は、API サーバーが返した Status の message です
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("Kubernetes API のエラー: %d: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("Kubernetes API のエラー: %d", e.Code)
+}
+
+// IsNotFound は、err が 404 Not Found の StatusError かどうかを返します。
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
+
+// Config は、API サーバーへの接続の設定です。
+type Config struct {
+	// Host は、API サーバーの URL です（例: "https://10.96.0.1:443"、"http://127.0.0.1:8001"）
+	Host string
+
+	// TokenFile は、Bearer トークンのファイルです（空の場合は認証しません）
+	// サービスアカウントのトークンは更新されるため、リクエストのたびに読み込みます
+	TokenFile string
+
+	// CAFile は、API サーバーの証明書を検証する CA 証明書のファイルです（空の場合はシステムの CA）
+	CAFile string
+
+	// Namespace は、Pod の名前空間です（InCluster の場合）
+	Namespace string
+}
+
+// InCluster は、Pod の中で動いている場合の、サービスアカウントを使う Config を返します。
+//
+// Returns:
+//   - Config: API サーバーへの接続の設定
+//   - error: Pod の中で動いていない場合（KUBERNETES_SERVICE_HOST がない場合など）
+func InCluster() (Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return Config{}, fmt.Errorf("Kubernetes の Pod の中ではありません (KUBERNETES_SERVICE_HOST が設定されていません)。-api-server で API サーバーを指定してください")
+	}
+	cfg := Config{
+		Host:      "https://" + net.JoinHostPort(host, port),
+		TokenFile: filepath.Join(serviceAccountDir, "token"),
+		CAFile:    filepath.Join(serviceAccountDir, "ca.crt"),
+	}
+	if data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		cfg.Namespace = strings.TrimSpace(string(data))
+	}
+	return cfg, nil
+}
+
+// Client は、DuckDNSRecord と Secret・ConfigMap・Lease を操作する API クライアントです。
+type Client struct {
+	host       string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// NewClient は、Config から Client を作成します。
+//
+// Parameters:
+//   - cfg: API サーバーへの接続の設定
+//
+// Returns:
+//   - *Client: 作成された Client
+//   - error: URL が不正な場合、または CA 証明書を読み込めない場合
+func NewClient(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.Host)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("API サーバーの URL が不正です: %s", cfg.Host)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("CA 証明書を読み込めません: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 証明書の形式が不正です: %s", cfg.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &Client{
+		host:       strings.TrimSuffix(cfg.Host, "/"),
+		tokenFile:  cfg.TokenFile,
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// recordsPath は、DuckDNSRecord の API のパスを返します（namespace が空の場合はすべての名前空間）。
+func recordsPath(namespace string) string {
+	if namespace == "" {
+		return fmt.Sprintf("/apis/%s/%s/%s", Group, Version, Resource)
+	}
+	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", Group, Version, url.PathEscape(namespace), Resource)
+}
+
+// ListRecords は、DuckDNSRecord の一覧を取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - namespace: 名前空間（空の場合はすべての名前空間）
+//
+// Returns:
+//   - *RecordList: DuckDNSRecord の一覧
+//   - error: 取得に失敗した場合
+func (c *Client) ListRecords(ctx context.Context, namespace string) (*RecordList, error) {
+	var list RecordList
+	if err := c.do(ctx, http.MethodGet, recordsPath(namespace), "", nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// WatchEvent は、watch で受け取った DuckDNSRecord の変更です。
+type WatchEvent struct {
+	// Type は、変更の種類です（"ADDED", "MODIFIED", "DELETED", "BOOKMARK"）
+	Type string
+
+	// Record は、変更後（DELETED の場合は削除前）のオブジェクトです
+	Record Record
+}
+
+// WatchRecords は、resourceVersion からの DuckDNSRecord の変更を監視して、変更ごとに fn を呼び出します。
+// API サーバーが接続を閉じるか ctx がキャンセルされるまで戻りません。
+//
+// Parameters:
+//   - ctx: 監視を止めるためのコンテキスト
+//   - namespace: 名前空間（空の場合はすべての名前空間）
+//   - resourceVersion: 監視を始めるバージョン（ListRecords の結果のもの）
+//   - fn: 変更ごとに呼び出す関数
+//
+// Returns:
+//   - error: 接続が閉じられた場合は nil、resourceVersion が古すぎる場合は ErrGone、そのほかの失敗の場合はエラー
+func (c *Client) WatchRecords(ctx context.Context, namespace, resourceVersion string, fn func(WatchEvent)) error {
+	query := url.Values{
+		"watch":               {"1"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+	}
+	resp, err := c.request(ctx, http.MethodGet, recordsPath(namespace)+"?"+query.Encode(), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := httpclient.NewEventDecoder(resp.Body, maxEventSize)
+	for {
+		var event struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := dec.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("watch のイベントの読み込みに失敗しました: %w", err)
+		}
+
+		if event.Type == "ERROR" {
+			var status apiStatus
+			_ = json.Unmarshal(event.Object, &status)
+			if status.Code == http.StatusGone {
+				return ErrGone
+			}
+			return &StatusError{Code: status.Code, Message: status.Message}
+		}
+
+		var record Record
+		if err := json.Unmarshal(event.Object, &record); err != nil {
+			return fmt.Errorf("watch のイベントの解析に失敗しました: %w", err)
+		}
+		fn(WatchEvent{Type: event.Type, Record: record})
+	}
+}
+
+// PatchRecordStatus は、DuckDNSRecord の status を書き換えます（status サブリソースへの merge patch）。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - namespace: 名前空間
+//   - name: オブジェクトの名前
+//   - status: 書き込む status
+//
+// Returns:
+//   - error: 書き込みに失敗した場合
+func (c *Client) PatchRecordStatus(ctx context.Context, namespace, name string, status RecordStatus) error {
+	body, err := json.Marshal(map[string]any{"status": status})
+	if err != nil {
+		return err
+	}
+	path := recordsPath(namespace) + "/" + url.PathEscape(name) + "/status"
+	return c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", body, nil)
+}
+
+// SecretValue は、Secret のキーの値を取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - namespace: 名前空間
+//   - name: Secret の名前
+//   - key: キー
+//
+// Returns:
+//   - string: キーの値（前後の空白は取り除きます）
+//   - error: Secret やキーがない場合、または取得に失敗した場合
+func (c *Client) SecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	var secret struct {
+		// Data の値は、JSON では Base64 ですが []byte にすると復号されます
+		Data map[string][]byte `json:"data"`
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/secrets/%s", url.PathEscape(namespace), url.PathEscape(name))
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &secret); err != nil {
+		if IsNotFound(err) {
+			return "", fmt.Errorf("Secret %s/%s がありません", namespace, name)
+		}
+		return "", err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("Secret %s/%s にキー %s がありません", namespace, name, key)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// ConfigMapValue は、ConfigMap のキーの値を取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - namespace: 名前空間
+//   - name: ConfigMap の名前
+//   - key: キー
+//
+// Returns:
+//   - string: キーの値
+//   - error: ConfigMap やキーがない場合、または取得に失敗した場合
+func (c *Client) ConfigMapValue(ctx context.Context, namespace, name, key string) (string, error) {
+	var configMap struct {
+		Data map[string]string `json:"data"`
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", url.PathEscape(namespace), url.PathEscape(name))
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &configMap); err != nil {
+		if IsNotFound(err) {
+			return "", fmt.Errorf("ConfigMap %s/%s がありません", namespace, name)
+		}
+		return "", err
+	}
+	value, ok := configMap.Data[key]
+	if !ok {
+		return "", fmt.Errorf("ConfigMap %s/%s にキー %s がありません", namespace, name, key)
+	}
+	return value, nil
+}
+
+// apiStatus は、API サーバーがエラーのときに返す Status です。
+type apiStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// do は、リクエストを送り、レスポンスの JSON を out に読み込みます（out が nil の場合は読み捨てます）。
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, out any) error {
+	resp, err := c.request(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("レスポンスの読み込みに失敗しました: %w", err)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("レスポンスの解析に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// request は、リクエストを送ります。2xx 以外のステータスの場合は StatusError を返します。
+func (c *Client) request(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.tokenFile != "" {
+		token, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("トークンを読み込めません: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var status apiStatus
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		_ = json.Unmarshal(data, &status)
+		if resp.StatusCode == http.StatusGone {
+			return nil, ErrGone
+		}
+		return nil, &StatusError{Code: resp.StatusCode, Message: status.Message}
+	}
+	return resp, nil
+}
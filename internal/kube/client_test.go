@@ -0,0 +1,213 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+const recordsURL = "/apis/duckdns.horitaku.github.io/v1alpha1/namespaces/default/duckdnsrecords"
+
+// newTestClient は、httptest のサーバーに接続する Client を作成します。
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient(Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// TestNewClient_InvalidHost は、URL が不正な場合にエラーを返すことをテストします。
+func TestNewClient_InvalidHost(t *testing.T) {
+	for _, host := range []string{"", "ftp://example.com", "http://"} {
+		if _, err := NewClient(Config{Host: host}); err == nil {
+			t.Errorf("%q: エラーを返すべき", host)
+		}
+	}
+}
+
+// TestClient_ListRecords は、一覧の取得とトークンの送信をテストします。
+func TestClient_ListRecords(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != recordsURL {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret-token" {
+			http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"metadata":{"resourceVersion":"10"},"items":[
+			{"metadata":{"name":"home","namespace":"default","generation":2},
+			 "spec":{"domain":"home","tokenSecretRef":{"name":"duckdns"},"interval":"10m"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Host: server.URL, TokenFile: tokenFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := client.ListRecords(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("ListRecords() error = %v", err)
+	}
+	if list.Metadata.ResourceVersion != "10" || len(list.Items) != 1 {
+		t.Fatalf("一覧を読み込むべき: %+v", list)
+	}
+	if r := list.Items[0]; r.Key() != "default/home" || r.Spec.TokenSecretRef.Name != "duckdns" || r.Metadata.Generation != 2 {
+		t.Errorf("オブジェクトを読み込むべき: %+v", r)
+	}
+}
+
+// TestClient_StatusError は、2xx 以外のステータスを StatusError にすることをテストします。
+func TestClient_StatusError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"kind":"Status","code":403,"message":"forbidden"}`))
+	})
+	_, err := client.ListRecords(context.Background(), "")
+	var se *StatusError
+	if !errors.As(err, &se) || se.Code != http.StatusForbidden || se.Message != "forbidden" {
+		t.Errorf("StatusError を返すべき: %v", err)
+	}
+}
+
+// TestClient_WatchRecords は、watch のイベントを順に渡し、接続が閉じられたら nil を返すことをテストします。
+func TestClient_WatchRecords(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "1" || r.URL.Query().Get("resourceVersion") != "10" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"type":"ADDED","object":{"metadata":{"name":"a","namespace":"default","resourceVersion":"11"}}}
+{"type":"DELETED","object":{"metadata":{"name":"a","namespace":"default","resourceVersion":"12"}}}
+`))
+	})
+
+	var events []WatchEvent
+	err := client.WatchRecords(context.Background(), "default", "10", func(e WatchEvent) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("WatchRecords() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Type != "ADDED" || events[1].Type != "DELETED" || events[1].Record.Metadata.ResourceVersion != "12" {
+		t.Errorf("イベントを順に渡すべき: %+v", events)
+	}
+}
+
+// TestClient_WatchRecords_Gone は、410 Gone の ERROR イベントで ErrGone を返すことをテストします。
+func TestClient_WatchRecords_Gone(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"ERROR","object":{"kind":"Status","code":410,"message":"too old resource version"}}` + "\n"))
+	})
+	err := client.WatchRecords(context.Background(), "default", "1", func(WatchEvent) {})
+	if !errors.Is(err, ErrGone) {
+		t.Errorf("ErrGone を返すべき: %v", err)
+	}
+}
+
+// TestClient_WatchRecords_TooLarge は、上限を超えるイベントで、読み込みを止めてエラーを返すことをテストします。
+func TestClient_WatchRecords_TooLarge(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"ADDED","object":{"metadata":{"name":"a","namespace":"default","resourceVersion":"11"}}}` + "\n"))
+		w.Write([]byte(`{"type":"ADDED","object":{"metadata":{"name":"` + strings.Repeat("a", maxEventSize) + `"}}}` + "\n"))
+	})
+
+	var events []WatchEvent
+	err := client.WatchRecords(context.Background(), "default", "10", func(e WatchEvent) {
+		events = append(events, e)
+	})
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("上限を超えるイベントは ErrResponseTooLarge を返すべき: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("上限より前のイベントだけを渡すべき: %d", len(events))
+	}
+}
+
+// TestClient_PatchRecordStatus は、status サブリソースに merge patch を送ることをテストします。
+func TestClient_PatchRecordStatus(t *testing.T) {
+	var method, path, contentType string
+	var body map[string]map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.Write([]byte(`{}`))
+	})
+
+	err := client.PatchRecordStatus(context.Background(), "default", "home", RecordStatus{Phase: PhaseError, Message: "failed"})
+	if err != nil {
+		t.Fatalf("PatchRecordStatus() error = %v", err)
+	}
+	if method != http.MethodPatch || path != recordsURL+"/home/status" || contentType != "application/merge-patch+json" {
+		t.Errorf("status に merge patch を送るべき: %s %s (%s)", method, path, contentType)
+	}
+	if body["status"]["phase"] != PhaseError || body["status"]["message"] != "failed" {
+		t.Errorf("status を送るべき: %v", body)
+	}
+}
+
+// TestClient_SecretValue は、Secret のキーの値を Base64 から復号して返すことをテストします。
+func TestClient_SecretValue(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/secrets/duckdns" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":404,"message":"not found"}`))
+			return
+		}
+		// "my-token\n" の Base64
+		w.Write([]byte(`{"data":{"token":"bXktdG9rZW4K"}}`))
+	})
+
+	value, err := client.SecretValue(context.Background(), "default", "duckdns", "token")
+	if err != nil || value != "my-token" {
+		t.Errorf("値を復号して返すべき: %q, %v", value, err)
+	}
+	if _, err := client.SecretValue(context.Background(), "default", "duckdns", "other"); err == nil {
+		t.Error("キーがない場合はエラーを返すべき")
+	}
+	if _, err := client.SecretValue(context.Background(), "default", "missing", "token"); err == nil {
+		t.Error("Secret がない場合はエラーを返すべき")
+	}
+}
+
+// TestClient_ConfigMapValue は、ConfigMap のキーの値を返すことをテストします。
+func TestClient_ConfigMapValue(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/kube-system/configmaps/extension-apiserver-authentication" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":404,"message":"not found"}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"requestheader-client-ca-file":"-----BEGIN CERTIFICATE-----\n"}}`))
+	})
+
+	value, err := client.ConfigMapValue(context.Background(), "kube-system", "extension-apiserver-authentication", "requestheader-client-ca-file")
+	if err != nil || value != "-----BEGIN CERTIFICATE-----\n" {
+		t.Errorf("値を返すべき: %q, %v", value, err)
+	}
+	if _, err := client.ConfigMapValue(context.Background(), "kube-system", "extension-apiserver-authentication", "other"); err == nil {
+		t.Error("キーがない場合はエラーを返すべき")
+	}
+	if _, err := client.ConfigMapValue(context.Background(), "kube-system", "missing", "key"); err == nil {
+		t.Error("ConfigMap がない場合はエラーを返すべき")
+	}
+}
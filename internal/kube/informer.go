@@ -0,0 +1,130 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultRetryInterval は、一覧の取得や watch に失敗した場合に、やり直すまで待つ時間の既定値です。
+const DefaultRetryInterval = 5 * time.Second
+
+// Informer は、DuckDNSRecord の一覧を取得してから変更を監視し、一覧が変わるたびに最新の一覧を渡します。
+// spec が変わらない変更（コントローラー自身による status の書き込みなど）では、一覧を渡しません。
+type Informer struct {
+	// Client は、API クライアントです
+	Client *Client
+
+	// Namespace は、監視する名前空間です（空の場合はすべての名前空間）
+	Namespace string
+
+	// RetryInterval は、失敗した場合にやり直すまで待つ時間です（0 の場合は DefaultRetryInterval）
+	RetryInterval time.Duration
+}
+
+// Run は、ctx がキャンセルされるまで DuckDNSRecord を監視し、一覧が変わるたびに onChange を呼び出します。
+// 最初に一覧を取得したときは、空でも必ず onChange を呼び出します。
+// onChange には、"namespace/name" の順に並べた一覧を渡します。
+//
+// Parameters:
+//   - ctx: 監視を止めるためのコンテキスト
+//   - onChange: 一覧が変わるたびに呼び出す関数
+func (i *Informer) Run(ctx context.Context, onChange func(records []Record)) {
+	retry := i.RetryInterval
+	if retry <= 0 {
+		retry = DefaultRetryInterval
+	}
+
+	for ctx.Err() == nil {
+		list, err := i.Client.ListRecords(ctx, i.Namespace)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("DuckDNSRecord の一覧を取得できませんでした",
+				"namespace", i.Namespace,
+				"retry_in", retry,
+				"error", err,
+			)
+			sleep(ctx, retry)
+			continue
+		}
+
+		records := make(map[string]Record, len(list.Items))
+		for _, r := range list.Items {
+			records[r.Key()] = r
+		}
+		onChange(sortedRecords(records))
+
+		resourceVersion := list.Metadata.ResourceVersion
+		for ctx.Err() == nil {
+			err := i.Client.WatchRecords(ctx, i.Namespace, resourceVersion, func(event WatchEvent) {
+				resourceVersion = event.Record.Metadata.ResourceVersion
+				if applyEvent(records, event) {
+					onChange(sortedRecords(records))
+				}
+			})
+			if err == nil {
+				// API サーバーが watch のタイムアウトで接続を閉じたので、続きから監視し直します
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, ErrGone) {
+				slog.Warn("DuckDNSRecord の監視が途切れたため、一覧を取得し直します",
+					"namespace", i.Namespace,
+					"retry_in", retry,
+					"error", err,
+				)
+				sleep(ctx, retry)
+			}
+			break
+		}
+	}
+}
+
+// applyEvent は、watch のイベントを一覧に反映し、spec が変わった場合に true を返します。
+func applyEvent(records map[string]Record, event WatchEvent) bool {
+	key := event.Record.Key()
+	switch event.Type {
+	case "ADDED", "MODIFIED":
+		prev, ok := records[key]
+		records[key] = event.Record
+		return !ok || !reflect.DeepEqual(prev.Spec, event.Record.Spec)
+	case "DELETED":
+		if _, ok := records[key]; !ok {
+			return false
+		}
+		delete(records, key)
+		return true
+	}
+	// BOOKMARK は resourceVersion を進めるだけです
+	return false
+}
+
+// sortedRecords は、一覧を "namespace/name" の順に並べて返します。
+func sortedRecords(records map[string]Record) []Record {
+	list := make([]Record, 0, len(records))
+	for _, r := range records {
+		list = append(list, r)
+	}
+	slices.SortFunc(list, func(a, b Record) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+	return list
+}
+
+// sleep は、d だけ待ちます。ctx がキャンセルされた場合はすぐに戻ります。
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
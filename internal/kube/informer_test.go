@@ -0,0 +1,97 @@
+package kube
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestApplyEvent は、spec が変わった場合だけ true を返すことをテストします。
+func TestApplyEvent(t *testing.T) {
+	records := map[string]Record{}
+	record := Record{Metadata: ObjectMeta{Name: "a", Namespace: "ns"}, Spec: RecordSpec{Domain: "home"}}
+
+	if !applyEvent(records, WatchEvent{Type: "ADDED", Record: record}) {
+		t.Error("追加した場合は true を返すべき")
+	}
+	withStatus := record
+	withStatus.Status = RecordStatus{Phase: PhaseReady}
+	if applyEvent(records, WatchEvent{Type: "MODIFIED", Record: withStatus}) {
+		t.Error("status だけが変わった場合は false を返すべき")
+	}
+	changed := record
+	changed.Spec.Interval = "1m"
+	if !applyEvent(records, WatchEvent{Type: "MODIFIED", Record: changed}) {
+		t.Error("spec が変わった場合は true を返すべき")
+	}
+	if applyEvent(records, WatchEvent{Type: "BOOKMARK", Record: Record{}}) {
+		t.Error("BOOKMARK では false を返すべき")
+	}
+	if !applyEvent(records, WatchEvent{Type: "DELETED", Record: record}) || len(records) != 0 {
+		t.Error("削除した場合は true を返して一覧から取り除くべき")
+	}
+	if applyEvent(records, WatchEvent{Type: "DELETED", Record: record}) {
+		t.Error("一覧にないオブジェクトの削除では false を返すべき")
+	}
+}
+
+// TestInformer_Run は、一覧を渡してから watch の変更を反映し、410 Gone では一覧を取得し直すことをテストします。
+func TestInformer_Run(t *testing.T) {
+	var lists atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "" {
+			if lists.Add(1) == 1 {
+				w.Write([]byte(`{"metadata":{"resourceVersion":"1"},"items":[
+					{"metadata":{"name":"b","namespace":"ns"},"spec":{"domain":"b"}}]}`))
+				return
+			}
+			w.Write([]byte(`{"metadata":{"resourceVersion":"5"},"items":[]}`))
+			return
+		}
+		if r.URL.Query().Get("resourceVersion") == "1" {
+			w.Write([]byte(`{"type":"ADDED","object":{"metadata":{"name":"a","namespace":"ns","resourceVersion":"2"},"spec":{"domain":"a"}}}
+{"type":"ERROR","object":{"code":410,"message":"gone"}}
+`))
+			return
+		}
+		// 取得し直したあとの watch は、テストが終わるまで待たせます
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []Record, 10)
+	done := make(chan struct{})
+	go func() {
+		(&Informer{Client: client, Namespace: "ns", RetryInterval: 10 * time.Millisecond}).Run(ctx, func(records []Record) {
+			changes <- records
+		})
+		close(done)
+	}()
+
+	want := [][]string{{"ns/b"}, {"ns/a", "ns/b"}, {}}
+	for i, keys := range want {
+		select {
+		case records := <-changes:
+			if len(records) != len(keys) {
+				t.Fatalf("%d 回目: 一覧 = %v (期待値 %v)", i+1, records, keys)
+			}
+			for j, r := range records {
+				if r.Key() != keys[j] {
+					t.Errorf("%d 回目: %d 番目 = %s (期待値 %s)", i+1, j, r.Key(), keys[j])
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%d 回目の一覧が渡されるべき", i+1)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("キャンセルしたら戻るべき")
+	}
+}
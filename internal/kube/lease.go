@@ -0,0 +1,141 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// microTimeFormat は、Lease の時刻（MicroTime）の形式です。
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// MicroTime は、マイクロ秒までの時刻です（Kubernetes の metav1.MicroTime と同じ JSON の形式）。
+type MicroTime struct {
+	time.Time
+}
+
+// MarshalJSON は、マイクロ秒までの RFC 3339 の文字列にします。
+func (t MicroTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format(microTimeFormat))
+}
+
+// UnmarshalJSON は、RFC 3339 の文字列を読み込みます。
+func (t *MicroTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// Lease は、coordination.k8s.io/v1 の Lease です（リーダー選出に使います）。
+type Lease struct {
+	// Metadata は、オブジェクトのメタデータです
+	Metadata ObjectMeta `json:"metadata"`
+
+	// Spec は、リースの保持者と期限です
+	Spec LeaseSpec `json:"spec"`
+}
+
+// LeaseSpec は、Lease の spec です。
+type LeaseSpec struct {
+	// HolderIdentity は、リースを保持しているインスタンスです（空の場合は誰も保持していません）
+	HolderIdentity string `json:"holderIdentity,omitempty"`
+
+	// LeaseDurationSeconds は、更新されなかった場合にリースが切れるまでの秒数です
+	LeaseDurationSeconds int `json:"leaseDurationSeconds,omitempty"`
+
+	// AcquireTime は、今の保持者がリースを取得した時刻です
+	AcquireTime *MicroTime `json:"acquireTime,omitempty"`
+
+	// RenewTime は、保持者が最後にリースを更新した時刻です
+	RenewTime *MicroTime `json:"renewTime,omitempty"`
+
+	// LeaseTransitions は、保持者が変わった回数です
+	LeaseTransitions int `json:"leaseTransitions,omitempty"`
+}
+
+// IsConflict は、err が 409 Conflict の StatusError かどうかを返します。
+// Lease を書き換える前に、ほかのクライアントが書き換えた場合に返されます。
+func IsConflict(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusConflict
+}
+
+// leasesPath は、Lease の API のパスを返します。
+func leasesPath(namespace string) string {
+	return fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases", url.PathEscape(namespace))
+}
+
+// GetLease は、Lease を取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - namespace: 名前空間
+//   - name: Lease の名前
+//
+// Returns:
+//   - *Lease: Lease
+//   - error: 取得に失敗した場合（Lease がない場合は IsNotFound が true になるエラー）
+func (c *Client) GetLease(ctx context.Context, namespace, name string) (*Lease, error) {
+	var lease Lease
+	if err := c.do(ctx, http.MethodGet, leasesPath(namespace)+"/"+url.PathEscape(name), "", nil, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// CreateLease は、Lease を作成します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - lease: 作成する Lease（metadata の namespace と name が必要）
+//
+// Returns:
+//   - *Lease: 作成された Lease
+//   - error: 作成に失敗した場合（すでにある場合は 409 Conflict の StatusError）
+func (c *Client) CreateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	return c.writeLease(ctx, http.MethodPost, leasesPath(lease.Metadata.Namespace), lease)
+}
+
+// UpdateLease は、Lease を書き換えます。
+// metadata.resourceVersion が今のものと違う（ほかのクライアントが書き換えた）場合は、書き換えません。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - lease: 書き換える Lease（GetLease で取得したものの spec を変えたもの）
+//
+// Returns:
+//   - *Lease: 書き換えた Lease
+//   - error: 書き換えに失敗した場合（ほかのクライアントが書き換えた場合は IsConflict が true になるエラー）
+func (c *Client) UpdateLease(ctx context.Context, lease *Lease) (*Lease, error) {
+	path := leasesPath(lease.Metadata.Namespace) + "/" + url.PathEscape(lease.Metadata.Name)
+	return c.writeLease(ctx, http.MethodPut, path, lease)
+}
+
+// writeLease は、Lease を送って、API サーバーが返した Lease を返します。
+func (c *Client) writeLease(ctx context.Context, method, path string, lease *Lease) (*Lease, error) {
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": "coordination.k8s.io/v1",
+		"kind":       "Lease",
+		"metadata":   lease.Metadata,
+		"spec":       lease.Spec,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out Lease
+	if err := c.do(ctx, method, path, "application/json", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,78 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const leaseURL = "/apis/coordination.k8s.io/v1/namespaces/default/leases"
+
+// TestClient_Lease は、Lease の取得・作成・書き換えと、409 Conflict の判定をテストします。
+func TestClient_Lease(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == leaseURL+"/duckdns":
+			w.Write([]byte(`{"metadata":{"name":"duckdns","namespace":"default","resourceVersion":"7"},
+				"spec":{"holderIdentity":"a","leaseDurationSeconds":15,"renewTime":"2024-01-02T03:04:05.123456Z"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == leaseURL:
+			body, _ := io.ReadAll(r.Body)
+			var got map[string]any
+			json.Unmarshal(body, &got)
+			if got["kind"] != "Lease" {
+				t.Errorf("kind = %v", got["kind"])
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case r.Method == http.MethodPut && r.URL.Path == leaseURL+"/duckdns":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code":409,"message":"the object has been modified"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	lease, err := client.GetLease(ctx, "default", "duckdns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	if lease.Spec.HolderIdentity != "a" || lease.Metadata.ResourceVersion != "7" || !lease.Spec.RenewTime.Equal(want) {
+		t.Errorf("Lease = %+v", lease)
+	}
+
+	now := MicroTime{want}
+	created, err := client.CreateLease(ctx, &Lease{
+		Metadata: ObjectMeta{Name: "new", Namespace: "default"},
+		Spec:     LeaseSpec{HolderIdentity: "b", RenewTime: &now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Spec.HolderIdentity != "b" || !created.Spec.RenewTime.Equal(want) {
+		t.Errorf("作成した Lease = %+v", created)
+	}
+
+	if _, err := client.UpdateLease(ctx, lease); !IsConflict(err) {
+		t.Errorf("409 の場合は IsConflict が true になるべき: %v", err)
+	}
+	if _, err := client.GetLease(ctx, "default", "missing"); !IsNotFound(err) {
+		t.Errorf("ない場合は IsNotFound が true になるべき: %v", err)
+	}
+}
+
+// TestMicroTime_MarshalJSON は、マイクロ秒までの UTC の文字列にすることをテストします。
+func TestMicroTime_MarshalJSON(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	data, err := json.Marshal(MicroTime{time.Date(2024, 1, 2, 12, 0, 0, 1500, jst)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"2024-01-02T03:00:00.000001Z"` {
+		t.Errorf("MarshalJSON() = %s", data)
+	}
+}
@@ -0,0 +1,156 @@
+// Package kube は、Kubernetes の DuckDNSRecord カスタムリソースを監視するための、最小限の API クライアントを提供します。
+// client-go には依存せず、API サーバーの REST API（list / watch / get / patch）だけを使います。
+package kube
+
+import (
+	"fmt"
+	"time"
+)
+
+// DuckDNSRecord のカスタムリソースの定義（CRD）です。
+const (
+	// Group は、DuckDNSRecord の API グループです
+	Group = "duckdns.horitaku.github.io"
+
+	// Version は、DuckDNSRecord の API バージョンです
+	Version = "v1alpha1"
+
+	// Resource は、DuckDNSRecord のリソース名（複数形）です
+	Resource = "duckdnsrecords"
+
+	// Kind は、DuckDNSRecord の種類です
+	Kind = "DuckDNSRecord"
+
+	// DefaultTokenKey は、tokenSecretRef.key を省略した場合の Secret のキーです
+	DefaultTokenKey = "token"
+)
+
+// レコードの状態（status.phase）です。
+const (
+	// PhaseReady は、最後のチェックと更新に成功したことを表します
+	PhaseReady = "Ready"
+
+	// PhaseError は、設定の誤りや更新の失敗でレコードを更新できていないことを表します
+	PhaseError = "Error"
+)
+
+// ObjectMeta は、Kubernetes のオブジェクトのメタデータのうち、コントローラーが使う項目です。
+type ObjectMeta struct {
+	// Name は、オブジェクトの名前です
+	Name string `json:"name"`
+
+	// Namespace は、オブジェクトの名前空間です
+	Namespace string `json:"namespace,omitempty"`
+
+	// ResourceVersion は、オブジェクトのバージョンです（watch の再開に使います）
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+
+	// Generation は、spec を変更するたびに増える番号です
+	Generation int64 `json:"generation,omitempty"`
+}
+
+// Record は、DuckDNSRecord のオブジェクトです。
+type Record struct {
+	// Metadata は、オブジェクトのメタデータです
+	Metadata ObjectMeta `json:"metadata"`
+
+	// Spec は、更新するレコードの定義です
+	Spec RecordSpec `json:"spec"`
+
+	// Status は、コントローラーが書き込む更新の状況です
+	Status RecordStatus `json:"status,omitempty"`
+}
+
+// Key は、"namespace/name" 形式のオブジェクトの識別子を返します。
+func (r *Record) Key() string {
+	return r.Metadata.Namespace + "/" + r.Metadata.Name
+}
+
+// RecordSpec は、DuckDNSRecord の spec です。
+type RecordSpec struct {
+	// Domain は、更新する DuckDNS のドメイン名です（例: "home" または "home.duckdns.org"）
+	Domain string `json:"domain"`
+
+	// TokenSecretRef は、DuckDNS のトークンを保存した Secret です（同じ名前空間）
+	TokenSecretRef SecretKeySelector `json:"tokenSecretRef"`
+
+	// Interval は、更新チェックの間隔です（例: "5m"。省略時は設定ファイルの update.interval）
+	Interval string `json:"interval,omitempty"`
+
+	// IPSources は、IPアドレスの取得ソースの URL です（省略時は設定ファイルの ip_sources）
+	IPSources []string `json:"ipSources,omitempty"`
+
+	// IPv6 は、IPv6アドレス（AAAA レコード）も更新するかどうかです（省略時は設定ファイルの update.ipv6）
+	IPv6 *bool `json:"ipv6,omitempty"`
+
+	// IPv6Sources は、IPv6アドレスの取得ソースの URL です（省略時は設定ファイルの ipv6_sources）
+	IPv6Sources []string `json:"ipv6Sources,omitempty"`
+}
+
+// IntervalDuration は、spec.interval を time.Duration にして返します（省略した場合は 0）。
+//
+// Returns:
+//   - time.Duration: 更新チェックの間隔
+//   - error: 形式が不正な場合、または正の値でない場合
+func (s RecordSpec) IntervalDuration() (time.Duration, error) {
+	if s.Interval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("spec.interval の形式が不正です: %s", s.Interval)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("spec.interval は正の値で指定してください: %s", s.Interval)
+	}
+	return d, nil
+}
+
+// SecretKeySelector は、Secret の名前とキーです。
+type SecretKeySelector struct {
+	// Name は、Secret の名前です
+	Name string `json:"name"`
+
+	// Key は、Secret のキーです（省略時は DefaultTokenKey）
+	Key string `json:"key,omitempty"`
+}
+
+// KeyOrDefault は、Secret のキーを返します（省略した場合は DefaultTokenKey）。
+func (s SecretKeySelector) KeyOrDefault() string {
+	if s.Key == "" {
+		return DefaultTokenKey
+	}
+	return s.Key
+}
+
+// RecordStatus は、DuckDNSRecord の status です。
+type RecordStatus struct {
+	// Phase は、レコードの状態です（PhaseReady または PhaseError）
+	Phase string `json:"phase,omitempty"`
+
+	// Message は、エラーの内容です（成功した場合は空。merge patch で前回のエラーを消すため、空でも書き込みます）
+	Message string `json:"message"`
+
+	// IP は、最後に登録した（または登録済みと確認した）IPアドレスです
+	IP string `json:"ip,omitempty"`
+
+	// LastCheckTime は、最後にチェックした時刻です
+	LastCheckTime *time.Time `json:"lastCheckTime,omitempty"`
+
+	// LastUpdateTime は、最後にレコードを更新した時刻です
+	LastUpdateTime *time.Time `json:"lastUpdateTime,omitempty"`
+
+	// ObservedGeneration は、この状況を記録したときの metadata.generation です
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// RecordList は、DuckDNSRecord の一覧です。
+type RecordList struct {
+	// Metadata は、一覧のメタデータです（resourceVersion から watch を始めます）
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+
+	// Items は、DuckDNSRecord のオブジェクトです
+	Items []Record `json:"items"`
+}
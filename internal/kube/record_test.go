@@ -0,0 +1,53 @@
+package kube
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRecordSpec_IntervalDuration は、spec.interval の解析をテストします。
+func TestRecordSpec_IntervalDuration(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"10m", 10 * time.Minute, false},
+		{"abc", 0, true},
+		{"-1m", 0, true},
+		{"0s", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := RecordSpec{Interval: tt.interval}.IntervalDuration()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%q: got = %v, err = %v (期待値 %v, エラー %v)", tt.interval, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestSecretKeySelector_KeyOrDefault は、キーを省略した場合に既定のキーを使うことをテストします。
+func TestSecretKeySelector_KeyOrDefault(t *testing.T) {
+	if got := (SecretKeySelector{Name: "s"}).KeyOrDefault(); got != DefaultTokenKey {
+		t.Errorf("省略した場合は %q を返すべき: %q", DefaultTokenKey, got)
+	}
+	if got := (SecretKeySelector{Name: "s", Key: "duckdns"}).KeyOrDefault(); got != "duckdns" {
+		t.Errorf("指定したキーを返すべき: %q", got)
+	}
+}
+
+// TestRecordStatus_JSON は、成功した場合も空の message を書き込んで、前回のエラーを消すことをテストします。
+func TestRecordStatus_JSON(t *testing.T) {
+	data, err := json.Marshal(RecordStatus{Phase: PhaseReady, IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"message":""`) {
+		t.Errorf("空の message を含むべき: %s", data)
+	}
+	if strings.Contains(string(data), "lastUpdateTime") {
+		t.Errorf("更新していない場合は lastUpdateTime を含まないべき: %s", data)
+	}
+}
@@ -0,0 +1,89 @@
+package launchd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Launchctl は、launchctl のコマンドを実行する関数です。
+// テストでは、実際には実行しない関数に置き換えます。
+type Launchctl func(ctx context.Context, args ...string) error
+
+// RunLaunchctl は、launchctl を実行します。
+// 失敗した場合は、launchctl が標準エラー出力に書いたメッセージをエラーに含めます。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - args: launchctl に渡す引数（例: "bootstrap", "system", "/Library/LaunchDaemons/x.plist"）
+//
+// Returns:
+//   - error: launchctl が見つからない場合、または 0 以外の終了コードで終了した場合
+func RunLaunchctl(ctx context.Context, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "launchctl", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("launchctl %s に失敗しました: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("launchctl %s に失敗しました: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Domain は、ジョブを読み込む launchd のドメインを返します。
+//
+// Parameters:
+//   - daemon: システム全体のデーモンの場合は true（"system"）、ユーザーのエージェントの場合は false（"gui/<uid>"）
+//   - uid: エージェントを読み込むユーザーの ID
+//
+// Returns:
+//   - string: launchd のドメイン
+func Domain(daemon bool, uid int) string {
+	if daemon {
+		return "system"
+	}
+	return "gui/" + strconv.Itoa(uid)
+}
+
+// Load は、plist を launchd に読み込みます。RunAtLoad により、読み込むとすぐに起動します。
+// 同じラベルのジョブが読み込まれている場合は、先に取り除いてから読み込み直します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - launchctl: launchctl を実行する関数（nil の場合は RunLaunchctl）
+//   - domain: launchd のドメイン（Domain の戻り値）
+//   - label: ジョブのラベル（空の場合は DefaultLabel）
+//   - path: plist のパス
+//
+// Returns:
+//   - error: launchctl の実行に失敗した場合
+func Load(ctx context.Context, launchctl Launchctl, domain, label, path string) error {
+	if launchctl == nil {
+		launchctl = RunLaunchctl
+	}
+	// 読み込まれていない場合は失敗しますが、そのまま読み込みます
+	_ = launchctl(ctx, "bootout", domain+"/"+labelOrDefault(label))
+	return launchctl(ctx, "bootstrap", domain, path)
+}
+
+// Unload は、ジョブを停止して launchd から取り除きます。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - launchctl: launchctl を実行する関数（nil の場合は RunLaunchctl）
+//   - domain: launchd のドメイン（Domain の戻り値）
+//   - label: ジョブのラベル（空の場合は DefaultLabel）
+//
+// Returns:
+//   - error: launchctl の実行に失敗した場合
+func Unload(ctx context.Context, launchctl Launchctl, domain, label string) error {
+	if launchctl == nil {
+		launchctl = RunLaunchctl
+	}
+	return launchctl(ctx, "bootout", domain+"/"+labelOrDefault(label))
+}
@@ -0,0 +1,43 @@
+package launchd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestDomain は、デーモンとエージェントのドメインをテストします。
+func TestDomain(t *testing.T) {
+	if got := Domain(true, 501); got != "system" {
+		t.Errorf("デーモンは system であるべき。実際: %s", got)
+	}
+	if got := Domain(false, 501); got != "gui/501" {
+		t.Errorf("エージェントは gui/<uid> であるべき。実際: %s", got)
+	}
+}
+
+// TestLoad は、読み込まれているジョブを取り除いてから読み込むことをテストします。
+func TestLoad(t *testing.T) {
+	var calls []string
+	launchctl := func(ctx context.Context, args ...string) error {
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "bootout" {
+			return errors.New("読み込まれていません")
+		}
+		return nil
+	}
+	if err := Load(context.Background(), launchctl, "gui/501", "", "/tmp/x.plist"); err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	want := []string{"bootout gui/501/com.github.horitaku.duckdns", "bootstrap gui/501 /tmp/x.plist"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("launchctl の呼び出しが一致しません: %v", calls)
+	}
+
+	calls = nil
+	if err := Unload(context.Background(), launchctl, "system", "local.ddns"); err == nil || !slices.Equal(calls, []string{"bootout system/local.ddns"}) {
+		t.Errorf("bootout の結果を返すべき: %v %v", err, calls)
+	}
+}
@@ -0,0 +1,191 @@
+// Package launchd は、duckdns を macOS の launchd のエージェントまたはデーモンとして実行するための plist の作成と読み込みを提供します。
+package launchd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	// DefaultLabel は、ラベルを省略した場合の値です
+	DefaultLabel = "com.github.horitaku.duckdns"
+
+	// DaemonDir は、システム全体のデーモン（LaunchDaemon）の plist を置くディレクトリです
+	DaemonDir = "/Library/LaunchDaemons"
+
+	// DaemonLogPath は、デーモンのログを書き込むファイルです
+	DaemonLogPath = "/Library/Logs/duckdns.log"
+
+	// DefaultExitTimeout は、停止するときに SIGTERM を送ってから SIGKILL を送るまで待つ秒数です
+	DefaultExitTimeout = 30
+)
+
+// Plist は、plist に書き込む内容です。
+type Plist struct {
+	// Label は、launchd でジョブを識別するラベルです（空の場合は DefaultLabel）
+	Label string
+
+	// Binary は、duckdns の実行ファイルの絶対パスです
+	Binary string
+
+	// ConfigPath は、run の -config に渡す設定ファイルのパスまたは URL です（空の場合は -config を付けません）
+	ConfigPath string
+
+	// LogPath は、標準出力と標準エラー出力を書き込むファイルです（空の場合は書き込みません）
+	LogPath string
+
+	// ExitTimeout は、停止するときに SIGTERM を送ってから SIGKILL を送るまで待つ秒数です（0 の場合は DefaultExitTimeout）
+	// update.shutdown_grace や update.on_shutdown の処理が終わるまで待てるようにします
+	ExitTimeout int
+}
+
+// AgentDir は、ログインしているユーザーのエージェント（LaunchAgent）の plist を置くディレクトリを返します。
+//
+// Returns:
+//   - string: ~/Library/LaunchAgents
+//   - error: ホームディレクトリが分からない場合
+func AgentDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents"), nil
+}
+
+// AgentLogPath は、エージェントのログを書き込むファイルを返します。
+//
+// Returns:
+//   - string: ~/Library/Logs/duckdns.log
+//   - error: ホームディレクトリが分からない場合
+func AgentLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Logs", "duckdns.log"), nil
+}
+
+// Path は、ラベルから plist のパスを返します。
+//
+// Parameters:
+//   - dir: plist を置くディレクトリ
+//   - label: ジョブのラベル（空の場合は DefaultLabel）
+//
+// Returns:
+//   - string: plist のパス（例: "/Library/LaunchDaemons/com.github.horitaku.duckdns.plist"）
+func Path(dir, label string) string {
+	return filepath.Join(dir, labelOrDefault(label)+".plist")
+}
+
+// labelOrDefault は、ラベルを返します（空の場合は DefaultLabel）。
+func labelOrDefault(label string) string {
+	if label == "" {
+		return DefaultLabel
+	}
+	return label
+}
+
+// Render は、plist の内容を作成します。
+// RunAtLoad で読み込んだときに起動し、KeepAlive で終了しても起動し直します。
+// 停止するときは launchd が SIGTERM を送るため、実行中の更新と停止するときの処理を終えてから終了します。
+//
+// Returns:
+//   - []byte: plist の内容
+//   - error: 実行ファイルのパスが絶対パスでない場合
+func (p Plist) Render() ([]byte, error) {
+	if !filepath.IsAbs(p.Binary) {
+		return nil, fmt.Errorf("実行ファイルのパスは絶対パスで指定してください: %s", p.Binary)
+	}
+	exitTimeout := p.ExitTimeout
+	if exitTimeout <= 0 {
+		exitTimeout = DefaultExitTimeout
+	}
+
+	args := []string{p.Binary, "run"}
+	if p.ConfigPath != "" {
+		args = append(args, "-config", p.ConfigPath)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	writeKey(&b, "Label")
+	writeString(&b, labelOrDefault(p.Label))
+	writeKey(&b, "ProgramArguments")
+	b.WriteString("\t<array>\n")
+	for _, arg := range args {
+		b.WriteString("\t")
+		writeString(&b, arg)
+	}
+	b.WriteString("\t</array>\n")
+	writeKey(&b, "RunAtLoad")
+	b.WriteString("\t<true/>\n")
+	writeKey(&b, "KeepAlive")
+	b.WriteString("\t<true/>\n")
+	// 続けて異常終了した場合に、起動し直す間隔を空けます
+	writeKey(&b, "ThrottleInterval")
+	b.WriteString("\t<integer>10</integer>\n")
+	writeKey(&b, "ExitTimeOut")
+	b.WriteString("\t<integer>" + strconv.Itoa(exitTimeout) + "</integer>\n")
+	writeKey(&b, "ProcessType")
+	writeString(&b, "Background")
+	if p.LogPath != "" {
+		writeKey(&b, "StandardOutPath")
+		writeString(&b, p.LogPath)
+		writeKey(&b, "StandardErrorPath")
+		writeString(&b, p.LogPath)
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes(), nil
+}
+
+// writeKey は、plist の dict のキーを書き込みます。
+func writeKey(b *bytes.Buffer, key string) {
+	b.WriteString("\t<key>")
+	xml.EscapeText(b, []byte(key))
+	b.WriteString("</key>\n")
+}
+
+// writeString は、plist の文字列の値を書き込みます。
+func writeString(b *bytes.Buffer, value string) {
+	b.WriteString("\t<string>")
+	xml.EscapeText(b, []byte(value))
+	b.WriteString("</string>\n")
+}
+
+// WriteFile は、plist を書き込みます。
+//
+// Parameters:
+//   - path: plist のパス
+//   - data: plist の内容
+//   - force: 既存のファイルを上書きするかどうか
+//
+// Returns:
+//   - error: ファイルが既に存在する場合（force が false のとき）、または書き込みに失敗した場合
+func WriteFile(path string, data []byte, force bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("plist が既に存在します (上書きするには -force を指定してください): %s", path)
+		}
+		return fmt.Errorf("plist の作成に失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("plist の書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package launchd
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPlist_Render は、起動の設定と引数を plist に書き込むことをテストします。
+func TestPlist_Render(t *testing.T) {
+	p := Plist{
+		Binary:     "/usr/local/bin/duckdns",
+		ConfigPath: "/Users/me/Library/Application Support/duckdns/config&.yaml",
+		LogPath:    "/Users/me/Library/Logs/duckdns.log",
+	}
+	data, err := p.Render()
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	plist := string(data)
+
+	for _, want := range []string{
+		"<key>Label</key>\n\t<string>com.github.horitaku.duckdns</string>",
+		"\t<string>/usr/local/bin/duckdns</string>\n\t\t<string>run</string>\n\t\t<string>-config</string>",
+		"config&amp;.yaml</string>",
+		"<key>RunAtLoad</key>\n\t<true/>",
+		"<key>KeepAlive</key>\n\t<true/>",
+		"<key>ExitTimeOut</key>\n\t<integer>30</integer>",
+		"<key>StandardErrorPath</key>\n\t<string>/Users/me/Library/Logs/duckdns.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("%q が含まれるべき:\n%s", want, plist)
+		}
+	}
+
+	// XML として読めること
+	decoder := xml.NewDecoder(strings.NewReader(plist))
+	decoder.Strict = false
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err.Error() != "EOF" {
+				t.Errorf("XML として読めるべき: %v", err)
+			}
+			break
+		}
+	}
+}
+
+// TestPlist_Render_Options は、省略した項目と絶対パスでない実行ファイルの扱いをテストします。
+func TestPlist_Render_Options(t *testing.T) {
+	data, err := Plist{Label: "local.ddns", Binary: "/opt/duckdns", ExitTimeout: 60}.Render()
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	plist := string(data)
+	if !strings.Contains(plist, "<string>local.ddns</string>") || !strings.Contains(plist, "<integer>60</integer>") {
+		t.Errorf("指定したラベルとタイムアウトを書き込むべき:\n%s", plist)
+	}
+	if strings.Contains(plist, "-config") || strings.Contains(plist, "StandardOutPath") {
+		t.Errorf("省略した項目は書き込まないべき:\n%s", plist)
+	}
+
+	if _, err := (Plist{Binary: "duckdns"}).Render(); err == nil {
+		t.Error("絶対パスでない場合はエラーになるべき")
+	}
+}
+
+// TestPath は、ラベルから plist のパスを作ることをテストします。
+func TestPath(t *testing.T) {
+	if got := Path(DaemonDir, ""); got != "/Library/LaunchDaemons/com.github.horitaku.duckdns.plist" {
+		t.Errorf("既定のパスが一致しません: %s", got)
+	}
+}
+
+// TestWriteFile は、既存の plist は force の場合だけ上書きすることをテストします。
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "LaunchAgents", "x.plist")
+	if err := WriteFile(path, []byte("first"), false); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+	if err := WriteFile(path, []byte("second"), false); err == nil {
+		t.Error("既存のファイルは force なしではエラーになるべき")
+	}
+	if err := WriteFile(path, []byte("third"), true); err != nil {
+		t.Fatalf("force の場合は上書きするべき: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third" {
+		t.Errorf("内容が一致しません: %s", data)
+	}
+}
@@ -0,0 +1,278 @@
+// Package leader は、複数のインスタンスを動かすときに、リースを使って1つのインスタンス（リーダー）だけが更新するようにするリーダー選出を提供します。
+// リースは Kubernetes の Lease か、共有ディレクトリのファイルに保存します。
+// リーダーでないインスタンスは待機しながらリースを見張り、リーダーが止まってリースが切れると代わりに更新を始めます。
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// リースの期限と確認の間隔の既定値です（Kubernetes のコントローラーと同じ値）。
+const (
+	// DefaultLeaseDuration は、リーダーがリースを更新しなかった場合に、ほかのインスタンスが引き継げるようになるまでの時間の既定値です
+	DefaultLeaseDuration = 15 * time.Second
+
+	// DefaultRetryPeriod は、リースを取得・更新する間隔の既定値です
+	DefaultRetryPeriod = 2 * time.Second
+)
+
+// releaseTimeout は、停止するときにリースを手放す書き込みのタイムアウトです。
+const releaseTimeout = 5 * time.Second
+
+// ErrConflict は、最後に Get してからほかのインスタンスがリースを書き換えた場合に、Lock.Update が返すエラーです。
+var ErrConflict = errors.New("ほかのインスタンスがリースを書き換えました")
+
+// Record は、リースの内容です。
+type Record struct {
+	// HolderIdentity は、リースを保持しているインスタンスです（空の場合は誰も保持していません）
+	HolderIdentity string
+
+	// LeaseDuration は、更新されなかった場合にリースが切れるまでの時間です
+	LeaseDuration time.Duration
+
+	// AcquireTime は、今の保持者がリースを取得した時刻です
+	AcquireTime time.Time
+
+	// RenewTime は、保持者が最後にリースを更新した時刻です
+	RenewTime time.Time
+
+	// Transitions は、保持者が変わった回数です
+	Transitions int
+}
+
+// Lock は、リースを保存する場所です。
+type Lock interface {
+	// Get は、今のリースを取得します（まだない場合は nil）
+	Get(ctx context.Context) (*Record, error)
+
+	// Update は、リースを書き込みます（まだない場合は作成します）
+	// 最後に Get してからほかのインスタンスが書き換えた場合は、書き込まずに ErrConflict を返します
+	Update(ctx context.Context, record Record) error
+
+	// String は、ログに出すリースの場所です
+	String() string
+}
+
+// DefaultIdentity は、インスタンスを区別する名前の既定値（"ホスト名_PID"）を返します。
+//
+// Returns:
+//   - string: インスタンスの名前
+func DefaultIdentity() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s_%d", host, os.Getpid())
+}
+
+// Elector は、リースを取得できたときだけ処理を実行するリーダー選出です。
+type Elector struct {
+	// Lock は、リースを保存する場所です
+	Lock Lock
+
+	// Identity は、インスタンスを区別する名前です（インスタンスごとに違う名前にします）
+	Identity string
+
+	// LeaseDuration は、リーダーがリースを更新しなかった場合に、ほかのインスタンスが引き継げるようになるまでの時間です（0 の場合は DefaultLeaseDuration）
+	LeaseDuration time.Duration
+
+	// RetryPeriod は、リースを取得・更新する間隔です（0 の場合は DefaultRetryPeriod）
+	RetryPeriod time.Duration
+
+	// observed は、最後に見たリースで、observedAt はそれが変わったのを見た時刻です
+	// インスタンスの間の時計のずれに影響されないように、リースの期限は自分の時計で見た時刻から数えます
+	observed   *Record
+	observedAt time.Time
+
+	leading atomic.Bool
+}
+
+// IsLeader は、今リーダーかどうかを返します。
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run は、ctx がキャンセルされるまで、リースを取得できたら lead を実行します。
+// リースを更新できないまま期限の 2/3 が過ぎたら、ほかのインスタンスが引き継ぐ前に lead の ctx をキャンセルし、
+// lead が戻ってから再びリースの取得を待ちます。lead が自分から戻った場合も、リースを手放して取得を待ち直します。
+// ctx がキャンセルされたら、lead が戻るのを待ってからリースを手放すため、待機しているインスタンスがすぐに引き継げます。
+//
+// Parameters:
+//   - ctx: リーダー選出を止めるためのコンテキスト
+//   - lead: リーダーの間に実行する処理（リーダーでなくなったら ctx がキャンセルされます）
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	retry := e.retryPeriod()
+	renewDeadline := e.leaseDuration() * 2 / 3
+
+	for ctx.Err() == nil {
+		if !e.acquire(ctx) {
+			return
+		}
+
+		slog.Info("リーダーになりました", "identity", e.Identity, "lease", e.Lock.String())
+		e.leading.Store(true)
+		leadCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			lead(leadCtx)
+		}()
+
+		lastRenew := time.Now()
+		ticker := time.NewTicker(retry)
+	renew:
+		for {
+			select {
+			case <-ctx.Done():
+				break renew
+			case <-done:
+				break renew
+			case <-ticker.C:
+				if e.tryAcquireOrRenew(ctx) {
+					lastRenew = time.Now()
+					continue
+				}
+				if time.Since(lastRenew) > renewDeadline {
+					slog.Warn("リースを更新できなかったため、リーダーをやめます",
+						"identity", e.Identity,
+						"lease", e.Lock.String(),
+					)
+					break renew
+				}
+			}
+		}
+		ticker.Stop()
+		stop()
+		<-done
+		e.leading.Store(false)
+		e.release()
+		slog.Info("リーダーをやめました", "identity", e.Identity)
+	}
+}
+
+// acquire は、リースを取得できるまで RetryPeriod ごとに試します。ctx がキャンセルされたら false を返します。
+func (e *Elector) acquire(ctx context.Context) bool {
+	retry := e.retryPeriod()
+	waiting := false
+	for {
+		if e.tryAcquireOrRenew(ctx) {
+			return true
+		}
+		if !waiting && e.observed != nil {
+			waiting = true
+			slog.Info("ほかのインスタンスがリーダーのため、待機します",
+				"identity", e.Identity,
+				"leader", e.observed.HolderIdentity,
+				"lease", e.Lock.String(),
+			)
+		}
+		timer := time.NewTimer(retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// tryAcquireOrRenew は、リースを取得するか、保持しているリースを更新します。できた場合に true を返します。
+func (e *Elector) tryAcquireOrRenew(ctx context.Context) bool {
+	apiCtx, cancel := context.WithTimeout(ctx, e.retryPeriod())
+	defer cancel()
+
+	now := time.Now()
+	desired := Record{
+		HolderIdentity: e.Identity,
+		LeaseDuration:  e.leaseDuration(),
+		AcquireTime:    now,
+		RenewTime:      now,
+	}
+
+	current, err := e.Lock.Get(apiCtx)
+	if err != nil {
+		slog.Warn("リースを取得できませんでした", "lease", e.Lock.String(), "error", err)
+		return false
+	}
+	if current != nil {
+		if e.observed == nil || !sameRecord(*e.observed, *current) {
+			e.observed = current
+			e.observedAt = now
+		}
+		held := current.HolderIdentity != "" && current.HolderIdentity != e.Identity
+		if held && now.Before(e.observedAt.Add(current.LeaseDuration)) {
+			return false
+		}
+		if current.HolderIdentity == e.Identity {
+			desired.AcquireTime = current.AcquireTime
+			desired.Transitions = current.Transitions
+		} else {
+			desired.Transitions = current.Transitions + 1
+		}
+	}
+
+	if err := e.Lock.Update(apiCtx, desired); err != nil {
+		if !errors.Is(err, ErrConflict) {
+			slog.Warn("リースを書き込めませんでした", "lease", e.Lock.String(), "error", err)
+		}
+		return false
+	}
+	e.observed = &desired
+	e.observedAt = now
+	return true
+}
+
+// release は、保持しているリースを手放して、待機しているインスタンスがすぐに引き継げるようにします。
+func (e *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	current, err := e.Lock.Get(ctx)
+	if err != nil || current == nil || current.HolderIdentity != e.Identity {
+		return
+	}
+	released := *current
+	released.HolderIdentity = ""
+	released.LeaseDuration = time.Second
+	released.RenewTime = time.Now()
+	if err := e.Lock.Update(ctx, released); err != nil {
+		slog.Warn("リースを手放せませんでした（期限が切れるまで、ほかのインスタンスは引き継げません）",
+			"lease", e.Lock.String(),
+			"error", err,
+		)
+		return
+	}
+	e.observed = &released
+	e.observedAt = time.Now()
+}
+
+// sameRecord は、2つのリースの内容が同じかどうかを返します。
+func sameRecord(a, b Record) bool {
+	return a.HolderIdentity == b.HolderIdentity &&
+		a.LeaseDuration == b.LeaseDuration &&
+		a.AcquireTime.Equal(b.AcquireTime) &&
+		a.RenewTime.Equal(b.RenewTime) &&
+		a.Transitions == b.Transitions
+}
+
+// leaseDuration は、リースの期限を返します（省略した場合は DefaultLeaseDuration）。
+func (e *Elector) leaseDuration() time.Duration {
+	if e.LeaseDuration <= 0 {
+		return DefaultLeaseDuration
+	}
+	return e.LeaseDuration
+}
+
+// retryPeriod は、リースを取得・更新する間隔を返します（省略した場合は DefaultRetryPeriod）。
+func (e *Elector) retryPeriod() time.Duration {
+	if e.RetryPeriod <= 0 {
+		return DefaultRetryPeriod
+	}
+	return e.RetryPeriod
+}
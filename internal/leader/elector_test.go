@@ -0,0 +1,106 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startElector は、Elector を実行して、リーダーになったら leading に名前を送ります。
+func startElector(ctx context.Context, path, identity string, leading chan<- string) <-chan struct{} {
+	e := &Elector{
+		Lock:          NewFileLock(path),
+		Identity:      identity,
+		LeaseDuration: 300 * time.Millisecond,
+		RetryPeriod:   20 * time.Millisecond,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx, func(ctx context.Context) {
+			leading <- identity
+			<-ctx.Done()
+		})
+	}()
+	return done
+}
+
+// TestElector_Run は、1つのインスタンスだけがリーダーになり、リーダーが止まると待機していたインスタンスが引き継ぐことをテストします。
+func TestElector_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duckdns.lease")
+	leading := make(chan string, 2)
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	defer stopA()
+	doneA := startElector(ctxA, path, "a", leading)
+
+	select {
+	case id := <-leading:
+		if id != "a" {
+			t.Fatalf("リーダー = %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("リーダーにならなかった")
+	}
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	doneB := startElector(ctxB, path, "b", leading)
+
+	select {
+	case id := <-leading:
+		t.Fatalf("リーダーがいる間は、ほかのインスタンスはリーダーになるべきでない: %s", id)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// a を止めるとリースを手放すので、b は期限を待たずに引き継ぎます
+	start := time.Now()
+	stopA()
+	<-doneA
+	select {
+	case id := <-leading:
+		if id != "b" {
+			t.Fatalf("リーダー = %s", id)
+		}
+		if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+			t.Errorf("リースを手放したら、期限を待たずに引き継ぐべき: %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("待機していたインスタンスが引き継がなかった")
+	}
+
+	stopB()
+	<-doneB
+}
+
+// TestElector_Expired は、リーダーがリースを更新しなくなったら、期限が切れてから引き継ぐことをテストします。
+func TestElector_Expired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duckdns.lease")
+	now := time.Now()
+	if err := NewFileLock(path).Update(context.Background(), Record{
+		HolderIdentity: "crashed",
+		LeaseDuration:  300 * time.Millisecond,
+		AcquireTime:    now,
+		RenewTime:      now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	leading := make(chan string, 1)
+	start := time.Now()
+	done := startElector(ctx, path, "b", leading)
+
+	select {
+	case <-leading:
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Errorf("期限が切れる前に引き継ぐべきでない: %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("期限が切れても引き継がなかった")
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,147 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleGuardAge は、書き込み中の印（.lock ファイル）が残っていても、書いたインスタンスが止まったとみなすまでの時間です。
+// 書き込みは一瞬で終わるので、これより古い印は消してから書き込みます。
+const staleGuardAge = 30 * time.Second
+
+// fileRecord は、リースのファイルの形式です（Kubernetes の Lease の spec と同じ項目）。
+type fileRecord struct {
+	HolderIdentity       string    `json:"holderIdentity"`
+	LeaseDurationSeconds float64   `json:"leaseDurationSeconds"`
+	AcquireTime          time.Time `json:"acquireTime"`
+	RenewTime            time.Time `json:"renewTime"`
+	LeaseTransitions     int       `json:"leaseTransitions"`
+}
+
+// FileLock は、リースをファイルに保存する Lock です。
+// 2台のサーバーで動かす場合は、両方からマウントした共有ディレクトリ（NFS など）のファイルを指定します。
+// 書き込むときは、同じディレクトリに O_EXCL で作る印（<path>.lock）で、ほかのインスタンスと同時に書き込まないようにします。
+type FileLock struct {
+	// Path は、リースのファイルのパスです
+	Path string
+
+	// last は、最後に Get したときのファイルの内容です（ファイルがなかった場合は nil）
+	last []byte
+}
+
+// NewFileLock は、ファイルに保存する Lock を作成します。
+//
+// Parameters:
+//   - path: リースのファイルのパス
+//
+// Returns:
+//   - *FileLock: 作成された Lock
+func NewFileLock(path string) *FileLock {
+	return &FileLock{Path: path}
+}
+
+// String は、リースのファイルのパスを返します。
+func (l *FileLock) String() string {
+	return l.Path
+}
+
+// Get は、リースのファイルを読み込みます（ファイルがない場合は nil）。
+func (l *FileLock) Get(ctx context.Context) (*Record, error) {
+	data, err := os.ReadFile(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		l.last = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("リースのファイルを読み込めません: %w", err)
+	}
+	var fr fileRecord
+	if err := json.Unmarshal(data, &fr); err != nil {
+		return nil, fmt.Errorf("リースのファイルの形式が不正です: %w", err)
+	}
+	l.last = data
+	return &Record{
+		HolderIdentity: fr.HolderIdentity,
+		LeaseDuration:  time.Duration(fr.LeaseDurationSeconds * float64(time.Second)),
+		AcquireTime:    fr.AcquireTime,
+		RenewTime:      fr.RenewTime,
+		Transitions:    fr.LeaseTransitions,
+	}, nil
+}
+
+// Update は、リースのファイルを書き込みます。
+// 最後に Get してからファイルが書き換えられていた場合や、ほかのインスタンスが書き込み中の場合は ErrConflict を返します。
+func (l *FileLock) Update(ctx context.Context, record Record) error {
+	data, err := json.MarshalIndent(fileRecord{
+		HolderIdentity:       record.HolderIdentity,
+		LeaseDurationSeconds: record.LeaseDuration.Seconds(),
+		AcquireTime:          record.AcquireTime,
+		RenewTime:            record.RenewTime,
+		LeaseTransitions:     record.Transitions,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return fmt.Errorf("リースのファイルのディレクトリを作成できません: %w", err)
+	}
+	unlock, err := l.guard()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// 印を作るまでの間に、ほかのインスタンスが書き換えていないか確かめます
+	current, err := os.ReadFile(l.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if l.last != nil {
+			return ErrConflict
+		}
+	case err != nil:
+		return fmt.Errorf("リースのファイルを読み込めません: %w", err)
+	case !bytes.Equal(current, l.last):
+		return ErrConflict
+	}
+
+	// 書きかけのファイルを読まれないように、一時ファイルに書いてから置き換えます
+	tmp := l.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("リースのファイルを書き込めません: %w", err)
+	}
+	if err := os.Rename(tmp, l.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("リースのファイルを書き込めません: %w", err)
+	}
+	l.last = data
+	return nil
+}
+
+// guard は、書き込み中の印を作り、消す関数を返します。
+// ほかのインスタンスが書き込み中の場合は ErrConflict を返します。
+func (l *FileLock) guard() (func(), error) {
+	path := l.Path + ".lock"
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("リースのファイルの印を作成できません: %w", err)
+		}
+		info, statErr := os.Stat(path)
+		if attempt > 0 || statErr != nil || time.Since(info.ModTime()) < staleGuardAge {
+			return nil, ErrConflict
+		}
+		// 書き込み中に止まったインスタンスの印なので、消してからやり直します
+		os.Remove(path)
+	}
+}
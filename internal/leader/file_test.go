@@ -0,0 +1,69 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFileLock は、リースのファイルの作成・読み込みと、ほかのインスタンスが書き換えた場合の ErrConflict をテストします。
+func TestFileLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "leases", "duckdns.lease")
+	a, b := NewFileLock(path), NewFileLock(path)
+
+	if record, err := a.Get(ctx); err != nil || record != nil {
+		t.Fatalf("ファイルがない場合は nil を返すべき: %v, %v", record, err)
+	}
+	if _, err := b.Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	if err := a.Update(ctx, Record{HolderIdentity: "a", LeaseDuration: 15 * time.Second, AcquireTime: now, RenewTime: now}); err != nil {
+		t.Fatal(err)
+	}
+	// b は a が書く前の状態しか見ていないので、書き込めません
+	if err := b.Update(ctx, Record{HolderIdentity: "b", LeaseDuration: 15 * time.Second}); !errors.Is(err, ErrConflict) {
+		t.Errorf("ほかのインスタンスが書き換えた場合は ErrConflict を返すべき: %v", err)
+	}
+
+	record, err := b.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.HolderIdentity != "a" || record.LeaseDuration != 15*time.Second || !record.RenewTime.Equal(now) {
+		t.Errorf("リース = %+v", record)
+	}
+	if err := b.Update(ctx, Record{HolderIdentity: "b", Transitions: 1}); err != nil {
+		t.Errorf("読み込み直したあとは書き込めるべき: %v", err)
+	}
+}
+
+// TestFileLock_Guard は、書き込み中の印があれば ErrConflict を返し、古い印は消して書き込むことをテストします。
+func TestFileLock_Guard(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "duckdns.lease")
+	lock := NewFileLock(path)
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lock.Update(ctx, Record{HolderIdentity: "a"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("書き込み中の印がある場合は ErrConflict を返すべき: %v", err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Update(ctx, Record{HolderIdentity: "a"}); err != nil {
+		t.Errorf("古い印は消して書き込むべき: %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("書き込んだあとは印を消すべき: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package leader
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/kube"
+)
+
+// KubernetesLock は、リースを Kubernetes の Lease（coordination.k8s.io/v1）に保存する Lock です。
+// 書き換えるときは resourceVersion を付けるので、ほかのインスタンスと同時に書き込んだ場合は API サーバーが拒否します。
+type KubernetesLock struct {
+	// Client は、API クライアントです
+	Client *kube.Client
+
+	// Namespace は、Lease の名前空間です
+	Namespace string
+
+	// Name は、Lease の名前です
+	Name string
+
+	// last は、最後に Get したときの Lease です（なかった場合は nil）
+	last *kube.Lease
+}
+
+// NewKubernetesLock は、Kubernetes の Lease に保存する Lock を作成します。
+//
+// Parameters:
+//   - client: API クライアント
+//   - namespace: Lease の名前空間
+//   - name: Lease の名前
+//
+// Returns:
+//   - *KubernetesLock: 作成された Lock
+func NewKubernetesLock(client *kube.Client, namespace, name string) *KubernetesLock {
+	return &KubernetesLock{Client: client, Namespace: namespace, Name: name}
+}
+
+// String は、"namespace/name" 形式の Lease の名前を返します。
+func (l *KubernetesLock) String() string {
+	return l.Namespace + "/" + l.Name
+}
+
+// Get は、Lease を取得します（ない場合は nil）。
+func (l *KubernetesLock) Get(ctx context.Context) (*Record, error) {
+	lease, err := l.Client.GetLease(ctx, l.Namespace, l.Name)
+	if kube.IsNotFound(err) {
+		l.last = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.last = lease
+
+	record := &Record{
+		HolderIdentity: lease.Spec.HolderIdentity,
+		LeaseDuration:  time.Duration(lease.Spec.LeaseDurationSeconds) * time.Second,
+		Transitions:    lease.Spec.LeaseTransitions,
+	}
+	if lease.Spec.AcquireTime != nil {
+		record.AcquireTime = lease.Spec.AcquireTime.Time
+	}
+	if lease.Spec.RenewTime != nil {
+		record.RenewTime = lease.Spec.RenewTime.Time
+	}
+	return record, nil
+}
+
+// Update は、Lease を作成するか書き換えます。
+// 最後に Get してからほかのインスタンスが書き換えていた場合は ErrConflict を返します。
+func (l *KubernetesLock) Update(ctx context.Context, record Record) error {
+	acquire := kube.MicroTime{Time: record.AcquireTime}
+	renew := kube.MicroTime{Time: record.RenewTime}
+	spec := kube.LeaseSpec{
+		HolderIdentity:       record.HolderIdentity,
+		LeaseDurationSeconds: int(math.Ceil(record.LeaseDuration.Seconds())),
+		AcquireTime:          &acquire,
+		RenewTime:            &renew,
+		LeaseTransitions:     record.Transitions,
+	}
+
+	var lease *kube.Lease
+	var err error
+	if l.last == nil {
+		lease, err = l.Client.CreateLease(ctx, &kube.Lease{
+			Metadata: kube.ObjectMeta{Name: l.Name, Namespace: l.Namespace},
+			Spec:     spec,
+		})
+	} else {
+		next := *l.last
+		next.Spec = spec
+		lease, err = l.Client.UpdateLease(ctx, &next)
+	}
+	if kube.IsConflict(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	l.last = lease
+	return nil
+}
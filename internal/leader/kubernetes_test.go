@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/kube"
+)
+
+// fakeLeaseServer は、1つの Lease を resourceVersion つきで保存する、API サーバーの代わりです。
+type fakeLeaseServer struct {
+	mu      sync.Mutex
+	lease   *kube.Lease
+	version int
+}
+
+func (f *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	const path = "/apis/coordination.k8s.io/v1/namespaces/duckdns/leases"
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == path+"/leader":
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":404,"message":"not found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(f.lease)
+	case r.Method == http.MethodPost && r.URL.Path == path, r.Method == http.MethodPut && r.URL.Path == path+"/leader":
+		var lease kube.Lease
+		json.NewDecoder(r.Body).Decode(&lease)
+		current := ""
+		if f.lease != nil {
+			current = f.lease.Metadata.ResourceVersion
+		}
+		if (r.Method == http.MethodPost && f.lease != nil) || (r.Method == http.MethodPut && lease.Metadata.ResourceVersion != current) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code":409,"message":"conflict"}`))
+			return
+		}
+		f.version++
+		lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		f.lease = &lease
+		json.NewEncoder(w).Encode(f.lease)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestKubernetesLock は、Lease の作成・書き換えと、ほかのインスタンスが書き換えた場合の ErrConflict をテストします。
+func TestKubernetesLock(t *testing.T) {
+	server := httptest.NewServer(&fakeLeaseServer{})
+	defer server.Close()
+	client, err := kube.NewClient(kube.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	a := NewKubernetesLock(client, "duckdns", "leader")
+	b := NewKubernetesLock(client, "duckdns", "leader")
+
+	if record, err := a.Get(ctx); err != nil || record != nil {
+		t.Fatalf("Lease がない場合は nil を返すべき: %v, %v", record, err)
+	}
+	if _, err := b.Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := a.Update(ctx, Record{HolderIdentity: "a", LeaseDuration: 15 * time.Second, AcquireTime: now, RenewTime: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Update(ctx, Record{HolderIdentity: "b"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("ほかのインスタンスが作成した場合は ErrConflict を返すべき: %v", err)
+	}
+
+	record, err := b.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.HolderIdentity != "a" || record.LeaseDuration != 15*time.Second || !record.RenewTime.Truncate(time.Microsecond).Equal(now.Truncate(time.Microsecond)) {
+		t.Errorf("リース = %+v", record)
+	}
+	// a は b が Get したあとに書き換えるので、b の書き込みは拒否されます
+	if err := a.Update(ctx, Record{HolderIdentity: "a", LeaseDuration: 15 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Update(ctx, Record{HolderIdentity: "b"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("ほかのインスタンスが書き換えた場合は ErrConflict を返すべき: %v", err)
+	}
+}
@@ -3,10 +3,13 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 // InitLogger は、指定されたログレベルとフォーマットでロガーを初期化します。
@@ -19,14 +22,34 @@ import (
 // 戻り値:
 //   - エラーが発生した場合は error を返します
 func InitLogger(level, format string, writer ...io.Writer) error {
+	return InitLoggerInLocation(level, format, nil, writer...)
+}
+
+// InitLoggerInLocation は、ログの時刻を指定したタイムゾーンで出力するロガーを初期化します。
+// time.Local を書き換えずに、ログの時刻だけをそのタイムゾーンに変換します。
+//
+// パラメータ:
+//   - level: ログレベル ("debug", "info", "warn", "error")
+//   - format: ログフォーマット ("json" または "text")
+//   - loc: ログの時刻のタイムゾーン (nil の場合はホストのローカルタイム)
+//   - writer: ログ出力先 (デフォルト: os.Stderr)
+//
+// 戻り値:
+//   - エラーが発生した場合は error を返します
+func InitLoggerInLocation(level, format string, loc *time.Location, writer ...io.Writer) error {
 	// 出力先を決定
 	var output io.Writer = os.Stderr
 	if len(writer) > 0 && writer[0] != nil {
 		output = writer[0]
 	}
 
-	// ログレベルを解析
-	logLevel := parseLogLevel(level)
+	opts := &slog.HandlerOptions{
+		Level:     parseLogLevel(level),
+		AddSource: true,
+	}
+	if loc != nil {
+		opts.ReplaceAttr = inLocation(loc)
+	}
 
 	// ログハンドラーを作成
 	var handler slog.Handler
@@ -34,24 +57,15 @@ func InitLogger(level, format string, writer ...io.Writer) error {
 	switch strings.ToLower(format) {
 	case "json":
 		// JSON形式でのログ出力
-		handler = slog.NewJSONHandler(output, &slog.HandlerOptions{
-			Level:     logLevel,
-			AddSource: true,
-		})
+		handler = slog.NewJSONHandler(output, opts)
 	case "text", "":
 		// テキスト形式でのログ出力（デフォルト）
-		handler = slog.NewTextHandler(output, &slog.HandlerOptions{
-			Level:     logLevel,
-			AddSource: true,
-		})
+		handler = slog.NewTextHandler(output, opts)
 	default:
 		// 不正なフォーマット
 		slog.Error("不正なログフォーマットが指定されました", "format", format)
 		// テキスト形式にフォールバック
-		handler = slog.NewTextHandler(output, &slog.HandlerOptions{
-			Level:     logLevel,
-			AddSource: true,
-		})
+		handler = slog.NewTextHandler(output, opts)
 	}
 
 	// デフォルトロガーを設定
@@ -66,6 +80,16 @@ func InitLogger(level, format string, writer ...io.Writer) error {
 	return nil
 }
 
+// inLocation は、ログの時刻を loc のタイムゾーンに変換する ReplaceAttr を返します。
+func inLocation(loc *time.Location) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+			return slog.Time(a.Key, a.Value.Time().In(loc))
+		}
+		return a
+	}
+}
+
 // parseLogLevel は、文字列のログレベルを slog.Level に変換します。
 func parseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
@@ -87,3 +111,23 @@ func parseLogLevel(level string) slog.Level {
 func GetLogger() *slog.Logger {
 	return slog.Default()
 }
+
+// OpenFile は、ログを追記するファイルを開きます。
+// ディレクトリがない場合は作成し、ファイルがない場合はパーミッション 0640 で作成します。
+//
+// パラメータ:
+//   - path: ログを追記するファイルのパス
+//
+// 戻り値:
+//   - 開いたファイル（使い終わったら閉じてください）
+//   - ファイルを開けない場合は error を返します
+func OpenFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("ログのディレクトリの作成に失敗しました: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("ログファイルを開けません: %w", err)
+	}
+	return file, nil
+}
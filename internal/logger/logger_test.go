@@ -3,8 +3,11 @@ package logger
 import (
 	"bytes"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestInitLogger_TextFormat(t *testing.T) {
@@ -34,6 +37,23 @@ func TestInitLogger_JSONFormat(t *testing.T) {
 	}
 }
 
+func TestInitLoggerInLocation(t *testing.T) {
+	var buf bytes.Buffer
+	local := time.Local
+	loc := time.FixedZone("NPT", 5*60*60+45*60)
+	err := InitLoggerInLocation("info", "json", loc, &buf)
+	if err != nil {
+		t.Errorf("エラーが発生しました: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "+05:45") {
+		t.Errorf("ログの時刻は指定したタイムゾーンであるべきです: %s", output)
+	}
+	if time.Local != local {
+		t.Error("time.Local は書き換えないべきです")
+	}
+}
+
 func TestInitLogger_DebugLevel(t *testing.T) {
 	var buf bytes.Buffer
 	err := InitLogger("debug", "text", &buf)
@@ -237,3 +257,42 @@ func TestInitLogger_EmptyFormat(t *testing.T) {
 		t.Errorf("ログメッセージが含まれていません: %s", output)
 	}
 }
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log", "duckdns.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("前の行\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("ログファイルを開けませんでした: %v", err)
+	}
+	if err := InitLogger("info", "text", file); err != nil {
+		t.Errorf("エラーが発生しました: %v", err)
+	}
+	file.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "前の行\n") || !strings.Contains(string(data), "ログシステムが初期化されました") {
+		t.Errorf("既存の内容に追記されるべき: %s", data)
+	}
+}
+
+func TestOpenFile_CreatesDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "duckdns.log")
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("ディレクトリも作成されるべき: %v", err)
+	}
+	file.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("ログファイルが作成されていません: %v", err)
+	}
+}
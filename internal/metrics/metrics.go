@@ -0,0 +1,168 @@
+// Package metrics は、状態ファイルに記録したドメインごとの更新状況を、Prometheus のテキスト形式のメトリクスとして公開します。
+// 連続して失敗した回数や最後に成功した時刻をしきい値で監視して、更新が黙って止まっていることに気づけるようにします。
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// ContentType は、Prometheus のテキスト形式のメトリクスの Content-Type です。
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// BuildInfo は、動いているプログラムのビルドの情報です。
+// duckdns_build_info のラベルと GET /api/version で公開し、古いビルドのままの機器をダッシュボードで見つけられるようにします。
+type BuildInfo struct {
+	// Version は、バージョンです
+	Version string `json:"version"`
+
+	// Commit は、ビルドしたコミットです
+	Commit string `json:"commit"`
+
+	// Date は、ビルドした日時です
+	Date string `json:"date"`
+
+	// GoVersion は、ビルドした Go のバージョンです
+	GoVersion string `json:"go_version"`
+}
+
+// WriteBuildInfo は、ビルドの情報をラベルに持ち、値が常に 1 の duckdns_build_info を Prometheus のテキスト形式で書き込みます。
+//
+// Parameters:
+//   - w: 書き込み先
+//   - b: ビルドの情報
+//
+// Returns:
+//   - error: 書き込みに失敗した場合
+func WriteBuildInfo(w io.Writer, b BuildInfo) error {
+	_, err := fmt.Fprintf(w, "# HELP duckdns_build_info 動いているプログラムのバージョン・コミット・ビルド日時（値は常に 1）\n"+
+		"# TYPE duckdns_build_info gauge\n"+
+		"duckdns_build_info{version=\"%s\",commit=\"%s\",date=\"%s\",goversion=\"%s\"} 1\n",
+		labelEscaper.Replace(b.Version),
+		labelEscaper.Replace(b.Commit),
+		labelEscaper.Replace(b.Date),
+		labelEscaper.Replace(b.GoVersion),
+	)
+	return err
+}
+
+// gauge は、ドメインごとに値を出力するゲージです。
+type gauge struct {
+	// name は、メトリクスの名前です
+	name string
+
+	// help は、メトリクスの説明です
+	help string
+
+	// value は、ドメインの状況からメトリクスの値を返します
+	value func(d *state.DomainStatus) float64
+}
+
+// gauges は、出力するゲージの一覧です。
+var gauges = []gauge{
+	{
+		name:  "duckdns_consecutive_failures",
+		help:  "ドメインのチェックに連続して失敗した回数",
+		value: func(d *state.DomainStatus) float64 { return float64(d.ConsecutiveFailures) },
+	},
+	{
+		name:  "duckdns_last_success_timestamp_seconds",
+		help:  "ドメインのチェックに最後に成功した時刻の Unix 時間（まだない場合は 0）",
+		value: func(d *state.DomainStatus) float64 { return timestamp(d.LastSuccess) },
+	},
+	{
+		name:  "duckdns_last_ip_change_timestamp_seconds",
+		help:  "DuckDNS に登録した IP アドレスが最後に変わった時刻の Unix 時間（まだない場合は 0）",
+		value: func(d *state.DomainStatus) float64 { return timestamp(d.LastIPChange) },
+	},
+}
+
+// Write は、ドメインごとの更新状況を Prometheus のテキスト形式で書き込みます。
+// ドメインは名前の順に並べ、まだ記録のない時刻は 0 として出力します。
+//
+// Parameters:
+//   - w: 書き込み先
+//   - st: 状態ファイルから読み込んだ状態
+//
+// Returns:
+//   - error: 書き込みに失敗した場合
+func Write(w io.Writer, st *state.State) error {
+	domains := st.SortedDomains()
+	bw := bufio.NewWriter(w)
+	for _, g := range gauges {
+		fmt.Fprintf(bw, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", g.name)
+		for _, d := range domains {
+			fmt.Fprintf(bw, "%s{domain=\"%s\"} %s\n",
+				g.name,
+				labelEscaper.Replace(d.Domain),
+				strconv.FormatFloat(g.value(d), 'f', -1, 64),
+			)
+		}
+	}
+	return bw.Flush()
+}
+
+// NewHandler は、メトリクスを公開する HTTP ハンドラーを作成します。
+//
+//   - GET /metrics     : ビルドの情報とドメインごとの更新状況を Prometheus のテキスト形式で返します
+//   - GET /api/version : ビルドの情報を JSON で返します
+//   - GET /healthz     : 動いているかどうかを返します
+//
+// Parameters:
+//   - build: 公開するビルドの情報
+//   - load: リクエストのたびに状態を読み込む関数
+//
+// Returns:
+//   - http.Handler: HTTP ハンドラー
+func NewHandler(build BuildInfo, load func() (*state.State, error)) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		st, err := load()
+		if err != nil {
+			slog.Error("metrics: 状態を読み込めませんでした", "error", err)
+			http.Error(w, "状態を読み込めませんでした", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		if err := WriteBuildInfo(w, build); err != nil {
+			slog.Debug("metrics: メトリクスを書き込めませんでした", "error", err)
+			return
+		}
+		if err := Write(w, st); err != nil {
+			slog.Debug("metrics: メトリクスを書き込めませんでした", "error", err)
+		}
+	})
+
+	mux.HandleFunc("GET /api/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(build)
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+// labelEscaper は、ラベルの値に含められない文字をエスケープします。
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// timestamp は、時刻を Unix 時間の秒に変換します（ゼロ値の場合は 0）。
+func timestamp(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixMilli()) / 1000
+}
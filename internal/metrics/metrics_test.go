@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/state"
+)
+
+// newTestState は、2つのドメインの状況を持つ状態を作成します。
+func newTestState() *state.State {
+	success := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	return &state.State{
+		Domains: map[string]*state.DomainStatus{
+			"home": {
+				Domain:       "home",
+				LastSuccess:  success,
+				LastIPChange: success.Add(-time.Hour),
+			},
+			"broken": {
+				Domain:              "broken",
+				ConsecutiveFailures: 3,
+			},
+		},
+	}
+}
+
+// TestWrite は、ドメインごとのゲージを Prometheus のテキスト形式で書き込むことをテストします。
+func TestWrite(t *testing.T) {
+	var sb strings.Builder
+	if err := Write(&sb, newTestState()); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+
+	want := `# HELP duckdns_consecutive_failures ドメインのチェックに連続して失敗した回数
+# TYPE duckdns_consecutive_failures gauge
+duckdns_consecutive_failures{domain="broken"} 3
+duckdns_consecutive_failures{domain="home"} 0
+# HELP duckdns_last_success_timestamp_seconds ドメインのチェックに最後に成功した時刻の Unix 時間（まだない場合は 0）
+# TYPE duckdns_last_success_timestamp_seconds gauge
+duckdns_last_success_timestamp_seconds{domain="broken"} 0
+duckdns_last_success_timestamp_seconds{domain="home"} 1767323045.5
+# HELP duckdns_last_ip_change_timestamp_seconds DuckDNS に登録した IP アドレスが最後に変わった時刻の Unix 時間（まだない場合は 0）
+# TYPE duckdns_last_ip_change_timestamp_seconds gauge
+duckdns_last_ip_change_timestamp_seconds{domain="broken"} 0
+duckdns_last_ip_change_timestamp_seconds{domain="home"} 1767319445.5
+`
+	if got := sb.String(); got != want {
+		t.Errorf("出力が一致しません。\n期待:\n%s\n実際:\n%s", want, got)
+	}
+}
+
+// TestWrite_EscapeLabel は、ラベルの値に含められない文字をエスケープすることをテストします。
+func TestWrite_EscapeLabel(t *testing.T) {
+	st := &state.State{Domains: map[string]*state.DomainStatus{
+		`a"b\c`: {Domain: `a"b\c`},
+	}}
+	var sb strings.Builder
+	if err := Write(&sb, st); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+	if !strings.Contains(sb.String(), `{domain="a\"b\\c"}`) {
+		t.Errorf("ラベルの値はエスケープされるべき: %s", sb.String())
+	}
+}
+
+// TestWriteBuildInfo は、ビルドの情報をラベルに持つ duckdns_build_info を書き込むことをテストします。
+func TestWriteBuildInfo(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteBuildInfo(&sb, BuildInfo{Version: "v1.2.3", Commit: "abc1234", Date: "2026-01-02T03:04:05Z", GoVersion: "go1.23.0"}); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+
+	want := `# HELP duckdns_build_info 動いているプログラムのバージョン・コミット・ビルド日時（値は常に 1）
+# TYPE duckdns_build_info gauge
+duckdns_build_info{version="v1.2.3",commit="abc1234",date="2026-01-02T03:04:05Z",goversion="go1.23.0"} 1
+`
+	if got := sb.String(); got != want {
+		t.Errorf("出力が一致しません。\n期待:\n%s\n実際:\n%s", want, got)
+	}
+}
+
+// TestNewHandler は、メトリクスと動作確認の応答をテストします。
+func TestNewHandler(t *testing.T) {
+	var loadErr error
+	build := BuildInfo{Version: "v1.2.3", Commit: "abc1234", Date: "2026-01-02T03:04:05Z", GoVersion: "go1.23.0"}
+	server := httptest.NewServer(NewHandler(build, func() (*state.State, error) {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return newTestState(), nil
+	}))
+	defer server.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get("/metrics")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != ContentType {
+		t.Errorf("メトリクスはテキスト形式で返すべき: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(body, `duckdns_consecutive_failures{domain="broken"} 3`) {
+		t.Errorf("ドメインごとのゲージを返すべき: %s", body)
+	}
+	if !strings.Contains(body, `duckdns_build_info{version="v1.2.3",`) {
+		t.Errorf("ビルドの情報を返すべき: %s", body)
+	}
+
+	resp, body = get("/api/version")
+	var got BuildInfo
+	if resp.StatusCode != http.StatusOK || json.Unmarshal([]byte(body), &got) != nil || got != build {
+		t.Errorf("ビルドの情報を JSON で返すべき: %d %s", resp.StatusCode, body)
+	}
+
+	if resp, body := get("/healthz"); resp.StatusCode != http.StatusOK || body != "ok" {
+		t.Errorf("動作確認は ok を返すべき: %d %s", resp.StatusCode, body)
+	}
+
+	loadErr = errors.New("読み込めません")
+	if resp, _ := get("/metrics"); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("状態を読み込めない場合は 500 を返すべき: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,279 @@
+// Package mqtt は、更新状況を MQTT ブローカーに送るための、最小限の MQTT 3.1.1 クライアントと Publisher を提供します。
+// Home Assistant の MQTT discovery のメッセージも送るので、設定を書かなくても IP アドレスや更新の状況がセンサーとして表示されます。
+// 送るのは QoS 0 の PUBLISH だけで、購読はしません。
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 接続の既定値です。
+const (
+	// DefaultKeepAlive は、KeepAlive を省略した場合の、ブローカーとの生存確認の間隔です
+	DefaultKeepAlive = 60 * time.Second
+
+	// DefaultTimeout は、Timeout を省略した場合の、接続と書き込みのタイムアウトです
+	DefaultTimeout = 10 * time.Second
+)
+
+// Message は、送るメッセージです。
+type Message struct {
+	// Topic は、トピックです
+	Topic string
+
+	// Payload は、本文です
+	Payload []byte
+
+	// Retain は、ブローカーにメッセージを残して、後から購読したクライアントにも届けるかどうかです
+	Retain bool
+}
+
+// Options は、ブローカーへの接続の設定です。
+type Options struct {
+	// Broker は、ブローカーの URL です（tcp://、mqtt://、ssl://、mqtts://、tls://。ポートを省略した場合は 1883 または 8883）
+	Broker string
+
+	// ClientID は、クライアント ID です（ブローカーの中で一意にします）
+	ClientID string
+
+	// Username は、ユーザー名です（省略した場合は認証しません）
+	Username string
+
+	// Password は、パスワードです
+	Password string
+
+	// KeepAlive は、生存確認の間隔です（0 の場合は DefaultKeepAlive）
+	KeepAlive time.Duration
+
+	// Will は、接続が切れたときにブローカーが代わりに送るメッセージです（nil の場合は送りません）
+	Will *Message
+
+	// TLS は、TLS で接続する場合の設定です（ssl:// などの場合に nil なら既定の設定を使います）
+	TLS *tls.Config
+
+	// Timeout は、接続と書き込みのタイムアウトです（0 の場合は DefaultTimeout）
+	Timeout time.Duration
+}
+
+// ParseBroker は、ブローカーの URL から、接続するアドレス（host:port）と TLS で接続するかどうかを返します。
+//
+// Parameters:
+//   - broker: ブローカーの URL
+//
+// Returns:
+//   - string: 接続するアドレス
+//   - bool: TLS で接続する場合は true
+//   - error: URL が不正な場合
+func ParseBroker(broker string) (string, bool, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("ブローカーの URL \"%s\" は tcp://host:port の形式で指定してください", broker)
+	}
+
+	var secure bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "mqtts", "tls":
+		secure, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("ブローカーの URL のスキーム \"%s\" は tcp、mqtt、ssl、mqtts、tls のいずれかで指定してください", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// Conn は、ブローカーとの接続です。
+// 生存確認の応答がない場合や、読み込みに失敗した場合は、接続を閉じて Done を閉じます。
+type Conn struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	writeMu sync.Mutex
+	pong    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// Dial は、ブローカーに接続して、CONNACK で受け入れられるまで待ちます。
+//
+// Parameters:
+//   - ctx: 接続を中止するためのコンテキスト
+//   - opts: 接続の設定
+//
+// Returns:
+//   - *Conn: 接続
+//   - error: 接続できなかった場合、またはブローカーが拒否した場合
+func Dial(ctx context.Context, opts Options) (*Conn, error) {
+	address, secure, err := ParseBroker(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	keepAlive := opts.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if secure {
+		config := opts.TLS
+		if config == nil {
+			config = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(address)
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: config}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ブローカー (%s) に接続できません: %w", address, err)
+	}
+
+	rd := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(connectPacket(opts, uint16(keepAlive/time.Second))); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ブローカー (%s) に接続できません: %w", address, err)
+	}
+	header, body, err := readPacket(rd)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ブローカー (%s) から CONNACK を受け取れません: %w", address, err)
+	}
+	if header>>4 != packetConnack || len(body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("ブローカー (%s) の応答が CONNACK ではありません", address)
+	}
+	if code := body[1]; code != 0 {
+		conn.Close()
+		msg, ok := connackErrors[code]
+		if !ok {
+			msg = fmt.Sprintf("戻りコード %d", code)
+		}
+		return nil, fmt.Errorf("ブローカー (%s) が接続を拒否しました: %s", address, msg)
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Conn{
+		conn:    conn,
+		timeout: timeout,
+		pong:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(rd)
+	go c.keepAlive(keepAlive)
+	return c, nil
+}
+
+// Publish は、QoS 0 でメッセージを送ります。
+func (c *Conn) Publish(msg Message) error {
+	return c.write(publishPacket(msg))
+}
+
+// Done は、接続が切れたときに閉じられるチャネルを返します。
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err は、接続が切れた理由を返します（切れていない場合は nil）。
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close は、DISCONNECT を送ってから接続を閉じます。
+// DISCONNECT で閉じた場合、ブローカーは Will のメッセージを送りません。
+func (c *Conn) Close() error {
+	err := c.write(packet(packetDisconnect<<4, nil))
+	c.shutdown(errors.New("接続を閉じました"))
+	return err
+}
+
+// write は、パケットを書き込みます。
+func (c *Conn) write(p []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.Err(); err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(p); err != nil {
+		c.shutdown(err)
+		return fmt.Errorf("ブローカーに送信できません: %w", err)
+	}
+	return nil
+}
+
+// readLoop は、接続が切れるまでブローカーからのパケットを読み込みます。
+func (c *Conn) readLoop(rd *bufio.Reader) {
+	for {
+		header, _, err := readPacket(rd)
+		if err != nil {
+			c.shutdown(fmt.Errorf("ブローカーとの接続が切れました: %w", err))
+			return
+		}
+		if header>>4 == packetPingresp {
+			select {
+			case c.pong <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// keepAlive は、interval ごとに PINGREQ を送り、次の PINGREQ までに応答がなければ接続を閉じます。
+func (c *Conn) keepAlive(interval time.Duration) {
+	// ブローカーは interval の 1.5 倍の間に何も届かないと切断するため、余裕をもって送ります
+	ticker := time.NewTicker(interval * 3 / 4)
+	defer ticker.Stop()
+	waiting := false
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.pong:
+			waiting = false
+		case <-ticker.C:
+			if waiting {
+				c.shutdown(errors.New("ブローカーから生存確認の応答がありません"))
+				return
+			}
+			if c.write(packet(packetPingreq<<4, nil)) != nil {
+				return
+			}
+			waiting = true
+		}
+	}
+}
+
+// shutdown は、接続を閉じて Done を閉じます（2回目以降は何もしません）。
+func (c *Conn) shutdown(err error) {
+	c.once.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
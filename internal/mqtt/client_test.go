@@ -0,0 +1,269 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// connectInfo は、テスト用のブローカーが受け取った CONNECT の内容です。
+type connectInfo struct {
+	clientID, username, password string
+	keepAlive                    uint16
+	will                         *Message
+}
+
+// fakeBroker は、テスト用の MQTT ブローカーです。
+type fakeBroker struct {
+	ln      net.Listener
+	code    byte
+	noPong  bool
+	connect chan connectInfo
+
+	mu           sync.Mutex
+	messages     []Message
+	disconnected bool
+	conns        []net.Conn
+}
+
+// newFakeBroker は、CONNACK で code を返すテスト用のブローカーを起動します。
+func newFakeBroker(t *testing.T, code byte) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("待ち受けに失敗: %v", err)
+	}
+	b := &fakeBroker{ln: ln, code: code, connect: make(chan connectInfo, 10)}
+	t.Cleanup(func() {
+		ln.Close()
+		b.closeConns()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// url は、ブローカーの URL を返します。
+func (b *fakeBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+// closeConns は、つながっている接続をすべて切ります。
+func (b *fakeBroker) closeConns() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+}
+
+// received は、受け取ったメッセージを返します。
+func (b *fakeBroker) received() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+// waitFor は、cond が true になるまで（最大2秒）待ちます。
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("待っている状態になりませんでした")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// serve は、1つの接続のパケットに応答します。
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		header, body, err := readPacket(rd)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case packetConnect:
+			b.connect <- parseConnect(body)
+			conn.Write([]byte{packetConnack << 4, 2, 0, b.code})
+		case packetPublish:
+			n := int(binary.BigEndian.Uint16(body))
+			b.mu.Lock()
+			b.messages = append(b.messages, Message{Topic: string(body[2 : 2+n]), Payload: body[2+n:], Retain: header&0x01 != 0})
+			b.mu.Unlock()
+		case packetPingreq:
+			if !b.noPong {
+				conn.Write([]byte{packetPingresp << 4, 0})
+			}
+		case packetDisconnect:
+			b.mu.Lock()
+			b.disconnected = true
+			b.mu.Unlock()
+			return
+		}
+	}
+}
+
+// parseConnect は、CONNECT の本体を読み込みます。
+func parseConnect(body []byte) connectInfo {
+	rd := bytes.NewReader(body)
+	readStr := func() string {
+		var n uint16
+		binary.Read(rd, binary.BigEndian, &n)
+		b := make([]byte, n)
+		rd.Read(b)
+		return string(b)
+	}
+	readStr() // "MQTT"
+	rd.ReadByte()
+	flags, _ := rd.ReadByte()
+	var info connectInfo
+	binary.Read(rd, binary.BigEndian, &info.keepAlive)
+	info.clientID = readStr()
+	if flags&flagWill != 0 {
+		info.will = &Message{Topic: readStr(), Payload: []byte(readStr()), Retain: flags&flagWillRetain != 0}
+	}
+	if flags&flagUsername != 0 {
+		info.username = readStr()
+	}
+	if flags&flagPassword != 0 {
+		info.password = readStr()
+	}
+	return info
+}
+
+// TestParseBroker は、ブローカーの URL からアドレスと TLS の有無を決めることをテストします。
+func TestParseBroker(t *testing.T) {
+	tests := []struct {
+		broker     string
+		wantAddr   string
+		wantSecure bool
+		wantErr    bool
+	}{
+		{broker: "tcp://localhost", wantAddr: "localhost:1883"},
+		{broker: "mqtt://192.0.2.1:1884", wantAddr: "192.0.2.1:1884"},
+		{broker: "ssl://broker.example.com", wantAddr: "broker.example.com:8883", wantSecure: true},
+		{broker: "mqtts://[2001:db8::1]:9883", wantAddr: "[2001:db8::1]:9883", wantSecure: true},
+		{broker: "http://broker.example.com", wantErr: true},
+		{broker: "localhost:1883", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.broker, func(t *testing.T) {
+			addr, secure, err := ParseBroker(tt.broker)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("エラーになるべき: %s", addr)
+				}
+				return
+			}
+			if err != nil || addr != tt.wantAddr || secure != tt.wantSecure {
+				t.Errorf("got %s %v %v, want %s %v", addr, secure, err, tt.wantAddr, tt.wantSecure)
+			}
+		})
+	}
+}
+
+// TestDial は、CONNECT でクライアント ID・認証情報・Will を送り、PUBLISH と DISCONNECT を送れることをテストします。
+func TestDial(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	conn, err := Dial(context.Background(), Options{
+		Broker:    broker.url(),
+		ClientID:  "duckdns-test",
+		Username:  "user",
+		Password:  "pass",
+		KeepAlive: 30 * time.Second,
+		Will:      &Message{Topic: "duckdns/status", Payload: []byte("offline"), Retain: true},
+	})
+	if err != nil {
+		t.Fatalf("接続に失敗しました: %v", err)
+	}
+
+	info := <-broker.connect
+	if info.clientID != "duckdns-test" || info.username != "user" || info.password != "pass" || info.keepAlive != 30 {
+		t.Errorf("CONNECT の内容が一致しません: %+v", info)
+	}
+	if info.will == nil || info.will.Topic != "duckdns/status" || string(info.will.Payload) != "offline" || !info.will.Retain {
+		t.Errorf("Will が一致しません: %+v", info.will)
+	}
+
+	payload := strings.Repeat("x", 300)
+	if err := conn.Publish(Message{Topic: "duckdns/home/state", Payload: []byte(payload), Retain: true}); err != nil {
+		t.Fatalf("送信に失敗しました: %v", err)
+	}
+	waitFor(t, func() bool { return len(broker.received()) == 1 })
+	if got := broker.received()[0]; got.Topic != "duckdns/home/state" || string(got.Payload) != payload || !got.Retain {
+		t.Errorf("受け取ったメッセージが一致しません: %s %d バイト", got.Topic, len(got.Payload))
+	}
+
+	conn.Close()
+	waitFor(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return broker.disconnected
+	})
+	if conn.Publish(Message{Topic: "x"}) == nil {
+		t.Error("閉じた接続では送れないべき")
+	}
+}
+
+// TestDial_Rejected は、ブローカーが拒否した場合に理由がわかるエラーになることをテストします。
+func TestDial_Rejected(t *testing.T) {
+	broker := newFakeBroker(t, 4)
+	_, err := Dial(context.Background(), Options{Broker: broker.url(), ClientID: "duckdns-test"})
+	if err == nil || !strings.Contains(err.Error(), "ユーザー名またはパスワード") {
+		t.Errorf("認証の失敗がわかるエラーになるべき: %v", err)
+	}
+}
+
+// TestConn_KeepAlive は、生存確認の応答がない場合に接続を閉じることをテストします。
+func TestConn_KeepAlive(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	broker.noPong = true
+	conn, err := Dial(context.Background(), Options{Broker: broker.url(), ClientID: "duckdns-test", KeepAlive: time.Second})
+	if err != nil {
+		t.Fatalf("接続に失敗しました: %v", err)
+	}
+	select {
+	case <-conn.Done():
+		if !strings.Contains(conn.Err().Error(), "生存確認") {
+			t.Errorf("生存確認のエラーになるべき: %v", conn.Err())
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("応答がない場合は接続を閉じるべき")
+	}
+}
+
+// TestPublishPacket は、Retain のフラグと、長い本文の残りの長さをテストします。
+func TestPublishPacket(t *testing.T) {
+	p := publishPacket(Message{Topic: "a", Payload: make([]byte, 200), Retain: true})
+	if p[0] != packetPublish<<4|0x01 {
+		t.Errorf("Retain のフラグが付くべき: %#x", p[0])
+	}
+	// 本文は 2 + 1 + 200 = 203 バイトなので、残りの長さは 2 バイト（0xcb 0x01）になるべき
+	if p[1] != 0xcb || p[2] != 0x01 || len(p) != 3+203 {
+		t.Errorf("残りの長さが一致しません: %#x %#x (%d バイト)", p[1], p[2], len(p))
+	}
+	if p := publishPacket(Message{Topic: "a"}); p[0] != packetPublish<<4 {
+		t.Errorf("Retain しない場合はフラグが付かないべき: %#x", p[0])
+	}
+}
@@ -0,0 +1,149 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DefaultDiscoveryPrefix は、Home Assistant の MQTT discovery のトピックの接頭辞の既定値です。
+const DefaultDiscoveryPrefix = "homeassistant"
+
+// Discovery は、Home Assistant の MQTT discovery の設定です。
+// ドメインごとに1つのデバイスを作り、IP アドレス・最後に更新した時刻・連続失敗回数のセンサーと、
+// 更新に失敗しているかどうかのバイナリセンサーを登録します。
+type Discovery struct {
+	// Prefix は、discovery のトピックの接頭辞です（空の場合は DefaultDiscoveryPrefix）
+	Prefix string
+
+	// Version は、デバイスのソフトウェアのバージョンとして表示するバージョンです
+	Version string
+}
+
+// discoveryDevice は、discovery の設定の device です。
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+	SWVersion    string   `json:"sw_version,omitempty"`
+}
+
+// discoveryConfig は、1つのエンティティの discovery の設定です。
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	StateClass        string          `json:"state_class,omitempty"`
+	EntityCategory    string          `json:"entity_category,omitempty"`
+	Icon              string          `json:"icon,omitempty"`
+	PayloadOn         string          `json:"payload_on,omitempty"`
+	PayloadOff        string          `json:"payload_off,omitempty"`
+	Device            discoveryDevice `json:"device"`
+}
+
+// discoveryEntity は、ドメインごとに登録するエンティティです。
+type discoveryEntity struct {
+	component string
+	object    string
+	config    discoveryConfig
+}
+
+// discoveryEntities は、ドメインごとに登録するエンティティの一覧です（トピックとデバイスは messages で設定します）。
+var discoveryEntities = []discoveryEntity{
+	{
+		component: "sensor",
+		object:    "ip",
+		config: discoveryConfig{
+			Name:          "IPアドレス",
+			ValueTemplate: "{{ value_json.ip }}",
+			Icon:          "mdi:ip-network",
+		},
+	},
+	{
+		component: "sensor",
+		object:    "last_update",
+		config: discoveryConfig{
+			Name: "最終更新",
+			// まだ更新していない場合は、"None" で「不明」にします
+			ValueTemplate: "{{ value_json.last_update | default(None) }}",
+			DeviceClass:   "timestamp",
+		},
+	},
+	{
+		component: "sensor",
+		object:    "consecutive_failures",
+		config: discoveryConfig{
+			Name:           "連続失敗回数",
+			ValueTemplate:  "{{ value_json.consecutive_failures }}",
+			StateClass:     "measurement",
+			EntityCategory: "diagnostic",
+			Icon:           "mdi:alert-circle-outline",
+		},
+	},
+	{
+		component: "binary_sensor",
+		object:    "problem",
+		config: discoveryConfig{
+			Name:          "更新の問題",
+			ValueTemplate: "{{ 'OFF' if value_json.healthy else 'ON' }}",
+			DeviceClass:   "problem",
+			PayloadOn:     "ON",
+			PayloadOff:    "OFF",
+		},
+	},
+}
+
+// messages は、ドメインのエンティティを登録する discovery のメッセージを返します。
+func (d *Discovery) messages(p *Publisher, domain string) []Message {
+	prefix := d.Prefix
+	if prefix == "" {
+		prefix = DefaultDiscoveryPrefix
+	}
+	nodeID := NodeID(domain)
+	device := discoveryDevice{
+		Identifiers:  []string{nodeID},
+		Name:         domain,
+		Manufacturer: "DuckDNS",
+		Model:        "duckdns",
+		SWVersion:    d.Version,
+	}
+
+	messages := make([]Message, 0, len(discoveryEntities))
+	for _, e := range discoveryEntities {
+		config := e.config
+		config.UniqueID = nodeID + "_" + e.object
+		config.StateTopic = p.StateTopic(domain)
+		config.AvailabilityTopic = p.AvailabilityTopic()
+		config.Device = device
+		payload, _ := json.Marshal(config)
+		messages = append(messages, Message{
+			Topic:   prefix + "/" + e.component + "/" + nodeID + "/" + e.object + "/config",
+			Payload: payload,
+			Retain:  true,
+		})
+	}
+	return messages
+}
+
+// NodeID は、discovery のトピックとエンティティの ID に使う、ドメインの ID（"duckdns_" + 英数字とアンダースコア）を返します。
+//
+// Parameters:
+//   - domain: ドメイン名
+//
+// Returns:
+//   - string: ドメインの ID
+func NodeID(domain string) string {
+	var b strings.Builder
+	b.WriteString("duckdns_")
+	for _, r := range strings.ToLower(domain) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
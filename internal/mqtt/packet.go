@@ -0,0 +1,152 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// パケットの種類（固定ヘッダーの上位4ビット）です。
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPingreq    = 12
+	packetPingresp   = 13
+	packetDisconnect = 14
+)
+
+// maxRemainingLength は、MQTT で送れる1つのパケットの最大の長さ（残りの長さ）です。
+const maxRemainingLength = 268435455
+
+// CONNECT の接続フラグです。
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// connackErrors は、CONNACK の戻りコードごとのエラーメッセージです。
+var connackErrors = map[byte]string{
+	1: "ブローカーが MQTT 3.1.1 に対応していません",
+	2: "クライアント ID が拒否されました",
+	3: "ブローカーが利用できません",
+	4: "ユーザー名またはパスワードが誤っています",
+	5: "接続が許可されていません",
+}
+
+// connectPacket は、CONNECT パケットを作成します。
+func connectPacket(opts Options, keepAlive uint16) []byte {
+	var body bytes.Buffer
+	writeString(&body, "MQTT")
+	body.WriteByte(4) // プロトコルレベル（3.1.1）
+
+	flags := byte(flagCleanSession)
+	if opts.Will != nil {
+		flags |= flagWill
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+	}
+	if opts.Password != "" {
+		flags |= flagPassword
+	}
+	body.WriteByte(flags)
+	binary.Write(&body, binary.BigEndian, keepAlive)
+
+	writeString(&body, opts.ClientID)
+	if opts.Will != nil {
+		writeString(&body, opts.Will.Topic)
+		writeBytes(&body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		writeString(&body, opts.Username)
+	}
+	if opts.Password != "" {
+		writeString(&body, opts.Password)
+	}
+	return packet(packetConnect<<4, body.Bytes())
+}
+
+// publishPacket は、QoS 0 の PUBLISH パケットを作成します。
+func publishPacket(msg Message) []byte {
+	var body bytes.Buffer
+	writeString(&body, msg.Topic)
+	body.Write(msg.Payload)
+
+	header := byte(packetPublish << 4)
+	if msg.Retain {
+		header |= 0x01
+	}
+	return packet(header, body.Bytes())
+}
+
+// packet は、固定ヘッダー（種類とフラグ、残りの長さ）を付けたパケットを作成します。
+func packet(header byte, body []byte) []byte {
+	out := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+// writeString は、2バイトの長さを付けた UTF-8 の文字列を書き込みます。
+func writeString(buf *bytes.Buffer, s string) {
+	writeBytes(buf, []byte(s))
+}
+
+// writeBytes は、2バイトの長さを付けたバイト列を書き込みます。
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	binary.Write(buf, binary.BigEndian, uint16(len(b)))
+	buf.Write(b)
+}
+
+// readPacket は、パケットを1つ読み込み、固定ヘッダーの1バイト目（上位4ビットが種類）と本体を返します。
+func readPacket(rd *bufio.Reader) (byte, []byte, error) {
+	header, err := rd.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("パケットの長さが不正です")
+		}
+		b, err := rd.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxRemainingLength {
+		return 0, nil, fmt.Errorf("パケットが長すぎます: %d バイト", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(rd, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
@@ -0,0 +1,283 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Publisher の既定値です。
+const (
+	// DefaultTopicPrefix は、TopicPrefix を省略した場合のトピックの接頭辞です
+	DefaultTopicPrefix = "duckdns"
+
+	// DefaultRetryInterval は、RetryInterval を省略した場合の、接続できなかったときに再接続するまでの間隔です
+	DefaultRetryInterval = 10 * time.Second
+)
+
+// 可用性のトピックに送る値です。
+const (
+	// PayloadOnline は、更新している間の値です
+	PayloadOnline = "online"
+
+	// PayloadOffline は、停止したとき（接続が切れたときはブローカーが代わりに送る）の値です
+	PayloadOffline = "offline"
+)
+
+// DomainState は、ドメインごとの状態のトピック（<prefix>/<domain>/state）に送る JSON です。
+type DomainState struct {
+	// Domain は、ドメイン名です
+	Domain string `json:"domain"`
+
+	// IP は、最後に取得した IP アドレスです（まだない場合は空）
+	IP string `json:"ip,omitempty"`
+
+	// Healthy は、最後のチェックが成功した場合に true です
+	Healthy bool `json:"healthy"`
+
+	// Error は、最後のチェックのエラーです（成功した場合は空）
+	Error string `json:"error,omitempty"`
+
+	// ConsecutiveFailures は、連続して失敗した回数です
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// LastCheck は、最後にチェックした時刻です
+	LastCheck *time.Time `json:"last_check,omitempty"`
+
+	// LastUpdate は、最後に更新に成功した時刻です
+	LastUpdate *time.Time `json:"last_update,omitempty"`
+}
+
+// Publisher は、チェックの結果をドメインごとの状態として MQTT ブローカーに送る Recorder です。
+// 状態のメッセージは Retain で送るので、後から購読したクライアントにも最新の状態が届きます。
+// 最初の結果を記録するまでは接続しないので、リーダー選出で待機しているインスタンスは接続しません。
+type Publisher struct {
+	// Options は、ブローカーへの接続の設定です（Will は Publisher が可用性のトピックに設定します）
+	Options Options
+
+	// TopicPrefix は、トピックの接頭辞です（空の場合は DefaultTopicPrefix）
+	TopicPrefix string
+
+	// Discovery は、Home Assistant の MQTT discovery の設定です（nil の場合は送りません）
+	Discovery *Discovery
+
+	// RetryInterval は、接続できなかったときに再接続するまでの間隔です（0 の場合は DefaultRetryInterval）
+	RetryInterval time.Duration
+
+	now func() time.Time
+
+	mu        sync.Mutex
+	domains   map[string]*DomainState
+	dirty     map[string]bool
+	announced map[string]bool
+	notify    chan struct{}
+}
+
+// NewPublisher は、Publisher を作成します。
+//
+// Parameters:
+//   - opts: ブローカーへの接続の設定
+//   - topicPrefix: トピックの接頭辞（空の場合は DefaultTopicPrefix）
+//   - discovery: Home Assistant の MQTT discovery の設定（nil の場合は送りません）
+//
+// Returns:
+//   - *Publisher: 作成された Publisher
+func NewPublisher(opts Options, topicPrefix string, discovery *Discovery) *Publisher {
+	if topicPrefix == "" {
+		topicPrefix = DefaultTopicPrefix
+	}
+	return &Publisher{
+		Options:     opts,
+		TopicPrefix: topicPrefix,
+		Discovery:   discovery,
+		now:         time.Now,
+		domains:     map[string]*DomainState{},
+		dirty:       map[string]bool{},
+		announced:   map[string]bool{},
+		notify:      make(chan struct{}, 1),
+	}
+}
+
+// AvailabilityTopic は、可用性のトピック（<prefix>/status）を返します。
+func (p *Publisher) AvailabilityTopic() string {
+	return p.TopicPrefix + "/status"
+}
+
+// StateTopic は、ドメインの状態のトピック（<prefix>/<domain>/state）を返します。
+func (p *Publisher) StateTopic(domain string) string {
+	return p.TopicPrefix + "/" + domain + "/state"
+}
+
+// Restore は、前回までに記録された IP アドレスと更新した時刻を、ドメインの状態として復元します。
+// 復元しただけでは接続しません。Run の前に呼び出してください。
+//
+// Parameters:
+//   - domain: ドメイン名
+//   - ip: 最後に登録した IP アドレス
+//   - lastUpdate: 最後に更新に成功した時刻（ない場合はゼロ値）
+func (p *Publisher) Restore(domain, ip string, lastUpdate time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.domain(domain)
+	st.IP = ip
+	st.Healthy = true
+	if !lastUpdate.IsZero() {
+		st.LastUpdate = &lastUpdate
+	}
+}
+
+// RecordResult は、1回のチェックの結果をドメインの状態に反映して、ブローカーに送ります。
+// スケジューラーの Recorder として使用します。送るのは Run のゴルーチンなので、ブロックしません。
+func (p *Publisher) RecordResult(domain, ip, source string, updated bool, checkErr error) {
+	p.mu.Lock()
+	now := p.now()
+	st := p.domain(domain)
+	st.LastCheck = &now
+	if checkErr != nil {
+		st.Healthy = false
+		st.Error = checkErr.Error()
+		st.ConsecutiveFailures++
+	} else {
+		st.Healthy = true
+		st.Error = ""
+		st.ConsecutiveFailures = 0
+		if ip != "" {
+			st.IP = ip
+		}
+		if updated {
+			st.LastUpdate = &now
+		}
+	}
+	p.dirty[domain] = true
+	p.mu.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// domain は、ドメインの状態を返します（ない場合は作成します）。mu を取得してから呼び出します。
+func (p *Publisher) domain(domain string) *DomainState {
+	st, ok := p.domains[domain]
+	if !ok {
+		st = &DomainState{Domain: domain}
+		p.domains[domain] = st
+	}
+	return st
+}
+
+// Run は、ctx がキャンセルされるまで、記録された状態をブローカーに送ります。
+// 接続が切れた場合は RetryInterval ごとに再接続し、つながったらすべてのドメインの状態を送り直します。
+// ctx がキャンセルされたら、可用性のトピックに "offline" を送ってから切断します。
+//
+// Parameters:
+//   - ctx: 停止するためのコンテキスト
+func (p *Publisher) Run(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-p.notify:
+	}
+
+	opts := p.Options
+	opts.Will = &Message{Topic: p.AvailabilityTopic(), Payload: []byte(PayloadOffline), Retain: true}
+	retry := p.RetryInterval
+	if retry <= 0 {
+		retry = DefaultRetryInterval
+	}
+
+	for {
+		conn, err := Dial(ctx, opts)
+		if err == nil {
+			slog.Info("MQTT ブローカーに接続しました", "broker", opts.Broker, "client_id", opts.ClientID)
+			err = p.serve(ctx, conn)
+			if ctx.Err() != nil {
+				conn.Publish(Message{Topic: p.AvailabilityTopic(), Payload: []byte(PayloadOffline), Retain: true})
+				conn.Close()
+				return
+			}
+			conn.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("MQTT ブローカーに送れないため、再接続します",
+			"broker", opts.Broker,
+			"retry_in", retry.String(),
+			"error", err,
+		)
+
+		timer := time.NewTimer(retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// serve は、接続が切れるか ctx がキャンセルされるまで、変わった状態を送ります。
+func (p *Publisher) serve(ctx context.Context, conn *Conn) error {
+	// つながるたびに、Home Assistant の設定とすべての状態を送り直します
+	p.mu.Lock()
+	p.announced = map[string]bool{}
+	for domain := range p.domains {
+		p.dirty[domain] = true
+	}
+	p.mu.Unlock()
+
+	if err := conn.Publish(Message{Topic: p.AvailabilityTopic(), Payload: []byte(PayloadOnline), Retain: true}); err != nil {
+		return err
+	}
+	for {
+		if err := p.flush(conn); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-conn.Done():
+			return conn.Err()
+		case <-p.notify:
+		}
+	}
+}
+
+// flush は、変わったドメインの状態（はじめてのドメインは Home Assistant の設定も）を送ります。
+// 送れなかったドメインは、再接続したときに送り直します。
+func (p *Publisher) flush(conn *Conn) error {
+	p.mu.Lock()
+	domains := make([]string, 0, len(p.dirty))
+	for domain := range p.dirty {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	var messages []Message
+	for _, domain := range domains {
+		if p.Discovery != nil && !p.announced[domain] {
+			messages = append(messages, p.Discovery.messages(p, domain)...)
+			p.announced[domain] = true
+		}
+		payload, _ := json.Marshal(p.domains[domain])
+		messages = append(messages, Message{Topic: p.StateTopic(domain), Payload: payload, Retain: true})
+	}
+	p.dirty = map[string]bool{}
+	p.mu.Unlock()
+
+	// 引き継いだリーダーが送った後に、前のリーダーの Will（offline）が届いても、次の状態で online に戻します
+	if len(messages) > 0 {
+		messages = append(messages, Message{Topic: p.AvailabilityTopic(), Payload: []byte(PayloadOnline), Retain: true})
+	}
+
+	for _, msg := range messages {
+		if err := conn.Publish(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
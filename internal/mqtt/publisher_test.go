@@ -0,0 +1,163 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// findMessage は、トピックが一致する最後のメッセージを返します。
+func findMessage(messages []Message, topic string) (Message, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Topic == topic {
+			return messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// TestPublisher は、結果をドメインの状態として送り、Home Assistant の設定と可用性も送ることをテストします。
+func TestPublisher(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	p := NewPublisher(Options{Broker: broker.url(), ClientID: "duckdns-test"}, "", &Discovery{Version: "1.2.3"})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	p.RecordResult("home", "192.0.2.1", "https://api.ipify.org", true, nil)
+	info := <-broker.connect
+	if info.will == nil || info.will.Topic != "duckdns/status" || string(info.will.Payload) != PayloadOffline || !info.will.Retain {
+		t.Errorf("Will は可用性のトピックの offline であるべき: %+v", info.will)
+	}
+	waitFor(t, func() bool {
+		_, ok := findMessage(broker.received(), "duckdns/home/state")
+		return ok
+	})
+
+	messages := broker.received()
+	state, _ := findMessage(messages, "duckdns/home/state")
+	var got DomainState
+	if err := json.Unmarshal(state.Payload, &got); err != nil {
+		t.Fatalf("状態の JSON を読み込めません: %v", err)
+	}
+	if got.IP != "192.0.2.1" || !got.Healthy || got.LastUpdate == nil || !got.LastUpdate.Equal(now) || !state.Retain {
+		t.Errorf("状態が一致しません: %s", state.Payload)
+	}
+	if online, ok := findMessage(messages, "duckdns/status"); !ok || string(online.Payload) != PayloadOnline || !online.Retain {
+		t.Errorf("可用性のトピックに online を送るべき: %+v", online)
+	}
+	for _, topic := range []string{
+		"homeassistant/sensor/duckdns_home/ip/config",
+		"homeassistant/sensor/duckdns_home/last_update/config",
+		"homeassistant/sensor/duckdns_home/consecutive_failures/config",
+		"homeassistant/binary_sensor/duckdns_home/problem/config",
+	} {
+		msg, ok := findMessage(messages, topic)
+		if !ok || !msg.Retain {
+			t.Errorf("Home Assistant の設定を Retain で送るべき: %s", topic)
+			continue
+		}
+		var config map[string]any
+		if err := json.Unmarshal(msg.Payload, &config); err != nil {
+			t.Fatalf("設定の JSON を読み込めません: %v", err)
+		}
+		if config["state_topic"] != "duckdns/home/state" || config["availability_topic"] != "duckdns/status" {
+			t.Errorf("設定のトピックが一致しません: %s", msg.Payload)
+		}
+		if device, _ := config["device"].(map[string]any); device["sw_version"] != "1.2.3" {
+			t.Errorf("デバイスのバージョンが一致しません: %s", msg.Payload)
+		}
+	}
+
+	// 2回目以降は、状態だけを送る
+	before := len(broker.received())
+	p.RecordResult("home", "", "", false, errors.New("fetch failed"))
+	waitFor(t, func() bool { return len(broker.received()) > before })
+	waitFor(t, func() bool {
+		state, _ := findMessage(broker.received(), "duckdns/home/state")
+		return strings.Contains(string(state.Payload), "fetch failed")
+	})
+	for _, msg := range broker.received()[before:] {
+		if strings.HasPrefix(msg.Topic, "homeassistant/") {
+			t.Errorf("同じ接続では設定を送り直さないべき: %s", msg.Topic)
+		}
+	}
+	state, _ = findMessage(broker.received(), "duckdns/home/state")
+	json.Unmarshal(state.Payload, &got)
+	if got.Healthy || got.ConsecutiveFailures != 1 || got.IP != "192.0.2.1" {
+		t.Errorf("失敗しても前回の IP アドレスを残すべき: %s", state.Payload)
+	}
+
+	cancel()
+	<-done
+	waitFor(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return broker.disconnected
+	})
+	if offline, _ := findMessage(broker.received(), "duckdns/status"); string(offline.Payload) != PayloadOffline {
+		t.Errorf("停止するときは offline を送るべき: %s", offline.Payload)
+	}
+}
+
+// TestPublisher_Lazy は、結果を記録するまでは接続しないことをテストします。
+func TestPublisher_Lazy(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	p := NewPublisher(Options{Broker: broker.url(), ClientID: "duckdns-test"}, "", nil)
+	p.Restore("home", "192.0.2.1", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	p.Run(ctx)
+
+	select {
+	case info := <-broker.connect:
+		t.Errorf("結果を記録するまでは接続しないべき: %+v", info)
+	default:
+	}
+}
+
+// TestPublisher_Reconnect は、接続が切れたら再接続して、すべての状態と設定を送り直すことをテストします。
+func TestPublisher_Reconnect(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	p := NewPublisher(Options{Broker: broker.url(), ClientID: "duckdns-test"}, "home-lab", &Discovery{Prefix: "ha"})
+	p.RetryInterval = 10 * time.Millisecond
+	p.Restore("restored", "192.0.2.9", time.Time{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	p.RecordResult("home", "192.0.2.1", "", true, nil)
+	<-broker.connect
+	waitFor(t, func() bool {
+		_, ok := findMessage(broker.received(), "home-lab/restored/state")
+		return ok
+	})
+
+	broker.closeConns()
+	<-broker.connect
+	before := len(broker.received())
+	waitFor(t, func() bool {
+		messages := broker.received()[before:]
+		_, state := findMessage(messages, "home-lab/home/state")
+		_, config := findMessage(messages, "ha/sensor/duckdns_home/ip/config")
+		return state && config
+	})
+}
+
+// TestNodeID は、ドメイン名を discovery の ID に使える文字にすることをテストします。
+func TestNodeID(t *testing.T) {
+	if got := NodeID("Home.Example-1.com"); got != "duckdns_home_example_1_com" {
+		t.Errorf("ID が一致しません: %s", got)
+	}
+}
@@ -0,0 +1,197 @@
+// Package netcheck は、インターネットにつながっているかどうかを軽く確認する機能を提供します。
+// 起動直後にネットワークの準備ができるまで待ったり、通信できない間に溜まった更新を送り直す時機を判断したりするのに使います。
+package netcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrOffline は、ネットワークにつながっていないことを表すエラーです。
+// Probe が返すエラーは、errors.Is で ErrOffline と判定できます。
+var ErrOffline = errors.New("ネットワークにつながっていません")
+
+// DefaultProbeURL は、接続の確認に使う URL を省略した場合の値です。
+const DefaultProbeURL = "https://www.duckdns.org/"
+
+// DefaultTimeout は、1回の接続の確認のタイムアウトの既定値です。
+const DefaultTimeout = 5 * time.Second
+
+// Wait の待ち時間の最初の値と上限です。
+const (
+	initialWaitInterval = 1 * time.Second
+	maxWaitInterval     = 30 * time.Second
+)
+
+// Prober は、URL に HEAD リクエストを送り、名前解決と接続ができるかどうかを確認します。
+// ステータスコードに関係なく応答があれば、つながっているとみなします。
+type Prober struct {
+	// URL は、接続の確認に使う URL です
+	URL string
+
+	// client は、確認に使う HTTP クライアントです
+	client *http.Client
+
+	// initialInterval と maxInterval は、Wait で確認を繰り返す待ち時間の最初の値と上限です
+	initialInterval time.Duration
+	maxInterval     time.Duration
+}
+
+// NewProber は、接続を確認する Prober を作成します。
+//
+// Parameters:
+//   - url: 接続の確認に使う URL（空の場合は DefaultProbeURL）
+//   - timeout: 1回の確認のタイムアウト（0 以下の場合は DefaultTimeout）
+//   - transport: 通信に使う Transport（nil の場合は http.DefaultTransport。プロキシや DNS の設定を反映するために渡します）
+//
+// Returns:
+//   - *Prober: 作成された Prober
+func NewProber(url string, timeout time.Duration, transport *http.Transport) *Prober {
+	if url == "" {
+		url = DefaultProbeURL
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if transport != nil {
+		client.Transport = transport
+	}
+	// リダイレクト先までは確認しない（応答があればつながっている）
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Prober{
+		URL:             url,
+		client:          client,
+		initialInterval: initialWaitInterval,
+		maxInterval:     maxWaitInterval,
+	}
+}
+
+// Probe は、1回だけ接続を確認します。
+//
+// Parameters:
+//   - ctx: キャンセルを制御するコンテキスト
+//
+// Returns:
+//   - error: 名前解決や接続に失敗した場合（応答があった場合は nil）
+func (p *Prober) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.URL, nil)
+	if err != nil {
+		return fmt.Errorf("リクエスト作成に失敗しました: %w", err)
+	}
+	req.Header.Set("User-Agent", "duckdns-updater/1.0")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: 接続を確認できません (URL: %s): %w", ErrOffline, p.URL, err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return nil
+}
+
+// Wait は、接続を確認できるまで、待ち時間を倍にしながら（最大30秒）確認を繰り返します。
+// maxWait を過ぎても確認できない場合は、最後のエラーを返します。
+//
+// Parameters:
+//   - ctx: キャンセルを制御するコンテキスト
+//   - maxWait: 待つ時間の上限（0 以下の場合は1回だけ確認します）
+//
+// Returns:
+//   - error: maxWait までに接続を確認できなかった場合、または ctx がキャンセルされた場合
+func (p *Prober) Wait(ctx context.Context, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	interval := p.initialInterval
+	for attempt := 1; ; attempt++ {
+		err := p.Probe(ctx)
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("ネットワークにつながりました", "url", p.URL, "attempts", attempt)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return err
+		}
+		slog.Info("ネットワークの準備ができるのを待っています",
+			"url", p.URL,
+			"attempt", attempt,
+			"retry_in", min(interval, remaining).String(),
+			"error", err,
+		)
+
+		timer := time.NewTimer(min(interval, remaining))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(interval*2, p.maxInterval)
+	}
+}
+
+// DNSProber は、ホスト名を名前解決できるかどうかで、ネットワークにつながっているかどうかを確認します。
+// HTTP の接続よりも軽く、DNS サーバーに届くかどうかだけを確認します。
+type DNSProber struct {
+	// Host は、名前解決するホスト名です
+	Host string
+
+	// resolver は、名前解決に使う Resolver です
+	resolver *net.Resolver
+
+	// timeout は、1回の確認のタイムアウトです
+	timeout time.Duration
+}
+
+// NewDNSProber は、名前解決で接続を確認する DNSProber を作成します。
+//
+// Parameters:
+//   - host: 名前解決するホスト名（空の場合は DefaultProbeURL のホスト名）
+//   - timeout: 1回の確認のタイムアウト（0 以下の場合は DefaultTimeout）
+//   - resolver: 名前解決に使う Resolver（nil の場合は net.DefaultResolver）
+//
+// Returns:
+//   - *DNSProber: 作成された DNSProber
+func NewDNSProber(host string, timeout time.Duration, resolver *net.Resolver) *DNSProber {
+	if host == "" {
+		host = "www.duckdns.org"
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSProber{Host: host, resolver: resolver, timeout: timeout}
+}
+
+// Probe は、1回だけ名前解決して接続を確認します。
+//
+// Parameters:
+//   - ctx: キャンセルを制御するコンテキスト
+//
+// Returns:
+//   - error: 名前解決に失敗した場合（ErrOffline と判定できます）
+func (p *DNSProber) Probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if _, err := p.resolver.LookupHost(ctx, p.Host); err != nil {
+		return fmt.Errorf("%w: 名前解決できません (ホスト: %s): %w", ErrOffline, p.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package netcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestProbe は、ステータスコードに関係なく応答があればつながっているとみなすことをテストします。
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("HEAD で確認するべき: %s", r.Method)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := NewProber(server.URL, time.Second, nil).Probe(context.Background()); err != nil {
+		t.Errorf("応答があればつながっているとみなすべき: %v", err)
+	}
+}
+
+// TestProbe_Unreachable は、接続できない場合にエラーになることをテストします。
+func TestProbe_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	if err := NewProber(url, time.Second, nil).Probe(context.Background()); !errors.Is(err, ErrOffline) {
+		t.Errorf("接続できない場合は ErrOffline になるべき: %v", err)
+	}
+}
+
+// TestNewProber_Defaults は、URL とタイムアウトを省略した場合の既定値をテストします。
+func TestNewProber_Defaults(t *testing.T) {
+	p := NewProber("", 0, nil)
+	if p.URL != DefaultProbeURL {
+		t.Errorf("URL が一致しません。期待: %s, 実際: %s", DefaultProbeURL, p.URL)
+	}
+	if p.client.Timeout != DefaultTimeout {
+		t.Errorf("タイムアウトが一致しません。期待: %v, 実際: %v", DefaultTimeout, p.client.Timeout)
+	}
+}
+
+// TestWait は、つながるまで確認を繰り返すことをテストします。
+func TestWait(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初の2回は接続を切って、つながっていない状態にする
+		if calls.Add(1) <= 2 {
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	p := NewProber(server.URL, time.Second, nil)
+	p.initialInterval, p.maxInterval = time.Millisecond, 5*time.Millisecond
+	if err := p.Wait(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("つながるまで待つべき: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("確認の回数が一致しません。期待: 3, 実際: %d", got)
+	}
+}
+
+// TestWait_GivesUp は、maxWait を過ぎたらあきらめることをテストします。
+func TestWait_GivesUp(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	p := NewProber(url, time.Second, nil)
+	p.initialInterval, p.maxInterval = time.Millisecond, 5*time.Millisecond
+	start := time.Now()
+	if err := p.Wait(context.Background(), 50*time.Millisecond); err == nil {
+		t.Error("つながらない場合はエラーになるべき")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("maxWait を過ぎたらすぐにあきらめるべき: %v", elapsed)
+	}
+}
+
+// TestWait_Canceled は、ctx がキャンセルされたら待つのをやめることをテストします。
+func TestWait_Canceled(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewProber(url, time.Second, nil).Wait(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("キャンセルされたら context.Canceled を返すべき: %v", err)
+	}
+}
+
+// TestDNSProber は、名前解決できるかどうかで接続を確認することをテストします。
+func TestDNSProber(t *testing.T) {
+	if err := NewDNSProber("localhost", time.Second, nil).Probe(context.Background()); err != nil {
+		t.Errorf("名前解決できる場合はつながっているとみなすべき: %v", err)
+	}
+
+	// DNS サーバーに届かない Resolver
+	unreachable := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("unreachable")
+		},
+	}
+	if err := NewDNSProber("www.duckdns.org", time.Second, unreachable).Probe(context.Background()); !errors.Is(err, ErrOffline) {
+		t.Errorf("名前解決できない場合は ErrOffline になるべき: %v", err)
+	}
+}
+
+// TestNewDNSProber_Defaults は、ホスト名を省略した場合の既定値をテストします。
+func TestNewDNSProber_Defaults(t *testing.T) {
+	p := NewDNSProber("", 0, nil)
+	if p.Host != "www.duckdns.org" || p.timeout != DefaultTimeout || p.resolver != net.DefaultResolver {
+		t.Errorf("既定値が一致しません: %+v", p)
+	}
+}
@@ -0,0 +1,114 @@
+// Package pidfile は、常駐しているプロセスの PID ファイルの作成と削除を提供します。
+// サービスマネージャーのない環境で、停止や二重起動の確認に使います。
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrRunning は、PID ファイルのプロセスがまだ動いていることを表すエラーです。
+var ErrRunning = errors.New("既に起動しています")
+
+// Write は、今のプロセスの PID を PID ファイルに書き込みます。
+// PID ファイルが既にあり、そのプロセスがまだ動いている場合は、二重に起動しないように ErrRunning を返します。
+// プロセスが終了している古い PID ファイルは、上書きします。
+//
+// Parameters:
+//   - path: PID ファイルのパス
+//
+// Returns:
+//   - error: 既に起動している場合（ErrRunning を含む）、または書き込みに失敗した場合
+func Write(path string) error {
+	if pid, ok := Running(path); ok && pid != os.Getpid() {
+		return fmt.Errorf("%w (PID: %d, PID ファイル: %s)", ErrRunning, pid, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("PID ファイルのディレクトリの作成に失敗しました: %w", err)
+	}
+	// 書きかけの PID ファイルを読まれないように、一時ファイルに書いてから置き換えます
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf("PID ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("PID ファイルの書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// Read は、PID ファイルから PID を読み込みます。
+//
+// Parameters:
+//   - path: PID ファイルのパス
+//
+// Returns:
+//   - int: PID
+//   - error: ファイルを読めない場合、または PID でない場合
+func Read(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("PID ファイルの内容が PID ではありません: %s", path)
+	}
+	return pid, nil
+}
+
+// Running は、PID ファイルのプロセスが動いているかどうかを返します。
+//
+// Parameters:
+//   - path: PID ファイルのパス
+//
+// Returns:
+//   - int: PID ファイルの PID（読めない場合は 0）
+//   - bool: プロセスが動いている場合は true
+func Running(path string) (int, bool) {
+	pid, err := Read(path)
+	if err != nil {
+		return 0, false
+	}
+	return pid, alive(pid)
+}
+
+// Remove は、PID ファイルが今のプロセスのものである場合に削除します。
+// ほかのプロセスが書き込み直した PID ファイルは削除しません。
+//
+// Parameters:
+//   - path: PID ファイルのパス
+//
+// Returns:
+//   - error: 削除に失敗した場合
+func Remove(path string) error {
+	pid, err := Read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if pid != os.Getpid() {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// alive は、PID のプロセスが動いているかどうかを返します。
+func alive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	// 権限がなくてシグナルを送れない場合も、プロセスは動いています
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,101 @@
+package pidfile
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestWrite は、今のプロセスの PID を書き込み、Remove で削除することをテストします。
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "duckdns.pid")
+	if err := Write(path); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+	pid, err := Read(path)
+	if err != nil || pid != os.Getpid() {
+		t.Errorf("今のプロセスの PID であるべき: %d %v", pid, err)
+	}
+
+	// 同じプロセスが書き込み直すのはエラーにしない
+	if err := Write(path); err != nil {
+		t.Errorf("同じプロセスなら書き込み直せるべき: %v", err)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatalf("削除に失敗しました: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PID ファイルが削除されていません: %v", err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("PID ファイルがない場合はエラーにしないべき: %v", err)
+	}
+}
+
+// TestWrite_Running は、ほかのプロセスが動いている場合は ErrRunning を返すことをテストします。
+func TestWrite_Running(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep を起動できません: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	path := filepath.Join(t.TempDir(), "duckdns.pid")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pid, ok := Running(path); !ok || pid != cmd.Process.Pid {
+		t.Errorf("動いているプロセスの PID を返すべき: %d %v", pid, ok)
+	}
+	if err := Write(path); !errors.Is(err, ErrRunning) {
+		t.Errorf("ほかのプロセスが動いている場合は ErrRunning を返すべき: %v", err)
+	}
+
+	// ほかのプロセスの PID ファイルは削除しない
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("ほかのプロセスの PID ファイルは残すべき: %v", err)
+	}
+}
+
+// TestWrite_Stale は、終了したプロセスの古い PID ファイルは上書きすることをテストします。
+func TestWrite_Stale(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("true を実行できません: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "duckdns.pid")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Running(path); ok {
+		t.Error("終了したプロセスは動いていないと判定するべき")
+	}
+	if err := Write(path); err != nil {
+		t.Errorf("古い PID ファイルは上書きするべき: %v", err)
+	}
+	if pid, _ := Read(path); pid != os.Getpid() {
+		t.Errorf("今のプロセスの PID に書き換えるべき: %d", pid)
+	}
+}
+
+// TestRead_Invalid は、PID でない内容をエラーにすることをテストします。
+func TestRead_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duckdns.pid")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Error("PID でない場合はエラーになるべき")
+	}
+}
@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// cloudflareBaseURL は、Cloudflare API v4 のエンドポイントです。
+const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare は、Cloudflare DNS の API で A / AAAA レコードを更新する Provider です。
+// レコードが存在しない場合は作成し、内容とプロキシの設定が同じ場合は何もしません。
+type Cloudflare struct {
+	httpClient *http.Client
+	baseURL    string
+	apiToken   string
+	zone       string
+
+	// proxied は、プロキシを有効にするかどうかです（nil の場合は既存のレコードの設定を変更しません）
+	proxied *bool
+
+	// ttl は、レコードの TTL です（0 の場合は作成時に自動、更新時は変更しません）
+	ttl int
+
+	// mu は、ゾーンIDのキャッシュを保護します
+	mu     sync.Mutex
+	zoneID string
+}
+
+// NewCloudflare は、cloudflare プロバイダーの設定から Provider を作成します。
+// 一時的な失敗を再試行する場合は、WithRetry で包んでください（New は自動で包みます）。
+//
+// Parameters:
+//   - cfg: cloudflare プロバイダーの設定（api_token, zone, zone_id, proxied, ttl）
+//
+// Returns:
+//   - *Cloudflare: 作成された Provider
+func NewCloudflare(cfg config.ProviderConfig) *Cloudflare {
+	return &Cloudflare{
+		httpClient: newHTTPClient(),
+		baseURL:    cloudflareBaseURL,
+		apiToken:   cfg.APIToken,
+		zone:       strings.TrimSuffix(strings.ToLower(strings.TrimSpace(cfg.Zone)), "."),
+		proxied:    cfg.Proxied,
+		ttl:        cfg.TTL,
+		zoneID:     strings.TrimSpace(cfg.ZoneID),
+	}
+}
+
+// Name は、プロバイダーの名前 "cloudflare" を返します。
+func (c *Cloudflare) Name() string {
+	return "cloudflare"
+}
+
+// cloudflareRecord は、Cloudflare の DNS レコードです。
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+	Proxied *bool  `json:"proxied,omitempty"`
+}
+
+// cloudflareResponse は、Cloudflare API の共通レスポンスです。
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Update は、ドメインの A（IPv6 の場合は AAAA）レコードを指定したIPアドレスに更新します。
+// domain がゾーン名で終わらない場合は、ゾーン内のレコード名（"home" → "home.example.com"）として扱います。
+func (c *Cloudflare) Update(ctx context.Context, domain, ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return fmt.Errorf("無効なIPアドレスです: %s", ip)
+	}
+	recordType := "A"
+	if parsed.To4() == nil {
+		recordType = "AAAA"
+	}
+	name := c.recordName(domain)
+
+	zoneID, err := c.lookupZoneID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 既存のレコードを検索
+	query := url.Values{}
+	query.Set("type", recordType)
+	query.Set("name", name)
+	var records []cloudflareRecord
+	if err := c.do(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &records); err != nil {
+		return fmt.Errorf("DNSレコードの取得に失敗しました (%s): %w", name, err)
+	}
+
+	if len(records) == 0 {
+		record := cloudflareRecord{Type: recordType, Name: name, Content: ip, TTL: 1, Proxied: c.proxied}
+		if c.ttl != 0 {
+			record.TTL = c.ttl
+		}
+		slog.Info("Cloudflare の DNS レコードを作成します", "name", name, "type", recordType, "ip", ip)
+		if err := c.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", record, nil); err != nil {
+			return fmt.Errorf("DNSレコードの作成に失敗しました (%s): %w", name, err)
+		}
+		return nil
+	}
+
+	existing := records[0]
+	proxiedChanged := c.proxied != nil && (existing.Proxied == nil || *existing.Proxied != *c.proxied)
+	ttlChanged := c.ttl != 0 && existing.TTL != c.ttl
+	if existing.Content == ip && !proxiedChanged && !ttlChanged {
+		slog.Debug("Cloudflare の DNS レコードは最新です", "name", name, "ip", ip)
+		return nil
+	}
+
+	slog.Info("Cloudflare の DNS レコードを更新します", "name", name, "type", recordType, "old_ip", existing.Content, "ip", ip)
+	patch := map[string]any{"content": ip}
+	if c.proxied != nil {
+		patch["proxied"] = *c.proxied
+	}
+	if c.ttl != 0 {
+		patch["ttl"] = c.ttl
+	}
+	if err := c.do(ctx, http.MethodPatch, "/zones/"+zoneID+"/dns_records/"+existing.ID, patch, nil); err != nil {
+		return fmt.Errorf("DNSレコードの更新に失敗しました (%s): %w", name, err)
+	}
+	return nil
+}
+
+// recordName は、ドメイン名をゾーン内の完全なレコード名にします。
+// zone_id だけを指定した場合は、ドメイン名を完全なレコード名として扱います。
+func (c *Cloudflare) recordName(domain string) string {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	switch {
+	case c.zone == "":
+		return name
+	case name == "@":
+		return c.zone
+	case name == c.zone || strings.HasSuffix(name, "."+c.zone):
+		return name
+	default:
+		return name + "." + c.zone
+	}
+}
+
+// lookupZoneID は、ゾーン名からゾーンIDを取得します（取得したIDはキャッシュします）
+func (c *Cloudflare) lookupZoneID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.zoneID != "" {
+		return c.zoneID, nil
+	}
+
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(c.zone), nil, &zones); err != nil {
+		return "", fmt.Errorf("ゾーンの取得に失敗しました (%s): %w", c.zone, err)
+	}
+	if len(zones) == 0 {
+		return "", rejected(fmt.Errorf("ゾーンが見つかりません: %s (API トークンの権限を確認してください)", c.zone))
+	}
+
+	c.zoneID = zones[0].ID
+	return c.zoneID, nil
+}
+
+// do は、Cloudflare API にリクエストを送信し、result を out にデコードします。
+// 認証エラーや 4xx で success=false の場合は、更新の拒否（ErrRejected）として扱います。
+// 5xx とレート制限（429）は一時的な失敗として扱い、WithRetry で再試行できるようにします。
+func (c *Cloudflare) do(ctx context.Context, method, path string, in, out any) error {
+	var body *bytes.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("リクエストの作成に失敗しました: %w", err)
+		}
+		body = bytes.NewReader(data)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("リクエスト作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiToken)
+	req.Header.Set("User-Agent", userAgent)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTPリクエスト実行に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+
+	var envelope cloudflareResponse
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("HTTPステータスエラー: %d", resp.StatusCode)
+		}
+		return fmt.Errorf("レスポンスの解析に失敗しました (ステータス: %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode >= 300 || !envelope.Success {
+		messages := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			messages = append(messages, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		apiErr := fmt.Errorf("Cloudflare API エラー (ステータス: %d): %s", resp.StatusCode, strings.Join(messages, "; "))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return apiErr
+		}
+		return rejected(apiErr)
+	}
+
+	if out != nil && len(envelope.Result) > 0 {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return fmt.Errorf("レスポンスの解析に失敗しました: %w", err)
+		}
+	}
+	return nil
+}
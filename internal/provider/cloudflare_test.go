@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// fakeCloudflare は、テスト用の Cloudflare API サーバーです。
+type fakeCloudflare struct {
+	mu          sync.Mutex
+	records     []cloudflareRecord
+	zoneLookups int
+	requests    []string
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer cf-token" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success":false,"errors":[{"code":9109,"message":"Invalid access token"}]}`))
+		return
+	}
+
+	reply := func(result any) {
+		data, _ := json.Marshal(result)
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "result": json.RawMessage(data)})
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/zones":
+		f.zoneLookups++
+		if r.URL.Query().Get("name") != "example.com" {
+			reply([]any{})
+			return
+		}
+		reply([]map[string]string{{"id": "zone-1"}})
+	case r.Method == http.MethodGet && r.URL.Path == "/zones/zone-1/dns_records":
+		var found []cloudflareRecord
+		for _, rec := range f.records {
+			if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+				found = append(found, rec)
+			}
+		}
+		reply(found)
+	case r.Method == http.MethodPost && r.URL.Path == "/zones/zone-1/dns_records":
+		var rec cloudflareRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = "rec-new"
+		f.records = append(f.records, rec)
+		reply(rec)
+	case r.Method == http.MethodPatch:
+		var patch struct {
+			Content string `json:"content"`
+			Proxied *bool  `json:"proxied"`
+			TTL     int    `json:"ttl"`
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		for i := range f.records {
+			if "/zones/zone-1/dns_records/"+f.records[i].ID == r.URL.Path {
+				f.records[i].Content = patch.Content
+				if patch.Proxied != nil {
+					f.records[i].Proxied = patch.Proxied
+				}
+				if patch.TTL != 0 {
+					f.records[i].TTL = patch.TTL
+				}
+				reply(f.records[i])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"success":false,"errors":[{"code":81044,"message":"Record not found"}]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"success":false,"errors":[]}`))
+	}
+}
+
+// newTestCloudflare は、テスト用サーバーに向けた Cloudflare の Provider を作成します。
+func newTestCloudflare(t *testing.T, fake *fakeCloudflare, cfg config.ProviderConfig) *Cloudflare {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	p := NewCloudflare(cfg)
+	p.baseURL = server.URL
+	return p
+}
+
+// TestCloudflare_UpdateExistingRecord は、既存のレコードの内容を更新することをテストします。
+func TestCloudflare_UpdateExistingRecord(t *testing.T) {
+	fake := &fakeCloudflare{records: []cloudflareRecord{
+		{ID: "rec-1", Type: "A", Name: "home.example.com", Content: "192.0.2.1"},
+	}}
+	p := newTestCloudflare(t, fake, config.ProviderConfig{APIToken: "cf-token", Zone: "example.com"})
+
+	if err := p.Update(context.Background(), "home", "192.0.2.2"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+	if fake.records[0].Content != "192.0.2.2" {
+		t.Errorf("レコードが更新されていません: %+v", fake.records[0])
+	}
+
+	// 同じIPアドレスでは PATCH しない。ゾーンIDはキャッシュされる
+	before := len(fake.requests)
+	if err := p.Update(context.Background(), "home.example.com", "192.0.2.2"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+	if got := fake.requests[before:]; len(got) != 1 || got[0] != "GET /zones/zone-1/dns_records" {
+		t.Errorf("内容が同じ場合は検索だけを行うべき: %v", got)
+	}
+	if fake.zoneLookups != 1 {
+		t.Errorf("ゾーンIDはキャッシュされるべき。検索回数: %d", fake.zoneLookups)
+	}
+}
+
+// TestCloudflare_CreateRecord は、レコードが存在しない場合に作成することをテストします。
+func TestCloudflare_CreateRecord(t *testing.T) {
+	fake := &fakeCloudflare{}
+	p := newTestCloudflare(t, fake, config.ProviderConfig{APIToken: "cf-token", Zone: "example.com"})
+
+	if err := p.Update(context.Background(), "vpn", "2001:db8::1"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+	if len(fake.records) != 1 {
+		t.Fatalf("レコードが作成されていません: %+v", fake.records)
+	}
+	if rec := fake.records[0]; rec.Type != "AAAA" || rec.Name != "vpn.example.com" || rec.Content != "2001:db8::1" {
+		t.Errorf("作成されたレコードが一致しません: %+v", rec)
+	}
+}
+
+// TestCloudflare_Rejected は、認証エラーとゾーンが見つからない場合に ErrRejected を返すことをテストします。
+func TestCloudflare_Rejected(t *testing.T) {
+	p := newTestCloudflare(t, &fakeCloudflare{}, config.ProviderConfig{APIToken: "wrong-token", Zone: "example.com"})
+	if err := p.Update(context.Background(), "home", "192.0.2.1"); !errors.Is(err, ErrRejected) {
+		t.Errorf("認証エラーは ErrRejected と判定されるべき: %v", err)
+	}
+
+	p = newTestCloudflare(t, &fakeCloudflare{}, config.ProviderConfig{APIToken: "cf-token", Zone: "other.example"})
+	if err := p.Update(context.Background(), "home", "192.0.2.1"); !errors.Is(err, ErrRejected) {
+		t.Errorf("ゾーンが見つからない場合は ErrRejected と判定されるべき: %v", err)
+	}
+}
+
+// TestCloudflare_ServerError は、5xx と 429 を拒否ではなく一時的な失敗として扱うことをテストします。
+func TestCloudflare_ServerError(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusTooManyRequests} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"try again"}]}`))
+		}))
+
+		p := NewCloudflare(config.ProviderConfig{APIToken: "cf-token", Zone: "example.com"})
+		p.baseURL = server.URL
+
+		err := p.Update(context.Background(), "home", "192.0.2.1")
+		if err == nil || errors.Is(err, ErrRejected) {
+			t.Errorf("%d は ErrRejected 以外のエラーになるべき: %v", status, err)
+		}
+		server.Close()
+	}
+}
+
+// TestCloudflare_ProxiedAndTTL は、proxied と ttl の設定がレコードに反映されることをテストします。
+func TestCloudflare_ProxiedAndTTL(t *testing.T) {
+	off, on := false, true
+	fake := &fakeCloudflare{records: []cloudflareRecord{
+		{ID: "rec-1", Type: "A", Name: "home.example.com", Content: "192.0.2.1", TTL: 1, Proxied: &off},
+	}}
+
+	// 省略した場合は既存の設定を変更しない
+	p := newTestCloudflare(t, fake, config.ProviderConfig{APIToken: "cf-token", Zone: "example.com"})
+	before := len(fake.requests)
+	if err := p.Update(context.Background(), "home", "192.0.2.1"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+	if got := fake.requests[before:]; len(got) != 2 {
+		t.Errorf("内容が同じ場合は PATCH しないべき: %v", got)
+	}
+
+	// IPアドレスが同じでもプロキシの設定が異なる場合は更新する
+	p = newTestCloudflare(t, fake, config.ProviderConfig{APIToken: "cf-token", Zone: "example.com", Proxied: &on, TTL: 300})
+	if err := p.Update(context.Background(), "home", "192.0.2.1"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+	if rec := fake.records[0]; rec.Proxied == nil || !*rec.Proxied || rec.TTL != 300 {
+		t.Errorf("proxied と ttl が反映されていません: %+v", rec)
+	}
+
+	// 新しく作成するレコードにも反映する
+	if err := p.Update(context.Background(), "vpn", "192.0.2.1"); err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	if rec := fake.records[1]; rec.Proxied == nil || !*rec.Proxied || rec.TTL != 300 {
+		t.Errorf("作成したレコードに proxied と ttl が反映されていません: %+v", rec)
+	}
+}
+
+// TestCloudflare_ZoneID は、zone_id を指定した場合にゾーンの検索を省略することをテストします。
+func TestCloudflare_ZoneID(t *testing.T) {
+	fake := &fakeCloudflare{}
+	p := newTestCloudflare(t, fake, config.ProviderConfig{APIToken: "cf-token", ZoneID: "zone-1"})
+
+	if err := p.Update(context.Background(), "home.example.com", "192.0.2.1"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+	if fake.zoneLookups != 0 {
+		t.Errorf("zone_id を指定した場合はゾーンを検索しないべき。検索回数: %d", fake.zoneLookups)
+	}
+	if len(fake.records) != 1 || fake.records[0].Name != "home.example.com" {
+		t.Errorf("レコードが作成されていません: %+v", fake.records)
+	}
+}
+
+// TestCloudflare_RecordName は、ドメイン名からレコード名への変換をテストします。
+func TestCloudflare_RecordName(t *testing.T) {
+	p := NewCloudflare(config.ProviderConfig{APIToken: "t", Zone: "Example.com."})
+	tests := map[string]string{
+		"home":              "home.example.com",
+		"home.example.com":  "home.example.com",
+		"HOME.example.com.": "home.example.com",
+		"@":                 "example.com",
+		"example.com":       "example.com",
+	}
+	for in, want := range tests {
+		if got := p.recordName(in); got != want {
+			t.Errorf("recordName(%q) = %q, 期待: %q", in, got, want)
+		}
+	}
+}
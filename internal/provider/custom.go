@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// Custom は、テンプレートで指定した URL にリクエストを送って更新する Provider です。
+// 専用の実装がない DDNS サービスでも、設定だけで更新できるようにします。
+type Custom struct {
+	httpClient   *http.Client
+	method       string
+	url          *template.Template
+	headers      map[string]*template.Template
+	body         *template.Template
+	successMatch *regexp.Regexp
+	username     string
+	password     string
+}
+
+// NewCustom は、custom プロバイダーの設定から Provider を作成します。
+//
+// Parameters:
+//   - cfg: custom プロバイダーの設定（url, method, headers, body, success_match）
+//
+// Returns:
+//   - *Custom: 作成された Provider
+//   - error: テンプレートまたは正規表現が無効な場合
+func NewCustom(cfg config.ProviderConfig) (*Custom, error) {
+	c := &Custom{
+		httpClient: newHTTPClient(),
+		method:     strings.ToUpper(cfg.Method),
+		headers:    make(map[string]*template.Template, len(cfg.Headers)),
+		username:   cfg.Username,
+		password:   cfg.Password,
+	}
+	if c.method == "" {
+		c.method = http.MethodGet
+	}
+
+	var err error
+	if c.url, err = config.ParseCustomTemplate("url", cfg.URL); err != nil {
+		return nil, fmt.Errorf("url のテンプレートが無効です: %w", err)
+	}
+	for name, value := range cfg.Headers {
+		if c.headers[name], err = config.ParseCustomTemplate("headers."+name, value); err != nil {
+			return nil, fmt.Errorf("ヘッダー %s のテンプレートが無効です: %w", name, err)
+		}
+	}
+	if cfg.Body != "" {
+		if c.body, err = config.ParseCustomTemplate("body", cfg.Body); err != nil {
+			return nil, fmt.Errorf("body のテンプレートが無効です: %w", err)
+		}
+	}
+	if cfg.SuccessMatch != "" {
+		if c.successMatch, err = regexp.Compile(cfg.SuccessMatch); err != nil {
+			return nil, fmt.Errorf("success_match の正規表現が無効です: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Name は、プロバイダーの名前 "custom" を返します。
+func (c *Custom) Name() string {
+	return "custom"
+}
+
+// Update は、テンプレートに値を埋め込んだリクエストを送信します。
+// 2xx のステータスコードで、success_match を指定した場合はレスポンスボディが一致したときに成功とみなします。
+// 4xx と success_match に一致しないレスポンスは更新の拒否、5xx は一時的な失敗として扱います。
+func (c *Custom) Update(ctx context.Context, domain, ip string) error {
+	data := config.CustomTemplateData{
+		Domain:   domain,
+		IP:       ip,
+		Type:     "A",
+		Username: c.username,
+		Password: c.password,
+	}
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		data.Type = "AAAA"
+	}
+
+	reqURL, err := render(c.url, data)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if c.body != nil {
+		rendered, err := render(c.body, data)
+		if err != nil {
+			return err
+		}
+		body = strings.NewReader(rendered)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method, reqURL, body)
+	if err != nil {
+		return fmt.Errorf("リクエスト作成に失敗しました: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	// ヘッダーは名前の順で設定する（Authorization を上書きする場合も結果が変わらないように）
+	names := make([]string, 0, len(c.headers))
+	for name := range c.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := render(c.headers[name], data)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	}
+
+	slog.Info("custom 更新リクエスト送信",
+		"domain", domain,
+		"ip", ip,
+		"method", c.method,
+		"host", req.URL.Host,
+	)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// URL のクエリにパスワードなどが含まれる場合があるため、URL を含めない
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("HTTPリクエスト実行に失敗しました (%s): %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+	response := strings.TrimSpace(string(respBody))
+
+	switch {
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("HTTPステータスエラー: %d", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return rejected(fmt.Errorf("custom の更新に失敗しました (ステータス: %d, レスポンス: %s)", resp.StatusCode, response))
+	case c.successMatch != nil && !c.successMatch.MatchString(response):
+		return rejected(fmt.Errorf("custom のレスポンスが success_match に一致しません: レスポンス=%s", response))
+	}
+
+	slog.Info("custom 更新成功",
+		"domain", domain,
+		"ip", ip,
+		"status_code", resp.StatusCode,
+	)
+	return nil
+}
+
+// render は、テンプレートに値を埋め込んだ文字列を返します。
+func render(tmpl *template.Template, data config.CustomTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("テンプレート %s の展開に失敗しました: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
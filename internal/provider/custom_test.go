@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// TestCustom_UpdateRequest は、テンプレートに値を埋め込んだリクエストが送信されることをテストします。
+func TestCustom_UpdateRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("メソッドが一致しません: %s", r.Method)
+		}
+		if r.URL.Path != "/update" || r.URL.Query().Get("host") != "home.example.com" || r.URL.Query().Get("ip") != "2001:db8::1" {
+			t.Errorf("URL が一致しません: %s", r.URL.String())
+		}
+		if got := r.Header.Get("X-Api-Key"); got != "key-secret" {
+			t.Errorf("ヘッダーが一致しません: %s", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"name":"home.example.com","type":"AAAA","content":"2001:db8::1"}` {
+			t.Errorf("ボディが一致しません: %s", body)
+		}
+		w.Write([]byte("status: updated"))
+	}))
+	defer server.Close()
+
+	p, err := NewCustom(config.ProviderConfig{
+		Type:         config.ProviderCustom,
+		URL:          server.URL + "/update?host={{.Domain}}&ip={{urlquery .IP}}",
+		Method:       "post",
+		Headers:      map[string]string{"X-Api-Key": "key-{{.Password}}"},
+		Body:         `{"name":"{{.Domain}}","type":"{{.Type}}","content":"{{.IP}}"}`,
+		SuccessMatch: "^status: (updated|unchanged)$",
+		Password:     "secret",
+	})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+
+	if err := p.Update(context.Background(), "home.example.com", "2001:db8::1"); err != nil {
+		t.Errorf("更新に失敗しました: %v", err)
+	}
+}
+
+// TestCustom_UpdateResponses は、ステータスコードと success_match による判定をテストします。
+func TestCustom_UpdateResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		successMatch string
+		wantErr      bool
+		wantRejected bool
+	}{
+		{name: "2xx で成功", status: http.StatusOK, body: "anything"},
+		{name: "success_match に一致", status: http.StatusOK, body: "OK", successMatch: "^OK$"},
+		{name: "success_match に一致しない", status: http.StatusOK, body: "ERROR", successMatch: "^OK$", wantErr: true, wantRejected: true},
+		{name: "4xx は拒否", status: http.StatusForbidden, body: "forbidden", wantErr: true, wantRejected: true},
+		{name: "5xx は一時的な失敗", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, pass, ok := r.BasicAuth()
+				if !ok || user != "user" || pass != "pass" {
+					t.Errorf("Basic 認証が一致しません: %s/%s", user, pass)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			p, err := NewCustom(config.ProviderConfig{
+				URL:          server.URL + "/?h={{.Domain}}",
+				SuccessMatch: tt.successMatch,
+				Username:     "user",
+				Password:     "pass",
+			})
+			if err != nil {
+				t.Fatalf("作成に失敗しました: %v", err)
+			}
+
+			err = p.Update(context.Background(), "home", "192.0.2.1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("エラーの有無が一致しません。期待: %v, 実際: %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrRejected) != tt.wantRejected {
+				t.Errorf("ErrRejected の判定が一致しません。期待: %v, 実際: %v", tt.wantRejected, err)
+			}
+		})
+	}
+}
+
+// TestNewCustom_Invalid は、無効なテンプレートや正規表現でエラーになることをテストします。
+func TestNewCustom_Invalid(t *testing.T) {
+	tests := map[string]config.ProviderConfig{
+		"存在しない値":  {URL: "https://example.com/?h={{.Domian}}"},
+		"構文エラー":   {URL: "https://example.com/?h={{.Domain"},
+		"無効な正規表現": {URL: "https://example.com/", SuccessMatch: "("},
+	}
+	for name, cfg := range tests {
+		if _, err := NewCustom(cfg); err == nil {
+			t.Errorf("%s: エラーになるべき", name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// DuckDNS は、DuckDNS のクライアントを Provider として使うためのアダプターです。
+// 同じトークンの複数のドメインは、UpdateBatch で1回のリクエストにまとめて更新できます。
+// UpdateDualStack では、ip と ipv6 を1回のリクエストで送信します。Clear でレコードを消去することもできます。
+// UpdateAutoDetect では、ip を送らずに、DuckDNS がリクエストの送信元から検出したアドレスを登録します。
+type DuckDNS struct {
+	// Client は、DuckDNS API クライアントです
+	Client *duckdns.Client
+
+	// Token は、DuckDNS API トークンです
+	Token string
+}
+
+// NewDuckDNS は、DuckDNS のクライアントとトークンから Provider を作成します。
+//
+// Parameters:
+//   - client: DuckDNS API クライアント（複数のドメインで共有できます）
+//   - token: DuckDNS API トークン
+//
+// Returns:
+//   - *DuckDNS: 作成された Provider
+func NewDuckDNS(client *duckdns.Client, token string) *DuckDNS {
+	return &DuckDNS{Client: client, Token: token}
+}
+
+// Name は、プロバイダーの名前 "duckdns" を返します。
+func (d *DuckDNS) Name() string {
+	return "duckdns"
+}
+
+// Update は、DuckDNS API でドメインのIPアドレスを更新します。
+// "KO" が返された場合は ErrRejected として扱います。
+func (d *DuckDNS) Update(ctx context.Context, domain, ip string) error {
+	return d.update(ctx, []string{domain}, ip, "")
+}
+
+// UpdateBatch は、カンマ区切りの domains= で複数のドメインを1回のリクエストで更新します。
+// DuckDNS はどれか1つでも更新できないと "KO" を返し、どのドメインが原因かは分からないため、
+// "KO" の場合はドメインごとに更新し直して、原因のドメインだけを失敗にします。
+func (d *DuckDNS) UpdateBatch(ctx context.Context, domains []string, ip string) []error {
+	return d.updateDomains(ctx, domains, ip, "")
+}
+
+// UpdateDualStack は、ip と ipv6 を1回のリクエストで送信して A と AAAA を同時に更新します。
+// 複数のドメインは UpdateBatch と同じように1回のリクエストにまとめます。
+func (d *DuckDNS) UpdateDualStack(ctx context.Context, domains []string, ipv4, ipv6 string) []error {
+	return d.updateDomains(ctx, domains, ipv4, ipv6)
+}
+
+// UpdateAutoDetect は、ip を送らずに verbose=true で更新し、DuckDNS がリクエストの送信元から検出して登録したアドレスを返します。
+// 複数のドメインは UpdateBatch と同じように1回のリクエストにまとめます。
+func (d *DuckDNS) UpdateAutoDetect(ctx context.Context, domains []string, ipv6 string) (DetectedIP, []error) {
+	var detected DetectedIP
+	errs := d.splitRejected(domains, func(domains []string) error {
+		body, err := d.Client.Update(ctx, duckdns.UpdateRequest{Domains: domains, Token: d.Token, IPv6: ipv6, Verbose: true})
+		if err != nil {
+			if errors.Is(err, duckdns.ErrRejected) {
+				return rejected(err)
+			}
+			return err
+		}
+		resp, err := duckdns.ParseVerboseResponse(body)
+		if err != nil {
+			return err
+		}
+		detected.IPv4, detected.IPv6 = resp.IPv4, resp.IPv6
+		detected.Changed = detected.Changed || resp.Changed
+		return nil
+	})
+	return detected, errs
+}
+
+// Clear は、DuckDNS API の clear=true で、複数のドメインの A と AAAA のレコードを1回のリクエストで消去します。
+// "KO" が返された場合は ErrRejected として扱います。
+func (d *DuckDNS) Clear(ctx context.Context, domains []string) error {
+	_, err := d.Client.Clear(ctx, domains, d.Token)
+	if errors.Is(err, duckdns.ErrRejected) {
+		return rejected(err)
+	}
+	return err
+}
+
+// updateDomains は、複数のドメインを1回のリクエストで更新します（ipv6 が空の場合は IPv4 のみ）
+// "KO" の場合は、ドメインごとに更新し直します。
+func (d *DuckDNS) updateDomains(ctx context.Context, domains []string, ipv4, ipv6 string) []error {
+	return d.splitRejected(domains, func(domains []string) error {
+		return d.update(ctx, domains, ipv4, ipv6)
+	})
+}
+
+// splitRejected は、update で複数のドメインを1回のリクエストで更新し、"KO" の場合はドメインごとに update し直します。
+func (d *DuckDNS) splitRejected(domains []string, update func(domains []string) error) []error {
+	errs := make([]error, len(domains))
+	if len(domains) == 1 {
+		errs[0] = update(domains)
+		return errs
+	}
+
+	err := update(domains)
+	if err == nil {
+		return errs
+	}
+	if !errors.Is(err, ErrRejected) {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	slog.Warn("まとめた更新が拒否されたため、ドメインごとに更新し直します",
+		"domains", domains,
+		"error", err,
+	)
+	for i, domain := range domains {
+		errs[i] = update([]string{domain})
+	}
+	return errs
+}
+
+// update は、DuckDNS API に1回の更新リクエストを送信します。
+func (d *DuckDNS) update(ctx context.Context, domains []string, ipv4, ipv6 string) error {
+	_, err := d.Client.Update(ctx, duckdns.UpdateRequest{Domains: domains, Token: d.Token, IPv4: ipv4, IPv6: ipv6})
+	if errors.Is(err, duckdns.ErrRejected) {
+		return rejected(err)
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 各プロバイダーの dyndns2 互換の更新エンドポイント
+const (
+	noIPUpdateURL = "https://dynupdate.no-ip.com/nic/update"
+	dynuUpdateURL = "https://api.dynu.com/nic/update"
+)
+
+// dyndns2ServerErrorHold は、サーバー側のエラー（911, dnserr）を受け取った後に更新を控える時間です。
+// dyndns2 の仕様では、少なくとも 30 分は再試行しないことが求められています。
+const dyndns2ServerErrorHold = 30 * time.Minute
+
+// dyndns2Fatal は、同じ内容で再試行しても成功しない（繰り返すとブロックされる）応答コードと説明です。
+// これらを受け取ったホスト名は、設定を再読み込みする（Provider を作り直す）まで更新を停止します。
+var dyndns2Fatal = map[string]string{
+	"badauth":  "ユーザー名またはパスワードが正しくありません",
+	"!donator": "このアカウントでは利用できない機能が指定されました",
+	"notfqdn":  "ホスト名が完全修飾ドメイン名ではありません",
+	"nohost":   "ホスト名がこのアカウントに存在しません",
+	"numhost":  "一度に更新できるホスト名の数を超えています",
+	"abuse":    "更新の繰り返しによりホスト名がブロックされています",
+	"badagent": "User-Agent またはリクエストがサーバーに拒否されました",
+}
+
+// DynDNS2 は、dyndns2 プロトコル（/nic/update）で更新する Provider です。
+// DynDNS や No-IP、Dynu など、dyndns2 互換の API を持つプロバイダーで共通して使用します。
+//
+// 応答コードは次のように扱います。
+//   - good, nochg: 成功
+//   - 911, dnserr: サーバー側の一時的な失敗（30 分間は更新を控えます）
+//   - badauth, abuse, nohost など: 更新の拒否（そのホスト名は再読み込みまで更新を停止します）
+type DynDNS2 struct {
+	httpClient *http.Client
+	name       string
+	serverURL  string
+	username   string
+	password   string
+
+	// now は、現在時刻を返します（テストで差し替えます）
+	now func() time.Time
+
+	// mu は、blocked と holdUntil を保護します
+	mu sync.Mutex
+
+	// blocked は、致命的な応答コードを受け取ったホスト名と応答コードです
+	blocked map[string]string
+
+	// holdUntil は、サーバー側のエラーで更新を控える期限です
+	holdUntil time.Time
+}
+
+// NewNoIP は、No-IP のユーザー名とパスワードから Provider を作成します。
+//
+// Parameters:
+//   - username: No-IP のユーザー名（または DDNS キーのユーザー名）
+//   - password: No-IP のパスワード（または DDNS キーのパスワード）
+//
+// Returns:
+//   - *DynDNS2: 作成された Provider
+func NewNoIP(username, password string) *DynDNS2 {
+	return NewDynDNS2("noip", noIPUpdateURL, username, password)
+}
+
+// NewDynu は、Dynu のユーザー名とパスワードから Provider を作成します。
+//
+// Parameters:
+//   - username: Dynu のユーザー名
+//   - password: Dynu のパスワード（または IP 更新用パスワード）
+//
+// Returns:
+//   - *DynDNS2: 作成された Provider
+func NewDynu(username, password string) *DynDNS2 {
+	return NewDynDNS2("dynu", dynuUpdateURL, username, password)
+}
+
+// NewDynDNS2 は、dyndns2 互換の更新サーバーを使う Provider を作成します。
+//
+// Parameters:
+//   - name: ログに出すプロバイダーの名前（例: "dyndns2"）
+//   - server: 更新サーバーのホスト名（"members.dyndns.org"）または更新 URL
+//   - username: ユーザー名
+//   - password: パスワード（または更新用のトークン）
+//
+// Returns:
+//   - *DynDNS2: 作成された Provider
+func NewDynDNS2(name, server, username, password string) *DynDNS2 {
+	return &DynDNS2{
+		httpClient: newHTTPClient(),
+		name:       name,
+		serverURL:  dyndns2ServerURL(server),
+		username:   username,
+		password:   password,
+		now:        time.Now,
+		blocked:    make(map[string]string),
+	}
+}
+
+// dyndns2ServerURL は、ホスト名だけの server を https://<host>/nic/update の形にします。
+// パスのない URL には /nic/update を付けます。
+func dyndns2ServerURL(server string) string {
+	server = strings.TrimSpace(server)
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	if u, err := url.Parse(server); err == nil && (u.Path == "" || u.Path == "/") {
+		u.Path = "/nic/update"
+		return u.String()
+	}
+	return server
+}
+
+// Name は、プロバイダーの名前（"noip", "dynu", "dyndns2" など）を返します。
+func (d *DynDNS2) Name() string {
+	return d.name
+}
+
+// Update は、dyndns2 プロトコルでホスト名のIPアドレスを更新します。
+func (d *DynDNS2) Update(ctx context.Context, domain, ip string) error {
+	if err := d.checkBlocked(domain); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("hostname", domain)
+	params.Set("myip", ip)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.serverURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("リクエスト作成に失敗しました: %w", err)
+	}
+	req.SetBasicAuth(d.username, d.password)
+	req.Header.Set("User-Agent", userAgent)
+
+	slog.Info("dyndns2 更新リクエスト送信",
+		"provider", d.name,
+		"domain", domain,
+		"ip", ip,
+		"url", d.serverURL,
+	)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTPリクエスト実行に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+	response := strings.TrimSpace(string(body))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return d.block(domain, "badauth", response)
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("HTTPステータスエラー: %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return rejected(fmt.Errorf("%s の更新に失敗しました (ステータス: %d, レスポンス: %s)", d.name, resp.StatusCode, response))
+	}
+
+	// 複数ホストの場合は行ごとに結果が返るため、1行目で判定する
+	line, _, _ := strings.Cut(response, "\n")
+	code, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	switch code {
+	case "good":
+		slog.Info("dyndns2 更新成功",
+			"provider", d.name,
+			"domain", domain,
+			"ip", ip,
+			"response", response,
+		)
+		return nil
+	case "nochg":
+		// nochg を繰り返すと abuse と判断されるサーバーがあるため、気付けるように警告する
+		slog.Warn("dyndns2 サーバーにはすでに同じIPアドレスが登録されています",
+			"provider", d.name,
+			"domain", domain,
+			"ip", ip,
+			"response", response,
+		)
+		return nil
+	case "911", "dnserr":
+		d.mu.Lock()
+		d.holdUntil = d.now().Add(dyndns2ServerErrorHold)
+		d.mu.Unlock()
+		return fmt.Errorf("%s のサーバーで一時的なエラーが発生しました（%s 間は更新を控えます）: %s", d.name, dyndns2ServerErrorHold, response)
+	}
+
+	if _, fatal := dyndns2Fatal[code]; fatal {
+		return d.block(domain, code, response)
+	}
+	return rejected(fmt.Errorf("%s が更新を拒否しました: レスポンス=%s", d.name, response))
+}
+
+// checkBlocked は、ホスト名の更新を停止している場合や、更新を控えている期間の場合にエラーを返します。
+// 停止中にリクエストを繰り返して、アカウントがブロックされないようにします。
+func (d *DynDNS2) checkBlocked(domain string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if code, ok := d.blocked[domain]; ok {
+		return rejected(fmt.Errorf("%s が %s を返したため、%s の更新を停止しています（設定を見直して再読み込みしてください）", d.name, code, domain))
+	}
+	if now := d.now(); now.Before(d.holdUntil) {
+		return fmt.Errorf("%s のサーバーエラーのため、%s まで更新を控えています", d.name, d.holdUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// block は、ホスト名の更新を停止し、更新の拒否を表すエラーを返します。
+func (d *DynDNS2) block(domain, code, response string) error {
+	d.mu.Lock()
+	d.blocked[domain] = code
+	d.mu.Unlock()
+
+	slog.Error("dyndns2 サーバーが更新を拒否したため、このホスト名の更新を停止します",
+		"provider", d.name,
+		"domain", domain,
+		"code", code,
+		"reason", dyndns2Fatal[code],
+	)
+	return rejected(fmt.Errorf("%s が更新を拒否しました (%s: %s): レスポンス=%s", d.name, code, dyndns2Fatal[code], response))
+}
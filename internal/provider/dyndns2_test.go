@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// newTestDynDNS2 は、テスト用サーバーに向けた dyndns2 の Provider を作成します。
+func newTestDynDNS2(t *testing.T, handler http.HandlerFunc) *DynDNS2 {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := NewNoIP("user", "pass")
+	p.serverURL = server.URL + "/nic/update"
+	return p
+}
+
+// TestDynDNS2_UpdateRequest は、dyndns2 のリクエストの内容をテストします。
+func TestDynDNS2_UpdateRequest(t *testing.T) {
+	p := newTestDynDNS2(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nic/update" {
+			t.Errorf("パスが一致しません: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("hostname"); got != "myhost.ddns.net" {
+			t.Errorf("hostname が一致しません: %s", got)
+		}
+		if got := r.URL.Query().Get("myip"); got != "192.0.2.1" {
+			t.Errorf("myip が一致しません: %s", got)
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			t.Errorf("Basic 認証が一致しません: %s/%s", user, pass)
+		}
+		if !strings.HasPrefix(r.UserAgent(), "duckdns-updater/") {
+			t.Errorf("User-Agent が一致しません: %s", r.UserAgent())
+		}
+		w.Write([]byte("good 192.0.2.1\n"))
+	})
+
+	if err := p.Update(context.Background(), "myhost.ddns.net", "192.0.2.1"); err != nil {
+		t.Errorf("更新に失敗しました: %v", err)
+	}
+}
+
+// TestDynDNS2_UpdateResponses は、dyndns2 の応答コードの扱いをテストします。
+func TestDynDNS2_UpdateResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantErr      bool
+		wantRejected bool
+	}{
+		{name: "good", status: http.StatusOK, body: "good 192.0.2.1"},
+		{name: "nochg", status: http.StatusOK, body: "nochg 192.0.2.1"},
+		{name: "badauth", status: http.StatusOK, body: "badauth", wantErr: true, wantRejected: true},
+		{name: "nohost", status: http.StatusOK, body: "nohost", wantErr: true, wantRejected: true},
+		{name: "notfqdn", status: http.StatusOK, body: "notfqdn", wantErr: true, wantRejected: true},
+		{name: "!donator", status: http.StatusOK, body: "!donator", wantErr: true, wantRejected: true},
+		{name: "abuse", status: http.StatusOK, body: "abuse", wantErr: true, wantRejected: true},
+		{name: "不明な応答", status: http.StatusOK, body: "whatever", wantErr: true, wantRejected: true},
+		{name: "911", status: http.StatusOK, body: "911", wantErr: true},
+		{name: "dnserr", status: http.StatusOK, body: "dnserr", wantErr: true},
+		{name: "401", status: http.StatusUnauthorized, body: "", wantErr: true, wantRejected: true},
+		{name: "503", status: http.StatusServiceUnavailable, body: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestDynDNS2(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			err := p.Update(context.Background(), "myhost.ddns.net", "192.0.2.1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("エラーの有無が一致しません。期待: %v, 実際: %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrRejected) != tt.wantRejected {
+				t.Errorf("ErrRejected の判定が一致しません。期待: %v, 実際: %v", tt.wantRejected, err)
+			}
+		})
+	}
+}
+
+// TestNewDynu は、Dynu の Provider が Dynu のエンドポイントを使うことをテストします。
+func TestNewDynu(t *testing.T) {
+	p := NewDynu("user", "pass")
+	if p.Name() != "dynu" || p.serverURL != dynuUpdateURL {
+		t.Errorf("Dynu の Provider が一致しません: %s %s", p.Name(), p.serverURL)
+	}
+}
+
+// TestDynDNS2_BlockAfterFatalResponse は、badauth や abuse を受け取ったホスト名の更新を
+// リクエストを送らずに停止し、ほかのホスト名は更新を続けることをテストします。
+func TestDynDNS2_BlockAfterFatalResponse(t *testing.T) {
+	var requests atomic.Int32
+	p := newTestDynDNS2(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("hostname") == "abused.ddns.net" {
+			w.Write([]byte("abuse"))
+			return
+		}
+		w.Write([]byte("good 192.0.2.1"))
+	})
+
+	for i := 0; i < 3; i++ {
+		err := p.Update(context.Background(), "abused.ddns.net", "192.0.2.1")
+		if !errors.Is(err, ErrRejected) {
+			t.Fatalf("[%d] ErrRejected が返されるべき: %v", i, err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("停止後はリクエストを送らないべき。リクエスト数: %d", got)
+	}
+
+	if err := p.Update(context.Background(), "other.ddns.net", "192.0.2.1"); err != nil {
+		t.Errorf("ほかのホスト名は更新できるべき: %v", err)
+	}
+}
+
+// TestDynDNS2_HoldAfterServerError は、911 を受け取った後の 30 分間は更新を控えることをテストします。
+func TestDynDNS2_HoldAfterServerError(t *testing.T) {
+	var requests atomic.Int32
+	response := "911"
+	p := newTestDynDNS2(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(response))
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	err := p.Update(context.Background(), "myhost.ddns.net", "192.0.2.1")
+	if err == nil || errors.Is(err, ErrRejected) {
+		t.Fatalf("911 は一時的な失敗になるべき: %v", err)
+	}
+
+	response = "good 192.0.2.1"
+	now = now.Add(29 * time.Minute)
+	if err := p.Update(context.Background(), "myhost.ddns.net", "192.0.2.1"); err == nil {
+		t.Error("30 分以内は更新を控えるべき")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("更新を控えている間はリクエストを送らないべき。リクエスト数: %d", got)
+	}
+
+	now = now.Add(time.Minute)
+	if err := p.Update(context.Background(), "myhost.ddns.net", "192.0.2.1"); err != nil {
+		t.Errorf("30 分後は更新できるべき: %v", err)
+	}
+}
+
+// TestDynDNS2ServerURL は、server の指定から更新 URL への変換をテストします。
+func TestDynDNS2ServerURL(t *testing.T) {
+	tests := map[string]string{
+		"members.dyndns.org":                   "https://members.dyndns.org/nic/update",
+		"ddns.example.com:8443":                "https://ddns.example.com:8443/nic/update",
+		"http://192.168.1.1":                   "http://192.168.1.1/nic/update",
+		"https://ddns.example.com/custom/path": "https://ddns.example.com/custom/path",
+	}
+	for in, want := range tests {
+		if got := dyndns2ServerURL(in); got != want {
+			t.Errorf("dyndns2ServerURL(%q) = %q, 期待: %q", in, got, want)
+		}
+	}
+}
+
+// TestNew_DynDNS2Server は、設定の server が更新サーバーとして使われることをテストします。
+func TestNew_DynDNS2Server(t *testing.T) {
+	p, err := New(config.ProviderConfig{Type: config.ProviderDynDNS2, Server: "members.dyndns.org", Username: "u", Password: "p"})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	if d := p.(*DynDNS2); d.Name() != "dyndns2" || d.serverURL != "https://members.dyndns.org/nic/update" {
+		t.Errorf("dyndns2 の Provider が一致しません: %s %s", d.Name(), d.serverURL)
+	}
+
+	p, err = New(config.ProviderConfig{Type: config.ProviderNoIP, Server: "https://noip.example.com/nic/update", Username: "u", Password: "p"})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	if d := p.(*DynDNS2); d.serverURL != "https://noip.example.com/nic/update" {
+		t.Errorf("noip の server が置き換えられていません: %s", d.serverURL)
+	}
+}
@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// DefaultExecTimeout は、exec プラグインの実行のデフォルトタイムアウトです。
+const DefaultExecTimeout = 30 * time.Second
+
+// execMaxOutputSize は、プラグインの標準出力と標準エラー出力それぞれから読み込む最大サイズです。
+// HTTP のレスポンスボディと同じ上限にして、誤動作したプラグインがデーモンのメモリを使い切らないようにします。
+const execMaxOutputSize = httpclient.DefaultMaxResponseSize
+
+// ExecProtocolVersion は、exec プラグインとやり取りする JSON の形式のバージョンです。
+// 互換性のない変更をする場合に上げます。
+const ExecProtocolVersion = 1
+
+// exec プラグインが返す status の値
+const (
+	// ExecStatusOK は、更新に成功したことを表します
+	ExecStatusOK = "ok"
+
+	// ExecStatusUnchanged は、すでに同じIPアドレスが登録されていたことを表します（成功として扱います）
+	ExecStatusUnchanged = "unchanged"
+
+	// ExecStatusRejected は、認証情報の誤りなどでプロバイダーが更新を拒否したことを表します
+	ExecStatusRejected = "rejected"
+
+	// ExecStatusError は、一時的な失敗（接続エラーなど）を表します
+	ExecStatusError = "error"
+)
+
+// ExecRequest は、exec プラグインの標準入力に渡す JSON です。
+type ExecRequest struct {
+	// Version は、JSON の形式のバージョン（ExecProtocolVersion）です
+	Version int `json:"version"`
+
+	// Action は、実行する操作です（現在は "update" だけです）
+	Action string `json:"action"`
+
+	// Domain は、更新するドメイン名です
+	Domain string `json:"domain"`
+
+	// IP は、登録するIPアドレスです
+	IP string `json:"ip"`
+
+	// Type は、IPアドレスの種類に合ったレコードの種類です（"A" または "AAAA"）
+	Type string `json:"type"`
+
+	// Options は、providers[].options の値です
+	Options map[string]string `json:"options"`
+}
+
+// ExecResponse は、exec プラグインが標準出力に返す JSON です。
+type ExecResponse struct {
+	// Status は、結果です（ok, unchanged, rejected, error）
+	Status string `json:"status"`
+
+	// Message は、ログやエラーメッセージに出す説明です（任意）
+	Message string `json:"message,omitempty"`
+}
+
+// Exec は、外部コマンド（プラグイン）に JSON を渡して更新する Provider です。
+// 専用の実装がないプロバイダーを、本体を変更せずに追加できるようにします。
+//
+// プラグインは標準入力から ExecRequest を読み、標準出力に ExecResponse を1つ書き込みます。
+// 標準エラーはログに出力します。
+type Exec struct {
+	command []string
+	options map[string]string
+	timeout time.Duration
+}
+
+// NewExec は、exec プロバイダーの設定から Provider を作成します。
+//
+// Parameters:
+//   - cfg: exec プロバイダーの設定（command, options, timeout）
+//
+// Returns:
+//   - *Exec: 作成された Provider
+//   - error: command が空の場合
+func NewExec(cfg config.ProviderConfig) (*Exec, error) {
+	if len(cfg.Command) == 0 || strings.TrimSpace(cfg.Command[0]) == "" {
+		return nil, errors.New("exec プロバイダーの command が設定されていません")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	return &Exec{
+		command: append([]string(nil), cfg.Command...),
+		options: cfg.Options,
+		timeout: timeout,
+	}, nil
+}
+
+// Name は、"exec:" にプラグインのファイル名を付けた名前を返します（例: "exec:gandi"）
+func (e *Exec) Name() string {
+	return "exec:" + filepath.Base(e.command[0])
+}
+
+// Update は、プラグインを実行してドメインのIPアドレスを更新します。
+func (e *Exec) Update(ctx context.Context, domain, ip string) error {
+	req := ExecRequest{
+		Version: ExecProtocolVersion,
+		Action:  "update",
+		Domain:  domain,
+		IP:      ip,
+		Type:    "A",
+		Options: e.options,
+	}
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		req.Type = "AAAA"
+	}
+	if req.Options == nil {
+		req.Options = map[string]string{}
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("プラグインへの入力の作成に失敗しました: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{limit: execMaxOutputSize}
+	stderr := &limitedBuffer{limit: execMaxOutputSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// プラグインが起動した子プロセスが出力を開いたままでも、タイムアウト後に待ち続けないようにする
+	cmd.WaitDelay = time.Second
+
+	slog.Info("プラグインを実行します",
+		"provider", e.Name(),
+		"domain", domain,
+		"ip", ip,
+	)
+
+	runErr := cmd.Run()
+	if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+		slog.Info("プラグインの出力", "provider", e.Name(), "stderr", msg, "truncated", stderr.exceeded)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("プラグイン %s の実行が中断されました: %w", e.Name(), ctx.Err())
+	}
+	// 途中で切り捨てた出力は解析せず、大きすぎることをエラーにします
+	if stdout.exceeded {
+		return fmt.Errorf("プラグイン %s の出力が大きすぎます: %w", e.Name(), &httpclient.ResponseTooLargeError{Limit: stdout.limit})
+	}
+
+	var resp ExecResponse
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.buf.Bytes()), &resp); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("プラグイン %s の実行に失敗しました: %w", e.Name(), runErr)
+		}
+		return fmt.Errorf("プラグイン %s の出力を解析できません: %w", e.Name(), err)
+	}
+
+	switch resp.Status {
+	case ExecStatusOK, ExecStatusUnchanged:
+		if runErr != nil {
+			return fmt.Errorf("プラグイン %s が status=%s を返しましたが、終了コードが失敗を示しています: %w", e.Name(), resp.Status, runErr)
+		}
+		slog.Info("プラグインによる更新に成功しました",
+			"provider", e.Name(),
+			"domain", domain,
+			"ip", ip,
+			"status", resp.Status,
+		)
+		return nil
+	case ExecStatusRejected:
+		return rejected(fmt.Errorf("プラグイン %s が更新を拒否しました: %s", e.Name(), resp.Message))
+	case ExecStatusError:
+		return fmt.Errorf("プラグイン %s の更新に失敗しました: %s", e.Name(), resp.Message)
+	default:
+		return fmt.Errorf("プラグイン %s が不明な status を返しました: %q", e.Name(), resp.Status)
+	}
+}
+
+// limitedBuffer は、limit バイトまでを保持し、超えた分は捨てて exceeded に記録する io.Writer です。
+// 超えても書き込みは成功として扱い、プラグインがパイプの詰まりで止まったままにならないようにします。
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+}
+
+// Write は、limit を超えない分だけを保持します。
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - int64(b.buf.Len())
+	if int64(len(p)) <= room {
+		return b.buf.Write(p)
+	}
+	b.exceeded = true
+	if room > 0 {
+		b.buf.Write(p[:room])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// writePlugin は、テスト用のプラグイン（シェルスクリプト）を作成します。
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("シェルスクリプトのプラグインは Windows では実行できません")
+	}
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("プラグインの作成に失敗: %v", err)
+	}
+	return path
+}
+
+// TestExec_UpdateRequest は、プラグインに渡す JSON の内容をテストします。
+func TestExec_UpdateRequest(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "input.json")
+	plugin := writePlugin(t, `cat > "$1"
+echo "updating" >&2
+echo '{"status":"ok","message":"updated"}'
+`)
+
+	p, err := NewExec(config.ProviderConfig{
+		Command: []string{plugin, inputFile},
+		Options: map[string]string{"api_key": "secret"},
+	})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	if p.Name() != "exec:plugin.sh" {
+		t.Errorf("プロバイダー名が一致しません: %s", p.Name())
+	}
+
+	if err := p.Update(context.Background(), "home.example.com", "2001:db8::1"); err != nil {
+		t.Fatalf("更新に失敗しました: %v", err)
+	}
+
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		t.Fatalf("入力の読み込みに失敗: %v", err)
+	}
+	var req ExecRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("入力の解析に失敗: %v (%s)", err, data)
+	}
+	if req.Version != ExecProtocolVersion || req.Action != "update" || req.Domain != "home.example.com" ||
+		req.IP != "2001:db8::1" || req.Type != "AAAA" || req.Options["api_key"] != "secret" {
+		t.Errorf("入力が一致しません: %+v", req)
+	}
+}
+
+// TestExec_UpdateResponses は、プラグインの出力と終了コードの扱いをテストします。
+func TestExec_UpdateResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		wantErr      bool
+		wantRejected bool
+		wantMessage  string
+	}{
+		{name: "ok", script: `echo '{"status":"ok"}'`},
+		{name: "unchanged", script: `echo '{"status":"unchanged"}'`},
+		{name: "rejected", script: `echo '{"status":"rejected","message":"bad api key"}'; exit 1`, wantErr: true, wantRejected: true, wantMessage: "bad api key"},
+		{name: "error", script: `echo '{"status":"error","message":"timeout"}'`, wantErr: true, wantMessage: "timeout"},
+		{name: "不明な status", script: `echo '{"status":"maybe"}'`, wantErr: true, wantMessage: "maybe"},
+		{name: "JSON 以外の出力", script: `echo 'done'`, wantErr: true},
+		{name: "出力なしで失敗", script: `exit 3`, wantErr: true, wantMessage: "exit status 3"},
+		{name: "ok だが失敗の終了コード", script: `echo '{"status":"ok"}'; exit 1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewExec(config.ProviderConfig{Command: []string{writePlugin(t, tt.script)}})
+			if err != nil {
+				t.Fatalf("作成に失敗しました: %v", err)
+			}
+
+			err = p.Update(context.Background(), "home", "192.0.2.1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("エラーの有無が一致しません。期待: %v, 実際: %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrRejected) != tt.wantRejected {
+				t.Errorf("ErrRejected の判定が一致しません。期待: %v, 実際: %v", tt.wantRejected, err)
+			}
+			if tt.wantMessage != "" && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("エラーメッセージに %q が含まれていません: %v", tt.wantMessage, err)
+			}
+		})
+	}
+}
+
+// TestExec_OutputTooLarge は、プラグインの出力が上限を超えた場合に、切り捨てた出力を解析せずにエラーにすることをテストします。
+func TestExec_OutputTooLarge(t *testing.T) {
+	p, err := NewExec(config.ProviderConfig{Command: []string{writePlugin(t, `printf '{"status":"ok","message":"'
+head -c 100000 /dev/zero | tr '\0' a
+printf '"}'
+`)}})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+
+	err = p.Update(context.Background(), "home", "192.0.2.1")
+	if !errors.Is(err, httpclient.ErrResponseTooLarge) {
+		t.Errorf("出力が上限を超えたら ErrResponseTooLarge を返すべき: %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "出力が大きすぎます") {
+		t.Errorf("エラーメッセージで出力が大きすぎることを知らせるべき: %v", err)
+	}
+}
+
+// TestExec_StderrTooLarge は、標準エラー出力が上限を超えても、切り捨てて更新を続けることをテストします。
+func TestExec_StderrTooLarge(t *testing.T) {
+	p, err := NewExec(config.ProviderConfig{Command: []string{writePlugin(t, `head -c 100000 /dev/zero | tr '\0' a >&2
+echo '{"status":"ok"}'
+`)}})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+
+	if err := p.Update(context.Background(), "home", "192.0.2.1"); err != nil {
+		t.Errorf("標準エラー出力が大きくても、標準出力の結果で更新するべき: %v", err)
+	}
+}
+
+// TestExec_Timeout は、タイムアウトでプラグインの実行が中断されることをテストします。
+func TestExec_Timeout(t *testing.T) {
+	p, err := NewExec(config.ProviderConfig{
+		Command: []string{writePlugin(t, "exec sleep 5\n")},
+		Timeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+
+	start := time.Now()
+	err = p.Update(context.Background(), "home", "192.0.2.1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("タイムアウトのエラーが返されるべき: %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("タイムアウトで中断されていません: %v", time.Since(start))
+	}
+}
+
+// TestNewExec_Invalid は、command が空の場合にエラーになることをテストします。
+func TestNewExec_Invalid(t *testing.T) {
+	if _, err := NewExec(config.ProviderConfig{}); err == nil {
+		t.Error("command が空の場合はエラーになるべき")
+	}
+}
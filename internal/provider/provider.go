@@ -0,0 +1,213 @@
+// Package provider は、DNS レコードを更新する DDNS プロバイダーの共通インターフェースを提供します。
+// DuckDNS に加えて Cloudflare・No-IP・Dynu・dyndns2 互換サーバーと、テンプレートで指定する custom、
+// 外部コマンドで更新する exec（プラグイン）に対応し、設定で選んだプロバイダーを
+// スケジューラーから同じように扱えるようにします。
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// DefaultHTTPTimeout は、プロバイダーの API リクエストのデフォルトタイムアウトです。
+const DefaultHTTPTimeout = 10 * time.Second
+
+// newHTTPClient は、プロバイダーの API リクエストに使う HTTP クライアントを作成します。
+// network の通信設定を反映した、プロセス全体で共有する Transport で送信します。
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: DefaultHTTPTimeout, Transport: httpclient.SharedRoundTripper}
+}
+
+// userAgent は、プロバイダーの API に送る User-Agent です。
+const userAgent = "duckdns-updater/1.0"
+
+// maxResponseSize は、読み込むレスポンスボディの最大サイズです。
+const maxResponseSize = 1 << 20
+
+// ErrRejected は、プロバイダーが更新を拒否したことを表すエラーです。
+// 認証情報やドメイン名の誤りが原因のため、接続エラーと区別するために errors.Is で判定できます。
+var ErrRejected = errors.New("更新を拒否されました")
+
+// Provider は、DNS レコードを更新する DDNS プロバイダーのインターフェースです。
+type Provider interface {
+	// Name は、ログに出すプロバイダーの名前を返します（例: "duckdns", "cloudflare"）
+	Name() string
+
+	// Update は、ドメインのレコードを指定したIPアドレスに更新します。
+	//
+	// Parameters:
+	//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+	//   - domain: 更新するドメイン名
+	//   - ip: 登録するIPアドレス
+	//
+	// Returns:
+	//   - error: 更新に失敗した場合（拒否された場合は ErrRejected を含む）
+	Update(ctx context.Context, domain, ip string) error
+}
+
+// BatchUpdater は、複数のドメインを1回のリクエストでまとめて更新できる Provider です。
+// スケジューラーは、同じ BatchUpdater で更新するドメインを UpdateBatch でまとめて更新します。
+type BatchUpdater interface {
+	Provider
+
+	// UpdateBatch は、複数のドメインのレコードを指定したIPアドレスにまとめて更新します。
+	//
+	// Parameters:
+	//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+	//   - domains: 更新するドメイン名の一覧
+	//   - ip: 登録するIPアドレス
+	//
+	// Returns:
+	//   - []error: domains と同じ順番のドメインごとの結果（成功した場合は nil）
+	UpdateBatch(ctx context.Context, domains []string, ip string) []error
+}
+
+// DualStackUpdater は、IPv4 と IPv6 のアドレスを1回のリクエストで同時に更新できる Provider です。
+// スケジューラーは、IPv6 も更新する場合にこのインターフェースで A と AAAA をまとめて更新し、
+// 対応していない Provider は種類ごとに Update を呼び出します。
+type DualStackUpdater interface {
+	Provider
+
+	// UpdateDualStack は、複数のドメインの A と AAAA レコードを同時に更新します。
+	//
+	// Parameters:
+	//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+	//   - domains: 更新するドメイン名の一覧
+	//   - ipv4: 登録するIPv4アドレス
+	//   - ipv6: 登録するIPv6アドレス
+	//
+	// Returns:
+	//   - []error: domains と同じ順番のドメインごとの結果（成功した場合は nil）
+	UpdateDualStack(ctx context.Context, domains []string, ipv4, ipv6 string) []error
+}
+
+// DetectedIP は、プロバイダーがリクエストの送信元から検出して登録したIPアドレスです。
+type DetectedIP struct {
+	// IPv4 は、A レコードに登録された IPv4アドレスです
+	IPv4 string
+
+	// IPv6 は、AAAA レコードに登録されている IPv6アドレスです（ない場合は空）
+	IPv6 string
+
+	// Changed は、この更新でレコードが変わった場合に true です
+	Changed bool
+}
+
+// AutoDetector は、IPアドレスを送らずに、プロバイダーがリクエストの送信元から検出したアドレスを登録できる Provider です。
+// スケジューラーは、自動検出のモード（SetAutoDetect）で IP取得ソースに問い合わせずにこのインターフェースで更新し、
+// 返されたIPアドレスを登録済みのアドレスとして記録します。
+type AutoDetector interface {
+	Provider
+
+	// UpdateAutoDetect は、IPアドレスを送らずに複数のドメインを更新し、登録されたIPアドレスを返します。
+	//
+	// Parameters:
+	//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+	//   - domains: 更新するドメイン名の一覧
+	//   - ipv6: 登録するIPv6アドレス（空の場合は AAAA レコードを変えません）
+	//
+	// Returns:
+	//   - DetectedIP: プロバイダーが登録したIPアドレス（すべてのドメインが失敗した場合はゼロ値）
+	//   - []error: domains と同じ順番のドメインごとの結果（成功した場合は nil）
+	UpdateAutoDetect(ctx context.Context, domains []string, ipv6 string) (DetectedIP, []error)
+}
+
+// Clearer は、ドメインのレコードを消去できる Provider です。
+// 停止するときの処理（update.on_shutdown の clear）で、使わなくなる IPアドレスを指したままにしないために使います。
+type Clearer interface {
+	Provider
+
+	// Clear は、複数のドメインのレコードを消去します。
+	//
+	// Parameters:
+	//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+	//   - domains: 消去するドメイン名の一覧
+	//
+	// Returns:
+	//   - error: 消去に失敗した場合（拒否された場合は ErrRejected を含む）
+	Clear(ctx context.Context, domains []string) error
+}
+
+// New は、プロバイダーの設定から Provider を作成します。
+//
+// Parameters:
+//   - cfg: プロバイダーの設定
+//
+// Returns:
+//   - Provider: 作成された Provider
+//   - error: 種類が不明な場合、または設定が無効な場合
+func New(cfg config.ProviderConfig) (Provider, error) {
+	return NewWithRetry(cfg, duckdns.RetryConfig{})
+}
+
+// NewWithRetry は、New と同様に Provider を作成し、一時的な失敗を retry の設定で再試行します。
+//
+// Parameters:
+//   - cfg: プロバイダーの設定
+//   - retry: 再試行する Provider（Cloudflare）の最大リトライ回数とバックオフ（ゼロ値の場合は既定値）
+//
+// Returns:
+//   - Provider: 作成された Provider
+//   - error: 種類が不明な場合、または設定が無効な場合
+func NewWithRetry(cfg config.ProviderConfig, retry duckdns.RetryConfig) (Provider, error) {
+	switch cfg.Type {
+	case config.ProviderCloudflare:
+		return WithRetry(NewCloudflare(cfg), retry), nil
+	case config.ProviderNoIP:
+		return withServer(NewNoIP(cfg.Username, cfg.Password), cfg.Server), nil
+	case config.ProviderDynu:
+		return withServer(NewDynu(cfg.Username, cfg.Password), cfg.Server), nil
+	case config.ProviderDynDNS2:
+		return NewDynDNS2(config.ProviderDynDNS2, cfg.Server, cfg.Username, cfg.Password), nil
+	case config.ProviderCustom:
+		return NewCustom(cfg)
+	case config.ProviderExec:
+		return NewExec(cfg)
+	default:
+		return nil, fmt.Errorf("不明なプロバイダーです: %s", cfg.Type)
+	}
+}
+
+// withServer は、server が指定されている場合に dyndns2 の更新サーバーを置き換えます。
+func withServer(d *DynDNS2, server string) *DynDNS2 {
+	if strings.TrimSpace(server) != "" {
+		d.serverURL = dyndns2ServerURL(server)
+	}
+	return d
+}
+
+// rejectedError は、プロバイダーが更新を拒否したことを表すエラーです。
+// メッセージは元のエラーのまま、errors.Is で ErrRejected と判定できるようにします。
+type rejectedError struct {
+	err error
+}
+
+func (e *rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectedError) Unwrap() []error {
+	return []error{ErrRejected, e.err}
+}
+
+// rejected は、err を更新の拒否として扱うエラーにします。
+func rejected(err error) error {
+	return &rejectedError{err: err}
+}
+
+// readBody は、レスポンスボディを上限付きで読み込みます。
+func readBody(resp *http.Response) ([]byte, error) {
+	body, err := httpclient.ReadBody(resp.Body, maxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("レスポンス読み込みに失敗しました: %w", err)
+	}
+	return body, nil
+}
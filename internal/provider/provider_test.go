@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/config"
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// TestNew は、設定の種類に合った Provider が作成されることをテストします。
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg      config.ProviderConfig
+		wantName string
+	}{
+		{config.ProviderConfig{Type: config.ProviderCloudflare, APIToken: "t", Zone: "example.com"}, "cloudflare"},
+		{config.ProviderConfig{Type: config.ProviderNoIP, Username: "u", Password: "p"}, "noip"},
+		{config.ProviderConfig{Type: config.ProviderDynu, Username: "u", Password: "p"}, "dynu"},
+	}
+
+	for _, tt := range tests {
+		p, err := New(tt.cfg)
+		if err != nil {
+			t.Fatalf("%s の作成に失敗しました: %v", tt.cfg.Type, err)
+		}
+		if p.Name() != tt.wantName {
+			t.Errorf("プロバイダー名が一致しません。期待: %s, 実際: %s", tt.wantName, p.Name())
+		}
+	}
+
+	if _, err := New(config.ProviderConfig{Type: "unknown"}); err == nil {
+		t.Error("不明な種類ではエラーになるべき")
+	}
+}
+
+// TestRejected は、拒否のエラーが元のメッセージのまま ErrRejected と判定できることをテストします。
+func TestRejected(t *testing.T) {
+	base := errors.New("badauth")
+	err := rejected(base)
+
+	if !errors.Is(err, ErrRejected) {
+		t.Error("ErrRejected と判定されるべき")
+	}
+	if !errors.Is(err, base) {
+		t.Error("元のエラーと判定されるべき")
+	}
+	if err.Error() != "badauth" {
+		t.Errorf("メッセージが一致しません: %s", err.Error())
+	}
+}
+
+// TestDuckDNS_Update は、DuckDNS のアダプターが "KO" を ErrRejected として扱うことをテストします。
+func TestDuckDNS_Update(t *testing.T) {
+	response := "OK"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "test-token" {
+			t.Errorf("トークンが一致しません: %s", r.URL.Query().Get("token"))
+		}
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	p := NewDuckDNS(client, "test-token")
+
+	if p.Name() != "duckdns" {
+		t.Errorf("プロバイダー名が一致しません: %s", p.Name())
+	}
+	if err := p.Update(context.Background(), "test-domain", "192.0.2.1"); err != nil {
+		t.Errorf("更新に失敗しました: %v", err)
+	}
+
+	response = "KO"
+	err := p.Update(context.Background(), "test-domain", "192.0.2.1")
+	if !errors.Is(err, ErrRejected) || !errors.Is(err, duckdns.ErrRejected) {
+		t.Errorf("KO は ErrRejected と判定されるべき: %v", err)
+	}
+}
+
+// TestDuckDNS_UpdateBatch は、複数のドメインを1回のリクエストで更新し、
+// "KO" の場合はドメインごとに更新し直して原因のドメインだけを失敗にすることをテストします。
+func TestDuckDNS_UpdateBatch(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		domains := r.URL.Query().Get("domains")
+		requests = append(requests, domains)
+		if strings.Contains(domains, "bad") {
+			w.Write([]byte("KO"))
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	p := NewDuckDNS(client, "test-token")
+
+	errs := p.UpdateBatch(context.Background(), []string{"a", "b"}, "192.0.2.1")
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("更新に失敗しました: %v", errs)
+	}
+	if len(requests) != 1 || requests[0] != "a,b" {
+		t.Errorf("1回のリクエストにまとめるべき: %v", requests)
+	}
+
+	requests = nil
+	errs = p.UpdateBatch(context.Background(), []string{"a", "bad"}, "192.0.2.1")
+	if errs[0] != nil {
+		t.Errorf("原因ではないドメインは成功するべき: %v", errs[0])
+	}
+	if !errors.Is(errs[1], ErrRejected) {
+		t.Errorf("原因のドメインは ErrRejected と判定されるべき: %v", errs[1])
+	}
+	if strings.Join(requests, " ") != "a,bad a bad" {
+		t.Errorf("KO の場合はドメインごとに更新し直すべき: %v", requests)
+	}
+}
+
+// TestDuckDNS_UpdateDualStack は、A と AAAA を1回のリクエストで同時に更新することをテストします。
+func TestDuckDNS_UpdateDualStack(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("domains")+" "+r.URL.Query().Get("ip")+" "+r.URL.Query().Get("ipv6"))
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	p := NewDuckDNS(client, "test-token")
+
+	errs := p.UpdateDualStack(context.Background(), []string{"a", "b"}, "192.0.2.1", "2001:db8::1")
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("更新に失敗しました: %v", errs)
+	}
+	if len(queries) != 1 || queries[0] != "a,b 192.0.2.1 2001:db8::1" {
+		t.Errorf("ip と ipv6 を1回のリクエストで送信するべき: %v", queries)
+	}
+}
+
+// TestDuckDNS_UpdateAutoDetect は、ip を送らずに verbose=true で更新し、DuckDNS が登録したアドレスを返すことをテストします。
+func TestDuckDNS_UpdateAutoDetect(t *testing.T) {
+	response := "OK\n198.51.100.7\n2001:db8::1\nUPDATED"
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		queries = append(queries, q.Get("domains")+" "+q.Get("ip")+" "+q.Get("ipv6")+" "+q.Get("verbose"))
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	p := NewDuckDNS(client, "test-token")
+
+	detected, errs := p.UpdateAutoDetect(context.Background(), []string{"a", "b"}, "2001:db8::1")
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("更新に失敗しました: %v", errs)
+	}
+	if len(queries) != 1 || queries[0] != "a,b  2001:db8::1 true" {
+		t.Errorf("ip を送らずに verbose=true で1回のリクエストにまとめるべき: %v", queries)
+	}
+	if detected != (DetectedIP{IPv4: "198.51.100.7", IPv6: "2001:db8::1", Changed: true}) {
+		t.Errorf("DuckDNS が登録したアドレスを返すべき: %+v", detected)
+	}
+
+	response = "OK"
+	if _, errs := p.UpdateAutoDetect(context.Background(), []string{"a"}, ""); errs[0] == nil {
+		t.Error("詳しい応答ではない場合はエラーになるべき")
+	}
+}
+
+// TestDuckDNS_Clear は、clear=true で複数のドメインのレコードを1回のリクエストで消去することをテストします。
+func TestDuckDNS_Clear(t *testing.T) {
+	response := "OK"
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("domains")+" "+r.URL.Query().Get("clear"))
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	var p Clearer = NewDuckDNS(client, "test-token")
+
+	if err := p.Clear(context.Background(), []string{"a", "b"}); err != nil {
+		t.Errorf("消去に失敗しました: %v", err)
+	}
+	if len(queries) != 1 || queries[0] != "a,b true" {
+		t.Errorf("clear=true を1回のリクエストで送信するべき: %v", queries)
+	}
+
+	response = "KO"
+	if err := p.Clear(context.Background(), []string{"a"}); !errors.Is(err, ErrRejected) {
+		t.Errorf("KO の場合は ErrRejected を返すべき: %v", err)
+	}
+}
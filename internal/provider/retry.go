@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// Retrying は、一時的な失敗の場合に DuckDNS クライアントと同じバックオフで更新を再試行する Provider です。
+// 更新の拒否（ErrRejected）は再試行しても成功しないため、すぐに返します。
+// Retry-After で待ち時間を指定された場合は、バックオフ時間の代わりにその時間だけ待ちます。
+type Retrying struct {
+	provider Provider
+	retry    duckdns.RetryConfig
+}
+
+// WithRetry は、Provider を再試行付きの Provider で包みます。
+// retry がゼロ値の場合は、DuckDNS クライアントの既定値（最大3回、1s/2s/4s）を使用します。
+//
+// Parameters:
+//   - p: 包む Provider
+//   - retry: 最大リトライ回数とバックオフ時間
+//
+// Returns:
+//   - *Retrying: 再試行付きの Provider
+func WithRetry(p Provider, retry duckdns.RetryConfig) *Retrying {
+	if retry.MaxRetries <= 0 {
+		retry.MaxRetries = duckdns.DefaultMaxRetries
+	}
+	if len(retry.Backoff) == 0 && retry.Strategy == nil {
+		retry.Backoff = append([]time.Duration(nil), duckdns.DefaultBackoff...)
+	}
+	return &Retrying{provider: p, retry: retry}
+}
+
+// Name は、包んでいる Provider の名前を返します。
+func (r *Retrying) Name() string {
+	return r.provider.Name()
+}
+
+// Unwrap は、包んでいる Provider を返します。
+func (r *Retrying) Unwrap() Provider {
+	return r.provider
+}
+
+// Update は、包んでいる Provider で更新し、一時的な失敗の場合はバックオフ後に再試行します。
+func (r *Retrying) Update(ctx context.Context, domain, ip string) error {
+	maxAttempts := r.retry.MaxRetries + 1 // 最初の試行 + リトライ回数
+
+	var lastErr error
+	start := time.Now()
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err := r.provider.Update(ctx, domain, ip)
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("更新がリトライで成功しました",
+					"provider", r.Name(),
+					"domain", domain,
+					"attempt", attempt,
+				)
+			}
+			return nil
+		}
+		if errors.Is(err, ErrRejected) || ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		backoff := r.retry.Delay(attempt)
+
+		// Retry-After で待ち時間を指定された場合は、それより早く再試行しない
+		if retryAfter := duckdns.RetryAfter(err); retryAfter > backoff {
+			if retryAfter > duckdns.MaxRetryAfter {
+				return err
+			}
+			backoff = retryAfter
+		}
+
+		// 次の試行が MaxElapsedTime を過ぎる場合は、リトライをあきらめる
+		if maxElapsed := r.retry.MaxElapsedTime(); maxElapsed > 0 && time.Since(start)+backoff > maxElapsed {
+			return fmt.Errorf("%s の更新に失敗しました（%d回試行、最大経過時間 %s）: %w", r.Name(), attempt, maxElapsed, lastErr)
+		}
+
+		slog.Warn("更新が失敗、バックオフ後にリトライします",
+			"provider", r.Name(),
+			"domain", domain,
+			"attempt", attempt,
+			"backoff", backoff.String(),
+			"error", err,
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("バックオフ中にキャンセルされました: %w", ctx.Err())
+		}
+	}
+
+	return fmt.Errorf("%s の更新に失敗しました（%d回試行）: %w", r.Name(), maxAttempts, lastErr)
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// stubProvider は、テスト用に決まったエラーを順に返す Provider です。
+type stubProvider struct {
+	errs  []error
+	calls int
+}
+
+func (s *stubProvider) Name() string {
+	return "stub"
+}
+
+func (s *stubProvider) Update(ctx context.Context, domain, ip string) error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+// TestWithRetry は、一時的な失敗を再試行し、拒否は再試行しないことをテストします。
+func TestWithRetry(t *testing.T) {
+	retry := duckdns.RetryConfig{MaxRetries: 2, Backoff: []time.Duration{time.Millisecond}}
+
+	// 一時的な失敗の後に成功する
+	stub := &stubProvider{errs: []error{errors.New("timeout"), errors.New("timeout")}}
+	if err := WithRetry(stub, retry).Update(context.Background(), "home", "192.0.2.1"); err != nil {
+		t.Errorf("リトライで成功するべき: %v", err)
+	}
+	if stub.calls != 3 {
+		t.Errorf("試行回数が一致しません。期待: 3, 実際: %d", stub.calls)
+	}
+
+	// すべて失敗する
+	stub = &stubProvider{errs: []error{errors.New("a"), errors.New("b"), errors.New("c")}}
+	err := WithRetry(stub, retry).Update(context.Background(), "home", "192.0.2.1")
+	if err == nil || stub.calls != 3 {
+		t.Errorf("最大回数まで試行して失敗するべき: %v (試行回数: %d)", err, stub.calls)
+	}
+
+	// 拒否は再試行しない
+	stub = &stubProvider{errs: []error{rejected(errors.New("bad token"))}}
+	err = WithRetry(stub, retry).Update(context.Background(), "home", "192.0.2.1")
+	if !errors.Is(err, ErrRejected) || stub.calls != 1 {
+		t.Errorf("拒否は再試行せずに返すべき: %v (試行回数: %d)", err, stub.calls)
+	}
+}
+
+// TestWithRetry_Defaults は、ゼロ値の設定で DuckDNS クライアントの既定値を使うことをテストします。
+func TestWithRetry_Defaults(t *testing.T) {
+	r := WithRetry(&stubProvider{}, duckdns.RetryConfig{})
+	if r.retry.MaxRetries != duckdns.DefaultMaxRetries || len(r.retry.Backoff) != len(duckdns.DefaultBackoff) {
+		t.Errorf("既定値が適用されていません: %+v", r.retry)
+	}
+	if r.Name() != "stub" || r.Unwrap().Name() != "stub" {
+		t.Errorf("包んでいる Provider の名前を返すべき: %s", r.Name())
+	}
+}
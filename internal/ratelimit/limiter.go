@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter は、トークンバケットでリクエストの頻度を制限します。
+// 期間（per）あたり requests 回までリクエストでき、使ったトークンは一定の速さで補充されます。
+// 複数の goroutine から同時に使用できます。
+type Limiter struct {
+	mu sync.Mutex
+
+	// rate は、1秒あたりに補充するトークンの数です
+	rate float64
+
+	// burst は、バケットに貯められるトークンの最大数です
+	burst float64
+
+	// tokens は、現在のトークンの数です（待機中のリクエストが予約した分だけ負になります）
+	tokens float64
+
+	// last は、最後にトークンを補充した時刻です
+	last time.Time
+
+	// now は、現在時刻を返す関数です（テストで差し替えます）
+	now func() time.Time
+}
+
+// NewLimiter は、期間 per あたり requests 回までリクエストできる Limiter を作成します。
+// 最初は requests 回まで待たずにリクエストできます。
+//
+// Parameters:
+//   - requests: 期間あたりのリクエスト数（1 以上）
+//   - per: 期間（正の値）
+//
+// Returns:
+//   - *Limiter: 作成された Limiter
+func NewLimiter(requests int, per time.Duration) *Limiter {
+	if requests < 1 {
+		requests = 1
+	}
+	if per <= 0 {
+		per = time.Second
+	}
+	return &Limiter{
+		rate:   float64(requests) / per.Seconds(),
+		burst:  float64(requests),
+		tokens: float64(requests),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Wait は、トークンを1つ使ってリクエストできるようになるまで待ちます。
+// コンテキストがキャンセルされた場合は、予約したトークンを戻してエラーを返します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - time.Duration: 待った時間（すぐにリクエストできた場合は 0）
+//   - error: 待機中にコンテキストがキャンセルされた場合
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	wait := l.reserve()
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// reserve は、トークンを1つ予約し、使えるようになるまでの待ち時間を返します。
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLimiter_Burst は、最初は requests 回まで待たずにリクエストでき、その後は補充を待つことをテストします。
+func TestLimiter_Burst(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(3, time.Minute)
+	l.last = now
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("%d回目: 待たずにリクエストできるべき。待ち時間: %v", i+1, wait)
+		}
+	}
+	if wait := l.reserve(); wait != 20*time.Second {
+		t.Errorf("4回目: 待ち時間 = %v, 期待: 20s", wait)
+	}
+	if wait := l.reserve(); wait != 40*time.Second {
+		t.Errorf("5回目: 待ち時間 = %v, 期待: 40s（前のリクエストの予約の後）", wait)
+	}
+}
+
+// TestLimiter_Refill は、時間の経過でトークンが補充され、burst を超えないことをテストします。
+func TestLimiter_Refill(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.last = now
+	l.now = func() time.Time { return now }
+
+	l.reserve()
+	l.reserve()
+
+	// 1時間後でも、貯められるのは burst の2回分まで
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("%d回目: 補充されたトークンで待たずにリクエストできるべき。待ち時間: %v", i+1, wait)
+		}
+	}
+	if wait := l.reserve(); wait != 30*time.Second {
+		t.Errorf("3回目: 待ち時間 = %v, 期待: 30s", wait)
+	}
+}
+
+// TestLimiter_WaitCancelled は、待機中にキャンセルされた場合はエラーを返し、予約を戻すことをテストします。
+func TestLimiter_WaitCancelled(t *testing.T) {
+	l := NewLimiter(1, time.Hour)
+	if _, err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("1回目は待たずにリクエストできるべき: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("キャンセルのエラーが返されるべき: %v", err)
+	}
+
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens < -0.01 || tokens > 0.01 {
+		t.Errorf("キャンセルした予約は戻されるべき。トークン数: %v", tokens)
+	}
+}
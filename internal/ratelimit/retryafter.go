@@ -0,0 +1,41 @@
+// Package ratelimit は、DuckDNS や IP取得ソースのリクエスト数の制限に対応するための機能を提供します。
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRetryAfter は、Retry-After ヘッダーの値を待ち時間に変換します。
+// 秒数（"120"）と HTTP の日時（"Wed, 21 Oct 2015 07:28:00 GMT"）のどちらの形式にも対応します。
+//
+// Parameters:
+//   - value: Retry-After ヘッダーの値
+//   - now: 日時形式の値から待ち時間を計算するときの現在時刻
+//
+// Returns:
+//   - time.Duration: 待ち時間（ヘッダーがない、解析できない、または過去の日時の場合は 0）
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	if wait := at.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
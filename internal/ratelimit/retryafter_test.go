@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestParseRetryAfter は、Retry-After ヘッダーの秒数と日時の形式を待ち時間に変換することをテストします。
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"空", "", 0},
+		{"秒数", "120", 2 * time.Minute},
+		{"前後の空白", " 5 ", 5 * time.Second},
+		{"負の秒数", "-1", 0},
+		{"日時", now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{"過去の日時", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"不正な値", "soon", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("%s: ParseRetryAfter(%q) = %v, 期待: %v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
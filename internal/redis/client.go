@@ -0,0 +1,292 @@
+// Package redis は、状態を複数のインスタンスで共有するための、最小限の Redis クライアントを提供します。
+// RESP（REdis Serialization Protocol）で GET・SET と、WATCH/MULTI/EXEC による楽観的なトランザクションだけを扱います。
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout は、Timeout を省略した場合の、1回の接続とコマンドのタイムアウトです。
+const DefaultTimeout = 5 * time.Second
+
+// maxModifyAttempts は、Modify がほかのクライアントの書き込みと重なったときにやり直す最大の回数です。
+const maxModifyAttempts = 10
+
+// ErrConflict は、ほかのクライアントが同時に書き込み続けたため、Modify が書き込めなかった場合のエラーです。
+var ErrConflict = errors.New("ほかのクライアントが同時に書き込んだため、書き込めませんでした")
+
+// Error は、Redis サーバーが返したエラー（"-ERR ..."）です。
+type Error string
+
+// Error は、サーバーが返したエラーメッセージを返します。
+func (e Error) Error() string {
+	return "Redis サーバーのエラー: " + string(e)
+}
+
+// Options は、Redis サーバーへの接続の設定です。
+type Options struct {
+	// Address は、サーバーのアドレス（host:port）です
+	Address string
+
+	// Username は、ACL のユーザー名です（省略した場合はパスワードだけで認証します）
+	Username string
+
+	// Password は、パスワードです（省略した場合は認証しません）
+	Password string
+
+	// DB は、使用するデータベースの番号です
+	DB int
+
+	// TLS は、TLS で接続する場合の設定です（nil の場合は平文で接続します）
+	TLS *tls.Config
+
+	// Timeout は、1回の接続とコマンドのタイムアウトです（0 の場合は DefaultTimeout）
+	Timeout time.Duration
+}
+
+// Client は、Redis サーバーとの1本の接続を使い回すクライアントです。
+// 複数のゴルーチンから同時に使っても安全です（コマンドは順番に実行します）。
+// 通信に失敗した接続は閉じ、次のコマンドでつなぎ直します。
+type Client struct {
+	opts Options
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewClient は、Redis のクライアントを作成します。接続は最初のコマンドを実行するときに行います。
+//
+// Parameters:
+//   - opts: 接続の設定
+//
+// Returns:
+//   - *Client: 作成されたクライアント
+func NewClient(opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Client{opts: opts}
+}
+
+// String は、ログに出す接続先（"redis://host:port/db"）を返します。パスワードは含みません。
+func (c *Client) String() string {
+	scheme := "redis"
+	if c.opts.TLS != nil {
+		scheme = "rediss"
+	}
+	return fmt.Sprintf("%s://%s/%d", scheme, c.opts.Address, c.opts.DB)
+}
+
+// Close は、接続を閉じます。
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.rd = nil, nil
+	return err
+}
+
+// Ping は、サーバーにつながるかどうかを確かめます。
+func (c *Client) Ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.do("PING")
+	return err
+}
+
+// Get は、キーの値を取得します。
+//
+// Parameters:
+//   - key: キー
+//
+// Returns:
+//   - []byte: 値（キーがない場合は nil）
+//   - error: 通信に失敗した場合
+func (c *Client) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+// Set は、キーに値を書き込みます。
+//
+// Parameters:
+//   - key: キー
+//   - value: 値
+//
+// Returns:
+//   - error: 通信に失敗した場合
+func (c *Client) Set(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.do("SET", key, string(value))
+	return err
+}
+
+// Modify は、キーの値を読み込んで fn で書き換えます。
+// WATCH したキーをほかのクライアントが書き換えていた場合は、読み込みからやり直すため、fn は何度か呼ばれることがあります。
+//
+// Parameters:
+//   - key: キー
+//   - fn: 今の値（ない場合は nil）を受け取って、書き込む値を返す関数（nil を返した場合は書き込みません）
+//
+// Returns:
+//   - error: 通信に失敗した場合、fn がエラーを返した場合、やり直しても書き込めなかった場合は ErrConflict
+func (c *Client) Modify(key string, fn func(current []byte) ([]byte, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for attempt := 0; attempt < maxModifyAttempts; attempt++ {
+		if _, err := c.do("WATCH", key); err != nil {
+			return err
+		}
+		current, err := c.get(key)
+		if err != nil {
+			return err
+		}
+		next, err := fn(current)
+		if err != nil || next == nil {
+			if _, unwatchErr := c.do("UNWATCH"); err == nil {
+				err = unwatchErr
+			}
+			return err
+		}
+
+		reply, err := c.pipeline([][]string{{"MULTI"}, {"SET", key, string(next)}, {"EXEC"}})
+		if err != nil {
+			return err
+		}
+		// WATCH したキーが書き換えられていた場合、EXEC は nil を返します
+		if reply == nil {
+			continue
+		}
+		if items, ok := reply.([]any); ok && len(items) == 1 {
+			if serverErr, ok := items[0].(Error); ok {
+				return serverErr
+			}
+		}
+		return nil
+	}
+	return ErrConflict
+}
+
+// get は、ロックを取得せずに GET を実行します（内部用）。
+func (c *Client) get(key string) ([]byte, error) {
+	reply, err := c.do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("GET の応答が不正です: %T", reply)
+	}
+}
+
+// do は、1つのコマンドを実行して応答を返します。
+func (c *Client) do(args ...string) (any, error) {
+	return c.pipeline([][]string{args})
+}
+
+// pipeline は、複数のコマンドをまとめて送り、最後のコマンドの応答を返します。
+// 途中のコマンドがエラーを返した場合は、そのエラーを返します。
+func (c *Client) pipeline(cmds [][]string) (any, error) {
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Now().Add(c.opts.Timeout))
+
+	var buf strings.Builder
+	for _, args := range cmds {
+		writeCommand(&buf, args)
+	}
+	if _, err := io.WriteString(c.conn, buf.String()); err != nil {
+		c.reset()
+		return nil, fmt.Errorf("Redis サーバー (%s) に送信できません: %w", c.opts.Address, err)
+	}
+
+	var last any
+	var firstErr error
+	for range cmds {
+		reply, err := readReply(c.rd)
+		if err != nil {
+			var serverErr Error
+			if !errors.As(err, &serverErr) {
+				c.reset()
+				return nil, fmt.Errorf("Redis サーバー (%s) の応答を読み込めません: %w", c.opts.Address, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		last = reply
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return last, nil
+}
+
+// connect は、まだつながっていなければ、サーバーに接続して認証とデータベースの選択をします。
+func (c *Client) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: c.opts.Timeout}
+	var conn net.Conn
+	var err error
+	if c.opts.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.opts.Address, c.opts.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", c.opts.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("Redis サーバー (%s) に接続できません: %w", c.opts.Address, err)
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if c.opts.Password != "" {
+		if c.opts.Username != "" {
+			setup = append(setup, []string{"AUTH", c.opts.Username, c.opts.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.opts.Password})
+		}
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	for _, args := range setup {
+		if _, err := c.pipeline([][]string{args}); err != nil {
+			c.reset()
+			return fmt.Errorf("Redis サーバー (%s) の %s に失敗しました: %w", c.opts.Address, args[0], err)
+		}
+	}
+	return nil
+}
+
+// reset は、通信に失敗した接続を閉じて、次のコマンドでつなぎ直すようにします。
+func (c *Client) reset() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn, c.rd = nil, nil
+}
@@ -0,0 +1,340 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer は、テスト用の Redis サーバーです。
+// GET・SET・WATCH・MULTI・EXEC・AUTH・SELECT・PING だけに応答します。
+type fakeServer struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	data     map[string]string
+	versions map[string]int
+	conns    []net.Conn
+}
+
+// newFakeServer は、テスト用の Redis サーバーを起動します。
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("待ち受けに失敗: %v", err)
+	}
+	s := &fakeServer{ln: ln, password: password, data: map[string]string{}, versions: map[string]int{}}
+	t.Cleanup(func() {
+		ln.Close()
+		s.closeConns()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// closeConns は、つながっている接続をすべて切ります。
+func (s *fakeServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// set は、ほかのクライアントが書き込んだように値を書き換えます。
+func (s *fakeServer) set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	s.versions[key]++
+}
+
+// serve は、1つの接続のコマンドに応答します。
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := s.password == ""
+	watched := map[string]int{}
+	var queue [][]string
+	inMulti := false
+
+	for {
+		reply, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+
+		var out string
+		switch {
+		case cmd == "AUTH":
+			if args[len(args)-1] == s.password {
+				authed = true
+				out = "+OK\r\n"
+			} else {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		case inMulti && cmd != "EXEC":
+			queue = append(queue, args)
+			out = "+QUEUED\r\n"
+		default:
+			s.mu.Lock()
+			switch cmd {
+			case "PING":
+				out = "+PONG\r\n"
+			case "SELECT":
+				out = "+OK\r\n"
+			case "GET":
+				if v, ok := s.data[args[1]]; ok {
+					out = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+				} else {
+					out = "$-1\r\n"
+				}
+			case "SET":
+				s.data[args[1]] = args[2]
+				s.versions[args[1]]++
+				out = "+OK\r\n"
+			case "WATCH":
+				watched[args[1]] = s.versions[args[1]]
+				out = "+OK\r\n"
+			case "UNWATCH":
+				watched = map[string]int{}
+				out = "+OK\r\n"
+			case "MULTI":
+				inMulti = true
+				out = "+OK\r\n"
+			case "EXEC":
+				conflict := false
+				for key, version := range watched {
+					if s.versions[key] != version {
+						conflict = true
+					}
+				}
+				if conflict {
+					out = "*-1\r\n"
+				} else {
+					out = fmt.Sprintf("*%d\r\n", len(queue))
+					for _, q := range queue {
+						s.data[q[1]] = q[2]
+						s.versions[q[1]]++
+						out += "+OK\r\n"
+					}
+				}
+				inMulti, queue, watched = false, nil, map[string]int{}
+			default:
+				out = "-ERR unknown command\r\n"
+			}
+			s.mu.Unlock()
+		}
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+// TestClient_GetSet は、書き込んだ値を読み込めることと、ないキーは nil になることをテストします。
+func TestClient_GetSet(t *testing.T) {
+	server := newFakeServer(t, "")
+	c := NewClient(Options{Address: server.ln.Addr().String(), DB: 1})
+	defer c.Close()
+
+	got, err := c.Get("missing")
+	if err != nil || got != nil {
+		t.Fatalf("ないキーは nil になるべき: %q, %v", got, err)
+	}
+	if err := c.Set("key", []byte("value\r\nwith newline")); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+	got, err = c.Get("key")
+	if err != nil || string(got) != "value\r\nwith newline" {
+		t.Errorf("書き込んだ値を読み込めるべき: %q, %v", got, err)
+	}
+	if err := c.Ping(); err != nil {
+		t.Errorf("PING は成功するべき: %v", err)
+	}
+}
+
+// TestClient_Auth は、パスワードで認証することと、誤ったパスワードはエラーになることをテストします。
+func TestClient_Auth(t *testing.T) {
+	server := newFakeServer(t, "secret")
+
+	c := NewClient(Options{Address: server.ln.Addr().String(), Password: "secret"})
+	defer c.Close()
+	if err := c.Ping(); err != nil {
+		t.Errorf("正しいパスワードでは成功するべき: %v", err)
+	}
+
+	wrong := NewClient(Options{Address: server.ln.Addr().String(), Username: "default", Password: "wrong"})
+	defer wrong.Close()
+	err := wrong.Ping()
+	var serverErr Error
+	if !errors.As(err, &serverErr) || !strings.Contains(err.Error(), "AUTH") {
+		t.Errorf("誤ったパスワードは AUTH のエラーになるべき: %v", err)
+	}
+}
+
+// TestClient_Modify は、読み込んだ値を書き換えることと、nil を返した場合は書き込まないことをテストします。
+func TestClient_Modify(t *testing.T) {
+	server := newFakeServer(t, "")
+	c := NewClient(Options{Address: server.ln.Addr().String()})
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		err := c.Modify("counter", func(current []byte) ([]byte, error) {
+			return append(current, 'x'), nil
+		})
+		if err != nil {
+			t.Fatalf("書き換えに失敗しました: %v", err)
+		}
+	}
+	if got, _ := c.Get("counter"); string(got) != "xxx" {
+		t.Errorf("3回書き換えられるべき: %q", got)
+	}
+
+	if err := c.Modify("counter", func(current []byte) ([]byte, error) { return nil, nil }); err != nil {
+		t.Fatalf("書き込まない場合もエラーにならないべき: %v", err)
+	}
+	if got, _ := c.Get("counter"); string(got) != "xxx" {
+		t.Errorf("nil を返した場合は書き込まないべき: %q", got)
+	}
+
+	wantErr := errors.New("invalid")
+	if err := c.Modify("counter", func(current []byte) ([]byte, error) { return nil, wantErr }); !errors.Is(err, wantErr) {
+		t.Errorf("fn のエラーを返すべき: %v", err)
+	}
+	// UNWATCH した後も、同じ接続でコマンドを続けられるべき
+	if got, err := c.Get("counter"); err != nil || string(got) != "xxx" {
+		t.Errorf("エラーの後もコマンドを続けられるべき: %q, %v", got, err)
+	}
+}
+
+// TestClient_Modify_Conflict は、ほかのクライアントが書き込んだ場合に、読み込みからやり直すことをテストします。
+func TestClient_Modify_Conflict(t *testing.T) {
+	server := newFakeServer(t, "")
+	c := NewClient(Options{Address: server.ln.Addr().String()})
+	defer c.Close()
+
+	calls := 0
+	err := c.Modify("key", func(current []byte) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			server.set("key", "other")
+		}
+		return append(current, "+mine"...), nil
+	})
+	if err != nil {
+		t.Fatalf("書き換えに失敗しました: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("やり直して fn が2回呼ばれるべき: %d", calls)
+	}
+	if got, _ := c.Get("key"); string(got) != "other+mine" {
+		t.Errorf("ほかのクライアントの書き込みに続けて書き換えるべき: %q", got)
+	}
+
+	err = c.Modify("key", func(current []byte) ([]byte, error) {
+		server.set("key", "always")
+		return []byte("never"), nil
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("書き込みが重なり続けた場合は ErrConflict になるべき: %v", err)
+	}
+}
+
+// TestClient_Reconnect は、接続が切れた後のコマンドでつなぎ直すことをテストします。
+func TestClient_Reconnect(t *testing.T) {
+	server := newFakeServer(t, "")
+	c := NewClient(Options{Address: server.ln.Addr().String()})
+	defer c.Close()
+
+	if err := c.Set("key", []byte("value")); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+	server.closeConns()
+
+	// 切れたことに気づいたコマンドは失敗してもよいが、続くコマンドはつなぎ直して成功するべき
+	var got []byte
+	var err error
+	for i := 0; i < 2; i++ {
+		if got, err = c.Get("key"); err == nil {
+			break
+		}
+	}
+	if err != nil || string(got) != "value" {
+		t.Errorf("つなぎ直して読み込めるべき: %q, %v", got, err)
+	}
+}
+
+// TestClient_String は、接続先にパスワードが含まれないことをテストします。
+func TestClient_String(t *testing.T) {
+	c := NewClient(Options{Address: "redis.example.com:6379", Password: "secret", DB: 2})
+	if got := c.String(); got != "redis://redis.example.com:6379/2" {
+		t.Errorf("接続先が一致しません: %s", got)
+	}
+}
+
+// TestReadReply は、RESP の応答の種類ごとの読み込みをテストします。
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "単純な文字列", input: "+OK\r\n", want: "OK"},
+		{name: "整数", input: ":42\r\n", want: "42"},
+		{name: "バルク文字列", input: "$5\r\nhello\r\n", want: "[104 101 108 108 111]"},
+		{name: "nil のバルク文字列", input: "$-1\r\n", want: "<nil>"},
+		{name: "配列", input: "*2\r\n+OK\r\n:1\r\n", want: "[OK 1]"},
+		{name: "nil の配列", input: "*-1\r\n", want: "<nil>"},
+		{name: "エラー", input: "-ERR bad\r\n", wantErr: true},
+		{name: "不明な型", input: "?x\r\n", wantErr: true},
+		{name: "LF だけの行", input: "+OK\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("エラーになるべき: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("エラーは返されないはず: %v", err)
+			}
+			if s := fmt.Sprint(got); s != tt.want {
+				t.Errorf("got %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
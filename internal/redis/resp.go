@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxBulkSize は、読み込む1つの値の最大のサイズです（壊れた応答で大きなメモリを確保しないようにします）。
+const maxBulkSize = 64 << 20
+
+// writeCommand は、コマンドを RESP の配列（バルク文字列の配列）にして buf に書き込みます。
+func writeCommand(buf *strings.Builder, args []string) {
+	fmt.Fprintf(buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// readReply は、RESP の応答を1つ読み込みます。
+// 単純な文字列は string、整数は int64、バルク文字列は []byte、配列は []any で返し、nil の応答は nil を返します。
+// サーバーがエラーを返した場合は Error を返します。
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("空の応答です")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("整数の応答が不正です: %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxBulkSize {
+			return nil, fmt.Errorf("バルク文字列の長さが不正です: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		if data[n] != '\r' || data[n+1] != '\n' {
+			return nil, errors.New("バルク文字列の終わりが不正です")
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("配列の長さが不正です: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readReply(rd)
+			var serverErr Error
+			if err != nil && !errors.As(err, &serverErr) {
+				return nil, err
+			}
+			if err != nil {
+				item = serverErr
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("不明な応答です: %q", line)
+	}
+}
+
+// readLine は、CRLF で終わる1行を、CRLF を除いて読み込みます。
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("行の終わりが不正です: %q", line)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/provider"
+)
+
+// AutoDetectSource は、自動検出のモードで、プロバイダーが検出したIPアドレスの取得元です（Result.Fetch.Source に入ります）。
+const AutoDetectSource = "auto-detect"
+
+// ErrAutoDetectUnsupported は、自動検出のモードで、更新先のプロバイダーがIPアドレスの自動検出に対応していないことを表すエラーです。
+var ErrAutoDetectUnsupported = errors.New("プロバイダーがIPアドレスの自動検出に対応していません")
+
+// SetAutoDetect は、IP取得ソースに問い合わせずに、IPアドレスを送らない更新でプロバイダーにリクエストの送信元のアドレスを登録させるモードにします。
+// 更新先のプロバイダーは provider.AutoDetector に対応している必要があります（対応していない更新先は ErrAutoDetectUnsupported で失敗します）。
+// IPアドレスの変化は更新するまでわからないため、チェックのたびに更新のリクエストを送り、プロバイダーが返したアドレスを登録済みのアドレスとして記録します。
+// IPv6 も更新する場合（SetIPv6Fetcher）は、IPv6アドレスだけを IP取得ソースから取得して一緒に送ります。
+// SetCrossCheck を設定した場合は、IPv4アドレスも IP取得ソースから取得して、検出したアドレスと照らし合わせます。
+// Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - enabled: 自動検出のモードにする場合は true
+func (s *Scheduler) SetAutoDetect(enabled bool) {
+	s.autoDetect = enabled
+}
+
+// checkAutoDetect は、自動検出のモードでチェックと更新を実行し、結果を results に書き込みます（内部用ヘルパー関数）
+// 同じ AutoDetector の更新先は、1回のリクエストにまとめます。
+func (s *Scheduler) checkAutoDetect(ctx context.Context, start time.Time, results []Result) {
+	// IPv6 も更新する場合と、検出したアドレスを照らし合わせる場合だけ、IP取得ソースに問い合わせる
+	var fetchedIPv6 ip.FetchResult
+	var fetched string
+	var ipv6Err, fetchErr error
+	if s.ipv6Fetcher != nil || s.crossCheckEnabled() {
+		s.pool.run(func() {
+			var wg sync.WaitGroup
+			if s.ipv6Fetcher != nil {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := s.protect(PanicInFetch, func() { fetchedIPv6, ipv6Err = s.fetch(ctx, s.ipv6Fetcher) }); err != nil {
+						ipv6Err = err
+					}
+				}()
+			}
+			fetched, fetchErr = s.fetchForCrossCheck(ctx)
+			wg.Wait()
+		})
+		if ipv6Err != nil {
+			slog.Warn("IPv6 アドレスの取得に失敗しました（IPv4 だけを更新します）",
+				"error", ipv6Err,
+			)
+		}
+	}
+
+	var wg sync.WaitGroup
+	detectors := make(map[provider.AutoDetector][]int)
+	for i, t := range s.targets {
+		results[i].FetchIPv6 = fetchedIPv6
+		d, ok := t.provider.(provider.AutoDetector)
+		if !ok {
+			results[i].Err = fmt.Errorf("%w: %s", ErrAutoDetectUnsupported, t.provider.Name())
+			results[i].Duration = s.since(start)
+			continue
+		}
+		detectors[d] = append(detectors[d], i)
+	}
+	for d, indexes := range detectors {
+		wg.Add(1)
+		go func(d provider.AutoDetector, indexes []int) {
+			defer wg.Done()
+			err := s.protect(PanicInUpdate, func() {
+				s.pool.run(func() { s.updateAutoDetect(ctx, d, indexes, fetchedIPv6.IP, results) })
+			})
+			for _, i := range indexes {
+				if err != nil {
+					results[i].Err = err
+				}
+				results[i].Duration = s.since(start)
+			}
+		}(d, indexes)
+	}
+	wg.Wait()
+
+	if ipv6Err != nil {
+		for i := range results {
+			results[i].IPv6Err = ipv6Err
+			if results[i].Err == nil {
+				results[i].Err = ipv6Err
+			}
+		}
+	}
+
+	if s.crossCheckEnabled() {
+		s.compareDetected(fetched, fetchErr, results)
+	}
+
+	var currentIP string
+	for _, r := range results {
+		if r.NewIP != "" {
+			currentIP = r.NewIP
+			break
+		}
+	}
+	s.logSummary(currentIP, fetchedIPv6.IP, results)
+	s.record(ctx, results)
+}
+
+// updateAutoDetect は、同じ AutoDetector の更新先をIPアドレスを送らずにまとめて更新し、結果を results に書き込みます（内部用ヘルパー関数）
+// プロバイダーが返したアドレスを、その更新先の lastIP（lastIPv6）にします。
+func (s *Scheduler) updateAutoDetect(ctx context.Context, d provider.AutoDetector, indexes []int, currentIPv6 string, results []Result) {
+	domains := make([]string, len(indexes))
+	for n, i := range indexes {
+		domains[n] = s.targets[i].domain
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	detected, errs := d.UpdateAutoDetect(ctx, domains, currentIPv6)
+	for n, i := range indexes {
+		t, r := s.targets[i], &results[i]
+		if errs[n] != nil {
+			_, r.Err = finishUpdate(t, errs[n], AutoDetectSource)
+			if currentIPv6 != "" {
+				r.IPv6Err = r.Err
+			}
+			continue
+		}
+
+		r.Fetch = ip.FetchResult{IP: detected.IPv4, Source: AutoDetectSource}
+		r.NewIP = detected.IPv4
+		ips := []string{r.NewIP}
+		if currentIPv6 != "" {
+			r.NewIPv6 = detected.IPv6
+			ips = append(ips, r.NewIPv6)
+		}
+		ipv4Changed := t.lastIP != detected.IPv4
+		ipv6Changed := currentIPv6 != "" && t.lastIPv6 != detected.IPv6
+		if detected.Changed {
+			if ipv4Changed {
+				logChange(t, t.lastIP, detected.IPv4)
+			}
+			if ipv6Changed {
+				logChange(t, t.lastIPv6, detected.IPv6)
+			}
+			finishUpdate(t, nil, ips...)
+			r.Updated = ipv4Changed || !ipv6Changed
+			r.UpdatedIPv6 = ipv6Changed
+		}
+		t.lastIP = detected.IPv4
+		if currentIPv6 != "" {
+			t.lastIPv6 = detected.IPv6
+		}
+	}
+}
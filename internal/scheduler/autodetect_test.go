@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/provider"
+)
+
+// TestScheduler_SetAutoDetect は、自動検出のモードでは IP取得ソースに問い合わせずに ip を省略して更新し、
+// DuckDNS が登録したアドレスを記録することをテストします。
+func TestScheduler_SetAutoDetect(t *testing.T) {
+	var detected atomic.Value
+	detected.Store("198.51.100.7")
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		q := r.URL.Query()
+		if q.Get("ip") != "" || q.Get("verbose") != "true" {
+			t.Errorf("ip を省略して verbose=true で送信するべき: %s", r.URL.RawQuery)
+		}
+		status := "NOCHANGE"
+		if n == 1 || n == 3 {
+			status = "UPDATED"
+		}
+		w.Write([]byte("OK\n" + detected.Load().(string) + "\n\n" + status))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	duck := provider.NewDuckDNS(client, "test-token")
+	fetcher := &MockFetcher{}
+	recorder := &mockRecorder{}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, duck, "a")
+	s.AddTarget(duck, "b")
+	s.SetRecorder(recorder)
+	s.SetAutoDetect(true)
+
+	results := s.CheckOnce(context.Background())
+	for _, r := range results {
+		if !r.Updated || r.Err != nil || r.NewIP != "198.51.100.7" || r.Fetch.Source != AutoDetectSource {
+			t.Errorf("DuckDNS が検出したアドレスで更新したと報告するべき: %+v", r)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("同じトークンのドメインは1回のリクエストにまとめるべき。リクエスト数: %d", got)
+	}
+	if fetcher.GetFetchCount() != 0 {
+		t.Errorf("IP取得ソースに問い合わせないべき: %d", fetcher.GetFetchCount())
+	}
+
+	// 変わらなくても、チェックのたびに更新のリクエストを送る
+	result := s.CheckOnce(context.Background())[0]
+	if result.Updated || result.Err != nil || result.OldIP != "198.51.100.7" {
+		t.Errorf("NOCHANGE の場合は更新していないと報告するべき: %+v", result)
+	}
+
+	detected.Store("198.51.100.8")
+	result = s.CheckOnce(context.Background())[0]
+	if !result.Updated || result.OldIP != "198.51.100.7" || result.NewIP != "198.51.100.8" {
+		t.Errorf("検出したアドレスが変わったら更新したと報告するべき: %+v", result)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("チェックごとに1回のリクエストであるべき。リクエスト数: %d", got)
+	}
+
+	if len(recorder.results) != 6 || recorder.results[0].ip != "198.51.100.7" || recorder.results[0].source != AutoDetectSource {
+		t.Errorf("検出したアドレスを記録するべき: %+v", recorder.results)
+	}
+}
+
+// TestScheduler_SetAutoDetect_Unsupported は、自動検出に対応していないプロバイダーの更新先は失敗にすることをテストします。
+func TestScheduler_SetAutoDetect_Unsupported(t *testing.T) {
+	var updates atomic.Int32
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		updates.Add(1)
+		return nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, &MockFetcher{}, p, "home")
+	s.SetAutoDetect(true)
+
+	result := s.CheckOnce(context.Background())[0]
+	if !errors.Is(result.Err, ErrAutoDetectUnsupported) {
+		t.Errorf("ErrAutoDetectUnsupported で失敗するべき: %+v", result)
+	}
+	if got := updates.Load(); got != 0 {
+		t.Errorf("対応していないプロバイダーは更新しないべき: %d", got)
+	}
+}
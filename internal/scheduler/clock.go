@@ -0,0 +1,73 @@
+package scheduler
+
+import "time"
+
+// Clock は、スケジューラーが使う時計です。
+// テストやシミュレーションで、実際の時間を待たずに定期チェックを進める場合に差し替えます。
+type Clock interface {
+	// Now は、現在の時刻を返します
+	Now() time.Time
+
+	// NewTicker は、d ごとに時刻を送る Ticker を作成します
+	NewTicker(d time.Duration) Ticker
+
+	// After は、d が経過したら時刻を送るチャネルを返します
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker は、Clock.NewTicker が作成する Ticker です。
+type Ticker interface {
+	// C は、時刻を送るチャネルを返します
+	C() <-chan time.Time
+
+	// Stop は、時刻を送るのをやめます
+	Stop()
+}
+
+// SystemClock は、実際の時刻を使う Clock です（既定の時計）。
+var SystemClock Clock = systemClock{}
+
+// systemClock は、time パッケージの時刻と Ticker を使う Clock です。
+type systemClock struct{}
+
+// Now は、現在の時刻を返します。
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker は、time.Ticker を作成します。
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// After は、time.After のチャネルを返します。
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// systemTicker は、time.Ticker を Ticker にします。
+type systemTicker struct{ t *time.Ticker }
+
+// C は、time.Ticker のチャネルを返します。
+func (t systemTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Stop は、time.Ticker を停止します。
+func (t systemTicker) Stop() {
+	t.t.Stop()
+}
+
+// SetClock は、スケジューラーが使う時計を設定します（nil の場合は SystemClock）。
+// Run または RunOnce の前に呼び出してください。
+func (s *Scheduler) SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+	s.clock = c
+}
+
+// since は、スケジューラーの時計で start からの経過時間を返します。
+func (s *Scheduler) since(start time.Time) time.Duration {
+	return s.clock.Now().Sub(start)
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock は、Advance で進めるテスト用の Clock です。
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// fakeTicker は、fakeClock の Ticker です。
+type fakeTicker struct {
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+	stopped  bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time, 1), interval: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return nil
+}
+
+// Advance は、時計を d だけ進めて、期限を過ぎた Ticker に時刻を送ります。
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.stopped && !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.interval)
+		}
+	}
+}
+
+// tickerCount は、作成された Ticker の数を返します。
+func (c *fakeClock) tickerCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tickers)
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+func (t *fakeTicker) Stop()               { t.stopped = true }
+
+// TestScheduler_SetClock は、差し替えた時計で、実際の時間を待たずに定期チェックと所要時間を進められることをテストします。
+func TestScheduler_SetClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		clock.Advance(3 * time.Second) // 取得に3秒かかったことにする
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetClock(clock)
+
+	if r := s.CheckOnce(context.Background())[0]; r.Duration != 3*time.Second {
+		t.Errorf("所要時間は差し替えた時計で測るべき: %s", r.Duration)
+	}
+	<-checked
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	<-checked // 起動直後のチェック
+	for clock.tickerCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(time.Hour)
+	select {
+	case <-checked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("時計を間隔だけ進めたら、定期チェックをするべき")
+	}
+	cancel()
+	<-done
+	if got := fetcher.GetFetchCount(); got != 3 {
+		t.Errorf("チェックは3回であるべき: %d", got)
+	}
+}
@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// DefaultCrossCheckThreshold は、SetCrossCheck の回数を省略した場合の値です。
+const DefaultCrossCheckThreshold = 3
+
+// ErrIPMismatch は、IP取得ソースが取得したアドレスと、プロバイダーが検出したアドレスが続けて一致しないことを表すエラーです。
+// プロキシや VPN の出口、CGNAT などで、送信元のアドレスが思っていたアドレスと違うことを表します。
+var ErrIPMismatch = errors.New("IP取得ソースのアドレスとプロバイダーが検出したアドレスが一致しません")
+
+// MismatchError は、IP取得ソースが取得したアドレスと、プロバイダーが検出したアドレスが続けて一致しないことを表すエラーです。
+// errors.Is(err, ErrIPMismatch) で判定できます。
+type MismatchError struct {
+	// Fetched は、IP取得ソースが取得したアドレスです
+	Fetched string
+
+	// Detected は、プロバイダーがリクエストの送信元から検出したアドレスです
+	Detected string
+
+	// Count は、続けて一致しなかったチェックの回数です
+	Count int
+}
+
+// Error は、2つのアドレスと回数を含むエラーメッセージを返します。
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%v (IP取得ソース %s, 検出 %s, %d 回連続)", ErrIPMismatch, e.Fetched, e.Detected, e.Count)
+}
+
+// Is は、target が ErrIPMismatch の場合に true を返します。
+func (e *MismatchError) Is(target error) bool {
+	return target == ErrIPMismatch
+}
+
+// crossCheck は、自動検出のモードで、検出したアドレスを IP取得ソースと照らし合わせる状態です。
+type crossCheck struct {
+	// threshold は、警告するまでに続けて一致しない回数です（0 の場合は照らし合わせません）
+	threshold int
+
+	// count は、続けて一致しなかったチェックの回数です
+	count int
+}
+
+// SetCrossCheck は、自動検出のモード（SetAutoDetect）で、プロバイダーが検出したアドレスを IP取得ソースが取得したアドレスと照らし合わせます。
+// threshold 回のチェックで続けて一致しなかった場合は、警告のログを出力し、そのチェックの結果の Mismatch に *MismatchError を入れて Notifier に知らせます。
+// 照らし合わせるだけで、更新するアドレスはプロバイダーが検出したアドレスのままです。
+// Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - threshold: 警告するまでに続けて一致しない回数（0 以下の場合は DefaultCrossCheckThreshold）
+func (s *Scheduler) SetCrossCheck(threshold int) {
+	if threshold <= 0 {
+		threshold = DefaultCrossCheckThreshold
+	}
+	s.crossCheck.threshold = threshold
+}
+
+// crossCheckEnabled は、検出したアドレスを IP取得ソースと照らし合わせるかどうかを返します。
+func (s *Scheduler) crossCheckEnabled() bool {
+	return s.crossCheck.threshold > 0 && s.ipFetcher != nil
+}
+
+// compareDetected は、IP取得ソースが取得した IPv4アドレスと、更新先ごとに検出したアドレスを照らし合わせます（内部用ヘルパー関数）
+// 一致しないチェックが threshold 回続いたときだけ、results の Mismatch に *MismatchError を入れます。
+func (s *Scheduler) compareDetected(fetched string, fetchErr error, results []Result) {
+	if fetchErr != nil {
+		slog.Debug("IP取得ソースからアドレスを取得できないため、検出したアドレスと照らし合わせません",
+			"error", fetchErr,
+		)
+		return
+	}
+
+	var detected string
+	for _, r := range results {
+		if r.Err == nil && r.NewIP != "" {
+			detected = r.NewIP
+			break
+		}
+	}
+	if detected == "" {
+		return
+	}
+
+	c := &s.crossCheck
+	if detected == fetched {
+		if c.count >= c.threshold {
+			slog.Info("IP取得ソースのアドレスと検出したアドレスが一致するようになりました",
+				"ip", detected,
+				"domains", s.Domains(),
+			)
+		}
+		c.count = 0
+		return
+	}
+
+	c.count++
+	slog.Debug("IP取得ソースのアドレスと検出したアドレスが一致しません",
+		"fetched_ip", fetched,
+		"detected_ip", detected,
+		"count", c.count,
+	)
+	if c.count != c.threshold {
+		return
+	}
+	err := &MismatchError{Fetched: fetched, Detected: detected, Count: c.count}
+	slog.Warn("IP取得ソースのアドレスと DuckDNS が検出したアドレスが続けて一致しません（プロキシ・VPN・CGNAT の可能性があります）",
+		"fetched_ip", fetched,
+		"detected_ip", detected,
+		"count", c.count,
+		"domains", s.Domains(),
+	)
+	for i := range results {
+		if results[i].NewIP == detected {
+			results[i].Mismatch = err
+		}
+	}
+}
+
+// fetchForCrossCheck は、照らし合わせる場合に、IP取得ソースから IPv4アドレスを取得します（照らし合わせない場合は空）。
+func (s *Scheduler) fetchForCrossCheck(ctx context.Context) (string, error) {
+	if !s.crossCheckEnabled() {
+		return "", nil
+	}
+	fetched, err := s.fetch(ctx, s.ipFetcher)
+	return fetched.IP, err
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/provider"
+)
+
+// TestScheduler_SetCrossCheck は、検出したアドレスと IP取得ソースのアドレスが続けて一致しない場合に、
+// 回数に達したチェックだけ Mismatch を入れて知らせ、一致したら数え直すことをテストします。
+func TestScheduler_SetCrossCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK\n198.51.100.7\n\nNOCHANGE"))
+	}))
+	defer server.Close()
+
+	var fetched atomic.Value
+	fetched.Store("192.0.2.1")
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return fetched.Load().(string), nil
+	}}
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	notifier := &mockNotifier{}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, provider.NewDuckDNS(client, "test-token"), "home")
+	s.SetAutoDetect(true)
+	s.SetCrossCheck(2)
+	s.SetNotifier(notifier)
+
+	if r := s.CheckOnce(context.Background())[0]; r.Mismatch != nil || r.Err != nil {
+		t.Errorf("1回目は知らせないべき: %+v", r)
+	}
+	r := s.CheckOnce(context.Background())[0]
+	var mismatch *MismatchError
+	if !errors.As(r.Mismatch, &mismatch) || !errors.Is(r.Mismatch, ErrIPMismatch) || mismatch.Fetched != "192.0.2.1" || mismatch.Detected != "198.51.100.7" || mismatch.Count != 2 {
+		t.Errorf("2回続けて一致しなければ Mismatch を入れるべき: %+v", r)
+	}
+	if r.Err != nil {
+		t.Errorf("一致しなくても更新は失敗にしないべき: %v", r.Err)
+	}
+	if r := s.CheckOnce(context.Background())[0]; r.Mismatch != nil {
+		t.Errorf("回数に達したチェックだけ知らせるべき: %+v", r)
+	}
+	if len(notifier.results) != 1 {
+		t.Errorf("Notifier に1回だけ知らせるべき: %d", len(notifier.results))
+	}
+
+	// 一致したら数え直す
+	fetched.Store("198.51.100.7")
+	s.CheckOnce(context.Background())
+	fetched.Store("192.0.2.1")
+	if r := s.CheckOnce(context.Background())[0]; r.Mismatch != nil {
+		t.Errorf("一致したら数え直すべき: %+v", r)
+	}
+	if r := s.CheckOnce(context.Background())[0]; r.Mismatch == nil {
+		t.Errorf("再び続けて一致しなければ知らせるべき: %+v", r)
+	}
+}
+
+// TestScheduler_SetCrossCheck_FetchError は、IP取得ソースから取得できない場合は照らし合わせず、更新は続けることをテストします。
+func TestScheduler_SetCrossCheck_FetchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK\n198.51.100.7\n\nUPDATED"))
+	}))
+	defer server.Close()
+
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return "", errors.New("fetch failed")
+	}}
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	s := NewSchedulerWithProvider(time.Hour, fetcher, provider.NewDuckDNS(client, "test-token"), "home")
+	s.SetAutoDetect(true)
+	s.SetCrossCheck(1)
+
+	r := s.CheckOnce(context.Background())[0]
+	if r.Err != nil || r.Mismatch != nil || !r.Updated {
+		t.Errorf("取得できなくても更新し、照らし合わせないべき: %+v", r)
+	}
+	if fetcher.GetFetchCount() != 1 {
+		t.Errorf("照らし合わせるために IP取得ソースに問い合わせるべき: %d", fetcher.GetFetchCount())
+	}
+}
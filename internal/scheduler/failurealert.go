@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// DefaultFailureAlertThreshold は、SetFailureAlert の回数を省略した場合の値です。
+const DefaultFailureAlertThreshold = 3
+
+// ErrUpdatesFailing は、更新先のチェックと更新に続けて失敗していることを表すエラーです。
+// IPアドレスが変わらなくても、IP取得ソースやプロバイダーに届かない状態が続いていることに気づけるようにします。
+var ErrUpdatesFailing = errors.New("更新に続けて失敗しています")
+
+// FailingError は、更新先のチェックと更新に続けて失敗していることを表すエラーです。
+// errors.Is(err, ErrUpdatesFailing) で判定でき、errors.Unwrap で最後のチェックのエラーを取り出せます。
+type FailingError struct {
+	// Count は、続けて失敗したチェックの回数です
+	Count int
+
+	// Err は、最後のチェックのエラーです
+	Err error
+}
+
+// Error は、回数と最後のエラーを含むエラーメッセージを返します。
+func (e *FailingError) Error() string {
+	return fmt.Sprintf("%v (%d 回連続): %v", ErrUpdatesFailing, e.Count, e.Err)
+}
+
+// Is は、target が ErrUpdatesFailing の場合に true を返します。
+func (e *FailingError) Is(target error) bool {
+	return target == ErrUpdatesFailing
+}
+
+// Unwrap は、最後のチェックのエラーを返します。
+func (e *FailingError) Unwrap() error {
+	return e.Err
+}
+
+// SetFailureAlert は、更新先ごとに threshold 回のチェックで続けて失敗した場合に、警告のログを出力し、
+// そのチェックの結果の Failing に *FailingError を入れて Notifier に知らせます。
+// 知らせたあとで成功した場合は、復旧したことを Result.Recovered で知らせます。
+// 事前の接続の確認に失敗してスキップしたチェック（Result.Offline）は、失敗にも成功にも数えません。
+// Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - threshold: 知らせるまでに続けて失敗する回数（0 以下の場合は DefaultFailureAlertThreshold）
+func (s *Scheduler) SetFailureAlert(threshold int) {
+	if threshold <= 0 {
+		threshold = DefaultFailureAlertThreshold
+	}
+	s.failureAlert = threshold
+}
+
+// checkFailures は、更新先ごとに続けて失敗した回数を数え、回数に達したチェックと復旧したチェックの結果に印をつけます（内部用ヘルパー関数）
+// キャンセルによる中断は数えません。
+func (s *Scheduler) checkFailures(ctx context.Context, results []Result) {
+	if s.failureAlert <= 0 || ctx.Err() != nil {
+		return
+	}
+	for i, t := range s.targets {
+		r := &results[i]
+		switch {
+		case r.Offline:
+			continue
+		case r.Err == nil:
+			if t.failures >= s.failureAlert {
+				r.Recovered = true
+				slog.Info("続けて失敗していた更新が復旧しました",
+					"domain", t.domain,
+					"provider", t.provider.Name(),
+					"failures", t.failures,
+				)
+			}
+			t.failures = 0
+			continue
+		}
+
+		t.failures++
+		if t.failures != s.failureAlert {
+			continue
+		}
+		r.Failing = &FailingError{Count: t.failures, Err: r.Err}
+		slog.Warn("更新に続けて失敗しています",
+			"domain", t.domain,
+			"provider", t.provider.Name(),
+			"failures", t.failures,
+			"error", r.Err,
+		)
+	}
+}
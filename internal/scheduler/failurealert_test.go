@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestScheduler_SetFailureAlert は、続けて失敗したチェックが回数に達したときだけ Failing を入れ、
+// そのあと成功したら Recovered で知らせることをテストします。
+func TestScheduler_SetFailureAlert(t *testing.T) {
+	var fail atomic.Bool
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		if fail.Load() {
+			return "", errors.New("fetch failed")
+		}
+		return "192.0.2.1", nil
+	}}
+	notifier := &mockNotifier{}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetFailureAlert(2)
+	s.SetNotifier(notifier)
+
+	s.CheckOnce(context.Background()) // 更新
+	fail.Store(true)
+	if r := s.CheckOnce(context.Background())[0]; r.Failing != nil {
+		t.Errorf("1回目の失敗では知らせないべき: %+v", r)
+	}
+	r := s.CheckOnce(context.Background())[0]
+	var failing *FailingError
+	if !errors.As(r.Failing, &failing) || !errors.Is(r.Failing, ErrUpdatesFailing) || failing.Count != 2 || failing.Err != r.Err {
+		t.Errorf("2回続けて失敗したら Failing を入れるべき: %+v", r)
+	}
+	if r := s.CheckOnce(context.Background())[0]; r.Failing != nil {
+		t.Errorf("回数に達したチェックだけ Failing を入れるべき: %+v", r)
+	}
+
+	fail.Store(false)
+	if r := s.CheckOnce(context.Background())[0]; !r.Recovered || r.Updated {
+		t.Errorf("知らせたあとで成功したら Recovered を入れるべき: %+v", r)
+	}
+	if r := s.CheckOnce(context.Background())[0]; r.Recovered {
+		t.Errorf("復旧は1回だけ知らせるべき: %+v", r)
+	}
+
+	// 更新・失敗3回・復旧の5件を知らせる（変更がなかったチェックは知らせない）
+	if len(notifier.results) != 5 || !notifier.results[4].Recovered {
+		t.Errorf("失敗と復旧を Notifier に知らせるべき: %+v", notifier.results)
+	}
+}
+
+// TestScheduler_SetFailureAlert_NotReached は、回数に達する前に成功した場合は数え直し、復旧も知らせないことをテストします。
+func TestScheduler_SetFailureAlert_NotReached(t *testing.T) {
+	var fail atomic.Bool
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		if fail.Load() {
+			return "", errors.New("fetch failed")
+		}
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetFailureAlert(0)
+
+	for i := 0; i < 2; i++ {
+		fail.Store(true)
+		for j := 0; j < DefaultFailureAlertThreshold-1; j++ {
+			if r := s.CheckOnce(context.Background())[0]; r.Failing != nil {
+				t.Errorf("回数に達する前は知らせないべき: %+v", r)
+			}
+		}
+		fail.Store(false)
+		if r := s.CheckOnce(context.Background())[0]; r.Recovered {
+			t.Errorf("知らせていなければ復旧も知らせないべき: %+v", r)
+		}
+	}
+}
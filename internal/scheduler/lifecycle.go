@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ライフサイクルのエラーです。
+var (
+	// ErrAlreadyStarted は、実行中の Scheduler の Start を呼び出した場合のエラーです
+	ErrAlreadyStarted = errors.New("スケジューラーはすでに実行中です")
+
+	// ErrStopTimeout は、Stop で待つ時間の上限までに、実行中のチェックと更新が終わらなかった場合のエラーです
+	ErrStopTimeout = errors.New("スケジューラーの停止が時間内に終わりませんでした")
+)
+
+// lifecycle は、Start で起動したバックグラウンドの実行の状態です。
+type lifecycle struct {
+	mu sync.Mutex
+
+	// stop は、定期チェックを停止します（実行していない場合は nil）
+	stop context.CancelFunc
+
+	// abort は、実行中のチェックと更新を中断します
+	abort context.CancelFunc
+
+	// done は、実行が終わったときに閉じられます（一度も起動していない場合は nil）
+	done chan struct{}
+}
+
+// Start は、スケジューラーをバックグラウンドで起動して、すぐに戻ります。
+// 起動直後に1回チェックと更新を実行し、その後は interval ごとに実行します。
+// ctx がキャンセルされるか Stop を呼び出すまで実行を続けます。停止した後は、もう一度 Start で起動できます。
+// Run と違ってブロックしないため、ほかのプログラムに組み込んで、Stop と Wait で停止を管理する場合に使います。
+//
+// Parameters:
+//   - ctx: 実行を制御するコンテキスト（キャンセルで停止）
+//
+// Returns:
+//   - error: すでに実行中の場合（ErrAlreadyStarted）、または更新チェックの実行間隔が 0 以下の場合
+func (s *Scheduler) Start(ctx context.Context) error {
+	if s.interval <= 0 {
+		return fmt.Errorf("更新チェックの実行間隔は 0 より大きくしてください: %s", s.interval)
+	}
+
+	l := &s.lifecycle
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop != nil {
+		return ErrAlreadyStarted
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	work, abort := WithGrace(runCtx, s.shutdownGrace)
+	done := make(chan struct{})
+	l.stop, l.abort, l.done = stop, abort, done
+
+	go func() {
+		defer close(done)
+		defer abort()
+		s.run(runCtx, work)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.done == done {
+			l.stop, l.abort = nil, nil
+		}
+		stop()
+	}()
+	return nil
+}
+
+// Stop は、Start で起動したスケジューラーに停止を求めて、止まるまで待ちます。
+// 実行中のチェックと更新は、SetShutdownGrace の猶予時間の間は中断せずに待ちます。
+// timeout を過ぎても終わらない場合は、実行中のチェックと更新を中断し、戻るのを待たずに ErrStopTimeout を返します。
+// 中断に応じない処理で止まっている場合もあるため、完全に止まるまで待つ場合は、そのあとで Wait を呼び出してください。
+// 実行していない場合は、何もせずに nil を返します。
+//
+// Parameters:
+//   - timeout: 止まるまで待つ時間の上限（0 以下の場合は、止まるまで待ちます）
+//
+// Returns:
+//   - error: timeout までに止まらなかった場合（ErrStopTimeout）
+func (s *Scheduler) Stop(timeout time.Duration) error {
+	l := &s.lifecycle
+	l.mu.Lock()
+	stop, abort, done := l.stop, l.abort, l.done
+	l.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+
+	stop()
+	if timeout <= 0 {
+		<-done
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		abort()
+		return fmt.Errorf("%w (%s)", ErrStopTimeout, timeout)
+	}
+}
+
+// Wait は、Start で起動したスケジューラーが止まるまでブロックします。
+// Stop が ErrStopTimeout を返した場合も、中断したチェックと更新が戻り、ループが完全に終わるまで待ちます。
+// Start を呼び出していない場合は、すぐに戻ります。
+func (s *Scheduler) Wait() {
+	s.lifecycle.mu.Lock()
+	done := s.lifecycle.done
+	s.lifecycle.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Running は、Start で起動したスケジューラーが実行中かどうかを返します。
+func (s *Scheduler) Running() bool {
+	s.lifecycle.mu.Lock()
+	defer s.lifecycle.mu.Unlock()
+	return s.lifecycle.stop != nil
+}
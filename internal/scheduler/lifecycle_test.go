@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestScheduler_StartStop は、Start でバックグラウンドで起動し、Stop で止まるまで待てることをテストします。
+func TestScheduler_StartStop(t *testing.T) {
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-checked // 起動直後のチェック
+	if !s.Running() {
+		t.Error("Start の後は実行中であるべき")
+	}
+	if err := s.Start(context.Background()); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("実行中の Start は ErrAlreadyStarted であるべき: %v", err)
+	}
+
+	if err := s.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if s.Running() {
+		t.Error("Stop の後は実行中でないべき")
+	}
+	s.Wait() // 止まった後はすぐに戻る
+	if err := s.Stop(time.Second); err != nil {
+		t.Errorf("止まった後の Stop は何もしないべき: %v", err)
+	}
+
+	// 止まった後は、もう一度起動できる
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("2回目の Start() error = %v", err)
+	}
+	<-checked
+	if err := s.Stop(0); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := fetcher.GetFetchCount(); got != 2 {
+		t.Errorf("起動するたびに1回チェックするべき: %d", got)
+	}
+}
+
+// TestScheduler_StartContext は、Start に渡した ctx をキャンセルすると止まり、Wait が戻ることをテストします。
+func TestScheduler_StartContext(t *testing.T) {
+	s := NewSchedulerWithProvider(time.Hour, &MockFetcher{}, &MockProvider{}, "home")
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ctx をキャンセルしたら Wait が戻るべき")
+	}
+	if s.Running() {
+		t.Error("ctx をキャンセルした後は実行中でないべき")
+	}
+}
+
+// TestScheduler_StopTimeout は、猶予時間の間に更新が終わらない場合、timeout で中断して ErrStopTimeout を返すことをテストします。
+func TestScheduler_StopTimeout(t *testing.T) {
+	started := make(chan struct{})
+	var updateErr error
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		close(started)
+		<-ctx.Done()
+		updateErr = ctx.Err()
+		return ctx.Err()
+	}}
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetShutdownGrace(time.Hour)
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-started
+	if err := s.Stop(50 * time.Millisecond); !errors.Is(err, ErrStopTimeout) {
+		t.Errorf("時間内に止まらない場合は ErrStopTimeout であるべき: %v", err)
+	}
+	s.Wait()
+	if updateErr == nil {
+		t.Error("timeout を過ぎたら、実行中の更新を中断するべき")
+	}
+}
+
+// TestScheduler_StopTimeout_IgnoresContext は、中断に応じない更新があっても、Stop は timeout で戻り、Wait が止まるまで待つことをテストします。
+func TestScheduler_StopTimeout_IgnoresContext(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		// ctx を無視して、release が閉じられるまで戻らない
+		close(started)
+		<-release
+		return nil
+	}}
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetShutdownGrace(time.Hour)
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(50 * time.Millisecond) }()
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, ErrStopTimeout) {
+			t.Errorf("時間内に止まらない場合は ErrStopTimeout であるべき: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("中断に応じない更新があっても、Stop は timeout で戻るべき")
+	}
+	if !s.Running() {
+		t.Error("中断した更新が戻るまでは、実行中であるべき")
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("中断した更新が戻るまでは、Wait は戻らないべき")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-waited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("中断した更新が戻ったら、Wait は戻るべき")
+	}
+	if s.Running() {
+		t.Error("Wait が戻った後は、実行中でないべき")
+	}
+}
+
+// TestScheduler_StartInvalidInterval は、実行間隔が 0 以下の場合は起動しないことをテストします。
+func TestScheduler_StartInvalidInterval(t *testing.T) {
+	s := NewSchedulerWithProvider(0, &MockFetcher{}, &MockProvider{}, "home")
+	if err := s.Start(context.Background()); err == nil {
+		t.Error("実行間隔が 0 の場合はエラーになるべき")
+	}
+	if s.Running() {
+		t.Error("起動できなかった場合は実行中でないべき")
+	}
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Notifier は、DNS レコードの更新と、チェックや更新の失敗を知らせる先（メール、チャット、Webhook など）のインターフェースです。
+// 自動検出のモードで、IP取得ソースのアドレスと検出したアドレスが続けて一致しない場合（Result.Mismatch）も知らせます。
+// SetFailureAlert を呼び出した場合は、続けて失敗したこと（Result.Failing）と、そのあと復旧したこと（Result.Recovered）も知らせます。
+// 変更がなかったチェックでは呼び出されません。すべての結果を残す場合は Recorder を使用します。
+type Notifier interface {
+	// Notify は、更新した、または失敗した更新先の結果を知らせます
+	// 知らせるのに時間がかかる場合は、チェックと更新を止めないように、Notify の中でゴルーチンを使ってください
+	//
+	// Parameters:
+	//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+	//   - r: 更新先の結果
+	//
+	// Returns:
+	//   - error: 知らせられなかった場合（ログに出力して、チェックと更新は続けます）
+	Notify(ctx context.Context, r Result) error
+}
+
+// SetNotifier は、更新と失敗を知らせる Notifier を設定します。
+// Run または RunOnce の前に呼び出してください。
+func (s *Scheduler) SetNotifier(n Notifier) {
+	s.notifier = n
+}
+
+// notify は、更新した、または失敗した更新先の結果を Notifier に知らせます。
+// キャンセルによる中断は知らせません。
+func (s *Scheduler) notify(ctx context.Context, results []Result) {
+	if s.notifier == nil || ctx.Err() != nil {
+		return
+	}
+	for _, r := range results {
+		if r.Err == nil && !r.Updated && !r.UpdatedIPv6 && r.Mismatch == nil && !r.Recovered {
+			continue
+		}
+		var err error
+		if perr := s.protect(PanicInNotifier, func() { err = s.notifier.Notify(ctx, r) }); perr != nil {
+			err = perr
+		}
+		if err != nil {
+			slog.Warn("更新の結果を知らせられませんでした",
+				"domain", r.Domain,
+				"provider", r.Provider,
+				"error", err,
+			)
+		}
+	}
+}
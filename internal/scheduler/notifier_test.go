@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockNotifier は、知らせられた結果を記録するテスト用の Notifier です。
+type mockNotifier struct {
+	results []Result
+	err     error
+}
+
+// Notify は、結果をスライスに追加します。
+func (m *mockNotifier) Notify(ctx context.Context, r Result) error {
+	m.results = append(m.results, r)
+	return m.err
+}
+
+// TestScheduler_Notifier は、更新と失敗だけを知らせ、変更がないチェックは知らせないことをテストします。
+func TestScheduler_Notifier(t *testing.T) {
+	var fail atomic.Bool
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		if fail.Load() {
+			return "", errors.New("fetch failed")
+		}
+		return "192.0.2.1", nil
+	}}
+	notifier := &mockNotifier{err: errors.New("送信に失敗")}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetNotifier(notifier)
+
+	_ = s.RunOnce(context.Background()) // 更新
+	_ = s.RunOnce(context.Background()) // 変更なし
+	fail.Store(true)
+	_ = s.RunOnce(context.Background()) // 失敗
+
+	if len(notifier.results) != 2 {
+		t.Fatalf("更新と失敗の2件を知らせるべき: %+v", notifier.results)
+	}
+	if r := notifier.results[0]; !r.Updated || r.NewIP != "192.0.2.1" || r.Domain != "home" {
+		t.Errorf("1件目は更新であるべき: %+v", r)
+	}
+	if r := notifier.results[1]; r.Err == nil {
+		t.Errorf("2件目は失敗であるべき: %+v", r)
+	}
+
+	// キャンセルされたチェックは知らせない
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = s.RunOnce(ctx)
+	if len(notifier.results) != 2 {
+		t.Errorf("キャンセル時は知らせないべき: %d", len(notifier.results))
+	}
+}
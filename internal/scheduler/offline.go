@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/provider"
+)
+
+// DefaultOfflineRetryInterval は、SetOfflineRetry の間隔を省略した場合の値です。
+const DefaultOfflineRetryInterval = 30 * time.Second
+
+// Prober は、ネットワークにつながっているかどうかを確認するインターフェースです（netcheck.Prober など）。
+type Prober interface {
+	// Probe は、つながっている場合に nil を返します
+	Probe(ctx context.Context) error
+}
+
+// pendingUpdate は、IPアドレスの変更を検知したものの、通信できずに更新できなかった更新です。
+type pendingUpdate struct {
+	// fetch と fetchIPv6 は、更新しようとしたIPアドレスの取得結果です
+	fetch, fetchIPv6 ip.FetchResult
+
+	// since は、更新を保留にした時刻です
+	since time.Time
+}
+
+// SetOfflineRetry は、通信できずに更新できなかった場合に、次の定期チェックを待たずに再試行するようにします。
+// 保留中の更新がある間は interval ごとに p でネットワークにつながったかどうかを確認し、
+// つながったら IPアドレスを取得し直さずに、保留中のIPアドレスで更新します。
+// Run の前に呼び出してください。
+//
+// Parameters:
+//   - p: ネットワークにつながったかどうかを確認する Prober
+//   - interval: 確認する間隔（0 以下の場合は DefaultOfflineRetryInterval）
+func (s *Scheduler) SetOfflineRetry(p Prober, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultOfflineRetryInterval
+	}
+	s.prober = p
+	s.offlineRetry = interval
+}
+
+// RestorePending は、前回の実行で保存した保留中の更新を復元します。
+// 起動直後に IPアドレスを取得できなくても、ネットワークにつながりしだいこのIPアドレスで更新を再試行します。
+// 最初のチェックでIPアドレスを取得できた場合は、そちらを使います。Run の前に呼び出してください。
+//
+// Parameters:
+//   - ipv4: 更新できていない IPv4アドレス
+//   - since: 更新を保留にした時刻
+func (s *Scheduler) RestorePending(ipv4 string, since time.Time) {
+	if ipv4 == "" {
+		return
+	}
+	s.pending = &pendingUpdate{fetch: ip.FetchResult{IP: ipv4}, since: since}
+}
+
+// retryAfter は、保留中の更新がある場合に、次に再試行するまで待つチャネルを返します。
+// 再試行しない場合は nil を返します（select で選ばれることはありません）。
+func (s *Scheduler) retryAfter() <-chan time.Time {
+	if s.pending == nil || s.prober == nil {
+		return nil
+	}
+	return s.clock.After(s.offlineRetry)
+}
+
+// retryPending は、ネットワークにつながっていれば、保留中の更新を再試行します。
+func (s *Scheduler) retryPending(ctx context.Context) {
+	p := s.pending
+	if p == nil || s.prober == nil {
+		return
+	}
+	if err := s.prober.Probe(ctx); err != nil {
+		slog.Debug("まだネットワークにつながっていないため、保留中の更新を待ちます",
+			"domains", s.Domains(),
+			"error", err,
+		)
+		return
+	}
+
+	slog.Info("ネットワークにつながったため、保留中の更新を再試行します",
+		"ip", p.fetch.IP,
+		"ipv6", p.fetchIPv6.IP,
+		"pending_since", p.since,
+		"domains", s.Domains(),
+	)
+	start := s.clock.Now()
+	results := s.newResults()
+	for i := range results {
+		results[i].Fetch = p.fetch
+		results[i].FetchIPv6 = p.fetchIPv6
+	}
+	s.updateFetched(ctx, start, p.fetch, p.fetchIPv6, nil, results)
+}
+
+// updatePending は、更新の結果から保留中の更新を決めます。
+// 登録できていない更新先がある場合は保留にし、すべて登録できた場合は保留をなくします。
+// 更新を拒否された（トークンの誤りなど）更新先は、再試行しても結果が変わらないため保留にしません。
+func (s *Scheduler) updatePending(ctx context.Context, fetched, fetchedIPv6 ip.FetchResult, results []Result) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	var pending []string
+	for i, t := range s.targets {
+		if t.lastIP == fetched.IP && (fetchedIPv6.IP == "" || t.lastIPv6 == fetchedIPv6.IP) {
+			continue
+		}
+		if errors.Is(results[i].Err, provider.ErrRejected) {
+			continue
+		}
+		pending = append(pending, t.provider.Name()+":"+t.domain)
+	}
+
+	if len(pending) == 0 {
+		if s.pending != nil && s.prober != nil {
+			slog.Info("保留中の更新がなくなりました", "domains", s.Domains())
+		}
+		s.pending = nil
+		return
+	}
+
+	since := s.clock.Now()
+	if s.pending != nil && s.pending.fetch.IP == fetched.IP && s.pending.fetchIPv6.IP == fetchedIPv6.IP {
+		since = s.pending.since
+	}
+	s.pending = &pendingUpdate{fetch: fetched, fetchIPv6: fetchedIPv6, since: since}
+	if s.prober != nil {
+		slog.Warn("更新できなかった更新先を保留にして、ネットワークにつながりしだい再試行します",
+			"ip", fetched.IP,
+			"ipv6", fetchedIPv6.IP,
+			"pending", pending,
+			"retry_interval", s.offlineRetry,
+		)
+	}
+}
@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/netcheck"
+	"github.com/horitaku/duckdns/internal/provider"
+)
+
+// mockProber は、テスト用の Prober モックです。
+type mockProber struct {
+	// failures は、つながっていないと答える残りの回数です
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+// Probe は mockProber の Probe メソッドを実装します。
+func (m *mockProber) Probe(ctx context.Context) error {
+	m.calls.Add(1)
+	if m.failures.Add(-1) >= 0 {
+		return errors.New("network down")
+	}
+	return nil
+}
+
+// recordingProvider は、更新を試みたIPアドレスを記録し、最初の fail 回は失敗するテスト用の Provider です。
+type recordingProvider struct {
+	mu   sync.Mutex
+	ips  []string
+	fail int
+	err  error
+}
+
+func (p *recordingProvider) Name() string { return "mock" }
+
+func (p *recordingProvider) Update(ctx context.Context, domain, ip string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ips = append(p.ips, ip)
+	if len(p.ips) <= p.fail {
+		return p.err
+	}
+	return nil
+}
+
+func (p *recordingProvider) updates() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.ips...)
+}
+
+// runUntil は、cond が true になるまで（最大2秒）スケジューラーを実行します。
+func runUntil(t *testing.T, s *Scheduler, cond func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+// TestScheduler_OfflineRetry は、通信できずに更新できなかった場合に、ネットワークにつながりしだい
+// IPアドレスを取得し直さずに再試行することをテストします。
+func TestScheduler_OfflineRetry(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	p := &recordingProvider{fail: 1, err: fmt.Errorf("接続できません")}
+	prober := &mockProber{}
+	prober.failures.Store(2)
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetOfflineRetry(prober, time.Millisecond)
+	runUntil(t, s, func() bool { return len(p.updates()) >= 2 })
+
+	if got := p.updates(); len(got) != 2 || got[1] != "192.0.2.1" {
+		t.Fatalf("つながったら保留中のIPアドレスで再試行するべき: %v", got)
+	}
+	if got := prober.calls.Load(); got != 3 {
+		t.Errorf("つながるまで確認を繰り返すべき。期待: 3, 実際: %d", got)
+	}
+	if got := fetcher.GetFetchCount(); got != 1 {
+		t.Errorf("再試行では IPアドレスを取得し直さないべき。実際の取得回数: %d", got)
+	}
+	if s.pending != nil {
+		t.Error("更新できたら保留中の更新はなくなるべき")
+	}
+}
+
+// TestScheduler_OfflineRetry_Rejected は、更新を拒否された場合は保留にしないことをテストします。
+func TestScheduler_OfflineRetry_Rejected(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	p := &recordingProvider{fail: 1, err: fmt.Errorf("%w: KO", provider.ErrRejected)}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetOfflineRetry(&mockProber{}, time.Millisecond)
+	s.checkAndUpdate(context.Background())
+
+	if s.pending != nil {
+		t.Error("更新を拒否された場合は保留にしないべき")
+	}
+	if s.retryAfter() != nil {
+		t.Error("保留中の更新がない場合は再試行しないべき")
+	}
+}
+
+// TestScheduler_RestorePending は、復元した保留中の更新を、IPアドレスを取得できない間も再試行することをテストします。
+func TestScheduler_RestorePending(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "", errors.New("fetch failed") }}
+	p := &recordingProvider{}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetOfflineRetry(&mockProber{}, time.Millisecond)
+	s.RestorePending("192.0.2.9", time.Now().Add(-time.Hour))
+	runUntil(t, s, func() bool { return len(p.updates()) >= 1 })
+
+	if got := p.updates(); len(got) != 1 || got[0] != "192.0.2.9" {
+		t.Errorf("復元したIPアドレスで更新するべき: %v", got)
+	}
+}
+
+// TestScheduler_OfflineRetry_Disabled は、SetOfflineRetry を呼ばない場合は再試行しないことをテストします。
+func TestScheduler_OfflineRetry_Disabled(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	p := &recordingProvider{fail: 1, err: errors.New("接続できません")}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.checkAndUpdate(context.Background())
+	if s.retryAfter() != nil {
+		t.Error("SetOfflineRetry を呼ばない場合は再試行しないべき")
+	}
+}
+
+// TestScheduler_Precheck は、接続を確認できない場合に IPアドレスを取得せずにオフラインとして終えることをテストします。
+func TestScheduler_Precheck(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	p := &recordingProvider{}
+	prober := &mockProber{}
+	prober.failures.Store(1)
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetPrecheck(prober)
+
+	results := s.CheckOnce(context.Background())
+	if len(results) != 1 || !results[0].Offline || !errors.Is(results[0].Err, netcheck.ErrOffline) {
+		t.Fatalf("オフラインとして終えるべき: %+v", results)
+	}
+	if fetcher.GetFetchCount() != 0 || len(p.updates()) != 0 {
+		t.Error("オフラインの場合は IPアドレスの取得と更新をしないべき")
+	}
+
+	results = s.CheckOnce(context.Background())
+	if results[0].Offline || results[0].Err != nil || !results[0].Updated {
+		t.Errorf("つながったら通常どおりチェックするべき: %+v", results[0])
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+// DefaultWorkers は、Pool の同時に実行する処理の数を省略した場合の値です。
+const DefaultWorkers = 8
+
+// Pool は、複数のスケジューラーで共有する、IPアドレスの取得とプロバイダーの更新を同時に実行する数の上限です。
+// ドメインが数十ある場合でも、小さな機器で goroutine や接続が増えすぎないようにします。
+// 上限に達している間は、空くまで待ってから実行するため、遅いドメインがほかのドメインをすべて止めることはありません。
+type Pool struct {
+	// slots は、実行中の処理の数だけ埋まるセマフォです
+	slots chan struct{}
+}
+
+// NewPool は、同時に workers 個まで処理を実行する Pool を作成します。
+//
+// Parameters:
+//   - workers: 同時に実行する処理の数（0 以下の場合は DefaultWorkers）
+//
+// Returns:
+//   - *Pool: 作成された Pool
+func NewPool(workers int) *Pool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Pool{slots: make(chan struct{}, workers)}
+}
+
+// Size は、同時に実行する処理の数の上限を返します。
+func (p *Pool) Size() int {
+	return cap(p.slots)
+}
+
+// run は、空きができるまで待ってから fn を実行します（nil の Pool の場合はすぐに実行します）。
+// run の中で run を呼び出すと上限によっては終わらなくなるため、fn の中では使わないでください。
+func (p *Pool) run(fn func()) {
+	if p == nil {
+		fn()
+		return
+	}
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+	fn()
+}
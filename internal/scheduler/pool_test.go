@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyTracker は、同時に実行している処理の数の最大値を記録します。
+type concurrencyTracker struct {
+	running atomic.Int32
+	max     atomic.Int32
+}
+
+// enter は、処理の開始を記録し、少し待ってから終了を記録します。
+func (c *concurrencyTracker) enter() {
+	n := c.running.Add(1)
+	for {
+		m := c.max.Load()
+		if n <= m || c.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.running.Add(-1)
+}
+
+// TestNewPool は、同時に実行する数を省略した場合に DefaultWorkers になることをテストします。
+func TestNewPool(t *testing.T) {
+	if size := NewPool(0).Size(); size != DefaultWorkers {
+		t.Errorf("NewPool(0).Size() = %d, 期待値 %d", size, DefaultWorkers)
+	}
+	if size := NewPool(3).Size(); size != 3 {
+		t.Errorf("NewPool(3).Size() = %d, 期待値 3", size)
+	}
+}
+
+// TestPool_Run は、同時に実行する処理の数が上限を超えないことをテストします。
+func TestPool_Run(t *testing.T) {
+	pool := NewPool(2)
+	var tracker concurrencyTracker
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(tracker.enter)
+		}()
+	}
+	wg.Wait()
+
+	if got := tracker.max.Load(); got != 2 {
+		t.Errorf("同時に実行した数の最大値 = %d, 期待値 2", got)
+	}
+
+	// nil の Pool は制限せずに実行する
+	var called bool
+	(*Pool)(nil).run(func() { called = true })
+	if !called {
+		t.Error("nil の Pool でも実行されるべき")
+	}
+}
+
+// TestScheduler_Pool は、複数のスケジューラーで共有した Pool で、すべてのドメインの更新を同時に実行する数を制限することをテストします。
+func TestScheduler_Pool(t *testing.T) {
+	pool := NewPool(3)
+	var tracker concurrencyTracker
+	var updated atomic.Int32
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		tracker.enter()
+		updated.Add(1)
+		return nil
+	}}
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+
+	schedulers := make([]*Scheduler, 2)
+	for i := range schedulers {
+		s := NewSchedulerWithProvider(time.Minute, fetcher, p, fmt.Sprintf("domain-%d-0", i))
+		for j := 1; j < 10; j++ {
+			s.AddTarget(p, fmt.Sprintf("domain-%d-%d", i, j))
+		}
+		s.SetPool(pool)
+		schedulers[i] = s
+	}
+
+	results := CheckAllOnce(context.Background(), schedulers)
+	if len(results) != 20 || updated.Load() != 20 {
+		t.Fatalf("結果の数 = %d, 更新した数 = %d; 期待値 20", len(results), updated.Load())
+	}
+	for _, r := range results {
+		if r.Err != nil || !r.Updated {
+			t.Errorf("%s が更新されていません: %+v", r.Domain, r)
+		}
+	}
+	if got := tracker.max.Load(); got > 3 {
+		t.Errorf("同時に更新した数の最大値 = %d, 上限 3 を超えています", got)
+	}
+}
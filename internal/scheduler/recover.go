@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// ErrPanic は、チェックと更新の途中でパニックが発生したことを表すエラーです。
+// プロバイダーの更新や IPアドレスの取得でパニックが発生した場合は、その更新先の Result.Err に入ります。
+var ErrPanic = errors.New("チェックと更新の途中でパニックが発生しました")
+
+// パニックが発生した場所（PanicReport.Where）です。
+const (
+	// PanicInLoop は、定期チェックのループでパニックが発生したことを表します
+	PanicInLoop = "loop"
+
+	// PanicInFetch は、IPアドレスの取得でパニックが発生したことを表します
+	PanicInFetch = "fetch"
+
+	// PanicInUpdate は、プロバイダーの更新でパニックが発生したことを表します
+	PanicInUpdate = "update"
+
+	// PanicInRecorder は、Recorder でパニックが発生したことを表します
+	PanicInRecorder = "recorder"
+
+	// PanicInNotifier は、Notifier でパニックが発生したことを表します
+	PanicInNotifier = "notifier"
+)
+
+// PanicReport は、チェックと更新の途中で発生したパニックの内容です。
+type PanicReport struct {
+	// Time は、パニックから回復した時刻です
+	Time time.Time
+
+	// Where は、パニックが発生した場所です（PanicInLoop など）
+	Where string
+
+	// Value は、panic に渡された値です
+	Value any
+
+	// Stack は、パニックが発生したゴルーチンのスタックトレースです
+	Stack []byte
+
+	// Domains は、パニックが発生したスケジューラーの更新先のドメイン名です
+	Domains []string
+}
+
+// PanicHandler は、パニックから回復したときに、クラッシュレポートを書き出すなどの処理をする関数です。
+// 同時に複数のゴルーチンから呼び出されることがあります。
+type PanicHandler func(r PanicReport)
+
+// SetPanicHandler は、チェックと更新の途中で発生したパニックから回復したときに呼び出す関数を設定します。
+// 設定しない場合も、パニックから回復してエラーのログ（スタックトレース）を出力します。
+// Run または RunOnce の前に呼び出してください。
+func (s *Scheduler) SetPanicHandler(h PanicHandler) {
+	s.panicHandler = h
+}
+
+// protect は、fn を実行し、パニックが発生した場合は回復して、ErrPanic と判定できるエラーを返します（内部用ヘルパー関数）
+// 回復したときは、エラーのログを出力し、SetPanicHandler の関数を呼び出します。
+func (s *Scheduler) protect(where string, fn func()) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		err = fmt.Errorf("%w (%s): %v", ErrPanic, where, v)
+		report := PanicReport{
+			Time:    s.clock.Now(),
+			Where:   where,
+			Value:   v,
+			Stack:   debug.Stack(),
+			Domains: s.Domains(),
+		}
+		slog.Error("パニックから回復しました",
+			"where", where,
+			"panic", v,
+			"domains", report.Domains,
+			"stack", string(report.Stack),
+		)
+		if s.panicHandler != nil {
+			s.panicHandler(report)
+		}
+	}()
+	fn()
+	return nil
+}
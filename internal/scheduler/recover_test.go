@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// panicRecorder は、SetPanicHandler に渡されたクラッシュレポートを記録します。
+type panicRecorder struct {
+	mu      sync.Mutex
+	reports []PanicReport
+}
+
+// handle は、PanicHandler としてクラッシュレポートを記録します。
+func (p *panicRecorder) handle(r PanicReport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reports = append(p.reports, r)
+}
+
+// get は、記録したクラッシュレポートを返します。
+func (p *panicRecorder) get() []PanicReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PanicReport(nil), p.reports...)
+}
+
+// panicNotifier は、知らせるたびにパニックを起こすテスト用の Notifier です。
+type panicNotifier struct{}
+
+// Notify は、パニックを起こします。
+func (panicNotifier) Notify(ctx context.Context, r Result) error {
+	panic("notifier が壊れています")
+}
+
+// TestScheduler_PanicInUpdate は、プロバイダーの更新でのパニックから回復して、その更新先のエラーにすることをテストします。
+func TestScheduler_PanicInUpdate(t *testing.T) {
+	var panicked atomic.Bool
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		if !panicked.Swap(true) {
+			panic("provider が壊れています")
+		}
+		return nil
+	}}
+	handler := &panicRecorder{}
+	s := NewSchedulerWithProvider(time.Hour, &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return "192.0.2.1", nil
+	}}, p, "home")
+	s.SetPanicHandler(handler.handle)
+	s.SetNotifier(panicNotifier{})
+
+	r := s.CheckOnce(context.Background())[0]
+	if !errors.Is(r.Err, ErrPanic) || r.Updated {
+		t.Errorf("パニックは更新先のエラーにするべき: %+v", r)
+	}
+	reports := handler.get()
+	if len(reports) != 2 || reports[0].Where != PanicInUpdate || reports[1].Where != PanicInNotifier {
+		t.Fatalf("更新と Notifier のパニックを知らせるべき: %+v", reports)
+	}
+	if reports[0].Value != "provider が壊れています" || len(reports[0].Stack) == 0 || reports[0].Domains[0] != "home" {
+		t.Errorf("クラッシュレポートにはパニックの値とスタックトレースを入れるべき: %+v", reports[0])
+	}
+
+	// 次のチェックでは更新し直す
+	if r := s.CheckOnce(context.Background())[0]; r.Err != nil || !r.Updated {
+		t.Errorf("パニックの後も更新を続けるべき: %+v", r)
+	}
+}
+
+// TestScheduler_PanicInLoop は、ループでのパニックから回復して、定期チェックの間隔だけ待ってから始め直すことをテストします。
+func TestScheduler_PanicInLoop(t *testing.T) {
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{}
+	fetcher.FetchFunc = func(ctx context.Context) (string, error) {
+		if fetcher.GetFetchCount() == 1 {
+			panic("fetcher が壊れています")
+		}
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}
+	handler := &panicRecorder{}
+	s := NewSchedulerWithProvider(20*time.Millisecond, fetcher, &MockProvider{}, "home")
+	s.SetPanicHandler(handler.handle)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-checked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("パニックの後もループを始め直してチェックするべき")
+	}
+	cancel()
+	<-done
+
+	if reports := handler.get(); len(reports) != 1 || reports[0].Where != PanicInLoop {
+		t.Errorf("ループのパニックを知らせるべき: %+v", reports)
+	}
+}
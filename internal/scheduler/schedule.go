@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Window は、1日のうちの時間帯です（Start から End の直前まで）。
+// End が Start より前の場合は、日付をまたぐ時間帯（例: 22:00〜06:00）です。
+// 時間帯は時計の表示（壁時計の時刻）で判定するため、夏時間に切り替わる日は実際の長さが変わります。
+// 時計が進む日は飛ばされた時刻の時間帯が短くなり（なくなることもあります）、時計が戻る日は重なった時刻の時間帯が長くなります。
+type Window struct {
+	// Start は、時間帯の始まりの、0時からの時間です
+	Start time.Duration
+
+	// End は、時間帯の終わりの、0時からの時間です（24時間まで）
+	End time.Duration
+}
+
+// Contains は、t の時計の表示が時間帯に含まれる場合に true を返します。
+// t は、判定するタイムゾーンに変換してから渡してください。
+//
+// Parameters:
+//   - t: 判定する時刻
+//
+// Returns:
+//   - bool: 時間帯に含まれる場合に true
+func (w Window) Contains(t time.Time) bool {
+	clock := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return w.Start <= clock && clock < w.End
+	}
+	return clock >= w.Start || clock < w.End
+}
+
+// String は、時間帯を "HH:MM-HH:MM" の形式で返します。
+func (w Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d",
+		int(w.Start/time.Hour), int(w.Start%time.Hour/time.Minute),
+		int(w.End/time.Hour), int(w.End%time.Hour/time.Minute))
+}
+
+// schedule は、定期チェックを実行する時間帯と、実行しない時間帯の設定です。
+type schedule struct {
+	// windows は、定期チェックを実行する時間帯です（空の場合は終日）
+	windows []Window
+
+	// quiet は、定期チェックを実行しない時間帯です（windows より優先します）
+	quiet []Window
+
+	// loc は、時間帯を判定するタイムゾーンです（nil の場合は time.Local）
+	loc *time.Location
+}
+
+// SetSchedule は、定期チェックを実行する時間帯（windows）と、実行しない時間帯（quiet）を設定します。
+// windows を指定した場合は、どれかの時間帯に入っている間だけ定期チェックを実行し、
+// quiet のどれかの時間帯に入っている間は、windows に関係なく定期チェックをスキップします。
+// 時間帯は、loc のタイムゾーンの時計の表示で判定します。ホストのタイムゾーンが UTC でも、設定したタイムゾーンの時刻で止められます。
+// 起動直後のチェック、Trigger、保留中の更新の再試行は、時間帯に関係なく実行します。
+// Run の前に呼び出してください。
+//
+// Parameters:
+//   - windows: 定期チェックを実行する時間帯（空の場合は終日）
+//   - quiet: 定期チェックを実行しない時間帯
+//   - loc: 時間帯を判定するタイムゾーン（nil の場合は time.Local）
+func (s *Scheduler) SetSchedule(windows, quiet []Window, loc *time.Location) {
+	s.schedule = schedule{windows: windows, quiet: quiet, loc: loc}
+}
+
+// allows は、now に定期チェックを実行してよい場合に true を返します（内部用ヘルパー関数）
+func (sc schedule) allows(now time.Time) bool {
+	loc := sc.loc
+	if loc == nil {
+		loc = time.Local
+	}
+	local := now.In(loc)
+	for _, w := range sc.quiet {
+		if w.Contains(local) {
+			return false
+		}
+	}
+	if len(sc.windows) == 0 {
+		return true
+	}
+	for _, w := range sc.windows {
+		if w.Contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduled は、今が定期チェックを実行する時間帯の場合に true を返します（内部用ヘルパー関数）
+// 実行しない時間帯の場合は、スキップしたことをログに出力します。
+func (s *Scheduler) scheduled() bool {
+	if s.schedule.allows(s.clock.Now()) {
+		return true
+	}
+	slog.Debug("定期チェックを実行しない時間帯なので、スキップします",
+		"domains", s.Domains(),
+	)
+	return false
+}
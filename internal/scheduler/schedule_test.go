@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// loadLocation は、テストで使うタイムゾーンを読み込みます。
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("タイムゾーン %s を読み込めません: %v", name, err)
+	}
+	return loc
+}
+
+// TestWindow_Contains は、時間帯の始まりを含み、終わりを含まないこと、日付をまたぐ時間帯を判定できることをテストします。
+func TestWindow_Contains(t *testing.T) {
+	day := Window{Start: 9 * time.Hour, End: 17 * time.Hour}
+	night := Window{Start: 22 * time.Hour, End: 6 * time.Hour}
+	allDay := Window{Start: 0, End: 24 * time.Hour}
+
+	tests := []struct {
+		name   string
+		window Window
+		hour   int
+		minute int
+		want   bool
+	}{
+		{name: "始まりの時刻は含む", window: day, hour: 9, minute: 0, want: true},
+		{name: "終わりの時刻は含まない", window: day, hour: 17, minute: 0, want: false},
+		{name: "時間帯の前", window: day, hour: 8, minute: 59, want: false},
+		{name: "日付をまたぐ時間帯の夜", window: night, hour: 23, minute: 30, want: true},
+		{name: "日付をまたぐ時間帯の朝", window: night, hour: 5, minute: 59, want: true},
+		{name: "日付をまたぐ時間帯の外", window: night, hour: 12, minute: 0, want: false},
+		{name: "終日", window: allDay, hour: 23, minute: 59, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, tt.hour, tt.minute, 0, 0, time.UTC)
+			if got := tt.window.Contains(now); got != tt.want {
+				t.Errorf("Contains(%s) = %v, %v であるべき", now.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSchedule_Location は、時間帯をホストのタイムゾーンではなく、指定したタイムゾーンで判定することをテストします。
+func TestSchedule_Location(t *testing.T) {
+	tokyo := loadLocation(t, "Asia/Tokyo")
+	// 東京の 0:00〜6:00 は、UTC の前日 15:00〜21:00
+	sc := schedule{quiet: []Window{{Start: 0, End: 6 * time.Hour}}, loc: tokyo}
+
+	if sc.allows(time.Date(2026, 1, 1, 16, 0, 0, 0, time.UTC)) {
+		t.Error("UTC 16:00（東京 1:00）は実行しない時間帯であるべき")
+	}
+	if !sc.allows(time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)) {
+		t.Error("UTC 3:00（東京 12:00）は実行する時間帯であるべき")
+	}
+
+	windows := schedule{windows: []Window{{Start: 9 * time.Hour, End: 17 * time.Hour}}, loc: tokyo}
+	if !windows.allows(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Error("UTC 1:00（東京 10:00）は実行する時間帯であるべき")
+	}
+	if windows.allows(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Error("UTC 10:00（東京 19:00）は実行する時間帯の外であるべき")
+	}
+}
+
+// TestSchedule_DST は、夏時間に切り替わる日も、時間帯をそのタイムゾーンの時計の表示で判定することをテストします。
+func TestSchedule_DST(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+
+	t.Run("時計が進む日", func(t *testing.T) {
+		// 2026-03-08 2:00 EST（UTC 7:00）に 3:00 EDT へ進む
+		sc := schedule{quiet: []Window{{Start: 90 * time.Minute, End: 150 * time.Minute}}, loc: newYork}
+		if sc.allows(time.Date(2026, 3, 8, 6, 45, 0, 0, time.UTC)) {
+			t.Error("1:45 EST は実行しない時間帯であるべき")
+		}
+		if !sc.allows(time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)) {
+			t.Error("切り替わった直後の 3:00 EDT は実行する時間帯であるべき")
+		}
+
+		// 飛ばされる 2:00〜3:00 だけの時間帯は、その日は一度も入らない
+		skipped := schedule{quiet: []Window{{Start: 2 * time.Hour, End: 3 * time.Hour}}, loc: newYork}
+		for now := time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC); now.Before(time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)); now = now.Add(time.Minute) {
+			if !skipped.allows(now) {
+				t.Fatalf("存在しない時刻の時間帯に %s で入るべきではない", now.In(newYork).Format(time.RFC3339))
+			}
+		}
+	})
+
+	t.Run("時計が戻る日", func(t *testing.T) {
+		// 2026-11-01 2:00 EDT（UTC 6:00）に 1:00 EST へ戻るため、1:00〜2:00 は2回ある
+		sc := schedule{quiet: []Window{{Start: time.Hour, End: 2 * time.Hour}}, loc: newYork}
+		quiet := 0
+		for now := time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC); now.Before(time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC)); now = now.Add(time.Minute) {
+			if !sc.allows(now) {
+				quiet++
+			}
+		}
+		if quiet != 120 {
+			t.Errorf("重なった 1:00〜2:00 は2回とも実行しない時間帯であるべき: %d 分", quiet)
+		}
+	})
+}
+
+// TestScheduler_SetSchedule は、実行しない時間帯の定期チェックをスキップし、時間帯を抜けたら再開することをテストします。
+// 夏時間が終わって時計が戻る日に、重なった時刻の間も止まったままになることを確かめます。
+func TestScheduler_SetSchedule(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	// UTC 4:30 は 0:30 EDT。1:00〜2:00 の時間帯は、UTC 5:00〜7:00 の2時間になる
+	clock := &fakeClock{now: time.Date(2026, 11, 1, 4, 30, 0, 0, time.UTC)}
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(30*time.Minute, fetcher, &MockProvider{}, "home")
+	s.SetClock(clock)
+	s.SetSchedule(nil, []Window{{Start: time.Hour, End: 2 * time.Hour}}, newYork)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	<-checked // 起動直後のチェックは時間帯に関係なく実行する
+	for clock.tickerCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// UTC 5:00, 5:30, 6:00, 6:30（1:00 EDT〜1:30 EST）は実行しない時間帯
+	for i := 0; i < 4; i++ {
+		clock.Advance(30 * time.Minute)
+		for len(clock.tickers[0].c) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// Trigger は時間帯に関係なく実行し、それまでに届いた定期チェックを処理し終えたことも確かめられる
+	if _, err := s.Trigger(ctx, "192.0.2.1", ""); err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+	if got := fetcher.GetFetchCount(); got != 1 {
+		t.Errorf("実行しない時間帯の定期チェックはスキップするべき: %d 回", got)
+	}
+
+	// UTC 7:00（2:00 EST）で時間帯を抜ける
+	clock.Advance(30 * time.Minute)
+	select {
+	case <-checked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("時間帯を抜けたら定期チェックを再開するべき")
+	}
+	cancel()
+	<-done
+}
@@ -1,18 +1,112 @@
 // Package scheduler は、DuckDNSのDNSレコードを定期的に更新するスケジューラーを提供します。
 // グローバルIPアドレスの変更を監視し、変更があった場合にDuckDNSを自動更新します。
+// DuckDNS 以外のプロバイダーも provider.Provider を渡すことで同じように更新できます。
 package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/horitaku/duckdns/internal/duckdns"
 	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/netcheck"
+	"github.com/horitaku/duckdns/internal/provider"
 )
 
+// Recorder は、チェックと更新の結果を記録するインターフェースです。
+// 状態ファイルへの保存など、結果を外部に残す場合に使用します。
+type Recorder interface {
+	// RecordResult は、1回のチェックの結果を記録します
+	// source は IPアドレスを返した IP取得ソースの URL（わからない場合は空）、
+	// updated は DuckDNS を更新した場合に true、err は失敗した場合のエラーです
+	RecordResult(domain, ip, source string, updated bool, err error)
+}
+
+// Result は、1回のチェックと更新の結果です（更新先のプロバイダーとドメインごと）
+type Result struct {
+	// Domain は、更新先のドメイン名です
+	Domain string
+
+	// Provider は、更新先のプロバイダーの名前です（例: "duckdns", "cloudflare"）
+	Provider string
+
+	// OldIP は、チェック前に登録済みとみなしていたIPアドレスです（起動後の初回は空）
+	OldIP string
+
+	// NewIP は、取得したIPアドレスです（取得に失敗した場合は空）
+	NewIP string
+
+	// OldIPv6 は、チェック前に登録済みとみなしていたIPv6アドレスです（IPv6 を更新しない場合は空）
+	OldIPv6 string
+
+	// NewIPv6 は、取得したIPv6アドレスです（IPv6 を更新しない場合、または取得に失敗した場合は空）
+	NewIPv6 string
+
+	// Updated は、プロバイダーの IPv4 のレコード（A）を更新した場合に true です
+	Updated bool
+
+	// UpdatedIPv6 は、プロバイダーの IPv6 のレコード（AAAA）を更新した場合に true です
+	UpdatedIPv6 bool
+
+	// IPv6Err は、IPv6アドレスの取得または AAAA の更新に失敗した場合のエラーです（Err にも含まれます）
+	IPv6Err error
+
+	// Fetch は、IPv4アドレスの取得の詳細な結果です（応答したソースや応答時間など）
+	Fetch ip.FetchResult
+
+	// FetchIPv6 は、IPv6アドレスの取得の詳細な結果です（IPv6 を更新しない場合はゼロ値）
+	FetchIPv6 ip.FetchResult
+
+	// Duration は、チェックと更新にかかった時間です
+	Duration time.Duration
+
+	// Err は、IPアドレスの取得またはプロバイダーの更新に失敗した場合のエラーです
+	// IPv6 だけが失敗した場合も、IPv6Err と同じエラーが入ります
+	Err error
+
+	// Mismatch は、自動検出のモードで、IP取得ソースのアドレスと検出したアドレスが続けて一致しなかった場合の *MismatchError です
+	// 一致しないチェックが SetCrossCheck の回数に達したときだけ入ります。更新の成否には影響しません
+	Mismatch error
+
+	// Failing は、この更新先のチェックと更新に続けて失敗した場合の *FailingError です
+	// 失敗したチェックが SetFailureAlert の回数に達したときだけ入ります
+	Failing error
+
+	// Recovered は、Failing を知らせたあとで、この更新先のチェックと更新に初めて成功した場合に true です
+	Recovered bool
+
+	// Offline は、事前の接続の確認に失敗したため、IPアドレスの取得と更新をしなかった場合に true です
+	// Err には netcheck.ErrOffline と判定できるエラーが入ります
+	Offline bool
+}
+
+// target は、スケジューラーが更新するプロバイダーとドメインの組です。
+type target struct {
+	// provider はDNSレコードを更新するプロバイダーです（DuckDNS など）
+	provider provider.Provider
+
+	// domain は更新するドメイン名です
+	domain string
+
+	// lastIP はこの更新先に最後に登録できたIPアドレスを保持します（変更検知に使用）
+	// 更新に失敗した場合は変わらないため、次回のチェックでこの更新先だけ再度更新します
+	lastIP string
+
+	// lastIPv6 はこの更新先に最後に登録できたIPv6アドレスを保持します（IPv6 を更新する場合）
+	lastIPv6 string
+
+	// failures はこの更新先のチェックと更新に続けて失敗した回数です（SetFailureAlert を呼び出した場合だけ数えます）
+	failures int
+}
+
 // Scheduler は、定期的にIPアドレスをチェックし、DuckDNSを更新する構造体です。
 // IP変更を検知した場合のみ更新を実行することで、不要なAPI呼び出しを削減します。
+// AddTarget で更新先を追加すると、1回取得したIPアドレスを複数のプロバイダーに並行して反映します。
 type Scheduler struct {
 	// interval は更新チェックの実行間隔です
 	interval time.Duration
@@ -20,17 +114,65 @@ type Scheduler struct {
 	// ipFetcher はグローバルIPアドレスを取得するためのインターフェースです
 	ipFetcher ip.Fetcher
 
-	// duckDNSClient はDuckDNS APIへの更新リクエストを行うクライアントです
-	duckDNSClient *duckdns.Client
+	// ipv6Fetcher はIPv6アドレスを取得するためのインターフェースです（nil の場合は IPv6 を更新しません）
+	ipv6Fetcher ip.Fetcher
 
-	// domain はDuckDNSに登録されているドメイン名です
-	domain string
+	// targets は更新先のプロバイダーとドメインの一覧です
+	targets []*target
 
-	// token はDuckDNS APIのアクセストークンです
-	token string
+	// recorder はチェックと更新の結果を記録します（nil の場合は記録しません）
+	recorder Recorder
 
-	// lastIP は前回取得したIPアドレスを保持します（変更検知に使用）
-	lastIP string
+	// pool はIPアドレスの取得とプロバイダーの更新を同時に実行する数の上限です（nil の場合は制限しません）
+	pool *Pool
+
+	// prober は、保留中の更新を再試行する前に、ネットワークにつながったかどうかを確認します（nil の場合は再試行しません）
+	prober Prober
+
+	// offlineRetry は、保留中の更新がある間に、ネットワークにつながったかどうかを確認する間隔です
+	offlineRetry time.Duration
+
+	// pending は、IPアドレスの変更を検知したものの、通信できずに更新できなかった更新です（ない場合は nil）
+	pending *pendingUpdate
+
+	// precheck は、チェックのたびに最初に接続を確認します（nil の場合は確認しません）
+	precheck Prober
+
+	// shutdownGrace は、停止するときに実行中のチェックと更新が終わるまで待つ時間の上限です（0 の場合はすぐに中断します）
+	shutdownGrace time.Duration
+
+	// triggers は、Trigger で求められた、定期チェックを待たないチェックと更新を Run に渡します
+	triggers chan triggerRequest
+
+	// clock は、定期チェックの間隔と所要時間に使う時計です
+	clock Clock
+
+	// notifier は、更新と失敗を知らせます（nil の場合は知らせません）
+	notifier Notifier
+
+	// timeouts は、チェックと更新の段階ごとのタイムアウトです
+	timeouts Timeouts
+
+	// autoDetect は、IP取得ソースに問い合わせずに、プロバイダーにリクエストの送信元のアドレスを登録させるかどうかです
+	autoDetect bool
+
+	// crossCheck は、自動検出のモードで、検出したアドレスを IP取得ソースと照らし合わせる状態です
+	crossCheck crossCheck
+
+	// schedule は、定期チェックを実行する時間帯と、実行しない時間帯です
+	schedule schedule
+
+	// failureAlert は、続けて失敗したことを知らせるまでの回数です（0 の場合は知らせません）
+	failureAlert int
+
+	// watchdog は、チェックと更新が終わらなくなったループを見張る状態です
+	watchdog watchdog
+
+	// panicHandler は、パニックから回復したときに呼び出します（nil の場合はログに出力するだけです）
+	panicHandler PanicHandler
+
+	// lifecycle は、Start で起動したバックグラウンドの実行の状態です
+	lifecycle lifecycle
 }
 
 // NewScheduler は、指定された設定で新しいSchedulerを作成します。
@@ -50,20 +192,142 @@ func NewScheduler(
 	duckDNSClient *duckdns.Client,
 	domain string,
 	token string,
+) *Scheduler {
+	return NewSchedulerWithProvider(interval, ipFetcher, provider.NewDuckDNS(duckDNSClient, token), domain)
+}
+
+// NewSchedulerWithProvider は、DuckDNS 以外のプロバイダーで更新する Scheduler を作成します。
+//
+// Parameters:
+//   - interval: 更新チェックの実行間隔
+//   - ipFetcher: グローバルIPアドレスを取得するFetcherインターフェース
+//   - p: DNSレコードを更新するプロバイダー
+//   - domain: 更新するドメイン名
+//
+// Returns:
+//   - *Scheduler: 初期化されたSchedulerインスタンス
+func NewSchedulerWithProvider(
+	interval time.Duration,
+	ipFetcher ip.Fetcher,
+	p provider.Provider,
+	domain string,
 ) *Scheduler {
 	slog.Info("Scheduler を初期化します",
 		"interval", interval,
 		"domain", domain,
+		"provider", p.Name(),
 	)
 
 	return &Scheduler{
-		interval:      interval,
-		ipFetcher:     ipFetcher,
-		duckDNSClient: duckDNSClient,
-		domain:        domain,
-		token:         token,
-		lastIP:        "", // 初回は必ず更新を実行
+		interval:  interval,
+		ipFetcher: ipFetcher,
+		targets:   []*target{{provider: p, domain: domain}}, // 初回は必ず更新を実行
+		triggers:  make(chan triggerRequest),
+		clock:     SystemClock,
+	}
+}
+
+// AddTarget は、同じIPアドレスを反映する更新先を追加します（ファンアウト）
+// IPアドレスの変更を検知すると、すべての更新先を並行して更新し、結果を更新先ごとに返します。
+// Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - p: DNSレコードを更新するプロバイダー
+//   - domain: 更新するドメイン名
+func (s *Scheduler) AddTarget(p provider.Provider, domain string) {
+	s.targets = append(s.targets, &target{provider: p, domain: domain})
+}
+
+// RestoreLastIP は、ほかのインスタンス（前のリーダー）が最後に登録した IPv4アドレスを、更新先の前回の値として復元します。
+// 最初のチェックで同じIPアドレスを取得した場合は、更新しません。
+// 復元しない場合は、起動して最初のチェックで必ず更新します。Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - domain: 更新先のドメイン名
+//   - ipv4: 最後に登録した IPv4アドレス
+func (s *Scheduler) RestoreLastIP(domain, ipv4 string) {
+	for _, t := range s.targets {
+		if t.domain == domain {
+			t.lastIP = ipv4
+		}
+	}
+}
+
+// SetIPv6Fetcher は、IPv6アドレスを取得する Fetcher を設定し、IPv4 に加えて IPv6 も更新するようにします。
+// DualStackUpdater に対応したプロバイダー（DuckDNS）は、A と AAAA を1回のリクエストで同時に更新します。
+// Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - f: IPv6アドレスを取得する Fetcher
+func (s *Scheduler) SetIPv6Fetcher(f ip.Fetcher) {
+	s.ipv6Fetcher = f
+}
+
+// SetRecorder は、チェックと更新の結果を記録する Recorder を設定します。
+// Run または RunOnce の前に呼び出してください。
+func (s *Scheduler) SetRecorder(r Recorder) {
+	s.recorder = r
+}
+
+// SetPool は、IPアドレスの取得とプロバイダーの更新を実行する Pool を設定します。
+// 複数のスケジューラーで同じ Pool を共有すると、すべてのドメインで同時に実行する数を制限できます。
+// Run または RunOnce の前に呼び出してください。
+func (s *Scheduler) SetPool(p *Pool) {
+	s.pool = p
+}
+
+// SetPrecheck は、チェックのたびに IPアドレスを取得する前に p で接続を確認するようにします。
+// 確認に失敗した場合は、IP取得ソースやプロバイダーに問い合わせずに、オフライン（Result.Offline）として終えます。
+// Run または RunOnce の前に呼び出してください。
+func (s *Scheduler) SetPrecheck(p Prober) {
+	s.precheck = p
+}
+
+// SetShutdownGrace は、停止するときに、実行中のチェックと更新を中断せずに終わるまで待つ時間の上限を設定します。
+// コンテナの停止などで更新のリクエストが途中で切られ、更新できたかどうかわからなくなるのを避けます。
+// Run の前に呼び出してください。
+//
+// Parameters:
+//   - grace: 待つ時間の上限（0 以下の場合はすぐに中断します）
+func (s *Scheduler) SetShutdownGrace(grace time.Duration) {
+	s.shutdownGrace = grace
+}
+
+// WithGrace は、ctx がキャンセルされても grace の間はキャンセルされないコンテキストを返します。
+// 実行中のチェックと更新に渡すと、停止を求められてから grace を過ぎるまでは中断せずに終わるのを待てます。
+// 返したコンテキストは、grace を過ぎるか、返した CancelFunc を呼び出すとキャンセルされます。
+//
+// Parameters:
+//   - ctx: 停止を求めるコンテキスト
+//   - grace: 待つ時間の上限（0 以下の場合は ctx と同時にキャンセルします）
+//
+// Returns:
+//   - context.Context: チェックと更新に渡すコンテキスト
+//   - context.CancelFunc: 使い終わったら呼び出す関数
+func WithGrace(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	if grace <= 0 {
+		return context.WithCancel(ctx)
 	}
+
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-work.Done():
+			return
+		case <-ctx.Done():
+		}
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-work.Done():
+		case <-timer.C:
+			slog.Warn("停止するまでの猶予時間を過ぎたため、実行中のチェックと更新を中断します",
+				"grace", grace,
+			)
+			cancel()
+		}
+	}()
+	return work, cancel
 }
 
 // Run は、スケジューラーを起動して定期的にIPアドレスをチェックし、
@@ -82,88 +346,543 @@ func NewScheduler(
 //	defer cancel()
 //	scheduler.Run(ctx)
 func (s *Scheduler) Run(ctx context.Context) {
+	// チェックと更新には、停止を求められても猶予時間の間はキャンセルされないコンテキストを渡す
+	// 実行中のチェックが終わったら、次の select で ctx のキャンセルに気づいて停止する
+	work, cancel := WithGrace(ctx, s.shutdownGrace)
+	defer cancel()
+	s.run(ctx, work)
+}
+
+// run は、ctx がキャンセルされるまで定期的にチェックと更新を実行します（Run と Start の本体）。
+// チェックと更新には work を渡します。SetWatchdog を呼び出した場合は、ループを見張りながら実行します。
+func (s *Scheduler) run(ctx, work context.Context) {
 	slog.Info("スケジューラーを開始します",
 		"interval", s.interval,
-		"domain", s.domain,
+		"domains", s.Domains(),
 	)
 
+	if s.watchdog.factor > 0 {
+		s.supervise(ctx, work)
+	} else {
+		s.runLoop(ctx, work)
+	}
+
+	slog.Info("スケジューラーを停止します",
+		"reason", ctx.Err(),
+	)
+}
+
+// runLoop は、ctx がキャンセルされるまで loop を実行します。
+// ループでパニックが発生した場合は、同じパニックを繰り返さないように interval だけ待ってから始め直します。
+func (s *Scheduler) runLoop(ctx, work context.Context) {
+	for {
+		if s.protect(PanicInLoop, func() { s.loop(ctx, work) }) == nil {
+			return
+		}
+		slog.Warn("パニックが発生したため、定期チェックの間隔だけ待ってからループを始め直します",
+			"interval", s.interval,
+			"domains", s.Domains(),
+		)
+		select {
+		case <-s.clock.After(s.interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// loop は、起動直後に1回、その後は interval ごとにチェックと更新を実行します。
+// ctx がキャンセルされると戻ります。
+func (s *Scheduler) loop(ctx, work context.Context) {
 	// 初回実行: 起動直後に一度チェックを実行
-	s.checkAndUpdate(ctx)
+	s.checkAndUpdate(work)
+	s.watchdog.beat(s.clock.Now())
 
 	// Ticker を作成して定期実行を設定
-	ticker := time.NewTicker(s.interval)
+	ticker := s.clock.NewTicker(s.interval)
 	defer ticker.Stop() // 終了時にTickerを停止してリソースを解放
 
 	// select 文で定期実行とコンテキストキャンセルを監視
+	// 保留中の更新がある間は、次の定期チェックを待たずに短い間隔で再試行する
 	for {
 		select {
-		case <-ticker.C:
-			// Ticker が発火: 定期チェックを実行
-			s.checkAndUpdate(ctx)
+		case <-ticker.C():
+			// Ticker が発火: 定期チェックを実行（停止を求められた後と、実行しない時間帯は新しく始めない）
+			if ctx.Err() == nil && s.scheduled() {
+				s.checkAndUpdate(work)
+			}
+
+		case <-s.retryAfter():
+			if ctx.Err() == nil {
+				s.retryPending(work)
+			}
+
+		case req := <-s.triggers:
+			// Trigger で求められた: 定期チェックを待たずにチェックと更新を実行
+			req.done <- s.checkTriggered(work, req.ipv4, req.ipv6)
 
 		case <-ctx.Done():
 			// コンテキストがキャンセルされた: 終了処理
-			slog.Info("スケジューラーを停止します",
-				"reason", ctx.Err(),
-			)
 			return
 		}
+		s.watchdog.beat(s.clock.Now())
 	}
 }
 
+// RunAll は、複数のスケジューラーをそれぞれ goroutine で並行に実行し、
+// すべてが停止するまでブロックします。
+// ドメインごとに更新間隔やIP取得ソースが異なる場合に使用します。
+//
+// Parameters:
+//   - ctx: 実行を制御するコンテキスト（キャンセルですべて停止）
+//   - schedulers: 実行するスケジューラーの一覧
+func RunAll(ctx context.Context, schedulers []*Scheduler) {
+	for _, s := range schedulers {
+		if err := s.Start(ctx); err != nil {
+			slog.Error("スケジューラーを開始できません",
+				"domains", s.Domains(),
+				"error", err,
+			)
+		}
+	}
+	for _, s := range schedulers {
+		s.Wait()
+	}
+}
+
+// RunOnce は、IPアドレスのチェックと更新を1回だけ実行します。
+// cron や systemd タイマーから起動するワンショット実行で使用します。
+//
+// Parameters:
+//   - ctx: 実行を制御するコンテキスト
+//
+// Returns:
+//   - error: IPアドレスの取得またはプロバイダーの更新に失敗した場合（更新先が複数の場合はまとめたもの）
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	return joinErrors(s.CheckOnce(ctx))
+}
+
+// CheckOnce は、RunOnce と同様にチェックと更新を1回だけ実行し、更新先ごとの詳細な結果を返します。
+// 更新前後のIPアドレスや所要時間を出力する場合に使用します。
+//
+// Parameters:
+//   - ctx: 実行を制御するコンテキスト
+//
+// Returns:
+//   - []Result: 更新先ごとのチェックと更新の結果（AddTarget で追加した順）
+func (s *Scheduler) CheckOnce(ctx context.Context) []Result {
+	return s.checkAndUpdate(ctx)
+}
+
+// RunAllOnce は、複数のスケジューラーで1回ずつチェックと更新を並行に実行し、
+// すべてが完了するまでブロックします。
+//
+// Parameters:
+//   - ctx: 実行を制御するコンテキスト
+//   - schedulers: 実行するスケジューラーの一覧
+//
+// Returns:
+//   - error: 失敗したドメインのエラーをまとめたもの（すべて成功した場合は nil）
+func RunAllOnce(ctx context.Context, schedulers []*Scheduler) error {
+	return joinErrors(CheckAllOnce(ctx, schedulers))
+}
+
+// joinErrors は、失敗した結果のエラーを "ドメイン: エラー" の形でまとめます。
+func joinErrors(results []Result) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Domain, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckAllOnce は、RunAllOnce と同様に1回ずつチェックと更新を並行に実行し、
+// スケジューラーごとの結果を返します。
+//
+// Parameters:
+//   - ctx: 実行を制御するコンテキスト
+//   - schedulers: 実行するスケジューラーの一覧
+//
+// Returns:
+//   - []Result: 更新先ごとの結果（schedulers の順、同じスケジューラー内は追加した順）
+func CheckAllOnce(ctx context.Context, schedulers []*Scheduler) []Result {
+	perScheduler := make([][]Result, len(schedulers))
+
+	var wg sync.WaitGroup
+	for i, s := range schedulers {
+		wg.Add(1)
+		go func(i int, s *Scheduler) {
+			defer wg.Done()
+			perScheduler[i] = s.CheckOnce(ctx)
+		}(i, s)
+	}
+	wg.Wait()
+
+	var results []Result
+	for _, r := range perScheduler {
+		results = append(results, r...)
+	}
+	return results
+}
+
 // checkAndUpdate は、現在のIPアドレスを取得し、
-// 前回と異なる場合にDuckDNSを更新します（内部用ヘルパー関数）
+// 前回と異なる更新先を並行して更新します（内部用ヘルパー関数）
 //
 // エラーが発生してもスケジューラーは継続して実行されます。
-func (s *Scheduler) checkAndUpdate(ctx context.Context) {
+// 戻り値の結果は、ワンショット実行の終了コードの判定と結果の出力に使用します。
+func (s *Scheduler) checkAndUpdate(ctx context.Context) []Result {
 	slog.Debug("IP アドレスのチェックを開始します")
 
-	// 1. 現在のIPアドレスを取得
-	currentIP, err := s.ipFetcher.Fetch(ctx)
+	start := s.clock.Now()
+	results := s.newResults()
+
+	// 0. 接続を確認できなければ、IP取得ソースやプロバイダーに問い合わせずにオフラインとして終える
+	if s.precheck != nil {
+		if err := s.precheck.Probe(ctx); err != nil {
+			if !errors.Is(err, netcheck.ErrOffline) {
+				err = fmt.Errorf("%w: %w", netcheck.ErrOffline, err)
+			}
+			slog.Warn("ネットワークにつながっていないため、今回のチェックをスキップします",
+				"domains", s.Domains(),
+				"error", err,
+			)
+			for i := range results {
+				results[i].Err = err
+				results[i].Offline = true
+				results[i].Duration = s.since(start)
+			}
+			s.record(ctx, results)
+			return results
+		}
+	}
+
+	// 自動検出のモードでは、IPアドレスを取得せずに、プロバイダーが検出したアドレスで更新する
+	if s.autoDetect {
+		s.checkAutoDetect(ctx, start, results)
+		return results
+	}
+
+	// 1. 現在のIPアドレスを取得（更新先がいくつあっても1回だけ）
+	// Pool を使う場合は、取得と更新で別々に空きを待つ（取得したまま更新の空きを待つと止まる場合がある）
+	var fetched, fetchedIPv6 ip.FetchResult
+	var ipv6Err, err error
+	s.pool.run(func() {
+		fetched, fetchedIPv6, ipv6Err, err = s.fetchIPs(ctx)
+	})
+	for i := range results {
+		results[i].Fetch = fetched
+		results[i].FetchIPv6 = fetchedIPv6
+	}
 	if err != nil {
 		// IP取得失敗: エラーログを出力して継続
 		slog.Error("IP アドレスの取得に失敗しました",
 			"error", err,
 		)
-		return
+		for i := range results {
+			results[i].Err = err
+			results[i].Duration = s.since(start)
+		}
+		s.record(ctx, results)
+		return results
 	}
 
 	slog.Debug("現在の IP アドレスを取得しました",
-		"ip", currentIP,
+		"ip", fetched.IP,
+		"source", fetched.Source,
+		"attempts", fetched.Attempts,
+		"ipv6", fetchedIPv6.IP,
+		"ipv6_source", fetchedIPv6.Source,
 	)
 
-	// 2. 前回のIPアドレスと比較
-	if s.lastIP == currentIP {
-		// IPアドレスに変更なし: スキップ
-		slog.Info("IP アドレスに変更はありません",
-			"ip", currentIP,
+	// 2. 前回のIPアドレスと異なる更新先を並行して更新
+	s.updateFetched(ctx, start, fetched, fetchedIPv6, ipv6Err, results)
+	return results
+}
+
+// updateFetched は、取得したIPアドレスと異なる更新先を更新し、結果をログと Recorder に残します（内部用ヘルパー関数）
+// ipv6Err は、IPv6アドレスの取得に失敗した場合のエラーです（IPv4 だけを更新して、IPv6 の失敗を報告します）
+func (s *Scheduler) updateFetched(ctx context.Context, start time.Time, fetched, fetchedIPv6 ip.FetchResult, ipv6Err error, results []Result) {
+	s.updateTargets(ctx, start, fetched.IP, fetchedIPv6.IP, results)
+
+	// IPv6アドレスを取得できなかった場合は、IPv4 だけを更新して IPv6 の失敗を報告する
+	if ipv6Err != nil {
+		for i := range results {
+			results[i].IPv6Err = ipv6Err
+			if results[i].Err == nil {
+				results[i].Err = ipv6Err
+			}
+		}
+	}
+
+	s.logSummary(fetched.IP, fetchedIPv6.IP, results)
+	s.updatePending(ctx, fetched, fetchedIPv6, results)
+	s.record(ctx, results)
+}
+
+// newResults は、更新先ごとの結果を、チェック前に登録済みとみなしていたIPアドレスで初期化して返します。
+func (s *Scheduler) newResults() []Result {
+	results := make([]Result, len(s.targets))
+	for i, t := range s.targets {
+		results[i] = Result{Domain: t.domain, Provider: t.provider.Name(), OldIP: t.lastIP, OldIPv6: t.lastIPv6}
+	}
+	return results
+}
+
+// updateTargets は、登録済みのIPアドレスと異なる更新先を並行して更新し、結果を results に書き込みます（内部用ヘルパー関数）
+// まとめて更新できるプロバイダー（同じトークンの DuckDNS など）は、1回のリクエストにまとめます。
+// IPv6 も更新する場合、DualStackUpdater は A と AAAA を同じリクエストで更新します。
+func (s *Scheduler) updateTargets(ctx context.Context, start time.Time, currentIP, currentIPv6 string, results []Result) {
+	var wg sync.WaitGroup
+	batches := make(map[provider.BatchUpdater][]int)
+	dualStacks := make(map[provider.DualStackUpdater][]int)
+	for i, t := range s.targets {
+		results[i].NewIP = currentIP
+		results[i].NewIPv6 = currentIPv6
+		if t.lastIP == currentIP && (currentIPv6 == "" || t.lastIPv6 == currentIPv6) {
+			results[i].Duration = s.since(start)
+			continue
+		}
+
+		if d, ok := t.provider.(provider.DualStackUpdater); ok && currentIPv6 != "" {
+			dualStacks[d] = append(dualStacks[d], i)
+			continue
+		}
+		if b, ok := t.provider.(provider.BatchUpdater); ok && currentIPv6 == "" {
+			batches[b] = append(batches[b], i)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, t *target) {
+			defer wg.Done()
+			if err := s.protect(PanicInUpdate, func() {
+				s.pool.run(func() { s.update(ctx, t, currentIP, currentIPv6, &results[i]) })
+			}); err != nil {
+				results[i].Err = err
+			}
+			results[i].Duration = s.since(start)
+		}(i, t)
+	}
+	for b, indexes := range batches {
+		wg.Add(1)
+		go func(b provider.BatchUpdater, indexes []int) {
+			defer wg.Done()
+			err := s.protect(PanicInUpdate, func() {
+				s.pool.run(func() { s.updateBatch(ctx, b, indexes, currentIP, results) })
+			})
+			for _, i := range indexes {
+				if err != nil {
+					results[i].Err = err
+				}
+				results[i].Duration = s.since(start)
+			}
+		}(b, indexes)
+	}
+	for d, indexes := range dualStacks {
+		wg.Add(1)
+		go func(d provider.DualStackUpdater, indexes []int) {
+			defer wg.Done()
+			err := s.protect(PanicInUpdate, func() {
+				s.pool.run(func() { s.updateDualStack(ctx, d, indexes, currentIP, currentIPv6, results) })
+			})
+			for _, i := range indexes {
+				if err != nil {
+					results[i].Err = err
+				}
+				results[i].Duration = s.since(start)
+			}
+		}(d, indexes)
+	}
+	wg.Wait()
+}
+
+// fetchIPs は、IPv4 と（IPv6 も更新する場合は）IPv6 のアドレスを並行して取得し、詳細な結果を返します。
+// IPv4 の取得に失敗した場合は err を返し、IPv6 の取得だけに失敗した場合は ipv6Err を返します。
+// DuckDNS は ip を省略すると送信元のアドレスを登録してしまうため、IPv6 だけでは更新しません。
+func (s *Scheduler) fetchIPs(ctx context.Context) (ipv4, ipv6 ip.FetchResult, ipv6Err, err error) {
+	if s.ipv6Fetcher == nil {
+		ipv4, err = s.fetch(ctx, s.ipFetcher)
+		return ipv4, ip.FetchResult{}, nil, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.protect(PanicInFetch, func() { ipv6, ipv6Err = s.fetch(ctx, s.ipv6Fetcher) }); err != nil {
+			ipv6Err = err
+		}
+	}()
+	ipv4, err = s.fetch(ctx, s.ipFetcher)
+	wg.Wait()
+
+	if err == nil && ipv6Err != nil {
+		slog.Warn("IPv6 アドレスの取得に失敗しました（IPv4 だけを更新します）",
+			"error", ipv6Err,
 		)
-		return
 	}
+	return ipv4, ipv6, ipv6Err, err
+}
+
+// update は、1つの更新先のレコードを種類ごとに更新し、結果を r に書き込みます（内部用ヘルパー関数）
+// 更新に成功した種類だけ、その更新先の lastIP（lastIPv6）を更新します。
+func (s *Scheduler) update(ctx context.Context, t *target, currentIP, currentIPv6 string, r *Result) {
+	// IPアドレスが変更された場合: プロバイダーで更新
+	if t.lastIP != currentIP {
+		logChange(t, t.lastIP, currentIP)
+		if r.Updated, r.Err = finishUpdate(t, s.updateOne(ctx, t, currentIP), currentIP); r.Updated {
+			t.lastIP = currentIP
+		}
+	}
+	if currentIPv6 != "" && t.lastIPv6 != currentIPv6 {
+		logChange(t, t.lastIPv6, currentIPv6)
+		if r.UpdatedIPv6, r.IPv6Err = finishUpdate(t, s.updateOne(ctx, t, currentIPv6), currentIPv6); r.UpdatedIPv6 {
+			t.lastIPv6 = currentIPv6
+		}
+		if r.Err == nil {
+			r.Err = r.IPv6Err
+		}
+	}
+}
 
-	// 3. IPアドレスが変更された場合: DuckDNSを更新
+// updateOne は、Update のタイムアウトを期限にして、1つの更新先のレコードを更新します。
+func (s *Scheduler) updateOne(ctx context.Context, t *target, addr string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	return t.provider.Update(ctx, t.domain, addr)
+}
+
+// updateBatch は、同じ BatchUpdater の更新先をまとめて更新し、結果を results に書き込みます（内部用ヘルパー関数）
+func (s *Scheduler) updateBatch(ctx context.Context, b provider.BatchUpdater, indexes []int, currentIP string, results []Result) {
+	domains := make([]string, len(indexes))
+	for n, i := range indexes {
+		logChange(s.targets[i], s.targets[i].lastIP, currentIP)
+		domains[n] = s.targets[i].domain
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	errs := b.UpdateBatch(ctx, domains, currentIP)
+	for n, i := range indexes {
+		t := s.targets[i]
+		if results[i].Updated, results[i].Err = finishUpdate(t, errs[n], currentIP); results[i].Updated {
+			t.lastIP = currentIP
+		}
+	}
+}
+
+// updateDualStack は、同じ DualStackUpdater の更新先の A と AAAA を同時に更新し、
+// 結果を results に書き込みます（内部用ヘルパー関数）
+// 1回のリクエストで更新するため、どちらか一方だけが更新されることはありません。
+func (s *Scheduler) updateDualStack(ctx context.Context, d provider.DualStackUpdater, indexes []int, currentIP, currentIPv6 string, results []Result) {
+	domains := make([]string, len(indexes))
+	for n, i := range indexes {
+		t := s.targets[i]
+		if t.lastIP != currentIP {
+			logChange(t, t.lastIP, currentIP)
+		}
+		if t.lastIPv6 != currentIPv6 {
+			logChange(t, t.lastIPv6, currentIPv6)
+		}
+		domains[n] = t.domain
+	}
+
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+	errs := d.UpdateDualStack(ctx, domains, currentIP, currentIPv6)
+	for n, i := range indexes {
+		t := s.targets[i]
+		ipv4Changed, ipv6Changed := t.lastIP != currentIP, t.lastIPv6 != currentIPv6
+		if _, err := finishUpdate(t, errs[n], currentIP, currentIPv6); err != nil {
+			results[i].Err, results[i].IPv6Err = err, err
+			continue
+		}
+		t.lastIP, t.lastIPv6 = currentIP, currentIPv6
+		results[i].Updated, results[i].UpdatedIPv6 = ipv4Changed, ipv6Changed
+	}
+}
+
+// logChange は、更新先のIPアドレスの変更を検知したことをログに出力します。
+func logChange(t *target, oldIP, newIP string) {
 	slog.Info("IP アドレスの変更を検知しました",
-		"old_ip", s.lastIP,
-		"new_ip", currentIP,
-		"domain", s.domain,
+		"old_ip", oldIP,
+		"new_ip", newIP,
+		"domain", t.domain,
+		"provider", t.provider.Name(),
 	)
+}
 
-	// DuckDNSを更新
-	_, err = s.duckDNSClient.Update(ctx, s.domain, s.token, currentIP)
+// finishUpdate は、更新の結果をログに出力し、更新に成功したかどうかとエラーを返します。
+// ips は、更新したIPアドレスです（A と AAAA を同時に更新した場合は2つ）
+func finishUpdate(t *target, err error, ips ...string) (bool, error) {
 	if err != nil {
 		// 更新失敗: エラーログを出力して継続
-		slog.Error("DuckDNS の更新に失敗しました",
+		slog.Error("DNS レコードの更新に失敗しました",
 			"error", err,
-			"domain", s.domain,
-			"ip", currentIP,
+			"domain", t.domain,
+			"provider", t.provider.Name(),
+			"ip", strings.Join(ips, ", "),
 		)
-		return
+		return false, err
 	}
 
-	// 4. 更新成功: lastIP を更新
-	s.lastIP = currentIP
-	slog.Info("DuckDNS の更新に成功しました",
-		"domain", s.domain,
-		"ip", currentIP,
+	slog.Info("DNS レコードの更新に成功しました",
+		"domain", t.domain,
+		"provider", t.provider.Name(),
+		"ip", strings.Join(ips, ", "),
 	)
+	return true, nil
+}
+
+// logSummary は、変更がなかったことや、一部の更新先だけが失敗したことをログに出力します。
+func (s *Scheduler) logSummary(currentIP, currentIPv6 string, results []Result) {
+	var updated, failed []string
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed = append(failed, r.Provider+":"+r.Domain)
+		case r.Updated || r.UpdatedIPv6:
+			updated = append(updated, r.Provider+":"+r.Domain)
+		}
+	}
+
+	switch {
+	case len(updated) == 0 && len(failed) == 0:
+		slog.Info("IP アドレスに変更はありません",
+			"ip", currentIP,
+			"ipv6", currentIPv6,
+		)
+	case len(updated) > 0 && len(failed) > 0:
+		slog.Warn("一部の更新先の更新に失敗しました（失敗した更新先は次回のチェックで再度更新します）",
+			"ip", currentIP,
+			"ipv6", currentIPv6,
+			"updated", updated,
+			"failed", failed,
+		)
+	}
+}
+
+// record は、更新先ごとの結果を Recorder に記録し、更新と失敗を Notifier に知らせます。
+// キャンセルによる中断は結果として記録しません。
+func (s *Scheduler) record(ctx context.Context, results []Result) {
+	s.checkFailures(ctx, results)
+	if s.recorder != nil && ctx.Err() == nil {
+		for _, r := range results {
+			s.protect(PanicInRecorder, func() {
+				s.recorder.RecordResult(r.Domain, r.NewIP, r.Fetch.Source, r.Updated || r.UpdatedIPv6, r.Err)
+			})
+		}
+	}
+	s.notify(ctx, results)
+}
+
+// Domains は、更新先のドメイン名の一覧を返します（AddTarget で追加した順）
+func (s *Scheduler) Domains() []string {
+	domains := make([]string, 0, len(s.targets))
+	for _, t := range s.targets {
+		domains = append(domains, t.domain)
+	}
+	return domains
 }
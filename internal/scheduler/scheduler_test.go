@@ -2,11 +2,18 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/ip"
+	"github.com/horitaku/duckdns/internal/provider"
 )
 
 // MockFetcher は、テスト用のIP Fetcher モックです。
@@ -25,6 +32,24 @@ func (m *MockFetcher) Fetch(ctx context.Context) (string, error) {
 	return "", nil
 }
 
+// MockProvider は、テスト用の Provider モックです。
+type MockProvider struct {
+	UpdateFunc func(ctx context.Context, domain, ip string) error
+}
+
+// Name は MockProvider の Name メソッドを実装します。
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+// Update は MockProvider の Update メソッドを実装します。
+func (m *MockProvider) Update(ctx context.Context, domain, ip string) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, domain, ip)
+	}
+	return nil
+}
+
 // GetFetchCount はスレッドセーフに取得回数を返します。
 func (m *MockFetcher) GetFetchCount() int {
 	return int(atomic.LoadInt32(&m.FetchCount))
@@ -44,16 +69,25 @@ func TestNewScheduler(t *testing.T) {
 		t.Errorf("interval が一致しません。期待: %v, 実際: %v", interval, scheduler.interval)
 	}
 
-	if scheduler.domain != domain {
-		t.Errorf("domain が一致しません。期待: %s, 実際: %s", domain, scheduler.domain)
+	if len(scheduler.targets) != 1 {
+		t.Fatalf("更新先は1件であるべき。実際: %d", len(scheduler.targets))
 	}
+	target := scheduler.targets[0]
 
-	if scheduler.token != token {
-		t.Errorf("token が一致しません。期待: %s, 実際: %s", token, scheduler.token)
+	if target.domain != domain {
+		t.Errorf("domain が一致しません。期待: %s, 実際: %s", domain, target.domain)
 	}
 
-	if scheduler.lastIP != "" {
-		t.Errorf("lastIP は空であるべき。期待: \"\", 実際: %s", scheduler.lastIP)
+	duck, ok := target.provider.(*provider.DuckDNS)
+	if !ok {
+		t.Fatalf("provider は *provider.DuckDNS であるべき: %T", target.provider)
+	}
+	if duck.Token != token {
+		t.Errorf("token が一致しません。期待: %s, 実際: %s", token, duck.Token)
+	}
+
+	if target.lastIP != "" {
+		t.Errorf("lastIP は空であるべき。期待: \"\", 実際: %s", target.lastIP)
 	}
 }
 
@@ -167,8 +201,8 @@ func TestScheduler_FetchError(t *testing.T) {
 
 	scheduler.Run(ctx)
 
-	if scheduler.lastIP != "" {
-		t.Errorf("lastIP は更新されていないはず。期待: \"\", 実際: %s", scheduler.lastIP)
+	if scheduler.targets[0].lastIP != "" {
+		t.Errorf("lastIP は更新されていないはず。期待: \"\", 実際: %s", scheduler.targets[0].lastIP)
 	}
 }
 
@@ -193,8 +227,8 @@ func TestScheduler_ContextCancelledDuringCheck(t *testing.T) {
 
 	scheduler.checkAndUpdate(ctx)
 
-	if scheduler.lastIP != "" {
-		t.Errorf("lastIP は更新されていないはず。期待: \"\", 実際: %s", scheduler.lastIP)
+	if scheduler.targets[0].lastIP != "" {
+		t.Errorf("lastIP は更新されていないはず。期待: \"\", 実際: %s", scheduler.targets[0].lastIP)
 	}
 }
 
@@ -244,3 +278,559 @@ func TestScheduler_MultipleCheckAndUpdate(t *testing.T) {
 		t.Errorf("Fetch が期待回数呼び出されていません。期待: 3, 実際: %d", mockFetcher.GetFetchCount())
 	}
 }
+
+// TestRunAll は、複数のスケジューラーが並行して実行されることをテストします。
+func TestRunAll(t *testing.T) {
+	fetchers := []*MockFetcher{
+		{FetchFunc: func(ctx context.Context) (string, error) { return "", context.Canceled }},
+		{FetchFunc: func(ctx context.Context) (string, error) { return "", context.Canceled }},
+	}
+
+	mockClient := duckdns.NewClient()
+	schedulers := []*Scheduler{
+		NewScheduler(10*time.Millisecond, fetchers[0], mockClient, "domain-a", "token-a"),
+		NewScheduler(time.Hour, fetchers[1], mockClient, "domain-b", "token-b"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		RunAll(ctx, schedulers)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunAll がキャンセル後も終了しません")
+	}
+
+	// 短い間隔のスケジューラーは複数回、長い間隔のスケジューラーは初回のみ実行される
+	if fetchers[0].GetFetchCount() < 2 {
+		t.Errorf("短い間隔のスケジューラーが定期実行されていません。実際: %d", fetchers[0].GetFetchCount())
+	}
+	if fetchers[1].GetFetchCount() != 1 {
+		t.Errorf("長い間隔のスケジューラーは初回のみ実行されるべき。実際: %d", fetchers[1].GetFetchCount())
+	}
+}
+
+// TestScheduler_RunOnce は、RunOnce が1回だけ更新し、結果をエラーで返すことをテストします。
+func TestScheduler_RunOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("domains") == "bad-domain" {
+			_, _ = w.Write([]byte("KO"))
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.168.1.1", nil }}
+
+	good := NewScheduler(time.Hour, fetcher, client, "good-domain", "token")
+	if err := good.RunOnce(context.Background()); err != nil {
+		t.Fatalf("更新は成功するべき: %v", err)
+	}
+	if good.targets[0].lastIP != "192.168.1.1" {
+		t.Errorf("lastIP が更新されていません。実際: %s", good.targets[0].lastIP)
+	}
+
+	bad := NewScheduler(time.Hour, fetcher, client, "bad-domain", "token")
+	if err := bad.RunOnce(context.Background()); err == nil {
+		t.Error("KO の場合はエラーを返すべき")
+	}
+
+	failing := NewScheduler(time.Hour, &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return "", errors.New("fetch failed")
+	}}, client, "good-domain", "token")
+	if err := failing.RunOnce(context.Background()); err == nil {
+		t.Error("IP 取得失敗の場合はエラーを返すべき")
+	}
+}
+
+// TestRunAllOnce は、失敗したドメインのエラーがまとめて返されることをテストします。
+func TestRunAllOnce(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "", errors.New("fetch failed") }}
+	mockClient := duckdns.NewClient()
+	schedulers := []*Scheduler{
+		NewScheduler(time.Hour, fetcher, mockClient, "domain-a", "token-a"),
+		NewScheduler(time.Hour, fetcher, mockClient, "domain-b", "token-b"),
+	}
+
+	err := RunAllOnce(context.Background(), schedulers)
+	if err == nil {
+		t.Fatal("エラーが返されるべき")
+	}
+	for _, domain := range []string{"domain-a", "domain-b"} {
+		if !strings.Contains(err.Error(), domain) {
+			t.Errorf("エラーに %s が含まれるべき: %v", domain, err)
+		}
+	}
+	if fetcher.GetFetchCount() != 2 {
+		t.Errorf("各スケジューラーで1回ずつ実行されるべき。実際: %d", fetcher.GetFetchCount())
+	}
+
+	if err := RunAllOnce(context.Background(), nil); err != nil {
+		t.Errorf("スケジューラーがない場合は nil を返すべき: %v", err)
+	}
+}
+
+// recordedResult は、テスト用 Recorder に記録された1件の結果です。
+type recordedResult struct {
+	domain  string
+	ip      string
+	source  string
+	updated bool
+	err     error
+}
+
+// mockRecorder は、テスト用の Recorder です。
+type mockRecorder struct {
+	results []recordedResult
+}
+
+// RecordResult は、結果をスライスに追加します。
+func (m *mockRecorder) RecordResult(domain, ip, source string, updated bool, err error) {
+	m.results = append(m.results, recordedResult{domain, ip, source, updated, err})
+}
+
+// TestScheduler_Recorder は、更新・変更なし・失敗が Recorder に記録されることをテストします。
+func TestScheduler_Recorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	var fail atomic.Bool
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		if fail.Load() {
+			return "", errors.New("fetch failed")
+		}
+		return "192.168.1.1", nil
+	}}
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	recorder := &mockRecorder{}
+
+	s := NewScheduler(time.Hour, fetcher, client, "test-domain", "test-token")
+	s.SetRecorder(recorder)
+
+	_ = s.RunOnce(context.Background())
+	_ = s.RunOnce(context.Background())
+	fail.Store(true)
+	_ = s.RunOnce(context.Background())
+
+	if len(recorder.results) != 3 {
+		t.Fatalf("3件記録されるべき。実際: %d", len(recorder.results))
+	}
+	if r := recorder.results[0]; !r.updated || r.ip != "192.168.1.1" || r.err != nil || r.domain != "test-domain" {
+		t.Errorf("1回目は更新として記録されるべき: %+v", r)
+	}
+	if r := recorder.results[1]; r.updated || r.err != nil {
+		t.Errorf("2回目は変更なしとして記録されるべき: %+v", r)
+	}
+	if r := recorder.results[2]; r.updated || r.err == nil {
+		t.Errorf("3回目は失敗として記録されるべき: %+v", r)
+	}
+
+	// キャンセルされたチェックは記録されない
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = s.RunOnce(ctx)
+	if len(recorder.results) != 3 {
+		t.Errorf("キャンセル時は記録されないべき。実際: %d", len(recorder.results))
+	}
+}
+
+// TestScheduler_CheckOnce は、CheckOnce が更新前後のIPアドレスと結果を返すことをテストします。
+func TestScheduler_CheckOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	currentIP := "192.168.1.1"
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return currentIP, nil }}
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	s := NewScheduler(time.Hour, fetcher, client, "test-domain", "test-token")
+
+	first := s.CheckOnce(context.Background())[0]
+	if first.Domain != "test-domain" || first.OldIP != "" || first.NewIP != "192.168.1.1" || !first.Updated || first.Err != nil {
+		t.Errorf("1回目の結果が一致しません: %+v", first)
+	}
+	if first.Duration <= 0 {
+		t.Errorf("所要時間が記録されていません: %v", first.Duration)
+	}
+
+	second := s.CheckOnce(context.Background())[0]
+	if second.OldIP != "192.168.1.1" || second.NewIP != "192.168.1.1" || second.Updated {
+		t.Errorf("変更がない場合は更新しないべき: %+v", second)
+	}
+
+	currentIP = "192.168.1.2"
+	third := s.CheckOnce(context.Background())[0]
+	if third.OldIP != "192.168.1.1" || third.NewIP != "192.168.1.2" || !third.Updated {
+		t.Errorf("変更があった場合は更新前後のIPアドレスが返されるべき: %+v", third)
+	}
+}
+
+// TestCheckAllOnce は、スケジューラーごとの結果が同じ順で返されることをテストします。
+func TestCheckAllOnce(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "", errors.New("fetch failed") }}
+	mockClient := duckdns.NewClient()
+	schedulers := []*Scheduler{
+		NewScheduler(time.Hour, fetcher, mockClient, "domain-a", "token-a"),
+		NewScheduler(time.Hour, fetcher, mockClient, "domain-b", "token-b"),
+	}
+
+	results := CheckAllOnce(context.Background(), schedulers)
+	if len(results) != 2 {
+		t.Fatalf("2件の結果が返されるべき。実際: %d", len(results))
+	}
+	for i, domain := range []string{"domain-a", "domain-b"} {
+		if results[i].Domain != domain {
+			t.Errorf("[%d] ドメインの順番が一致しません。期待: %s, 実際: %s", i, domain, results[i].Domain)
+		}
+		if results[i].Err == nil {
+			t.Errorf("[%d] エラーが返されるべき", i)
+		}
+	}
+}
+
+// TestNewSchedulerWithProvider は、DuckDNS 以外のプロバイダーで更新できることをテストします。
+func TestNewSchedulerWithProvider(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	var updatedDomain, updatedIP string
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		updatedDomain, updatedIP = domain, ip
+		return nil
+	}}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home.example.com")
+	result := s.CheckOnce(context.Background())[0]
+	if result.Err != nil || !result.Updated || result.Provider != "mock" {
+		t.Fatalf("更新が成功するべき: %+v", result)
+	}
+	if updatedDomain != "home.example.com" || updatedIP != "192.0.2.1" {
+		t.Errorf("プロバイダーに渡された値が一致しません: %s %s", updatedDomain, updatedIP)
+	}
+
+	p.UpdateFunc = func(ctx context.Context, domain, ip string) error {
+		return provider.ErrRejected
+	}
+	fetcher.FetchFunc = func(ctx context.Context) (string, error) { return "192.0.2.2", nil }
+	result = s.CheckOnce(context.Background())[0]
+	if !errors.Is(result.Err, provider.ErrRejected) || result.Updated {
+		t.Errorf("プロバイダーのエラーが返されるべき: %+v", result)
+	}
+}
+
+// TestScheduler_FanOut は、1回取得したIPアドレスを複数の更新先に並行して反映し、
+// 失敗した更新先だけを次回のチェックで再度更新することをテストします。
+func TestScheduler_FanOut(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+
+	var goodCalls, flakyCalls atomic.Int32
+	good := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		goodCalls.Add(1)
+		return nil
+	}}
+	flakyErr := errors.New("temporary failure")
+	flaky := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		if flakyCalls.Add(1) == 1 {
+			return flakyErr
+		}
+		return nil
+	}}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, good, "a.example.com")
+	s.AddTarget(flaky, "b.example.com")
+	recorder := &mockRecorder{}
+	s.SetRecorder(recorder)
+
+	first := s.CheckOnce(context.Background())
+	if len(first) != 2 {
+		t.Fatalf("更新先ごとに2件の結果が返されるべき。実際: %d", len(first))
+	}
+	if first[0].Domain != "a.example.com" || !first[0].Updated || first[0].Err != nil {
+		t.Errorf("1件目は更新に成功するべき: %+v", first[0])
+	}
+	if first[1].Domain != "b.example.com" || first[1].Updated || !errors.Is(first[1].Err, flakyErr) {
+		t.Errorf("2件目は更新に失敗するべき: %+v", first[1])
+	}
+	if got := fetcher.GetFetchCount(); got != 1 {
+		t.Errorf("IPアドレスの取得は更新先の数に関係なく1回であるべき。実際: %d", got)
+	}
+	if err := joinErrors(first); err == nil || !strings.Contains(err.Error(), "b.example.com") {
+		t.Errorf("失敗した更新先のエラーが返されるべき: %v", err)
+	}
+
+	// 2回目: IPアドレスは変わらないが、失敗した更新先だけを再度更新する
+	second := s.CheckOnce(context.Background())
+	if second[0].Updated || second[0].Err != nil {
+		t.Errorf("成功済みの更新先は更新しないべき: %+v", second[0])
+	}
+	if !second[1].Updated || second[1].Err != nil {
+		t.Errorf("失敗した更新先は再度更新するべき: %+v", second[1])
+	}
+	if goodCalls.Load() != 1 || flakyCalls.Load() != 2 {
+		t.Errorf("更新回数が一致しません。good: %d, flaky: %d", goodCalls.Load(), flakyCalls.Load())
+	}
+
+	if len(recorder.results) != 4 {
+		t.Errorf("更新先ごとに結果が記録されるべき。実際: %d", len(recorder.results))
+	}
+}
+
+// TestScheduler_RestoreLastIP は、復元した前回のIPアドレスと同じ場合は更新しないことをテストします。
+func TestScheduler_RestoreLastIP(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	var calls atomic.Int32
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		calls.Add(1)
+		return nil
+	}}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "a")
+	s.AddTarget(p, "b")
+	s.RestoreLastIP("a", "192.0.2.1")
+	s.RestoreLastIP("b", "192.0.2.9")
+	s.RestoreLastIP("unknown", "192.0.2.1")
+
+	results := s.CheckOnce(context.Background())
+	if results[0].Updated || results[0].OldIP != "192.0.2.1" {
+		t.Errorf("前回と同じIPアドレスの更新先は更新しないべき: %+v", results[0])
+	}
+	if !results[1].Updated || results[1].OldIP != "192.0.2.9" {
+		t.Errorf("前回と違うIPアドレスの更新先は更新するべき: %+v", results[1])
+	}
+	if calls.Load() != 1 {
+		t.Errorf("更新は1回だけであるべき。実際: %d", calls.Load())
+	}
+}
+
+// TestCheckAllOnce_FanOut は、CheckAllOnce がスケジューラーごとの結果をつなげて返すことをテストします。
+func TestCheckAllOnce_FanOut(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	first := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "a")
+	first.AddTarget(&MockProvider{}, "b")
+	second := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "c")
+
+	results := CheckAllOnce(context.Background(), []*Scheduler{first, second})
+	var domains []string
+	for _, r := range results {
+		domains = append(domains, r.Domain)
+	}
+	if strings.Join(domains, ",") != "a,b,c" {
+		t.Errorf("結果の順番が一致しません: %v", domains)
+	}
+}
+
+// TestScheduler_BatchDuckDNS は、同じトークンの DuckDNS のドメインを1回のリクエストにまとめて更新することをテストします。
+func TestScheduler_BatchDuckDNS(t *testing.T) {
+	var requests atomic.Int32
+	var gotDomains atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		gotDomains.Store(r.URL.Query().Get("domains"))
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	duck := provider.NewDuckDNS(client, "test-token")
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, duck, "a")
+	s.AddTarget(duck, "b")
+	s.AddTarget(&MockProvider{}, "c")
+
+	results := s.CheckOnce(context.Background())
+	for _, r := range results {
+		if !r.Updated || r.Err != nil {
+			t.Errorf("すべての更新先の更新に成功するべき: %+v", r)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("同じトークンのドメインは1回のリクエストにまとめるべき。リクエスト数: %d", got)
+	}
+	if got := gotDomains.Load(); got != "a,b" {
+		t.Errorf("domains が一致しません: %v", got)
+	}
+}
+
+// TestScheduler_DualStack は、IPv6 も更新する場合に DuckDNS へ ip と ipv6 を1回のリクエストで送信し、
+// 種類ごとの結果を返すことをテストします。
+func TestScheduler_DualStack(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("ip") == "" || r.URL.Query().Get("ipv6") == "" {
+			t.Errorf("ip と ipv6 を同じリクエストで送信するべき: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client := duckdns.NewClientWithOptions(server.Client(), server.URL, duckdns.RetryConfig{})
+	ipv6 := "2001:db8::1"
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	ipv6Fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return ipv6, nil }}
+
+	s := NewScheduler(time.Hour, fetcher, client, "test-domain", "test-token")
+	s.SetIPv6Fetcher(ipv6Fetcher)
+
+	result := s.CheckOnce(context.Background())[0]
+	if !result.Updated || !result.UpdatedIPv6 || result.Err != nil || result.NewIPv6 != ipv6 {
+		t.Errorf("A と AAAA の両方を更新するべき: %+v", result)
+	}
+
+	// IPv6 だけが変わった場合も、ip と ipv6 を一緒に送信する
+	ipv6 = "2001:db8::2"
+	result = s.CheckOnce(context.Background())[0]
+	if result.Updated || !result.UpdatedIPv6 || result.OldIPv6 != "2001:db8::1" {
+		t.Errorf("AAAA だけが更新されたと報告するべき: %+v", result)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("チェックごとに1回のリクエストであるべき。リクエスト数: %d", got)
+	}
+}
+
+// TestScheduler_DualStackFallback は、同時更新に対応していないプロバイダーは種類ごとに更新し、
+// IPv6 の取得に失敗した場合は IPv4 だけを更新することをテストします。
+func TestScheduler_DualStackFallback(t *testing.T) {
+	var mu sync.Mutex
+	var updated []string
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		updated = append(updated, ip)
+		return nil
+	}}
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	ipv6Err := errors.New("no ipv6 route")
+	ipv6Fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "", ipv6Err }}
+
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "a.example.com")
+	s.SetIPv6Fetcher(ipv6Fetcher)
+
+	result := s.CheckOnce(context.Background())[0]
+	if !result.Updated || result.UpdatedIPv6 {
+		t.Errorf("IPv4 だけを更新するべき: %+v", result)
+	}
+	if !errors.Is(result.IPv6Err, ipv6Err) || !errors.Is(result.Err, ipv6Err) {
+		t.Errorf("IPv6 の取得の失敗を報告するべき: %+v", result)
+	}
+
+	ipv6Fetcher.FetchFunc = func(ctx context.Context) (string, error) { return "2001:db8::1", nil }
+	result = s.CheckOnce(context.Background())[0]
+	if result.Updated || !result.UpdatedIPv6 || result.Err != nil {
+		t.Errorf("AAAA だけを更新するべき: %+v", result)
+	}
+	if strings.Join(updated, ",") != "192.0.2.1,2001:db8::1" {
+		t.Errorf("種類ごとに Update を呼び出すべき: %v", updated)
+	}
+}
+
+// detailedFetcher は、詳細な結果を返すテスト用の Fetcher です。
+type detailedFetcher struct {
+	result ip.FetchResult
+}
+
+// Fetch は、IPアドレスだけを返します。
+func (f *detailedFetcher) Fetch(ctx context.Context) (string, error) {
+	return f.result.IP, nil
+}
+
+// FetchDetailed は、設定した詳細な結果を返します。
+func (f *detailedFetcher) FetchDetailed(ctx context.Context) (ip.FetchResult, error) {
+	return f.result, nil
+}
+
+// TestScheduler_FetchResult は、IPアドレスを返したソースが結果と Recorder に渡されることをテストします。
+func TestScheduler_FetchResult(t *testing.T) {
+	fetched := ip.FetchResult{IP: "192.168.1.1", Source: "https://api.ipify.org", Latency: 30 * time.Millisecond, Attempts: 2}
+	p := &MockProvider{}
+	recorder := &mockRecorder{}
+
+	s := NewSchedulerWithProvider(time.Hour, &detailedFetcher{result: fetched}, p, "test-domain")
+	s.SetRecorder(recorder)
+	results := s.CheckOnce(context.Background())
+
+	if len(results) != 1 || results[0].Fetch != fetched {
+		t.Fatalf("結果の Fetch = %+v, 期待値 %+v", results, fetched)
+	}
+	if len(recorder.results) != 1 || recorder.results[0].source != "https://api.ipify.org" {
+		t.Errorf("Recorder に IP取得ソースが記録されるべき: %+v", recorder.results)
+	}
+}
+
+// TestWithGrace は、停止を求められても猶予時間の間はキャンセルされないことをテストします。
+func TestWithGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	work, stop := WithGrace(ctx, 50*time.Millisecond)
+	defer stop()
+
+	cancel()
+	select {
+	case <-work.Done():
+		t.Fatal("猶予時間の間はキャンセルされないべき")
+	case <-time.After(10 * time.Millisecond):
+	}
+	select {
+	case <-work.Done():
+	case <-time.After(time.Second):
+		t.Fatal("猶予時間を過ぎたらキャンセルされるべき")
+	}
+
+	// 猶予時間が 0 の場合は、すぐにキャンセルされる
+	ctx, cancel = context.WithCancel(context.Background())
+	work, stop = WithGrace(ctx, 0)
+	defer stop()
+	cancel()
+	if work.Err() == nil {
+		t.Error("猶予時間が 0 の場合はすぐにキャンセルされるべき")
+	}
+}
+
+// TestScheduler_Run_ShutdownGrace は、停止を求められても実行中の更新を中断せずに終えることをテストします。
+func TestScheduler_Run_ShutdownGrace(t *testing.T) {
+	mockFetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	started := make(chan struct{})
+	var updateErr atomic.Value
+	mockProvider := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		close(started)
+		select {
+		case <-ctx.Done():
+			updateErr.Store(ctx.Err())
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		}
+	}}
+	scheduler := NewSchedulerWithProvider(time.Hour, mockFetcher, mockProvider, "home")
+	scheduler.SetShutdownGrace(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("実行中の更新が終わったら停止するべき")
+	}
+	if err := updateErr.Load(); err != nil {
+		t.Errorf("猶予時間の間は更新を中断しないべき: %v", err)
+	}
+	if scheduler.targets[0].lastIP != "192.0.2.1" {
+		t.Errorf("更新を終えてから停止するべき。lastIP: %s", scheduler.targets[0].lastIP)
+	}
+}
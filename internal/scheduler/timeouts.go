@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/ip"
+)
+
+// Timeouts は、チェックと更新の段階ごとのタイムアウトです。
+// 回線が遅い環境で、段階ごとに待つ時間を変えられるように、コンテキストの期限として設定します。
+// 0 の段階は、Scheduler では期限を設けません（Fetcher やプロバイダーの HTTP のタイムアウトだけになります）。
+type Timeouts struct {
+	// Fetch は、IPアドレスの取得（IPv4 と IPv6 のそれぞれ。ソースへのフォールバックを含む）のタイムアウトです
+	Fetch time.Duration
+
+	// Update は、プロバイダーでの1回の更新（まとめた更新は1回。プロバイダーの再試行を含む）のタイムアウトです
+	Update time.Duration
+}
+
+// SetTimeouts は、チェックと更新の段階ごとのタイムアウトを設定します。
+// Run または RunOnce の前に呼び出してください。
+//
+// Parameters:
+//   - t: 段階ごとのタイムアウト（0 の段階は期限を設けません）
+func (s *Scheduler) SetTimeouts(t Timeouts) {
+	s.timeouts = t
+}
+
+// fetch は、Fetch のタイムアウトを期限にして、f で IPアドレスを取得します。
+func (s *Scheduler) fetch(ctx context.Context, f ip.Fetcher) (ip.FetchResult, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Fetch)
+	defer cancel()
+	return ip.FetchDetailed(ctx, f)
+}
+
+// withTimeout は、d が 0 より大きい場合は d を期限にしたコンテキストを返し、それ以外は期限のないコンテキストを返します。
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestScheduler_SetTimeouts は、IPアドレスの取得と更新に、段階ごとのタイムアウトを期限として渡すことをテストします。
+func TestScheduler_SetTimeouts(t *testing.T) {
+	var fetchDeadline, updateDeadline time.Duration
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		deadline, _ := ctx.Deadline()
+		fetchDeadline = time.Until(deadline)
+		return "192.0.2.1", nil
+	}}
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		deadline, _ := ctx.Deadline()
+		updateDeadline = time.Until(deadline)
+		return nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetTimeouts(Timeouts{Fetch: 30 * time.Second, Update: time.Minute})
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if fetchDeadline <= 0 || fetchDeadline > 30*time.Second {
+		t.Errorf("取得の期限は30秒以内であるべき: %s", fetchDeadline)
+	}
+	if updateDeadline <= 30*time.Second || updateDeadline > time.Minute {
+		t.Errorf("更新の期限は1分以内であるべき: %s", updateDeadline)
+	}
+}
+
+// TestScheduler_SetTimeouts_Exceeded は、更新がタイムアウトを過ぎたら中断して失敗にし、次のチェックで再び更新することをテストします。
+func TestScheduler_SetTimeouts_Exceeded(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		return "192.0.2.1", nil
+	}}
+	updates := 0
+	p := &MockProvider{UpdateFunc: func(ctx context.Context, domain, ip string) error {
+		updates++
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+	s.SetTimeouts(Timeouts{Update: 10 * time.Millisecond})
+
+	if err := s.RunOnce(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("タイムアウトで失敗するべき: %v", err)
+	}
+	_ = s.RunOnce(context.Background())
+	if updates != 2 {
+		t.Errorf("タイムアウトした更新は、次のチェックで再び更新するべき: %d", updates)
+	}
+}
+
+// TestScheduler_NoTimeouts は、タイムアウトを設定しない場合は期限を設けないことをテストします。
+func TestScheduler_NoTimeouts(t *testing.T) {
+	hasDeadline := true
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		_, hasDeadline = ctx.Deadline()
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if hasDeadline {
+		t.Error("タイムアウトを設定しない場合は期限を設けないべき")
+	}
+}
@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/horitaku/duckdns/internal/ip"
+)
+
+// TriggerSource は、Trigger で通知されたIPアドレスの取得元です（Result.Fetch.Source に入ります）。
+const TriggerSource = "trigger"
+
+// triggerRequest は、Trigger で求められたチェックと更新です。
+type triggerRequest struct {
+	// ipv4 と ipv6 は、通知されたIPアドレスです（空の場合は IP取得ソースから取得します）
+	ipv4, ipv6 string
+
+	// done は、チェックと更新の結果を返します
+	done chan []Result
+}
+
+// Trigger は、次の定期チェックを待たずに、チェックと更新を実行します。
+// ルーターが WAN のアドレスの変更を通知してきた場合などに使います。
+// ipv4 を指定した場合は、IP取得ソースに問い合わせずにそのアドレスで更新します。
+// 実行中の Run が処理するため、Run を実行していない間は ctx がキャンセルされるまで待ちます。
+//
+// Parameters:
+//   - ctx: 待つ時間を制御するコンテキスト
+//   - ipv4: 通知された IPv4アドレス（空の場合は IP取得ソースから取得します）
+//   - ipv6: 通知された IPv6アドレス（空の場合は、IPv6 を更新するときだけ IP取得ソースから取得します）
+//
+// Returns:
+//   - []Result: 更新先ごとのチェックと更新の結果（AddTarget で追加した順）
+//   - error: 結果を受け取る前に ctx がキャンセルされた場合
+func (s *Scheduler) Trigger(ctx context.Context, ipv4, ipv6 string) ([]Result, error) {
+	req := triggerRequest{ipv4: ipv4, ipv6: ipv6, done: make(chan []Result, 1)}
+	select {
+	case s.triggers <- req:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case results := <-req.done:
+		return results, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// checkTriggered は、Trigger で求められたチェックと更新を実行します。
+// 通知されたIPアドレスがない場合は、定期チェックと同じく IP取得ソースから取得します。
+func (s *Scheduler) checkTriggered(ctx context.Context, ipv4, ipv6 string) []Result {
+	if ipv4 == "" {
+		slog.Info("更新を求められたため、IP アドレスをチェックします", "domains", s.Domains())
+		return s.checkAndUpdate(ctx)
+	}
+
+	slog.Info("通知された IP アドレスで更新します",
+		"ip", ipv4,
+		"ipv6", ipv6,
+		"domains", s.Domains(),
+	)
+	start := s.clock.Now()
+	fetched := ip.FetchResult{IP: ipv4, Source: TriggerSource}
+	var fetchedIPv6 ip.FetchResult
+	var ipv6Err error
+	switch {
+	case s.ipv6Fetcher == nil:
+		// IPv6 を更新しない場合は、通知された IPv6アドレスも使いません
+	case ipv6 != "":
+		fetchedIPv6 = ip.FetchResult{IP: ipv6, Source: TriggerSource}
+	default:
+		s.pool.run(func() {
+			fetchedIPv6, ipv6Err = s.fetch(ctx, s.ipv6Fetcher)
+		})
+		if ipv6Err != nil {
+			slog.Warn("IPv6 アドレスの取得に失敗しました（IPv4 だけを更新します）",
+				"error", ipv6Err,
+			)
+		}
+	}
+
+	results := s.newResults()
+	for i := range results {
+		results[i].Fetch = fetched
+		results[i].FetchIPv6 = fetchedIPv6
+	}
+	s.updateFetched(ctx, start, fetched, fetchedIPv6, ipv6Err, results)
+	return results
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestScheduler_Trigger は、Trigger で通知されたIPアドレスで、定期チェックを待たずに更新することをテストします。
+func TestScheduler_Trigger(t *testing.T) {
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) { return "192.0.2.1", nil }}
+	p := &recordingProvider{}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, p, "home")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	wait, stop := context.WithTimeout(context.Background(), 2*time.Second)
+	defer stop()
+
+	// 通知されたアドレスは、IP取得ソースに問い合わせずに使います
+	results, err := s.Trigger(wait, "192.0.2.2", "")
+	if err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+	if len(results) != 1 || !results[0].Updated || results[0].Fetch.Source != TriggerSource {
+		t.Errorf("通知されたアドレスで更新するべき: %+v", results)
+	}
+	if fetcher.GetFetchCount() != 1 {
+		t.Errorf("起動直後のチェックのほかに IP取得ソースに問い合わせるべきではない: %d 回", fetcher.GetFetchCount())
+	}
+
+	// 同じアドレスの通知では更新しません
+	results, err = s.Trigger(wait, "192.0.2.2", "")
+	if err != nil || results[0].Updated {
+		t.Errorf("同じアドレスでは更新するべきではない: %+v, %v", results, err)
+	}
+
+	// アドレスを省略した場合は、IP取得ソースから取得します
+	results, err = s.Trigger(wait, "", "")
+	if err != nil || !results[0].Updated || results[0].NewIP != "192.0.2.1" {
+		t.Errorf("IP取得ソースのアドレスで更新するべき: %+v, %v", results, err)
+	}
+
+	if got, want := p.updates(), []string{"192.0.2.1", "192.0.2.2", "192.0.2.1"}; !slices.Equal(got, want) {
+		t.Errorf("更新 = %v (期待値 %v)", got, want)
+	}
+}
+
+// TestScheduler_Trigger_NotRunning は、Run を実行していない場合に ctx のキャンセルで戻ることをテストします。
+func TestScheduler_Trigger_NotRunning(t *testing.T) {
+	s := NewSchedulerWithProvider(time.Hour, &MockFetcher{}, &recordingProvider{}, "home")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.Trigger(ctx, "192.0.2.1", ""); err == nil {
+		t.Error("Run を実行していない場合はエラーを返すべき")
+	}
+}
@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWatchdogFactor は、SetWatchdog の倍率を省略した場合の値です。
+const DefaultWatchdogFactor = 3
+
+// minWatchdogFactor は、SetWatchdog の倍率の下限です。
+// 定期チェックの間隔の1倍では、所要時間の分だけ遅れたチェックでもループを止めてしまうためです。
+const minWatchdogFactor = 2
+
+// watchdogWaitLogInterval は、止めたループが戻るのを待っている間に、エラーのログを出力する間隔です。
+// コンテキストのキャンセルに応じない処理で止まっている場合も、ループの状態を2つのループで同時に変更しないよう、
+// 止めたループが戻るまでは新しいループを始めません（テストで短くするため変数にしています）。
+var watchdogWaitLogInterval = 5 * time.Second
+
+// watchdogStackSize は、診断のためにログに出力するゴルーチンのスタックトレースの最大サイズです。
+const watchdogStackSize = 64 << 10
+
+// watchdog は、チェックと更新が終わらなくなったループを見張る状態です。
+type watchdog struct {
+	// factor は、ループを止めるまでに待つ時間の、定期チェックの間隔に対する倍率です（0 の場合は見張りません）
+	factor int
+
+	// lastCycle は、最後にチェックと更新が終わった時刻（Unix 時間のナノ秒）です
+	lastCycle atomic.Int64
+
+	// restarts は、ループを止めて始め直した回数です
+	restarts int
+}
+
+// beat は、チェックと更新が終わったことを記録します。
+func (w *watchdog) beat(now time.Time) {
+	w.lastCycle.Store(now.UnixNano())
+}
+
+// last は、最後にチェックと更新が終わった時刻を返します。
+func (w *watchdog) last() time.Time {
+	return time.Unix(0, w.lastCycle.Load())
+}
+
+// SetWatchdog は、チェックと更新が終わらなくなったループを見張ります。
+// 定期チェックの間隔の factor 倍の時間、1回もチェックと更新が終わらなかった場合は、
+// タイムアウトを設定していても止まってしまった通信などがあるとみなし、診断のためのログ（ゴルーチンのスタックトレース）を出力して、
+// 実行中のチェックと更新を中断し、中断した処理が戻るのを待ってから、ループを始め直します（始め直すとすぐにチェックと更新を実行します）。
+// Run または Start の前に呼び出してください。
+//
+// Parameters:
+//   - factor: ループを止めるまでに待つ時間の、定期チェックの間隔に対する倍率（0 以下の場合は DefaultWatchdogFactor、2 未満の場合は 2）
+func (s *Scheduler) SetWatchdog(factor int) {
+	switch {
+	case factor <= 0:
+		factor = DefaultWatchdogFactor
+	case factor < minWatchdogFactor:
+		factor = minWatchdogFactor
+	}
+	s.watchdog.factor = factor
+}
+
+// supervise は、ループを実行し、チェックと更新が終わらなくなったら中断して始め直します（内部用ヘルパー関数）
+// ctx がキャンセルされ、ループが戻ると戻ります。
+func (s *Scheduler) supervise(ctx, work context.Context) {
+	limit := time.Duration(s.watchdog.factor) * s.interval
+	ticker := s.clock.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		loopCtx, stop := context.WithCancel(ctx)
+		loopWork, abort := context.WithCancel(work)
+		done := make(chan struct{})
+		s.watchdog.beat(s.clock.Now())
+		go func() {
+			defer close(done)
+			s.runLoop(loopCtx, loopWork)
+		}()
+
+		if !s.waitWedged(ctx, ticker, done, limit) {
+			stop()
+			abort()
+			return
+		}
+
+		s.dumpWedged(limit)
+		stop()
+		abort()
+		s.waitAbandoned(done)
+		if ctx.Err() != nil {
+			return
+		}
+		s.watchdog.restarts++
+	}
+}
+
+// waitWedged は、ループが limit の間チェックと更新を終えなくなるまで待ちます。
+// ループが戻った場合（ctx がキャンセルされた場合）は false を返します。
+// 停止を求められた後は、猶予時間の間に終わるのを待つため、ループを止めません。
+func (s *Scheduler) waitWedged(ctx context.Context, ticker Ticker, done <-chan struct{}, limit time.Duration) bool {
+	for {
+		select {
+		case <-done:
+			return false
+		case <-ticker.C():
+			if ctx.Err() == nil && s.since(s.watchdog.last()) >= limit {
+				return true
+			}
+		}
+	}
+}
+
+// waitAbandoned は、止めたループが戻るまで待ちます。
+// 戻るまでの間は、watchdogWaitLogInterval ごとにエラーのログを出力します。
+func (s *Scheduler) waitAbandoned(done <-chan struct{}) {
+	started := time.Now()
+	ticker := time.NewTicker(watchdogWaitLogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			slog.Error("中断したチェックと更新が戻らないため、戻るまでループを始め直さずに待ちます",
+				"domains", s.Domains(),
+				"waited", time.Since(started).Round(time.Second),
+			)
+		}
+	}
+}
+
+// dumpWedged は、ループを止める理由と、診断のためのゴルーチンのスタックトレースをログに出力します。
+func (s *Scheduler) dumpWedged(limit time.Duration) {
+	buf := make([]byte, watchdogStackSize)
+	buf = buf[:runtime.Stack(buf, true)]
+	slog.Error("チェックと更新が終わらなくなったため、中断してループを始め直します",
+		"domains", s.Domains(),
+		"last_cycle", s.watchdog.last(),
+		"limit", limit,
+		"restarts", s.watchdog.restarts,
+		"goroutines", runtime.NumGoroutine(),
+		"stack", string(buf),
+	)
+}
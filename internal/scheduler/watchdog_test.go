@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestScheduler_SetWatchdog は、チェックと更新が間隔の倍率の時間終わらなかった場合に、中断してループを始め直すことをテストします。
+func TestScheduler_SetWatchdog(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	started := make(chan struct{}, 10)
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{}
+	fetcher.FetchFunc = func(ctx context.Context) (string, error) {
+		if fetcher.GetFetchCount() == 1 {
+			// 最初のチェックは、中断されるまで終わらない
+			started <- struct{}{}
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetClock(clock)
+	s.SetWatchdog(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	<-started
+
+	clock.Advance(time.Hour)
+	select {
+	case <-checked:
+		t.Fatal("倍率の時間が経つまでは始め直さないべき")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(time.Hour)
+	select {
+	case <-checked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("倍率の時間チェックが終わらなければ、始め直してチェックするべき")
+	}
+
+	cancel()
+	<-done
+	if got := fetcher.GetFetchCount(); got != 2 {
+		t.Errorf("チェックは2回であるべき: %d", got)
+	}
+	if s.watchdog.restarts != 1 {
+		t.Errorf("始め直したのは1回であるべき: %d", s.watchdog.restarts)
+	}
+}
+
+// TestScheduler_SetWatchdog_Healthy は、定期チェックが終わっている間は、ループを始め直さないことをテストします。
+func TestScheduler_SetWatchdog_Healthy(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{FetchFunc: func(ctx context.Context) (string, error) {
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetClock(clock)
+	s.SetWatchdog(1) // 2 に切り上げる
+	if s.watchdog.factor != minWatchdogFactor {
+		t.Errorf("倍率は下限に切り上げるべき: %d", s.watchdog.factor)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	<-checked
+	for clock.tickerCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Hour)
+		select {
+		case <-checked:
+		case <-time.After(2 * time.Second):
+			t.Fatal("時計を間隔だけ進めたら、定期チェックをするべき")
+		}
+	}
+
+	cancel()
+	<-done
+	if s.watchdog.restarts != 0 || clock.tickerCount() != 2 {
+		t.Errorf("チェックが終わっている間は始め直さないべき: restarts=%d tickers=%d", s.watchdog.restarts, clock.tickerCount())
+	}
+}
+
+// TestScheduler_SetWatchdog_IgnoresContext は、中断に応じないチェックが戻るまでは、ループを始め直さないことをテストします。
+func TestScheduler_SetWatchdog_IgnoresContext(t *testing.T) {
+	// 戻るのを待つ間のログを何度も出力させ、それでも始め直さないことを確かめる
+	orig := watchdogWaitLogInterval
+	watchdogWaitLogInterval = 10 * time.Millisecond
+	defer func() { watchdogWaitLogInterval = orig }()
+
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	checked := make(chan struct{}, 10)
+	fetcher := &MockFetcher{}
+	fetcher.FetchFunc = func(ctx context.Context) (string, error) {
+		if fetcher.GetFetchCount() == 1 {
+			// 最初のチェックは、ctx を無視して release が閉じられるまで終わらない
+			started <- struct{}{}
+			<-release
+			return "", context.Canceled
+		}
+		checked <- struct{}{}
+		return "192.0.2.1", nil
+	}
+	s := NewSchedulerWithProvider(time.Hour, fetcher, &MockProvider{}, "home")
+	s.SetClock(clock)
+	s.SetWatchdog(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	<-started
+
+	clock.Advance(2 * time.Hour)
+	select {
+	case <-checked:
+		t.Fatal("中断したチェックが戻るまでは始め直さないべき")
+	case <-time.After(200 * time.Millisecond):
+	}
+	if got := fetcher.GetFetchCount(); got != 1 {
+		t.Errorf("中断したチェックが戻るまでは、チェックは1回であるべき: %d", got)
+	}
+
+	close(release)
+	select {
+	case <-checked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("中断したチェックが戻ったら、始め直してチェックするべき")
+	}
+
+	cancel()
+	<-done
+	if s.watchdog.restarts != 1 {
+		t.Errorf("始め直したのは1回であるべき: %d", s.watchdog.restarts)
+	}
+}
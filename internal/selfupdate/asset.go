@@ -0,0 +1,172 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// maxBinarySize は、アーカイブから取り出す実行ファイルの最大サイズです。
+const maxBinarySize = 200 << 20
+
+// Platform は、ダウンロードするアーカイブの OS とアーキテクチャです。
+type Platform struct {
+	// OS は、GOOS の値です（例: "linux"）
+	OS string
+
+	// Arch は、GOARCH の値です（例: "amd64"）
+	Arch string
+
+	// ARM は、Arch が "arm" の場合の GOARM の値です（例: "7"）
+	ARM string
+}
+
+// CurrentPlatform は、今の実行ファイルの Platform を返します。
+// GOARM はビルド情報から読み込み、分からない場合は両方の機器で動く "6" にします。
+func CurrentPlatform() Platform {
+	p := Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if p.Arch != "arm" {
+		return p
+	}
+	p.ARM = "6"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "GOARM" && setting.Value != "" {
+				// "7,softfloat" のような値は、番号だけを使います
+				p.ARM, _, _ = strings.Cut(setting.Value, ",")
+			}
+		}
+	}
+	return p
+}
+
+// String は、"linux/armv7" のような表示用の文字列を返します。
+func (p Platform) String() string {
+	if p.ARM != "" {
+		return p.OS + "/" + p.Arch + "v" + p.ARM
+	}
+	return p.OS + "/" + p.Arch
+}
+
+// ArchiveName は、GoReleaser の設定（archives.name_template）と同じ規則でアーカイブのファイル名を返します。
+// 例: "duckdns_1.2.3_linux_x86_64.tar.gz"、"duckdns_1.2.3_linux_armv7.tar.gz"、"duckdns_1.2.3_windows_x86_64.zip"
+//
+// Parameters:
+//   - version: リリースのバージョン（"v" は付けません）
+//
+// Returns:
+//   - string: アーカイブのファイル名
+func (p Platform) ArchiveName(version string) string {
+	arch := p.Arch
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	if p.Arch == "arm" && p.ARM != "" {
+		arch += "v" + p.ARM
+	}
+	ext := ".tar.gz"
+	if p.OS == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("duckdns_%s_%s_%s%s", strings.TrimPrefix(version, "v"), p.OS, arch, ext)
+}
+
+// BinaryName は、アーカイブの中の実行ファイルの名前を返します。
+func (p Platform) BinaryName() string {
+	if p.OS == "windows" {
+		return "duckdns.exe"
+	}
+	return "duckdns"
+}
+
+// ExtractBinary は、アーカイブ（.tar.gz または .zip）から実行ファイルを取り出します。
+// 実行ファイルは、アーカイブの直下またはサブディレクトリにある、名前が一致する通常のファイルです。
+//
+// Parameters:
+//   - archiveName: アーカイブのファイル名（拡張子で形式を判断します）
+//   - data: アーカイブの内容
+//   - binaryName: 取り出す実行ファイルの名前
+//
+// Returns:
+//   - []byte: 実行ファイルの内容
+//   - error: アーカイブが壊れている場合、または実行ファイルが見つからない場合
+func ExtractBinary(archiveName string, data []byte, binaryName string) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(archiveName, ".tar.gz"), strings.HasSuffix(archiveName, ".tgz"):
+		return extractTarGz(data, binaryName)
+	case strings.HasSuffix(archiveName, ".zip"):
+		return extractZip(data, binaryName)
+	}
+	return nil, fmt.Errorf("対応していないアーカイブの形式です: %s", archiveName)
+}
+
+// extractTarGz は、.tar.gz から実行ファイルを取り出します。
+func extractTarGz(data []byte, binaryName string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("アーカイブの展開に失敗しました: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("アーカイブの展開に失敗しました: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || path.Base(header.Name) != binaryName {
+			continue
+		}
+		return readBinary(tr)
+	}
+	return nil, fmt.Errorf("アーカイブに %s が見つかりません", binaryName)
+}
+
+// extractZip は、.zip から実行ファイルを取り出します。
+func extractZip(data []byte, binaryName string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("アーカイブの展開に失敗しました: %w", err)
+	}
+	for _, file := range zr.File {
+		if !file.Mode().IsRegular() || path.Base(file.Name) != binaryName {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("アーカイブの展開に失敗しました: %w", err)
+		}
+		defer rc.Close()
+		return readBinary(rc)
+	}
+	return nil, fmt.Errorf("アーカイブに %s が見つかりません", binaryName)
+}
+
+// readBinary は、maxBinarySize までの実行ファイルを読み込みます。
+func readBinary(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBinarySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("アーカイブの展開に失敗しました: %w", err)
+	}
+	if len(data) > maxBinarySize {
+		return nil, fmt.Errorf("実行ファイルが大きすぎます (上限: %d バイト)", maxBinarySize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("アーカイブの実行ファイルが空です")
+	}
+	return data, nil
+}
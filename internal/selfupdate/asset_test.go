@@ -0,0 +1,87 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+// TestPlatform_ArchiveName は、GoReleaser と同じ規則でアーカイブのファイル名を作ることをテストします。
+func TestPlatform_ArchiveName(t *testing.T) {
+	tests := []struct {
+		platform Platform
+		want     string
+	}{
+		{Platform{OS: "linux", Arch: "amd64"}, "duckdns_1.2.3_linux_x86_64.tar.gz"},
+		{Platform{OS: "linux", Arch: "arm64"}, "duckdns_1.2.3_linux_arm64.tar.gz"},
+		{Platform{OS: "linux", Arch: "arm", ARM: "7"}, "duckdns_1.2.3_linux_armv7.tar.gz"},
+		{Platform{OS: "darwin", Arch: "arm64"}, "duckdns_1.2.3_darwin_arm64.tar.gz"},
+		{Platform{OS: "windows", Arch: "amd64"}, "duckdns_1.2.3_windows_x86_64.zip"},
+	}
+	for _, tt := range tests {
+		if got := tt.platform.ArchiveName("v1.2.3"); got != tt.want {
+			t.Errorf("%s: %s, 期待値 %s", tt.platform, got, tt.want)
+		}
+	}
+	if got := (Platform{OS: "windows", Arch: "amd64"}).BinaryName(); got != "duckdns.exe" {
+		t.Errorf("Windows の実行ファイルは duckdns.exe であるべき: %s", got)
+	}
+}
+
+// makeTarGz は、テスト用の .tar.gz を作成します。
+func makeTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestExtractBinary は、.tar.gz と .zip から実行ファイルだけを取り出すことをテストします。
+func TestExtractBinary(t *testing.T) {
+	tarData := makeTarGz(t, map[string]string{
+		"README.md":   "readme",
+		"duckdns":     "binary",
+		"deploy/x.sh": "script",
+	})
+	got, err := ExtractBinary("duckdns_1.2.3_linux_x86_64.tar.gz", tarData, "duckdns")
+	if err != nil || string(got) != "binary" {
+		t.Errorf(".tar.gz から取り出せるべき: %q %v", got, err)
+	}
+	if _, err := ExtractBinary("a.tar.gz", tarData, "duckdns.exe"); err == nil {
+		t.Error("実行ファイルがない場合はエラーになるべき")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("duckdns_1.2.3_windows_x86_64/duckdns.exe")
+	w.Write([]byte("exe"))
+	zw.Close()
+	got, err = ExtractBinary("duckdns_1.2.3_windows_x86_64.zip", buf.Bytes(), "duckdns.exe")
+	if err != nil || string(got) != "exe" {
+		t.Errorf(".zip のサブディレクトリから取り出せるべき: %q %v", got, err)
+	}
+
+	if _, err := ExtractBinary("a.tar.gz", []byte("not gzip"), "duckdns"); err == nil {
+		t.Error("壊れたアーカイブはエラーになるべき")
+	}
+	if _, err := ExtractBinary("a.rar", tarData, "duckdns"); err == nil {
+		t.Error("対応していない形式はエラーになるべき")
+	}
+}
@@ -0,0 +1,222 @@
+// Package selfupdate は、GitHub のリリースから新しいバージョンを確認し、実行ファイルを検証して置き換える機能を提供します。
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepository は、リリースを確認する GitHub のリポジトリです
+	DefaultRepository = "horitaku/duckdns"
+
+	// DefaultAPIURL は、GitHub API のベース URL です
+	DefaultAPIURL = "https://api.github.com"
+
+	// DefaultTimeout は、HTTP クライアントを省略した場合のリクエストのタイムアウトです
+	DefaultTimeout = 5 * time.Minute
+
+	// maxMetadataSize は、リリース情報とチェックサムのファイルとして読み込む最大サイズです
+	maxMetadataSize = 1 << 20
+
+	// maxArchiveSize は、ダウンロードするアーカイブの最大サイズです
+	maxArchiveSize = 200 << 20
+)
+
+// ErrNoRelease は、リポジトリにリリースがない場合のエラーです。
+var ErrNoRelease = errors.New("リリースが見つかりません")
+
+// errNotFound は、URL が 404 を返した場合のエラーです。
+var errNotFound = errors.New("見つかりません (404)")
+
+// Release は、GitHub のリリースです。
+type Release struct {
+	// Tag は、リリースのタグ名です（例: "v1.2.3"）
+	Tag string `json:"tag_name"`
+
+	// URL は、リリースのページの URL です
+	URL string `json:"html_url"`
+
+	// PublishedAt は、リリースを公開した日時です
+	PublishedAt time.Time `json:"published_at"`
+
+	// Assets は、リリースに添付されたファイルです
+	Assets []Asset `json:"assets"`
+}
+
+// Asset は、リリースに添付されたファイルです。
+type Asset struct {
+	// Name は、ファイル名です
+	Name string `json:"name"`
+
+	// URL は、ファイルをダウンロードする URL です
+	URL string `json:"browser_download_url"`
+
+	// Size は、ファイルのサイズ（バイト）です
+	Size int64 `json:"size"`
+}
+
+// Version は、タグから先頭の "v" を除いたバージョンを返します（例: "1.2.3"）。
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Asset は、指定した名前の添付ファイルを返します。
+//
+// Parameters:
+//   - name: ファイル名
+//
+// Returns:
+//   - Asset: 添付ファイル
+//   - bool: 見つかった場合は true
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Source は、リリースを取得する GitHub のリポジトリです。
+type Source struct {
+	// Repository は、"owner/name" 形式のリポジトリです（空の場合は DefaultRepository）
+	Repository string
+
+	// APIURL は、GitHub API のベース URL です（空の場合は DefaultAPIURL）
+	APIURL string
+
+	// Token は、API のレート制限を緩和するためのトークンです（空の場合は認証しません）
+	Token string
+
+	// HTTPClient は、リクエストに使う HTTP クライアントです（nil の場合は DefaultTimeout の新しいクライアント）
+	HTTPClient *http.Client
+
+	// UserAgent は、リクエストの User-Agent です
+	UserAgent string
+}
+
+// Latest は、最新のリリースを取得します。
+// ドラフトとプレリリースは、GitHub API が除外します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//
+// Returns:
+//   - *Release: 最新のリリース
+//   - error: 取得に失敗した場合（リリースがない場合は ErrNoRelease）
+func (s Source) Latest(ctx context.Context) (*Release, error) {
+	return s.release(ctx, "releases/latest")
+}
+
+// Tag は、指定したタグのリリースを取得します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - tag: タグ名（"v" は省略できます）
+//
+// Returns:
+//   - *Release: リリース
+//   - error: 取得に失敗した場合（タグがない場合は ErrNoRelease）
+func (s Source) Tag(ctx context.Context, tag string) (*Release, error) {
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+	return s.release(ctx, "releases/tags/"+url.PathEscape(tag))
+}
+
+// release は、リポジトリの API のパスからリリースを取得します。
+func (s Source) release(ctx context.Context, path string) (*Release, error) {
+	repo := s.Repository
+	if repo == "" {
+		repo = DefaultRepository
+	}
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("リポジトリは owner/name の形式で指定してください: %s", repo)
+	}
+	base := strings.TrimSuffix(s.APIURL, "/")
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/%s", base, url.PathEscape(owner), url.PathEscape(name), path)
+
+	data, err := s.get(ctx, endpoint, "application/vnd.github+json", maxMetadataSize)
+	if errors.Is(err, errNotFound) {
+		return nil, ErrNoRelease
+	}
+	if err != nil {
+		return nil, err
+	}
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("リリース情報の解析に失敗しました: %w", err)
+	}
+	if release.Tag == "" {
+		return nil, fmt.Errorf("リリース情報にタグがありません")
+	}
+	return &release, nil
+}
+
+// Download は、添付ファイルをダウンロードします。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - asset: ダウンロードする添付ファイル
+//
+// Returns:
+//   - []byte: ファイルの内容
+//   - error: ダウンロードに失敗した場合、またはサイズが上限を超えた場合
+func (s Source) Download(ctx context.Context, asset Asset) ([]byte, error) {
+	return s.get(ctx, asset.URL, "application/octet-stream", maxArchiveSize)
+}
+
+// get は、URL から limit バイトまでの内容を取得します。
+func (s Source) get(ctx context.Context, rawURL, accept string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return nil, fmt.Errorf("%w: %s", errNotFound, rawURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return nil, fmt.Errorf("HTTPステータスエラー: %d (%s)", resp.StatusCode, rawURL)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("レスポンスの読み込みに失敗しました: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("ファイルが大きすぎます (上限: %d バイト): %s", limit, rawURL)
+	}
+	return data, nil
+}
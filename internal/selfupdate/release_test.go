@@ -0,0 +1,69 @@
+package selfupdate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSource_Latest は、GitHub API から最新のリリースを取得できることをテストします。
+func TestSource_Latest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("トークンを送るべき: %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"tag_name":"v1.2.3","html_url":"https://example.com/r","assets":[{"name":"checksums.txt","browser_download_url":"https://example.com/c","size":10}]}`))
+	}))
+	defer server.Close()
+
+	source := Source{Repository: "owner/repo", APIURL: server.URL, Token: "secret"}
+	release, err := source.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("取得に失敗しました: %v", err)
+	}
+	if release.Tag != "v1.2.3" || release.Version() != "1.2.3" {
+		t.Errorf("タグとバージョンが違います: %s %s", release.Tag, release.Version())
+	}
+	if asset, ok := release.Asset("checksums.txt"); !ok || asset.URL != "https://example.com/c" {
+		t.Errorf("添付ファイルを見つけられるべき: %+v %v", asset, ok)
+	}
+	if _, ok := release.Asset("missing"); ok {
+		t.Error("ない添付ファイルは見つからないべき")
+	}
+}
+
+// TestSource_Tag は、タグを指定してリリースを取得し、ない場合は ErrNoRelease を返すことをテストします。
+func TestSource_Tag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/releases/tags/v1.0.0":
+			w.Write([]byte(`{"tag_name":"v1.0.0"}`))
+		case "/repos/owner/repo/releases/tags/v9.9.9":
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "error", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	source := Source{Repository: "owner/repo", APIURL: server.URL}
+	release, err := source.Tag(context.Background(), "1.0.0")
+	if err != nil || release.Tag != "v1.0.0" {
+		t.Errorf("v を省略したタグで取得できるべき: %+v %v", release, err)
+	}
+	if _, err := source.Tag(context.Background(), "v9.9.9"); !errors.Is(err, ErrNoRelease) {
+		t.Errorf("ないタグは ErrNoRelease になるべき: %v", err)
+	}
+	if _, err := source.Tag(context.Background(), "v5.0.0"); err == nil || errors.Is(err, ErrNoRelease) {
+		t.Errorf("サーバーエラーは ErrNoRelease 以外のエラーになるべき: %v", err)
+	}
+	if _, err := (Source{Repository: "invalid", APIURL: server.URL}).Latest(context.Background()); err == nil {
+		t.Error("owner/name の形式でないリポジトリはエラーになるべき")
+	}
+}
@@ -0,0 +1,139 @@
+package selfupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Fetch は、リリースから Platform に合うアーカイブをダウンロードし、検証してから実行ファイルを取り出します。
+// アーカイブは checksums.txt の SHA-256 で検証します。
+// publicKey を指定した場合は、先に checksums.txt.sig の Ed25519 署名で checksums.txt を検証します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - source: リリースを取得するリポジトリ
+//   - release: ダウンロードするリリース
+//   - platform: ダウンロードするアーカイブの OS とアーキテクチャ
+//   - publicKey: 署名を検証する Base64 の Ed25519 公開鍵（空の場合は署名を検証しません）
+//
+// Returns:
+//   - []byte: 検証した実行ファイルの内容
+//   - error: アーカイブやチェックサムがない場合、ダウンロードや検証に失敗した場合
+func Fetch(ctx context.Context, source Source, release *Release, platform Platform, publicKey string) ([]byte, error) {
+	archiveName := platform.ArchiveName(release.Version())
+	archive, ok := release.Asset(archiveName)
+	if !ok {
+		return nil, fmt.Errorf("リリース %s に %s 向けのアーカイブ (%s) がありません", release.Tag, platform, archiveName)
+	}
+	checksumAsset, ok := release.Asset(ChecksumsName)
+	if !ok {
+		return nil, fmt.Errorf("リリース %s にチェックサムのファイル (%s) がありません", release.Tag, ChecksumsName)
+	}
+
+	checksums, err := source.Download(ctx, checksumAsset)
+	if err != nil {
+		return nil, fmt.Errorf("%s のダウンロードに失敗しました: %w", ChecksumsName, err)
+	}
+	if publicKey != "" {
+		sigAsset, ok := release.Asset(SignatureName)
+		if !ok {
+			return nil, ErrSignatureMissing
+		}
+		sig, err := source.Download(ctx, sigAsset)
+		if err != nil {
+			return nil, fmt.Errorf("%s のダウンロードに失敗しました: %w", SignatureName, err)
+		}
+		if err := VerifySignature(checksums, sig, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	sums, err := ParseChecksums(checksums)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := source.Download(ctx, archive)
+	if err != nil {
+		return nil, fmt.Errorf("%s のダウンロードに失敗しました: %w", archiveName, err)
+	}
+	if err := VerifyChecksum(sums, archiveName, data); err != nil {
+		return nil, err
+	}
+	return ExtractBinary(archiveName, data, platform.BinaryName())
+}
+
+// Replace は、実行ファイルを新しい内容に置き換えます。
+// 同じディレクトリに一時ファイルを書いてから rename するため、途中で失敗しても元の実行ファイルは壊れません。
+// 実行中のファイルを上書きできない Windows では、元のファイルを "<名前>.old" に移してから置き換えます。
+//
+// Parameters:
+//   - exe: 置き換える実行ファイルのパス（シンボリックリンクの場合はリンク先を置き換えます）
+//   - binary: 新しい実行ファイルの内容
+//   - check: 置き換える前に一時ファイルを確かめる関数（nil の場合は確かめません。例: 一時ファイルを実行してみる）
+//
+// Returns:
+//   - error: 書き込みや確認、置き換えに失敗した場合
+func Replace(exe string, binary []byte, check func(path string) error) error {
+	target, err := filepath.EvalSymlinks(exe)
+	if err != nil {
+		return fmt.Errorf("実行ファイルのパスが分かりません: %w", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("実行ファイルの情報を取得できません: %w", err)
+	}
+
+	dir, name := filepath.Split(target)
+	tmp, err := os.CreateTemp(dir, "."+name+".new-*")
+	if err != nil {
+		return fmt.Errorf("一時ファイルの作成に失敗しました (実行ファイルのディレクトリに書き込めるか確認してください): %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("一時ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("一時ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("一時ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0111); err != nil {
+		return fmt.Errorf("一時ファイルのパーミッションの変更に失敗しました: %w", err)
+	}
+
+	if check != nil {
+		if err := check(tmpPath); err != nil {
+			return fmt.Errorf("新しい実行ファイルを確認できませんでした: %w", err)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Rename(tmpPath, target); err != nil {
+			return fmt.Errorf("実行ファイルの置き換えに失敗しました: %w", err)
+		}
+		return nil
+	}
+
+	old := target + ".old"
+	if err := os.Remove(old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("前回の %s を削除できません: %w", old, err)
+	}
+	if err := os.Rename(target, old); err != nil {
+		return fmt.Errorf("実行ファイルの置き換えに失敗しました: %w", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		// 元の実行ファイルを戻します
+		_ = os.Rename(old, target)
+		return fmt.Errorf("実行ファイルの置き換えに失敗しました: %w", err)
+	}
+	return nil
+}
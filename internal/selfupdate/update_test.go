@@ -0,0 +1,172 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// newReleaseServer は、アーカイブとチェックサム（と署名）を配信するテスト用のサーバーとリリースを作成します。
+func newReleaseServer(t *testing.T, files map[string][]byte) (*httptest.Server, *Release) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	release := &Release{Tag: "v1.2.3"}
+	for name := range files {
+		release.Assets = append(release.Assets, Asset{Name: name, URL: server.URL + "/download/" + name})
+	}
+	return server, release
+}
+
+// TestFetch は、チェックサムと署名を検証してから実行ファイルを取り出すことをテストします。
+func TestFetch(t *testing.T) {
+	platform := Platform{OS: "linux", Arch: "amd64"}
+	archiveName := platform.ArchiveName("1.2.3")
+	archive := makeTarGz(t, map[string]string{"duckdns": "new binary"})
+	sum := sha256.Sum256(archive)
+	checksums := []byte(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName))
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, checksums)))
+
+	t.Run("検証に成功", func(t *testing.T) {
+		_, release := newReleaseServer(t, map[string][]byte{
+			archiveName:   archive,
+			ChecksumsName: checksums,
+			SignatureName: sig,
+		})
+		binary, err := Fetch(context.Background(), Source{}, release, platform, key)
+		if err != nil || string(binary) != "new binary" {
+			t.Errorf("実行ファイルを取り出せるべき: %q %v", binary, err)
+		}
+	})
+
+	t.Run("アーカイブが改ざんされている", func(t *testing.T) {
+		_, release := newReleaseServer(t, map[string][]byte{
+			archiveName:   makeTarGz(t, map[string]string{"duckdns": "evil"}),
+			ChecksumsName: checksums,
+		})
+		if _, err := Fetch(context.Background(), Source{}, release, platform, ""); err == nil {
+			t.Error("チェックサムが一致しない場合はエラーになるべき")
+		}
+	})
+
+	t.Run("チェックサムが改ざんされている", func(t *testing.T) {
+		evil := makeTarGz(t, map[string]string{"duckdns": "evil"})
+		evilSum := sha256.Sum256(evil)
+		_, release := newReleaseServer(t, map[string][]byte{
+			archiveName:   evil,
+			ChecksumsName: []byte(fmt.Sprintf("%s  %s\n", hex.EncodeToString(evilSum[:]), archiveName)),
+			SignatureName: sig,
+		})
+		if _, err := Fetch(context.Background(), Source{}, release, platform, key); err == nil {
+			t.Error("署名が一致しない場合はエラーになるべき")
+		}
+	})
+
+	t.Run("署名がない", func(t *testing.T) {
+		_, release := newReleaseServer(t, map[string][]byte{
+			archiveName:   archive,
+			ChecksumsName: checksums,
+		})
+		if _, err := Fetch(context.Background(), Source{}, release, platform, key); !errors.Is(err, ErrSignatureMissing) {
+			t.Errorf("公開鍵を指定して署名がない場合は ErrSignatureMissing になるべき: %v", err)
+		}
+	})
+
+	t.Run("プラットフォームのアーカイブがない", func(t *testing.T) {
+		_, release := newReleaseServer(t, map[string][]byte{ChecksumsName: checksums})
+		if _, err := Fetch(context.Background(), Source{}, release, platform, ""); err == nil {
+			t.Error("アーカイブがない場合はエラーになるべき")
+		}
+	})
+}
+
+// TestReplace は、実行ファイルを置き換え、確認に失敗した場合は元のままにすることをテストします。
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "duckdns")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replace(exe, []byte("broken"), func(string) error { return errors.New("実行できない") }); err == nil {
+		t.Error("確認に失敗した場合はエラーになるべき")
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Errorf("確認に失敗した場合は元のままであるべき: %q", data)
+	}
+
+	var checked string
+	err := Replace(exe, []byte("new"), func(path string) error {
+		checked = path
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != "new" {
+			return fmt.Errorf("一時ファイルの内容が違います: %q %v", data, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("置き換えに失敗しました: %v", err)
+	}
+	if filepath.Dir(checked) != dir {
+		t.Errorf("一時ファイルは同じディレクトリに作るべき: %s", checked)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new" {
+		t.Errorf("置き換わっていません: %q", data)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(exe); info.Mode().Perm() != 0755 {
+			t.Errorf("パーミッションを引き継ぐべき: %v", info.Mode().Perm())
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("一時ファイルが残っています: %v", entries)
+	}
+}
+
+// TestReplace_Symlink は、シンボリックリンクの場合はリンク先を置き換えることをテストします。
+func TestReplace_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "duckdns-1.0.0")
+	link := filepath.Join(dir, "duckdns")
+	if err := os.WriteFile(target, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("シンボリックリンクを作れません: %v", err)
+	}
+
+	if err := Replace(link, []byte("new"), nil); err != nil {
+		t.Fatalf("置き換えに失敗しました: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("リンク先が置き換わるべき: %q", data)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("シンボリックリンクは残るべき: %v", err)
+	}
+}
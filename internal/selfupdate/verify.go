@@ -0,0 +1,105 @@
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ChecksumsName は、GoReleaser がリリースに添付するチェックサムのファイル名です（checksum.name_template）
+	ChecksumsName = "checksums.txt"
+
+	// SignatureName は、チェックサムのファイルの Ed25519 署名（Base64）のファイル名です
+	SignatureName = ChecksumsName + ".sig"
+)
+
+// ErrSignatureMissing は、公開鍵を指定したのにリリースに署名がない場合のエラーです。
+var ErrSignatureMissing = errors.New("リリースに署名 (" + SignatureName + ") がありません")
+
+// ParseChecksums は、sha256sum と同じ形式（"<16進数> <ファイル名>"）のチェックサムのファイルを読み込みます。
+//
+// Parameters:
+//   - data: チェックサムのファイルの内容
+//
+// Returns:
+//   - map[string]string: ファイル名から小文字の16進数のチェックサムへのマップ
+//   - error: 形式が不正な行がある場合
+func ParseChecksums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("チェックサムのファイルの %d 行目の形式が不正です", line)
+		}
+		sum := strings.ToLower(fields[0])
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("チェックサムのファイルの %d 行目が SHA-256 ではありません", line)
+		}
+		// "*" はバイナリモードの印なので、ファイル名には含めません
+		sums[strings.TrimPrefix(fields[1], "*")] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("チェックサムのファイルの読み込みに失敗しました: %w", err)
+	}
+	return sums, nil
+}
+
+// VerifyChecksum は、ファイルの SHA-256 がチェックサムのファイルの値と一致するか確認します。
+//
+// Parameters:
+//   - sums: ParseChecksums で読み込んだチェックサム
+//   - name: ファイル名
+//   - data: ファイルの内容
+//
+// Returns:
+//   - error: チェックサムのファイルにない場合、または一致しない場合
+func VerifyChecksum(sums map[string]string, name string, data []byte) error {
+	expected, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("チェックサムのファイルに %s がありません", name)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("%s のチェックサムが一致しません (期待: %s, 実際: %s)", name, expected, actual)
+	}
+	return nil
+}
+
+// VerifySignature は、Ed25519 署名（Base64）を公開鍵（Base64）で検証します。
+// リモート設定の -config-pubkey と同じ形式の鍵と署名を使います。
+//
+// Parameters:
+//   - data: 署名したデータ（チェックサムのファイル）
+//   - sig: Base64 の署名
+//   - publicKey: Base64 の Ed25519 公開鍵
+//
+// Returns:
+//   - error: 公開鍵や署名の形式が不正な場合、または検証に失敗した場合
+func VerifySignature(data, sig []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("署名検証用の公開鍵が不正です (Base64 の Ed25519 公開鍵を指定してください)")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("署名の形式が不正です: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, signature) {
+		return fmt.Errorf("%s の署名検証に失敗しました", ChecksumsName)
+	}
+	return nil
+}
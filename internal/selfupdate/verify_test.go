@@ -0,0 +1,64 @@
+package selfupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// TestParseChecksums は、sha256sum の形式のチェックサムを読み込めることをテストします。
+func TestParseChecksums(t *testing.T) {
+	sum := sha256.Sum256([]byte("archive"))
+	hexSum := hex.EncodeToString(sum[:])
+	data := fmt.Sprintf("%s  duckdns_1.2.3_linux_x86_64.tar.gz\n\n%s *duckdns_1.2.3_windows_x86_64.zip\n", hexSum, hexSum)
+
+	sums, err := ParseChecksums([]byte(data))
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if len(sums) != 2 || sums["duckdns_1.2.3_windows_x86_64.zip"] != hexSum {
+		t.Errorf("2件のチェックサムを読み込むべき: %v", sums)
+	}
+	if err := VerifyChecksum(sums, "duckdns_1.2.3_linux_x86_64.tar.gz", []byte("archive")); err != nil {
+		t.Errorf("一致するチェックサムはエラーにしないべき: %v", err)
+	}
+	if err := VerifyChecksum(sums, "duckdns_1.2.3_linux_x86_64.tar.gz", []byte("tampered")); err == nil {
+		t.Error("一致しないチェックサムはエラーになるべき")
+	}
+	if err := VerifyChecksum(sums, "duckdns_1.2.3_darwin_arm64.tar.gz", []byte("archive")); err == nil {
+		t.Error("チェックサムのないファイルはエラーになるべき")
+	}
+
+	for _, bad := range []string{"abc duckdns", hexSum + "\n", "zz" + hexSum[2:] + " duckdns"} {
+		if _, err := ParseChecksums([]byte(bad)); err == nil {
+			t.Errorf("不正な形式はエラーになるべき: %q", bad)
+		}
+	}
+}
+
+// TestVerifySignature は、Ed25519 署名を検証できることをテストします。
+func TestVerifySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("checksums")
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+	key := base64.StdEncoding.EncodeToString(pub)
+
+	if err := VerifySignature(data, []byte(sig+"\n"), key); err != nil {
+		t.Errorf("正しい署名はエラーにしないべき: %v", err)
+	}
+	if err := VerifySignature([]byte("tampered"), []byte(sig), key); err == nil {
+		t.Error("書き換えたデータはエラーになるべき")
+	}
+	if err := VerifySignature(data, []byte("!!"), key); err == nil {
+		t.Error("不正な形式の署名はエラーになるべき")
+	}
+	if err := VerifySignature(data, []byte(sig), "short"); err == nil {
+		t.Error("不正な公開鍵はエラーになるべき")
+	}
+}
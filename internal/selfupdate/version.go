@@ -0,0 +1,116 @@
+package selfupdate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Compare は、"v1.2.3" や "1.2.3-rc.1" のようなセマンティックバージョンを比べます。
+// 足りない部分は 0 として扱い、プレリリース（"-" 以降）は同じ番号のリリースより古いとみなします。
+// "+" 以降のビルドメタデータは無視します。
+//
+// Parameters:
+//   - a: 比べるバージョン
+//   - b: 比べるバージョン
+//
+// Returns:
+//   - int: a が古い場合は -1、同じ場合は 0、a が新しい場合は 1
+func Compare(a, b string) int {
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+	for i := 0; i < 3; i++ {
+		if c := compareInt(coreA[i], coreB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return comparePrerelease(preA, preB)
+}
+
+// IsRelease は、バージョンがリリースのビルドのものかどうかを返します。
+// "dev" や "1.2.3-next"（スナップショット）のような、タグから作っていないビルドは false です。
+func IsRelease(version string) bool {
+	core, pre := splitVersion(version)
+	if pre != "" {
+		return false
+	}
+	v := strings.TrimPrefix(strings.SplitN(version, "+", 2)[0], "v")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	for i, part := range parts {
+		if n, err := strconv.Atoi(part); err != nil || n != core[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitVersion は、バージョンを数値の3つの部分とプレリリースに分けます。
+// 数値として読めない部分は 0 にします。
+func splitVersion(version string) ([3]int, string) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, pre, _ := strings.Cut(v, "-")
+
+	var core [3]int
+	for i, part := range strings.SplitN(v, ".", 3) {
+		if n, err := strconv.Atoi(part); err == nil && n >= 0 {
+			core[i] = n
+		}
+	}
+	return core, pre
+}
+
+// comparePrerelease は、プレリリースの識別子を "." ごとに比べます。
+// 数値の識別子は数値として比べ、数値は文字列より古いとみなします。
+func comparePrerelease(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if c := compareIdentifier(partsA[i], partsB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(partsA) < len(partsB):
+		return -1
+	case len(partsA) > len(partsB):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier は、プレリリースの識別子を1つ比べます。
+func compareIdentifier(a, b string) int {
+	numA, errA := strconv.Atoi(a)
+	numB, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(numA, numB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareInt は、整数を比べます。
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
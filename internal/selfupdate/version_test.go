@@ -0,0 +1,48 @@
+package selfupdate
+
+import "testing"
+
+// TestCompare は、セマンティックバージョンを比べられることをテストします。
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.9", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3-rc.1", "1.2.3", -1},
+		{"1.2.3", "1.2.3-rc.1", 1},
+		{"1.2.3-rc.2", "1.2.3-rc.10", -1},
+		{"1.2.3-alpha", "1.2.3-beta", -1},
+		{"1.2.3-1", "1.2.3-alpha", -1},
+		{"1.2.3-rc", "1.2.3-rc.1", -1},
+		{"1.2.3+build.5", "1.2.3", 0},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, 期待値 %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestIsRelease は、タグから作ったビルドのバージョンだけを判別できることをテストします。
+func TestIsRelease(t *testing.T) {
+	tests := map[string]bool{
+		"1.2.3":      true,
+		"v1.2.3":     true,
+		"dev":        false,
+		"":           false,
+		"1.2.4-next": false,
+		"1.2":        false,
+		"1.2.x":      false,
+	}
+	for version, want := range tests {
+		if got := IsRelease(version); got != want {
+			t.Errorf("IsRelease(%q) = %v, 期待値 %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package selfupdate
+
+import (
+	"context"
+	"time"
+)
+
+// Check は、最新のリリースを取得して、current より新しいかどうかを返します。
+// current が開発版のビルド（IsRelease が false）の場合は、新しいとはみなしません。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - current: 動いているバージョン
+//
+// Returns:
+//   - *Release: 最新のリリース
+//   - bool: current より新しい場合は true
+//   - error: 取得に失敗した場合
+func (s Source) Check(ctx context.Context, current string) (*Release, bool, error) {
+	release, err := s.Latest(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return release, IsRelease(current) && Compare(release.Version(), current) > 0, nil
+}
+
+// Watch は、delay だけ待ってから、interval ごとに最新のリリースを確認して report を呼び出します。
+// 確認するだけで、インストールはしません。ctx がキャンセルされるまで戻りません。
+//
+// Parameters:
+//   - ctx: 確認を止めるためのコンテキスト
+//   - source: リリースを取得するリポジトリ
+//   - current: 動いているバージョン
+//   - interval: 確認する間隔
+//   - delay: 最初に確認するまでの待ち時間（前回の確認から interval が経っていない場合など）
+//   - report: 確認するたびに結果を受け取る関数（失敗した場合は release が nil で err が設定されます）
+func Watch(ctx context.Context, source Source, current string, interval, delay time.Duration, report func(release *Release, newer bool, err error)) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		release, newer, err := source.Check(ctx, current)
+		if ctx.Err() != nil {
+			return
+		}
+		report(release, newer, err)
+		timer.Reset(interval)
+	}
+}
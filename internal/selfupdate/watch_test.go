@@ -0,0 +1,73 @@
+package selfupdate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSource_Check は、最新のリリースが今のバージョンより新しいかどうかを判別することをテストします。
+func TestSource_Check(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"v1.2.0"}`))
+	}))
+	defer server.Close()
+	source := Source{Repository: "owner/repo", APIURL: server.URL}
+
+	tests := map[string]bool{"1.1.9": true, "1.2.0": false, "1.3.0": false, "dev": false}
+	for current, want := range tests {
+		release, newer, err := source.Check(context.Background(), current)
+		if err != nil || release.Version() != "1.2.0" || newer != want {
+			t.Errorf("%s: newer = %v (期待値 %v), err = %v", current, newer, want, err)
+		}
+	}
+}
+
+// TestWatch は、間隔ごとに確認して結果を渡し、ctx をキャンセルすると戻ることをテストします。
+func TestWatch(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			http.Error(w, "rate limited", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"tag_name":"v2.0.0"}`))
+	}))
+	defer server.Close()
+	source := Source{Repository: "owner/repo", APIURL: server.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		newer bool
+		err   error
+	}
+	results := make(chan result, 10)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, source, "1.0.0", 10*time.Millisecond, 0, func(release *Release, newer bool, err error) {
+			results <- result{newer, err}
+		})
+		close(done)
+	}()
+
+	first := <-results
+	if first.err == nil {
+		t.Error("1回目はエラーを渡すべき")
+	}
+	second := <-results
+	if second.err != nil || !second.newer {
+		t.Errorf("2回目は新しいバージョンがあることを渡すべき: %+v", second)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("キャンセルしたら戻るべき")
+	}
+}
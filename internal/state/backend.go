@@ -0,0 +1,147 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/horitaku/duckdns/internal/redis"
+)
+
+// DefaultRedisKey は、Redis に状態を保存するキーの既定値です。
+const DefaultRedisKey = "duckdns:state"
+
+// Backend は、状態（JSON）を保存する場所です。
+// 複数のインスタンスで同じ場所を使う場合は、Update の読み込みから書き込みまでをほかのインスタンスと重ならないようにします。
+type Backend interface {
+	// Read は、保存されている状態を読み込みます（まだない場合は nil）
+	Read() ([]byte, error)
+
+	// Update は、保存されている状態（まだない場合は nil）を fn で書き換えます
+	// fn が nil を返した場合は書き込みません。ほかのインスタンスと重なった場合は、fn を何度か呼ぶことがあります
+	Update(fn func(current []byte) ([]byte, error)) error
+
+	// String は、ログや status コマンドに出す保存先です
+	String() string
+}
+
+// FileBackend は、状態をファイルに保存する Backend です。
+// 書き込みは一時ファイルに書いてから置き換えるので、書きかけの状態を読まれることはありません。
+type FileBackend struct {
+	// Path は、状態ファイルのパスです
+	Path string
+}
+
+// String は、状態ファイルのパスを返します。
+func (b *FileBackend) String() string {
+	return b.Path
+}
+
+// Read は、状態ファイルを読み込みます（ファイルがない場合は nil）。
+func (b *FileBackend) Read() ([]byte, error) {
+	data, err := os.ReadFile(b.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("状態ファイルの読み込みに失敗しました: %w", err)
+	}
+	return data, nil
+}
+
+// Update は、状態ファイルを読み込んで fn で書き換えます。
+// 同じプロセスの中での排他は Store が行います。
+func (b *FileBackend) Update(fn func(current []byte) ([]byte, error)) error {
+	current, err := b.Read()
+	if err != nil {
+		return err
+	}
+	data, err := fn(current)
+	if err != nil || data == nil {
+		return err
+	}
+	return b.write(data)
+}
+
+// write は、一時ファイルに書き込んでから置き換えることで、状態ファイルを保存します。
+func (b *FileBackend) write(data []byte) error {
+	dir := filepath.Dir(b.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("状態ファイルのディレクトリの作成に失敗しました: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-"+filepath.Base(b.Path))
+	if err != nil {
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.Path); err != nil {
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// RedisBackend は、状態を Redis の1つのキーに保存する Backend です。
+// 複数のインスタンス（リーダー選出で待機しているインスタンスなど）で同じキーを使うと、
+// 最後に登録した IP アドレスや更新履歴を共有できます。
+// 書き込みは WATCH/MULTI/EXEC で行うので、ほかのインスタンスと同時に書き込んでも記録が失われません。
+type RedisBackend struct {
+	// Client は、Redis のクライアントです
+	Client *redis.Client
+
+	// Key は、状態を保存するキーです（空の場合は DefaultRedisKey）
+	Key string
+}
+
+// NewRedisBackend は、Redis に保存する Backend を作成します。
+//
+// Parameters:
+//   - client: Redis のクライアント
+//   - key: 状態を保存するキー（空の場合は DefaultRedisKey）
+//
+// Returns:
+//   - *RedisBackend: 作成された Backend
+func NewRedisBackend(client *redis.Client, key string) *RedisBackend {
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	return &RedisBackend{Client: client, Key: key}
+}
+
+// String は、"redis://host:port/db#key" 形式の保存先を返します（パスワードは含みません）。
+func (b *RedisBackend) String() string {
+	return b.Client.String() + "#" + b.Key
+}
+
+// Read は、Redis から状態を読み込みます（キーがない場合は nil）。
+func (b *RedisBackend) Read() ([]byte, error) {
+	data, err := b.Client.Get(b.Key)
+	if err != nil {
+		return nil, fmt.Errorf("Redis から状態を読み込めません: %w", err)
+	}
+	return data, nil
+}
+
+// Update は、Redis の状態を読み込んで fn で書き換えます。
+// ほかのインスタンスが同時に書き換えた場合は、読み込みからやり直します。
+func (b *RedisBackend) Update(fn func(current []byte) ([]byte, error)) error {
+	var fnErr error
+	err := b.Client.Modify(b.Key, func(current []byte) ([]byte, error) {
+		data, err := fn(current)
+		fnErr = err
+		return data, err
+	})
+	if err != nil && fnErr == nil {
+		return fmt.Errorf("Redis に状態を書き込めません: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package state
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/redis"
+)
+
+// sharedBackend は、ほかのインスタンスと共有している保存先を模したテスト用の Backend です。
+// concurrent が設定されていると、最初の書き込みの直前にほかのインスタンスが書き込んだものとして、読み込みからやり直します。
+type sharedBackend struct {
+	mu         sync.Mutex
+	data       []byte
+	writes     int
+	concurrent func(current []byte) []byte
+}
+
+func (b *sharedBackend) String() string { return "shared" }
+
+func (b *sharedBackend) Read() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data, nil
+}
+
+func (b *sharedBackend) Update(fn func(current []byte) ([]byte, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		next, err := fn(b.data)
+		if err != nil || next == nil {
+			return err
+		}
+		if b.concurrent != nil {
+			b.data = b.concurrent(b.data)
+			b.concurrent = nil
+			continue
+		}
+		b.data = next
+		b.writes++
+		return nil
+	}
+}
+
+// TestStore_Backend_Conflict は、ほかのインスタンスと書き込みが重なった場合に、その記録を残したまま記録し直すことをテストします。
+func TestStore_Backend_Conflict(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	backend := &sharedBackend{}
+	other := NewStoreWithBackend(backend)
+	other.now = func() time.Time { return now }
+	store := NewStoreWithBackend(backend)
+	store.now = func() time.Time { return now }
+
+	if err := other.Record("a", "192.0.2.1", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	backend.concurrent = func(current []byte) []byte {
+		// 最初の書き込みの直前に、ほかのインスタンスが b を記録したことにする
+		st, err := other.decode(current)
+		if err != nil {
+			t.Fatalf("解析に失敗しました: %v", err)
+		}
+		other.record(st, now, "b", "192.0.2.2", "", true, nil)
+		data, _ := json.Marshal(st)
+		return data
+	}
+	if err := store.Record("a", "192.0.2.3", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	if st.Domains["a"] == nil || st.Domains["a"].IP != "192.0.2.3" || st.Domains["b"] == nil || st.Domains["b"].IP != "192.0.2.2" {
+		t.Errorf("両方のインスタンスの記録が残るべき: %+v", st.Domains)
+	}
+	if len(st.History) != 3 {
+		t.Errorf("やり直しても履歴は1件だけ増えるべき: %d", len(st.History))
+	}
+}
+
+// TestStore_Backend_NoChange は、変わらない記録では書き込まないことをテストします。
+func TestStore_Backend_NoChange(t *testing.T) {
+	backend := &sharedBackend{}
+	store := NewStoreWithBackend(backend)
+
+	if err := store.RecordSourceStatus("https://api.ipify.org", 0, time.Time{}, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	if backend.writes != 0 {
+		t.Errorf("変わらない記録では書き込まないべき: %d", backend.writes)
+	}
+	if store.Path() != "shared" {
+		t.Errorf("保存先は Backend の String であるべき: %s", store.Path())
+	}
+}
+
+// TestRedisBackend_String は、保存先にキーが含まれ、パスワードが含まれないことをテストします。
+func TestRedisBackend_String(t *testing.T) {
+	b := NewRedisBackend(redis.NewClient(redis.Options{Address: "redis:6379", Password: "secret"}), "")
+	if got := b.String(); got != "redis://redis:6379/0#"+DefaultRedisKey {
+		t.Errorf("保存先が一致しません: %s", got)
+	}
+}
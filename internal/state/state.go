@@ -0,0 +1,496 @@
+// Package state は、ドメインごとの更新状況と更新履歴をファイルに保存します。
+// 常駐プロセスが記録した内容を、status や history コマンドから参照するために使用します。
+// 複数のインスタンスで共有する場合は、ファイルの代わりに Redis に保存することもできます（Backend）。
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/netcheck"
+)
+
+// DefaultMaxHistory は、保存する更新履歴のデフォルトの最大件数です。
+const DefaultMaxHistory = 100
+
+// 履歴イベントの結果
+const (
+	// ResultUpdated は、DuckDNS の更新に成功したことを表します
+	ResultUpdated = "updated"
+
+	// ResultFailed は、IPアドレスの取得または DuckDNS の更新に失敗したことを表します
+	ResultFailed = "failed"
+
+	// ResultOffline は、事前の接続の確認に失敗したため、チェックをスキップしたことを表します
+	ResultOffline = "offline"
+
+	// ResultCleared は、clear コマンドで DuckDNS のレコードを消去したことを表します
+	ResultCleared = "cleared"
+)
+
+// DomainStatus は、ドメインごとの最新の状況です。
+type DomainStatus struct {
+	// Domain は、DuckDNS のドメイン名です
+	Domain string `json:"domain"`
+
+	// IP は、最後に DuckDNS に登録した IP アドレスです
+	IP string `json:"ip,omitempty"`
+
+	// LastCheck は、最後に IP アドレスをチェックした時刻です
+	LastCheck time.Time `json:"last_check"`
+
+	// LastUpdate は、最後に DuckDNS の更新に成功した時刻です
+	LastUpdate time.Time `json:"last_update"`
+
+	// LastSuccess は、最後にチェックに成功した時刻です（変更がなく更新しなかった場合も含みます）
+	LastSuccess time.Time `json:"last_success"`
+
+	// LastIPChange は、DuckDNS に登録した IP アドレスが最後に変わった時刻です
+	LastIPChange time.Time `json:"last_ip_change"`
+
+	// LastError は、最後のチェックで発生したエラーです（成功した場合は空）
+	LastError string `json:"last_error,omitempty"`
+
+	// ConsecutiveFailures は、連続して失敗した回数です
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// PendingIP は、変更を検知したものの、更新に失敗してまだ登録できていない IP アドレスです（ない場合は空）
+	// 再起動した後も、ネットワークにつながりしだいこの IP アドレスで更新を再試行するために使います
+	PendingIP string `json:"pending_ip,omitempty"`
+
+	// PendingSince は、PendingIP の更新を保留にした時刻です（ない場合は nil）
+	PendingSince *time.Time `json:"pending_since,omitempty"`
+
+	// Offline は、最後のチェックで接続を確認できず、チェックをスキップした場合に true です
+	// 失敗の回数（ConsecutiveFailures）には数えません
+	Offline bool `json:"offline,omitempty"`
+}
+
+// SourceStatus は、IP取得ソースごとの最新の状況です。
+// 続けて失敗したソースと、一時的に使わないようにしているソースを確認するために記録します。
+type SourceStatus struct {
+	// URL は、IP取得ソースの URL です
+	URL string `json:"url"`
+
+	// ConsecutiveFailures は、連続して失敗した回数です
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// BlacklistedUntil は、続けて失敗したため、このソースを使わない期限です（使える場合はゼロ値）
+	BlacklistedUntil time.Time `json:"blacklisted_until"`
+
+	// LastError は、最後に失敗したときのエラーです（復旧した場合は空）
+	LastError string `json:"last_error,omitempty"`
+
+	// UpdatedAt は、この状況を記録した時刻です
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Blacklisted は、指定した時刻にこのソースを使わないようにしているかどうかを返します。
+func (s *SourceStatus) Blacklisted(now time.Time) bool {
+	return now.Before(s.BlacklistedUntil)
+}
+
+// ReleaseStatus は、新しいバージョンのリリースを確認した結果です。
+type ReleaseStatus struct {
+	// Current は、確認したときに動いていたバージョンです
+	Current string `json:"current"`
+
+	// Latest は、最新のリリースのバージョンです（確認に失敗した場合は前回の値）
+	Latest string `json:"latest,omitempty"`
+
+	// UpdateAvailable は、Current より新しいリリースがある場合に true です
+	UpdateAvailable bool `json:"update_available"`
+
+	// URL は、最新のリリースのページの URL です
+	URL string `json:"url,omitempty"`
+
+	// CheckedAt は、最後に確認した時刻です（失敗した場合も含みます）
+	CheckedAt time.Time `json:"checked_at"`
+
+	// LastError は、最後の確認に失敗した場合のエラーです（成功した場合は空）
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Event は、更新履歴の1件です。
+// 変更がなかったチェックは記録しません。
+type Event struct {
+	// Time は、イベントが発生した時刻です
+	Time time.Time `json:"time"`
+
+	// Domain は、DuckDNS のドメイン名です
+	Domain string `json:"domain"`
+
+	// IP は、登録しようとした IP アドレスです（取得に失敗した場合は空）
+	IP string `json:"ip,omitempty"`
+
+	// Source は、IP アドレスを返した IP取得ソースの URL です（わからない場合は空）
+	Source string `json:"source,omitempty"`
+
+	// Result は、結果（ResultUpdated、ResultFailed、ResultOffline または ResultCleared）です
+	Result string `json:"result"`
+
+	// Error は、失敗した場合のエラーメッセージです
+	Error string `json:"error,omitempty"`
+}
+
+// State は、状態ファイルに保存する内容です。
+type State struct {
+	// Domains は、ドメイン名ごとの最新の状況です
+	Domains map[string]*DomainStatus `json:"domains"`
+
+	// History は、古い順に並んだ更新履歴です
+	History []Event `json:"history"`
+
+	// Sources は、失敗したことがある IP取得ソースの URL ごとの最新の状況です
+	Sources map[string]*SourceStatus `json:"sources,omitempty"`
+
+	// Release は、新しいバージョンのリリースを最後に確認した結果です（release_check を有効にした場合だけ記録します）
+	Release *ReleaseStatus `json:"release,omitempty"`
+}
+
+// SortedDomains は、ドメイン名の順に並べた状況の一覧を返します。
+func (s *State) SortedDomains() []*DomainStatus {
+	domains := make([]*DomainStatus, 0, len(s.Domains))
+	for _, d := range s.Domains {
+		domains = append(domains, d)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Domain < domains[j].Domain
+	})
+	return domains
+}
+
+// SortedSources は、URL の順に並べた IP取得ソースの状況の一覧を返します。
+func (s *State) SortedSources() []*SourceStatus {
+	sources := make([]*SourceStatus, 0, len(s.Sources))
+	for _, source := range s.Sources {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].URL < sources[j].URL
+	})
+	return sources
+}
+
+// Store は、状態ファイルの読み書きを行います。
+// 複数のスケジューラーから同時に記録しても安全です。
+type Store struct {
+	backend    Backend
+	maxHistory int
+	now        func() time.Time
+	mu         sync.Mutex
+}
+
+// NewStore は、指定したパスの状態ファイルを扱う Store を作成します。
+//
+// Parameters:
+//   - path: 状態ファイルのパス
+//
+// Returns:
+//   - *Store: 初期化された Store
+func NewStore(path string) *Store {
+	return NewStoreWithBackend(&FileBackend{Path: path})
+}
+
+// NewStoreWithBackend は、指定した場所に状態を保存する Store を作成します。
+//
+// Parameters:
+//   - backend: 状態を保存する場所（FileBackend や RedisBackend）
+//
+// Returns:
+//   - *Store: 初期化された Store
+func NewStoreWithBackend(backend Backend) *Store {
+	return &Store{
+		backend:    backend,
+		maxHistory: DefaultMaxHistory,
+		now:        time.Now,
+	}
+}
+
+// Path は、状態の保存先（状態ファイルのパスや Redis のキー）を返します。
+func (s *Store) Path() string {
+	return s.backend.String()
+}
+
+// DefaultPath は、状態ファイルのデフォルトのパスを返します。
+//
+// root で実行している場合は /var/lib/duckdns/state.json、
+// それ以外は $XDG_STATE_HOME/duckdns/state.json（未設定時は ~/.local/state/duckdns/state.json）です。
+func DefaultPath() string {
+	if os.Geteuid() == 0 {
+		return filepath.Join("/var/lib", "duckdns", "state.json")
+	}
+
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		if home, err := os.UserHomeDir(); err == nil && home != "" {
+			stateHome = filepath.Join(home, ".local", "state")
+		}
+	}
+	if stateHome == "" {
+		return "state.json"
+	}
+	return filepath.Join(stateHome, "duckdns", "state.json")
+}
+
+// Load は、状態ファイルを読み込みます。
+// ファイルが存在しない場合は、空の State を返します。
+//
+// Returns:
+//   - *State: 読み込んだ状態
+//   - error: 読み込みまたは解析に失敗した場合
+func (s *Store) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// load は、ロックを取得せずに状態ファイルを読み込みます（内部用）。
+func (s *Store) load() (*State, error) {
+	data, err := s.backend.Read()
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(data)
+}
+
+// decode は、保存されていた JSON を State にします（data が nil の場合は空の State）。
+func (s *Store) decode(data []byte) (*State, error) {
+	st := &State{Domains: map[string]*DomainStatus{}}
+	if data == nil {
+		return st, nil
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("状態ファイルの解析に失敗しました (%s): %w", s.backend, err)
+	}
+	if st.Domains == nil {
+		st.Domains = map[string]*DomainStatus{}
+	}
+	return st, nil
+}
+
+// modify は、保存されている状態を読み込んで fn で書き換え、fn が true を返した場合に保存します。
+// 共有している保存先では、ほかのインスタンスと書き込みが重なると fn を読み込み直した状態で呼び直すことがあります。
+func (s *Store) modify(fn func(st *State) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.backend.Update(func(current []byte) ([]byte, error) {
+		st, err := s.decode(current)
+		if err != nil {
+			return nil, err
+		}
+		if !fn(st) {
+			return nil, nil
+		}
+		return json.MarshalIndent(st, "", "  ")
+	})
+}
+
+// Record は、1回のチェックの結果を状態ファイルに記録します。
+//
+// Parameters:
+//   - domain: DuckDNS のドメイン名
+//   - ip: 取得した IP アドレス（取得に失敗した場合は空）
+//   - source: IP アドレスを返した IP取得ソースの URL（わからない場合は空）
+//   - updated: DuckDNS を更新した場合は true（変更がなかった場合は false）
+//   - checkErr: IP アドレスの取得または更新のエラー（成功した場合は nil）
+//
+// Returns:
+//   - error: 状態ファイルの読み書きに失敗した場合
+func (s *Store) Record(domain, ip, source string, updated bool, checkErr error) error {
+	now := s.now()
+	return s.modify(func(st *State) bool {
+		s.record(st, now, domain, ip, source, updated, checkErr)
+		return true
+	})
+}
+
+// record は、1回のチェックの結果を st に反映します。
+func (s *Store) record(st *State, now time.Time, domain, ip, source string, updated bool, checkErr error) {
+	status, ok := st.Domains[domain]
+	if !ok {
+		status = &DomainStatus{Domain: domain}
+		st.Domains[domain] = status
+	}
+	status.LastCheck = now
+	wasOffline := status.Offline
+	status.Offline = false
+
+	switch {
+	case errors.Is(checkErr, netcheck.ErrOffline):
+		// オフラインの間は、チェックのたびに履歴が増えないように、オフラインになったときだけ記録する
+		status.LastError = checkErr.Error()
+		status.Offline = true
+		if !wasOffline {
+			st.History = append(st.History, Event{
+				Time: now, Domain: domain, Result: ResultOffline, Error: checkErr.Error(),
+			})
+		}
+	case checkErr != nil:
+		status.LastError = checkErr.Error()
+		status.ConsecutiveFailures++
+		// 取得できた IP アドレスを登録できなかった場合は、保留中の更新として残す
+		if ip != "" && ip != status.IP && ip != status.PendingIP {
+			status.PendingIP = ip
+			status.PendingSince = &now
+		}
+		st.History = append(st.History, Event{
+			Time: now, Domain: domain, IP: ip, Source: source, Result: ResultFailed, Error: checkErr.Error(),
+		})
+	case updated:
+		if ip != status.IP {
+			status.LastIPChange = now
+		}
+		status.IP = ip
+		status.LastUpdate = now
+		status.LastSuccess = now
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+		status.PendingIP, status.PendingSince = "", nil
+		st.History = append(st.History, Event{
+			Time: now, Domain: domain, IP: ip, Source: source, Result: ResultUpdated,
+		})
+	default:
+		status.LastSuccess = now
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+		status.PendingIP, status.PendingSince = "", nil
+	}
+
+	if len(st.History) > s.maxHistory {
+		st.History = st.History[len(st.History)-s.maxHistory:]
+	}
+}
+
+// RecordResult は、Record を呼び出し、失敗した場合は警告ログを出力します。
+// スケジューラーの Recorder として使用します。
+func (s *Store) RecordResult(domain, ip, source string, updated bool, checkErr error) {
+	if err := s.Record(domain, ip, source, updated, checkErr); err != nil {
+		slog.Warn("状態ファイルへの記録に失敗しました",
+			"path", s.Path(),
+			"error", err,
+		)
+	}
+}
+
+// RecordSourceStatus は、IP取得ソースの状況を状態ファイルに記録します。
+// 復旧して連続失敗回数が 0 になったソースは、記録から取り除きます。
+//
+// Parameters:
+//   - url: IP取得ソースの URL
+//   - failures: 連続して失敗した回数
+//   - blacklistedUntil: 続けて失敗したため、このソースを使わない期限（使える場合はゼロ値）
+//   - sourceErr: 最後に失敗したときのエラー（成功した場合は nil）
+//
+// Returns:
+//   - error: 状態ファイルの読み書きに失敗した場合
+func (s *Store) RecordSourceStatus(url string, failures int, blacklistedUntil time.Time, sourceErr error) error {
+	status := &SourceStatus{
+		URL:                 url,
+		ConsecutiveFailures: failures,
+		BlacklistedUntil:    blacklistedUntil,
+		UpdatedAt:           s.now(),
+	}
+	if sourceErr != nil {
+		status.LastError = sourceErr.Error()
+	}
+
+	return s.modify(func(st *State) bool {
+		if failures == 0 {
+			if _, ok := st.Sources[url]; !ok {
+				return false
+			}
+			delete(st.Sources, url)
+			return true
+		}
+
+		if st.Sources == nil {
+			st.Sources = map[string]*SourceStatus{}
+		}
+		st.Sources[url] = status
+		return true
+	})
+}
+
+// RecordSource は、RecordSourceStatus を呼び出し、失敗した場合は警告ログを出力します。
+// IP取得の SourceRecorder として使用します。
+func (s *Store) RecordSource(url string, failures int, blacklistedUntil time.Time, sourceErr error) {
+	if err := s.RecordSourceStatus(url, failures, blacklistedUntil, sourceErr); err != nil {
+		slog.Warn("状態ファイルへの記録に失敗しました",
+			"path", s.Path(),
+			"error", err,
+		)
+	}
+}
+
+// RecordClear は、ドメインのレコードを消去した結果を状態ファイルに記録します。
+// 消去できた場合は登録した IP アドレスと保留中の更新を取り除き、消去できなかった場合は失敗として履歴にだけ記録します。
+//
+// Parameters:
+//   - domain: DuckDNS のドメイン名
+//   - clearErr: 消去に失敗した場合のエラー（成功した場合は nil）
+//
+// Returns:
+//   - error: 状態ファイルの読み書きに失敗した場合
+func (s *Store) RecordClear(domain string, clearErr error) error {
+	now := s.now()
+	return s.modify(func(st *State) bool {
+		if clearErr != nil {
+			st.History = append(st.History, Event{Time: now, Domain: domain, Result: ResultFailed, Error: clearErr.Error()})
+		} else {
+			status, ok := st.Domains[domain]
+			if !ok {
+				status = &DomainStatus{Domain: domain}
+				st.Domains[domain] = status
+			}
+			if status.IP != "" {
+				status.LastIPChange = now
+			}
+			status.IP = ""
+			status.LastUpdate = now
+			status.PendingIP, status.PendingSince = "", nil
+			st.History = append(st.History, Event{Time: now, Domain: domain, Result: ResultCleared})
+		}
+		if len(st.History) > s.maxHistory {
+			st.History = st.History[len(st.History)-s.maxHistory:]
+		}
+		return true
+	})
+}
+
+// RecordRelease は、新しいバージョンのリリースを確認した結果を状態ファイルに記録します。
+// 確認に失敗した場合は、前回わかった最新のバージョンを残したままエラーだけを記録します。
+//
+// Parameters:
+//   - current: 動いているバージョン
+//   - latest: 最新のリリースのバージョン（失敗した場合は空）
+//   - url: 最新のリリースのページの URL
+//   - available: current より新しいリリースがあるかどうか
+//   - checkErr: 確認に失敗した場合のエラー（成功した場合は nil）
+//
+// Returns:
+//   - error: 状態ファイルの読み書きに失敗した場合
+func (s *Store) RecordRelease(current, latest, url string, available bool, checkErr error) error {
+	now := s.now()
+	return s.modify(func(st *State) bool {
+		status := &ReleaseStatus{Current: current, CheckedAt: now}
+		if checkErr != nil {
+			if prev := st.Release; prev != nil && prev.Current == current {
+				status.Latest, status.URL, status.UpdateAvailable = prev.Latest, prev.URL, prev.UpdateAvailable
+			}
+			status.LastError = checkErr.Error()
+		} else {
+			status.Latest, status.URL, status.UpdateAvailable = latest, url, available
+		}
+		st.Release = status
+		return true
+	})
+}
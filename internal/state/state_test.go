@@ -0,0 +1,371 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/netcheck"
+)
+
+// newTestStore は、一時ディレクトリの状態ファイルと固定の時刻を使う Store を作成します。
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewStore(filepath.Join(t.TempDir(), "sub", "state.json"))
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+// TestStore_Load_NotExist は、状態ファイルがない場合に空の状態が返されることをテストします。
+func TestStore_Load_NotExist(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("エラーは返されないはず: %v", err)
+	}
+	if len(st.Domains) != 0 || len(st.History) != 0 {
+		t.Errorf("空の状態が返されるべき: %+v", st)
+	}
+}
+
+// TestStore_Record は、更新・変更なし・失敗の記録をテストします。
+func TestStore_Record(t *testing.T) {
+	store, now := newTestStore(t)
+
+	if err := store.Record("example", "192.0.2.1", "https://api.ipify.org", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	updatedAt := *now
+
+	*now = now.Add(time.Minute)
+	if err := store.Record("example", "192.0.2.1", "", false, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := store.Record("example", "", "", false, errors.New("fetch failed")); err != nil {
+			t.Fatalf("記録に失敗しました: %v", err)
+		}
+	}
+
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+
+	status := st.Domains["example"]
+	if status == nil {
+		t.Fatal("ドメインの状況が記録されるべき")
+	}
+	if status.IP != "192.0.2.1" {
+		t.Errorf("IP は最後に更新した値のままであるべき。実際: %s", status.IP)
+	}
+	if !status.LastUpdate.Equal(updatedAt) {
+		t.Errorf("LastUpdate が一致しません。期待: %v, 実際: %v", updatedAt, status.LastUpdate)
+	}
+	if !status.LastCheck.Equal(*now) {
+		t.Errorf("LastCheck が一致しません。期待: %v, 実際: %v", *now, status.LastCheck)
+	}
+	if status.ConsecutiveFailures != 2 || status.LastError != "fetch failed" {
+		t.Errorf("失敗の記録が一致しません: %+v", status)
+	}
+
+	// 変更なしのチェックは履歴に残らない
+	if len(st.History) != 3 {
+		t.Fatalf("履歴は3件であるべき。実際: %d", len(st.History))
+	}
+	if st.History[0].Result != ResultUpdated || st.History[0].Source != "https://api.ipify.org" || st.History[2].Result != ResultFailed {
+		t.Errorf("履歴の結果が一致しません: %+v", st.History)
+	}
+
+	// 成功すると連続失敗回数がリセットされる
+	if err := store.Record("example", "192.0.2.2", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if got := st.Domains["example"]; got.ConsecutiveFailures != 0 || got.LastError != "" || got.IP != "192.0.2.2" {
+		t.Errorf("成功後の状況が一致しません: %+v", got)
+	}
+}
+
+// TestStore_RecordClear は、レコードを消去した結果を記録することをテストします。
+func TestStore_RecordClear(t *testing.T) {
+	store, _ := newTestStore(t)
+	if err := store.Record("example", "192.0.2.1", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+
+	if err := store.RecordClear("example", errors.New("KO")); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ := store.Load()
+	if got := st.Domains["example"]; got.IP != "192.0.2.1" {
+		t.Errorf("消去に失敗した場合は IP アドレスを残すべき: %+v", got)
+	}
+
+	if err := store.RecordClear("example", nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if got := st.Domains["example"]; got.IP != "" || got.LastIPChange.IsZero() {
+		t.Errorf("消去した場合は IP アドレスを取り除くべき: %+v", got)
+	}
+	if len(st.History) != 3 || st.History[1].Result != ResultFailed || st.History[1].Error != "KO" || st.History[2].Result != ResultCleared {
+		t.Errorf("消去の結果を履歴に残すべき: %+v", st.History)
+	}
+}
+
+// TestStore_Record_MaxHistory は、履歴が最大件数で切り詰められることをテストします。
+func TestStore_Record_MaxHistory(t *testing.T) {
+	store, _ := newTestStore(t)
+	store.maxHistory = 3
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5"} {
+		if err := store.Record("example", ip, "", true, nil); err != nil {
+			t.Fatalf("記録に失敗しました: %v", err)
+		}
+	}
+
+	st, _ := store.Load()
+	if len(st.History) != 3 {
+		t.Fatalf("履歴は3件に切り詰められるべき。実際: %d", len(st.History))
+	}
+	if st.History[0].IP != "192.0.2.3" || st.History[2].IP != "192.0.2.5" {
+		t.Errorf("古い履歴から削除されるべき: %+v", st.History)
+	}
+}
+
+// TestStore_Record_LastSuccess は、最後に成功した時刻と IP アドレスが変わった時刻の記録をテストします。
+func TestStore_Record_LastSuccess(t *testing.T) {
+	store, now := newTestStore(t)
+
+	if err := store.Record("example", "192.0.2.1", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	changedAt := *now
+
+	*now = now.Add(time.Minute)
+	if err := store.Record("example", "192.0.2.1", "", false, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	succeededAt := *now
+
+	*now = now.Add(time.Minute)
+	if err := store.Record("example", "", "", false, errors.New("fetch failed")); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+
+	st, _ := store.Load()
+	status := st.Domains["example"]
+	if !status.LastSuccess.Equal(succeededAt) {
+		t.Errorf("LastSuccess は変更がなかったチェックの時刻であるべき。期待: %v, 実際: %v", succeededAt, status.LastSuccess)
+	}
+	if !status.LastIPChange.Equal(changedAt) {
+		t.Errorf("LastIPChange は IP アドレスが変わった時刻であるべき。期待: %v, 実際: %v", changedAt, status.LastIPChange)
+	}
+
+	// 同じ IP アドレスでの更新（IPv6 だけが変わった場合など）は、IP アドレスの変更に数えない
+	*now = now.Add(time.Minute)
+	if err := store.Record("example", "192.0.2.1", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if got := st.Domains["example"]; !got.LastIPChange.Equal(changedAt) || !got.LastSuccess.Equal(*now) {
+		t.Errorf("同じ IP アドレスでの更新は LastSuccess だけを変えるべき: %+v", got)
+	}
+}
+
+// TestStore_Record_Pending は、取得した IP アドレスを登録できなかった場合に保留中の更新として残すことをテストします。
+func TestStore_Record_Pending(t *testing.T) {
+	store, now := newTestStore(t)
+	if err := store.Record("example", "192.0.2.1", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	pendingAt := *now
+	if err := store.Record("example", "192.0.2.2", "", false, errors.New("network down")); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	// IP アドレスの取得にも失敗した場合や、同じ IP アドレスで続けて失敗した場合は、最初に保留にした時刻のまま
+	*now = now.Add(time.Minute)
+	_ = store.Record("example", "", "", false, errors.New("fetch failed"))
+	_ = store.Record("example", "192.0.2.2", "", false, errors.New("network down"))
+
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	status := st.Domains["example"]
+	if status.PendingIP != "192.0.2.2" || status.PendingSince == nil || !status.PendingSince.Equal(pendingAt) {
+		t.Errorf("保留中の更新が一致しません: %q, %v", status.PendingIP, status.PendingSince)
+	}
+
+	if err := store.Record("example", "192.0.2.2", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if status := st.Domains["example"]; status.PendingIP != "" || status.PendingSince != nil {
+		t.Errorf("更新に成功したら保留中の更新はなくなるべき: %q, %v", status.PendingIP, status.PendingSince)
+	}
+}
+
+// TestStore_Record_Offline は、オフラインの記録を失敗と区別し、オフラインになったときだけ履歴に残すことをテストします。
+func TestStore_Record_Offline(t *testing.T) {
+	store, now := newTestStore(t)
+	offline := fmt.Errorf("%w: 名前解決できません", netcheck.ErrOffline)
+	for i := 0; i < 3; i++ {
+		*now = now.Add(time.Minute)
+		if err := store.Record("example", "", "", false, offline); err != nil {
+			t.Fatalf("記録に失敗しました: %v", err)
+		}
+	}
+
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	status := st.Domains["example"]
+	if !status.Offline || status.ConsecutiveFailures != 0 {
+		t.Errorf("オフラインは失敗に数えないべき: offline=%v, failures=%d", status.Offline, status.ConsecutiveFailures)
+	}
+	if len(st.History) != 1 || st.History[0].Result != ResultOffline {
+		t.Errorf("オフラインになったときだけ履歴に残すべき: %+v", st.History)
+	}
+
+	if err := store.Record("example", "192.0.2.1", "", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if st.Domains["example"].Offline {
+		t.Error("チェックできたらオフラインではなくなるべき")
+	}
+}
+
+// TestStore_Load_Invalid は、壊れた状態ファイルでエラーになることをテストします。
+func TestStore_Load_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{invalid"), 0600); err != nil {
+		t.Fatalf("テンポラリーファイルの作成に失敗: %v", err)
+	}
+
+	if _, err := NewStore(path).Load(); err == nil {
+		t.Error("壊れた状態ファイルはエラーになるべき")
+	}
+}
+
+// TestState_SortedDomains は、ドメイン名の順に並ぶことをテストします。
+func TestState_SortedDomains(t *testing.T) {
+	st := &State{Domains: map[string]*DomainStatus{
+		"b": {Domain: "b"},
+		"a": {Domain: "a"},
+		"c": {Domain: "c"},
+	}}
+
+	domains := st.SortedDomains()
+	for i, want := range []string{"a", "b", "c"} {
+		if domains[i].Domain != want {
+			t.Errorf("%d 番目のドメインが一致しません。期待: %s, 実際: %s", i, want, domains[i].Domain)
+		}
+	}
+}
+
+// TestStore_RecordSourceStatus は、IP取得ソースの失敗の記録と、復旧したときに取り除かれることをテストします。
+func TestStore_RecordSourceStatus(t *testing.T) {
+	store, now := newTestStore(t)
+	url := "https://api.ipify.org"
+	until := now.Add(10 * time.Minute)
+
+	if err := store.RecordSourceStatus(url, 3, until, errors.New("timeout")); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	source := st.Sources[url]
+	if source == nil || source.ConsecutiveFailures != 3 || !source.BlacklistedUntil.Equal(until) || source.LastError != "timeout" || !source.UpdatedAt.Equal(*now) {
+		t.Fatalf("ソースの状況が一致しません: %+v", source)
+	}
+	if !source.Blacklisted(*now) || source.Blacklisted(until) {
+		t.Error("期限までは使わないようにしているべき")
+	}
+
+	if err := store.RecordSourceStatus(url, 0, time.Time{}, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	if st, _ = store.Load(); len(st.Sources) != 0 {
+		t.Errorf("復旧したソースは取り除かれるべき: %+v", st.Sources)
+	}
+}
+
+// TestStore_RecordRelease は、リリースの確認の結果を記録し、失敗した場合は前回の最新のバージョンを残すことをテストします。
+func TestStore_RecordRelease(t *testing.T) {
+	store, now := newTestStore(t)
+
+	if err := store.RecordRelease("1.0.0", "1.1.0", "https://example.com/r", true, nil); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("読み込みに失敗しました: %v", err)
+	}
+	release := st.Release
+	if release == nil || release.Current != "1.0.0" || release.Latest != "1.1.0" || !release.UpdateAvailable || !release.CheckedAt.Equal(*now) {
+		t.Fatalf("確認の結果が一致しません: %+v", release)
+	}
+
+	if err := store.RecordRelease("1.0.0", "", "", false, errors.New("rate limit")); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if release = st.Release; release.Latest != "1.1.0" || !release.UpdateAvailable || release.LastError != "rate limit" {
+		t.Errorf("失敗した場合は前回の最新のバージョンを残すべき: %+v", release)
+	}
+
+	// 更新したあとのバージョンでは、前回の結果を引き継がない
+	if err := store.RecordRelease("1.1.0", "", "", false, errors.New("timeout")); err != nil {
+		t.Fatalf("記録に失敗しました: %v", err)
+	}
+	st, _ = store.Load()
+	if release = st.Release; release.Latest != "" || release.UpdateAvailable {
+		t.Errorf("バージョンが変わった場合は前回の結果を引き継がないべき: %+v", release)
+	}
+}
+
+// TestState_SortedSources は、URL の順に並ぶことをテストします。
+func TestState_SortedSources(t *testing.T) {
+	st := &State{Sources: map[string]*SourceStatus{
+		"https://b.example": {URL: "https://b.example"},
+		"https://a.example": {URL: "https://a.example"},
+	}}
+
+	sources := st.SortedSources()
+	for i, want := range []string{"https://a.example", "https://b.example"} {
+		if sources[i].URL != want {
+			t.Errorf("%d 番目のソースが一致しません。期待: %s, 実際: %s", i, want, sources[i].URL)
+		}
+	}
+}
+
+// TestDefaultPath は、XDG_STATE_HOME が使われることをテストします。
+func TestDefaultPath(t *testing.T) {
+	if os.Geteuid() == 0 {
+		if got := DefaultPath(); got != "/var/lib/duckdns/state.json" {
+			t.Errorf("root の場合は /var/lib/duckdns/state.json であるべき。実際: %s", got)
+		}
+		return
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	if got, want := DefaultPath(), filepath.Join(dir, "duckdns", "state.json"); got != want {
+		t.Errorf("パスが一致しません。期待: %s, 実際: %s", want, got)
+	}
+}
@@ -0,0 +1,237 @@
+// Package summary は、一定の期間のチェックと更新の結果を集計して、動作の要約をつくります。
+// 同じようなログが続く長期間のログの中でも、動き続けているかどうかを1行で確かめられるようにします。
+package summary
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Snapshot は、ある期間の動作の要約です。
+type Snapshot struct {
+	// Start は、集計を始めた時刻です
+	Start time.Time
+
+	// End は、集計を終えた時刻です
+	End time.Time
+
+	// Uptime は、Collector をつくってから End までの時間（動き続けている時間）です
+	Uptime time.Duration
+
+	// Checks は、ドメインごとのチェックの回数です
+	Checks int
+
+	// Successes は、成功したチェックの数です
+	Successes int
+
+	// Failures は、失敗したチェックの数です
+	Failures int
+
+	// IPChanges は、ドメインの IP アドレスが変わった回数です
+	IPChanges int
+
+	// IPs は、ドメインごとの最新の IP アドレスです（期間より前に取得したアドレスも含みます）
+	IPs map[string]string
+
+	// SourceFailures は、IP取得ソースの URL ごとの失敗した回数です（失敗しなかったソースは含みません）
+	SourceFailures map[string]int
+}
+
+// Attrs は、要約をログに出力する属性にします。
+// ドメインごとの IP アドレスとソースごとの失敗した回数は、名前の順に並べたグループにします。
+func (s Snapshot) Attrs() []any {
+	ips := make([]any, 0, len(s.IPs))
+	for _, domain := range slices.Sorted(maps.Keys(s.IPs)) {
+		ips = append(ips, slog.String(domain, s.IPs[domain]))
+	}
+	sources := make([]any, 0, len(s.SourceFailures))
+	for _, url := range slices.Sorted(maps.Keys(s.SourceFailures)) {
+		sources = append(sources, slog.Int(url, s.SourceFailures[url]))
+	}
+	return []any{
+		"period", s.End.Sub(s.Start).Round(time.Second).String(),
+		"uptime", s.Uptime.Round(time.Second).String(),
+		"checks", s.Checks,
+		"successes", s.Successes,
+		"failures", s.Failures,
+		"ip_changes", s.IPChanges,
+		slog.Group("ip", ips...),
+		slog.Group("source_failures", sources...),
+	}
+}
+
+// Collector は、チェックの結果と IP取得ソースの失敗を集計する scheduler.Recorder と ip.SourceRecorder です。
+// 集計は Take を呼び出すたびにやり直します。
+type Collector struct {
+	// now は、現在時刻を返す関数です（テスト用）
+	now func() time.Time
+
+	mu             sync.Mutex
+	started        time.Time
+	start          time.Time
+	checks         int
+	successes      int
+	failures       int
+	ipChanges      int
+	ips            map[string]string
+	sourceFailures map[string]int
+}
+
+// NewCollector は、今から集計を始める Collector を作成します。
+func NewCollector() *Collector {
+	return newCollector(time.Now)
+}
+
+// newCollector は、現在時刻を返す関数を指定して Collector を作成します（内部用ヘルパー関数）
+func newCollector(now func() time.Time) *Collector {
+	started := now()
+	return &Collector{
+		now:            now,
+		started:        started,
+		start:          started,
+		ips:            make(map[string]string),
+		sourceFailures: make(map[string]int),
+	}
+}
+
+// RecordResult は、ドメインのチェックの結果を集計します（scheduler.Recorder）。
+// 前に取得したアドレスと違うアドレスを取得した場合は、IP アドレスが変わったと数えます。
+//
+// Parameters:
+//   - domain: ドメイン名
+//   - ip: 取得した IP アドレス（取得できなかった場合は空）
+//   - source: IP アドレスを返したソース（使いません）
+//   - updated: 更新した場合に true（使いません）
+//   - checkErr: チェックのエラー（成功した場合は nil）
+func (c *Collector) RecordResult(domain, ip, source string, updated bool, checkErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks++
+	if checkErr != nil {
+		c.failures++
+	} else {
+		c.successes++
+	}
+	if ip == "" {
+		return
+	}
+	if previous, ok := c.ips[domain]; ok && previous != ip {
+		c.ipChanges++
+	}
+	c.ips[domain] = ip
+}
+
+// RecordSource は、IP取得ソースの失敗を集計します（ip.SourceRecorder）。
+//
+// Parameters:
+//   - url: IP取得ソースの URL
+//   - failures: 連続して失敗した回数（使いません）
+//   - blacklistedUntil: このソースを使わない期限（使いません）
+//   - sourceErr: 失敗したときのエラー（成功した場合は nil）
+func (c *Collector) RecordSource(url string, failures int, blacklistedUntil time.Time, sourceErr error) {
+	if sourceErr == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sourceFailures[url]++
+}
+
+// Take は、前回の Take（初めての場合は NewCollector）からの要約を返し、集計をやり直します。
+// ドメインごとの最新の IP アドレスは、次の期間にも引き継ぎます。
+//
+// Returns:
+//   - Snapshot: 動作の要約
+func (c *Collector) Take() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now()
+	s := Snapshot{
+		Start:          c.start,
+		End:            end,
+		Uptime:         end.Sub(c.started),
+		Checks:         c.checks,
+		Successes:      c.successes,
+		Failures:       c.failures,
+		IPChanges:      c.ipChanges,
+		IPs:            maps.Clone(c.ips),
+		SourceFailures: c.sourceFailures,
+	}
+	c.start = end
+	c.checks, c.successes, c.failures, c.ipChanges = 0, 0, 0, 0
+	c.sourceFailures = make(map[string]int)
+	return s
+}
+
+// Run は、ctx がキャンセルされるまで、interval ごとに Take した要約を report に渡します。
+//
+// Parameters:
+//   - ctx: 停止を制御するコンテキスト
+//   - interval: 要約をつくる間隔
+//   - report: 要約を受け取る関数
+func (c *Collector) Run(ctx context.Context, interval time.Duration, report func(Snapshot)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report(c.Take())
+		}
+	}
+}
+
+// RunDaily は、ctx がキャンセルされるまで、毎日 loc のタイムゾーンの hour:minute に Take した要約を report に渡します。
+// 最初の要約は、次にその時刻になったときまでの要約です。
+//
+// Parameters:
+//   - ctx: 停止を制御するコンテキスト
+//   - hour: 時
+//   - minute: 分
+//   - loc: 時刻のタイムゾーン
+//   - report: 要約を受け取る関数
+func (c *Collector) RunDaily(ctx context.Context, hour, minute int, loc *time.Location, report func(Snapshot)) {
+	for {
+		now := c.now()
+		timer := time.NewTimer(NextDaily(now, hour, minute, loc).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			report(c.Take())
+		}
+	}
+}
+
+// NextDaily は、now より後で最初に来る、loc のタイムゾーンの hour:minute の時刻を返します。
+//
+// Parameters:
+//   - now: 現在時刻
+//   - hour: 時
+//   - minute: 分
+//   - loc: 時刻のタイムゾーン
+//
+// Returns:
+//   - time.Time: 次の時刻
+func NextDaily(now time.Time, hour, minute int, loc *time.Location) time.Time {
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next
+}
+
+// Log は、要約を1行の情報のログに出力します。Run の report に渡して使います。
+//
+// Parameters:
+//   - s: 動作の要約
+func Log(s Snapshot) {
+	slog.Info("動作の要約", s.Attrs()...)
+}
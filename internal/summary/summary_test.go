@@ -0,0 +1,115 @@
+package summary
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNow は、テスト用に進められる現在時刻です。
+type fakeNow struct {
+	t time.Time
+}
+
+// now は、現在時刻を返します。
+func (f *fakeNow) now() time.Time {
+	return f.t
+}
+
+// TestCollector_Take は、チェックの結果とソースの失敗を集計し、Take のたびにやり直すことをテストします。
+func TestCollector_Take(t *testing.T) {
+	clock := &fakeNow{t: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}
+	c := newCollector(clock.now)
+
+	c.RecordResult("home", "192.0.2.1", "https://api.ipify.org", true, nil)
+	c.RecordResult("home", "", "", false, errors.New("取得できません"))
+	c.RecordResult("home", "192.0.2.2", "https://api.ipify.org", true, nil)
+	c.RecordResult("office", "198.51.100.1", "https://api.ipify.org", false, nil)
+	c.RecordSource("https://ifconfig.me", 1, time.Time{}, errors.New("タイムアウト"))
+	c.RecordSource("https://ifconfig.me", 2, time.Time{}, errors.New("タイムアウト"))
+	c.RecordSource("https://api.ipify.org", 0, time.Time{}, nil)
+
+	clock.t = clock.t.Add(6 * time.Hour)
+	s := c.Take()
+	if s.Checks != 4 || s.Successes != 3 || s.Failures != 1 || s.IPChanges != 1 {
+		t.Errorf("チェックの結果を集計するべき: %+v", s)
+	}
+	if want := map[string]string{"home": "192.0.2.2", "office": "198.51.100.1"}; !maps.Equal(s.IPs, want) {
+		t.Errorf("ドメインごとの最新の IP アドレス = %v, want %v", s.IPs, want)
+	}
+	if want := map[string]int{"https://ifconfig.me": 2}; !maps.Equal(s.SourceFailures, want) {
+		t.Errorf("ソースごとの失敗した回数 = %v, want %v", s.SourceFailures, want)
+	}
+	if s.End.Sub(s.Start) != 6*time.Hour || s.Uptime != 6*time.Hour {
+		t.Errorf("期間と動き続けている時間 = %s, %s", s.End.Sub(s.Start), s.Uptime)
+	}
+
+	// 次の期間は集計をやり直し、IP アドレスだけ引き継ぐ
+	clock.t = clock.t.Add(6 * time.Hour)
+	c.RecordResult("home", "192.0.2.3", "https://api.ipify.org", true, nil)
+	s = c.Take()
+	if s.Checks != 1 || s.Failures != 0 || s.IPChanges != 1 || len(s.SourceFailures) != 0 {
+		t.Errorf("Take のたびに集計をやり直すべき: %+v", s)
+	}
+	if s.IPs["office"] != "198.51.100.1" || s.Uptime != 12*time.Hour || s.End.Sub(s.Start) != 6*time.Hour {
+		t.Errorf("IP アドレスと動き続けている時間は引き継ぐべき: %+v", s)
+	}
+}
+
+// TestLog は、要約を1行のログに出力することをテストします。
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	start := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	Log(Snapshot{
+		Start:          start,
+		End:            start.Add(6 * time.Hour),
+		Uptime:         30 * time.Hour,
+		Checks:         72,
+		Successes:      71,
+		Failures:       1,
+		IPChanges:      1,
+		IPs:            map[string]string{"home": "192.0.2.1"},
+		SourceFailures: map[string]int{"https://ifconfig.me": 2},
+	})
+
+	out := buf.String()
+	if strings.Count(out, "\n") != 1 {
+		t.Errorf("1行で出力するべき: %s", out)
+	}
+	for _, want := range []string{"period=6h0m0s", "uptime=30h0m0s", "checks=72", "successes=71", "failures=1", "ip_changes=1", "ip.home=192.0.2.1", "source_failures.https://ifconfig.me=2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q を含むべき: %s", want, out)
+		}
+	}
+}
+
+// TestNextDaily は、次に指定した時刻になる時刻をテストします。
+func TestNextDaily(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"今日のうち", time.Date(2026, 1, 2, 7, 59, 0, 0, tokyo), time.Date(2026, 1, 2, 8, 0, 0, 0, tokyo)},
+		{"ちょうどその時刻", time.Date(2026, 1, 2, 8, 0, 0, 0, tokyo), time.Date(2026, 1, 3, 8, 0, 0, 0, tokyo)},
+		{"過ぎたら翌日", time.Date(2026, 1, 2, 9, 0, 0, 0, tokyo), time.Date(2026, 1, 3, 8, 0, 0, 0, tokyo)},
+		{"月末", time.Date(2026, 1, 31, 23, 0, 0, 0, tokyo), time.Date(2026, 2, 1, 8, 0, 0, 0, tokyo)},
+		{"ほかのタイムゾーン", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 3, 8, 0, 0, 0, tokyo)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextDaily(tt.now, 8, 0, tokyo); !got.Equal(tt.want) {
+				t.Errorf("NextDaily() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Systemctl は、systemctl のコマンドを実行する関数です。
+// テストでは、実際には実行しない関数に置き換えます。
+type Systemctl func(ctx context.Context, args ...string) error
+
+// RunSystemctl は、systemctl を実行します。
+// 失敗した場合は、systemctl が標準エラー出力に書いたメッセージをエラーに含めます。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - args: systemctl に渡す引数（例: "enable", "--now", "duckdns.service"）
+//
+// Returns:
+//   - error: systemctl が見つからない場合、または 0 以外の終了コードで終了した場合
+func RunSystemctl(ctx context.Context, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("systemctl %s に失敗しました: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("systemctl %s に失敗しました: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Enable は、systemd にユニットファイルを読み込み直させてから、サービスを有効にします。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - systemctl: systemctl を実行する関数（nil の場合は RunSystemctl）
+//   - name: サービスの名前
+//   - start: すぐに起動するかどうか（false の場合は次の起動から有効にします）
+//
+// Returns:
+//   - error: systemctl の実行に失敗した場合
+func Enable(ctx context.Context, systemctl Systemctl, name string, start bool) error {
+	if systemctl == nil {
+		systemctl = RunSystemctl
+	}
+	if err := systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	}
+	args := []string{"enable"}
+	if start {
+		args = append(args, "--now")
+	}
+	return systemctl(ctx, append(args, ServiceName(name))...)
+}
+
+// Disable は、サービスを停止して無効にします。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - systemctl: systemctl を実行する関数（nil の場合は RunSystemctl）
+//   - name: サービスの名前
+//
+// Returns:
+//   - error: systemctl の実行に失敗した場合
+func Disable(ctx context.Context, systemctl Systemctl, name string) error {
+	if systemctl == nil {
+		systemctl = RunSystemctl
+	}
+	return systemctl(ctx, "disable", "--now", ServiceName(name))
+}
+
+// Reload は、systemd にユニットファイルを読み込み直させます。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - systemctl: systemctl を実行する関数（nil の場合は RunSystemctl）
+//
+// Returns:
+//   - error: systemctl の実行に失敗した場合
+func Reload(ctx context.Context, systemctl Systemctl) error {
+	if systemctl == nil {
+		systemctl = RunSystemctl
+	}
+	return systemctl(ctx, "daemon-reload")
+}
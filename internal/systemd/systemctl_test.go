@@ -0,0 +1,56 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recordSystemctl は、呼び出された systemctl の引数を記録し、fail を含むコマンドは失敗させます。
+func recordSystemctl(calls *[]string, fail string) Systemctl {
+	return func(ctx context.Context, args ...string) error {
+		call := strings.Join(args, " ")
+		*calls = append(*calls, call)
+		if fail != "" && strings.Contains(call, fail) {
+			return errors.New("失敗")
+		}
+		return nil
+	}
+}
+
+// TestEnable は、daemon-reload のあとでサービスを有効にすることをテストします。
+func TestEnable(t *testing.T) {
+	var calls []string
+	if err := Enable(context.Background(), recordSystemctl(&calls, ""), "", true); err != nil {
+		t.Fatalf("有効化に失敗しました: %v", err)
+	}
+	if want := []string{"daemon-reload", "enable --now duckdns.service"}; !slices.Equal(calls, want) {
+		t.Errorf("systemctl の呼び出しが一致しません: %v", calls)
+	}
+
+	calls = nil
+	if err := Enable(context.Background(), recordSystemctl(&calls, ""), "ddns", false); err != nil {
+		t.Fatalf("有効化に失敗しました: %v", err)
+	}
+	if want := []string{"daemon-reload", "enable ddns.service"}; !slices.Equal(calls, want) {
+		t.Errorf("起動しない場合は --now を付けないべき: %v", calls)
+	}
+
+	calls = nil
+	if err := Enable(context.Background(), recordSystemctl(&calls, "daemon-reload"), "", true); err == nil || len(calls) != 1 {
+		t.Errorf("daemon-reload に失敗したら有効にしないべき: %v %v", err, calls)
+	}
+}
+
+// TestDisable は、サービスを停止して無効にすることをテストします。
+func TestDisable(t *testing.T) {
+	var calls []string
+	if err := Disable(context.Background(), recordSystemctl(&calls, ""), ""); err != nil {
+		t.Fatalf("無効化に失敗しました: %v", err)
+	}
+	if want := []string{"disable --now duckdns.service"}; !slices.Equal(calls, want) {
+		t.Errorf("systemctl の呼び出しが一致しません: %v", calls)
+	}
+}
@@ -0,0 +1,222 @@
+// Package systemd は、duckdns を systemd のサービスとして実行するためのユニットファイルの作成とインストールを提供します。
+package systemd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultName は、サービスの名前を省略した場合の値です
+	DefaultName = "duckdns"
+
+	// DefaultUnitDir は、ユニットファイルを書き込むディレクトリを省略した場合の値です
+	DefaultUnitDir = "/etc/systemd/system"
+
+	// DefaultEnvironmentFile は、環境変数ファイルのパスを省略した場合の値です
+	DefaultEnvironmentFile = "/etc/duckdns/duckdns.env"
+)
+
+// Unit は、ユニットファイルに書き込む内容です。
+type Unit struct {
+	// Binary は、duckdns の実行ファイルの絶対パスです
+	Binary string
+
+	// ConfigPath は、run の -config に渡す設定ファイルのパスまたは URL です（空の場合は -config を付けません）
+	ConfigPath string
+
+	// EnvironmentFile は、トークンなどを環境変数で渡すファイルのパスです（空の場合は読み込みません）
+	EnvironmentFile string
+}
+
+// Path は、サービスの名前からユニットファイルのパスを返します。
+//
+// Parameters:
+//   - dir: ユニットファイルを書き込むディレクトリ（空の場合は DefaultUnitDir）
+//   - name: サービスの名前（空の場合は DefaultName）
+//
+// Returns:
+//   - string: ユニットファイルのパス（例: "/etc/systemd/system/duckdns.service"）
+func Path(dir, name string) string {
+	if dir == "" {
+		dir = DefaultUnitDir
+	}
+	return filepath.Join(dir, ServiceName(name))
+}
+
+// ServiceName は、systemctl に渡すサービスの名前を返します（例: "duckdns.service"）。
+func ServiceName(name string) string {
+	if name == "" {
+		name = DefaultName
+	}
+	if strings.HasSuffix(name, ".service") {
+		return name
+	}
+	return name + ".service"
+}
+
+// Render は、ユニットファイルの内容を作成します。
+// DynamicUser で専用の一時的なユーザーとして実行し、ProtectSystem などで書き込めるディレクトリを状態とキャッシュだけに制限します。
+// EnvironmentFile は systemd が root として読み込むため、トークンは 0600 のファイルに置いたまま渡せます。
+//
+// Returns:
+//   - []byte: ユニットファイルの内容
+//   - error: 実行ファイルのパスが絶対パスでない場合
+func (u Unit) Render() ([]byte, error) {
+	if !filepath.IsAbs(u.Binary) {
+		return nil, fmt.Errorf("実行ファイルのパスは絶対パスで指定してください: %s", u.Binary)
+	}
+
+	args := []string{u.Binary, "run"}
+	if u.ConfigPath != "" {
+		args = append(args, "-config", u.ConfigPath)
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+
+	var b strings.Builder
+	b.WriteString("# duckdns install -systemd で作成したユニットファイルです。\n")
+	b.WriteString("# 削除するには duckdns uninstall -systemd を実行してください。\n")
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=DuckDNS Auto Update Service\n")
+	b.WriteString("Documentation=https://github.com/horitaku/duckdns\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(quoted, " "))
+	b.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+	b.WriteString("Restart=always\n")
+	b.WriteString("RestartSec=10\n")
+	b.WriteString("SyslogIdentifier=duckdns\n")
+	if u.EnvironmentFile != "" {
+		// 先頭の "-" で、ファイルがなくても起動できるようにします
+		fmt.Fprintf(&b, "EnvironmentFile=-%s\n", escapeSpecifiers(u.EnvironmentFile))
+	}
+	b.WriteString("\n# 状態ファイルとリモート設定のキャッシュは、systemd が用意するディレクトリに書き込みます\n")
+	b.WriteString("StateDirectory=duckdns\n")
+	b.WriteString("CacheDirectory=duckdns\n")
+	b.WriteString("WorkingDirectory=/var/lib/duckdns\n")
+	b.WriteString("Environment=DUCKDNS_STATE_FILE=/var/lib/duckdns/state.json\n")
+	b.WriteString("Environment=XDG_CACHE_HOME=/var/cache\n")
+	b.WriteString("\n# 権限を最小限にします\n")
+	for _, line := range hardening {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return []byte(b.String()), nil
+}
+
+// hardening は、ユニットファイルに書き込むサンドボックスの設定です。
+var hardening = []string{
+	"DynamicUser=yes",
+	"NoNewPrivileges=yes",
+	"CapabilityBoundingSet=",
+	"AmbientCapabilities=",
+	"ProtectSystem=strict",
+	"ProtectHome=read-only",
+	"PrivateTmp=yes",
+	"PrivateDevices=yes",
+	"ProtectKernelTunables=yes",
+	"ProtectKernelModules=yes",
+	"ProtectKernelLogs=yes",
+	"ProtectControlGroups=yes",
+	"ProtectClock=yes",
+	"ProtectHostname=yes",
+	"RestrictAddressFamilies=AF_INET AF_INET6 AF_UNIX AF_NETLINK",
+	"RestrictNamespaces=yes",
+	"RestrictRealtime=yes",
+	"RestrictSUIDSGID=yes",
+	"LockPersonality=yes",
+	"MemoryDenyWriteExecute=yes",
+	"SystemCallArchitectures=native",
+	"SystemCallFilter=@system-service",
+	"SystemCallErrorNumber=EPERM",
+}
+
+// quoteArg は、ExecStart の引数を systemd の書式でエスケープします。
+// 空白や引用符を含む場合は二重引用符で囲み、"%" と "$" は展開されないようにします。
+func quoteArg(arg string) string {
+	arg = escapeSpecifiers(arg)
+	arg = strings.ReplaceAll(arg, "$", "$$")
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+// escapeSpecifiers は、systemd の指定子（%h など）として展開されないように "%" をエスケープします。
+func escapeSpecifiers(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// WriteFile は、ユニットファイルを書き込みます。
+//
+// Parameters:
+//   - path: ユニットファイルのパス
+//   - data: ユニットファイルの内容
+//   - force: 既存のファイルを上書きするかどうか
+//
+// Returns:
+//   - error: ファイルが既に存在する場合（force が false のとき）、または書き込みに失敗した場合
+func WriteFile(path string, data []byte, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("ユニットファイルが既に存在します (上書きするには -force を指定してください): %s", path)
+		}
+		return fmt.Errorf("ユニットファイルの作成に失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("ユニットファイルの書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// environmentTemplate は、環境変数ファイルを新しく作成する場合の内容です。
+const environmentTemplate = `# duckdns のサービスに渡す環境変数です。
+# systemd が root として読み込むため、このファイルはパーミッション 0600 のままで構いません。
+# トークンは設定ファイルではなく、ここに書くことをおすすめします。
+#DUCKDNS_TOKEN=your-token
+#DUCKDNS_LOG_LEVEL=info
+`
+
+// WriteEnvironmentFile は、環境変数ファイルがない場合に、パーミッション 0600 で雛形を作成します。
+// 既にある場合は、トークンを書き換えないように何もしません。
+//
+// Parameters:
+//   - path: 環境変数ファイルのパス
+//
+// Returns:
+//   - bool: 新しく作成した場合は true
+//   - error: 作成に失敗した場合
+func WriteEnvironmentFile(path string) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("ディレクトリの作成に失敗しました: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("環境変数ファイルの作成に失敗しました: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(environmentTemplate); err != nil {
+		return false, fmt.Errorf("環境変数ファイルの書き込みに失敗しました: %w", err)
+	}
+	return true, nil
+}
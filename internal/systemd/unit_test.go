@@ -0,0 +1,128 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestUnit_Render は、実行ファイルと設定ファイルのパスを ExecStart に書き込み、権限を制限する設定を含めることをテストします。
+func TestUnit_Render(t *testing.T) {
+	u := Unit{
+		Binary:          "/usr/local/bin/duckdns",
+		ConfigPath:      "/etc/duckdns/config.yaml",
+		EnvironmentFile: "/etc/duckdns/duckdns.env",
+	}
+	data, err := u.Render()
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	unit := string(data)
+
+	for _, want := range []string{
+		"ExecStart=/usr/local/bin/duckdns run -config /etc/duckdns/config.yaml\n",
+		"ExecReload=/bin/kill -HUP $MAINPID\n",
+		"EnvironmentFile=-/etc/duckdns/duckdns.env\n",
+		"DynamicUser=yes\n",
+		"ProtectSystem=strict\n",
+		"StateDirectory=duckdns\n",
+		"Environment=DUCKDNS_STATE_FILE=/var/lib/duckdns/state.json\n",
+		"WantedBy=multi-user.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("%q が含まれるべき:\n%s", want, unit)
+		}
+	}
+}
+
+// TestUnit_Render_Options は、省略した項目と絶対パスでない実行ファイルの扱いをテストします。
+func TestUnit_Render_Options(t *testing.T) {
+	data, err := Unit{Binary: "/opt/duck dns/duckdns"}.Render()
+	if err != nil {
+		t.Fatalf("作成に失敗しました: %v", err)
+	}
+	unit := string(data)
+	if !strings.Contains(unit, "ExecStart=\"/opt/duck dns/duckdns\" run\n") {
+		t.Errorf("空白を含むパスは引用符で囲み、-config は付けないべき:\n%s", unit)
+	}
+	if strings.Contains(unit, "EnvironmentFile=") {
+		t.Errorf("環境変数ファイルを省略した場合は EnvironmentFile を書かないべき:\n%s", unit)
+	}
+
+	if _, err := (Unit{Binary: "duckdns"}).Render(); err == nil {
+		t.Error("絶対パスでない場合はエラーになるべき")
+	}
+}
+
+// TestQuoteArg は、ExecStart の引数のエスケープをテストします。
+func TestQuoteArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"/usr/bin/duckdns", "/usr/bin/duckdns"},
+		{"https://example.com/c.yaml?v=1%20", "https://example.com/c.yaml?v=1%%20"},
+		{"/tmp/$HOME", "/tmp/$$HOME"},
+		{`/a b/"c"`, `"/a b/\"c\""`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := quoteArg(tt.arg); got != tt.want {
+			t.Errorf("quoteArg(%q) = %q, 期待: %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// TestPath は、サービスの名前からユニットファイルのパスを作ることをテストします。
+func TestPath(t *testing.T) {
+	if got := Path("", ""); got != "/etc/systemd/system/duckdns.service" {
+		t.Errorf("既定のパスが一致しません: %s", got)
+	}
+	if got := Path("/tmp/units", "ddns.service"); got != "/tmp/units/ddns.service" {
+		t.Errorf("指定したパスが一致しません: %s", got)
+	}
+}
+
+// TestWriteFile は、既存のユニットファイルは force の場合だけ上書きすることをテストします。
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duckdns.service")
+	if err := WriteFile(path, []byte("first"), false); err != nil {
+		t.Fatalf("書き込みに失敗しました: %v", err)
+	}
+	if err := WriteFile(path, []byte("second"), false); err == nil {
+		t.Error("既存のファイルは force なしではエラーになるべき")
+	}
+	if err := WriteFile(path, []byte("third"), true); err != nil {
+		t.Fatalf("force の場合は上書きするべき: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third" {
+		t.Errorf("内容が一致しません: %s", data)
+	}
+}
+
+// TestWriteEnvironmentFile は、環境変数ファイルを 0600 で作成し、既存のファイルは書き換えないことをテストします。
+func TestWriteEnvironmentFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duckdns", "duckdns.env")
+	created, err := WriteEnvironmentFile(path)
+	if err != nil || !created {
+		t.Fatalf("作成に失敗しました: %v %v", created, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("パーミッションは 0600 であるべき。実際: %o", info.Mode().Perm())
+	}
+
+	if err := os.WriteFile(path, []byte("DUCKDNS_TOKEN=secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if created, err := WriteEnvironmentFile(path); err != nil || created {
+		t.Errorf("既存のファイルは作成し直さないべき: %v %v", created, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "DUCKDNS_TOKEN=secret\n" {
+		t.Errorf("既存のファイルを書き換えないべき: %s", data)
+	}
+}
@@ -0,0 +1,90 @@
+// Package config は、ほかの Go のプログラムから、duckdns コマンドと同じ設定ファイル（YAML）と環境変数を読み込むための API を提供します。
+// Targets で、ドメインごとの上書きを反映した更新先の一覧を取得できます。
+//
+//	cfg, err := config.Load("/etc/duckdns/config.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	if err := cfg.Validate(); err != nil {
+//		return err
+//	}
+//	for _, target := range cfg.Targets() {
+//		fetcher := ip.NewMultipleFetcher(target.IPSources.URLs(), ip.IPv4, 10*time.Second)
+//		schedulers = append(schedulers, scheduler.NewScheduler(target.Interval, fetcher, client, target.Domain, target.Token))
+//	}
+package config
+
+import (
+	"github.com/horitaku/duckdns/internal/config"
+)
+
+// DefaultInterval は、更新チェック間隔の既定値です。
+const DefaultInterval = config.DefaultInterval
+
+// Config は、設定ファイルと環境変数から読み込んだ設定の全体です。
+type Config = config.Config
+
+// DuckDNSConfig は、DuckDNS のドメインとトークンの設定です（duckdns セクション）。
+type DuckDNSConfig = config.DuckDNSConfig
+
+// DomainConfig は、ドメインごとの上書きの設定です（duckdns.domains の要素）。
+type DomainConfig = config.DomainConfig
+
+// UpdateConfig は、更新チェック間隔などの設定です（update セクション）。
+type UpdateConfig = config.UpdateConfig
+
+// IPSource は、IP アドレスを取得するソースの設定です。
+type IPSource = config.IPSource
+
+// IPSources は、IP アドレスを取得するソースのリストです。URLs で URL の一覧を取得できます。
+type IPSources = config.IPSources
+
+// Target は、ドメインごとの上書きを反映した、実際に更新する対象の設定です。
+type Target = config.Target
+
+// LoadOptions は、設定ファイルの読み込み時の動作（未知のキーの扱いやプロファイル）を制御するオプションです。
+type LoadOptions = config.LoadOptions
+
+// ValidationError は、設定のバリデーションエラーです（設定項目のキーごとのエラーを含みます）。
+type ValidationError = config.ValidationError
+
+// Load は、YAML ファイルと環境変数から設定を読み込みます。環境変数の値は、YAML ファイルの値より優先されます。
+// 読み込んだ設定は検証しないため、使う前に Validate を呼び出してください。
+//
+// Parameters:
+//   - path: 読み込む YAML 設定ファイルのパス（空文字列の場合は環境変数のみ）
+//
+// Returns:
+//   - *Config: 読み込まれた設定
+//   - error: 読み込めなかった場合
+func Load(path string) (*Config, error) {
+	return config.Load(path)
+}
+
+// LoadWithOptions は、オプションを指定して YAML ファイルと環境変数から設定を読み込みます。
+//
+// Parameters:
+//   - path: 読み込む YAML 設定ファイルのパス（空文字列の場合は環境変数のみ）
+//   - opts: 読み込みオプション
+//
+// Returns:
+//   - *Config: 読み込まれた設定
+//   - error: 読み込めなかった場合
+func LoadWithOptions(path string, opts LoadOptions) (*Config, error) {
+	return config.LoadWithOptions(path, opts)
+}
+
+// LoadFromFile は、YAML ファイルだけから設定を読み込みます（環境変数は使いません）。
+func LoadFromFile(path string) (*Config, error) {
+	return config.LoadFromFile(path)
+}
+
+// LoadFromEnv は、環境変数（DUCKDNS_DOMAIN、DUCKDNS_TOKEN など）だけから設定を読み込みます。
+func LoadFromEnv() (*Config, error) {
+	return config.LoadFromEnv()
+}
+
+// NewIPSources は、URL のリストから IPSources を作成します。
+func NewIPSources(urls []string) IPSources {
+	return config.NewIPSources(urls)
+}
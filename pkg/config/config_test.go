@@ -0,0 +1,64 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/pkg/config"
+)
+
+// TestLoadFromFile は、設定ファイルを読み込んで、ドメインごとの更新先を取得できることをテストします。
+func TestLoadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `duckdns:
+  token: test-token
+  domains:
+    - name: home
+    - name: office
+      interval: 1m
+update:
+  interval: 10m
+ip_sources:
+  - https://api.ipify.org
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	targets := cfg.Targets()
+	if len(targets) != 2 {
+		t.Fatalf("更新先は2つであるべき: %+v", targets)
+	}
+	if targets[0].Domain != "home" || targets[0].Interval != 10*time.Minute || targets[0].Token != "test-token" {
+		t.Errorf("共通の設定を使うべき: %+v", targets[0])
+	}
+	if targets[1].Domain != "office" || targets[1].Interval != time.Minute {
+		t.Errorf("ドメインごとの上書きを反映するべき: %+v", targets[1])
+	}
+	if urls := targets[0].IPSources.URLs(); len(urls) != 1 || urls[0] != "https://api.ipify.org" {
+		t.Errorf("IP取得ソースが一致しません: %v", urls)
+	}
+}
+
+// TestConfig_Validate は、検証エラーから設定項目のキーを取得できることをテストします。
+func TestConfig_Validate(t *testing.T) {
+	cfg := &config.Config{
+		DuckDNS:   config.DuckDNSConfig{Domain: "home"},
+		Update:    config.UpdateConfig{Interval: config.DefaultInterval},
+		IPSources: config.NewIPSources([]string{"https://api.ipify.org"}),
+	}
+	var ve *config.ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) || len(ve.Keys) == 0 {
+		t.Errorf("トークンがない場合は ValidationError になるべき: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package duckdns は、ほかの Go のプログラムから DuckDNS のレコードを更新するための、DuckDNS API のクライアントを提供します。
+// duckdns コマンドが使っているクライアントと同じもので、リトライ・サーキットブレーカー・タイムアウトなどを設定できます。
+//
+//	client := duckdns.NewClient(duckdns.WithTimeout(5 * time.Second))
+//	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: os.Getenv("DUCKDNS_TOKEN")}); err != nil {
+//		return err
+//	}
+//
+// IP アドレスを空にすると、DuckDNS はリクエストの送信元の IP アドレスを登録します。
+package duckdns
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// 既定値です。
+const (
+	// DefaultHTTPTimeout は、1回のリクエストのタイムアウトの既定値です
+	DefaultHTTPTimeout = duckdns.DefaultHTTPTimeout
+
+	// DefaultMaxRetries は、一時的な失敗を再試行する回数の既定値です
+	DefaultMaxRetries = duckdns.DefaultMaxRetries
+
+	// DefaultUserAgent は、User-Agent ヘッダーの既定値です
+	DefaultUserAgent = duckdns.DefaultUserAgent
+)
+
+// Client は、DuckDNS API に更新のリクエストを送るクライアントです。
+// 複数のゴルーチンから同時に使用できます。
+type Client = duckdns.Client
+
+// UpdateRequest は、Client の Update と UpdateWithRetry に渡す、1回の更新リクエストのパラメーターです。
+// 更新するドメインとトークン、登録する IPv4・IPv6 アドレスや TXT レコードの値をフィールドで指定します。
+type UpdateRequest = duckdns.UpdateRequest
+
+// VerboseResponse は、UpdateRequest の Verbose で求めた DuckDNS の詳しい応答です。
+// ParseVerboseResponse で、登録したIPアドレスと更新の有無を取り出します。
+type VerboseResponse = duckdns.VerboseResponse
+
+// Option は、NewClient で作成するクライアントの既定値を変更するオプションです。
+type Option = duckdns.Option
+
+// RetryConfig は、一時的な失敗を再試行する回数と間隔の設定です。
+type RetryConfig = duckdns.RetryConfig
+
+// BackoffStrategy は、再試行の間隔の決め方です。
+type BackoffStrategy = duckdns.BackoffStrategy
+
+// HTTPDoer は、http.Client の Do メソッド互換の HTTP クライアントです。
+type HTTPDoer = duckdns.HTTPDoer
+
+// HTTPDoerFunc は、関数を HTTPDoer として使用するための型です。
+type HTTPDoerFunc = duckdns.HTTPDoerFunc
+
+// Middleware は、HTTPDoer を包んで、リクエストの送信の前後に処理を追加する関数です。
+type Middleware = duckdns.Middleware
+
+// CircuitBreaker は、DuckDNS への一時的な失敗が続いた場合に、しばらくリクエストを止めるサーキットブレーカーです。
+type CircuitBreaker = duckdns.CircuitBreaker
+
+// StatusError は、DuckDNS が 200 以外の HTTP ステータスを返した場合のエラーです。
+type StatusError = duckdns.StatusError
+
+// クライアントが返すエラーの種類です。errors.Is で失敗の種類を判定できます。
+var (
+	// ErrRejected は、DuckDNS が更新を拒否した（"KO" などを返した）ことを表すエラーです
+	// ドメイン名やトークンの誤りが原因です
+	ErrRejected = duckdns.ErrRejected
+
+	// ErrServerStatus は、DuckDNS が 200 以外の HTTP ステータスを返したことを表すエラーです
+	ErrServerStatus = duckdns.ErrServerStatus
+
+	// ErrRateLimited は、DuckDNS が 429 (Too Many Requests) を返したことを表すエラーです
+	ErrRateLimited = duckdns.ErrRateLimited
+
+	// ErrNetwork は、名前解決・接続・タイムアウトなどで DuckDNS と通信できなかったことを表すエラーです
+	ErrNetwork = duckdns.ErrNetwork
+
+	// ErrCancelled は、コンテキストのキャンセルにより更新を中断したことを表すエラーです
+	ErrCancelled = duckdns.ErrCancelled
+
+	// ErrCircuitOpen は、サーキットが開いているため、リクエストを送信しなかったことを表すエラーです
+	ErrCircuitOpen = duckdns.ErrCircuitOpen
+)
+
+// NewClient は、DuckDNS API のクライアントを作成します。
+// オプションを省略した場合は、https://www.duckdns.org/update に既定のタイムアウトと再試行で送信します。
+//
+// Parameters:
+//   - opts: クライアントのオプション
+//
+// Returns:
+//   - *Client: 作成されたクライアント
+func NewClient(opts ...Option) *Client {
+	return duckdns.NewClient(opts...)
+}
+
+// NewCircuitBreaker は、連続で threshold 回失敗すると cooldown の間リクエストを止めるサーキットブレーカーを作成します。
+//
+// Parameters:
+//   - threshold: サーキットを開くまでの連続失敗回数
+//   - cooldown: サーキットを開いてから試しに送信するまでの時間
+//
+// Returns:
+//   - *CircuitBreaker: 作成されたサーキットブレーカー
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return duckdns.NewCircuitBreaker(threshold, cooldown)
+}
+
+// WithBaseURL は、DuckDNS の更新APIのエンドポイントを指定します（テストや互換サーバー向け）。
+func WithBaseURL(baseURL string) Option {
+	return duckdns.WithBaseURL(baseURL)
+}
+
+// WithHTTPClient は、リクエストの送信に使う HTTP クライアントを指定します。
+// 指定した場合、WithTimeout と WithTransport は使用されません。
+func WithHTTPClient(httpClient HTTPDoer) Option {
+	return duckdns.WithHTTPClient(httpClient)
+}
+
+// WithTransport は、既定の HTTP クライアントが使う http.RoundTripper を指定します。
+func WithTransport(transport http.RoundTripper) Option {
+	return duckdns.WithTransport(transport)
+}
+
+// WithTimeout は、1回のリクエストのタイムアウトを指定します。
+func WithTimeout(timeout time.Duration) Option {
+	return duckdns.WithTimeout(timeout)
+}
+
+// WithRetry は、一時的な失敗を再試行する回数と間隔を指定します。
+func WithRetry(retry RetryConfig) Option {
+	return duckdns.WithRetry(retry)
+}
+
+// WithCircuitBreaker は、一時的な失敗が続いた場合にリクエストを止めるサーキットブレーカーを指定します。
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return duckdns.WithCircuitBreaker(breaker)
+}
+
+// WithMiddleware は、リクエストの送信を包む Middleware を追加します（先に指定したものが外側になります）。
+func WithMiddleware(middlewares ...Middleware) Option {
+	return duckdns.WithMiddleware(middlewares...)
+}
+
+// WithUserAgent は、リクエストの User-Agent ヘッダーの値を指定します。
+func WithUserAgent(userAgent string) Option {
+	return duckdns.WithUserAgent(userAgent)
+}
+
+// WithMaxResponseSize は、読み込むレスポンスボディの最大サイズ（バイト）を指定します。
+func WithMaxResponseSize(size int64) Option {
+	return duckdns.WithMaxResponseSize(size)
+}
+
+// LogRequests は、送信したリクエストとその結果を Debug レベルでログに出力する Middleware を返します。
+// URL のトークンは伏せて出力します。
+func LogRequests(logger *slog.Logger) Middleware {
+	return duckdns.LogRequests(logger)
+}
+
+// NormalizeDomain は、ドメイン名（"home"、"home.duckdns.org"、URL）を DuckDNS のサブドメイン名（"home"）にします。
+//
+// Parameters:
+//   - domain: ドメイン名
+//
+// Returns:
+//   - string: 正規化したサブドメイン名（小文字）
+//   - error: DuckDNS のドメインではない場合、または使用できない文字や長さの場合
+func NormalizeDomain(domain string) (string, error) {
+	return duckdns.NormalizeDomain(domain)
+}
+
+// ParseVerboseResponse は、UpdateRequest の Verbose で求めた DuckDNS の詳しい応答を解析します。
+// ip を省略した場合に、DuckDNS がリクエストの送信元から検出して登録したアドレスを知るために使います。
+//
+// Parameters:
+//   - body: Update が返したレスポンスボディ
+//
+// Returns:
+//   - VerboseResponse: 解析した応答
+//   - error: 詳しい応答ではない場合
+func ParseVerboseResponse(body string) (VerboseResponse, error) {
+	return duckdns.ParseVerboseResponse(body)
+}
+
+// IsTemporary は、再試行すると成功する可能性がある一時的な失敗（通信の失敗、5xx、429）かどうかを返します。
+func IsTemporary(err error) bool {
+	return duckdns.IsTemporary(err)
+}
+
+// RetryAfter は、エラーに含まれる Retry-After の待ち時間を返します（指定がない場合は 0）。
+func RetryAfter(err error) time.Duration {
+	return duckdns.RetryAfter(err)
+}
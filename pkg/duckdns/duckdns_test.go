@@ -0,0 +1,56 @@
+package duckdns_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/horitaku/duckdns/pkg/duckdns"
+	"github.com/horitaku/duckdns/pkg/duckdnstest"
+)
+
+// TestClient_Update は、公開した API だけでドメインを更新できることをテストします。
+func TestClient_Update(t *testing.T) {
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+	srv.AddDomain("test-token", "home")
+	client := duckdns.NewClient(duckdns.WithBaseURL(srv.URL), duckdns.WithUserAgent("embedded/1.0"))
+
+	resp, err := client.Update(context.Background(), duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if err != nil || resp != "OK" {
+		t.Fatalf("Update() = %q, %v", resp, err)
+	}
+	if got := srv.Record("home"); got.IP != "192.0.2.1" {
+		t.Errorf("IPアドレスを登録するべき: %+v", got)
+	}
+	if requests := srv.Requests(); len(requests) != 1 || requests[0].UserAgent != "embedded/1.0" {
+		t.Errorf("指定した User-Agent で送るべき: %+v", requests)
+	}
+}
+
+// TestClient_Update_Errors は、失敗の種類を公開したエラーで判定できることをテストします。
+func TestClient_Update_Errors(t *testing.T) {
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+	srv.AddDomain("test-token", "home")
+	client := duckdns.NewClient(duckdns.WithBaseURL(srv.URL), duckdns.WithRetry(duckdns.RetryConfig{}))
+
+	if _, err := client.Update(context.Background(), duckdns.UpdateRequest{Domains: []string{"home"}, Token: "wrong-token"}); !errors.Is(err, duckdns.ErrRejected) || duckdns.IsTemporary(err) {
+		t.Errorf("KO は ErrRejected で、一時的な失敗ではないべき: %v", err)
+	}
+
+	srv.Enqueue(duckdnstest.Unavailable)
+	_, err := client.Update(context.Background(), duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token"})
+	var statusErr *duckdns.StatusError
+	if !errors.Is(err, duckdns.ErrServerStatus) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || !duckdns.IsTemporary(err) {
+		t.Errorf("503 は StatusError で、一時的な失敗であるべき: %v", err)
+	}
+}
+
+// TestNormalizeDomain は、FQDN をサブドメイン名にすることをテストします。
+func TestNormalizeDomain(t *testing.T) {
+	if got, err := duckdns.NormalizeDomain("Home.duckdns.org"); err != nil || got != "home" {
+		t.Errorf("NormalizeDomain() = %q, %v", got, err)
+	}
+}
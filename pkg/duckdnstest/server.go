@@ -0,0 +1,358 @@
+// Package duckdnstest は、DuckDNS API の代わりに使うテスト用のサーバーを提供します。
+// 本物の DuckDNS に接続せずに、クライアントやスケジューラーを使うプログラムを最後までテストできます。
+//
+//	srv := duckdnstest.NewServer()
+//	defer srv.Close()
+//	srv.AddDomain("test-token", "home")
+//
+//	client := srv.Client()
+//	client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"})
+//	srv.Record("home").IP // "192.0.2.1"
+//
+// 応答は Enqueue で1回ずつ、SetDefault で以降のすべてを変えられるので、KO・429・5xx・遅い応答の場合もテストできます。
+package duckdnstest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/pkg/duckdns"
+)
+
+// Response は、サーバーが返す応答です。
+// ゼロ値は、本物の DuckDNS と同じようにトークンとドメインを確認して "OK" または "KO" を返します。
+type Response struct {
+	// Status は、HTTP ステータスコードです（0 の場合は 200）
+	Status int
+
+	// Body は、レスポンスボディです（空で Status が 200 の場合は、トークンとドメインを確認して "OK" または "KO"）
+	Body string
+
+	// Delay は、応答するまでに待つ時間です（クライアントがキャンセルした場合は待つのをやめます）
+	Delay time.Duration
+
+	// RetryAfter は、Retry-After ヘッダーで指定する待ち時間です（0 の場合は付けません。秒単位に切り捨てます）
+	RetryAfter time.Duration
+}
+
+// よく使う応答です。
+var (
+	// OK は、トークンとドメインを確認せずに "OK" を返して、レコードを更新します
+	OK = Response{Body: "OK"}
+
+	// KO は、レコードを更新せずに "KO" を返します（トークンやドメインが誤っている場合と同じ応答です）
+	KO = Response{Body: "KO"}
+
+	// Unavailable は、503 を返します
+	Unavailable = Response{Status: http.StatusServiceUnavailable}
+)
+
+// RateLimited は、429 (Too Many Requests) を返す応答を返します。
+//
+// Parameters:
+//   - retryAfter: Retry-After ヘッダーで指定する待ち時間（0 の場合は付けません）
+//
+// Returns:
+//   - Response: 429 の応答
+func RateLimited(retryAfter time.Duration) Response {
+	return Response{Status: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// Slow は、delay だけ待ってから、トークンとドメインを確認して応答する Response を返します。
+//
+// Parameters:
+//   - delay: 応答するまでに待つ時間
+//
+// Returns:
+//   - Response: 遅い応答
+func Slow(delay time.Duration) Response {
+	return Response{Delay: delay}
+}
+
+// Record は、ドメインのレコードの今の値です。
+type Record struct {
+	// IP は、A レコードの IPv4 アドレスです（ない場合は空）
+	IP string
+
+	// IPv6 は、AAAA レコードの IPv6 アドレスです（ない場合は空）
+	IPv6 string
+
+	// TXT は、TXT レコードの値です（ない場合は空）
+	TXT string
+}
+
+// Request は、サーバーが受け取った更新のリクエストです。
+type Request struct {
+	// Domains は、domains パラメーターのドメイン名です（".duckdns.org" を除いた名前）
+	Domains []string
+
+	// Token は、token パラメーターです
+	Token string
+
+	// IP は、ip パラメーターです
+	IP string
+
+	// IPv6 は、ipv6 パラメーターです
+	IPv6 string
+
+	// TXT は、txt パラメーターです
+	TXT string
+
+	// HasTXT は、txt パラメーターがある（TXT レコードのリクエストの）場合に true です
+	HasTXT bool
+
+	// Clear は、clear=true の場合に true です
+	Clear bool
+
+	// Verbose は、verbose=true の場合に true です
+	Verbose bool
+
+	// UserAgent は、User-Agent ヘッダーの値です
+	UserAgent string
+
+	// Time は、リクエストを受け取った時刻です
+	Time time.Time
+}
+
+// Server は、DuckDNS API の代わりに使うテスト用のサーバーです。
+// ドメインとトークンは AddDomain で登録し、登録したトークンとドメインの組み合わせだけを "OK" で更新します。
+// 何も登録していない場合は、すべてのトークンとドメインを受け付けます。
+type Server struct {
+	// URL は、更新 API の URL です（duckdns.WithBaseURL に渡します）
+	URL string
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	tokens   map[string]map[string]bool
+	records  map[string]*Record
+	requests []Request
+	queue    []Response
+	fallback Response
+}
+
+// NewServer は、テスト用のサーバーを起動します。使い終わったら Close を呼び出してください。
+//
+// Returns:
+//   - *Server: 起動したサーバー
+func NewServer() *Server {
+	s := &Server{
+		tokens:  map[string]map[string]bool{},
+		records: map[string]*Record{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL + "/update"
+	return s
+}
+
+// Close は、サーバーを停止します。
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client は、このサーバーに送る DuckDNS のクライアントを作成します。
+// opts は、サーバーの URL の後に適用します（再試行を待たずにテストする場合は duckdns.WithRetry(duckdns.RetryConfig{}) を指定します）。
+//
+// Parameters:
+//   - opts: クライアントのオプション
+//
+// Returns:
+//   - *duckdns.Client: 作成されたクライアント
+func (s *Server) Client(opts ...duckdns.Option) *duckdns.Client {
+	return duckdns.NewClient(append([]duckdns.Option{duckdns.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// AddDomain は、token で更新できるドメインを登録します。
+// 1つでも登録すると、登録していないトークンとドメインの組み合わせには "KO" を返します。
+//
+// Parameters:
+//   - token: DuckDNS API のトークン
+//   - domains: ドメイン名（"home" または "home.duckdns.org"）
+func (s *Server) AddDomain(token string, domains ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens[token] == nil {
+		s.tokens[token] = map[string]bool{}
+	}
+	for _, domain := range domains {
+		s.tokens[token][normalize(domain)] = true
+	}
+}
+
+// Enqueue は、次のリクエストから順に1回ずつ返す応答を追加します。
+// 追加した応答を返し終わったら、SetDefault の応答に戻ります。
+func (s *Server) Enqueue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, responses...)
+}
+
+// SetDefault は、Enqueue した応答がない場合に返す応答を変更します（ゼロ値に戻すと、トークンとドメインを確認します）。
+func (s *Server) SetDefault(response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = response
+}
+
+// Record は、ドメインのレコードの今の値を返します（更新していない場合はゼロ値）。
+func (s *Server) Record(domain string) Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[normalize(domain)]; ok {
+		return *r
+	}
+	return Record{}
+}
+
+// Requests は、受け取ったリクエストを受け取った順に返します。
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset は、受け取ったリクエストとレコードと Enqueue した応答を消去し、既定の応答をゼロ値に戻します（登録したドメインは残します）。
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = map[string]*Record{}
+	s.requests = nil
+	s.queue = nil
+	s.fallback = Response{}
+}
+
+// handle は、更新のリクエストに応答します。
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := Request{
+		Token:     q.Get("token"),
+		IP:        q.Get("ip"),
+		IPv6:      q.Get("ipv6"),
+		TXT:       q.Get("txt"),
+		HasTXT:    q.Has("txt"),
+		Clear:     q.Get("clear") == "true",
+		Verbose:   q.Get("verbose") == "true",
+		UserAgent: r.UserAgent(),
+		Time:      time.Now(),
+	}
+	for _, domain := range strings.Split(q.Get("domains"), ",") {
+		if domain = normalize(domain); domain != "" {
+			req.Domains = append(req.Domains, domain)
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	response := s.fallback
+	if len(s.queue) > 0 {
+		response = s.queue[0]
+		s.queue = s.queue[1:]
+	}
+	s.mu.Unlock()
+
+	if response.Delay > 0 {
+		timer := time.NewTimer(response.Delay)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+	if response.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(response.RetryAfter/time.Second)))
+	}
+	if response.Status != 0 && response.Status != http.StatusOK {
+		w.WriteHeader(response.Status)
+		w.Write([]byte(response.Body))
+		return
+	}
+
+	body := response.Body
+	if body == "" {
+		body = "KO"
+		if s.authorized(req) {
+			body = "OK"
+		}
+	}
+	if body == "OK" {
+		changed, rec := s.apply(req, r.RemoteAddr)
+		if req.Verbose {
+			// 本物の DuckDNS と同じように、登録したアドレスと更新の有無を返す
+			status := "NOCHANGE"
+			if changed {
+				status = "UPDATED"
+			}
+			body = strings.Join([]string{"OK", rec.IP, rec.IPv6, status}, "\n")
+		}
+	}
+	w.Write([]byte(body))
+}
+
+// authorized は、リクエストのトークンですべてのドメインを更新できるかどうかを返します。
+func (s *Server) authorized(req Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Token == "" || len(req.Domains) == 0 {
+		return false
+	}
+	if len(s.tokens) == 0 {
+		return true
+	}
+	for _, domain := range req.Domains {
+		if !s.tokens[req.Token][domain] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply は、リクエストをドメインのレコードに反映します。
+// DuckDNS と同じように、txt のリクエストは TXT レコードだけを変え、ip を省略した場合は送信元の IPv4 アドレスを登録します。
+// どれかのレコードが変わったかどうかと、最後のドメインのレコードを返します（verbose=true の応答に使います）。
+func (s *Server) apply(req Request, remoteAddr string) (bool, Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	var last Record
+	for _, domain := range req.Domains {
+		rec, ok := s.records[domain]
+		if !ok {
+			rec = &Record{}
+			s.records[domain] = rec
+		}
+		before := *rec
+		switch {
+		case req.HasTXT && req.Clear:
+			rec.TXT = ""
+		case req.HasTXT:
+			rec.TXT = req.TXT
+		case req.Clear:
+			rec.IP, rec.IPv6 = "", ""
+		default:
+			rec.IP = req.IP
+			if rec.IP == "" {
+				if host, _, err := net.SplitHostPort(remoteAddr); err == nil && !strings.Contains(host, ":") {
+					rec.IP = host
+				}
+			}
+			if req.IPv6 != "" {
+				rec.IPv6 = req.IPv6
+			}
+		}
+		changed = changed || *rec != before
+		last = *rec
+	}
+	return changed, last
+}
+
+// normalize は、ドメイン名を ".duckdns.org" を除いた小文字の名前にします。
+func normalize(domain string) string {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	return strings.TrimSuffix(domain, ".duckdns.org")
+}
@@ -0,0 +1,158 @@
+package duckdnstest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/pkg/duckdns"
+	"github.com/horitaku/duckdns/pkg/duckdnstest"
+)
+
+// newServer は、"test-token" で "home" と "office" を更新できるサーバーを起動します。
+func newServer(t *testing.T) *duckdnstest.Server {
+	t.Helper()
+	srv := duckdnstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddDomain("test-token", "home", "office.duckdns.org")
+	return srv
+}
+
+// TestServer_Update は、登録したトークンとドメインだけを更新し、レコードとリクエストを記録することをテストします。
+func TestServer_Update(t *testing.T) {
+	srv := newServer(t)
+	client := srv.Client(duckdns.WithUserAgent("duckdnstest/1.0"))
+	ctx := context.Background()
+
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home", "office.duckdns.org"}, Token: "test-token", IPv4: "192.0.2.1", IPv6: "2001:db8::1"}); err != nil {
+		t.Fatalf("登録したドメインは更新できるべき: %v", err)
+	}
+	if got := srv.Record("office"); got.IP != "192.0.2.1" || got.IPv6 != "2001:db8::1" {
+		t.Errorf("レコードが一致しません: %+v", got)
+	}
+
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "wrong-token", IPv4: "192.0.2.2"}); !errors.Is(err, duckdns.ErrRejected) {
+		t.Errorf("誤ったトークンは KO になるべき: %v", err)
+	}
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"unknown"}, Token: "test-token", IPv4: "192.0.2.2"}); !errors.Is(err, duckdns.ErrRejected) {
+		t.Errorf("登録していないドメインは KO になるべき: %v", err)
+	}
+	if got := srv.Record("home"); got.IP != "192.0.2.1" {
+		t.Errorf("KO の場合はレコードを変えないべき: %+v", got)
+	}
+
+	// ip を省略した場合は、送信元のアドレスを登録する
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Record("home"); got.IP != "127.0.0.1" || got.IPv6 != "2001:db8::1" {
+		t.Errorf("送信元のアドレスを登録し、AAAA は変えないべき: %+v", got)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("4回のリクエストを記録するべき: %d", len(requests))
+	}
+	if r := requests[0]; len(r.Domains) != 2 || r.Domains[1] != "office" || r.Token != "test-token" || r.UserAgent != "duckdnstest/1.0" {
+		t.Errorf("リクエストが一致しません: %+v", r)
+	}
+}
+
+// TestServer_Verbose は、verbose=true の場合に、登録したアドレスと更新の有無を本物の DuckDNS と同じ形式で返すことをテストします。
+func TestServer_Verbose(t *testing.T) {
+	srv := newServer(t)
+	client := srv.Client()
+	ctx := context.Background()
+
+	for _, want := range []duckdns.VerboseResponse{
+		{IPv4: "127.0.0.1", Changed: true},
+		{IPv4: "127.0.0.1"},
+	} {
+		body, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", Verbose: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := duckdns.ParseVerboseResponse(body)
+		if err != nil {
+			t.Fatalf("詳しい応答を解析できるべき: %v", err)
+		}
+		if got != want {
+			t.Errorf("詳しい応答 = %+v, %+v であるべき", got, want)
+		}
+	}
+	if !srv.Requests()[0].Verbose {
+		t.Error("verbose=true を記録するべき")
+	}
+}
+
+// TestServer_TXT は、TXT レコードのリクエストが A と AAAA を変えないことと、消去をテストします。
+func TestServer_TXT(t *testing.T) {
+	srv := newServer(t)
+	client := srv.Client()
+	ctx := context.Background()
+
+	client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if _, err := client.SetTXT(ctx, []string{"home"}, "test-token", "challenge"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Record("home"); got.TXT != "challenge" || got.IP != "192.0.2.1" {
+		t.Errorf("TXT だけを変えるべき: %+v", got)
+	}
+	client.ClearTXT(ctx, []string{"home"}, "test-token")
+	client.Clear(ctx, []string{"home"}, "test-token")
+	if got := srv.Record("home"); got != (duckdnstest.Record{}) {
+		t.Errorf("すべて消去されるべき: %+v", got)
+	}
+}
+
+// TestServer_Responses は、Enqueue と SetDefault で応答を変えられることをテストします。
+func TestServer_Responses(t *testing.T) {
+	srv := newServer(t)
+	client := srv.Client(duckdns.WithRetry(duckdns.RetryConfig{}))
+	ctx := context.Background()
+
+	srv.Enqueue(duckdnstest.RateLimited(30*time.Second), duckdnstest.Unavailable, duckdnstest.KO)
+	_, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, duckdns.ErrRateLimited) || duckdns.RetryAfter(err) != 30*time.Second {
+		t.Errorf("429 と Retry-After を返すべき: %v", err)
+	}
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"}); !errors.Is(err, duckdns.ErrServerStatus) || !duckdns.IsTemporary(err) {
+		t.Errorf("503 を返すべき: %v", err)
+	}
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"}); !errors.Is(err, duckdns.ErrRejected) {
+		t.Errorf("正しいトークンでも KO を返すべき: %v", err)
+	}
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"}); err != nil {
+		t.Errorf("Enqueue した応答の後は、元の応答に戻るべき: %v", err)
+	}
+
+	srv.SetDefault(duckdnstest.OK)
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"unknown"}, Token: "any", IPv4: "192.0.2.3"}); err != nil || srv.Record("unknown").IP != "192.0.2.3" {
+		t.Errorf("OK はトークンを確認せずに更新するべき: %v", err)
+	}
+
+	srv.Reset()
+	if len(srv.Requests()) != 0 || srv.Record("home") != (duckdnstest.Record{}) {
+		t.Error("Reset でリクエストとレコードを消去するべき")
+	}
+	if _, err := client.Update(ctx, duckdns.UpdateRequest{Domains: []string{"unknown"}, Token: "any", IPv4: "192.0.2.3"}); !errors.Is(err, duckdns.ErrRejected) {
+		t.Errorf("Reset で既定の応答に戻り、登録したドメインは残るべき: %v", err)
+	}
+}
+
+// TestServer_Slow は、遅い応答でクライアントのタイムアウトをテストできることをテストします。
+func TestServer_Slow(t *testing.T) {
+	srv := newServer(t)
+	srv.SetDefault(duckdnstest.Slow(time.Second))
+	client := srv.Client(duckdns.WithTimeout(50*time.Millisecond), duckdns.WithRetry(duckdns.RetryConfig{}))
+
+	start := time.Now()
+	_, err := client.Update(context.Background(), duckdns.UpdateRequest{Domains: []string{"home"}, Token: "test-token", IPv4: "192.0.2.1"})
+	if !errors.Is(err, duckdns.ErrNetwork) || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("タイムアウトで失敗するべき: %v (%s)", err, time.Since(start))
+	}
+	if got := srv.Record("home"); got.IP != "" {
+		t.Errorf("応答する前に切断された場合は更新しないべき: %+v", got)
+	}
+}
@@ -0,0 +1,127 @@
+// Package ip は、ほかの Go のプログラムから、グローバル IP アドレスを外部のサービスなどから取得するための Fetcher を提供します。
+// duckdns コマンドが使っている Fetcher と同じもので、複数のソースへのフォールバックやキャッシュに対応しています。
+//
+//	f := ip.NewMultipleFetcher(ip.DefaultIPv4Sources(), ip.IPv4, 10*time.Second)
+//	addr, err := f.Fetch(ctx)
+package ip
+
+import (
+	"context"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/ip"
+)
+
+// Fetcher は、グローバル IP アドレスを取得するインターフェースです。
+// 独自の取得方法を Scheduler に渡す場合は、このインターフェースを実装します。
+type Fetcher = ip.Fetcher
+
+// DetailedFetcher は、取得した IP アドレスと一緒に、応答したソースなどの詳細な結果を返す Fetcher です。
+type DetailedFetcher = ip.DetailedFetcher
+
+// FetchResult は、IP アドレスの取得の詳細な結果です。
+type FetchResult = ip.FetchResult
+
+// Family は、取得する IP アドレスの種類（IPv4 または IPv6）です。
+type Family = ip.Family
+
+// IP アドレスの種類です。
+const (
+	// IPv4 は、IPv4 アドレスを取得することを表します
+	IPv4 = ip.IPv4
+
+	// IPv6 は、IPv6 アドレスを取得することを表します
+	IPv6 = ip.IPv6
+)
+
+// MultipleFetcher は、複数のソースから順に IP アドレスを取得する Fetcher です。
+// 1つのソースが失敗しても、次のソースから取得します。
+type MultipleFetcher = ip.MultipleFetcher
+
+// HTTPFetcher は、1つの URL から HTTP で IP アドレスを取得する Fetcher です。
+type HTTPFetcher = ip.HTTPFetcher
+
+// FileFetcher は、ファイルから IP アドレスを読み込む Fetcher です。
+type FileFetcher = ip.FileFetcher
+
+// エラーです。
+var (
+	// ErrInvalidIP は、ソースの応答が IP アドレスではない場合のエラーです
+	ErrInvalidIP = ip.ErrInvalidIP
+
+	// ErrRateLimited は、ソースが 429 (Too Many Requests) を返した場合のエラーです
+	ErrRateLimited = ip.ErrRateLimited
+)
+
+// DefaultIPv4Sources は、IPv4 アドレスを取得する既定のソースの URL を返します。
+func DefaultIPv4Sources() []string {
+	return append([]string(nil), ip.DefaultIPv4Sources...)
+}
+
+// DefaultIPv6Sources は、IPv6 アドレスを取得する既定のソースの URL を返します。
+func DefaultIPv6Sources() []string {
+	return append([]string(nil), ip.DefaultIPv6Sources...)
+}
+
+// NewMultipleFetcher は、urls から順に family の IP アドレスを取得する MultipleFetcher を作成します。
+//
+// Parameters:
+//   - urls: 試行する IP アドレス取得エンドポイントの URL のリスト
+//   - family: 取得する IP アドレスの種類
+//   - timeout: 各 HTTP リクエストのタイムアウト
+//
+// Returns:
+//   - *MultipleFetcher: 作成された MultipleFetcher
+func NewMultipleFetcher(urls []string, family Family, timeout time.Duration) *MultipleFetcher {
+	return ip.NewMultipleFetcherForFamily(urls, family, timeout)
+}
+
+// NewHTTPFetcher は、url から HTTP で family の IP アドレスを取得する HTTPFetcher を作成します。
+//
+// Parameters:
+//   - url: IP アドレス取得エンドポイントの URL
+//   - family: 取得する IP アドレスの種類
+//   - timeout: HTTP リクエストのタイムアウト
+//
+// Returns:
+//   - *HTTPFetcher: 作成された HTTPFetcher
+func NewHTTPFetcher(url string, family Family, timeout time.Duration) *HTTPFetcher {
+	return ip.NewHTTPFetcherForFamily(url, family, timeout)
+}
+
+// NewSourceFetcher は、ソースの URL に合った Fetcher を作成します。
+// "file:" のソースはファイルから読み込み、それ以外は HTTP で取得します。
+//
+// Parameters:
+//   - source: ソースの URL（"https://..." または "file:/..."）
+//   - family: 取得する IP アドレスの種類
+//   - timeout: HTTP リクエストのタイムアウト（"file:" のソースでは使いません）
+//
+// Returns:
+//   - Fetcher: 作成された Fetcher
+func NewSourceFetcher(source string, family Family, timeout time.Duration) Fetcher {
+	return ip.NewSourceFetcher(source, family, timeout)
+}
+
+// FetchDetailed は、Fetcher から IP アドレスを取得して、詳細な結果を返します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - f: IP アドレスを取得する Fetcher
+//
+// Returns:
+//   - FetchResult: 取得の詳細な結果
+//   - error: 取得できなかった場合
+func FetchDetailed(ctx context.Context, f Fetcher) (FetchResult, error) {
+	return ip.FetchDetailed(ctx, f)
+}
+
+// ValidateIPv4 は、IPv4 アドレスが有効かどうかを確認します。
+func ValidateIPv4(addr string) error {
+	return ip.ValidateIPv4(addr)
+}
+
+// ValidateIPv6 は、IPv6 アドレスが有効かどうかを確認します。
+func ValidateIPv6(addr string) error {
+	return ip.ValidateIPv6(addr)
+}
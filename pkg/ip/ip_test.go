@@ -0,0 +1,44 @@
+package ip_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/pkg/ip"
+)
+
+// TestNewMultipleFetcher は、失敗したソースの次のソースから IP アドレスを取得できることをテストします。
+func TestNewMultipleFetcher(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.7\n"))
+	}))
+	defer working.Close()
+
+	f := ip.NewMultipleFetcher([]string{failing.URL, working.URL}, ip.IPv4, time.Second)
+	result, err := ip.FetchDetailed(context.Background(), f)
+	if err != nil {
+		t.Fatalf("FetchDetailed() error = %v", err)
+	}
+	if result.IP != "203.0.113.7" || result.Source != working.URL || result.Attempts != 2 {
+		t.Errorf("2つ目のソースから取得するべき: %+v", result)
+	}
+}
+
+// TestDefaultSources は、既定のソースのリストを変更しても元のリストに影響しないことをテストします。
+func TestDefaultSources(t *testing.T) {
+	sources := ip.DefaultIPv4Sources()
+	if len(sources) == 0 || len(ip.DefaultIPv6Sources()) == 0 {
+		t.Fatal("既定のソースがあるべき")
+	}
+	sources[0] = "http://example.invalid/"
+	if ip.DefaultIPv4Sources()[0] == sources[0] {
+		t.Error("既定のソースのリストはコピーを返すべき")
+	}
+}
@@ -0,0 +1,305 @@
+// Package libdns は、libdns（github.com/libdns/libdns）と同じメソッドで DuckDNS のレコードを読み書きする Provider を提供します。
+// Caddy などの libdns を使うプログラムから、このリポジトリの DuckDNS クライアントを使うためのものです。
+//
+// Record は libdns v0.2 の libdns.Record と同じフィールドを同じ順に持つため、型の変換（libdns.Record(r)）でそのまま受け渡せます。
+//
+// DuckDNS のドメインには A・AAAA・TXT レコードが1つずつしかありません。
+// "_acme-challenge.home" のようなドメインの下の名前の TXT レコードは、ドメイン（"home"）の TXT レコードになります。
+package libdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+	"github.com/horitaku/duckdns/internal/httpclient"
+)
+
+// zoneSuffix は、DuckDNS のゾーンです。
+const zoneSuffix = "duckdns.org"
+
+// TTL は、DuckDNS のレコードの TTL です。DuckDNS では変更できません。
+const TTL = 60 * time.Second
+
+// ErrUnsupported は、DuckDNS では登録できないレコードの場合のエラーです。
+var ErrUnsupported = errors.New("DuckDNS では登録できないレコードです")
+
+// Record は、DNS のレコードです（libdns.Record と同じフィールド）。
+type Record struct {
+	// ID は、レコードの識別子です（DuckDNS では使いません）
+	ID string
+
+	// Type は、レコードの種類です（"A", "AAAA", "TXT"）
+	Type string
+
+	// Name は、ゾーンからの相対的な名前です（例: "home"、"_acme-challenge.home"。ゾーンそのものは "@"）
+	Name string
+
+	// Value は、レコードの値です
+	Value string
+
+	// TTL は、レコードの TTL です（DuckDNS では常に 60秒）
+	TTL time.Duration
+
+	// Priority は、MX などの優先度です（DuckDNS では使いません）
+	Priority uint
+
+	// Weight は、SRV などの重みです（DuckDNS では使いません）
+	Weight uint
+}
+
+// Provider は、DuckDNS のレコードを libdns と同じメソッドで読み書きします。
+// ゼロ値のまま APIToken だけを指定して使えます。
+type Provider struct {
+	// APIToken は、DuckDNS API のトークンです
+	APIToken string `json:"api_token,omitempty"`
+
+	// Resolver は、GetRecords でレコードを問い合わせる DNS サーバーです（例: "1.1.1.1:53"。空の場合はシステムのリゾルバー）
+	Resolver string `json:"resolver,omitempty"`
+
+	once   sync.Once
+	client *duckdns.Client
+}
+
+// duckDNS は、DuckDNS のクライアントを返します（最初に呼ばれたときに作成します）。
+func (p *Provider) duckDNS() *duckdns.Client {
+	p.once.Do(func() {
+		if p.client == nil {
+			p.client = duckdns.NewClient()
+		}
+	})
+	return p.client
+}
+
+// GetRecords は、ゾーンのドメインの A・AAAA・TXT レコードを DNS で問い合わせて返します。
+// DuckDNS の API はレコードを読み出せないため、ゾーンはドメイン（例: "home.duckdns.org."）で指定します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - zone: ゾーン（例: "home.duckdns.org."）
+//
+// Returns:
+//   - []Record: レコードの一覧（名前は "@"）
+//   - error: ゾーンが DuckDNS のドメインではない場合、または問い合わせに失敗した場合
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]Record, error) {
+	fqdn := strings.TrimSuffix(strings.ToLower(zone), ".")
+	if _, ok := strings.CutSuffix(fqdn, "."+zoneSuffix); !ok {
+		return nil, fmt.Errorf("%w: ゾーン %s のレコードは一覧にできません (\"<ドメイン>.duckdns.org.\" を指定してください)", ErrUnsupported, zone)
+	}
+
+	var resolver *net.Resolver
+	if p.Resolver == "" {
+		resolver = net.DefaultResolver
+	} else {
+		var err error
+		if resolver, err = httpclient.NewResolver([]string{p.Resolver}); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []Record
+	addrs, err := resolver.LookupIPAddr(ctx, fqdn)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("%s の名前解決に失敗しました: %w", fqdn, err)
+	}
+	for _, addr := range addrs {
+		recordType := "AAAA"
+		if addr.IP.To4() != nil {
+			recordType = "A"
+		}
+		records = append(records, Record{Type: recordType, Name: "@", Value: addr.IP.String(), TTL: TTL})
+	}
+	txts, err := resolver.LookupTXT(ctx, fqdn)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("%s の TXT レコードの問い合わせに失敗しました: %w", fqdn, err)
+	}
+	for _, txt := range txts {
+		records = append(records, Record{Type: "TXT", Name: "@", Value: txt, TTL: TTL})
+	}
+	return records, nil
+}
+
+// AppendRecords は、レコードを追加します。
+// DuckDNS では同じ種類のレコードは1つだけのため、SetRecords と同じく今の値を置き換えます。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - zone: ゾーン（例: "duckdns.org." または "home.duckdns.org."）
+//   - records: 追加するレコード
+//
+// Returns:
+//   - []Record: 追加したレコード
+//   - error: 登録できないレコードの場合、または DuckDNS に拒否された場合
+func (p *Provider) AppendRecords(ctx context.Context, zone string, records []Record) ([]Record, error) {
+	return p.SetRecords(ctx, zone, records)
+}
+
+// SetRecords は、レコードを指定した値にします。
+// 同じドメインの A と AAAA は、1回のリクエストでまとめて登録します。
+// AAAA だけのレコードは登録できません（DuckDNS は ip を省略すると、リクエストの送信元のアドレスを登録するため）。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - zone: ゾーン（例: "duckdns.org." または "home.duckdns.org."）
+//   - records: 登録するレコード
+//
+// Returns:
+//   - []Record: 登録したレコード
+//   - error: 登録できないレコードの場合、または DuckDNS に拒否された場合
+func (p *Provider) SetRecords(ctx context.Context, zone string, records []Record) ([]Record, error) {
+	changes, err := p.group(zone, records)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Record
+	for _, c := range changes {
+		switch {
+		case c.a != nil:
+			ipv6 := ""
+			if c.aaaa != nil {
+				ipv6 = c.aaaa.Value
+			}
+			if _, err := p.duckDNS().Update(ctx, duckdns.UpdateRequest{Domains: []string{c.domain}, Token: p.APIToken, IPv4: c.a.Value, IPv6: ipv6}); err != nil {
+				return done, err
+			}
+			done = appendRecord(done, c.a, c.aaaa)
+		case c.aaaa != nil:
+			return done, fmt.Errorf("%w: %s の AAAA レコードだけを登録することはできません (A レコードも指定してください)", ErrUnsupported, c.domain)
+		}
+		if c.txt != nil {
+			if _, err := p.duckDNS().SetTXT(ctx, []string{c.domain}, p.APIToken, c.txt.Value); err != nil {
+				return done, err
+			}
+			done = appendRecord(done, c.txt)
+		}
+	}
+	return done, nil
+}
+
+// DeleteRecords は、レコードを消去します。
+// DuckDNS は A と AAAA を一緒に消去するため、どちらかを指定すると両方を消去します。
+//
+// Parameters:
+//   - ctx: キャンセルやタイムアウトを制御するコンテキスト
+//   - zone: ゾーン（例: "duckdns.org." または "home.duckdns.org."）
+//   - records: 消去するレコード（値は使いません）
+//
+// Returns:
+//   - []Record: 消去したレコード
+//   - error: 登録できないレコードの場合、または DuckDNS に拒否された場合
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []Record) ([]Record, error) {
+	changes, err := p.group(zone, records)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Record
+	for _, c := range changes {
+		if c.a != nil || c.aaaa != nil {
+			if _, err := p.duckDNS().Clear(ctx, []string{c.domain}, p.APIToken); err != nil {
+				return done, err
+			}
+			done = appendRecord(done, c.a, c.aaaa)
+		}
+		if c.txt != nil {
+			if _, err := p.duckDNS().ClearTXT(ctx, []string{c.domain}, p.APIToken); err != nil {
+				return done, err
+			}
+			done = appendRecord(done, c.txt)
+		}
+	}
+	return done, nil
+}
+
+// change は、1つのドメインに書き込むレコードです。
+type change struct {
+	domain       string
+	a, aaaa, txt *Record
+}
+
+// group は、レコードをドメインごとにまとめます（順番はレコードに最初に出てきた順です）。
+// DuckDNS では1つのドメインに同じ種類のレコードは1つだけのため、値の違う同じ種類のレコードはエラーにします。
+func (p *Provider) group(zone string, records []Record) ([]*change, error) {
+	var changes []*change
+	byDomain := make(map[string]*change)
+	for i := range records {
+		r := &records[i]
+		domain, err := domainOf(r.Name, zone, r.Type == "TXT")
+		if err != nil {
+			return nil, err
+		}
+		c, ok := byDomain[domain]
+		if !ok {
+			c = &change{domain: domain}
+			byDomain[domain] = c
+			changes = append(changes, c)
+		}
+
+		var slot **Record
+		switch r.Type {
+		case "A":
+			slot = &c.a
+		case "AAAA":
+			slot = &c.aaaa
+		case "TXT":
+			slot = &c.txt
+		default:
+			return nil, fmt.Errorf("%w: %s の %s レコード", ErrUnsupported, r.Name, r.Type)
+		}
+		if *slot != nil && (*slot).Value != r.Value {
+			return nil, fmt.Errorf("%w: %s には %s レコードを1つしか登録できません", ErrUnsupported, domain, r.Type)
+		}
+		*slot = r
+	}
+	return changes, nil
+}
+
+// domainOf は、ゾーンと相対的な名前から DuckDNS のサブドメイン名を返します。
+// TXT レコードはドメインの下のどの名前でもドメインのレコードになるため、"_acme-challenge.home" も使えます。
+// A と AAAA は、ドメインそのものの名前だけを使えます。
+func domainOf(name, zone string, allowSubdomain bool) (string, error) {
+	zone = strings.TrimSuffix(strings.ToLower(zone), ".")
+	name = strings.ToLower(name)
+	fqdn := zone
+	switch {
+	case name == "" || name == "@":
+	case strings.HasSuffix(name, "."):
+		// 末尾が "." の絶対的な名前（libdns.AbsoluteName の結果）も受け付けます
+		fqdn = strings.TrimSuffix(name, ".")
+	default:
+		fqdn = name + "." + zone
+	}
+
+	labels, ok := strings.CutSuffix(fqdn, "."+zoneSuffix)
+	if !ok || labels == "" {
+		return "", fmt.Errorf("%w: %s は DuckDNS のドメインではありません", ErrUnsupported, fqdn)
+	}
+	if strings.Contains(labels, ".") && !allowSubdomain {
+		return "", fmt.Errorf("%w: %s の A と AAAA レコードは、ドメイン (\"<ドメイン>.duckdns.org\") にだけ登録できます", ErrUnsupported, fqdn)
+	}
+	return duckdns.NormalizeDomain(fqdn)
+}
+
+// appendRecord は、nil ではないレコードに DuckDNS の TTL を付けて追加します。
+func appendRecord(list []Record, records ...*Record) []Record {
+	for _, r := range records {
+		if r != nil {
+			done := *r
+			done.TTL = TTL
+			list = append(list, done)
+		}
+	}
+	return list
+}
+
+// isNotFound は、err がレコードがないことを表す DNS のエラーかどうかを返します。
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
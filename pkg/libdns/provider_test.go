@@ -0,0 +1,121 @@
+package libdns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/horitaku/duckdns/internal/duckdns"
+)
+
+// newTestProvider は、受け取ったクエリを queries に記録する DuckDNS API のサーバーに接続する Provider を作成します。
+func newTestProvider(t *testing.T, queries *[]url.Values) *Provider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.Query())
+		w.Write([]byte("OK"))
+	}))
+	t.Cleanup(server.Close)
+	return &Provider{
+		APIToken: "test-token",
+		client:   duckdns.NewClientWithOptions(&http.Client{}, server.URL, duckdns.RetryConfig{}),
+	}
+}
+
+// TestProvider_SetRecords は、同じドメインの A と AAAA を1回で送り、TXT を別に送ることをテストします。
+func TestProvider_SetRecords(t *testing.T) {
+	var queries []url.Values
+	p := newTestProvider(t, &queries)
+
+	records, err := p.SetRecords(context.Background(), "duckdns.org.", []Record{
+		{Type: "A", Name: "home", Value: "192.0.2.1"},
+		{Type: "AAAA", Name: "home", Value: "2001:db8::1"},
+		{Type: "TXT", Name: "_acme-challenge.home", Value: "challenge"},
+	})
+	if err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if len(records) != 3 || records[0].TTL != TTL {
+		t.Errorf("登録したレコードを TTL 付きで返すべき: %+v", records)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("リクエストは2回であるべき: %v", queries)
+	}
+	if q := queries[0]; q.Get("domains") != "home" || q.Get("ip") != "192.0.2.1" || q.Get("ipv6") != "2001:db8::1" || q.Get("token") != "test-token" {
+		t.Errorf("A と AAAA を1回で送るべき: %v", q)
+	}
+	if q := queries[1]; q.Get("domains") != "home" || q.Get("txt") != "challenge" || q.Has("ip") {
+		t.Errorf("TXT だけを送るべき: %v", q)
+	}
+}
+
+// TestProvider_AppendRecords_Zone は、ドメインのゾーンの相対的な名前と絶対的な名前を受け付けることをテストします。
+func TestProvider_AppendRecords_Zone(t *testing.T) {
+	for _, name := range []string{"_acme-challenge", "_acme-challenge.home.duckdns.org.", "@"} {
+		var queries []url.Values
+		p := newTestProvider(t, &queries)
+		if _, err := p.AppendRecords(context.Background(), "home.duckdns.org.", []Record{{Type: "TXT", Name: name, Value: "v"}}); err != nil {
+			t.Errorf("%s: AppendRecords() error = %v", name, err)
+			continue
+		}
+		if len(queries) != 1 || queries[0].Get("domains") != "home" {
+			t.Errorf("%s: ドメイン home の TXT を書き換えるべき: %v", name, queries)
+		}
+	}
+}
+
+// TestProvider_DeleteRecords は、TXT と A・AAAA をそれぞれ消去することをテストします。
+func TestProvider_DeleteRecords(t *testing.T) {
+	var queries []url.Values
+	p := newTestProvider(t, &queries)
+
+	_, err := p.DeleteRecords(context.Background(), "duckdns.org.", []Record{
+		{Type: "TXT", Name: "_acme-challenge.home", Value: "challenge"},
+		{Type: "A", Name: "other", Value: "192.0.2.1"},
+	})
+	if err != nil {
+		t.Fatalf("DeleteRecords() error = %v", err)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("リクエストは2回であるべき: %v", queries)
+	}
+	if q := queries[0]; q.Get("domains") != "home" || q.Get("clear") != "true" || !q.Has("txt") {
+		t.Errorf("TXT を消去するべき: %v", q)
+	}
+	if q := queries[1]; q.Get("domains") != "other" || q.Get("clear") != "true" || q.Has("txt") {
+		t.Errorf("A と AAAA を消去するべき: %v", q)
+	}
+}
+
+// TestProvider_Unsupported は、DuckDNS では登録できないレコードをエラーにして、何も送らないことをテストします。
+func TestProvider_Unsupported(t *testing.T) {
+	tests := map[string][]Record{
+		"CNAME":      {{Type: "CNAME", Name: "home", Value: "example.com"}},
+		"サブドメインの A":  {{Type: "A", Name: "www.home", Value: "192.0.2.1"}},
+		"AAAA だけ":    {{Type: "AAAA", Name: "home", Value: "2001:db8::1"}},
+		"TXT が2つ":    {{Type: "TXT", Name: "home", Value: "a"}, {Type: "TXT", Name: "_acme-challenge.home", Value: "b"}},
+		"ゾーンそのもの":    {{Type: "A", Name: "@", Value: "192.0.2.1"}},
+		"DuckDNS 以外": {{Type: "TXT", Name: "www.example.com.", Value: "v"}},
+	}
+	for name, records := range tests {
+		var queries []url.Values
+		p := newTestProvider(t, &queries)
+		if _, err := p.SetRecords(context.Background(), "duckdns.org.", records); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: ErrUnsupported を返すべき: %v", name, err)
+		}
+		if len(queries) != 0 {
+			t.Errorf("%s: DuckDNS に送るべきではない: %v", name, queries)
+		}
+	}
+}
+
+// TestProvider_GetRecords_Zone は、ドメインではないゾーンの一覧をエラーにすることをテストします。
+func TestProvider_GetRecords_Zone(t *testing.T) {
+	p := &Provider{}
+	if _, err := p.GetRecords(context.Background(), "duckdns.org."); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ErrUnsupported を返すべき: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"github.com/horitaku/duckdns/internal/provider"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/pkg/ip"
+)
+
+// Fetcher は、グローバル IP アドレスを取得するインターフェースです（ip.Fetcher と同じです）。
+// NewScheduler と NewSchedulerWithUpdater に渡します。
+type Fetcher = ip.Fetcher
+
+// Updater は、ドメインの DNS レコードを更新するインターフェースです。
+// NewSchedulerWithUpdater または Scheduler の AddTarget に渡します。拒否された場合は ErrRejected を含むエラーを返してください。
+type Updater = provider.Provider
+
+// BatchUpdater は、複数のドメインを1回でまとめて更新できる Updater です。
+// 同じ BatchUpdater で複数のドメインを AddTarget すると、UpdateBatch でまとめて更新します。
+type BatchUpdater = provider.BatchUpdater
+
+// DualStackUpdater は、A と AAAA レコードを同時に更新できる Updater です。
+// Scheduler の SetIPv6Fetcher で IPv6 も更新する場合に、UpdateDualStack で同時に更新します。
+type DualStackUpdater = provider.DualStackUpdater
+
+// AutoDetector は、IP アドレスを送らずに、更新先がリクエストの送信元から検出したアドレスを登録できる Updater です。
+// Scheduler の SetAutoDetect で自動検出のモードにすると、IP を取得せずに UpdateAutoDetect で更新します。
+type AutoDetector = provider.AutoDetector
+
+// DetectedIP は、AutoDetector が返す、更新先が検出して登録した IP アドレスです。
+type DetectedIP = provider.DetectedIP
+
+// MismatchError は、Result の Mismatch に入る、取得したアドレスと検出したアドレスと続けて一致しなかった回数です。
+type MismatchError = scheduler.MismatchError
+
+// FailingError は、Result の Failing に入る、続けて失敗した回数と最後のチェックのエラーです。
+type FailingError = scheduler.FailingError
+
+// PanicReport は、Scheduler がパニックから回復したときに PanicHandler に渡す、パニックの値とスタックトレースです。
+type PanicReport = scheduler.PanicReport
+
+// PanicHandler は、Scheduler の SetPanicHandler で設定する、パニックから回復したときに呼び出される関数です。
+// クラッシュレポートを書き出す場合などに使います。
+type PanicHandler = scheduler.PanicHandler
+
+// Clock は、Scheduler が使う時計です。
+// Scheduler の SetClock で設定すると、テストで実際の時間を待たずに定期チェックを進められます。
+type Clock = scheduler.Clock
+
+// Ticker は、Clock.NewTicker が作成する Ticker です。
+type Ticker = scheduler.Ticker
+
+// Window は、1日のうちの時間帯です。
+// Scheduler の SetSchedule で、定期チェックを実行する時間帯と実行しない時間帯を、指定したタイムゾーンの時刻で設定します。
+type Window = scheduler.Window
+
+// Notifier は、DNS レコードの更新と、チェックや更新の失敗を知らせるインターフェースです。
+// Scheduler の SetNotifier で設定すると、変更がなかったチェックを除いて呼び出されます。
+type Notifier = scheduler.Notifier
+
+// Recorder は、チェックと更新の結果を記録するインターフェースです。
+// Scheduler の SetRecorder で設定すると、チェックのたびに呼び出されます。
+type Recorder = scheduler.Recorder
+
+// ErrRejected は、Updater が更新を拒否された場合に返すエラーです（errors.Is で判定します）。
+// 拒否は再試行しても成功しないため、WithRetry は再試行しません。
+var ErrRejected = provider.ErrRejected
+
+// AutoDetectSource は、自動検出のモードで、Result の Fetch.Source に入る取得元です。
+const AutoDetectSource = scheduler.AutoDetectSource
+
+// ErrAutoDetectUnsupported は、自動検出のモードで、AutoDetector ではない Updater の更新先が返すエラーです。
+var ErrAutoDetectUnsupported = scheduler.ErrAutoDetectUnsupported
+
+// ErrIPMismatch は、自動検出のモードで SetCrossCheck を設定した場合に、IP アドレスを取得した結果と更新先が検出したアドレスが
+// 続けて一致しなかったことを表すエラーです。Result の Mismatch に入ります（errors.Is で判定します）。
+var ErrIPMismatch = scheduler.ErrIPMismatch
+
+// ErrUpdatesFailing は、SetFailureAlert を設定した場合に、更新先のチェックと更新に続けて失敗したことを表すエラーです。
+// Result の Failing に入ります（errors.Is で判定します）。
+var ErrUpdatesFailing = scheduler.ErrUpdatesFailing
+
+// ErrPanic は、プロバイダーの更新などでのパニックから回復した場合に、Result の Err に入るエラーです（errors.Is で判定します）。
+var ErrPanic = scheduler.ErrPanic
+
+// PanicReport の Where に入る、パニックが発生した場所です。
+const (
+	PanicInLoop     = scheduler.PanicInLoop
+	PanicInFetch    = scheduler.PanicInFetch
+	PanicInUpdate   = scheduler.PanicInUpdate
+	PanicInRecorder = scheduler.PanicInRecorder
+	PanicInNotifier = scheduler.PanicInNotifier
+)
+
+// SystemClock は、実際の時刻を使う Clock です（Scheduler の既定の時計）。
+var SystemClock = scheduler.SystemClock
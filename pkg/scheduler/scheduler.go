@@ -0,0 +1,103 @@
+// Package scheduler は、ほかの Go のプログラムから、IP アドレスの変化を定期的にチェックして DuckDNS を更新するための Scheduler を提供します。
+// duckdns コマンドが使っている Scheduler と同じもので、IP アドレスが変わった場合だけ DuckDNS を更新します。
+//
+//	client := duckdns.NewClient()
+//	fetcher := ip.NewMultipleFetcher(ip.DefaultIPv4Sources(), ip.IPv4, 10*time.Second)
+//	s := scheduler.NewScheduler(5*time.Minute, fetcher, client, "home", os.Getenv("DUCKDNS_TOKEN"))
+//	s.Run(ctx) // ctx がキャンセルされるまでブロックします
+//
+// ブロックせずにバックグラウンドで実行する場合は、Start で起動して Stop で停止します。
+//
+//	if err := s.Start(ctx); err != nil { ... }
+//	defer s.Stop(30 * time.Second) // 実行中の更新が終わるまで、最大30秒待ちます
+//
+// IP アドレスの取得（Fetcher）、DNS の更新先（Updater）、時計（Clock）、通知先（Notifier）は interfaces.go のインターフェースで、
+// 独自の実装に差し替えられます。
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/horitaku/duckdns/internal/provider"
+	"github.com/horitaku/duckdns/internal/scheduler"
+	"github.com/horitaku/duckdns/pkg/duckdns"
+	"github.com/horitaku/duckdns/pkg/ip"
+)
+
+// Scheduler は、定期的に IP アドレスをチェックし、変わった場合だけ DuckDNS を更新します。
+type Scheduler = scheduler.Scheduler
+
+// Result は、1回のチェックと更新の結果です（更新先のドメインごと）。
+type Result = scheduler.Result
+
+// Timeouts は、IP アドレスの取得と更新の段階ごとのタイムアウトです（Scheduler の SetTimeouts で設定します）。
+type Timeouts = scheduler.Timeouts
+
+// ライフサイクルのエラーです。
+var (
+	// ErrAlreadyStarted は、実行中の Scheduler の Start を呼び出した場合のエラーです
+	ErrAlreadyStarted = scheduler.ErrAlreadyStarted
+
+	// ErrStopTimeout は、Stop で待つ時間の上限までに、実行中のチェックと更新が終わらなかった場合のエラーです
+	ErrStopTimeout = scheduler.ErrStopTimeout
+)
+
+// NewScheduler は、interval ごとに fetcher で IP アドレスを取得し、変わった場合に client で domain を更新する Scheduler を作成します。
+//
+// Parameters:
+//   - interval: 更新チェックの実行間隔
+//   - fetcher: グローバル IP アドレスを取得する Fetcher
+//   - client: DuckDNS API のクライアント
+//   - domain: DuckDNS のドメイン名
+//   - token: DuckDNS API のトークン
+//
+// Returns:
+//   - *Scheduler: 作成された Scheduler
+func NewScheduler(interval time.Duration, fetcher ip.Fetcher, client *duckdns.Client, domain, token string) *Scheduler {
+	return scheduler.NewScheduler(interval, fetcher, client, domain, token)
+}
+
+// RunAll は、複数の Scheduler をそれぞれゴルーチンで並行に実行し、ctx がキャンセルされてすべてが停止するまでブロックします。
+func RunAll(ctx context.Context, schedulers []*Scheduler) {
+	scheduler.RunAll(ctx, schedulers)
+}
+
+// RunAllOnce は、複数の Scheduler で1回ずつチェックと更新を並行に実行し、すべてが完了するまでブロックします。
+// 失敗したドメインがある場合は、それらのエラーをまとめて返します。
+func RunAllOnce(ctx context.Context, schedulers []*Scheduler) error {
+	return scheduler.RunAllOnce(ctx, schedulers)
+}
+
+// CheckAllOnce は、RunAllOnce と同様に1回ずつチェックと更新を並行に実行し、更新先ごとの結果を返します。
+func CheckAllOnce(ctx context.Context, schedulers []*Scheduler) []Result {
+	return scheduler.CheckAllOnce(ctx, schedulers)
+}
+
+// NewSchedulerWithUpdater は、DuckDNS の代わりに独自の Updater で domain を更新する Scheduler を作成します。
+// 一時的な失敗を再試行する場合は、WithRetry で包んだ Updater を渡します。
+//
+// Parameters:
+//   - interval: 更新チェックの実行間隔
+//   - fetcher: グローバル IP アドレスを取得する Fetcher
+//   - u: DNS レコードを更新する Updater
+//   - domain: 更新するドメイン名
+//
+// Returns:
+//   - *Scheduler: 作成された Scheduler
+func NewSchedulerWithUpdater(interval time.Duration, fetcher Fetcher, u Updater, domain string) *Scheduler {
+	return scheduler.NewSchedulerWithProvider(interval, fetcher, u, domain)
+}
+
+// WithRetry は、一時的な失敗の場合に DuckDNS クライアントと同じバックオフで更新を再試行するように Updater を包みます。
+// ErrRejected を含むエラー（拒否）は再試行しません。retry がゼロ値の場合は、DuckDNS クライアントの既定値を使用します。
+//
+// Parameters:
+//   - u: 包む Updater
+//   - retry: 最大リトライ回数とバックオフ時間
+//
+// Returns:
+//   - Updater: 再試行付きの Updater
+func WithRetry(u Updater, retry duckdns.RetryConfig) Updater {
+	return provider.WithRetry(u, retry)
+}
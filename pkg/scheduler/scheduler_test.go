@@ -0,0 +1,182 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/horitaku/duckdns/pkg/duckdns"
+	"github.com/horitaku/duckdns/pkg/duckdnstest"
+	"github.com/horitaku/duckdns/pkg/ip"
+	"github.com/horitaku/duckdns/pkg/scheduler"
+)
+
+// fixedFetcher は、常に同じ IP アドレスを返す Fetcher です。
+type fixedFetcher string
+
+// Fetch は、IP アドレスを返します。
+func (f fixedFetcher) Fetch(ctx context.Context) (string, error) {
+	return string(f), nil
+}
+
+// TestNewScheduler は、公開した API だけで IP アドレスを取得して DuckDNS を更新できることをテストします。
+func TestNewScheduler(t *testing.T) {
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+	srv.AddDomain("test-token", "home")
+
+	var fetcher ip.Fetcher = fixedFetcher("198.51.100.4")
+	s := scheduler.NewScheduler(time.Minute, fetcher, srv.Client(), "home", "test-token")
+
+	results := scheduler.CheckAllOnce(context.Background(), []*scheduler.Scheduler{s})
+	if len(results) != 1 || results[0].Err != nil || !results[0].Updated || results[0].NewIP != "198.51.100.4" {
+		t.Fatalf("最初のチェックで更新するべき: %+v", results)
+	}
+	if got := srv.Record("home"); got.IP != "198.51.100.4" {
+		t.Errorf("取得した IP アドレスを登録するべき: %+v", got)
+	}
+
+	// IP アドレスが変わっていなければ、更新しない
+	if err := scheduler.RunAllOnce(context.Background(), []*scheduler.Scheduler{s}); err != nil {
+		t.Fatalf("RunAllOnce() error = %v", err)
+	}
+	if requests := srv.Requests(); len(requests) != 1 {
+		t.Errorf("IP アドレスが変わっていなければ更新しないべき: %+v", requests)
+	}
+}
+
+// TestScheduler_SetAutoDetect は、自動検出のモードでは IP アドレスを取得せずに、DuckDNS が検出したアドレスを登録することをテストします。
+func TestScheduler_SetAutoDetect(t *testing.T) {
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+	srv.AddDomain("test-token", "home")
+
+	s := scheduler.NewScheduler(time.Minute, fixedFetcher("198.51.100.4"), srv.Client(), "home", "test-token")
+	s.SetAutoDetect(true)
+
+	results := scheduler.CheckAllOnce(context.Background(), []*scheduler.Scheduler{s})
+	if len(results) != 1 || results[0].Err != nil || !results[0].Updated || results[0].NewIP != "127.0.0.1" || results[0].Fetch.Source != scheduler.AutoDetectSource {
+		t.Fatalf("DuckDNS が検出したアドレスで更新するべき: %+v", results)
+	}
+	if r := srv.Requests()[0]; r.IP != "" || !r.Verbose {
+		t.Errorf("ip を省略して verbose=true で送信するべき: %+v", r)
+	}
+}
+
+// TestNewScheduler_Rejected は、DuckDNS が拒否した場合に結果のエラーになり、次のチェックで再び更新することをテストします。
+func TestNewScheduler_Rejected(t *testing.T) {
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+	srv.AddDomain("test-token", "home")
+	srv.Enqueue(duckdnstest.KO)
+
+	s := scheduler.NewScheduler(time.Minute, fixedFetcher("198.51.100.4"), srv.Client(), "home", "test-token")
+	if err := scheduler.RunAllOnce(context.Background(), []*scheduler.Scheduler{s}); err == nil {
+		t.Fatal("KO の場合はエラーになるべき")
+	}
+	if err := scheduler.RunAllOnce(context.Background(), []*scheduler.Scheduler{s}); err != nil {
+		t.Fatalf("次のチェックで更新するべき: %v", err)
+	}
+	if got := srv.Record("home"); got.IP != "198.51.100.4" || len(srv.Requests()) != 2 {
+		t.Errorf("2回目で登録するべき: %+v", got)
+	}
+}
+
+// memoryUpdater は、更新したドメインと IP アドレスを覚える Updater です。
+type memoryUpdater struct {
+	records map[string]string
+	err     error
+}
+
+// Name は、Updater の名前を返します。
+func (m *memoryUpdater) Name() string {
+	return "memory"
+}
+
+// Update は、IP アドレスを覚えます。
+func (m *memoryUpdater) Update(ctx context.Context, domain, ip string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.records[domain] = ip
+	return nil
+}
+
+// stoppedClock は、時刻が進まない Clock です。
+type stoppedClock struct {
+	scheduler.Clock
+	now time.Time
+}
+
+// Now は、決まった時刻を返します。
+func (c stoppedClock) Now() time.Time {
+	return c.now
+}
+
+// notifierFunc は、関数を Notifier にします。
+type notifierFunc func(ctx context.Context, r scheduler.Result) error
+
+// Notify は、関数を呼び出します。
+func (f notifierFunc) Notify(ctx context.Context, r scheduler.Result) error {
+	return f(ctx, r)
+}
+
+// TestNewSchedulerWithUpdater は、公開したインターフェースで、更新先・時計・通知先を独自の実装に差し替えられることをテストします。
+func TestNewSchedulerWithUpdater(t *testing.T) {
+	updater := &memoryUpdater{records: map[string]string{}}
+	var notified []scheduler.Result
+	s := scheduler.NewSchedulerWithUpdater(time.Minute, fixedFetcher("198.51.100.4"), scheduler.WithRetry(updater, duckdns.RetryConfig{}), "home.example.com")
+	s.SetClock(stoppedClock{Clock: scheduler.SystemClock, now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	s.SetNotifier(notifierFunc(func(ctx context.Context, r scheduler.Result) error {
+		notified = append(notified, r)
+		return nil
+	}))
+
+	results := s.CheckOnce(context.Background())
+	if len(results) != 1 || results[0].Err != nil || !results[0].Updated || results[0].Provider != "memory" {
+		t.Fatalf("Updater で更新するべき: %+v", results)
+	}
+	if updater.records["home.example.com"] != "198.51.100.4" {
+		t.Errorf("Updater に IP アドレスを渡すべき: %+v", updater.records)
+	}
+	if results[0].Duration != 0 {
+		t.Errorf("所要時間は差し替えた時計で測るべき: %s", results[0].Duration)
+	}
+	if len(notified) != 1 || notified[0].NewIP != "198.51.100.4" {
+		t.Errorf("更新を Notifier に知らせるべき: %+v", notified)
+	}
+
+	// 拒否は ErrRejected で判定できる
+	updater.err = fmt.Errorf("テスト: %w", scheduler.ErrRejected)
+	s = scheduler.NewSchedulerWithUpdater(time.Minute, fixedFetcher("198.51.100.4"), scheduler.WithRetry(updater, duckdns.RetryConfig{}), "home.example.com")
+	if err := s.RunOnce(context.Background()); !errors.Is(err, scheduler.ErrRejected) {
+		t.Errorf("拒否のエラーは ErrRejected であるべき: %v", err)
+	}
+}
+
+// TestScheduler_StartStop は、公開した API で、Start でバックグラウンドで起動して Stop で停止できることをテストします。
+func TestScheduler_StartStop(t *testing.T) {
+	srv := duckdnstest.NewServer()
+	defer srv.Close()
+
+	s := scheduler.NewScheduler(time.Hour, fixedFetcher("198.51.100.4"), srv.Client(), "home", "test-token")
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Start(context.Background()); !errors.Is(err, scheduler.ErrAlreadyStarted) {
+		t.Errorf("実行中の Start は ErrAlreadyStarted であるべき: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.Record("home").IP == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	s.Wait()
+	if got := srv.Record("home"); got.IP != "198.51.100.4" {
+		t.Errorf("起動直後に更新するべき: %+v", got)
+	}
+}